@@ -5,14 +5,20 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/SAP/astonish/pkg/agent"
+	"github.com/SAP/astonish/pkg/api"
 	"github.com/SAP/astonish/pkg/client"
 	"github.com/SAP/astonish/pkg/config"
 	"github.com/SAP/astonish/pkg/credentials"
@@ -20,7 +26,6 @@ import (
 	"github.com/SAP/astonish/pkg/launcher"
 	"github.com/SAP/astonish/pkg/tools"
 	"github.com/SAP/astonish/pkg/ui"
-	"google.golang.org/adk/session"
 )
 
 func handleFlowsCommand(args []string) error {
@@ -41,21 +46,39 @@ func handleFlowsCommand(args []string) error {
 		return handleListCommand()
 	case "show":
 		return handleShowCommand(args[1:])
+	case "info":
+		return handleInfoCommand(args[1:])
+	case "test":
+		return handleFlowTestCommand(args[1:])
+	case "graph":
+		return handleGraphCommand(args[1:])
+	case "artifacts":
+		return handleArtifactsCommand(args[1:])
+	case "create":
+		return handleCreateCommand(args[1:])
+	case "generate":
+		return handleGenerateCommand(args[1:])
 	case "edit":
 		return handleEditCommand(args[1:])
 	case "import":
 		return handleImportCommand(args[1:])
+	case "export":
+		return handleExportCommand(args[1:])
 	case "remove":
 		return handleRemoveCommand(args[1:])
 	case "store":
 		return handleStoreCommand(args[1:])
+	case "search":
+		return handleStoreSearchCommand(args[1:])
+	case "tap":
+		return handleTapCommand(args[1:])
 	default:
 		return fmt.Errorf("unknown flows command: %s", args[0])
 	}
 }
 
 func printFlowsUsage() {
-	fmt.Println("usage: astonish flows [-h] {run,list,show,edit,import,remove,store} ...")
+	fmt.Println("usage: astonish flows [-h] {run,list,show,info,test,graph,artifacts,create,generate,edit,import,export,remove,store,search,tap} ...")
 	fmt.Println("")
 	fmt.Println("Design and run AI flows - powerful automation workflows")
 	fmt.Println("powered by LLMs with visual design and CLI execution.")
@@ -64,10 +87,19 @@ func printFlowsUsage() {
 	fmt.Println("  run                 Execute a flow")
 	fmt.Println("  list                List available flows")
 	fmt.Println("  show                Visualize flow structure")
+	fmt.Println("  info                Show a flow's variables and inputs")
+	fmt.Println("  test                Run a flow's unit tests with canned model responses")
+	fmt.Println("  graph               Export the flow graph as Mermaid or DOT")
+	fmt.Println("  artifacts           List or download the artifacts of a run")
+	fmt.Println("  create              Build a new flow with an interactive wizard")
+	fmt.Println("  generate            Draft a new flow from a description with AI")
 	fmt.Println("  edit                Edit a flow YAML file")
-	fmt.Println("  import              Import a flow from a local YAML file")
+	fmt.Println("  import              Import a flow from a YAML file, bundle or URL")
+	fmt.Println("  export              Export a flow as a bundle with the MCP servers it needs")
 	fmt.Println("  remove              Remove a flow")
 	fmt.Println("  store               Browse and install flows from stores")
+	fmt.Println("  search              Search the flows of all taps (same as 'store search')")
+	fmt.Println("  tap                 Add, list or remove taps (same as 'astonish tap')")
 	fmt.Println("")
 	fmt.Println("options:")
 	fmt.Println("  -h, --help          Show this help message")
@@ -89,9 +121,26 @@ func handleRunCommand(args []string) error {
 	port := runCmd.Int("port", 8080, "Port for web server (only used with --browser)")
 	debugMode := runCmd.Bool("debug", false, "Enable debug mode to show tool inputs and responses")
 	autoApprove := runCmd.Bool("auto-approve", false, "Automatically approve all tool executions")
+	dumpLLMIO := runCmd.String("dump-llm-io", "", "Write every LLM request and response of the run as numbered JSON files to this directory")
+	noCache := runCmd.Bool("no-cache", false, "Call the model even for nodes with cache_response")
+	approvalProfile := runCmd.String("approval-profile", "", "Approval profile of the config (approval_profiles) that approves, prompts for or denies tool calls")
+	nonInteractive := runCmd.Bool("non-interactive", false, "Never prompt (for CI): input nodes need -p values, unapproved tool calls are denied")
+	exportState := runCmd.String("export-state", "", "Write the final flow state to this JSON file when the flow reaches END")
+	outputFormat := runCmd.String("output", "text", "Output format: text (interactive console) or json (newline-delimited events on stdout)")
+	trace := runCmd.Bool("trace", false, "Show a live trace panel with the node path, retries and timings (console only)")
+	chat := runCmd.Bool("chat", false, "Run the flow as a multi-turn chat: after END, wait for the next message and start over")
+	resume := runCmd.String("resume", "", "Resume a cancelled run by its session ID (needs sessions.storage: file)")
+	step := runCmd.Bool("step", false, "Pause before each node to inspect its prompt, arguments and state, and continue, skip it, edit state or abort")
+	record := runCmd.String("record", "", "Record every model call, tool call and answer of the run into this cassette file")
+	preview := runCmd.Bool("preview", false, "Show a dimmed live preview of the text of LLM nodes whose output is not streamed (output_model)")
+	replay := runCmd.String("replay", "", "Re-execute a recorded run from this cassette file, without calling providers or tools")
+	transcript := runCmd.String("transcript", "", "Write a Markdown transcript of the run (nodes, messages, tool calls, outputs) to this file")
+	transcriptPrompts := runCmd.Bool("transcript-prompts", false, "Include the prompt of each LLM node in the --transcript")
 
 	var params stringArray
 	runCmd.Var(&params, "p", "Parameter to pass to the agent in key=value format (can be used multiple times)")
+	var vars stringArray
+	runCmd.Var(&vars, "var", "Value for a flow variable in key=value format (can be used multiple times)")
 
 	// Pre-process args to allow positional agent name to be anywhere
 	// We extract the first non-flag argument as the agent name
@@ -111,7 +160,7 @@ func handleRunCommand(args []string) error {
 			// Check if it's a flag that takes an argument and doesn't use =
 			if !strings.Contains(arg, "=") {
 				name := strings.TrimLeft(arg, "-")
-				if name == "provider" || name == "model" || name == "port" || name == "p" || name == "param" {
+				if name == "provider" || name == "model" || name == "port" || name == "p" || name == "param" || name == "var" || name == "output" || name == "export-state" || name == "resume" || name == "record" || name == "replay" || name == "transcript" || name == "approval-profile" || name == "dump-llm-io" {
 					skipNext = true
 				}
 			}
@@ -140,28 +189,55 @@ func handleRunCommand(args []string) error {
 		}
 	}
 
+	if *nonInteractive && *useBrowser {
+		return fmt.Errorf("--non-interactive cannot be combined with --browser")
+	}
+	jsonOutput := *outputFormat == "json"
+	if !jsonOutput && *outputFormat != "text" {
+		return fmt.Errorf("unknown output format %q (use text or json)", *outputFormat)
+	}
+	if jsonOutput && *useBrowser {
+		return fmt.Errorf("--output json cannot be combined with --browser")
+	}
+	if *chat && (jsonOutput || *useBrowser) {
+		return fmt.Errorf("--chat needs the console (no --output json or --browser)")
+	}
+	if *step && (jsonOutput || *useBrowser || *nonInteractive) {
+		return fmt.Errorf("--step needs the interactive console (no --output json, --browser or --non-interactive)")
+	}
+	if *record != "" || *replay != "" {
+		if jsonOutput || *useBrowser {
+			return fmt.Errorf("--record and --replay need the console (no --output json or --browser)")
+		}
+		if *record != "" && *replay != "" {
+			return fmt.Errorf("--record cannot be combined with --replay")
+		}
+	}
+	if *transcript != "" && (jsonOutput || *useBrowser) {
+		return fmt.Errorf("--transcript needs the console (no --output json or --browser)")
+	}
+	if *transcriptPrompts && *transcript == "" {
+		return fmt.Errorf("--transcript-prompts needs --transcript")
+	}
+	approvalPolicy, err := agent.NewApprovalPolicy(appCfg, *approvalProfile)
+	if err != nil {
+		return err
+	}
+	if *resume != "" {
+		if jsonOutput || *useBrowser {
+			return fmt.Errorf("--resume needs the console (no --output json or --browser)")
+		}
+		if appCfg.Sessions.Storage != "file" {
+			return fmt.Errorf("--resume needs sessions.storage: file; runs kept in memory cannot be resumed")
+		}
+	}
+
 	// If provider is still empty, default to gemini
 	if *providerName == "" {
 		*providerName = "gemini"
 	}
 
-	// Set up provider credentials from the encrypted credential store.
-	// After migration, secrets are scrubbed from config.yaml and stored
-	// only in the credential store. InjectProviderSecretsToConfig re-hydrates
-	// the in-memory config map so GetProvider() can read them.
-	configDir, configDirErr := config.GetConfigDir()
-	if configDirErr == nil {
-		if cs, csErr := credentials.Open(configDir); csErr == nil {
-			tools.SetCredentialStore(cs)
-			config.SetInstalledSecretGetter(cs.GetSecret)
-			config.InjectProviderSecretsToConfig(appCfg, cs.GetSecret)
-			config.SetupAllProviderEnvFromStore(appCfg, cs.GetSecret)
-		} else {
-			config.SetupAllProviderEnv(appCfg)
-		}
-	} else {
-		config.SetupAllProviderEnv(appCfg)
-	}
+	setupProviderCredentials(appCfg)
 
 	// Load MCP config and set environment variables from all servers
 	// This allows internal tools to access configuration defined for MCP servers (e.g. GITHUB_HOST)
@@ -186,7 +262,142 @@ func handleRunCommand(args []string) error {
 		}
 	}
 
-	// Try to find the agent file
+	// Keep stdout for the event stream in JSON mode
+	progressOut := io.Writer(os.Stdout)
+	if jsonOutput {
+		progressOut = os.Stderr
+	}
+	agentPath, err := resolveFlowPath(agentName, progressOut)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadAgent(agentPath)
+	if err != nil {
+		return fmt.Errorf("failed to load agent: %w", err)
+	}
+	flowName := strings.TrimSuffix(filepath.Base(agentPath), filepath.Ext(agentPath))
+
+	for _, d := range cfg.SchemaWarnings {
+		fmt.Fprintf(progressOut, "Warning: %s\n", d)
+	}
+
+	// Parse variables and check them against the flow before starting
+	variables := make(map[string]string)
+	for _, v := range vars {
+		key, value, ok := strings.Cut(v, "=")
+		if !ok {
+			return fmt.Errorf("malformed --var %q (expected key=value)", v)
+		}
+		variables[strings.TrimSpace(key)] = value
+	}
+	if _, err := cfg.ResolveVariables(variables); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	// Create the base session service and wrap it to fix state initialization bug.
+	// With sessions.storage: file runs are kept, so cancelled ones can be resumed
+	baseService, err := launcher.NewFlowSessionService(appCfg)
+	if err != nil {
+		return err
+	}
+	safeService := NewAutoInitService(baseService)
+
+	// Scripted runs: no UI, one JSON event per line on stdout
+	if jsonOutput {
+		events := launcher.NewJSONEventWriter(os.Stdout, cfg, *autoApprove)
+		output, state, err := launcher.RunHeadlessWithState(ctx, &launcher.HeadlessConfig{
+			AgentConfig:    cfg,
+			AppConfig:      appCfg,
+			FlowName:       flowName,
+			ProviderName:   *providerName,
+			ModelName:      *modelName,
+			SessionService: safeService,
+			Parameters:     parameters,
+			Variables:      variables,
+			DebugMode:      *debugMode,
+			OnEvent:        events.HandleEvent,
+			DenyApprovals:  !*autoApprove,
+			ApprovalPolicy: approvalPolicy,
+			NoCache:        *noCache,
+			DumpLLMIO:      *dumpLLMIO,
+			ExportState:    *exportState,
+		})
+		events.Finish(output, state, err)
+		return err
+	}
+
+	// Choose launcher based on --browser flag
+	if *useBrowser {
+		// Use simple web launcher with chat-only UI
+		return launcher.RunSimpleWeb(ctx, &launcher.SimpleWebConfig{
+			AgentConfig:    cfg,
+			ProviderName:   *providerName,
+			ModelName:      *modelName,
+			SessionService: safeService,
+			Port:           *port,
+			AutoApprove:    *autoApprove,
+			ApprovalPolicy: approvalPolicy,
+			NoCache:        *noCache,
+			DumpLLMIO:      *dumpLLMIO,
+			Variables:      variables,
+		})
+	}
+
+	// Use our custom console launcher
+	return launcher.RunConsole(ctx, &launcher.ConsoleConfig{
+		AgentConfig:    cfg,
+		AppConfig:      appCfg,
+		FlowName:       flowName,
+		ProviderName:   *providerName,
+		ModelName:      *modelName,
+		SessionService: safeService,
+		DebugMode:      *debugMode,
+		AutoApprove:    *autoApprove,
+		ApprovalPolicy: approvalPolicy,
+		NoCache:        *noCache,
+		DumpLLMIO:      *dumpLLMIO,
+		Parameters:     parameters,
+		Variables:      variables,
+		Trace:          *trace,
+		NonInteractive: *nonInteractive,
+		ExportState:    *exportState,
+		Chat:           *chat,
+		ResumeSession:  *resume,
+		Step:           *step,
+		Record:         *record,
+		Replay:         *replay,
+		Transcript:     *transcript,
+		Preview:        *preview,
+
+		TranscriptPrompts: *transcriptPrompts,
+	})
+}
+
+// setupProviderCredentials sets up provider credentials from the encrypted
+// credential store. After migration, secrets are scrubbed from config.yaml
+// and stored only in the credential store. InjectProviderSecretsToConfig
+// re-hydrates the in-memory config map so GetProvider() can read them.
+func setupProviderCredentials(appCfg *config.AppConfig) {
+	configDir, configDirErr := config.GetConfigDir()
+	if configDirErr == nil {
+		if cs, csErr := credentials.Open(configDir); csErr == nil {
+			tools.SetCredentialStore(cs)
+			config.SetInstalledSecretGetter(cs.GetSecret)
+			config.InjectProviderSecretsToConfig(appCfg, cs.GetSecret)
+			config.SetupAllProviderEnvFromStore(appCfg, cs.GetSecret)
+			return
+		}
+	}
+	config.SetupAllProviderEnv(appCfg)
+}
+
+// resolveFlowPath finds the YAML file of a flow: a path, a file in the
+// current directory, the flows directories, or the flow store (installing it
+// on demand). Store download progress is written to out.
+func resolveFlowPath(agentName string, out io.Writer) (string, error) {
 	// 1. Check if it's a full path or in current dir
 	agentPath := agentName
 	if _, err := os.Stat(agentPath); os.IsNotExist(err) {
@@ -198,8 +409,7 @@ func handleRunCommand(args []string) error {
 			if err == nil {
 				sysAgentPath := filepath.Join(agentsDir, fmt.Sprintf("%s.yaml", agentName))
 				if _, err := os.Stat(sysAgentPath); err == nil {
-					agentPath = sysAgentPath
-					goto Found
+					return sysAgentPath, nil
 				}
 			}
 
@@ -208,8 +418,7 @@ func handleRunCommand(args []string) error {
 			if err == nil {
 				flowPath := filepath.Join(flowsDir, fmt.Sprintf("%s.yaml", agentName))
 				if _, err := os.Stat(flowPath); err == nil {
-					agentPath = flowPath
-					goto Found
+					return flowPath, nil
 				}
 			}
 
@@ -221,19 +430,17 @@ func handleRunCommand(args []string) error {
 
 				// Check installed flows for this specific tap
 				if path, ok := store.GetInstalledFlowPath(tapName, flowName); ok {
-					agentPath = path
-					goto Found
+					return path, nil
 				}
 
 				// Try to fetch from store
 				// - Bare names (no /) only check official store
 				// - Prefixed names (tap/flow) check specific tap
-				fmt.Printf("Flow not found locally, checking %s store...\n", tapName)
+				fmt.Fprintf(out, "Flow not found locally, checking %s store...\n", tapName)
 				if err := store.InstallFlow(tapName, flowName); err == nil {
 					if path, ok := store.GetInstalledFlowPath(tapName, flowName); ok {
-						fmt.Printf("✓ Downloaded from %s store\n", tapName)
-						agentPath = path
-						goto Found
+						fmt.Fprintf(out, "✓ Downloaded from %s store\n", tapName)
+						return path, nil
 					}
 				}
 			}
@@ -241,48 +448,11 @@ func handleRunCommand(args []string) error {
 			// 7. Check in local dev path (fallback)
 			agentPath = fmt.Sprintf("agents/%s.yaml", agentName)
 			if _, err := os.Stat(agentPath); os.IsNotExist(err) {
-				return fmt.Errorf("flow not found: %s\nTip: Run 'astonish flows store list' to see available flows", agentName)
+				return "", fmt.Errorf("flow not found: %s\nTip: Run 'astonish flows store list' to see available flows", agentName)
 			}
 		}
 	}
-
-Found:
-
-	cfg, err := config.LoadAgent(agentPath)
-	if err != nil {
-		return fmt.Errorf("failed to load agent: %w", err)
-	}
-
-	ctx := context.Background()
-
-	// Create the base session service and wrap it to fix state initialization bug
-	baseService := session.InMemoryService()
-	safeService := NewAutoInitService(baseService)
-
-	// Choose launcher based on --browser flag
-	if *useBrowser {
-		// Use simple web launcher with chat-only UI
-		return launcher.RunSimpleWeb(ctx, &launcher.SimpleWebConfig{
-			AgentConfig:    cfg,
-			ProviderName:   *providerName,
-			ModelName:      *modelName,
-			SessionService: safeService,
-			Port:           *port,
-			AutoApprove:    *autoApprove,
-		})
-	}
-
-	// Use our custom console launcher
-	return launcher.RunConsole(ctx, &launcher.ConsoleConfig{
-		AgentConfig:    cfg,
-		AppConfig:      appCfg,
-		ProviderName:   *providerName,
-		ModelName:      *modelName,
-		SessionService: safeService,
-		DebugMode:      *debugMode,
-		AutoApprove:    *autoApprove,
-		Parameters:     parameters,
-	})
+	return agentPath, nil
 }
 
 // stringArray implements flag.Value interface for multiple string flags
@@ -564,25 +734,73 @@ Found:
 
 func handleImportCommand(args []string) error {
 	if len(args) < 1 {
-		fmt.Println("usage: astonish flows import <file.yaml>")
-		fmt.Println("       astonish flows import <file.yaml> [--as <name>]")
+		fmt.Println("usage: astonish flows import <file.yaml|bundle|url>")
+		fmt.Println("       astonish flows import <file.yaml|bundle|url> [--as <name>] [--yes]")
 		return fmt.Errorf("no file specified")
 	}
 
 	sourcePath := args[0]
+	isURL := strings.HasPrefix(sourcePath, "http://") || strings.HasPrefix(sourcePath, "https://")
 
 	// Check if source file exists
-	if _, err := os.Stat(sourcePath); os.IsNotExist(err) {
-		return fmt.Errorf("file not found: %s", sourcePath)
+	if !isURL {
+		if _, err := os.Stat(sourcePath); os.IsNotExist(err) {
+			return fmt.Errorf("file not found: %s", sourcePath)
+		}
+	}
+
+	// Check for the --as and --yes flags
+	var destName string
+	yes := false
+	for i := 1; i < len(args); i++ {
+		switch {
+		case args[i] == "--as" && i+1 < len(args):
+			destName = args[i+1]
+			if !strings.HasSuffix(destName, ".yaml") && !strings.HasSuffix(destName, ".yml") {
+				destName += ".yaml"
+			}
+			i++
+		case args[i] == "--yes" || args[i] == "-y":
+			yes = true
+		}
+	}
+
+	// Read source file
+	data, err := flowstore.ReadBundleSource(sourcePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	// Bundles bring the MCP servers the flow needs
+	if flowstore.IsBundle(data) {
+		bundle, err := flowstore.LoadBundle(data)
+		if err != nil {
+			return err
+		}
+		return importBundle(bundle, destName, yes)
+	}
+
+	sourceName := filepath.Base(sourcePath)
+	if isURL {
+		if u, err := url.Parse(sourcePath); err == nil {
+			sourceName = path.Base(u.Path)
+		}
 	}
 
 	// Validate it's a YAML file
-	if !strings.HasSuffix(strings.ToLower(sourcePath), ".yaml") && !strings.HasSuffix(strings.ToLower(sourcePath), ".yml") {
+	if !strings.HasSuffix(strings.ToLower(sourceName), ".yaml") && !strings.HasSuffix(strings.ToLower(sourceName), ".yml") {
 		return fmt.Errorf("file must be a YAML file (.yaml or .yml)")
 	}
 
+	// The flow is copied alone, so the files it includes are inlined
+	if !isURL {
+		if data, err = config.ExpandIncludes(sourcePath); err != nil {
+			return fmt.Errorf("invalid flow file: %w", err)
+		}
+	}
+
 	// Validate it's a valid flow config
-	if _, err := config.LoadAgent(sourcePath); err != nil {
+	if _, err := config.LoadAgentFromBytes(data); err != nil {
 		return fmt.Errorf("invalid flow file: %w", err)
 	}
 
@@ -598,17 +816,8 @@ func handleImportCommand(args []string) error {
 	}
 
 	// Determine destination name
-	destName := filepath.Base(sourcePath)
-
-	// Check for --as flag
-	for i := 1; i < len(args); i++ {
-		if args[i] == "--as" && i+1 < len(args) {
-			destName = args[i+1]
-			if !strings.HasSuffix(destName, ".yaml") && !strings.HasSuffix(destName, ".yml") {
-				destName += ".yaml"
-			}
-			break
-		}
+	if destName == "" {
+		destName = sourceName
 	}
 
 	destPath := filepath.Join(flowsDir, destName)
@@ -618,12 +827,6 @@ func handleImportCommand(args []string) error {
 		return fmt.Errorf("flow already exists: %s\nUse a different name with --as <name>", destName)
 	}
 
-	// Read source file
-	data, err := os.ReadFile(sourcePath)
-	if err != nil {
-		return fmt.Errorf("failed to read file: %w", err)
-	}
-
 	// Write to destination
 	if err := os.WriteFile(destPath, data, 0644); err != nil {
 		return fmt.Errorf("failed to write file: %w", err)
@@ -885,8 +1088,8 @@ func flowMatchesTags(f flowstore.Flow, tags []string) bool {
 
 func handleStoreInstallCommand(args []string) error {
 	if len(args) < 1 {
-		fmt.Println("usage: astonish flows store install <tap>/<flow>")
-		fmt.Println("       astonish flows store install <flow>  (from official store)")
+		fmt.Println("usage: astonish flows store install <tap>/<flow> [--yes]")
+		fmt.Println("       astonish flows store install <flow> [--yes]  (from official store)")
 		return fmt.Errorf("no flow specified")
 	}
 
@@ -909,10 +1112,55 @@ func handleStoreInstallCommand(args []string) error {
 		runName = tapName + "/" + flowName
 	}
 	fmt.Printf("✓ Installed flow: %s\n", runName)
+
+	// Install the MCP servers the flow depends on
+	yes := slices.Contains(args[1:], "--yes") || slices.Contains(args[1:], "-y")
+	if path, ok := store.GetInstalledFlowPath(tapName, flowName); ok {
+		if err := installFlowDependencies(path, yes); err != nil {
+			return err
+		}
+	}
 	fmt.Printf("  Run with: astonish flows run %s\n", runName)
 	return nil
 }
 
+// installFlowDependencies installs the MCP servers in the mcp_dependencies
+// of the flow at path that are not configured yet, from their inline config
+// or the MCP store and taps, once the user confirms them (or yes is set).
+func installFlowDependencies(path string, yes bool) error {
+	cfg, err := config.LoadAgent(path)
+	if err != nil {
+		return fmt.Errorf("failed to load flow: %w", err)
+	}
+	if len(cfg.MCPDependencies) == 0 {
+		return nil
+	}
+	mcpConfig, err := config.LoadMCPConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load MCP config: %w", err)
+	}
+	storeServers, err := loadStoreServers()
+	if err != nil {
+		return err
+	}
+	install, unresolved := api.PlanMCPDependencyInstall(cfg.MCPDependencies, mcpConfig, storeServers)
+	ok, err := confirmMCPServers(install, yes)
+	if err != nil {
+		return fmt.Errorf("the flow is installed, but its MCP servers were not added: %w", err)
+	}
+	if !ok {
+		fmt.Println("  MCP servers not added; add them later with 'astonish mcp install'")
+		return nil
+	}
+	if err := installMCPServers(install); err != nil {
+		return err
+	}
+	for _, server := range unresolved {
+		fmt.Printf("  MCP server '%s' is needed but not in the store; add it with 'astonish mcp install'\n", server)
+	}
+	return nil
+}
+
 func handleStoreUninstallCommand(args []string) error {
 	if len(args) < 1 {
 		fmt.Println("usage: astonish flows store uninstall <tap>/<flow>")
@@ -1062,7 +1310,7 @@ func handleFlowsRemote(args []string) error {
 			return fmt.Errorf("usage: astonish flows run <name>")
 		}
 		return handleFlowsRunRemote(args[1:])
-	case "show", "edit", "import", "remove", "store":
+	case "show", "info", "graph", "create", "generate", "edit", "import", "export", "remove", "store", "search", "tap":
 		return fmt.Errorf("'flows %s' is not available in remote mode (use Studio UI)", args[0])
 	default:
 		return fmt.Errorf("unknown flows command: %s", args[0])
@@ -1241,6 +1489,7 @@ func handleFlowsRunRemote(args []string) error {
 		// Process SSE events for this turn
 		needsInput := false
 		needsApproval := false
+		var approvalOpts []string
 		flowDone := false
 		agentPrefixPrinted := false
 		inToolBox := false
@@ -1327,7 +1576,7 @@ func handleFlowsRunRemote(args []string) error {
 							needsApproval = true
 							for _, opt := range payload.Options {
 								if s, ok := opt.(string); ok {
-									_ = s // approvalOptions tracked below
+									approvalOpts = append(approvalOpts, s)
 								}
 							}
 						}
@@ -1444,12 +1693,17 @@ func handleFlowsRunRemote(args []string) error {
 				continue
 			}
 			opts := []string{"Yes", "No"}
+			if len(approvalOpts) > 0 {
+				opts = approvalOpts
+			}
 			selection, selErr := ui.ReadSelection(opts, "Approval Required", "")
 			if selErr != nil {
 				return selErr
 			}
 			if selection == "Yes" {
 				fmt.Println(ui.RenderStatusBadge("Command approved", true))
+			} else if agent.ApprovesCall(selection) {
+				fmt.Println(ui.RenderStatusBadge("Command approved, "+strings.ToLower(selection), true))
 			} else {
 				fmt.Println(ui.RenderStatusBadge("Command rejected", false))
 			}
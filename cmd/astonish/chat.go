@@ -185,6 +185,12 @@ func handleChatCommand(args []string) error {
 		return handleChatModelCommand(args[1:])
 	}
 
+	// `astonish chat <flow>` runs a flow as a multi-turn chat; the flags
+	// are those of `flows run`
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") && !client.IsRemoteMode() {
+		return handleRunCommand(append([]string{"--chat"}, args...))
+	}
+
 	// Remote mode: run against the remote server
 	if client.IsRemoteMode() {
 		return handleChatRemote(args)
@@ -434,6 +440,7 @@ func printModelResult(sessionID, pinnedProvider, pinnedModel, effectiveProvider,
 
 func printChatUsage() {
 	fmt.Println("usage: astonish chat [options]")
+	fmt.Println("       astonish chat <flow> [flows run options]")
 	fmt.Println("")
 	fmt.Println("Start an interactive chat session with an AI agent that can use tools.")
 	fmt.Println("The agent dynamically decides how to solve tasks using available tools.")
@@ -468,6 +475,7 @@ func printChatUsage() {
 	fmt.Println("  astonish chat model openai:gpt-4o")
 	fmt.Println("  astonish chat model \"\"                     # clear pin")
 	fmt.Println("  astonish chat model --session <id> anthropic:claude-sonnet-4")
+	fmt.Println("  astonish chat support_bot                   # chat with a flow, turn by turn")
 	fmt.Println("")
 	fmt.Println("In chat mode, the agent has access to all configured tools (internal + MCP)")
 	fmt.Println("and will call them as needed to accomplish your tasks.")
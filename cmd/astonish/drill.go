@@ -486,6 +486,8 @@ var containerToolNames = map[string]bool{
 	"file_tree":                 true,
 	"grep_search":               true,
 	"find_files":                true,
+	"list_directory":            true,
+	"glob":                      true,
 	"process_read":              true,
 	"process_write":             true,
 	"process_list":              true,
@@ -0,0 +1,100 @@
+package astonish
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/SAP/astonish/pkg/artifacts"
+)
+
+// handleArtifactsCommand lists the artifacts a flow run saved, or writes one
+// of them to a file or stdout.
+func handleArtifactsCommand(args []string) error {
+	artCmd := flag.NewFlagSet("artifacts", flag.ExitOnError)
+	output := artCmd.String("output", "", "Write the artifact to this file (default: its name in the current directory; - for stdout)")
+	version := artCmd.Int64("version", 0, "Artifact version (default: latest)")
+	artCmd.Usage = func() {
+		fmt.Println("Usage: astonish flows artifacts <session_id> [artifact_name] [--output <file>] [--version <n>]")
+		artCmd.PrintDefaults()
+	}
+
+	// Allow the positional arguments before or after the flags
+	var positional, flagArgs []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if strings.HasPrefix(arg, "-") && arg != "-" {
+			flagArgs = append(flagArgs, arg)
+			name := strings.TrimLeft(arg, "-")
+			if (name == "output" || name == "version") && i+1 < len(args) {
+				flagArgs = append(flagArgs, args[i+1])
+				i++
+			}
+			continue
+		}
+		positional = append(positional, arg)
+	}
+	if err := artCmd.Parse(flagArgs); err != nil {
+		return err
+	}
+	if len(positional) == 0 || len(positional) > 2 {
+		artCmd.Usage()
+		return fmt.Errorf("expected a session ID and optionally an artifact name")
+	}
+
+	svc, err := artifacts.Default()
+	if err != nil {
+		return err
+	}
+	sessionID := positional[0]
+
+	if len(positional) == 1 {
+		saved, err := svc.SessionArtifacts(sessionID)
+		if err != nil {
+			return err
+		}
+		if len(saved) == 0 {
+			fmt.Printf("No artifacts saved in session %s\n", sessionID)
+			return nil
+		}
+		fmt.Printf("%-32s %-8s %-10s %-24s %s\n", "NAME", "VERSION", "SIZE", "TYPE", "CREATED")
+		for _, info := range saved {
+			fmt.Printf("%-32s %-8d %-10d %-24s %s\n", info.Name, info.Version, info.Size, info.MimeType, info.CreatedAt.Local().Format("2006-01-02 15:04:05"))
+		}
+		return nil
+	}
+
+	info, err := svc.SessionArtifact(sessionID, positional[1], *version)
+	if err != nil {
+		return err
+	}
+	src, err := os.Open(info.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read artifact: %w", err)
+	}
+	defer src.Close()
+
+	if *output == "-" {
+		_, err := io.Copy(os.Stdout, src)
+		return err
+	}
+	path := *output
+	if path == "" {
+		path = info.Name
+	}
+	dst, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	fmt.Printf("✓ Wrote %s (%s, version %d)\n", path, info.MimeType, info.Version)
+	return nil
+}
@@ -0,0 +1,147 @@
+package astonish
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/SAP/astonish/pkg/api"
+	"github.com/SAP/astonish/pkg/cache"
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/flowstore"
+	"github.com/SAP/astonish/pkg/tools"
+	"gopkg.in/yaml.v3"
+)
+
+// handleExportCommand writes a flow as a bundle that 'flows import' installs
+// on another machine, with the MCP servers its tools need.
+func handleExportCommand(args []string) error {
+	exportCmd := flag.NewFlagSet("export", flag.ExitOnError)
+	output := exportCmd.String("o", "", "Bundle file to write, - for stdout (default: <flow>"+flowstore.BundleSuffix+")")
+	exportCmd.Usage = func() {
+		fmt.Println("Usage: astonish flows export <flow_name> [-o <file>]")
+		fmt.Println("")
+		fmt.Println("  The bundle holds the flow, the specs of the MCP servers its tools come from")
+		fmt.Println("  and the docs of its variables. Secrets of the servers are left out.")
+		fmt.Println("")
+		exportCmd.PrintDefaults()
+	}
+	args = parseFlagsAfterArg(exportCmd, args)
+	if len(args) != 1 {
+		exportCmd.Usage()
+		return fmt.Errorf("no flow name provided")
+	}
+
+	flowPath, err := resolveFlowPath(args[0], os.Stderr)
+	if err != nil {
+		return err
+	}
+	mcpConfig, err := config.LoadMCPConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load MCP config: %w", err)
+	}
+	_, _ = cache.LoadCache()
+
+	name := strings.TrimSuffix(filepath.Base(flowPath), filepath.Ext(flowPath))
+	bundle, err := flowstore.ExportBundle(name, flowPath, mcpConfig, cache.GetServerForTool, builtInFlowTools())
+	if err != nil {
+		return err
+	}
+	out, err := yaml.Marshal(bundle)
+	if err != nil {
+		return fmt.Errorf("failed to encode bundle: %w", err)
+	}
+
+	if *output == "-" {
+		_, err := os.Stdout.Write(out)
+		return err
+	}
+	dest := *output
+	if dest == "" {
+		dest = name + flowstore.BundleSuffix
+	}
+	if err := os.WriteFile(dest, out, 0644); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	fmt.Printf("✓ Exported %s to %s (%d MCP servers, %d variables)\n", name, dest, len(bundle.MCPServers), len(bundle.Variables))
+	fmt.Printf("  Install it elsewhere with: astonish flows import %s\n", dest)
+	return nil
+}
+
+// importBundle installs the flow of a bundle as destName, adds the MCP
+// servers it needs that are not configured yet once the user confirms
+// them (or yes is set), asking for their environment, and checks that the
+// flow's tools are available.
+func importBundle(bundle *flowstore.Bundle, destName string, yes bool) error {
+	if destName == "" {
+		destName = bundle.Name + ".yaml"
+	}
+	flowsDir, err := flowstore.GetFlowsDir()
+	if err != nil {
+		return fmt.Errorf("failed to get flows directory: %w", err)
+	}
+	if err := os.MkdirAll(flowsDir, 0755); err != nil {
+		return fmt.Errorf("failed to create flows directory: %w", err)
+	}
+	destPath := filepath.Join(flowsDir, destName)
+	if _, err := os.Stat(destPath); err == nil {
+		return fmt.Errorf("flow already exists: %s\nUse a different name with --as <name>", destName)
+	}
+
+	fullConfig, err := config.LoadMCPConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load MCP config: %w", err)
+	}
+	missingServers := bundle.MissingServers(fullConfig)
+	servers := make([]api.MCPServerInstall, len(missingServers))
+	for i, name := range missingServers {
+		servers[i] = api.MCPServerInstall{Server: name, Config: bundle.MCPServers[name], Source: "bundle"}
+	}
+	if ok, err := confirmMCPServers(servers, yes); err != nil {
+		return err
+	} else if !ok {
+		return fmt.Errorf("import cancelled")
+	}
+
+	if err := os.WriteFile(destPath, []byte(bundle.Flow), 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	flowName := strings.TrimSuffix(destName, filepath.Ext(destName))
+	successStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("42")).Bold(true)
+	fmt.Println(successStyle.Render("✓ Imported flow: " + flowName))
+
+	if err := installMCPServers(servers); err != nil {
+		return err
+	}
+	_, _ = cache.LoadCache()
+
+	builtIn := builtInFlowTools()
+	missing := bundle.MissingTools(func(tool string) bool {
+		return builtIn[tool] || cache.GetServerForTool(tool) != ""
+	})
+	if len(missing) > 0 {
+		warnStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+		fmt.Println(warnStyle.Render("  Tools not available yet: " + strings.Join(missing, ", ")))
+		fmt.Println("  Install their servers with 'astonish mcp install' or fix them with 'astonish tools edit',")
+		fmt.Println("  then run 'astonish tools refresh'.")
+	}
+	if len(bundle.Variables) > 0 {
+		fmt.Printf("  See its variables with: astonish flows info %s\n", flowName)
+	}
+	fmt.Printf("  Run with: astonish flows run %s\n", flowName)
+	return nil
+}
+
+// builtInFlowTools returns the names of the tools flows have without an
+// MCP server.
+func builtInFlowTools() map[string]bool {
+	decls := tools.GetAllFlowToolDeclarations()
+	names := make(map[string]bool, len(decls))
+	for _, decl := range decls {
+		names[decl.Name] = true
+	}
+	return names
+}
@@ -0,0 +1,568 @@
+package astonish
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/SAP/astonish/pkg/api"
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/flowstore"
+	"github.com/SAP/astonish/pkg/tools"
+	"github.com/SAP/astonish/pkg/ui"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	flowNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+	nodeNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// Choices offered by the flow builder wizard.
+const (
+	wizardAddNode      = "Add another node"
+	wizardDoneNodes    = "Done adding nodes"
+	wizardSequential   = "Run the nodes in the order they were added"
+	wizardCustomEdges  = "Choose the next step of each node"
+	wizardBranch       = "Branch on a condition"
+	wizardAddBranch    = "Add another branch"
+	wizardDoneBranches = "Done with this node"
+	wizardOtherTool    = "Other (type the tool name)"
+	wizardDoneTools    = "Done choosing tools"
+	wizardSaveAnyway   = "Save anyway"
+	wizardDiscard      = "Discard"
+)
+
+// builtFlow is the document written by the flow builder wizard.
+type builtFlow struct {
+	Name        string            `yaml:"name"`
+	Description string            `yaml:"description"`
+	Nodes       []config.Node     `yaml:"nodes"`
+	Flow        []config.FlowItem `yaml:"flow"`
+}
+
+// handleCreateCommand creates a new flow. Only the interactive wizard is
+// supported on the command line; Studio covers visual design.
+func handleCreateCommand(args []string) error {
+	createCmd := flag.NewFlagSet("create", flag.ExitOnError)
+	interactive := createCmd.Bool("interactive", false, "Build the flow step by step with prompts")
+	output := createCmd.String("output", "", "Write the flow to this file instead of the flows directory")
+	createCmd.Usage = func() {
+		fmt.Println("Usage: astonish flows create --interactive [--output <file.yaml>] [flow_name]")
+	}
+	if err := createCmd.Parse(args); err != nil {
+		return err
+	}
+	if !*interactive {
+		createCmd.Usage()
+		return fmt.Errorf("use --interactive to build a flow with the wizard, or design it in Studio")
+	}
+
+	b := &flowBuilder{}
+	if err := b.loadTools(); err != nil {
+		return err
+	}
+	return b.run(createCmd.Arg(0), *output)
+}
+
+// flowBuilder walks the user through defining a flow with selection menus
+// and text prompts.
+type flowBuilder struct {
+	flow      builtFlow
+	toolNames []string // selectable tools, sorted
+	extra     []string // tool names typed by the user
+}
+
+// loadTools collects the internal tool names offered in tool menus. MCP
+// tools can still be entered by name.
+func (b *flowBuilder) loadTools() error {
+	internalTools, err := tools.GetInternalTools()
+	if err != nil {
+		return fmt.Errorf("failed to get internal tools: %w", err)
+	}
+	for _, t := range internalTools {
+		b.toolNames = append(b.toolNames, t.Name())
+	}
+	sort.Strings(b.toolNames)
+	return nil
+}
+
+func (b *flowBuilder) run(name, output string) error {
+	var err error
+	if name == "" {
+		name, err = readRequired("Flow name", "Letters, digits, '-' and '_' (e.g. summarize_issues)", func(s string) error {
+			if !flowNamePattern.MatchString(s) {
+				return fmt.Errorf("invalid flow name %q", s)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	} else if !flowNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid flow name %q: use letters, digits, '-' and '_'", name)
+	}
+	b.flow.Name = name
+
+	destPath := output
+	if destPath == "" {
+		flowsDir, err := flowstore.GetFlowsDir()
+		if err != nil {
+			return fmt.Errorf("failed to get flows directory: %w", err)
+		}
+		destPath = filepath.Join(flowsDir, name+".yaml")
+	}
+	if _, err := os.Stat(destPath); err == nil {
+		return fmt.Errorf("flow already exists: %s", destPath)
+	}
+
+	if b.flow.Description, err = readRequired("Description", "What does this flow do?", nil); err != nil {
+		return err
+	}
+
+	for {
+		node, err := b.readNode()
+		if err != nil {
+			return err
+		}
+		b.flow.Nodes = append(b.flow.Nodes, node)
+
+		next, err := ui.ReadSelection([]string{wizardAddNode, wizardDoneNodes}, fmt.Sprintf("%d node(s) defined", len(b.flow.Nodes)), "")
+		if err != nil {
+			return err
+		}
+		if next == wizardDoneNodes {
+			break
+		}
+	}
+
+	mode := wizardSequential
+	if len(b.flow.Nodes) > 1 {
+		if mode, err = ui.ReadSelection([]string{wizardSequential, wizardCustomEdges}, "Flow edges", "How do the nodes connect?"); err != nil {
+			return err
+		}
+	}
+	if mode == wizardSequential {
+		b.flow.Flow = sequentialEdges(b.flow.Nodes)
+	} else if b.flow.Flow, err = b.readEdges(); err != nil {
+		return err
+	}
+
+	data, err := encodeFlow(b.flow)
+	if err != nil {
+		return fmt.Errorf("failed to encode flow: %w", err)
+	}
+
+	if result := api.ValidateFlowYAML(string(data), b.toolInfos()); !result.Valid {
+		fmt.Println("\nThe flow has validation errors:")
+		for i, e := range result.Errors {
+			fmt.Printf("  %d. %s\n", i+1, e)
+		}
+		choice, err := ui.ReadSelection([]string{wizardDiscard, wizardSaveAnyway}, "Save the flow?", "You can fix the errors later with `astonish flows edit`.")
+		if err != nil {
+			return err
+		}
+		if choice == wizardDiscard {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return fmt.Errorf("failed to create flows directory: %w", err)
+	}
+	if err := os.WriteFile(destPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	fmt.Printf("✓ Created flow: %s\n", destPath)
+	if output == "" {
+		fmt.Printf("  Run with: astonish flows run %s\n", name)
+	} else {
+		fmt.Printf("  Run with: astonish flows run %s\n", destPath)
+	}
+	return nil
+}
+
+// readNode asks for the type of the next node and its type-specific fields.
+func (b *flowBuilder) readNode() (config.Node, error) {
+	var node config.Node
+	typ, err := ui.ReadSelection([]string{"llm", "input", "tool", "output"}, "Node type",
+		"llm: ask the model (optionally with tools) · input: ask the user · tool: call one tool directly · output: show a message")
+	if err != nil {
+		return node, err
+	}
+	node.Type = typ
+
+	node.Name, err = readRequired("Node name", "snake_case, unique within the flow", func(s string) error {
+		if !nodeNamePattern.MatchString(s) {
+			return fmt.Errorf("invalid node name %q", s)
+		}
+		for _, n := range b.flow.Nodes {
+			if n.Name == s {
+				return fmt.Errorf("node %q already exists", s)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return node, err
+	}
+
+	switch typ {
+	case "llm":
+		err = b.readLLMNode(&node)
+	case "input":
+		err = readInputNode(&node)
+	case "tool":
+		err = b.readToolNode(&node)
+	case "output":
+		err = readOutputNode(&node)
+	}
+	return node, err
+}
+
+func (b *flowBuilder) readLLMNode(node *config.Node) error {
+	var err error
+	if node.Prompt, err = readRequired("Prompt", "Reference state with {key}, e.g. Summarize {text}", nil); err != nil {
+		return err
+	}
+	if node.System, err = ui.ReadInput("System instruction (optional)", "Leave empty for none"); err != nil {
+		return err
+	}
+
+	for {
+		raw, err := ui.ReadInput("Output model (optional)", "State keys the result is saved to, e.g. summary:str, items:list. Leave empty to stream the answer")
+		if err != nil {
+			return err
+		}
+		if node.OutputModel, err = parseOutputModel(raw); err == nil {
+			break
+		}
+		fmt.Println(err)
+	}
+	if len(node.OutputModel) > 0 {
+		show, err := ui.ReadSelection([]string{"Yes", "No"}, "Show the output to the user?", "")
+		if err != nil {
+			return err
+		}
+		if show == "Yes" {
+			node.UserMessage = sortedKeys(node.OutputModel)
+		}
+	}
+
+	useTools, err := ui.ReadSelection([]string{"No", "Yes"}, "Can the model use tools?", "")
+	if err != nil {
+		return err
+	}
+	if useTools == "Yes" {
+		if node.ToolsSelection, err = b.readToolSelection(); err != nil {
+			return err
+		}
+		node.Tools = true
+	}
+	return nil
+}
+
+func readInputNode(node *config.Node) error {
+	var err error
+	if node.Prompt, err = readRequired("Question", "What to ask the user", nil); err != nil {
+		return err
+	}
+	key, err := readRequired("State key", "The answer is saved to this key", validStateKey)
+	if err != nil {
+		return err
+	}
+	node.OutputModel = map[string]string{key: "str"}
+
+	raw, err := ui.ReadInput("Options (optional)", "Comma-separated choices, e.g. yes, no. Leave empty for free text")
+	if err != nil {
+		return err
+	}
+	node.Options = splitList(raw)
+	return nil
+}
+
+func (b *flowBuilder) readToolNode(node *config.Node) error {
+	name, err := b.readTool("Tool to call", b.toolNames)
+	if err != nil {
+		return err
+	}
+	node.ToolsSelection = []string{name}
+
+	for {
+		raw, err := ui.ReadInput("Argument (optional)", "name=value, e.g. command=ls -la {dir}. Leave empty when done")
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(raw) == "" {
+			break
+		}
+		key, value, err := parseToolArg(raw)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		if node.Args == nil {
+			node.Args = map[string]interface{}{}
+		}
+		node.Args[key] = value
+	}
+
+	key, err := readRequired("State key", "The tool result is saved to this key", validStateKey)
+	if err != nil {
+		return err
+	}
+	node.OutputModel = map[string]string{key: "str"}
+	return nil
+}
+
+func readOutputNode(node *config.Node) error {
+	for {
+		part, err := ui.ReadInput("Message part", "A state key (its value is shown) or literal text. Leave empty when done")
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(part) == "" {
+			if len(node.UserMessage) > 0 {
+				return nil
+			}
+			fmt.Println("An output node needs at least one message part.")
+			continue
+		}
+		node.UserMessage = append(node.UserMessage, part)
+	}
+}
+
+// readToolSelection lets the user pick tools one at a time until done.
+func (b *flowBuilder) readToolSelection() ([]string, error) {
+	var selected []string
+	for {
+		var remaining []string
+		for _, name := range b.toolNames {
+			if !containsString(selected, name) {
+				remaining = append(remaining, name)
+			}
+		}
+		if len(selected) > 0 {
+			remaining = append([]string{wizardDoneTools}, remaining...)
+		}
+		name, err := b.readTool(fmt.Sprintf("Tools (%d selected)", len(selected)), remaining)
+		if err != nil {
+			return nil, err
+		}
+		if name == wizardDoneTools {
+			return selected, nil
+		}
+		selected = append(selected, name)
+	}
+}
+
+// readTool selects one tool from options, or reads a typed name for tools
+// that are not listed (e.g. MCP tools).
+func (b *flowBuilder) readTool(title string, options []string) (string, error) {
+	choice, err := ui.ReadSelection(append(append([]string{}, options...), wizardOtherTool), title, "")
+	if err != nil || choice != wizardOtherTool {
+		return choice, err
+	}
+	name, err := readRequired("Tool name", "As listed by `astonish tools list`", nil)
+	if err != nil {
+		return "", err
+	}
+	if !containsString(b.toolNames, name) && !containsString(b.extra, name) {
+		b.extra = append(b.extra, name)
+	}
+	return name, nil
+}
+
+// readEdges asks for the first node and, for every node, where the flow
+// continues: another node, END or conditional branches.
+func (b *flowBuilder) readEdges() ([]config.FlowItem, error) {
+	names := make([]string, len(b.flow.Nodes))
+	for i, n := range b.flow.Nodes {
+		names[i] = n.Name
+	}
+	targets := append(append([]string{}, names...), "END")
+
+	first, err := ui.ReadSelection(names, "First node", "The node that runs after START")
+	if err != nil {
+		return nil, err
+	}
+	items := []config.FlowItem{{From: "START", To: first}}
+
+	for _, name := range names {
+		next, err := ui.ReadSelection(append(append([]string{}, targets...), wizardBranch), fmt.Sprintf("After %s", name), "")
+		if err != nil {
+			return nil, err
+		}
+		if next != wizardBranch {
+			items = append(items, config.FlowItem{From: name, To: next})
+			continue
+		}
+
+		item := config.FlowItem{From: name}
+		for {
+			cond, err := readRequired("Condition", "Python lambda over state, e.g. lambda x: x['choice'] == 'yes'", func(s string) error {
+				if !strings.HasPrefix(strings.TrimSpace(s), "lambda x:") {
+					return fmt.Errorf("conditions start with 'lambda x:'")
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			to, err := ui.ReadSelection(targets, "Go to", cond)
+			if err != nil {
+				return nil, err
+			}
+			item.Edges = append(item.Edges, config.Edge{To: to, Condition: cond})
+
+			more, err := ui.ReadSelection([]string{wizardAddBranch, wizardDoneBranches}, fmt.Sprintf("%d branch(es) from %s", len(item.Edges), name), "")
+			if err != nil {
+				return nil, err
+			}
+			if more == wizardDoneBranches {
+				break
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (b *flowBuilder) toolInfos() []api.ToolInfo {
+	infos := make([]api.ToolInfo, 0, len(b.toolNames)+len(b.extra))
+	for _, name := range b.toolNames {
+		infos = append(infos, api.ToolInfo{Name: name, Source: "internal"})
+	}
+	for _, name := range b.extra {
+		infos = append(infos, api.ToolInfo{Name: name})
+	}
+	return infos
+}
+
+// encodeFlow renders the flow as YAML with the two-space indentation used by
+// hand-written flows.
+func encodeFlow(flow builtFlow) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(flow); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// sequentialEdges connects START, the nodes in order, and END.
+func sequentialEdges(nodes []config.Node) []config.FlowItem {
+	items := make([]config.FlowItem, 0, len(nodes)+1)
+	prev := "START"
+	for _, n := range nodes {
+		items = append(items, config.FlowItem{From: prev, To: n.Name})
+		prev = n.Name
+	}
+	return append(items, config.FlowItem{From: prev, To: "END"})
+}
+
+// parseOutputModel parses "key:type, key:type" into an output_model. The
+// type defaults to str.
+func parseOutputModel(raw string) (map[string]string, error) {
+	parts := splitList(raw)
+	if len(parts) == 0 {
+		return nil, nil
+	}
+	model := make(map[string]string, len(parts))
+	for _, part := range parts {
+		key, typ, _ := strings.Cut(part, ":")
+		key, typ = strings.TrimSpace(key), strings.TrimSpace(typ)
+		if typ == "" {
+			typ = "str"
+		}
+		if err := validStateKey(key); err != nil {
+			return nil, err
+		}
+		switch typ {
+		case "str", "int", "float", "bool", "list", "dict", "any":
+		default:
+			return nil, fmt.Errorf("unknown type %q for %q (use str, int, float, bool, list or dict)", typ, key)
+		}
+		model[key] = typ
+	}
+	return model, nil
+}
+
+// parseToolArg splits "name=value" into a tool argument.
+func parseToolArg(raw string) (string, string, error) {
+	key, value, ok := strings.Cut(raw, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" {
+		return "", "", fmt.Errorf("invalid argument %q: expected name=value", raw)
+	}
+	return key, strings.TrimSpace(value), nil
+}
+
+func validStateKey(s string) error {
+	if !nodeNamePattern.MatchString(s) {
+		return fmt.Errorf("invalid state key %q: use letters, digits and '_'", s)
+	}
+	return nil
+}
+
+// readRequired prompts until the user enters a non-empty value that passes
+// validate (when given).
+func readRequired(title, description string, validate func(string) error) (string, error) {
+	for {
+		value, err := ui.ReadInput(title, description)
+		if err != nil {
+			return "", err
+		}
+		value = strings.TrimSpace(value)
+		if value == "" {
+			fmt.Printf("%s is required.\n", title)
+			continue
+		}
+		if validate != nil {
+			if err := validate(value); err != nil {
+				fmt.Println(err)
+				continue
+			}
+		}
+		return value, nil
+	}
+}
+
+func splitList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package astonish
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/api"
+	"github.com/SAP/astonish/pkg/config"
+)
+
+func TestParseOutputModel(t *testing.T) {
+	model, err := parseOutputModel("summary, items:list , count: int")
+	if err != nil {
+		t.Fatalf("parseOutputModel: %v", err)
+	}
+	if len(model) != 3 || model["summary"] != "str" || model["items"] != "list" || model["count"] != "int" {
+		t.Errorf("unexpected model %v", model)
+	}
+
+	if model, err := parseOutputModel("  "); err != nil || model != nil {
+		t.Errorf("empty input: got %v, %v", model, err)
+	}
+	for _, raw := range []string{"a:tuple", "bad key:str"} {
+		if _, err := parseOutputModel(raw); err == nil {
+			t.Errorf("parseOutputModel(%q): expected error", raw)
+		}
+	}
+}
+
+func TestParseToolArg(t *testing.T) {
+	key, value, err := parseToolArg("command = ls -la {dir} | grep a=b")
+	if err != nil || key != "command" || value != "ls -la {dir} | grep a=b" {
+		t.Errorf("got %q, %q, %v", key, value, err)
+	}
+	if _, _, err := parseToolArg("no separator"); err == nil {
+		t.Error("expected error for missing '='")
+	}
+}
+
+func TestBuiltFlowValidates(t *testing.T) {
+	nodes := []config.Node{
+		{Name: "get_topic", Type: "input", Prompt: "Topic?", OutputModel: map[string]string{"topic": "str"}},
+		{Name: "research", Type: "llm", Prompt: "Research {topic}", OutputModel: map[string]string{"notes": "str"}, UserMessage: []string{"notes"}, Tools: true, ToolsSelection: []string{"web_search"}},
+	}
+	flow := builtFlow{Name: "research", Description: "Research a topic", Nodes: nodes, Flow: sequentialEdges(nodes)}
+
+	want := "START>get_topic get_topic>research research>END"
+	var got []string
+	for _, item := range flow.Flow {
+		got = append(got, item.From+">"+item.To)
+	}
+	if strings.Join(got, " ") != want {
+		t.Errorf("edges = %v, want %s", got, want)
+	}
+
+	data, err := encodeFlow(flow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := api.ValidateFlowYAML(string(data), []api.ToolInfo{{Name: "web_search"}}); !result.Valid {
+		t.Errorf("generated flow is invalid: %v\n%s", result.Errors, data)
+	}
+	if _, err := config.LoadAgentFromBytes(data); err != nil {
+		t.Errorf("generated flow does not load: %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+package astonish
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/SAP/astonish/pkg/api"
+	"github.com/SAP/astonish/pkg/cache"
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/flowstore"
+	"github.com/SAP/astonish/pkg/provider"
+	"github.com/SAP/astonish/pkg/tools"
+	"gopkg.in/yaml.v3"
+)
+
+var invalidFlowNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// handleGenerateCommand drafts a flow from a natural-language description
+// with the configured LLM, validates it and saves it to the flows directory.
+func handleGenerateCommand(args []string) error {
+	appCfg, err := config.LoadAppConfig()
+	if err != nil {
+		fmt.Printf("Warning: Failed to load config: %v\n", err)
+		appCfg = &config.AppConfig{}
+	}
+
+	genCmd := flag.NewFlagSet("generate", flag.ExitOnError)
+	providerName := genCmd.String("provider", appCfg.General.DefaultProvider, "LLM provider used to draft the flow")
+	modelName := genCmd.String("model", appCfg.General.DefaultModel, "Model name")
+	name := genCmd.String("name", "", "Flow name (default: the name chosen by the model)")
+	output := genCmd.String("output", "", "Write the flow to this file instead of the flows directory ('-' prints it)")
+	genCmd.Usage = func() {
+		fmt.Println("Usage: astonish flows generate [flags] \"<what the flow should do>\"")
+		genCmd.PrintDefaults()
+	}
+	if err := genCmd.Parse(args); err != nil {
+		return err
+	}
+	description := strings.TrimSpace(strings.Join(genCmd.Args(), " "))
+	if description == "" {
+		genCmd.Usage()
+		return fmt.Errorf("no description provided")
+	}
+	if *providerName == "" {
+		*providerName = "gemini"
+	}
+	if *modelName == "" {
+		*modelName = "gemini-2.0-flash"
+	}
+
+	setupProviderCredentials(appCfg)
+	ctx := context.Background()
+	llm, err := provider.GetProvider(ctx, *providerName, *modelName, appCfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize %s provider: %w", *providerName, err)
+	}
+
+	availableTools, err := generateToolInfos()
+	if err != nil {
+		return err
+	}
+
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+	result, err := api.GenerateFlow(ctx, llm, description, availableTools, func(msg string) {
+		fmt.Fprintln(os.Stderr, dimStyle.Render(msg))
+	})
+	if err != nil {
+		return err
+	}
+	if len(result.Errors) > 0 {
+		fmt.Fprintf(os.Stderr, "\nThe last draft is still invalid after %d attempts:\n", result.Attempts)
+		for i, e := range result.Errors {
+			fmt.Fprintf(os.Stderr, "  %d. %s\n", i+1, e)
+		}
+		fmt.Fprintf(os.Stderr, "\n%s\n", result.YAML)
+		return fmt.Errorf("flow not saved: fix the errors above or rephrase the description")
+	}
+
+	data := []byte(result.YAML + "\n")
+	if *output == "-" {
+		fmt.Print(string(data))
+		return nil
+	}
+
+	flowName := *name
+	if flowName == "" {
+		flowName = generatedFlowName(result.YAML)
+	}
+	if !flowNamePattern.MatchString(flowName) {
+		return fmt.Errorf("invalid flow name %q: use letters, digits, '-' and '_'", flowName)
+	}
+	data = []byte(setFlowName(result.YAML, flowName) + "\n")
+
+	destPath := *output
+	if destPath == "" {
+		flowsDir, err := flowstore.GetFlowsDir()
+		if err != nil {
+			return fmt.Errorf("failed to get flows directory: %w", err)
+		}
+		destPath = filepath.Join(flowsDir, flowName+".yaml")
+	}
+	if _, err := os.Stat(destPath); err == nil {
+		return fmt.Errorf("flow already exists: %s\nUse a different name with --name <name>", destPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return fmt.Errorf("failed to create flows directory: %w", err)
+	}
+	if err := os.WriteFile(destPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	ref := flowName
+	if *output != "" {
+		ref = destPath
+	}
+	fmt.Printf("✓ Generated flow: %s\n", destPath)
+	fmt.Printf("  Review with: astonish flows show %s\n", ref)
+	fmt.Printf("  Run with: astonish flows run %s\n", ref)
+	return nil
+}
+
+// generateToolInfos lists the tools a generated flow may use: the internal
+// tools plus the MCP tools in the tools cache (see `astonish tools refresh`).
+func generateToolInfos() ([]api.ToolInfo, error) {
+	internalTools, err := tools.GetInternalTools()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get internal tools: %w", err)
+	}
+	infos := make([]api.ToolInfo, 0, len(internalTools))
+	seen := make(map[string]bool)
+	for _, t := range internalTools {
+		infos = append(infos, api.ToolInfo{Name: t.Name(), Description: t.Description(), Source: "internal"})
+		seen[t.Name()] = true
+	}
+	if _, err := cache.LoadCache(); err == nil {
+		for _, t := range cache.GetAllTools() {
+			if !seen[t.Name] {
+				infos = append(infos, api.ToolInfo{Name: t.Name, Description: t.Description, Source: t.Source})
+				seen[t.Name] = true
+			}
+		}
+	}
+	return infos, nil
+}
+
+// generatedFlowName returns the name the model gave the flow, made safe for
+// a file name.
+func generatedFlowName(flowYAML string) string {
+	var doc struct {
+		Name string `yaml:"name"`
+	}
+	_ = yaml.Unmarshal([]byte(flowYAML), &doc)
+	name := strings.Trim(invalidFlowNameChars.ReplaceAllString(strings.TrimSpace(doc.Name), "_"), "_-")
+	if name == "" {
+		return "generated_flow"
+	}
+	return strings.ToLower(name)
+}
+
+// setFlowName rewrites the top-level name of flowYAML, leaving the rest of
+// the document as the model wrote it.
+func setFlowName(flowYAML, name string) string {
+	lines := strings.Split(flowYAML, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "name:") {
+			lines[i] = "name: " + name
+			return strings.Join(lines, "\n")
+		}
+	}
+	return "name: " + name + "\n" + flowYAML
+}
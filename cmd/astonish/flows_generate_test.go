@@ -0,0 +1,29 @@
+package astonish
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGeneratedFlowName(t *testing.T) {
+	for in, want := range map[string]string{
+		"name: Review PR Risks\nnodes: []":   "review_pr_risks",
+		"description: no name\nnodes: []":    "generated_flow",
+		"name: \"../etc/passwd\"\nnodes: []": "etc_passwd",
+		"not: [valid":                        "generated_flow",
+	} {
+		if got := generatedFlowName(in); got != want {
+			t.Errorf("generatedFlowName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSetFlowName(t *testing.T) {
+	got := setFlowName("name: draft\ndescription: x\nnodes:\n  - name: keep_me", "pr_review")
+	if !strings.HasPrefix(got, "name: pr_review\n") || !strings.Contains(got, "  - name: keep_me") {
+		t.Errorf("unexpected result:\n%s", got)
+	}
+	if got := setFlowName("description: x", "pr_review"); !strings.HasPrefix(got, "name: pr_review\ndescription: x") {
+		t.Errorf("missing name not added:\n%s", got)
+	}
+}
@@ -0,0 +1,97 @@
+package astonish
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/SAP/astonish/pkg/client"
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/flowgraph"
+)
+
+// handleGraphCommand prints a flow's node graph as Mermaid or Graphviz DOT,
+// or renders it to an HTML page opened in the browser.
+func handleGraphCommand(args []string) error {
+	graphCmd := flag.NewFlagSet("graph", flag.ExitOnError)
+	format := graphCmd.String("format", flowgraph.FormatMermaid, "Output format: mermaid or dot")
+	output := graphCmd.String("output", "", "Write the graph to this file instead of stdout")
+	open := graphCmd.Bool("open", false, "Render the graph to an HTML page and open it in the browser")
+	graphCmd.Usage = func() {
+		fmt.Println("Usage: astonish flows graph [--format mermaid|dot] [--output <file>] [--open] <flow_name>")
+		graphCmd.PrintDefaults()
+	}
+
+	// Allow the flow name before or after the flags
+	var flowName string
+	var flagArgs []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case strings.HasPrefix(arg, "-"):
+			flagArgs = append(flagArgs, arg)
+			name := strings.TrimLeft(arg, "-")
+			if (name == "format" || name == "output") && i+1 < len(args) {
+				flagArgs = append(flagArgs, args[i+1])
+				i++
+			}
+		case flowName == "":
+			flowName = arg
+		default:
+			flagArgs = append(flagArgs, arg)
+		}
+	}
+	if err := graphCmd.Parse(flagArgs); err != nil {
+		return err
+	}
+	if flowName == "" {
+		graphCmd.Usage()
+		return fmt.Errorf("no flow name provided")
+	}
+
+	agentPath, err := resolveFlowPath(flowName, os.Stderr)
+	if err != nil {
+		return err
+	}
+	cfg, err := config.LoadAgent(agentPath)
+	if err != nil {
+		return fmt.Errorf("failed to load agent: %w", err)
+	}
+	title := strings.TrimSuffix(filepath.Base(agentPath), filepath.Ext(agentPath))
+
+	if *open {
+		path := *output
+		if path == "" {
+			f, err := os.CreateTemp("", "astonish-graph-"+title+"-*.html")
+			if err != nil {
+				return fmt.Errorf("failed to create HTML file: %w", err)
+			}
+			f.Close()
+			path = f.Name()
+		}
+		if err := os.WriteFile(path, []byte(flowgraph.HTML(title, cfg)), 0644); err != nil {
+			return fmt.Errorf("failed to write HTML file: %w", err)
+		}
+		fmt.Printf("✓ Wrote %s\n", path)
+		if err := client.OpenBrowser("file://" + path); err != nil {
+			fmt.Printf("  Could not open a browser (%v); open the file manually.\n", err)
+		}
+		return nil
+	}
+
+	graph, err := flowgraph.Render(cfg, *format)
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		fmt.Print(graph)
+		return nil
+	}
+	if err := os.WriteFile(*output, []byte(graph), 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	fmt.Printf("✓ Wrote %s\n", *output)
+	return nil
+}
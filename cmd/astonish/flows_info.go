@@ -0,0 +1,84 @@
+package astonish
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/SAP/astonish/pkg/config"
+)
+
+// handleInfoCommand prints a flow's description, the variables it declares
+// (set with `flows run --var`) and its input nodes (set with `-p`).
+func handleInfoCommand(args []string) error {
+	if len(args) < 1 || args[0] == "-h" || args[0] == "--help" {
+		fmt.Println("Usage: astonish flows info <flow_name>")
+		if len(args) < 1 {
+			return fmt.Errorf("no flow name provided")
+		}
+		return nil
+	}
+
+	agentPath, err := resolveFlowPath(args[0], os.Stdout)
+	if err != nil {
+		return err
+	}
+	cfg, err := config.LoadAgent(agentPath)
+	if err != nil {
+		return fmt.Errorf("failed to load agent: %w", err)
+	}
+
+	sectionStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("63")).Bold(true)
+	nameStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Bold(true)
+	descStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("252"))
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+
+	fmt.Println(lipgloss.NewStyle().Bold(true).Render(strings.TrimSuffix(args[0], ".yaml")))
+	if cfg.Description != "" {
+		fmt.Printf("  %s\n", descStyle.Render(cfg.Description))
+	}
+	fmt.Printf("  %s\n", dimStyle.Render(fmt.Sprintf("%s · %d nodes", agentPath, len(cfg.Nodes))))
+
+	fmt.Println(sectionStyle.Render(fmt.Sprintf("\nVariables (%d)", len(cfg.Variables))))
+	if len(cfg.Variables) == 0 {
+		fmt.Println(dimStyle.Render("  none"))
+	}
+	for _, name := range cfg.VariableNames() {
+		v := cfg.Variables[name]
+		typ := v.Type
+		if typ == "" {
+			typ = "str"
+		}
+		detail := typ
+		switch {
+		case v.Default != nil:
+			detail += fmt.Sprintf(", default: %v", v.Default)
+		case v.Required:
+			detail += ", required"
+		}
+		fmt.Printf("  %s %s\n", nameStyle.Render(name), dimStyle.Render("("+detail+")"))
+		if v.Description != "" {
+			fmt.Printf("    %s\n", descStyle.Render(v.Description))
+		}
+	}
+
+	var inputs []config.Node
+	for _, node := range cfg.Nodes {
+		if node.Type == "input" {
+			inputs = append(inputs, node)
+		}
+	}
+	if len(inputs) > 0 {
+		fmt.Println(sectionStyle.Render(fmt.Sprintf("\nInputs (%d)", len(inputs))))
+		for _, node := range inputs {
+			fmt.Printf("  %s\n", nameStyle.Render(node.Name))
+			if node.Prompt != "" {
+				fmt.Printf("    %s\n", descStyle.Render(node.Prompt))
+			}
+		}
+	}
+
+	fmt.Println(dimStyle.Render("\nRun with: astonish flows run " + args[0] + " --var name=value -p input=value"))
+	return nil
+}
@@ -0,0 +1,108 @@
+package astonish
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/flowtest"
+	"github.com/SAP/astonish/pkg/ui"
+	"github.com/charmbracelet/lipgloss"
+	"gopkg.in/yaml.v3"
+)
+
+// handleFlowTestCommand runs the unit tests of a flow (its tests: section,
+// or a separate file) against canned model responses and reports each one.
+func handleFlowTestCommand(args []string) error {
+	testCmd := flag.NewFlagSet("test", flag.ContinueOnError)
+	testsFile := testCmd.String("tests", "", "Read the tests from this YAML file (a tests: list) instead of the flow")
+	only := testCmd.String("run", "", "Run only the test with this name")
+	testCmd.Usage = func() {
+		fmt.Println("Usage: astonish test [--tests file] [--run name] <flow_name>")
+		testCmd.PrintDefaults()
+	}
+
+	// Accept the flow name before or after the flags
+	var flowName string
+	var flagArgs []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--tests" || arg == "-tests" || arg == "--run" || arg == "-run":
+			flagArgs = append(flagArgs, arg)
+			if i+1 < len(args) {
+				i++
+				flagArgs = append(flagArgs, args[i])
+			}
+		case len(arg) > 0 && arg[0] == '-':
+			flagArgs = append(flagArgs, arg)
+		case flowName == "":
+			flowName = arg
+		default:
+			flagArgs = append(flagArgs, arg)
+		}
+	}
+	if err := testCmd.Parse(flagArgs); err != nil {
+		if err == flag.ErrHelp {
+			return nil
+		}
+		return err
+	}
+	if flowName == "" {
+		testCmd.Usage()
+		return fmt.Errorf("no flow name provided")
+	}
+
+	flowPath, err := resolveFlowPath(flowName, os.Stdout)
+	if err != nil {
+		return err
+	}
+	flow, err := config.LoadAgent(flowPath)
+	if err != nil {
+		return fmt.Errorf("failed to load flow: %w", err)
+	}
+	tests := flow.Tests
+	if *testsFile != "" {
+		data, err := os.ReadFile(*testsFile)
+		if err != nil {
+			return fmt.Errorf("failed to read tests: %w", err)
+		}
+		var file config.FlowTestFile
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", *testsFile, err)
+		}
+		tests = file.Tests
+	}
+	if len(tests) == 0 {
+		return fmt.Errorf("flow %s has no tests (add a tests: section or pass --tests)", flowName)
+	}
+
+	results := flowtest.RunAll(context.Background(), flow, tests, *only)
+	if len(results) == 0 {
+		return fmt.Errorf("no test named %q", *only)
+	}
+
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+	failed := 0
+	for _, res := range results {
+		fmt.Println(ui.RenderStatusBadge(res.Name, res.Passed()))
+		if res.Passed() {
+			continue
+		}
+		failed++
+		for _, failure := range res.Failures {
+			fmt.Printf("    %s\n", failure)
+		}
+		if len(res.Path) > 0 {
+			fmt.Printf("    %s\n", dimStyle.Render(fmt.Sprintf("path: %v", res.Path)))
+		}
+	}
+
+	fmt.Printf("\n%d passed, %d failed\n", len(results)-failed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d flow tests failed", failed, len(results))
+	}
+	return nil
+}
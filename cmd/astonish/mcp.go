@@ -0,0 +1,173 @@
+package astonish
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/mcp"
+)
+
+func handleMCPCommand(args []string) error {
+	if len(args) < 1 || args[0] == "-h" || args[0] == "--help" {
+		printMCPUsage()
+		return nil
+	}
+
+	switch args[0] {
+	case "status":
+		return handleMCPStatusCommand(args[1:])
+	case "install":
+		return handleMCPInstallCommand(args[1:])
+	case "login":
+		return handleMCPLoginCommand(args[1:])
+	default:
+		return fmt.Errorf("unknown mcp command: %s", args[0])
+	}
+}
+
+func printMCPUsage() {
+	fmt.Println("usage: astonish mcp [-h] {status,install,login} ...")
+	fmt.Println("")
+	fmt.Println("positional arguments:")
+	fmt.Println("  {status,install,login}")
+	fmt.Println("                        MCP server commands")
+	fmt.Println("    status [name...]    Start the MCP servers and show their health")
+	fmt.Println("    install <id|url>    Install an MCP server from the store or a URL")
+	fmt.Println("    login <name>        Authorize an OAuth MCP server in the browser")
+	fmt.Println("")
+	fmt.Println("options:")
+	fmt.Println("  -h, --help            show this help message and exit")
+}
+
+// handleMCPStatusCommand starts the configured MCP servers (or the named
+// ones), checks each once and prints its health.
+func handleMCPStatusCommand(args []string) error {
+	statusCmd := flag.NewFlagSet("status", flag.ExitOnError)
+	jsonOutput := statusCmd.Bool("json", false, "Output in JSON format")
+	timeout := statusCmd.Duration("timeout", mcp.DefaultHealthConfig.Timeout, "Deadline of each server's check")
+
+	if err := statusCmd.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+
+	mcpConfig, err := config.LoadMCPConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load MCP config: %w", err)
+	}
+	names := statusCmd.Args()
+	if len(names) == 0 {
+		for name := range mcpConfig.MCPServers {
+			names = append(names, name)
+		}
+	}
+
+	var health []mcp.ServerHealth
+	var enabled []string
+	for _, name := range names {
+		cfg, ok := mcpConfig.MCPServers[name]
+		switch {
+		case !ok:
+			return fmt.Errorf("MCP server '%s' not found in config", name)
+		case !cfg.IsEnabled():
+			health = append(health, mcp.ServerHealth{Name: name, Transport: transportName(cfg), Status: "disabled"})
+		default:
+			enabled = append(enabled, name)
+		}
+	}
+
+	if len(enabled) > 0 {
+		ctx := context.Background()
+		mgr := mcp.NewManagerFromConfig(mcpConfig)
+		defer mgr.Cleanup()
+		if err := mgr.InitializeSelectiveToolsets(ctx, enabled); err != nil {
+			return fmt.Errorf("failed to start MCP servers: %w", err)
+		}
+		checkCtx, cancel := context.WithTimeout(ctx, *timeout)
+		checked := mgr.CheckHealth(checkCtx)
+		cancel()
+		started := make(map[string]bool, len(checked))
+		for _, h := range checked {
+			started[h.Name] = true
+		}
+		health = append(health, checked...)
+		for _, name := range enabled {
+			if !started[name] {
+				health = append(health, mcp.ServerHealth{
+					Name:      name,
+					Transport: transportName(mcpConfig.MCPServers[name]),
+					Status:    mcp.HealthUnhealthy,
+					LastError: "failed to start (check the server's command or URL)",
+				})
+			}
+		}
+	}
+	sort.Slice(health, func(i, j int) bool { return health[i].Name < health[j].Name })
+
+	if *jsonOutput {
+		data, err := json.MarshalIndent(health, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal health to JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	headerStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("63")).
+		Bold(true).
+		PaddingBottom(1)
+	healthyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+	unhealthyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	disabledStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	nameStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Bold(true)
+
+	fmt.Println(headerStyle.Render("MCP SERVER HEALTH"))
+
+	if len(health) == 0 {
+		fmt.Println("  No MCP servers configured.")
+		fmt.Println("  Run 'astonish tools store' to browse and install servers.")
+		return nil
+	}
+
+	maxLen := 0
+	for _, h := range health {
+		maxLen = max(maxLen, len(h.Name))
+	}
+	for _, h := range health {
+		padding := strings.Repeat(" ", maxLen-len(h.Name)+1)
+		switch h.Status {
+		case mcp.HealthHealthy:
+			fmt.Printf("  %s %s%s%-6s %s\n", healthyStyle.Render("✓"), nameStyle.Render(h.Name), padding, h.Transport,
+				healthyStyle.Render(fmt.Sprintf("healthy (%d tools, %s)", h.Tools, h.Latency.Round(time.Millisecond))))
+		case "disabled":
+			fmt.Printf("  %s %s%s%-6s %s\n", disabledStyle.Render("-"), disabledStyle.Render(h.Name), padding, h.Transport,
+				disabledStyle.Render("(disabled)"))
+		default:
+			fmt.Printf("  %s %s%s%-6s %s\n", unhealthyStyle.Render("✗"), nameStyle.Render(h.Name), padding, h.Transport,
+				unhealthyStyle.Render(h.Status))
+			if h.LastError != "" {
+				fmt.Printf("      %s\n", disabledStyle.Render(firstLine(h.LastError)))
+			}
+		}
+	}
+	return nil
+}
+
+func transportName(cfg config.MCPServerConfig) string {
+	if cfg.Transport == "" {
+		return "stdio"
+	}
+	return cfg.Transport
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
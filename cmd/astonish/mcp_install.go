@@ -0,0 +1,286 @@
+package astonish
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/SAP/astonish/pkg/api"
+	"github.com/SAP/astonish/pkg/cache"
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/mcp"
+	"github.com/SAP/astonish/pkg/mcpstore"
+	"golang.org/x/term"
+)
+
+// handleMCPInstallCommand installs an MCP server from the store or from a
+// URL, adds it to the MCP config and caches its tools.
+func handleMCPInstallCommand(args []string) error {
+	installCmd := flag.NewFlagSet("install", flag.ExitOnError)
+	name := installCmd.String("name", "", "Name of the server in the MCP config (default: derived from the store entry or page)")
+	transport := installCmd.String("transport", "", "Install the URL itself as a remote server (streamable-http or sse) instead of reading the page")
+	var envs stringArray
+	installCmd.Var(&envs, "env", "Environment variable of the server as KEY=VALUE (repeatable); others are prompted for")
+	installCmd.Usage = func() {
+		fmt.Println("Usage: astonish mcp install <store-id|url> [--name <name>] [--env KEY=VALUE]... [--transport streamable-http|sse]")
+		fmt.Println("")
+		fmt.Println("  <store-id> is an id or name from 'astonish tools store list'. A URL is read with")
+		fmt.Println("  the configured web extract tool to find the server's install command.")
+		fmt.Println("")
+		installCmd.PrintDefaults()
+	}
+	args = parseFlagsAfterArg(installCmd, args)
+	if len(args) != 1 {
+		installCmd.Usage()
+		return fmt.Errorf("expected a store id or URL")
+	}
+
+	given := make(map[string]string)
+	for _, env := range envs {
+		key, value, ok := strings.Cut(env, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("--env must be KEY=VALUE, got %q", env)
+		}
+		given[key] = value
+	}
+
+	ctx := context.Background()
+	displayName, serverCfg, err := resolveMCPInstallTarget(ctx, args[0], *transport)
+	if err != nil {
+		return err
+	}
+	serverKey := *name
+	if serverKey == "" {
+		serverKey = api.SanitizeMCPServerName(displayName)
+	}
+	if serverKey == "" {
+		return fmt.Errorf("cannot derive a server name from %q; pass --name", displayName)
+	}
+
+	// Save to mcp_config.json only, without the standard servers of config.yaml
+	mcpConfig, err := config.LoadMCPConfigRaw()
+	if err != nil {
+		return fmt.Errorf("failed to load MCP config: %w", err)
+	}
+	if _, exists := mcpConfig.MCPServers[serverKey]; exists {
+		return fmt.Errorf("MCP server '%s' is already installed; choose another --name", serverKey)
+	}
+
+	if serverCfg.Env, err = promptServerEnv(displayName, serverCfg.Env, given); err != nil {
+		return err
+	}
+	mcpConfig.MCPServers[serverKey] = serverCfg
+	if err := config.SaveMCPConfig(mcpConfig); err != nil {
+		return fmt.Errorf("failed to save MCP config: %w", err)
+	}
+
+	successStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("42")).Bold(true)
+	fmt.Println(successStyle.Render(fmt.Sprintf("✓ %s installed as '%s'", displayName, serverKey)))
+
+	// Start the new server once so its tools are cached
+	fullConfig, err := config.LoadMCPConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load MCP config: %w", err)
+	}
+	_, failed := mcp.SyncToolsCache(ctx, fullConfig)
+	if err := failed[serverKey]; err != nil {
+		fmt.Printf("  The server did not start: %v\n", err)
+		fmt.Printf("  Fix its config with 'astonish tools edit', then run 'astonish tools refresh'.\n")
+		return nil
+	}
+	fmt.Printf("  %d tools cached. Run 'astonish tools list' to see them.\n", len(cache.GetToolsForServer(serverKey)))
+	return nil
+}
+
+// resolveMCPInstallTarget returns the name and config of the server a store
+// id or URL refers to.
+func resolveMCPInstallTarget(ctx context.Context, target, transport string) (string, config.MCPServerConfig, error) {
+	if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
+		if transport != "" {
+			if transport != "streamable-http" && transport != "sse" {
+				return "", config.MCPServerConfig{}, fmt.Errorf("unsupported transport %q (want streamable-http or sse)", transport)
+			}
+			u, err := url.Parse(target)
+			if err != nil {
+				return "", config.MCPServerConfig{}, fmt.Errorf("invalid URL: %w", err)
+			}
+			return u.Hostname(), config.MCPServerConfig{Transport: transport, URL: target}, nil
+		}
+
+		appCfg, err := config.LoadAppConfig()
+		if err != nil {
+			return "", config.MCPServerConfig{}, fmt.Errorf("failed to load config: %w", err)
+		}
+		fmt.Printf("Reading %s...\n", target)
+		found, err := api.ExtractMCPServerFromURL(ctx, target, appCfg)
+		if err != nil {
+			return "", config.MCPServerConfig{}, err
+		}
+		if found == nil || found.Command == "" {
+			return "", config.MCPServerConfig{}, fmt.Errorf("no MCP server install command found at %s (pass --transport for a remote server URL)", target)
+		}
+		return found.Name, config.MCPServerConfig{
+			Command:   found.Command,
+			Args:      found.Args,
+			Env:       found.EnvVars,
+			Transport: "stdio",
+		}, nil
+	}
+
+	if transport != "" {
+		return "", config.MCPServerConfig{}, fmt.Errorf("--transport needs a URL")
+	}
+	servers, err := loadStoreServers()
+	if err != nil {
+		return "", config.MCPServerConfig{}, err
+	}
+	srv := mcpstore.GetServer(servers, target)
+	if srv == nil {
+		srv = mcpstore.GetServerByName(servers, target)
+	}
+	if srv == nil || srv.Config == nil {
+		return "", config.MCPServerConfig{}, fmt.Errorf("MCP server '%s' not found in the store (see 'astonish tools store list')", target)
+	}
+	return srv.Name, config.MCPServerConfig{
+		Command:   srv.Config.Command,
+		Args:      srv.Config.Args,
+		Env:       srv.Config.Env,
+		Transport: srv.Config.Transport,
+		URL:       srv.Config.URL,
+	}, nil
+}
+
+// promptServerEnv returns the server's environment: values in given are
+// used as is, the other variables are prompted for with their defaults.
+func promptServerEnv(serverName string, defaults, given map[string]string) (map[string]string, error) {
+	env := make(map[string]string, len(defaults)+len(given))
+	var keys []string
+	for key, value := range defaults {
+		if _, ok := given[key]; !ok {
+			env[key] = value
+			keys = append(keys, key)
+		}
+	}
+	for key, value := range given {
+		env[key] = value
+	}
+	if len(keys) == 0 {
+		if len(env) == 0 {
+			return nil, nil
+		}
+		return env, nil
+	}
+
+	sort.Strings(keys)
+	values := make([]string, len(keys))
+	fields := make([]huh.Field, len(keys))
+	for i, key := range keys {
+		values[i] = env[key]
+		fields[i] = huh.NewInput().
+			Title(key).
+			Description(fmt.Sprintf("Environment variable for %s", serverName)).
+			Value(&values[i])
+	}
+	if err := huh.NewForm(huh.NewGroup(fields...).Title(fmt.Sprintf("Configure %s", serverName))).Run(); err != nil {
+		return nil, fmt.Errorf("configuration cancelled (set variables with --env KEY=VALUE): %w", err)
+	}
+	for i, key := range keys {
+		env[key] = values[i]
+	}
+	return env, nil
+}
+
+// stdinIsTerminal reports whether the user can answer prompts. A variable
+// so tests can stand in for a terminal.
+var stdinIsTerminal = func() bool { return term.IsTerminal(int(os.Stdin.Fd())) }
+
+// confirmMCPServer asks the user to confirm a prompt; a variable so tests
+// can answer it.
+var confirmMCPServer = func(title string) (bool, error) {
+	var confirm bool
+	err := huh.NewForm(huh.NewGroup(huh.NewConfirm().Title(title).Value(&confirm))).Run()
+	return confirm, err
+}
+
+// confirmMCPServers shows the servers a flow from elsewhere wants to add,
+// whose commands run on this machine, and asks to go on. Without a
+// terminal the servers are refused unless yes (--yes) is set.
+func confirmMCPServers(servers []api.MCPServerInstall, yes bool) (bool, error) {
+	if len(servers) == 0 {
+		return true, nil
+	}
+	fmt.Println("  The flow adds these MCP servers, which run on this machine:")
+	for _, srv := range servers {
+		switch srv.Source {
+		case "inline":
+			fmt.Printf("    %s (spec written in the flow)\n", srv.Server)
+		case "bundle":
+			fmt.Printf("    %s (spec from the bundle)\n", srv.Server)
+		case "", "official":
+			fmt.Printf("    %s (spec from the curated MCP store)\n", srv.Server)
+		default:
+			fmt.Printf("    %s (spec from tap %s)\n", srv.Server, srv.Source)
+		}
+		if srv.Config.Command != "" {
+			fmt.Printf("      command: %s\n", srv.Config.Command)
+		}
+		if len(srv.Config.Args) > 0 {
+			fmt.Printf("      args:    %q\n", srv.Config.Args)
+		}
+		if srv.Config.URL != "" {
+			fmt.Printf("      url:     %s\n", srv.Config.URL)
+		}
+	}
+	if yes {
+		return true, nil
+	}
+	if !stdinIsTerminal() {
+		return false, fmt.Errorf("refusing to add MCP servers without confirmation; review them and pass --yes")
+	}
+	return confirmMCPServer("Add and start these MCP servers?")
+}
+
+// installMCPServers adds servers a flow needs to the MCP config, asking for
+// their environment, and starts them once so their tools are cached.
+// Servers that do not start are reported, not failed.
+func installMCPServers(servers []api.MCPServerInstall) error {
+	if len(servers) == 0 {
+		return nil
+	}
+	// Save to mcp_config.json only, without the standard servers of config.yaml
+	mcpConfig, err := config.LoadMCPConfigRaw()
+	if err != nil {
+		return fmt.Errorf("failed to load MCP config: %w", err)
+	}
+	for _, srv := range servers {
+		spec := srv.Config
+		if spec.Env, err = promptServerEnv(srv.Server, spec.Env, nil); err != nil {
+			return err
+		}
+		mcpConfig.MCPServers[srv.Server] = spec
+	}
+	if err := config.SaveMCPConfig(mcpConfig); err != nil {
+		return fmt.Errorf("failed to save MCP config: %w", err)
+	}
+
+	fullConfig, err := config.LoadMCPConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load MCP config: %w", err)
+	}
+	_, failed := mcp.SyncToolsCache(context.Background(), fullConfig)
+	warnStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+	for _, srv := range servers {
+		if err := failed[srv.Server]; err != nil {
+			fmt.Println(warnStyle.Render(fmt.Sprintf("  MCP server '%s' did not start: %v", srv.Server, err)))
+			continue
+		}
+		fmt.Printf("  Added MCP server '%s' (%d tools)\n", srv.Server, len(cache.GetToolsForServer(srv.Server)))
+	}
+	return nil
+}
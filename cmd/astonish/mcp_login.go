@@ -0,0 +1,78 @@
+package astonish
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/mcp"
+)
+
+// handleMCPLoginCommand authorizes a server with auth type oauth through
+// the device flow and caches its tools with the new token.
+func handleMCPLoginCommand(args []string) error {
+	loginCmd := flag.NewFlagSet("login", flag.ExitOnError)
+	loginCmd.Usage = func() {
+		fmt.Println("Usage: astonish mcp login <name>")
+		fmt.Println("")
+		fmt.Println("  The server needs an auth section of type oauth with client_id,")
+		fmt.Println("  device_auth_url and token_url in mcp_config.json.")
+	}
+	if err := loginCmd.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	if loginCmd.NArg() != 1 {
+		loginCmd.Usage()
+		return fmt.Errorf("expected a server name")
+	}
+	name := loginCmd.Arg(0)
+
+	mcpConfig, err := config.LoadMCPConfigRaw()
+	if err != nil {
+		return fmt.Errorf("failed to load MCP config: %w", err)
+	}
+	serverCfg, ok := mcpConfig.MCPServers[name]
+	if !ok {
+		return fmt.Errorf("MCP server '%s' not found in config", name)
+	}
+	if serverCfg.Auth == nil || !strings.EqualFold(serverCfg.Auth.Type, "oauth") {
+		return fmt.Errorf("MCP server '%s' does not use oauth auth", name)
+	}
+
+	// Record where the tokens are kept so the server finds them at startup
+	if serverCfg.Auth.Credential == "" {
+		serverCfg.Auth.Credential = mcp.OAuthCredentialName(name, serverCfg.Auth)
+		mcpConfig.MCPServers[name] = serverCfg
+		if err := config.SaveMCPConfig(mcpConfig); err != nil {
+			return fmt.Errorf("failed to save MCP config: %w", err)
+		}
+	}
+
+	ctx := context.Background()
+	err = mcp.Login(ctx, serverCfg.Auth, func(code *mcp.DeviceCode) {
+		codeStyle := lipgloss.NewStyle().Bold(true)
+		fmt.Printf("Open %s and enter the code %s\n", code.VerificationURI, codeStyle.Render(code.UserCode))
+		if code.VerificationURIComplete != "" {
+			fmt.Printf("  or open %s\n", code.VerificationURIComplete)
+		}
+		fmt.Println("Waiting for approval...")
+	})
+	if err != nil {
+		return fmt.Errorf("login failed: %w", err)
+	}
+
+	successStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("42")).Bold(true)
+	fmt.Println(successStyle.Render(fmt.Sprintf("✓ Logged in to '%s' (credential %s)", name, serverCfg.Auth.Credential)))
+
+	fullConfig, err := config.LoadMCPConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load MCP config: %w", err)
+	}
+	if _, failed := mcp.SyncToolsCache(ctx, fullConfig); failed[name] != nil {
+		fmt.Printf("  The server did not start: %v\n", failed[name])
+	}
+	return nil
+}
@@ -33,8 +33,9 @@ func Execute() error {
 
 	// Check for updates — skip for non-interactive / structured-stdout
 	// subcommands where stdout is a protocol channel (e.g. "node" emits
-	// NDJSON) or already handles its own output ("version").
-	if os.Args[1] != "version" && os.Args[1] != "node" {
+	// NDJSON, "serve-mcp" speaks MCP, "schema" prints JSON) or already
+	// handles its own output ("version").
+	if os.Args[1] != "version" && os.Args[1] != "node" && os.Args[1] != "serve-mcp" && os.Args[1] != "schema" {
 		checkForUpdates()
 	}
 
@@ -64,8 +65,16 @@ func Execute() error {
 		return handleSetupCommand()
 	case "config":
 		return handleConfigCommand(os.Args[2:])
+	case "schema":
+		return handleSchemaCommand(os.Args[2:])
 	case "tools":
 		return handleToolsCommand(os.Args[2:])
+	case "mcp":
+		mustNotBeRemote("mcp")
+		return handleMCPCommand(os.Args[2:])
+	case "serve-mcp":
+		mustNotBeRemote("serve-mcp")
+		return handleServeMCPCommand(os.Args[2:])
 	case "memory":
 		mustNotBeRemote("memory")
 		return handleMemoryCommand(os.Args[2:])
@@ -75,7 +84,7 @@ func Execute() error {
 	case "channels":
 		mustNotBeRemote("channels")
 		return handleChannelsCommand(os.Args[2:])
-	case "scheduler":
+	case "scheduler", "schedule":
 		return handleSchedulerCommand(os.Args[2:])
 	case "fleet":
 		return handleFleetCommand(os.Args[2:])
@@ -85,9 +94,15 @@ func Execute() error {
 	case "skills":
 		mustBeRemote("skills")
 		return handleSkillsCommand(os.Args[2:])
-	case "drill", "test":
+	case "drill":
 		mustBeRemote("drill")
 		return handleDrillCommand(os.Args[2:])
+	case "test":
+		// Connected to a server, "test" stays the alias of drill
+		if client.IsRemoteMode() {
+			return handleDrillCommand(os.Args[2:])
+		}
+		return handleFlowTestCommand(os.Args[2:])
 	case "sandbox":
 		mustNotBeRemote("sandbox")
 		return handleSandboxCommand(os.Args[2:])
@@ -108,7 +123,7 @@ func printUsage() {
 	fmt.Println("usage: astonish [-h] [-v] {login,logout,status,org,team,chat,sessions,flows,...} ...")
 	fmt.Println("")
 	fmt.Println("positional arguments:")
-	fmt.Println("  {chat,sessions,flows,tap,daemon,channels,scheduler,fleet,credential,skills,sandbox,drill,config,setup,tools,memory,platform}")
+	fmt.Println("  {chat,sessions,flows,tap,daemon,channels,scheduler,fleet,credential,skills,sandbox,drill,test,config,schema,setup,tools,mcp,serve-mcp,memory,platform}")
 	fmt.Println("                        Astonish CLI commands")
 	fmt.Println("    login               Connect to a remote Astonish server")
 	fmt.Println("    logout              Disconnect from the remote server")
@@ -127,9 +142,13 @@ func printUsage() {
 	fmt.Println("    skills              Manage CLI tool skill guides")
 	fmt.Println("    sandbox             Manage session container isolation")
 	fmt.Println("    drill               Run deterministic drill suites")
+	fmt.Println("    test                Run a flow's unit tests with canned model responses")
 	fmt.Println("    config              Manage configuration")
+	fmt.Println("    schema              Print the JSON Schema of flow files")
 	fmt.Println("    setup               Run interactive setup")
 	fmt.Println("    tools               Manage MCP tools")
+	fmt.Println("    mcp                 Check the health of MCP servers")
+	fmt.Println("    serve-mcp           Expose a flow as an MCP tool over stdio")
 	fmt.Println("    memory              Manage semantic memory and knowledge")
 	fmt.Println("    platform            Manage the multi-tenant platform")
 	fmt.Println("")
@@ -20,6 +20,8 @@ func handleSchedulerCommand(args []string) error {
 	}
 
 	switch args[0] {
+	case "add":
+		return handleSchedulerAdd(args[1:])
 	case "list", "ls":
 		return handleSchedulerList()
 	case "enable":
@@ -51,11 +53,12 @@ func handleSchedulerCommand(args []string) error {
 }
 
 func printSchedulerUsage() {
-	fmt.Println("usage: astonish scheduler {list,enable,disable,remove,run,status}")
+	fmt.Println("usage: astonish scheduler {add,list,enable,disable,remove,run,status}")
 	fmt.Println("")
-	fmt.Println("Manage scheduled jobs. Jobs are created through chat (talk to the AI).")
+	fmt.Println("Manage scheduled jobs. Jobs are created through chat (talk to the AI) or with 'add'.")
 	fmt.Println("")
 	fmt.Println("subcommands:")
+	fmt.Println("  add --cron <expr> <flow>  Schedule a flow (see 'astonish scheduler add -h')")
 	fmt.Println("  list (ls)            List all scheduled jobs")
 	fmt.Println("  enable <name>        Enable a scheduled job")
 	fmt.Println("  disable <name>       Disable a scheduled job")
@@ -0,0 +1,123 @@
+package astonish
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/SAP/astonish/pkg/scheduler"
+)
+
+// handleSchedulerAdd creates a routine job that runs a flow on a cron
+// schedule through the daemon API.
+func handleSchedulerAdd(args []string) error {
+	addCmd := flag.NewFlagSet("add", flag.ExitOnError)
+	cron := addCmd.String("cron", "", "Schedule as a 5-field cron expression, e.g. \"0 9 * * 1-5\"")
+	timezone := addCmd.String("tz", "", "IANA timezone of the schedule (default: the daemon's local time)")
+	name := addCmd.String("name", "", "Job name (default: the flow name)")
+	disabled := addCmd.Bool("disabled", false, "Create the job disabled")
+	var params stringArray
+	addCmd.Var(&params, "p", "Flow parameter in key=value format for input nodes (can be used multiple times)")
+	addCmd.Usage = func() {
+		fmt.Println("Usage: astonish scheduler add --cron <expr> [--tz <zone>] [--name <name>] [-p key=value]... <flow>")
+		addCmd.PrintDefaults()
+	}
+
+	// Allow the flow name before or after the flags
+	var flowName string
+	var flagArgs []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case strings.HasPrefix(arg, "-"):
+			flagArgs = append(flagArgs, arg)
+			switch strings.TrimLeft(arg, "-") {
+			case "cron", "tz", "name", "p":
+				if i+1 < len(args) {
+					flagArgs = append(flagArgs, args[i+1])
+					i++
+				}
+			}
+		case flowName == "":
+			flowName = arg
+		default:
+			flagArgs = append(flagArgs, arg)
+		}
+	}
+	if err := addCmd.Parse(flagArgs); err != nil {
+		return err
+	}
+	if flowName == "" || *cron == "" {
+		addCmd.Usage()
+		return fmt.Errorf("a flow name and --cron are required")
+	}
+	if err := scheduler.ValidateCron(*cron); err != nil {
+		return fmt.Errorf("invalid cron expression %q: %w", *cron, err)
+	}
+	if *timezone != "" {
+		if _, err := time.LoadLocation(*timezone); err != nil {
+			return fmt.Errorf("invalid timezone %q: %w", *timezone, err)
+		}
+	}
+
+	parameters := make(map[string]string)
+	for _, p := range params {
+		key, value, ok := strings.Cut(p, "=")
+		if !ok {
+			return fmt.Errorf("malformed parameter %q (expected key=value)", p)
+		}
+		parameters[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	if *name == "" {
+		*name = flowName
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"name":     *name,
+		"mode":     string(scheduler.ModeRoutine),
+		"cron":     *cron,
+		"timezone": *timezone,
+		"flow":     flowName,
+		"params":   parameters,
+		"enabled":  !*disabled,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	req, err := newAPIRequest(http.MethodPost, getDaemonBaseURL()+"/api/scheduler/jobs", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to contact daemon (is it running?): %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("daemon returned HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	var job schedulerJobAPI
+	if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+		return fmt.Errorf("invalid response from daemon")
+	}
+
+	fmt.Printf("Job %q scheduled (%s", job.Name, job.Schedule.Cron)
+	if job.Schedule.Timezone != "" {
+		fmt.Printf(" %s", job.Schedule.Timezone)
+	}
+	fmt.Println(")")
+	if job.NextRun != nil {
+		fmt.Printf("  Next run: %s\n", *job.NextRun)
+	} else if !job.Enabled {
+		fmt.Printf("  Enable with: astonish scheduler enable %s\n", job.Name)
+	}
+	return nil
+}
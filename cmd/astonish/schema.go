@@ -0,0 +1,78 @@
+package astonish
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/SAP/astonish/pkg/config"
+)
+
+// handleSchemaCommand prints the JSON Schema of flow files, for editors to
+// validate and complete them, or checks a flow against it.
+func handleSchemaCommand(args []string) error {
+	if len(args) > 0 && args[0] == "validate" {
+		return handleSchemaValidateCommand(args[1:])
+	}
+
+	schemaCmd := flag.NewFlagSet("schema", flag.ExitOnError)
+	output := schemaCmd.String("output", "", "Write the schema to this file instead of stdout")
+	schemaCmd.Usage = func() {
+		fmt.Println("Usage: astonish schema [--output <file>]")
+		fmt.Println("       astonish schema validate <flow_name|file>")
+		schemaCmd.PrintDefaults()
+	}
+	if err := schemaCmd.Parse(args); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(config.FlowJSONSchema(), "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if *output == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(*output, data, 0644); err != nil {
+		return fmt.Errorf("failed to write schema: %w", err)
+	}
+	fmt.Printf("✓ Wrote the flow schema to %s\n", *output)
+	return nil
+}
+
+// handleSchemaValidateCommand lists the problems of a flow with their
+// positions, including the unknown fields a flow still loads with.
+func handleSchemaValidateCommand(args []string) error {
+	if len(args) != 1 {
+		fmt.Println("Usage: astonish schema validate <flow_name|file>")
+		return fmt.Errorf("no flow specified")
+	}
+	agentPath, err := resolveFlowPath(args[0], os.Stderr)
+	if err != nil {
+		return err
+	}
+	diags, err := config.ValidateFlowFile(agentPath)
+	if err != nil {
+		return err
+	}
+
+	errCount := 0
+	for _, d := range diags {
+		if d.Unknown {
+			fmt.Printf("Warning: %s\n", d)
+		} else {
+			fmt.Printf("Error: %s\n", d)
+			errCount++
+		}
+	}
+	if errCount > 0 {
+		return fmt.Errorf("%d schema error(s) in %s", errCount, agentPath)
+	}
+	if len(diags) == 0 {
+		fmt.Printf("✓ %s matches the flow schema\n", agentPath)
+	}
+	return nil
+}
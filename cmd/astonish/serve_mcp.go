@@ -0,0 +1,99 @@
+package astonish
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/launcher"
+)
+
+// handleServeMCPCommand exposes a flow as an MCP tool over stdio, so MCP
+// clients (IDEs, desktop assistants) can call it like any other tool.
+// stdout carries the protocol; everything else goes to stderr.
+func handleServeMCPCommand(args []string) error {
+	if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+		printServeMCPUsage()
+		return nil
+	}
+
+	appCfg, err := config.LoadAppConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to load config: %v\n", err)
+		appCfg = &config.AppConfig{}
+	}
+
+	// The flow name may come before or after the flags
+	var flowName string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		flowName, args = args[0], args[1:]
+	}
+	fs := flag.NewFlagSet("serve-mcp", flag.ExitOnError)
+	fs.SetOutput(os.Stderr)
+	providerName := fs.String("provider", appCfg.General.DefaultProvider, "LLM provider (gemini, openai, sap_ai_core)")
+	modelName := fs.String("model", appCfg.General.DefaultModel, "Model name")
+	debugMode := fs.Bool("debug", false, "Enable debug logging (to stderr)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if flowName == "" {
+		flowName = fs.Arg(0)
+	}
+	if flowName == "" {
+		printServeMCPUsage()
+		return fmt.Errorf("no flow name provided")
+	}
+	if *providerName == "" {
+		*providerName = "gemini"
+	}
+
+	flowPath, err := resolveFlowPath(flowName, os.Stderr)
+	if err != nil {
+		return err
+	}
+	cfg, err := config.LoadAgent(flowPath)
+	if err != nil {
+		return fmt.Errorf("failed to load flow: %w", err)
+	}
+
+	// Same environment as `flows run`: MCP server env vars are visible to
+	// internal tools (e.g. GITHUB_HOST)
+	if mcpCfg, err := config.LoadMCPConfig(); err == nil {
+		for _, server := range mcpCfg.MCPServers {
+			for k, v := range server.Env {
+				if v != "" {
+					os.Setenv(k, v)
+				}
+			}
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Fprintf(os.Stderr, "Serving flow %q as MCP tool %q on stdio\n", flowName, launcher.FlowToolName(flowName))
+	return launcher.RunFlowMCPServer(ctx, &launcher.FlowMCPServerConfig{
+		FlowName:     flowName,
+		AgentConfig:  cfg,
+		AppConfig:    appCfg,
+		ProviderName: *providerName,
+		ModelName:    *modelName,
+		DebugMode:    *debugMode,
+	})
+}
+
+func printServeMCPUsage() {
+	fmt.Println("usage: astonish serve-mcp <flow> [--provider P] [--model M] [--debug]")
+	fmt.Println("")
+	fmt.Println("Expose a flow as an MCP tool over stdio. The flow's input nodes become")
+	fmt.Println("the tool's parameters; each call runs the flow headlessly with tools")
+	fmt.Println("auto-approved and returns its output and declared results.")
+	fmt.Println("")
+	fmt.Println("Example MCP client configuration:")
+	fmt.Println(`  {"command": "astonish", "args": ["serve-mcp", "pr_review"]}`)
+}
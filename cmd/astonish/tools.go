@@ -13,31 +13,14 @@ import (
 	"github.com/charmbracelet/huh"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/SAP/astonish/pkg/cache"
-	"github.com/SAP/astonish/pkg/common"
 	"github.com/SAP/astonish/pkg/config"
 	"github.com/SAP/astonish/pkg/flowstore"
 	"github.com/SAP/astonish/pkg/mcp"
 	"github.com/SAP/astonish/pkg/mcpstore"
 	"github.com/SAP/astonish/pkg/tools"
-	"google.golang.org/adk/session"
 	"google.golang.org/adk/tool"
-	"google.golang.org/genai"
 )
 
-// minimalReadonlyContext implements agent.ReadonlyContext for tool listing
-type minimalReadonlyContext struct {
-	context.Context
-}
-
-func (m *minimalReadonlyContext) AgentName() string                    { return "tools-list" }
-func (m *minimalReadonlyContext) AppName() string                      { return "astonish" }
-func (m *minimalReadonlyContext) UserContent() *genai.Content          { return nil }
-func (m *minimalReadonlyContext) InvocationID() string                 { return "" }
-func (m *minimalReadonlyContext) ReadonlyState() session.ReadonlyState { return nil }
-func (m *minimalReadonlyContext) UserID() string                       { return "" }
-func (m *minimalReadonlyContext) SessionID() string                    { return "" }
-func (m *minimalReadonlyContext) Branch() string                       { return "" }
-
 func handleToolsCommand(args []string) error {
 	if len(args) < 1 || args[0] == "-h" || args[0] == "--help" {
 		printToolsUsage()
@@ -47,6 +30,8 @@ func handleToolsCommand(args []string) error {
 	switch args[0] {
 	case "list":
 		return handleToolsListCommand(args[1:])
+	case "run":
+		return handleToolsRunCommand(args[1:])
 	case "search":
 		return handleToolsSearchCommand(args[1:])
 	case "edit":
@@ -67,12 +52,13 @@ func handleToolsCommand(args []string) error {
 }
 
 func printToolsUsage() {
-	fmt.Println("usage: astonish tools [-h] {list,search,edit,store,servers,enable,disable,refresh} ...")
+	fmt.Println("usage: astonish tools [-h] {list,run,search,edit,store,servers,enable,disable,refresh} ...")
 	fmt.Println("")
 	fmt.Println("positional arguments:")
-	fmt.Println("  {list,search,edit,store,servers,enable,disable,refresh}")
+	fmt.Println("  {list,run,search,edit,store,servers,enable,disable,refresh}")
 	fmt.Println("                        Tools management commands")
 	fmt.Println("    list                List available tools (internal + MCP)")
+	fmt.Println("    run <tool>          Run a single tool with --args '{...}' (asks for approval unless --yes)")
 	fmt.Println("    search <query>      Semantic search across the tool index (use '*' to list all)")
 	fmt.Println("    edit                Edit MCP configuration")
 	fmt.Println("    store               Browse and install MCP servers from the store")
@@ -259,60 +245,55 @@ func handleToolsListCommand(args []string) error {
 		Source      string `json:"source"`
 	}
 
-	ctx := context.Background()
-
 	// Get internal tools
 	internalTools, err := tools.GetInternalTools()
 	if err != nil {
 		return fmt.Errorf("failed to get internal tools: %w", err)
 	}
 
-	// Get MCP servers and their tools
-	mcpManager, err := mcp.NewManager()
+	// MCP tools come from the persistent tools cache. The daemon keeps it
+	// warm; servers that are new or changed since are refreshed first.
 	type MCPServerInfo struct {
 		Name  string
 		Tools []ToolInfo
 	}
 	var mcpServers []MCPServerInfo
 
-	if err == nil {
-		if err := mcpManager.InitializeToolsets(ctx); err == nil {
-			// Get named toolsets with their server names
-			namedToolsets := mcpManager.GetNamedToolsets()
-
-			// Create minimal context for fetching tools
-			minimalCtx := &minimalReadonlyContext{Context: ctx}
-
-			// Iterate toolsets with their proper names
-			for _, namedToolset := range namedToolsets {
-				serverInfo := MCPServerInfo{
-					Name:  namedToolset.Name,
-					Tools: []ToolInfo{},
-				}
+	if mcpCfg, err := config.LoadMCPConfig(); err == nil {
+		refreshed, failed := mcp.SyncToolsCache(context.Background(), mcpCfg)
+		if len(refreshed) > 0 && !*jsonOutput {
+			fmt.Fprintf(os.Stderr, "Refreshed tools cache for %s\n", strings.Join(refreshed, ", "))
+		}
+		for serverName, err := range failed {
+			slog.Warn("failed to refresh server", "component", "cache", "server", serverName, "error", err)
+		}
 
-				// Fetch tools from this toolset
-				mcpTools, err := namedToolset.Toolset.Tools(minimalCtx)
-				if err == nil {
-					for _, tool := range mcpTools {
-						serverInfo.Tools = append(serverInfo.Tools, ToolInfo{
-							Name:        tool.Name(),
-							Description: tool.Description(),
-							Source:      namedToolset.Name,
-						})
-					}
-				}
-				// Sort tools within server
-				sort.Slice(serverInfo.Tools, func(i, j int) bool {
-					return serverInfo.Tools[i].Name < serverInfo.Tools[j].Name
+		for serverName, serverCfg := range mcpCfg.MCPServers {
+			if !serverCfg.IsEnabled() {
+				continue
+			}
+			serverInfo := MCPServerInfo{
+				Name:  serverName,
+				Tools: []ToolInfo{},
+			}
+			for _, entry := range cache.GetToolsForServer(serverName) {
+				serverInfo.Tools = append(serverInfo.Tools, ToolInfo{
+					Name:        entry.Name,
+					Description: entry.Description,
+					Source:      serverName,
 				})
-
-				mcpServers = append(mcpServers, serverInfo)
 			}
-			// Sort servers by name
-			sort.Slice(mcpServers, func(i, j int) bool {
-				return mcpServers[i].Name < mcpServers[j].Name
+			// Sort tools within server
+			sort.Slice(serverInfo.Tools, func(i, j int) bool {
+				return serverInfo.Tools[i].Name < serverInfo.Tools[j].Name
 			})
+
+			mcpServers = append(mcpServers, serverInfo)
 		}
+		// Sort servers by name
+		sort.Slice(mcpServers, func(i, j int) bool {
+			return mcpServers[i].Name < mcpServers[j].Name
+		})
 	}
 
 	// Combine all tools for JSON output or max length calculation
@@ -470,11 +451,12 @@ func handleToolsStoreCommand(args []string) error {
 	}
 }
 
-func handleToolsStoreList() error {
+// loadStoreServers returns the installable MCP servers of all taps.
+func loadStoreServers() ([]mcpstore.Server, error) {
 	// Load servers from taps
 	store, err := flowstore.NewStore()
 	if err != nil {
-		return fmt.Errorf("failed to load flow store: %w", err)
+		return nil, fmt.Errorf("failed to load flow store: %w", err)
 	}
 	if err := store.UpdateAllManifests(); err != nil {
 		slog.Warn("failed to update manifests", "error", err)
@@ -506,7 +488,14 @@ func handleToolsStoreList() error {
 		})
 	}
 
-	servers := mcpstore.ListServers(inputs)
+	return mcpstore.ListServers(inputs), nil
+}
+
+func handleToolsStoreList() error {
+	servers, err := loadStoreServers()
+	if err != nil {
+		return err
+	}
 
 	// Styles
 	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("5"))
@@ -557,43 +546,11 @@ func handleToolsStoreList() error {
 }
 
 func handleToolsStoreInstall() error {
-	// Load servers from taps
-	store, err := flowstore.NewStore()
+	servers, err := loadStoreServers()
 	if err != nil {
-		return fmt.Errorf("failed to load flow store: %w", err)
-	}
-	if err := store.UpdateAllManifests(); err != nil {
-		slog.Warn("failed to update manifests", "error", err)
-	}
-	tappedMCPs := store.ListAllMCPs()
-
-	// Convert to mcpstore inputs
-	var inputs []mcpstore.TappedMCPInput
-	for _, mcp := range tappedMCPs {
-		// Skip MCPs that have neither command nor URL (not installable)
-		if mcp.Command == "" && mcp.URL == "" {
-			continue
-		}
-		inputs = append(inputs, mcpstore.TappedMCPInput{
-			ID:             mcp.ID,
-			Name:           mcp.Name,
-			Description:    mcp.Description,
-			Author:         mcp.Author,
-			GithubUrl:      mcp.GithubUrl,
-			GithubStars:    mcp.GithubStars,
-			RequiresApiKey: mcp.RequiresApiKey,
-			Command:        mcp.Command,
-			Args:           mcp.Args,
-			Env:            mcp.Env,
-			Tags:           mcp.Tags,
-			Transport:      mcp.Transport,
-			URL:            mcp.URL,
-			TapName:        mcp.TapName,
-		})
+		return err
 	}
 
-	servers := mcpstore.ListServers(inputs)
-
 	if len(servers) == 0 {
 		fmt.Println("No MCP servers available in store.")
 		return nil
@@ -787,37 +744,15 @@ func handleToolsRefreshCommand(args []string) error {
 
 		fmt.Printf("  [....] %s", name)
 
-		namedToolset, initErr := mcpManager.InitializeSingleToolset(ctx, name)
-		if initErr != nil {
-			fmt.Printf("\r  [FAIL] %s: %v\n", name, initErr)
+		count, refreshErr := mcpManager.CacheServerTools(ctx, name)
+		if refreshErr != nil {
+			fmt.Printf("\r  [FAIL] %s: %v\n", name, refreshErr)
 			failCount++
 			continue
 		}
 
-		minCtx := &minimalReadonlyContext{Context: ctx}
-		mcpTools, toolErr := namedToolset.Toolset.Tools(minCtx)
-		if toolErr != nil {
-			fmt.Printf("\r  [FAIL] %s: %v\n", name, toolErr)
-			failCount++
-			continue
-		}
-
-		// Build cache entries with schemas
-		entries := make([]cache.ToolEntry, 0, len(mcpTools))
-		for _, t := range mcpTools {
-			entries = append(entries, cache.ToolEntry{
-				Name:        t.Name(),
-				Description: t.Description(),
-				Source:      name,
-				InputSchema: common.ExtractToolInputSchema(t),
-			})
-		}
-
-		checksum := cache.ComputeServerChecksum(serverCfg.Command, serverCfg.Args, serverCfg.Env)
-		cache.AddServerTools(name, entries, checksum)
-
-		fmt.Printf("\r  [ OK ] %s (%d tools)\n", name, len(mcpTools))
-		totalTools += len(mcpTools)
+		fmt.Printf("\r  [ OK ] %s (%d tools)\n", name, count)
+		totalTools += count
 		successCount++
 	}
 
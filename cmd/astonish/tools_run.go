@@ -0,0 +1,176 @@
+package astonish
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/SAP/astonish/pkg/cache"
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/mcp"
+	"github.com/SAP/astonish/pkg/tools"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/memory"
+	"google.golang.org/adk/session"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/toolconfirmation"
+	"google.golang.org/genai"
+)
+
+// cliToolContext implements tool.Context for running a tool from the CLI
+type cliToolContext struct {
+	context.Context
+}
+
+func (c *cliToolContext) Actions() *session.EventActions       { return &session.EventActions{} }
+func (c *cliToolContext) Branch() string                       { return "" }
+func (c *cliToolContext) AgentName() string                    { return "tools-run" }
+func (c *cliToolContext) AppName() string                      { return "astonish" }
+func (c *cliToolContext) Artifacts() agent.Artifacts           { return nil }
+func (c *cliToolContext) FunctionCallID() string               { return "" }
+func (c *cliToolContext) InvocationID() string                 { return "" }
+func (c *cliToolContext) SessionID() string                    { return "" }
+func (c *cliToolContext) UserID() string                       { return "" }
+func (c *cliToolContext) UserContent() *genai.Content          { return nil }
+func (c *cliToolContext) ReadonlyState() session.ReadonlyState { return nil }
+func (c *cliToolContext) State() session.State                 { return nil }
+func (c *cliToolContext) SearchMemory(ctx context.Context, query string) (*memory.SearchResponse, error) {
+	return nil, nil
+}
+func (c *cliToolContext) RequestConfirmation(hint string, payload any) error   { return nil }
+func (c *cliToolContext) ToolConfirmation() *toolconfirmation.ToolConfirmation { return nil }
+
+// handleToolsRunCommand runs a single internal or MCP tool with JSON
+// arguments, for debugging tools outside a flow.
+func handleToolsRunCommand(args []string) error {
+	runCmd := flag.NewFlagSet("run", flag.ExitOnError)
+	argsJSON := runCmd.String("args", "{}", "Tool arguments as a JSON object")
+	yes := runCmd.Bool("yes", false, "Run without asking for approval")
+	runCmd.Usage = func() {
+		fmt.Println("Usage: astonish tools run <tool> [--args '{...}'] [--yes]")
+		fmt.Println("")
+		fmt.Println("  <tool> is an internal tool name, an MCP tool name, or <server>.<tool>")
+		fmt.Println("")
+		runCmd.PrintDefaults()
+	}
+	args = parseFlagsAfterArg(runCmd, args)
+	if len(args) != 1 {
+		runCmd.Usage()
+		return fmt.Errorf("expected exactly one tool name")
+	}
+	name := args[0]
+
+	var toolArgs map[string]any
+	if err := json.Unmarshal([]byte(*argsJSON), &toolArgs); err != nil {
+		return fmt.Errorf("--args must be a JSON object: %w", err)
+	}
+	if toolArgs == nil {
+		toolArgs = map[string]any{}
+	}
+
+	ctx := context.Background()
+	t, cleanup, err := resolveRunnableTool(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	// Same policy as chat: every tool call is approved unless auto_approve is set
+	if !*yes {
+		appCfg, _ := config.LoadAppConfig()
+		if appCfg == nil || !appCfg.Chat.AutoApprove {
+			pretty, _ := json.MarshalIndent(toolArgs, "", "  ")
+			fmt.Printf("Run %s with:\n%s\nApprove? [y/N] ", name, pretty)
+			answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			answer = strings.TrimSpace(strings.ToLower(answer))
+			if answer != "y" && answer != "yes" {
+				return fmt.Errorf("tool call not approved (pass --yes to skip the prompt)")
+			}
+		}
+	}
+
+	runnable, ok := t.(interface {
+		Run(tool.Context, any) (map[string]any, error)
+	})
+	if !ok {
+		return fmt.Errorf("tool '%s' cannot be run directly", name)
+	}
+	result, err := runnable.Run(&cliToolContext{Context: ctx}, toolArgs)
+	if err != nil {
+		return fmt.Errorf("tool '%s' failed: %w", name, err)
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal result: %w", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// parseFlagsAfterArg parses fs allowing flags after a leading positional
+// argument ("run <tool> --args ..."), and returns the positional arguments.
+func parseFlagsAfterArg(fs *flag.FlagSet, args []string) []string {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		fs.Parse(args[1:])
+		return append([]string{args[0]}, fs.Args()...)
+	}
+	fs.Parse(args)
+	return fs.Args()
+}
+
+// resolveRunnableTool finds a tool by name: internal tools first, then
+// <server>.<tool>, then the MCP server the tools cache lists it under.
+// cleanup stops any MCP server that was started.
+func resolveRunnableTool(ctx context.Context, name string) (tool.Tool, func(), error) {
+	noop := func() {}
+	internalTools, err := tools.GetInternalTools()
+	if err != nil {
+		return nil, noop, fmt.Errorf("failed to get internal tools: %w", err)
+	}
+	for _, t := range internalTools {
+		if t.Name() == name {
+			return t, noop, nil
+		}
+	}
+
+	mcpCfg, err := config.LoadMCPConfig()
+	if err != nil {
+		return nil, noop, fmt.Errorf("failed to load MCP config: %w", err)
+	}
+	serverName, toolName := "", name
+	if server, rest, ok := strings.Cut(name, "."); ok {
+		if _, exists := mcpCfg.MCPServers[server]; exists {
+			serverName, toolName = server, rest
+		}
+	}
+	if serverName == "" {
+		mcp.SyncToolsCache(ctx, mcpCfg)
+		serverName = cache.GetServerForTool(name)
+	}
+	if serverName == "" {
+		return nil, noop, fmt.Errorf("tool '%s' not found (see 'astonish tools list')", name)
+	}
+
+	mcpManager := mcp.NewManagerFromConfig(mcpCfg)
+	namedToolset, err := mcpManager.InitializeSingleToolset(ctx, serverName)
+	if err != nil {
+		mcpManager.Cleanup()
+		return nil, noop, fmt.Errorf("failed to start MCP server '%s': %w", serverName, err)
+	}
+	serverTools, err := namedToolset.Toolset.Tools(&cliToolContext{Context: ctx})
+	if err != nil {
+		mcpManager.Cleanup()
+		return nil, noop, fmt.Errorf("failed to list tools of '%s': %w", serverName, err)
+	}
+	for _, t := range serverTools {
+		if t.Name() == toolName {
+			return t, mcpManager.Cleanup, nil
+		}
+	}
+	mcpManager.Cleanup()
+	return nil, noop, fmt.Errorf("MCP server '%s' has no tool '%s'", serverName, toolName)
+}
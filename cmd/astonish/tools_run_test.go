@@ -0,0 +1,65 @@
+package astonish
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/SAP/astonish/pkg/cache"
+	"google.golang.org/adk/tool"
+)
+
+func TestResolveRunnableTool(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	cache.SetCacheDir(t.TempDir())
+	t.Cleanup(func() { cache.SetCacheDir("") })
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "remote", Version: "1.0.0"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "get_pr", Description: "Get a pull request"},
+		func(ctx context.Context, req *mcp.CallToolRequest, in struct {
+			Number int `json:"number"`
+		}) (*mcp.CallToolResult, any, error) {
+			return nil, map[string]any{"number": in.Number}, nil
+		})
+	srv := httptest.NewServer(mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil))
+	defer srv.Close()
+
+	dir := filepath.Join(configHome, "astonish")
+	os.MkdirAll(dir, 0755)
+	mcpConfig := `{"mcpServers": {"github": {"transport": "streamable-http", "url": "` + srv.URL + `"}}}`
+	if err := os.WriteFile(filepath.Join(dir, "mcp_config.json"), []byte(mcpConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if internal, _, err := resolveRunnableTool(ctx, "read_file"); err != nil || internal.Name() != "read_file" {
+		t.Errorf("read_file resolved to %v, %v", internal, err)
+	}
+
+	// By <server>.<tool>, and by tool name through the tools cache
+	for _, name := range []string{"github.get_pr", "get_pr"} {
+		found, cleanup, err := resolveRunnableTool(ctx, name)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		result, err := found.(interface {
+			Run(tool.Context, any) (map[string]any, error)
+		}).Run(&cliToolContext{Context: ctx}, map[string]any{"number": 12})
+		cleanup()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if output, _ := result["output"].(map[string]any); output == nil || output["number"] != float64(12) {
+			t.Errorf("%s returned %v, want the PR number echoed", name, result)
+		}
+	}
+
+	if _, _, err := resolveRunnableTool(ctx, "github.missing"); err == nil {
+		t.Error("a tool the server does not have resolved")
+	}
+}
@@ -1,14 +1,25 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
 	"github.com/SAP/astonish/cmd/astonish"
+	"github.com/SAP/astonish/pkg/launcher"
 )
 
 func main() {
 	if err := astonish.Execute(); err != nil {
+		// An interrupted run already reported where it stopped
+		if errors.Is(err, launcher.ErrRunCancelled) {
+			os.Exit(130)
+		}
+		// An aborted run already showed why; exit with the code it asked for
+		var aborted *launcher.RunAbortedError
+		if errors.As(err, &aborted) {
+			os.Exit(aborted.ExitCode)
+		}
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
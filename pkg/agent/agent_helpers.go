@@ -80,16 +80,10 @@ func (a *AstonishAgent) renderString(tmpl string, state session.State) string {
 	// This allows for expressions like {comment["patch"]}
 	re := regexp.MustCompile(`\{([^{}]+)\}`)
 
-	// Protect {{CREDENTIAL:...}} and <<<SECRET_N>>> patterns from being
-	// garbled by state variable interpolation. These placeholders are resolved
-	// later at the tool execution boundary (BeforeToolCallback / node_tool).
-	var credHoles []string
-	credRe := regexp.MustCompile(`\{\{CREDENTIAL:[^}]+\}\}`)
-	tmpl = credRe.ReplaceAllStringFunc(tmpl, func(m string) string {
-		idx := len(credHoles)
-		credHoles = append(credHoles, m)
-		return fmt.Sprintf("\x00CRED_%d\x00", idx)
-	})
+	// Protect {{CREDENTIAL:...}} patterns from being garbled by state
+	// variable interpolation. These placeholders are resolved later at the
+	// tool execution boundary (BeforeToolCallback / node_tool).
+	tmpl, restoreCredentials := protectCredentials(tmpl)
 
 	// Convert state to map once for efficiency if needed, but renderString might be called often
 	// For now, convert inside the loop or pass it?
@@ -127,11 +121,7 @@ func (a *AstonishAgent) renderString(tmpl string, state session.State) string {
 	})
 
 	// Restore protected credential placeholders
-	for i, orig := range credHoles {
-		result = strings.Replace(result, fmt.Sprintf("\x00CRED_%d\x00", i), orig, 1)
-	}
-
-	return result
+	return restoreCredentials(result)
 }
 
 // resolveCredentialVarsInRawContext resolves state variable references that are
@@ -258,8 +248,10 @@ func getKeysStr(m map[string]string) []string {
 	return keys
 }
 
-// emitNodeTransition emits a node transition event
-func (a *AstonishAgent) emitNodeTransition(nodeName string, state session.State, yield func(*session.Event, error) bool) bool {
+// emitNodeTransition emits a node transition event. It marks the start of
+// the node's history window unless the node resumes its execution (after a
+// tool approval).
+func (a *AstonishAgent) emitNodeTransition(nodeName string, resumed bool, state session.State, yield func(*session.Event, error) bool) bool {
 	if nodeName == "END" {
 		event := &session.Event{
 			Actions: session.EventActions{
@@ -269,6 +261,7 @@ func (a *AstonishAgent) emitNodeTransition(nodeName string, state session.State,
 				},
 			},
 		}
+		markNodeStart(event, "END")
 		return yield(event, nil)
 	}
 
@@ -298,6 +291,9 @@ func (a *AstonishAgent) emitNodeTransition(nodeName string, state session.State,
 			},
 		},
 	}
+	if !resumed {
+		markNodeStart(event, nodeName)
+	}
 
 	return yield(event, nil)
 }
@@ -0,0 +1,151 @@
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"google.golang.org/adk/session"
+)
+
+// approvalKey returns the node-scoped state key holding a tool approval.
+func approvalKey(nodeName, toolName string) string {
+	return fmt.Sprintf("approval:%s:%s", nodeName, toolName)
+}
+
+// approvalArgsHash fingerprints tool call arguments. JSON encoding sorts map
+// keys and renders ints and floats alike, so args that round-tripped through
+// session state hash the same as the live call.
+func approvalArgsHash(args any) string {
+	data, err := json.Marshal(args)
+	if err != nil {
+		data = fmt.Appendf(nil, "%v", args)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// grantApproval stores an approval for the pending call, bound to the
+// approval_args the user was shown. A later call with different args will
+// not match and is prompted again.
+func grantApproval(state session.State, nodeName, toolName string) string {
+	args, _ := state.Get("approval_args")
+	key := approvalKey(nodeName, toolName)
+	if err := state.Set(key, approvalArgsHash(args)); err != nil {
+		slog.Warn("failed to set approval state", "key", key, "error", err)
+	}
+	return key
+}
+
+// hasApproval reports whether the user approved this exact call, or gave a
+// standing approval for the tool. Approvals granted for other args are stale
+// and don't count.
+func hasApproval(state session.State, nodeName, toolName string, args any) bool {
+	if hasStandingApproval(state, nodeName, toolName) {
+		return true
+	}
+	val, _ := state.Get(approvalKey(nodeName, toolName))
+	granted, ok := val.(string)
+	if !ok || granted == "" {
+		return false
+	}
+	if granted != approvalArgsHash(args) {
+		slog.Debug("stale approval: tool args changed since approval", "node", nodeName, "tool", toolName)
+		return false
+	}
+	return true
+}
+
+// revokeApproval clears any approval for the tool so the next call prompts.
+func revokeApproval(state session.State, nodeName, toolName string) {
+	state.Set(approvalKey(nodeName, toolName), false)
+}
+
+// Answers to a tool approval besides Yes and No. They approve the call and
+// grant the later calls of the tool, whatever their args, without asking.
+const (
+	ApproveForNode = "Always for this node" // Until the node's execution ends
+	ApproveForRun  = "Always for this run"  // Until the run reaches END
+)
+
+// approvalOptions are the answers offered for a tool approval.
+var approvalOptions = []string{"Yes", "No", ApproveForNode, ApproveForRun}
+
+// approvalGrantsKey holds the standing approvals of the run: "node:<node>:<tool>"
+// maps to the node execution the grant is valid for, "run:<tool>" to true.
+const approvalGrantsKey = "_approval_grants"
+
+// ApprovesCall reports whether answer to a tool approval lets the call run.
+func ApprovesCall(answer string) bool {
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "yes", "y", "approve", strings.ToLower(ApproveForNode), strings.ToLower(ApproveForRun):
+		return true
+	}
+	return false
+}
+
+// grantStandingApproval records the standing approval an answer asks for,
+// if any, and returns the approvals to persist as a state delta (nil when
+// the answer grants none).
+func grantStandingApproval(state session.State, nodeName, toolName, answer string) map[string]any {
+	var key string
+	var value any
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case strings.ToLower(ApproveForNode):
+		execID, _ := stateString(state, nodeExecutionIDKey)
+		key, value = "node:"+nodeName+":"+toolName, execID
+	case strings.ToLower(ApproveForRun):
+		key, value = "run:"+toolName, true
+	default:
+		return nil
+	}
+	grants := approvalGrants(state)
+	grants[key] = value
+	if err := state.Set(approvalGrantsKey, grants); err != nil {
+		slog.Warn("failed to set approval grants", "error", err)
+	}
+	return grants
+}
+
+// hasStandingApproval reports whether a standing approval covers the calls
+// of toolName at nodeName. A node grant only holds during the execution of
+// the node it was given in.
+func hasStandingApproval(state session.State, nodeName, toolName string) bool {
+	grants := approvalGrants(state)
+	if grants["run:"+toolName] == true {
+		return true
+	}
+	execID, ok := grants["node:"+nodeName+":"+toolName].(string)
+	if !ok {
+		return false
+	}
+	current, _ := stateString(state, nodeExecutionIDKey)
+	return execID == current
+}
+
+// expireRunApprovals drops the standing approvals when the run ends and
+// adds the change to delta.
+func expireRunApprovals(state session.State, delta map[string]any) {
+	if len(approvalGrants(state)) == 0 {
+		return
+	}
+	if err := state.Set(approvalGrantsKey, map[string]any{}); err != nil {
+		slog.Warn("failed to clear approval grants", "error", err)
+	}
+	delta[approvalGrantsKey] = map[string]any{}
+}
+
+// approvalGrants returns a copy of the run's standing approvals.
+func approvalGrants(state session.State) map[string]any {
+	grants := make(map[string]any)
+	val, _ := state.Get(approvalGrantsKey)
+	if m, ok := val.(map[string]any); ok {
+		for k, v := range m {
+			grants[k] = v
+		}
+	}
+	return grants
+}
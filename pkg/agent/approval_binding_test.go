@@ -0,0 +1,61 @@
+package agent
+
+import "testing"
+
+func TestStandingApprovals(t *testing.T) {
+	state := NewMockState()
+	state.Set(nodeExecutionIDKey, "abc:fetch:1")
+
+	if grants := grantStandingApproval(state, "fetch", "get_file", "Yes"); grants != nil {
+		t.Errorf("Yes granted %v, want no standing approval", grants)
+	}
+	if hasApproval(state, "fetch", "get_file", map[string]any{"path": "a.go"}) {
+		t.Fatal("approved without an answer")
+	}
+
+	// A node grant covers any args during the node's execution only
+	grantStandingApproval(state, "fetch", "get_file", ApproveForNode)
+	for _, path := range []string{"a.go", "b.go"} {
+		if !hasApproval(state, "fetch", "get_file", map[string]any{"path": path}) {
+			t.Errorf("node grant did not cover %s", path)
+		}
+	}
+	if hasApproval(state, "review", "get_file", nil) {
+		t.Error("node grant covered another node")
+	}
+	state.Set(nodeExecutionIDKey, "abc:fetch:2")
+	if hasApproval(state, "fetch", "get_file", nil) {
+		t.Error("node grant survived the node's next execution")
+	}
+
+	// A run grant covers every node until the run ends
+	grants := grantStandingApproval(state, "fetch", "get_file", "always for this run")
+	if grants["run:get_file"] != true {
+		t.Errorf("grants = %v, want the run grant to persist", grants)
+	}
+	if !hasApproval(state, "review", "get_file", nil) {
+		t.Error("run grant did not cover another node")
+	}
+	if hasApproval(state, "review", "shell_command", nil) {
+		t.Error("run grant covered another tool")
+	}
+	delta := map[string]any{}
+	expireRunApprovals(state, delta)
+	if hasApproval(state, "review", "get_file", nil) {
+		t.Error("run grant survived the end of the run")
+	}
+	if _, ok := delta[approvalGrantsKey]; !ok {
+		t.Error("expiry was not added to the state delta")
+	}
+}
+
+func TestApprovesCall(t *testing.T) {
+	for answer, want := range map[string]bool{
+		"Yes": true, "y": true, ApproveForNode: true, ApproveForRun: true,
+		"No": false, "": false, "maybe": false,
+	} {
+		if got := ApprovesCall(answer); got != want {
+			t.Errorf("ApprovesCall(%q) = %v, want %v", answer, got, want)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/SAP/astonish/pkg/config"
+)
+
+// ApprovalPolicy decides tool calls with the rules of an approval profile
+// before the user is asked. Denials apply even with auto approval.
+type ApprovalPolicy struct {
+	Name          string
+	rules         []approvalRule
+	defaultAction string
+}
+
+type approvalRule struct {
+	tools  []string
+	args   map[string]*regexp.Regexp
+	action string
+}
+
+// NewApprovalPolicy returns the policy of the approval profile name of the
+// app config, or nil for an empty name.
+func NewApprovalPolicy(appCfg *config.AppConfig, name string) (*ApprovalPolicy, error) {
+	if name == "" {
+		return nil, nil
+	}
+	var profile config.ApprovalProfile
+	found := false
+	if appCfg != nil {
+		profile, found = appCfg.ApprovalProfiles[name]
+	}
+	if !found {
+		return nil, fmt.Errorf("unknown approval profile %q (defined: %s)", name, profileNames(appCfg))
+	}
+
+	p := &ApprovalPolicy{Name: name, defaultAction: config.ApprovalPrompt}
+	if profile.Default != "" {
+		if !slices.Contains(config.ApprovalActions, profile.Default) {
+			return nil, fmt.Errorf("approval profile %q: invalid default %q (use %s)", name, profile.Default, strings.Join(config.ApprovalActions, ", "))
+		}
+		p.defaultAction = profile.Default
+	}
+	for i, r := range profile.Rules {
+		if !slices.Contains(config.ApprovalActions, r.Action) {
+			return nil, fmt.Errorf("approval profile %q, rule %d: invalid action %q (use %s)", name, i+1, r.Action, strings.Join(config.ApprovalActions, ", "))
+		}
+		if len(r.Tools) == 0 {
+			return nil, fmt.Errorf("approval profile %q, rule %d: no tools", name, i+1)
+		}
+		rule := approvalRule{tools: r.Tools, action: r.Action, args: make(map[string]*regexp.Regexp, len(r.Args))}
+		for _, pattern := range r.Tools {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("approval profile %q, rule %d: invalid tool pattern %q", name, i+1, pattern)
+			}
+		}
+		for arg, expr := range r.Args {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("approval profile %q, rule %d: invalid pattern for %s: %w", name, i+1, arg, err)
+			}
+			rule.args[arg] = re
+		}
+		p.rules = append(p.rules, rule)
+	}
+	return p, nil
+}
+
+// profileNames lists the approval profiles of the app config.
+func profileNames(appCfg *config.AppConfig) string {
+	if appCfg == nil || len(appCfg.ApprovalProfiles) == 0 {
+		return "none"
+	}
+	names := make([]string, 0, len(appCfg.ApprovalProfiles))
+	for name := range appCfg.ApprovalProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// Decide returns the action for a call of toolName with args: the action
+// of the first matching rule, else the profile's default. A nil policy
+// prompts.
+func (p *ApprovalPolicy) Decide(toolName string, args map[string]any) string {
+	if p == nil {
+		return config.ApprovalPrompt
+	}
+	for _, r := range p.rules {
+		if r.matches(toolName, args) {
+			return r.action
+		}
+	}
+	return p.defaultAction
+}
+
+// approver names the policy as the approver of the calls it decides.
+func (p *ApprovalPolicy) approver() string {
+	return "profile:" + p.Name
+}
+
+func (r approvalRule) matches(toolName string, args map[string]any) bool {
+	if !slices.ContainsFunc(r.tools, func(pattern string) bool {
+		ok, _ := path.Match(pattern, toolName)
+		return ok
+	}) {
+		return false
+	}
+	for arg, re := range r.args {
+		v, ok := args[arg]
+		if !ok || !re.MatchString(argText(v)) {
+			return false
+		}
+	}
+	return true
+}
+
+// argText renders an argument for matching: strings as they are, other
+// values as JSON.
+func argText(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// profileDenial is the tool result of a call the approval profile denied.
+func (p *ApprovalPolicy) profileDenial(toolName string) map[string]any {
+	return map[string]any{
+		"status": "denied",
+		"error":  fmt.Sprintf("The call of %s was denied by the approval profile '%s'. Do not retry it; continue without this tool call.", toolName, p.Name),
+	}
+}
@@ -0,0 +1,86 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/artifact"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// ArtifactsStateKey is the state key holding references to the artifacts
+// saved during a run: a map from artifact name to {name, version,
+// session_id}. The session ID and name are enough to download the artifact
+// after the run.
+const ArtifactsStateKey = "_artifacts"
+
+// trackedArtifacts records the versions saved through it in an event's
+// ArtifactDelta, as ADK's tool contexts do, for the tool contexts the flow
+// agent builds itself.
+type trackedArtifacts struct {
+	agent.Artifacts
+	actions *session.EventActions
+}
+
+func (t *trackedArtifacts) Save(ctx context.Context, name string, data *genai.Part) (*artifact.SaveResponse, error) {
+	resp, err := t.Artifacts.Save(ctx, name, data)
+	if err == nil {
+		if t.actions.ArtifactDelta == nil {
+			t.actions.ArtifactDelta = make(map[string]int64)
+		}
+		t.actions.ArtifactDelta[name] = resp.Version
+	}
+	return resp, err
+}
+
+// runArtifacts returns the artifacts of the run's session, or nil when the
+// runner has no artifact service.
+func runArtifacts(ctx context.Context) agent.Artifacts {
+	if ic, ok := ctx.(agent.InvocationContext); ok {
+		return ic.Artifacts()
+	}
+	return nil
+}
+
+// recordArtifacts adds references to the artifacts in saved (name to
+// version) to the ArtifactsStateKey map, updating state and delta.
+func recordArtifacts(ctx context.Context, state session.State, saved map[string]int64, delta map[string]any) {
+	if len(saved) == 0 {
+		return
+	}
+	var sessionID string
+	if ic, ok := ctx.(agent.InvocationContext); ok && ic.Session() != nil {
+		sessionID = ic.Session().ID()
+	}
+	refs := map[string]any{}
+	if existing, err := state.Get(ArtifactsStateKey); err == nil {
+		if m, ok := existing.(map[string]any); ok {
+			for k, v := range m {
+				refs[k] = v
+			}
+		}
+	}
+	for name, version := range saved {
+		refs[name] = map[string]any{"name": name, "version": version, "session_id": sessionID}
+	}
+	_ = state.Set(ArtifactsStateKey, refs)
+	delta[ArtifactsStateKey] = refs
+}
+
+// saveOutputArtifact saves an output node's message as a text artifact and
+// records it in the event's actions and in state.
+func saveOutputArtifact(ctx agent.InvocationContext, name, message string, state session.State, actions *session.EventActions) error {
+	arts := ctx.Artifacts()
+	if arts == nil {
+		return fmt.Errorf("artifact storage is not available in this run")
+	}
+	resp, err := arts.Save(ctx, name, genai.NewPartFromText(message))
+	if err != nil {
+		return err
+	}
+	actions.ArtifactDelta = map[string]int64{name: resp.Version}
+	recordArtifacts(ctx, state, actions.ArtifactDelta, actions.StateDelta)
+	return nil
+}
@@ -10,11 +10,12 @@ import (
 
 	"github.com/SAP/astonish/pkg/config"
 	"github.com/SAP/astonish/pkg/credentials"
+	"github.com/SAP/astonish/pkg/memory"
+	"github.com/SAP/astonish/pkg/provider"
 	"google.golang.org/adk/agent"
 	"google.golang.org/adk/model"
 	"google.golang.org/adk/session"
 	"google.golang.org/adk/tool"
-	"google.golang.org/genai"
 )
 
 // ErrWaitingForApproval is returned when a tool needs user approval
@@ -33,6 +34,29 @@ type AstonishAgent struct {
 	Redactor        *credentials.Redactor          // Redacts credential values from tool/LLM outputs (nil = disabled)
 	CredentialStore credentials.CredentialResolver // Credential store for placeholder substitution (nil = disabled)
 	PendingSecrets  *credentials.PendingVault      // Per-session vault for <<<SECRET_N>>> token resolution (nil = disabled)
+	AppConfig       *config.AppConfig              // Provider settings for per-node models and model_fallbacks (nil = disabled)
+	ProviderName    string                         // Provider instance behind LLM
+	ModelName       string                         // Model name behind LLM
+	MCPHealth       func() map[string]bool         // Health flags of the flow's MCP servers (mcp:<server>:healthy), refreshed before each node (nil = disabled)
+	WorkspaceDir    string                         // Directory reports are written to (default: the working directory)
+	Variables       map[string]string              // --var values for the flow's variables: section (nil = defaults only)
+	FlowName        string                         // Name of the flow, for flow-scoped memory (empty = derived from the node names)
+	Memory          memory.FactStore               // Long-term facts of memory nodes (nil = the store configured in AppConfig)
+	Retriever       *memory.Retriever              // Vector store of vector nodes (nil = the store configured in AppConfig)
+	Debugger        Debugger                       // Consulted before each node (--step); nil = run without pausing
+	ApprovalPolicy  *ApprovalPolicy                // Approval profile deciding tool calls before the user is asked (nil = ask)
+	NoCache         bool                           // Call the model even for cache_response nodes (--no-cache)
+	DumpLLMIO       string                         // Directory every model request and response is written to as JSON (--dump-llm-io; empty = off)
+
+	// ToolsetsWithEnv returns the MCP toolsets started with a node's env
+	// (nil = nodes with env: cannot use MCP tools).
+	ToolsetsWithEnv func(ctx context.Context, env map[string]string) ([]tool.Toolset, error)
+
+	// NewModel creates the per-node and fallback model clients, e.g. to
+	// record or replay their calls (nil = the shared pool).
+	NewModel func(ctx context.Context, providerName, modelName string) (model.LLM, error)
+
+	llmPool *provider.Pool // Per-node and fallback model clients, shared across nodes
 }
 
 // NewAstonishAgent creates a new AstonishAgent.
@@ -42,6 +66,7 @@ func NewAstonishAgent(cfg *config.AgentConfig, llm model.LLM, tools []tool.Tool)
 		LLM:      llm,
 		Tools:    tools,
 		Toolsets: nil,
+		llmPool:  provider.NewPool(),
 	}
 }
 
@@ -52,11 +77,16 @@ func NewAstonishAgentWithToolsets(cfg *config.AgentConfig, llm model.LLM, tools
 		LLM:      llm,
 		Tools:    tools,
 		Toolsets: toolsets,
+		llmPool:  provider.NewPool(),
 	}
 }
 
 // Run executes the agent flow with stateful workflow management.
 func (a *AstonishAgent) Run(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+	// The flow's shell section reaches shell_command through the context
+	if a.Config != nil && a.Config.Shell != nil {
+		ctx = ctx.WithContext(context.WithValue(ctx, flowShellKey{}, a.Config.Shell))
+	}
 	if a.DebugMode {
 		if a.Toolsets != nil {
 			// Create a minimal context for listing tools
@@ -143,7 +173,7 @@ func (a *AstonishAgent) Run(ctx agent.InvocationContext) iter.Seq2[*session.Even
 				slog.Debug("run awaiting approval", "tool", toolNameStr, "input", input)
 			}
 
-			if strings.EqualFold(input, "Yes") {
+			if ApprovesCall(input) {
 				// Approved!
 				if toolNameStr != "" {
 					// Get current node for node-scoped approval
@@ -153,10 +183,7 @@ func (a *AstonishAgent) Run(ctx agent.InvocationContext) iter.Seq2[*session.Even
 							currentNode = nodeName
 						}
 					}
-					approvalKey := fmt.Sprintf("approval:%s:%s", currentNode, toolNameStr)
-					if err := state.Set(approvalKey, true); err != nil {
-						slog.Warn("failed to set approval state", "key", approvalKey, "error", err)
-					}
+					approvalKey := grantApproval(state, currentNode, toolNameStr)
 					if a.DebugMode {
 						slog.Debug("set approval", "tool", toolNameStr, "key", approvalKey)
 					}
@@ -180,12 +207,39 @@ func (a *AstonishAgent) Run(ctx agent.InvocationContext) iter.Seq2[*session.Even
 			currentNodeName = "START"
 		}
 
+		// Values of sensitive keys from earlier turns are masked from the start
+		a.protectSensitiveState(state)
+
 		// Pending state delta to be attached to the next event
 		pendingStateDelta := make(map[string]any)
 
+		chatMode := a.Config.ChatEnabled()
+
+		// Stable IDs of node executions and events
+		ids := a.newEventIDTracker(ctx, state, hasUserInput)
+
+		// Timing and counts of the run, summarized at END
+		stats := runStats(state)
+
+		// Lifecycle events go to the hooks of the flow and the app config
+		lifecycle := a.newRunHooks(ctx)
+		defer lifecycle.close()
+		if lifecycle != nil {
+			ctx = ctx.WithContext(context.WithValue(ctx, runHooksKey{}, lifecycle))
+		}
+
+		// A cancelled run (Ctrl+C, a closed connection) stops at the node it
+		// was running. Errors caused by the cancellation are not reported;
+		// a final _cancelled event records the node so the run can resume
+		resumeNode := currentNodeName
+		consumerStopped := false
+
 		// Wrap yield to inject pendingStateDelta and redact credential values
 		originalYield := yield
 		yield = func(event *session.Event, err error) bool {
+			if err != nil && ctx.Err() != nil {
+				return false
+			}
 			if event != nil && len(pendingStateDelta) > 0 {
 				if event.Actions.StateDelta == nil {
 					event.Actions.StateDelta = make(map[string]any)
@@ -199,16 +253,58 @@ func (a *AstonishAgent) Run(ctx agent.InvocationContext) iter.Seq2[*session.Even
 				// Clear pendingStateDelta so we don't send it again
 				pendingStateDelta = make(map[string]any)
 			}
+			var finished *NodeRunSummary
+			if event != nil {
+				ids.stamp(state, event)
+				finished = stats.current()
+				if stats.observe(event, time.Now()) {
+					if event.Actions.StateDelta == nil {
+						event.Actions.StateDelta = make(map[string]any)
+					}
+					event.Actions.StateDelta[runStatsKey] = stats.snapshot()
+				}
+			}
+			lifecycle.observe(event, err, finished, stats)
 			// Redact credential values from LLM text responses before they
 			// reach the user. The LLM may have received raw secrets via
 			// resolve_credential and could accidentally echo them.
+			if event != nil {
+				a.protectSensitiveDelta(event.Actions.StateDelta)
+			}
 			redactEventText(a.Redactor, event)
-			return originalYield(event, err)
+			if chatMode {
+				recordChatReply(state, event)
+			}
+			if !originalYield(event, err) {
+				consumerStopped = true
+				return false
+			}
+			return true
 		}
+		defer func() {
+			if ctx.Err() != nil && !consumerStopped && resumeNode != "END" {
+				cancelRun(state, resumeNode, yield)
+			}
+		}()
 
 		// Initialize state keys from all nodes if not present
 		// This mimics Python's behavior of pre-populating keys
 		if currentNodeName == "START" {
+			// Declared variables come first so output_model keys of the
+			// same name keep their value
+			values, err := a.Config.ResolveVariables(a.Variables)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			for key, val := range values {
+				if _, err := state.Get(key); err != nil {
+					if err := state.Set(key, val); err != nil {
+						slog.Warn("failed to initialize state key", "key", key, "error", err)
+					}
+					pendingStateDelta[key] = val
+				}
+			}
 			for _, node := range a.Config.Nodes {
 				// Initialize output_model keys
 				for key := range node.OutputModel {
@@ -234,6 +330,17 @@ func (a *AstonishAgent) Run(ctx agent.InvocationContext) iter.Seq2[*session.Even
 			}
 		}
 
+		// Check if we're awaiting confirmation of the estimated cost
+		if awaitingCost, _ := state.Get("awaiting_cost_confirmation"); awaitingCost == true {
+			if !a.handleCostConfirmation(ctx, state, yield) {
+				return
+			}
+		}
+
+		// A node resumed after a tool approval continues its execution and
+		// keeps its history window
+		resumedNode := ""
+
 		// Check if we're awaiting tool approval
 		if awaitingApproval, _ := state.Get("awaiting_approval"); awaitingApproval == true {
 			if !a.handleToolApproval(ctx, state, yield) {
@@ -242,13 +349,39 @@ func (a *AstonishAgent) Run(ctx agent.InvocationContext) iter.Seq2[*session.Even
 			// After handling approval, get the current node again
 			currentNodeNameVal, _ = state.Get("current_node")
 			currentNodeName, _ = currentNodeNameVal.(string)
+			resumedNode = currentNodeName
 		}
 
 		// Check if we have user content (meaning this is a resume after user input)
 		hasUserInput := ctx.UserContent() != nil && len(ctx.UserContent().Parts) > 0
 
+		// In chat mode a user message at START or END starts a new turn;
+		// after END the turn starts over at the loop_to node
+		chatTurn := chatMode && hasUserInput && (currentNodeName == "START" || currentNodeName == "END")
+		if chatTurn {
+			var inputBuilder strings.Builder
+			for _, part := range ctx.UserContent().Parts {
+				inputBuilder.WriteString(part.Text)
+			}
+			startChatTurn(state, strings.TrimSpace(StripTimestamp(inputBuilder.String())), pendingStateDelta)
+			if currentNodeName == "END" {
+				entry, err := a.chatEntryNode(state)
+				if err != nil {
+					yield(nil, err)
+					return
+				}
+				currentNodeName = entry
+				if err := state.Set("current_node", currentNodeName); err != nil {
+					slog.Warn("failed to set current_node state", "error", err)
+				}
+			}
+		}
+
 		// If we're at START, move to first node
 		if currentNodeName == "START" {
+			if !a.confirmCost(state, "flow", a.estimateFlowCost(state), yield) {
+				return
+			}
 			nextNode, err := a.getNextNode("START", state)
 			if err != nil {
 				yield(nil, err)
@@ -261,57 +394,7 @@ func (a *AstonishAgent) Run(ctx agent.InvocationContext) iter.Seq2[*session.Even
 			node, found := a.getNode(currentNodeName)
 			if found && node.Type == "input" && !hasUserInput {
 				// Show the prompt and return, waiting for user input
-				prompt := a.renderString(node.Prompt, state)
-
-				// Resolve options if present
-				var inputOptions []string
-				if len(node.Options) > 0 {
-					for _, opt := range node.Options {
-						// Check if option is a state variable
-						if val, err := state.Get(opt); err == nil {
-							// If it's a list of strings, expand it
-							if list, ok := val.([]string); ok {
-								inputOptions = append(inputOptions, list...)
-								continue
-							}
-							// If it's a generic list, try to convert elements to strings
-							if list, ok := val.([]interface{}); ok {
-								for _, item := range list {
-									inputOptions = append(inputOptions, fmt.Sprintf("%v", item))
-								}
-								continue
-							}
-							// If it's a single string (LLM returned one item as string instead of array),
-							// treat it as a single option
-							if strVal, ok := val.(string); ok && strings.TrimSpace(strVal) != "" {
-								inputOptions = append(inputOptions, strings.TrimSpace(strVal))
-								continue
-							}
-						}
-						// Otherwise treat as literal option
-						inputOptions = append(inputOptions, opt)
-					}
-				}
-
-				promptEvent := &session.Event{
-					LLMResponse: model.LLMResponse{
-						Content: &genai.Content{
-							Parts: []*genai.Part{{Text: prompt}},
-							Role:  "model",
-						},
-					},
-					Actions: session.EventActions{
-						StateDelta: map[string]any{
-							"current_node":      currentNodeName,
-							"input_options":     inputOptions,
-							"waiting_for_input": true,
-						},
-					},
-				}
-
-				if !yield(promptEvent, nil) {
-					return
-				}
+				yield(a.inputPromptEvent(node, state, ""), nil)
 				return
 			}
 
@@ -322,9 +405,9 @@ func (a *AstonishAgent) Run(ctx agent.InvocationContext) iter.Seq2[*session.Even
 		}
 
 		// Handle resume from input
-		if currentNodeName != "START" && currentNodeName != "END" && hasUserInput {
+		if currentNodeName != "START" && currentNodeName != "END" && hasUserInput && !chatTurn {
 			node, found := a.getNode(currentNodeName)
-			if found && node.Type == "input" {
+			if found && (node.Type == "input" || node.Confirm != "") {
 				// Extract user input
 				var inputBuilder strings.Builder
 				for _, part := range ctx.UserContent().Parts {
@@ -334,12 +417,18 @@ func (a *AstonishAgent) Run(ctx agent.InvocationContext) iter.Seq2[*session.Even
 				}
 				input := strings.TrimSpace(StripTimestamp(inputBuilder.String()))
 
+				// Ask again instead of storing an invalid answer
+				value, err := a.parseInput(node, input, state)
+				if err != nil {
+					yield(a.inputPromptEvent(node, state, err.Error()), nil)
+					return
+				}
+
 				// Build state delta with the input value
 				stateDelta := make(map[string]any)
-				for key := range node.OutputModel {
-					stateDelta[key] = input
-					state.Set(key, input)
-					break
+				if key := inputStateKey(node); key != "" {
+					stateDelta[key] = value
+					state.Set(key, value)
 				}
 
 				// Move to next node
@@ -362,10 +451,38 @@ func (a *AstonishAgent) Run(ctx agent.InvocationContext) iter.Seq2[*session.Even
 		}
 
 		// Main execution loop
+		ranNode := ""
 		for {
+			if ctx.Err() != nil {
+				return
+			}
+			resumeNode = currentNodeName
+
+			// A node that set _abort stops the run: it goes to END with a
+			// failure summary instead of following the edges
+			if currentNodeName != "END" {
+				if abort, ok := takeAbort(state, pendingStateDelta); ok {
+					if ranNode == "" {
+						ranNode = currentNodeName
+					}
+					if !yield(abortEvent(ranNode, abort), nil) {
+						return
+					}
+					currentNodeName = "END"
+					continue
+				}
+			}
+
 			if currentNodeName == "END" {
+				if chatMode {
+					finishChatTurn(state, pendingStateDelta)
+				}
+				expireRunApprovals(state, pendingStateDelta)
 				// Emit transition to END so UI knows we are done
-				if !a.emitNodeTransition("END", state, yield) {
+				if !a.emitNodeTransition("END", false, state, yield) {
+					return
+				}
+				if !yield(a.runSummaryEvent(state, stats, time.Now()), nil) {
 					return
 				}
 
@@ -382,14 +499,82 @@ func (a *AstonishAgent) Run(ctx agent.InvocationContext) iter.Seq2[*session.Even
 				return
 			}
 
+			// Refresh the MCP health flags so the node and its edges see them
+			a.refreshMCPHealth(state, pendingStateDelta)
+
+			// Step-through debugging: the user may skip the node, change
+			// state or stop the run before the node starts
+			if a.Debugger != nil {
+				switch a.stepNode(ctx, node, state, pendingStateDelta) {
+				case StepSkip:
+					nextNode, err := a.getNextNode(currentNodeName, state)
+					if err != nil {
+						yield(nil, err)
+						return
+					}
+					currentNodeName = nextNode
+					continue
+				case StepAbort:
+					yield(stepAbortedEvent(currentNodeName), nil)
+					return
+				}
+			}
+
+			// A node with an on_error handler continues there when it fails;
+			// errors it reports are trapped instead of ending the run
+			handler := a.Config.ErrorHandlerFor(node)
+			var nodeErr error
+			nodeYield := yield
+			if handler != "" {
+				nodeYield = func(event *session.Event, err error) bool {
+					if err != nil {
+						nodeErr = err
+						return false
+					}
+					return yield(event, nil)
+				}
+			}
+			onFailure := func() bool {
+				if handler == "" || ctx.Err() != nil {
+					return false
+				}
+				if nodeErr != nil {
+					recordNodeError(state, currentNodeName, nodeErr)
+				} else if hasError, _ := state.Get("_has_error"); hasError != true {
+					return false // Paused (e.g. awaiting approval), not failed
+				}
+				slog.Info("node failed, continuing at its error handler", "node", currentNodeName, "on_error", handler)
+				enterErrorHandler(state, handler)
+				currentNodeName = handler
+				return true
+			}
+
 			// Emit node transition before processing
-			if !a.emitNodeTransition(currentNodeName, state, yield) {
+			resumed := currentNodeName == resumedNode
+			resumedNode = ""
+
+			// A node that keeps running is in a loop whose exit never holds
+			if limit := a.maxNodeVisits(); !resumed && limit > 0 && stats.visits(currentNodeName) >= limit {
+				slog.Warn("loop detected, stopping the run", "node", currentNodeName, "max_node_visits", limit)
+				recordNodeError(state, currentNodeName, fmt.Errorf("max_node_visits (%d) reached", limit))
+				if !yield(loopDetectedEvent(currentNodeName, limit, stats.loopTo(currentNodeName)), nil) {
+					return
+				}
+				currentNodeName = "END"
+				continue
+			}
+
+			if !a.emitNodeTransition(currentNodeName, resumed, state, yield) {
 				return
 			}
+			ranNode = currentNodeName
 
 			// Check for Parallel execution
 			if node.Parallel != nil {
-				if !a.handleParallelNode(ctx, node, state, yield) {
+				if !a.handleParallelNode(ctx, node, state, nodeYield) {
+					if onFailure() {
+						continue
+					}
 					return
 				}
 
@@ -404,63 +589,10 @@ func (a *AstonishAgent) Run(ctx agent.InvocationContext) iter.Seq2[*session.Even
 			}
 
 			if node.Type == "input" {
-				// Render prompt
-				prompt := a.renderString(node.Prompt, state)
-
-				// Resolve options if present
-				var inputOptions []string
-				if len(node.Options) > 0 {
-					for _, opt := range node.Options {
-						// Check if option is a state variable
-						if val, err := state.Get(opt); err == nil {
-							// If it's a list of strings, expand it
-							if list, ok := val.([]string); ok {
-								inputOptions = append(inputOptions, list...)
-								continue
-							}
-							// If it's a generic list, try to convert elements to strings
-							if list, ok := val.([]interface{}); ok {
-								for _, item := range list {
-									inputOptions = append(inputOptions, fmt.Sprintf("%v", item))
-								}
-								continue
-							}
-							// If it's a single string (LLM returned one item as string instead of array),
-							// treat it as a single option
-							if strVal, ok := val.(string); ok && strings.TrimSpace(strVal) != "" {
-								inputOptions = append(inputOptions, strings.TrimSpace(strVal))
-								continue
-							}
-						}
-						// Otherwise treat as literal option
-						inputOptions = append(inputOptions, opt)
-					}
-				}
-
-				// Yield prompt event and update state
-				promptEvent := &session.Event{
-					LLMResponse: model.LLMResponse{
-						Content: &genai.Content{
-							Parts: []*genai.Part{{Text: prompt}},
-							Role:  "model",
-						},
-					},
-					Actions: session.EventActions{
-						StateDelta: map[string]any{
-							"current_node":      currentNodeName,
-							"input_options":     inputOptions,
-							"waiting_for_input": true,
-						},
-					},
-				}
-
-				if !yield(promptEvent, nil) {
-					return
-				}
-
+				yield(a.inputPromptEvent(node, state, ""), nil)
 				return
 			} else if node.Type == "llm" {
-				success := a.executeLLMNode(ctx, node, currentNodeName, state, yield)
+				success := a.executeLLMNode(ctx, node, currentNodeName, state, nodeYield)
 
 				// Check if node failed and set error flag
 				if !success {
@@ -470,6 +602,12 @@ func (a *AstonishAgent) Run(ctx agent.InvocationContext) iter.Seq2[*session.Even
 						// Error occurred and was handled by retry logic
 						// Check if we should stop or continue
 						// For now, transition to END to stop execution
+						if onFailure() {
+							continue
+						}
+						if ctx.Err() != nil {
+							return
+						}
 						if a.DebugMode {
 							slog.Debug("node failed with error, transitioning to END", "node", currentNodeName)
 						}
@@ -477,10 +615,18 @@ func (a *AstonishAgent) Run(ctx agent.InvocationContext) iter.Seq2[*session.Even
 						continue
 					}
 					// Node failed but no error flag - this shouldn't happen, but handle it
+					if onFailure() {
+						continue
+					}
 					return
 				}
 
-				// Node succeeded - move to next node
+				// Node succeeded - move to next node, unless a fallback
+				// (on_content_filter: route) picked one
+				if target := takeRoute(state); target != "" {
+					currentNodeName = target
+					continue
+				}
 				nextNode, err := a.getNextNode(currentNodeName, state)
 				if err != nil {
 					yield(nil, err)
@@ -490,21 +636,32 @@ func (a *AstonishAgent) Run(ctx agent.InvocationContext) iter.Seq2[*session.Even
 				// Don't emit transition here - the main loop will do it
 
 			} else if node.Type == "tool" {
-				success := a.handleToolNode(ctx, node, state, yield)
+				success := a.handleToolNode(ctx, node, state, nodeYield)
 
 				// Check if node failed and set error flag (same pattern as LLM nodes)
 				if !success {
 					// Check if this failure should stop execution
 					hasError, _ := state.Get("_has_error")
 					if hasErrorBool, ok := hasError.(bool); ok && hasErrorBool {
-						// Error occurred and was handled - transition to END
+						// Error occurred and was handled - continue at the
+						// error handler, or transition to END
+						if onFailure() {
+							continue
+						}
+						if ctx.Err() != nil {
+							return
+						}
 						if a.DebugMode {
 							slog.Debug("tool node failed with error, transitioning to END", "node", currentNodeName)
 						}
 						currentNodeName = "END"
 						continue
 					}
-					// Node failed but no error flag - this is a pause (e.g., awaiting approval)
+					// Node failed but no error flag - this is a pause (e.g.,
+					// awaiting approval), unless the node reported an error
+					if onFailure() {
+						continue
+					}
 					return
 				}
 
@@ -518,7 +675,61 @@ func (a *AstonishAgent) Run(ctx agent.InvocationContext) iter.Seq2[*session.Even
 				// Don't emit transition here - the main loop will do it
 
 			} else if node.Type == "update_state" {
-				if !a.handleUpdateStateNode(ctx, node, state, yield) {
+				if !a.handleUpdateStateNode(ctx, node, state, nodeYield) {
+					if onFailure() {
+						continue
+					}
+					return
+				}
+
+				// Move to next node
+				nextNode, err := a.getNextNode(currentNodeName, state)
+				if err != nil {
+					yield(nil, err)
+					return
+				}
+				currentNodeName = nextNode
+				// Don't emit transition here - the main loop will do it
+
+			} else if node.Type == "memory" {
+				if !a.handleMemoryNode(ctx, node, state, nodeYield) {
+					if onFailure() {
+						continue
+					}
+					return
+				}
+
+				// Move to next node
+				nextNode, err := a.getNextNode(currentNodeName, state)
+				if err != nil {
+					yield(nil, err)
+					return
+				}
+				currentNodeName = nextNode
+				// Don't emit transition here - the main loop will do it
+
+			} else if node.Type == "vector" {
+				if !a.handleVectorNode(ctx, node, state, nodeYield) {
+					if onFailure() {
+						continue
+					}
+					return
+				}
+
+				// Move to next node
+				nextNode, err := a.getNextNode(currentNodeName, state)
+				if err != nil {
+					yield(nil, err)
+					return
+				}
+				currentNodeName = nextNode
+				// Don't emit transition here - the main loop will do it
+
+			} else if node.Type == "script" {
+				if !a.handleScriptNode(ctx, node, state, nodeYield) {
+					if onFailure() {
+						continue
+					}
 					return
 				}
 
@@ -532,7 +743,10 @@ func (a *AstonishAgent) Run(ctx agent.InvocationContext) iter.Seq2[*session.Even
 				// Don't emit transition here - the main loop will do it
 
 			} else if node.Type == "output" {
-				if !a.handleOutputNode(ctx, node, state, yield) {
+				if !a.handleOutputNode(ctx, node, state, nodeYield) {
+					if onFailure() {
+						continue
+					}
 					return
 				}
 
@@ -541,6 +755,12 @@ func (a *AstonishAgent) Run(ctx agent.InvocationContext) iter.Seq2[*session.Even
 				// might be processed before the output content is fully flushed.
 				time.Sleep(50 * time.Millisecond)
 
+				// Wait for Accept/Reject; the answer is stored when the run resumes
+				if node.Confirm != "" {
+					yield(a.inputPromptEvent(node, state, ""), nil)
+					return
+				}
+
 				// Move to next node
 				nextNode, err := a.getNextNode(currentNodeName, state)
 				if err != nil {
@@ -550,6 +770,12 @@ func (a *AstonishAgent) Run(ctx agent.InvocationContext) iter.Seq2[*session.Even
 				currentNodeName = nextNode
 				// Don't emit transition here - the main loop will do it
 
+			} else if node.Type == "abort" {
+				if !yield(abortEvent(currentNodeName, a.nodeAbort(node, state)), nil) {
+					return
+				}
+				currentNodeName = "END"
+
 			} else {
 				yield(nil, fmt.Errorf("unsupported node type: %s", node.Type))
 				return
@@ -307,109 +307,6 @@ func (m *MockInvocationContext) WithContext(ctx context.Context) agent.Invocatio
 func (m *MockInvocationContext) RequestConfirmation(hint string, payload any) error   { return nil }
 func (m *MockInvocationContext) ToolConfirmation() *toolconfirmation.ToolConfirmation { return nil }
 
-func TestReActFallbackTrigger(t *testing.T) {
-	t.Skip("Skip trigger path due to ADK llmagent nil deref in Flow.callLLM; covered by TestReActFallbackDirect")
-	// Setup
-	calls := 0
-	mockLLM := &MockLLM{
-		GenerateContentFunc: func(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
-			return func(yield func(*model.LLMResponse, error) bool) {
-				calls++
-				if calls == 1 {
-					fmt.Println("DEBUG: GenerateContent called, yielding error with non-nil response to satisfy ADK callbacks")
-					// Simulate OpenRouter 404 error, but provide a non-nil response to prevent nil deref in ADK after-model callbacks
-					yield(&model.LLMResponse{
-						Content: &genai.Content{
-							Parts: []*genai.Part{{Text: ""}},
-						},
-					}, fmt.Errorf("error, status code: 404, status: 404 Not Found, message: No endpoints found that support tool use"))
-				} else {
-					// Allow fallback planner to complete by returning a final answer
-					yield(&model.LLMResponse{
-						Content: &genai.Content{
-							Parts: []*genai.Part{{Text: "Final Answer: ok"}},
-						},
-					}, nil)
-				}
-			}
-		},
-	}
-
-	cfg := &config.AgentConfig{
-		Description: "Test Agent",
-		Nodes: []config.Node{
-			{
-				Name:   "test_node",
-				Type:   "llm",
-				Prompt: "Hello",
-				Tools:  false,
-				// ToolsSelection: []string{"mock_tool"},
-			},
-		},
-		Flow: []config.FlowItem{
-			{
-				From: "START",
-				To:   "test_node",
-			},
-			{
-				From: "test_node",
-				To:   "END",
-			},
-		},
-	}
-
-	// mockTool := &MockTool{
-	// 	NameFunc: func() string { return "mock_tool" },
-	// }
-	// tools := []tool.Tool{mockTool}
-
-	agent := &AstonishAgent{
-		Config: cfg,
-		LLM:    mockLLM,
-		// Tools:     tools,
-		DebugMode: true,
-	}
-
-	// Mock session service
-	state := NewMockState()
-
-	mockSession := &MockSessionService{
-		State: state,
-	}
-	agent.SessionService = mockSession
-
-	// Execute
-	ctx := &MockInvocationContext{
-		Context:  context.Background(),
-		StateVal: state,
-	}
-
-	// Run the agent and consume events safely using range over the sequence.
-	fmt.Println("DEBUG: Consuming iterator (range)...")
-	for event, err := range agent.Run(ctx) {
-		fmt.Printf("DEBUG: range received event=%v, err=%v\n", event, err)
-
-		// Check if fallback is set
-		val, _ := state.Get("_use_react_fallback")
-		if b, ok := val.(bool); ok && b {
-			fmt.Println("DEBUG: Fallback state set!")
-			break
-		}
-		// Do not fail immediately on interim errors; allow fallback flag to be set
-	}
-
-	// Verify
-	// Check state
-	val, err := state.Get("_use_react_fallback")
-	if err != nil {
-		t.Fatalf("Expected _use_react_fallback to be set")
-	}
-
-	if boolVal, ok := val.(bool); !ok || !boolVal {
-		t.Errorf("Expected _use_react_fallback to be true")
-	}
-}
-
 // MockSessionService implements session.Service
 type MockSessionService struct {
 	State session.State
@@ -0,0 +1,26 @@
+package agent
+
+import (
+	"log/slog"
+
+	"google.golang.org/adk/session"
+)
+
+// cancelRun records a run stopped by cancellation at nodeName: current_node
+// points at the node so running the session again without a message resumes
+// there, and a _cancelled event tells clients where the run stopped. The
+// failure the cancellation caused is not kept as a node error.
+func cancelRun(state session.State, nodeName string, yield func(*session.Event, error) bool) {
+	slog.Info("run cancelled", "node", nodeName)
+	state.Set("current_node", nodeName)
+	state.Set("_has_error", false)
+	yield(&session.Event{
+		Actions: session.EventActions{
+			StateDelta: map[string]any{
+				"current_node": nodeName,
+				"_has_error":   false,
+				"_cancelled":   map[string]any{"node": nodeName},
+			},
+		},
+	}, nil)
+}
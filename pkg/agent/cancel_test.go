@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/config"
+)
+
+func TestCancelledRunResumesAtNode(t *testing.T) {
+	cfg := &config.AgentConfig{
+		Nodes: []config.Node{
+			{Name: "first", Type: "script", Script: `x["count"] = 1`},
+			{Name: "second", Type: "script", Script: `x["count"] = x["count"] + 1`},
+		},
+		Flow: []config.FlowItem{
+			{From: "START", To: "first"},
+			{From: "first", To: "second"},
+			{From: "second", To: "END"},
+		},
+	}
+	a := &AstonishAgent{Config: cfg}
+	state := NewMockState()
+
+	// Cancel while the first node runs: the script is interrupted, so the
+	// node runs again on resume
+	runCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx := &MockInvocationContext{Context: runCtx, StateVal: state}
+	var cancelledAt any
+	for ev, err := range a.Run(ctx) {
+		if err != nil {
+			t.Fatalf("cancellation should not be reported as an error: %v", err)
+		}
+		if ev.Actions.StateDelta["current_node"] == "first" && ev.Actions.StateDelta["node_type"] != nil {
+			cancel()
+		}
+		if info, ok := ev.Actions.StateDelta["_cancelled"].(map[string]any); ok {
+			cancelledAt = info["node"]
+		}
+	}
+	if cancelledAt != "first" {
+		t.Fatalf("_cancelled node = %v, want first", cancelledAt)
+	}
+	if node, _ := state.Get("current_node"); node != "first" {
+		t.Fatalf("current_node = %v, want first", node)
+	}
+	if hasError, _ := state.Get("_has_error"); hasError == true {
+		t.Error("the interrupted node was recorded as failed")
+	}
+
+	// Running again without a message continues where the run stopped
+	ctx = &MockInvocationContext{Context: context.Background(), StateVal: state}
+	var nodes []string
+	for ev, err := range a.Run(ctx) {
+		if err != nil {
+			t.Fatalf("resume: %v", err)
+		}
+		if node, ok := ev.Actions.StateDelta["current_node"].(string); ok && ev.Actions.StateDelta["node_type"] != nil {
+			nodes = append(nodes, node)
+		}
+	}
+	if got := strings.Join(nodes, " "); got != "first second END" {
+		t.Errorf("resumed run visited %q", got)
+	}
+	if count := state.Data["count"]; count != int64(2) && count != 2 {
+		t.Errorf("count = %v (%T), want 2", count, count)
+	}
+}
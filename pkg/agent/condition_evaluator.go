@@ -1,13 +1,27 @@
 package agent
 
 import (
+	"context"
 	"fmt"
 	"reflect"
+	"regexp"
 	"strings"
 
+	starjson "go.starlark.net/lib/json"
 	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
 )
 
+// maxScriptSteps bounds the work of a script node so a runaway loop fails
+// instead of hanging the flow.
+const maxScriptSteps = 50_000_000
+
+// scriptFileOptions enables the Starlark language features script nodes may use.
+var scriptFileOptions = &syntax.FileOptions{Set: true, While: true, TopLevelControl: true, GlobalReassign: true, Recursion: true}
+
+// scriptIdentifier matches state keys usable as Starlark global names.
+var scriptIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
 // EvaluateCondition evaluates a Python-style condition using Starlark
 func EvaluateCondition(conditionStr string, state map[string]interface{}) (bool, error) {
 	// Strip "lambda x:" prefix if present
@@ -63,6 +77,65 @@ func EvaluateExpression(expr string, state map[string]interface{}) (interface{},
 	return fromStarlarkValue(val), nil
 }
 
+// ExecuteScript runs a Starlark program against a copy of state and returns
+// the state keys it changed. Like EvaluateExpression, state is available as
+// the mutable dict x and its top-level keys as read-only globals; assignments
+// to x["key"] are written back. Globals the script defines whose names are in
+// exports are returned too. print() goes to printFn when set.
+func ExecuteScript(ctx context.Context, script string, state map[string]interface{}, exports []string, printFn func(string)) (map[string]interface{}, error) {
+	starlarkDict := convertMapToStarlark(state)
+	predeclared := starlark.StringDict{
+		"x":    starlarkDict,
+		"json": starjson.Module,
+	}
+	for k, v := range state {
+		if _, reserved := predeclared[k]; !reserved && scriptIdentifier.MatchString(k) {
+			predeclared[k] = toStarlarkValue(v)
+		}
+	}
+
+	thread := &starlark.Thread{Name: "script"}
+	thread.SetMaxExecutionSteps(maxScriptSteps)
+	if printFn != nil {
+		thread.Print = func(_ *starlark.Thread, msg string) { printFn(msg) }
+	}
+	stop := context.AfterFunc(ctx, func() { thread.Cancel(ctx.Err().Error()) })
+	defer stop()
+
+	globals, err := starlark.ExecFileOptions(scriptFileOptions, thread, "<script>", script, predeclared)
+	if err != nil {
+		if evalErr, ok := err.(*starlark.EvalError); ok {
+			return nil, fmt.Errorf("script error: %s", evalErr.Backtrace())
+		}
+		return nil, fmt.Errorf("script error: %v", err)
+	}
+
+	updates := make(map[string]interface{})
+	for _, item := range starlarkDict.Items() {
+		key, ok := item[0].(starlark.String)
+		if !ok {
+			continue
+		}
+		value := fromStarlarkValue(item[1])
+		if old, existed := state[string(key)]; existed && reflect.DeepEqual(fromStarlarkValue(toStarlarkValue(old)), value) {
+			continue
+		}
+		updates[string(key)] = value
+	}
+	for _, name := range exports {
+		if v, ok := globals[name]; ok {
+			updates[name] = fromStarlarkValue(v)
+		}
+	}
+	return updates, nil
+}
+
+// ParseScript reports syntax errors of a script node's program.
+func ParseScript(script string) error {
+	_, err := scriptFileOptions.Parse("<script>", script, 0)
+	return err
+}
+
 // convertMapToStarlark converts a Go map to a Starlark dict
 func convertMapToStarlark(m map[string]interface{}) *starlark.Dict {
 	dict := starlark.NewDict(len(m))
@@ -103,6 +176,8 @@ func toStarlarkValue(v interface{}) starlark.Value {
 		return starlark.NewList(list)
 	case map[string]interface{}:
 		return convertMapToStarlark(val)
+	case starlark.Value:
+		return val
 	default:
 		// Use reflection to handle other slice types
 		rVal := reflect.ValueOf(val)
@@ -142,6 +217,12 @@ func fromStarlarkValue(v starlark.Value) interface{} {
 			list = append(list, fromStarlarkValue(item))
 		}
 		return list
+	case starlark.Tuple:
+		list := make([]interface{}, 0, len(val))
+		for _, item := range val {
+			list = append(list, fromStarlarkValue(item))
+		}
+		return list
 	case *starlark.Dict:
 		dict := make(map[string]interface{})
 		for _, item := range val.Keys() {
@@ -0,0 +1,105 @@
+package agent
+
+import (
+	"strings"
+
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/provider/llmerror"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// defaultRephraseInstruction is added to the system prompt when a node's
+// on_content_filter action is "rephrase" without its own instruction.
+const defaultRephraseInstruction = "Your previous answer was blocked by the provider's content safety filter. " +
+	"Answer again in neutral, factual and professional language. Do not quote or reproduce offensive, " +
+	"violent or sensitive material verbatim; describe or summarize it instead."
+
+// isContentFilterFinish reports whether a finish reason means the provider's
+// safety filter blocked the response.
+func isContentFilterFinish(reason genai.FinishReason) bool {
+	switch reason {
+	case genai.FinishReasonSafety, genai.FinishReasonBlocklist, genai.FinishReasonProhibitedContent,
+		genai.FinishReasonSPII, genai.FinishReasonImageSafety, genai.FinishReasonImageProhibitedContent:
+		return true
+	}
+	return false
+}
+
+// contentFilterEvent reports a blocked response. action is the node's
+// on_content_filter action, or "fail" when it has none.
+func contentFilterEvent(nodeName, action string, err error) *session.Event {
+	return &session.Event{
+		Actions: session.EventActions{
+			StateDelta: map[string]any{
+				"_content_filter": map[string]any{
+					"node":   nodeName,
+					"action": action,
+					"error":  err.Error(),
+				},
+			},
+		},
+	}
+}
+
+// handleContentFilter applies the node's on_content_filter fallback after
+// blocked stopped an attempt. handled is false when the node has no
+// fallback, leaving the failure to the retry loop. Otherwise success reports
+// whether the fallback produced a result (or routed to another node), and
+// err is the fallback attempt's failure.
+func (a *AstonishAgent) handleContentFilter(ctx agent.InvocationContext, node *config.Node, nodeName string, state session.State, blocked error, yield func(*session.Event, error) bool) (handled, success bool, err error) {
+	cfg := node.OnContentFilter
+	action := "fail"
+	if cfg != nil {
+		action = cfg.Action
+	}
+	if !yield(contentFilterEvent(nodeName, action, blocked), nil) || cfg == nil {
+		return cfg != nil, false, nil
+	}
+
+	fallback := *node
+	fallback.OnContentFilter = nil
+	switch cfg.Action {
+	case "rephrase":
+		instruction := cfg.Instruction
+		if instruction == "" {
+			instruction = defaultRephraseInstruction
+		}
+		if fallback.System != "" {
+			fallback.System += "\n\n"
+		}
+		fallback.System += instruction
+	case "switch_model":
+		fallback.Provider, fallback.Model = "", cfg.Model
+		if prefix, rest, ok := strings.Cut(cfg.Model, "/"); ok && a.AppConfig != nil && a.hasProvider(prefix) {
+			fallback.Provider, fallback.Model = prefix, rest
+		}
+	case "route":
+		// Hand over to e.g. a human-review node; the blocked error is
+		// available there as {_last_error}
+		state.Set("_last_error", blocked.Error())
+		state.Set("_error_node", nodeName)
+		state.Set("_error_class", string(llmerror.ClassContentFilter))
+		state.Set("_route_to", cfg.Node)
+		return true, true, nil
+	default:
+		return false, false, nil
+	}
+
+	ok, err := a.executeLLMNodeAttempt(ctx, &fallback, nodeName, state, yield)
+	return true, ok, err
+}
+
+// takeRoute returns and clears a node override set by a fallback (such as
+// on_content_filter: route), or "" when the flow continues normally. The
+// target is entered as the handler of the recorded error.
+func takeRoute(state session.State) string {
+	val, _ := state.Get("_route_to")
+	target, _ := val.(string)
+	if target != "" {
+		state.Set("_route_to", "")
+		enterErrorHandler(state, target)
+	}
+	return target
+}
@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/provider/llmerror"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+func TestHandleContentFilter_RouteAndFail(t *testing.T) {
+	a := NewAstonishAgent(&config.AgentConfig{}, nil, nil)
+	state := NewMockState()
+	ctx := &MockInvocationContext{Context: context.Background(), StateVal: state}
+	blocked := &llmerror.ContentFilterError{Provider: "openai", FinishReason: string(genai.FinishReasonSafety)}
+	if llmerror.Classify(blocked) != llmerror.ClassContentFilter {
+		t.Fatal("a blocked response should classify as content_filter")
+	}
+
+	var events []*session.Event
+	yield := func(ev *session.Event, err error) bool {
+		events = append(events, ev)
+		return true
+	}
+
+	node := &config.Node{Name: "draft", Type: "llm", OnContentFilter: &config.ContentFilterConfig{Action: "route", Node: "human_review"}}
+	handled, success, err := a.handleContentFilter(ctx, node, node.Name, state, blocked, yield)
+	if !handled || !success || err != nil {
+		t.Fatalf("route: handled=%v success=%v err=%v", handled, success, err)
+	}
+	if got := takeRoute(state); got != "human_review" {
+		t.Errorf("route target = %q, want human_review", got)
+	}
+	if takeRoute(state) != "" {
+		t.Error("a route should only be taken once")
+	}
+	if class, _ := state.Get("_error_class"); class != "content_filter" {
+		t.Errorf("_error_class = %v", class)
+	}
+	info := events[0].Actions.StateDelta["_content_filter"].(map[string]any)
+	if info["action"] != "route" || info["node"] != "draft" {
+		t.Errorf("event = %v", info)
+	}
+
+	// Without on_content_filter the failure is left to the retry loop
+	events = nil
+	handled, _, _ = a.handleContentFilter(ctx, &config.Node{Name: "plain"}, "plain", state, blocked, yield)
+	if handled {
+		t.Error("a node without on_content_filter should not be handled")
+	}
+	if info := events[0].Actions.StateDelta["_content_filter"].(map[string]any); info["action"] != "fail" {
+		t.Errorf("event action = %v, want fail", info["action"])
+	}
+}
@@ -0,0 +1,231 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/provider"
+	persistentsession "github.com/SAP/astonish/pkg/session"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+const (
+	// contextBudgetShare is the part of the model's context window the
+	// history of an LLM node may fill when the flow sets no context_budget;
+	// the rest is left to the instruction, tools and the answer.
+	contextBudgetShare = 0.5
+	// contextBudgetKeepEvents is the number of recent events that are never
+	// summarized.
+	contextBudgetKeepEvents = 4
+	// summaryAuthor is the author of the synthetic event that replaces the
+	// summarized history.
+	summaryAuthor = "context_summary"
+)
+
+// contextBudget limits the history an LLM node sends: when the events of the
+// node are estimated above Tokens, the older ones are replaced by a summary.
+type contextBudget struct {
+	Tokens int
+	LLM    model.LLM // Summarizes the older events (nil = truncation summary)
+}
+
+// historySummary is the summary of a session's events up to an event, kept
+// so the history is summarized once rather than on every model call.
+type historySummary struct {
+	firstID string
+	lastID  string
+	event   *session.Event
+}
+
+var (
+	historySummariesMu sync.Mutex
+	historySummaries   = make(map[string]historySummary) // by session ID
+)
+
+// nodeContextBudget returns the history budget of an LLM node: the node's
+// context_budget, the flow's, or a share of the model's context window.
+// Nil means no limit.
+func (a *AstonishAgent) nodeContextBudget(ctx context.Context, node *config.Node, llm model.LLM) *contextBudget {
+	tokens := node.ContextBudget
+	if tokens == 0 && a.Config != nil {
+		tokens = a.Config.ContextBudget
+	}
+	if tokens == 0 && a.AppConfig != nil && a.ProviderName != "" {
+		providerName, modelName := a.ProviderName, a.ModelName
+		if node.Provider != "" {
+			providerName = node.Provider
+		}
+		if node.Model != "" {
+			modelName = node.Model
+		}
+		tokens = int(float64(provider.ResolveContextWindowCached(ctx, providerName, modelName, a.AppConfig)) * contextBudgetShare)
+	}
+	if tokens <= 0 {
+		return nil
+	}
+	return &contextBudget{Tokens: tokens, LLM: llm}
+}
+
+// fit returns events unchanged when they fit the budget. Otherwise the
+// oldest events, all but the most recent ones that fit half the budget, are
+// replaced by one synthetic event summarizing them.
+func (b *contextBudget) fit(ctx context.Context, sessionID string, events session.Events) session.Events {
+	all := make([]*session.Event, 0, events.Len())
+	for ev := range events.All() {
+		all = append(all, ev)
+	}
+	if len(all) <= contextBudgetKeepEvents || estimateEventTokens(all) <= b.Tokens {
+		return events
+	}
+
+	// Keep the most recent events within half the budget, so the summary is
+	// not redone on every call as the node adds events
+	split := len(all) - contextBudgetKeepEvents
+	recent := estimateEventTokens(all[split:])
+	for split > 0 {
+		tokens := estimateEventTokens(all[split-1 : split])
+		if recent+tokens > b.Tokens/2 {
+			break
+		}
+		recent += tokens
+		split--
+	}
+	// Never separate tool responses from their calls
+	for split > 0 && hasFunctionResponse(all[split]) {
+		split--
+	}
+	if split == 0 {
+		return events
+	}
+
+	summary := b.summarize(ctx, sessionID, all[:split])
+	fitted := make([]*session.Event, 0, len(all)-split+1)
+	fitted = append(fitted, summary)
+	fitted = append(fitted, all[split:]...)
+	return eventList(fitted)
+}
+
+// summarize returns the synthetic summary event for old, extending the
+// session's previous summary when old starts with the same events.
+func (b *contextBudget) summarize(ctx context.Context, sessionID string, old []*session.Event) *session.Event {
+	firstID, lastID := old[0].ID, old[len(old)-1].ID
+
+	cacheable := firstID != "" && lastID != ""
+
+	historySummariesMu.Lock()
+	prev, ok := historySummaries[sessionID]
+	historySummariesMu.Unlock()
+	ok = ok && cacheable
+	if ok && prev.firstID == firstID && prev.lastID == lastID {
+		return prev.event
+	}
+
+	var contents []*genai.Content
+	rest := old
+	if ok && prev.firstID == firstID {
+		for i, ev := range old {
+			if ev.ID == prev.lastID {
+				contents = append(contents, prev.event.Content)
+				rest = old[i+1:]
+				break
+			}
+		}
+	}
+	for _, ev := range rest {
+		if ev.Content != nil {
+			contents = append(contents, ev.Content)
+		}
+	}
+
+	compactor := persistentsession.NewCompactor(b.Tokens)
+	if b.LLM != nil {
+		compactor.LLM = llmTextFunc(b.LLM)
+	}
+	summary := fmt.Sprintf("[Context Summary — %d earlier events summarized to fit the context budget]\n\n%s",
+		len(old), compactor.Summarize(ctx, contents))
+
+	slog.Info("history over the context budget, summarized older events",
+		"component", "context_budget", "session", sessionID, "events", len(old), "budget", b.Tokens)
+
+	event := session.NewEvent("")
+	event.Author = summaryAuthor
+	event.Content = genai.NewContentFromText(summary, genai.RoleUser)
+
+	if cacheable {
+		historySummariesMu.Lock()
+		historySummaries[sessionID] = historySummary{firstID: firstID, lastID: lastID, event: event}
+		historySummariesMu.Unlock()
+	}
+	return event
+}
+
+// estimateEventTokens estimates the tokens of the events' contents.
+func estimateEventTokens(events []*session.Event) int {
+	contents := make([]*genai.Content, 0, len(events))
+	for _, ev := range events {
+		if ev.Content != nil {
+			contents = append(contents, ev.Content)
+		}
+	}
+	return persistentsession.EstimateTokens(contents)
+}
+
+func hasFunctionResponse(ev *session.Event) bool {
+	if ev.Content == nil {
+		return false
+	}
+	for _, p := range ev.Content.Parts {
+		if p.FunctionResponse != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// llmTextFunc adapts a model to the prompt-to-text function summarizers use.
+func llmTextFunc(llm model.LLM) persistentsession.LLMFunc {
+	return func(ctx context.Context, prompt string) (string, error) {
+		req := &model.LLMRequest{
+			Model:    llm.Name(),
+			Contents: []*genai.Content{genai.NewContentFromText(prompt, genai.RoleUser)},
+		}
+		var text strings.Builder
+		for resp, err := range llm.GenerateContent(ctx, req, false) {
+			if err != nil {
+				return "", err
+			}
+			if resp.Content == nil {
+				continue
+			}
+			for _, part := range resp.Content.Parts {
+				if !part.Thought {
+					text.WriteString(part.Text)
+				}
+			}
+		}
+		return text.String(), nil
+	}
+}
+
+// eventList is a session.Events over a slice.
+type eventList []*session.Event
+
+func (l eventList) All() iter.Seq[*session.Event] {
+	return func(yield func(*session.Event) bool) {
+		for _, ev := range l {
+			if !yield(ev) {
+				return
+			}
+		}
+	}
+}
+
+func (l eventList) Len() int                { return len(l) }
+func (l eventList) At(i int) *session.Event { return l[i] }
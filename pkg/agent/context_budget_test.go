@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+func budgetTestEvents(n int) []*session.Event {
+	events := make([]*session.Event, 0, n)
+	for i := 0; i < n; i++ {
+		ev := session.NewEvent("inv")
+		ev.ID = fmt.Sprintf("ev-%d", i)
+		ev.Author = "node"
+		ev.Content = genai.NewContentFromText(fmt.Sprintf("step %d: %s", i, strings.Repeat("x", 3000)), genai.RoleModel)
+		events = append(events, ev)
+	}
+	return events
+}
+
+func TestContextBudgetFit(t *testing.T) {
+	ctx := context.Background()
+
+	// 12 events of ~1000 tokens each
+	events := budgetTestEvents(12)
+	small := &contextBudget{Tokens: 20000}
+	if got := small.fit(ctx, "budget-fits", eventList(events)); got.Len() != 12 {
+		t.Fatalf("events within the budget were changed: %d events", got.Len())
+	}
+
+	budget := &contextBudget{Tokens: 8000}
+	fitted := budget.fit(ctx, "budget-over", eventList(events))
+	if fitted.Len() < 2 || fitted.At(0).Author != summaryAuthor {
+		t.Fatalf("expected a summary event first, got %d events", fitted.Len())
+	}
+	if got := estimateEventTokens(collectEvents(fitted)); got > budget.Tokens {
+		t.Errorf("fitted history is %d tokens, over the %d budget", got, budget.Tokens)
+	}
+	if last := fitted.At(fitted.Len() - 1); last.ID != "ev-11" {
+		t.Errorf("the most recent event must be kept, got %s", last.ID)
+	}
+
+	// The summary is reused while the summarized events do not change
+	again := budget.fit(ctx, "budget-over", eventList(events))
+	if again.At(0) != fitted.At(0) {
+		t.Error("expected the cached summary event")
+	}
+}
+
+func TestContextBudgetKeepsToolPairs(t *testing.T) {
+	events := budgetTestEvents(10)
+	// Make the first kept event a tool response; its call must stay with it
+	call := session.NewEvent("inv")
+	call.ID = "call"
+	call.Content = &genai.Content{Role: genai.RoleModel, Parts: []*genai.Part{{FunctionCall: &genai.FunctionCall{ID: "1", Name: "read_file"}}}}
+	resp := session.NewEvent("inv")
+	resp.ID = "resp"
+	resp.Content = &genai.Content{Role: genai.RoleUser, Parts: []*genai.Part{{FunctionResponse: &genai.FunctionResponse{ID: "1", Name: "read_file", Response: map[string]any{"content": "ok"}}}}}
+	events = append(events[:5], append([]*session.Event{call, resp}, events[5:8]...)...)
+
+	fitted := (&contextBudget{Tokens: 7000}).fit(context.Background(), "budget-pairs", eventList(events))
+	for i := 0; i < fitted.Len(); i++ {
+		if fitted.At(i).ID == "resp" && (i == 0 || fitted.At(i-1).ID != "call") {
+			t.Fatal("tool response kept without its call")
+		}
+	}
+}
+
+func collectEvents(events session.Events) []*session.Event {
+	out := make([]*session.Event, 0, events.Len())
+	for ev := range events.All() {
+		out = append(out, ev)
+	}
+	return out
+}
@@ -0,0 +1,151 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"log/slog"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/SAP/astonish/pkg/config"
+	persistentsession "github.com/SAP/astonish/pkg/session"
+	"github.com/SAP/astonish/pkg/ui"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/session"
+)
+
+// overflowValueChars is the size a state value rendered into the prompt is
+// cut to when a node is retried after a context overflow.
+const overflowValueChars = 8000
+
+// placeholderKeyRe matches {key} placeholders that name a state key directly.
+var placeholderKeyRe = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// contextRecoveryKey marks the context of an attempt that retries after a
+// context overflow; executeLLMNodeAttempt then compresses the history.
+type contextRecoveryKey struct{}
+
+// recoverContextOverflow retries a node once after the provider rejected its
+// request as larger than the context window. Unlike a plain retry, the
+// attempt truncates the largest state values rendered into the prompt and
+// compresses the conversation history (oversized tool responses are cut and
+// older turns replaced by a summary). The recovery is recorded as a
+// _context_recovery event, so it shows up in the transcript.
+func (a *AstonishAgent) recoverContextOverflow(ctx agent.InvocationContext, node *config.Node, nodeName string, state session.State, overflow error, yield func(*session.Event, error) bool) (bool, error) {
+	overrides := truncatedPlaceholders(node, state)
+	truncated := make([]string, 0, len(overrides))
+	for key := range overrides {
+		truncated = append(truncated, key)
+	}
+	sort.Strings(truncated)
+
+	slog.Info("context overflow, compressing context and retrying once",
+		"component", "retry", "node", nodeName, "truncated_keys", truncated, "error", overflow)
+
+	if !yield(&session.Event{
+		Actions: session.EventActions{
+			StateDelta: map[string]any{
+				"_context_recovery": map[string]any{
+					"node":           nodeName,
+					"truncated_keys": truncated,
+					"history":        "compressed",
+					"error":          overflow.Error(),
+				},
+				"_processing_info": true,
+			},
+		},
+	}, nil) {
+		return false, nil
+	}
+
+	recoveryCtx := ctx.WithContext(context.WithValue(ctx, contextRecoveryKey{}, true))
+	return a.executeLLMNodeAttempt(recoveryCtx, node, nodeName, &overlayState{State: state, values: overrides}, yield)
+}
+
+// truncatedPlaceholders returns cut-down versions of the state values the
+// node's prompt and system instruction reference that are larger than
+// overflowValueChars.
+func truncatedPlaceholders(node *config.Node, state session.State) map[string]any {
+	overrides := make(map[string]any)
+	for _, text := range []string{node.Prompt, node.System} {
+		for _, m := range placeholderKeyRe.FindAllStringSubmatch(text, -1) {
+			key := m[1]
+			if _, done := overrides[key]; done {
+				continue
+			}
+			val, err := state.Get(key)
+			if err != nil || val == nil {
+				continue
+			}
+			rendered, ok := val.(string)
+			if !ok {
+				rendered = ui.FormatAsYamlLike(val, 0)
+			}
+			if len(rendered) <= overflowValueChars {
+				continue
+			}
+			overrides[key] = rendered[:overflowValueChars] + fmt.Sprintf(
+				"\n... [truncated %d characters to fit the model's context window]", len(rendered)-overflowValueChars)
+		}
+	}
+	return overrides
+}
+
+// contextRecoveryCallbacks returns the history compression callbacks for an
+// attempt started by recoverContextOverflow, and nil otherwise.
+func contextRecoveryCallbacks(ctx context.Context) []llmagent.BeforeModelCallback {
+	if recovering, _ := ctx.Value(contextRecoveryKey{}).(bool); !recovering {
+		return nil
+	}
+	// A one-token window makes every request exceed the threshold; without
+	// an LLM the compactor summarizes by truncation, so recovery does not
+	// itself send a large request.
+	compactor := persistentsession.NewCompactor(1)
+	return []llmagent.BeforeModelCallback{
+		TruncateToolResponsesCallback(),
+		compactor.BeforeModelCallback(),
+	}
+}
+
+// overlayState shows overridden values for some keys on top of a session
+// state. Setting an overridden key drops the override.
+type overlayState struct {
+	session.State
+	mu     sync.Mutex
+	values map[string]any
+}
+
+func (o *overlayState) Get(key string) (any, error) {
+	o.mu.Lock()
+	val, ok := o.values[key]
+	o.mu.Unlock()
+	if ok {
+		return val, nil
+	}
+	return o.State.Get(key)
+}
+
+func (o *overlayState) Set(key string, value any) error {
+	o.mu.Lock()
+	delete(o.values, key)
+	o.mu.Unlock()
+	return o.State.Set(key, value)
+}
+
+func (o *overlayState) All() iter.Seq2[string, any] {
+	return func(yield func(string, any) bool) {
+		for key, val := range o.State.All() {
+			o.mu.Lock()
+			if v, ok := o.values[key]; ok {
+				val = v
+			}
+			o.mu.Unlock()
+			if !yield(key, val) {
+				return
+			}
+		}
+	}
+}
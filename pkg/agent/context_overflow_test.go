@@ -0,0 +1,49 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/config"
+)
+
+func TestTruncatedPlaceholders(t *testing.T) {
+	state := NewMockState()
+	state.Set("diff", strings.Repeat("x", overflowValueChars*3))
+	state.Set("title", "Fix login")
+
+	node := &config.Node{Prompt: "Review {title}:\n{diff}\nAgain: {diff}"}
+	overrides := truncatedPlaceholders(node, state)
+	if len(overrides) != 1 {
+		t.Fatalf("overrides = %v, want only the oversized diff", overrides)
+	}
+	diff, _ := overrides["diff"].(string)
+	if len(diff) > overflowValueChars+100 || !strings.Contains(diff, "truncated") {
+		t.Errorf("diff was not truncated: %d chars", len(diff))
+	}
+
+	// The overlay shows the truncated value without changing state, until
+	// the key is written again
+	overlay := &overlayState{State: state, values: overrides}
+	if got, _ := overlay.Get("diff"); got != diff {
+		t.Error("overlay should return the truncated value")
+	}
+	if got, _ := state.Get("diff"); len(got.(string)) != overflowValueChars*3 {
+		t.Error("state should keep the full value")
+	}
+	overlay.Set("diff", "new")
+	if got, _ := overlay.Get("diff"); got != "new" {
+		t.Errorf("after Set overlay diff = %v, want new", got)
+	}
+}
+
+func TestContextRecoveryCallbacks(t *testing.T) {
+	if cbs := contextRecoveryCallbacks(context.Background()); cbs != nil {
+		t.Error("normal attempts should not compress history")
+	}
+	ctx := context.WithValue(context.Background(), contextRecoveryKey{}, true)
+	if cbs := contextRecoveryCallbacks(ctx); len(cbs) != 2 {
+		t.Errorf("recovery attempt callbacks = %d, want 2", len(cbs))
+	}
+}
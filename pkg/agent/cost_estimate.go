@@ -0,0 +1,203 @@
+package agent
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/adk/session"
+)
+
+// defaultModelPricing holds list prices (USD per million tokens) for common
+// models, matched by longest name prefix. cost_guard.pricing overrides it.
+var defaultModelPricing = map[string]config.ModelPrice{
+	"gpt-4o-mini":       {Input: 0.15, Output: 0.6},
+	"gpt-4o":            {Input: 2.5, Output: 10},
+	"gpt-4.1-nano":      {Input: 0.1, Output: 0.4},
+	"gpt-4.1-mini":      {Input: 0.4, Output: 1.6},
+	"gpt-4.1":           {Input: 2, Output: 8},
+	"gpt-4-turbo":       {Input: 10, Output: 30},
+	"gpt-4":             {Input: 30, Output: 60},
+	"gpt-3.5-turbo":     {Input: 0.5, Output: 1.5},
+	"o1-mini":           {Input: 1.1, Output: 4.4},
+	"o1":                {Input: 15, Output: 60},
+	"o3-mini":           {Input: 1.1, Output: 4.4},
+	"o3":                {Input: 2, Output: 8},
+	"o4-mini":           {Input: 1.1, Output: 4.4},
+	"claude-3-opus":     {Input: 15, Output: 75},
+	"claude-opus":       {Input: 15, Output: 75},
+	"claude-3-5-sonnet": {Input: 3, Output: 15},
+	"claude-3-7-sonnet": {Input: 3, Output: 15},
+	"claude-sonnet":     {Input: 3, Output: 15},
+	"claude-3-5-haiku":  {Input: 0.8, Output: 4},
+	"claude-3-haiku":    {Input: 0.25, Output: 1.25},
+	"claude-haiku":      {Input: 0.8, Output: 4},
+	"gemini-1.5-pro":    {Input: 1.25, Output: 5},
+	"gemini-1.5-flash":  {Input: 0.075, Output: 0.3},
+	"gemini-2.0-flash":  {Input: 0.1, Output: 0.4},
+	"gemini-2.5-pro":    {Input: 1.25, Output: 10},
+	"gemini-2.5-flash":  {Input: 0.3, Output: 2.5},
+	"grok-":             {Input: 3, Output: 15},
+}
+
+// Rough per-call token assumptions for the estimate. Prompts are measured;
+// everything the model adds (history, tool results, answer) is guessed.
+const (
+	estimateCharsPerToken      = 4
+	estimateInstructionTokens  = 500  // system prompt scaffolding per call
+	estimateToolDefTokens      = 2000 // tool declarations when tools are enabled
+	estimateToolCallsPerNode   = 3    // model round-trips for a tool-using node
+	estimateDefaultOutputToken = 1000
+)
+
+// costLine is the estimate for one node.
+type costLine struct {
+	Node         string
+	Model        string
+	Calls        int
+	InputTokens  int // per call
+	OutputTokens int // per call
+	Cost         float64
+	Priced       bool
+	FanOut       string // "" or a note about the parallel fan-out
+}
+
+// costEstimate is a breakdown of a run's expected LLM cost.
+type costEstimate struct {
+	Lines []costLine
+	Total float64
+}
+
+// modelPrice looks up a model's price: cost_guard.pricing first (exact
+// name), then the built-in table by longest prefix. Provider prefixes such
+// as "openai/" are ignored.
+func (a *AstonishAgent) modelPrice(modelName string) (config.ModelPrice, bool) {
+	if a.AppConfig != nil {
+		if p, ok := a.AppConfig.CostGuard.Pricing[modelName]; ok {
+			return p, true
+		}
+	}
+	name := strings.ToLower(modelName)
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	best := ""
+	for prefix := range defaultModelPricing {
+		if strings.HasPrefix(name, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return config.ModelPrice{}, false
+	}
+	return defaultModelPricing[best], true
+}
+
+// estimateNodeCost estimates one LLM node run `runs` times.
+func (a *AstonishAgent) estimateNodeCost(node *config.Node, state session.State, runs int) costLine {
+	modelName := a.nodeModelName(node)
+
+	promptChars := len(a.renderNodeString(node, node.System, state)) + len(a.renderNodeString(node, node.Prompt, state)) + len(node.RawContext) + len(a.Config.GlossaryFor(node))
+	input := promptChars/estimateCharsPerToken + estimateInstructionTokens
+	output := estimateDefaultOutputToken
+	if node.Generation != nil && node.Generation.MaxOutputTokens > 0 {
+		output = int(node.Generation.MaxOutputTokens)
+	}
+	calls := runs
+	if node.Tools {
+		input += estimateToolDefTokens
+		calls *= estimateToolCallsPerNode
+	}
+
+	line := costLine{Node: node.Name, Model: modelName, Calls: calls, InputTokens: input, OutputTokens: output}
+	a.priceLine(&line)
+	return line
+}
+
+// priceLine sets the line's cost from its model's price, if known.
+func (a *AstonishAgent) priceLine(l *costLine) {
+	if price, ok := a.modelPrice(l.Model); ok {
+		l.Priced = true
+		l.Cost = float64(l.Calls) * (float64(l.InputTokens)*price.Input + float64(l.OutputTokens)*price.Output) / 1e6
+	}
+}
+
+// estimateParallelCost estimates a parallel node over items, including the
+// reduce calls when reduce instructions are set.
+func (a *AstonishAgent) estimateParallelCost(node *config.Node, state session.State, items int) costEstimate {
+	var est costEstimate
+	line := a.estimateNodeCost(node, state, items)
+	line.FanOut = fmt.Sprintf("× %d items", items)
+	est.add(line)
+
+	if node.Type == "llm" && node.Parallel.Reduce != "" {
+		chunks := len(chunkParallelItems(make([]any, items), node.Parallel.MaxItems))
+		reduce := a.estimateNodeCost(&config.Node{Name: node.Name + " (reduce)", Model: node.Model, Generation: node.Generation}, state, chunks)
+		reduce.InputTokens += line.OutputTokens * items / chunks
+		if chunks > 1 {
+			reduce.Calls++
+		}
+		a.priceLine(&reduce)
+		est.add(reduce)
+	}
+	return est
+}
+
+// estimateFlowCost estimates one pass over every LLM node in the flow.
+// Parallel nodes whose list is already in state are multiplied out; others
+// are counted once and re-checked when they run.
+func (a *AstonishAgent) estimateFlowCost(state session.State) costEstimate {
+	var est costEstimate
+	for i := range a.Config.Nodes {
+		node := &a.Config.Nodes[i]
+		if node.Type != "llm" {
+			continue
+		}
+		if node.Parallel == nil {
+			est.add(a.estimateNodeCost(node, state, 1))
+			continue
+		}
+		items, known := 1, false
+		if v, err := state.Get(strings.Trim(node.Parallel.ForEach, "{}")); err == nil {
+			if list, ok := v.([]any); ok {
+				items, known = len(list), true
+			}
+		}
+		sub := a.estimateParallelCost(node, state, items)
+		if !known {
+			sub.Lines[0].FanOut = "× list size (checked at run time)"
+		}
+		for _, l := range sub.Lines {
+			est.add(l)
+		}
+	}
+	return est
+}
+
+func (e *costEstimate) add(l costLine) {
+	e.Lines = append(e.Lines, l)
+	e.Total += l.Cost
+}
+
+// String renders the breakdown shown in the confirmation prompt, most
+// expensive nodes first.
+func (e costEstimate) String() string {
+	lines := append([]costLine(nil), e.Lines...)
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].Cost > lines[j].Cost })
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Estimated cost: ~$%.2f\n", e.Total)
+	for _, l := range lines {
+		cost := "unknown pricing"
+		if l.Priced {
+			cost = fmt.Sprintf("$%.2f", l.Cost)
+		}
+		fmt.Fprintf(&sb, "  - %s: %d call(s) × ~%d in / ~%d out tokens on %s = %s", l.Node, l.Calls, l.InputTokens, l.OutputTokens, l.Model, cost)
+		if l.FanOut != "" {
+			fmt.Fprintf(&sb, " (%s)", l.FanOut)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,130 @@
+package agent
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// confirmCost pauses the run for a Yes/No confirmation when est exceeds the
+// cost_guard threshold. scope is "flow" for the whole run or the name of a
+// parallel node; a confirmation given for a scope is consumed here. It
+// returns true when execution may proceed.
+func (a *AstonishAgent) confirmCost(state session.State, scope string, est costEstimate, yield func(*session.Event, error) bool) bool {
+	if a.AppConfig == nil || !a.AppConfig.CostGuard.IsEnabled() {
+		return true
+	}
+	threshold := a.AppConfig.CostGuard.GetThreshold()
+	if est.Total <= threshold {
+		return true
+	}
+
+	confirmedKey := "_cost_confirmed:" + scope
+	if confirmed, _ := state.Get(confirmedKey); confirmed == true {
+		state.Set(confirmedKey, false)
+		return true
+	}
+
+	if a.AutoApprove {
+		slog.Warn("estimated cost exceeds cost_guard threshold, continuing (auto-approve)",
+			"scope", scope, "estimate", est.Total, "threshold", threshold)
+		return true
+	}
+
+	currentNode, _ := state.Get("current_node")
+	if currentNode == nil {
+		currentNode = "START"
+	}
+	state.Set("awaiting_cost_confirmation", true)
+	state.Set("_cost_scope", scope)
+
+	prompt := est.String() + fmt.Sprintf("\nThis is above the cost_guard threshold of $%.2f. Continue?", threshold)
+	yield(&session.Event{
+		LLMResponse: model.LLMResponse{
+			Content: &genai.Content{
+				Parts: []*genai.Part{{Text: prompt}},
+				Role:  "model",
+			},
+		},
+		Actions: session.EventActions{
+			StateDelta: map[string]any{
+				"current_node":               currentNode,
+				"awaiting_cost_confirmation": true,
+				"_cost_scope":                scope,
+				"_cost_estimate":             est.Total,
+				"input_options":              []string{"Yes", "No"},
+				"waiting_for_input":          true,
+			},
+		},
+	}, nil)
+	return false
+}
+
+// handleCostConfirmation processes the user's answer to a cost confirmation.
+// "Yes" records a one-shot confirmation for the pending scope and consumes
+// the input; anything else ends the run. It returns true when execution may
+// continue.
+func (a *AstonishAgent) handleCostConfirmation(ctx agent.InvocationContext, state session.State, yield func(*session.Event, error) bool) bool {
+	if ctx.UserContent() == nil || len(ctx.UserContent().Parts) == 0 {
+		return true
+	}
+
+	var responseText string
+	for _, part := range ctx.UserContent().Parts {
+		responseText += part.Text
+	}
+	responseText = strings.ToLower(strings.TrimSpace(StripTimestamp(responseText)))
+
+	scopeVal, _ := state.Get("_cost_scope")
+	scope, _ := scopeVal.(string)
+	state.Set("awaiting_cost_confirmation", false)
+	state.Set("waiting_for_input", false)
+
+	if responseText == "yes" || responseText == "y" {
+		state.Set("_cost_confirmed:"+scope, true)
+		// The answer is not input for the flow
+		ctx.UserContent().Parts = nil
+		yield(&session.Event{
+			Actions: session.EventActions{
+				StateDelta: map[string]any{
+					"awaiting_cost_confirmation": false,
+					"waiting_for_input":          false,
+				},
+			},
+		}, nil)
+		return true
+	}
+
+	state.Set("current_node", "END")
+	yield(&session.Event{
+		LLMResponse: model.LLMResponse{
+			Content: &genai.Content{
+				Parts: []*genai.Part{{Text: "Run cancelled: estimated cost not confirmed."}},
+				Role:  "model",
+			},
+		},
+		Actions: session.EventActions{
+			StateDelta: map[string]any{
+				"current_node":               "END",
+				"awaiting_cost_confirmation": false,
+				"waiting_for_input":          false,
+			},
+		},
+	}, nil)
+	return false
+}
+
+// guardParallelCost re-checks the cost of a parallel node once its list is
+// known.
+func (a *AstonishAgent) guardParallelCost(node *config.Node, state session.State, items int, yield func(*session.Event, error) bool) bool {
+	if node.Type != "llm" {
+		return true
+	}
+	return a.confirmCost(state, node.Name, a.estimateParallelCost(node, state, items), yield)
+}
@@ -0,0 +1,98 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+type answerContext struct {
+	*MockInvocationContext
+	content *genai.Content
+}
+
+func (c *answerContext) UserContent() *genai.Content { return c.content }
+
+func TestCostGuard_ConfirmAndResume(t *testing.T) {
+	cfg := &config.AgentConfig{Nodes: []config.Node{{
+		Name:     "review",
+		Type:     "llm",
+		Prompt:   "Review {file}",
+		Model:    "gpt-4",
+		Parallel: &config.ParallelConfig{ForEach: "{files}", As: "file"},
+		Generation: &config.GenerationConfig{
+			MaxOutputTokens: 4000,
+		},
+	}}}
+	a := NewAstonishAgent(cfg, nil, nil)
+	a.AppConfig = &config.AppConfig{CostGuard: config.CostGuardConfig{Threshold: 1}}
+	state := NewMockState()
+
+	// 4000 output tokens at $60/M is $0.24 per item: 10 items exceed $1
+	est := a.estimateParallelCost(&cfg.Nodes[0], state, 10)
+	if est.Total < 2 || est.Total > 3 {
+		t.Fatalf("estimate = %.2f, want ~2.5", est.Total)
+	}
+	if !strings.Contains(est.String(), "× 10 items") {
+		t.Errorf("breakdown should show the fan-out:\n%s", est)
+	}
+
+	var events []*session.Event
+	yield := func(ev *session.Event, err error) bool {
+		events = append(events, ev)
+		return true
+	}
+	if a.confirmCost(state, "review", est, yield) {
+		t.Fatal("expected the run to pause for confirmation")
+	}
+	delta := events[0].Actions.StateDelta
+	if delta["awaiting_cost_confirmation"] != true || delta["waiting_for_input"] != true {
+		t.Fatalf("prompt event state delta = %v", delta)
+	}
+
+	ctx := &answerContext{
+		MockInvocationContext: &MockInvocationContext{Context: context.Background(), StateVal: state},
+		content:               genai.NewContentFromText("yes", genai.RoleUser),
+	}
+	if !a.handleCostConfirmation(ctx, state, yield) {
+		t.Fatal("expected yes to continue the run")
+	}
+	if len(ctx.content.Parts) != 0 {
+		t.Error("the confirmation answer should not reach the flow as input")
+	}
+	if !a.confirmCost(state, "review", est, yield) {
+		t.Fatal("expected the confirmed scope to proceed")
+	}
+	if a.confirmCost(state, "review", est, yield) != false {
+		t.Error("a confirmation should only be used once")
+	}
+
+	ctx.content = genai.NewContentFromText("no", genai.RoleUser)
+	if a.handleCostConfirmation(ctx, state, yield) {
+		t.Fatal("expected no to stop the run")
+	}
+	if node, _ := state.Get("current_node"); node != "END" {
+		t.Errorf("current_node = %v, want END", node)
+	}
+}
+
+func TestCostGuard_BelowThresholdAndDisabled(t *testing.T) {
+	cfg := &config.AgentConfig{Nodes: []config.Node{{Name: "ask", Type: "llm", Prompt: "hi", Model: "gpt-4o-mini"}}}
+	a := NewAstonishAgent(cfg, nil, nil)
+	a.AppConfig = &config.AppConfig{}
+	yield := func(*session.Event, error) bool { t.Fatal("unexpected prompt"); return false }
+
+	if !a.confirmCost(NewMockState(), "flow", a.estimateFlowCost(NewMockState()), yield) {
+		t.Error("a cheap flow should not ask for confirmation")
+	}
+
+	disabled := false
+	a.AppConfig.CostGuard = config.CostGuardConfig{Enabled: &disabled, Threshold: 0.0001}
+	if !a.confirmCost(NewMockState(), "flow", costEstimate{Total: 100}, yield) {
+		t.Error("a disabled guard should not ask for confirmation")
+	}
+}
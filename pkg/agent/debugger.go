@@ -0,0 +1,104 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/adk/session"
+)
+
+// StepAction is a debugger's decision about the node that is about to run.
+type StepAction string
+
+const (
+	StepContinue StepAction = "continue" // Run the node
+	StepSkip     StepAction = "skip"     // Do not run the node; follow its edges as if it had succeeded
+	StepAbort    StepAction = "abort"    // Stop the run before the node
+)
+
+// StepInfo describes a node that is about to run, rendered against the
+// current state.
+type StepInfo struct {
+	Node   *config.Node
+	Prompt string         // Rendered prompt of LLM and input nodes
+	System string         // Rendered system instruction of LLM nodes
+	Args   map[string]any // Resolved arguments of tool nodes
+	Vars   map[string]any // State values the node references
+}
+
+// StepDecision is what a debugger answers for a node: the action and the
+// state values to change before it runs.
+type StepDecision struct {
+	Action StepAction
+	Set    map[string]any
+}
+
+// Debugger is consulted before each node of a run (astonish flows run
+// --step). Implementations are called on the run's goroutine and may block
+// while they wait for the user.
+type Debugger interface {
+	BeforeNode(ctx context.Context, info StepInfo) StepDecision
+}
+
+// stepInfo renders what a debugger shows for node.
+func (a *AstonishAgent) stepInfo(node *config.Node, state session.State) StepInfo {
+	info := StepInfo{Node: node, Vars: make(map[string]any)}
+	sources := []string{node.Prompt, node.System}
+	switch node.Type {
+	case "llm":
+		info.Prompt = a.renderNodeString(node, node.Prompt, state)
+		info.System = a.renderNodeString(node, node.System, state)
+	case "input":
+		info.Prompt = a.renderNodeString(node, node.Prompt, state)
+	case "tool":
+		info.Args = a.resolveToolArgs(node, state)
+		for _, val := range node.Args {
+			if s, ok := val.(string); ok {
+				sources = append(sources, s)
+			}
+		}
+	}
+
+	var keys []string
+	for _, text := range sources {
+		for _, m := range placeholderKeyRe.FindAllStringSubmatch(text, -1) {
+			keys = append(keys, m[1])
+		}
+	}
+	keys = append(keys, node.UserMessage...)
+	for _, key := range keys {
+		if val, err := state.Get(key); err == nil {
+			info.Vars[key] = val
+		}
+	}
+	return info
+}
+
+// stepNode asks the debugger about node and applies the state values it
+// changed. It returns the action to take.
+func (a *AstonishAgent) stepNode(ctx context.Context, node *config.Node, state session.State, pendingStateDelta map[string]any) StepAction {
+	decision := a.Debugger.BeforeNode(ctx, a.stepInfo(node, state))
+	for key, val := range decision.Set {
+		if err := state.Set(key, val); err != nil {
+			slog.Warn("failed to set state from the debugger", "key", key, "error", err)
+			continue
+		}
+		pendingStateDelta[key] = val
+	}
+	if decision.Action == "" {
+		return StepContinue
+	}
+	return decision.Action
+}
+
+// stepAbortedEvent reports a run the debugger stopped before nodeName.
+func stepAbortedEvent(nodeName string) *session.Event {
+	return &session.Event{
+		Actions: session.EventActions{
+			StateDelta: map[string]any{
+				"_step_aborted": map[string]any{"node": nodeName},
+			},
+		},
+	}
+}
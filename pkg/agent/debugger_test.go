@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/config"
+)
+
+// scriptedDebugger answers with a fixed decision per node and records what
+// it was shown.
+type scriptedDebugger struct {
+	decisions map[string]StepDecision
+	shown     map[string]StepInfo
+}
+
+func (d *scriptedDebugger) BeforeNode(ctx context.Context, info StepInfo) StepDecision {
+	d.shown[info.Node.Name] = info
+	return d.decisions[info.Node.Name]
+}
+
+func TestDebuggerStepsThroughNodes(t *testing.T) {
+	cfg := &config.AgentConfig{
+		Nodes: []config.Node{
+			{Name: "seed", Type: "script", Script: `x["doubled"] = x["count"] * 2`},
+			{Name: "reset", Type: "script", Script: `x["doubled"] = 0`},
+			{Name: "report", Type: "output", UserMessage: []string{"doubled"}},
+		},
+		Flow: []config.FlowItem{
+			{From: "START", To: "seed"},
+			{From: "seed", To: "reset"},
+			{From: "reset", To: "report"},
+			{From: "report", To: "END"},
+		},
+	}
+	debugger := &scriptedDebugger{
+		decisions: map[string]StepDecision{
+			"seed":   {Action: StepContinue, Set: map[string]any{"count": 21}},
+			"reset":  {Action: StepSkip},
+			"report": {Action: StepAbort},
+		},
+		shown: make(map[string]StepInfo),
+	}
+	a := &AstonishAgent{Config: cfg, Debugger: debugger}
+	state := NewMockState()
+	ctx := &MockInvocationContext{Context: context.Background(), StateVal: state}
+
+	var abortedAt any
+	for ev, err := range a.Run(ctx) {
+		if err != nil {
+			t.Fatalf("run: %v", err)
+		}
+		if info, ok := ev.Actions.StateDelta["_step_aborted"].(map[string]any); ok {
+			abortedAt = info["node"]
+		}
+		if node := ev.Actions.StateDelta["current_node"]; node == "report" || node == "END" {
+			t.Errorf("node %v ran after the debugger aborted", node)
+		}
+	}
+
+	// The edited count was used and the skipped node did not reset the result
+	if got := state.Data["doubled"]; got != int64(42) && got != 42 {
+		t.Errorf("doubled = %v (%T), want 42", got, got)
+	}
+	if abortedAt != "report" {
+		t.Errorf("_step_aborted node = %v, want report", abortedAt)
+	}
+	if vars := debugger.shown["report"].Vars; vars["doubled"] == nil {
+		t.Errorf("report vars = %v, want the doubled value it shows", vars)
+	}
+}
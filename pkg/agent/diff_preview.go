@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"fmt"
+
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/ui"
+	"github.com/aymanbagabas/go-udiff"
+	"google.golang.org/adk/session"
+)
+
+// DiffPreviewKey is the state delta key of the diff an output node shows:
+// its path, style and unified diff, for the console to render.
+const DiffPreviewKey = "_diff_preview"
+
+// diffPreview returns the unified diff between the old and new texts of an
+// output node's diff block (empty when they are equal) and the path it is
+// labeled with.
+func (a *AstonishAgent) diffPreview(node *config.Node, state session.State) (path, diff string) {
+	d := node.Diff
+	path = a.renderNodeString(node, d.Path, state)
+	label := path
+	if label == "" {
+		label = "text"
+	}
+	oldText := a.diffText(node, d.Old, state)
+	newText := a.diffText(node, d.New, state)
+	return path, udiff.Unified("a/"+label, "b/"+label, oldText, newText)
+}
+
+// diffText resolves one side of a diff: the value of a state key, or else
+// the rendered template.
+func (a *AstonishAgent) diffText(node *config.Node, ref string, state session.State) string {
+	if ref == "" {
+		return ""
+	}
+	if val, err := state.Get(ref); err == nil {
+		if s, ok := val.(string); ok {
+			return s
+		}
+		return ui.FormatAsYamlLike(val, 0)
+	}
+	return a.renderNodeString(node, ref, state)
+}
+
+// diffMessage returns the text of a diff preview: a fenced unified diff,
+// which clients without the console's renderer show as is.
+func diffMessage(path, diff string) string {
+	if diff == "" {
+		if path != "" {
+			return fmt.Sprintf("No changes to %s.", path)
+		}
+		return "No changes."
+	}
+	return "```diff\n" + diff + "```"
+}
@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/config"
+)
+
+func TestDiffPreviewOutputNode(t *testing.T) {
+	cfg := &config.AgentConfig{
+		Nodes: []config.Node{{
+			Name: "review", Type: "output", UserMessage: []string{"Proposed change:"},
+			Diff:    &config.DiffPreview{Old: "original", New: "proposed", Path: "{file}"},
+			Confirm: "apply",
+		}},
+		Flow: []config.FlowItem{{From: "START", To: "review"}, {From: "review", To: "END"}},
+	}
+	a := &AstonishAgent{Config: cfg}
+	state := NewMockState()
+	state.Set("file", "main.go")
+	state.Set("original", "package main\n\nfunc main() {}\n")
+	state.Set("proposed", "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n")
+	ctx := &MockInvocationContext{Context: context.Background(), StateVal: state}
+
+	var texts []string
+	var preview map[string]any
+	var prompt map[string]any
+	for ev, err := range a.Run(ctx) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p, ok := ev.Actions.StateDelta[DiffPreviewKey].(map[string]any); ok {
+			preview = p
+		}
+		if ev.Actions.StateDelta["waiting_for_input"] == true {
+			prompt = ev.Actions.StateDelta
+		}
+		if ev.Content != nil && len(ev.Content.Parts) > 0 {
+			texts = append(texts, ev.Content.Parts[0].Text)
+		}
+	}
+
+	if preview == nil || preview["path"] != "main.go" || !strings.Contains(preview["diff"].(string), "+\tprintln(\"hi\")") {
+		t.Fatalf("diff preview = %v", preview)
+	}
+	if got := strings.Join(texts, "\n"); !strings.Contains(got, "```diff\n--- a/main.go\n+++ b/main.go") || !strings.Contains(got, "Proposed change:") || !strings.Contains(got, "Accept these changes?") {
+		t.Errorf("texts = %q", got)
+	}
+	if prompt == nil || prompt["current_node"] != "review" {
+		t.Fatalf("run did not wait for Accept/Reject: %v", prompt)
+	}
+	if opts, _ := prompt["input_options"].([]string); strings.Join(opts, ",") != "Accept,Reject" {
+		t.Errorf("options = %v", prompt["input_options"])
+	}
+
+	node, _ := a.getNode("review")
+	if v, err := a.parseInput(node, "Reject", state); err != nil || v != false || inputStateKey(node) != "apply" {
+		t.Errorf("Reject = %v, %v (key %q)", v, err, inputStateKey(node))
+	}
+	if _, err := a.parseInput(node, "maybe", state); err == nil {
+		t.Error("an answer other than Accept or Reject was accepted")
+	}
+}
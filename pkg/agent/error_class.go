@@ -0,0 +1,57 @@
+package agent
+
+import (
+	"errors"
+
+	"github.com/SAP/astonish/pkg/provider/llmerror"
+	"google.golang.org/adk/session"
+)
+
+// failureInfo builds the _failure_info payload for a node failure. Provider
+// errors (auth, quota, content filter, ...) replace the generic title,
+// reason and suggestion with a plain-language explanation; error_class is
+// set on every failure so UIs and automation can branch on it.
+func failureInfo(title, reason, suggestion string, err error) map[string]any {
+	class := llmerror.Classify(err)
+	if classTitle, classReason, classSuggestion := class.Explain(llmerror.Provider(err)); classTitle != "" {
+		title, reason, suggestion = classTitle, classReason, classSuggestion
+	}
+	return map[string]any{
+		"title":          title,
+		"reason":         reason,
+		"suggestion":     suggestion,
+		"original_error": err.Error(),
+		"error_class":    errorClass(err),
+	}
+}
+
+// recordNodeError stores the error details read by error handler nodes and
+// flow conditions (_last_error, _error_node, _error_class).
+func recordNodeError(state session.State, nodeName string, err error) {
+	state.Set("_last_error", err.Error())
+	state.Set("_error_node", nodeName)
+	state.Set("_error_class", errorClass(err))
+	state.Set("_has_error", true)
+}
+
+// enterErrorHandler marks handler as the node handling the recorded error,
+// so it reads _last_error, _error_node and _error_class instead of clearing
+// them when it starts. The error counts as handled: _has_error is reset so
+// later pauses are not mistaken for failures.
+func enterErrorHandler(state session.State, handler string) {
+	state.Set("_error_handler", handler)
+	state.Set("_has_error", false)
+}
+
+// guardrailErrorClass is the _error_class of output blocked by guardrails.
+const guardrailErrorClass = "guardrail"
+
+// errorClass returns the _error_class of a node failure: the provider error
+// class, or "guardrail" for output the node's guardrails blocked.
+func errorClass(err error) string {
+	var guardErr *GuardrailError
+	if errors.As(err, &guardErr) {
+		return guardrailErrorClass
+	}
+	return string(llmerror.Classify(err))
+}
@@ -32,6 +32,8 @@ type RecoveryDecision struct {
 	OneLiner    string `json:"one_liner"`    // Ultra-short summary for badges (max 60 chars)
 	Reason      string `json:"reason"`       // Explanation of the decision
 	Suggestion  string `json:"suggestion"`   // What to fix or try differently (if retry)
+
+	RetryArgs map[string]any `json:"retry_args,omitempty"` // Corrected tool arguments for the next attempt (tool nodes only)
 }
 
 // ErrorRecoveryNode analyzes errors and decides whether to retry or abort
@@ -186,6 +188,9 @@ func (e *ErrorRecoveryNode) buildUserPrompt(errCtx ErrorContext) string {
 			argsJSON, _ := json.MarshalIndent(errCtx.ToolArgs, "  ", "  ")
 			sb.WriteString(fmt.Sprintf("- Tool Arguments:\n  %s\n", string(argsJSON)))
 		}
+		if errCtx.NodeType == "tool" {
+			sb.WriteString("\nIf different arguments would fix the error, add a \"retry_args\" object to your JSON with the corrected arguments to use on retry.\n")
+		}
 	}
 
 	sb.WriteString(fmt.Sprintf("\n**Question:** Should the system RETRY or ABORT?"))
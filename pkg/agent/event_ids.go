@@ -0,0 +1,198 @@
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+)
+
+// Keys of the stable IDs stamped into the CustomMetadata of every event a
+// flow yields. They are persisted with the event, so transcripts, SSE
+// payloads and run history all carry the same IDs.
+const (
+	MetaNodeExecutionID = "astonish:node_execution_id"
+	MetaEventID         = "astonish:event_id"
+)
+
+// MetaMarker is the CustomMetadata key of the boundary marker a node
+// transition carries: "node_start:<node>". The history an LLM node sees
+// starts after the last marker.
+const MetaMarker = "astonish:marker"
+
+const nodeStartPrefix = "node_start:"
+
+// State keys of the ID counters, persisted so IDs continue across the
+// turns of a run (input nodes, tool approvals).
+const (
+	nodeRunsKey        = "_node_runs"         // node name -> executions so far
+	nodeExecutionIDKey = "_node_execution_id" // ID of the current execution
+)
+
+// EventIDs returns the node execution ID and event ID stamped on an event,
+// or empty strings for events that did not come from a flow.
+func EventIDs(event *session.Event) (nodeExecutionID, eventID string) {
+	if event == nil || event.CustomMetadata == nil {
+		return "", ""
+	}
+	nodeExecutionID, _ = event.CustomMetadata[MetaNodeExecutionID].(string)
+	eventID, _ = event.CustomMetadata[MetaEventID].(string)
+	return nodeExecutionID, eventID
+}
+
+// NodeStart returns the node a node_start marker event starts.
+func NodeStart(event *session.Event) (string, bool) {
+	if event == nil || event.CustomMetadata == nil {
+		return "", false
+	}
+	marker, _ := event.CustomMetadata[MetaMarker].(string)
+	return strings.CutPrefix(marker, nodeStartPrefix)
+}
+
+// markNodeStart adds the node_start marker of node to event.
+func markNodeStart(event *session.Event, node string) {
+	if event.CustomMetadata == nil {
+		event.CustomMetadata = make(map[string]any)
+	}
+	event.CustomMetadata[MetaMarker] = nodeStartPrefix + node
+}
+
+// FlowHash returns a short hash of the flow definition. It prefixes all IDs
+// so executions of different versions of a flow never collide.
+func FlowHash(cfg any) string {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return "000000000000"
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// eventIDTracker assigns the IDs of a flow's events. A node execution is
+// identified by <flow hash>:<node>:<n>, n counting the node's executions in
+// the run, and an event by <node execution ID>:<seq>. Streaming chunks share
+// the ID of the final event they belong to.
+type eventIDTracker struct {
+	flowHash string
+	node     string
+	execID   string
+	seq      int
+	// resumed is set while the node transition of a resumed execution is
+	// still to come: an approval re-runs the node, and an input node's
+	// answer names the next node before the main loop enters it.
+	resumed bool
+}
+
+// newEventIDTracker continues the IDs of an earlier turn of the session.
+func (a *AstonishAgent) newEventIDTracker(ctx agent.InvocationContext, state session.State, hasUserInput bool) *eventIDTracker {
+	t := &eventIDTracker{flowHash: FlowHash(a.Config)}
+	execID, _ := stateString(state, nodeExecutionIDKey)
+	if execID == "" {
+		return t
+	}
+	t.execID = execID
+	t.node = nodeOfExecution(execID)
+	currentNode, _ := stateString(state, "current_node")
+	t.resumed = hasUserInput && t.node == currentNode
+
+	events := ctx.Session().Events()
+	if events == nil {
+		return t
+	}
+	for i := events.Len() - 1; i >= 0; i-- {
+		exec, id := EventIDs(events.At(i))
+		if exec != execID {
+			continue
+		}
+		if n, err := strconv.Atoi(id[strings.LastIndex(id, ":")+1:]); err == nil {
+			t.seq = n
+		}
+		break
+	}
+	return t
+}
+
+// stamp records the IDs of an event. A node transition (current_node with
+// node_type in the delta) or a move to another node starts a new execution.
+func (t *eventIDTracker) stamp(state session.State, event *session.Event) {
+	delta := event.Actions.StateDelta
+	if node, ok := delta["current_node"].(string); ok && node != "" {
+		_, transition := delta["node_type"]
+		switch {
+		case node != t.node || (transition && !t.resumed):
+			t.begin(state, event, node)
+			t.resumed = !transition
+		case transition:
+			t.resumed = false
+		}
+	}
+	if t.execID == "" {
+		t.begin(state, event, "START")
+	}
+
+	seq := t.seq + 1
+	if !event.Partial {
+		t.seq = seq
+	}
+	if event.CustomMetadata == nil {
+		event.CustomMetadata = make(map[string]any)
+	}
+	event.CustomMetadata[MetaNodeExecutionID] = t.execID
+	event.CustomMetadata[MetaEventID] = t.execID + ":" + strconv.Itoa(seq)
+}
+
+// begin starts the next execution of a node and persists the counters
+// through the event's state delta.
+func (t *eventIDTracker) begin(state session.State, event *session.Event, node string) {
+	runs := make(map[string]any)
+	if v, err := state.Get(nodeRunsKey); err == nil {
+		if m, ok := v.(map[string]any); ok {
+			for k, n := range m {
+				runs[k] = n
+			}
+		}
+	}
+	var n int
+	switch v := runs[node].(type) {
+	case int:
+		n = v
+	case float64: // decoded from a persisted session
+		n = int(v)
+	}
+	n++
+	runs[node] = n
+
+	t.node = node
+	t.execID = t.flowHash + ":" + node + ":" + strconv.Itoa(n)
+	t.seq = 0
+
+	_ = state.Set(nodeRunsKey, runs)
+	_ = state.Set(nodeExecutionIDKey, t.execID)
+	if event.Actions.StateDelta == nil {
+		event.Actions.StateDelta = make(map[string]any)
+	}
+	event.Actions.StateDelta[nodeRunsKey] = runs
+	event.Actions.StateDelta[nodeExecutionIDKey] = t.execID
+}
+
+// nodeOfExecution returns the node name of a node execution ID.
+func nodeOfExecution(execID string) string {
+	_, rest, _ := strings.Cut(execID, ":")
+	if i := strings.LastIndex(rest, ":"); i >= 0 {
+		return rest[:i]
+	}
+	return rest
+}
+
+func stateString(state session.State, key string) (string, bool) {
+	v, err := state.Get(key)
+	if err != nil {
+		return "", false
+	}
+	s, ok := v.(string)
+	return s, ok
+}
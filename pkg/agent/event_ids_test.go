@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/adk/session"
+)
+
+func TestEventIDTracker_NodeExecutions(t *testing.T) {
+	cfg := &config.AgentConfig{Nodes: []config.Node{{Name: "ask", Type: "input"}, {Name: "work", Type: "llm"}}}
+	a := &AstonishAgent{Config: cfg}
+	state := NewMockState()
+	ctx := &MockInvocationContext{Context: context.Background(), StateVal: state}
+	hash := FlowHash(cfg)
+
+	stamp := func(ids *eventIDTracker, delta map[string]any, partial bool) (string, string) {
+		ev := session.NewEvent("inv")
+		ev.Actions.StateDelta = delta
+		ev.Partial = partial
+		ids.stamp(state, ev)
+		return EventIDs(ev)
+	}
+
+	ids := a.newEventIDTracker(ctx, state, false)
+	if exec, id := stamp(ids, nil, false); exec != hash+":START:1" || id != exec+":1" {
+		t.Fatalf("event before any node = %s %s", exec, id)
+	}
+	exec, _ := stamp(ids, map[string]any{"current_node": "work", "node_type": "llm"}, false)
+	if exec != hash+":work:1" {
+		t.Fatalf("execution = %s", exec)
+	}
+	_, chunk := stamp(ids, nil, true)
+	_, final := stamp(ids, nil, false)
+	if chunk != final || final != exec+":2" {
+		t.Errorf("partial %s and final %s should share the ID %s:2", chunk, final, exec)
+	}
+
+	// Paused for tool approval: the next turn re-runs the node within the
+	// same execution.
+	state.Set("current_node", "work")
+	ids = a.newEventIDTracker(ctx, state, true)
+	if exec, _ := stamp(ids, map[string]any{"current_node": "work", "node_type": "llm"}, false); exec != hash+":work:1" {
+		t.Errorf("approval resume started a new execution %s", exec)
+	}
+
+	// A loop back to the node is its second execution.
+	if exec, _ := stamp(ids, map[string]any{"current_node": "work", "node_type": "llm"}, false); exec != hash+":work:2" {
+		t.Errorf("second visit = %s", exec)
+	}
+	if got := state.Data[nodeExecutionIDKey]; got != hash+":work:2" {
+		t.Errorf("state %s = %v", nodeExecutionIDKey, got)
+	}
+	if nodeOfExecution(hash+":a:b:3") != "a:b" {
+		t.Errorf("nodeOfExecution = %q", nodeOfExecution(hash+":a:b:3"))
+	}
+}
@@ -0,0 +1,147 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/adk/session"
+)
+
+// State keys of chat-mode flows (chat_mode / loop_to).
+const (
+	// ChatMessageStateKey holds the user message of the current turn.
+	ChatMessageStateKey = "chat_message"
+	// ChatHistoryStateKey holds the earlier turns as a "User: / Assistant:"
+	// transcript, for prompts that place the history themselves.
+	ChatHistoryStateKey = "chat_history"
+
+	chatTurnsStateKey = "_chat_turns" // [{user, assistant}], oldest first
+	chatReplyStateKey = "_chat_reply" // last text the current turn emitted
+)
+
+// maxChatTurns bounds the turns kept in the chat history.
+const maxChatTurns = 20
+
+// chatEntryNode returns the node a chat turn starts at: loop_to, or the
+// node the flow starts at.
+func (a *AstonishAgent) chatEntryNode(state session.State) (string, error) {
+	if a.Config.LoopTo != "" {
+		if _, ok := a.getNode(a.Config.LoopTo); !ok {
+			return "", fmt.Errorf("loop_to node not found: %s", a.Config.LoopTo)
+		}
+		return a.Config.LoopTo, nil
+	}
+	return a.getNextNode("START", state)
+}
+
+// startChatTurn stores the user's message as the message of a new turn.
+func startChatTurn(state session.State, message string, delta map[string]any) {
+	state.Set(ChatMessageStateKey, message)
+	state.Set(chatReplyStateKey, "")
+	delta[ChatMessageStateKey] = message
+	delta[chatReplyStateKey] = ""
+}
+
+// recordChatReply remembers the text of a final model event as the turn's
+// reply so far: the last output node or LLM answer of the turn wins.
+func recordChatReply(state session.State, event *session.Event) {
+	if event == nil || event.Partial || event.Content == nil || event.Author == "user" {
+		return
+	}
+	for _, key := range []string{"awaiting_approval", "waiting_for_input", "_error_message_display", "_processing_info", "_failure_info", "_retry_info"} {
+		if _, ok := event.Actions.StateDelta[key]; ok {
+			return
+		}
+	}
+	var sb strings.Builder
+	for _, part := range event.Content.Parts {
+		if part.FunctionCall != nil || part.FunctionResponse != nil || part.Thought {
+			return
+		}
+		sb.WriteString(part.Text)
+	}
+	text := strings.TrimSpace(sb.String())
+	if text == "" {
+		return
+	}
+	state.Set(chatReplyStateKey, text)
+	if event.Actions.StateDelta == nil {
+		event.Actions.StateDelta = make(map[string]any)
+	}
+	event.Actions.StateDelta[chatReplyStateKey] = text
+}
+
+// finishChatTurn appends the finished turn to the chat history.
+func finishChatTurn(state session.State, delta map[string]any) {
+	message, _ := stateString(state, ChatMessageStateKey)
+	reply, _ := stateString(state, chatReplyStateKey)
+	if message == "" && reply == "" {
+		return
+	}
+
+	turns := chatTurns(state)
+	turns = append(turns, map[string]any{"user": message, "assistant": reply})
+	if len(turns) > maxChatTurns {
+		turns = turns[len(turns)-maxChatTurns:]
+	}
+	history := formatChatHistory(turns)
+
+	state.Set(chatTurnsStateKey, turns)
+	state.Set(ChatHistoryStateKey, history)
+	state.Set(chatReplyStateKey, "")
+	delta[chatTurnsStateKey] = turns
+	delta[ChatHistoryStateKey] = history
+	delta[chatReplyStateKey] = ""
+}
+
+// chatTurns reads the recorded turns, as stored in memory or decoded from
+// a persisted session.
+func chatTurns(state session.State) []any {
+	val, err := state.Get(chatTurnsStateKey)
+	if err != nil {
+		return nil
+	}
+	switch turns := val.(type) {
+	case []any:
+		return append([]any(nil), turns...)
+	case []map[string]any:
+		out := make([]any, len(turns))
+		for i, t := range turns {
+			out[i] = t
+		}
+		return out
+	}
+	return nil
+}
+
+func formatChatHistory(turns []any) string {
+	var sb strings.Builder
+	for _, t := range turns {
+		turn, ok := t.(map[string]any)
+		if !ok {
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteString("\n\n")
+		}
+		fmt.Fprintf(&sb, "User: %v\nAssistant: %v", turn["user"], turn["assistant"])
+	}
+	return sb.String()
+}
+
+// chatHistoryInstruction returns the conversation so far for the system
+// instruction of an LLM node in a chat-mode flow, unless the node places
+// chat_history in its prompts itself.
+func (a *AstonishAgent) chatHistoryInstruction(prompt, system string, state session.State) string {
+	if !a.Config.ChatEnabled() {
+		return ""
+	}
+	if strings.Contains(prompt, ChatHistoryStateKey) || strings.Contains(system, ChatHistoryStateKey) {
+		return ""
+	}
+	history, _ := stateString(state, ChatHistoryStateKey)
+	if history == "" {
+		return ""
+	}
+	return "Conversation so far:\n\n" + history
+}
@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/genai"
+)
+
+// chatTurnContext delivers a user message to the flow.
+type chatTurnContext struct {
+	*MockInvocationContext
+	message string
+}
+
+func (c *chatTurnContext) UserContent() *genai.Content {
+	return genai.NewContentFromText(c.message, genai.RoleUser)
+}
+
+func TestChatModeLoopsToEntryNode(t *testing.T) {
+	cfg := &config.AgentConfig{
+		LoopTo: "reply",
+		Nodes: []config.Node{
+			{Name: "greet", Type: "output", UserMessage: []string{"Welcome!"}},
+			{Name: "reply", Type: "output", UserMessage: []string{"chat_message"}},
+		},
+		Flow: []config.FlowItem{
+			{From: "START", To: "greet"},
+			{From: "greet", To: "reply"},
+			{From: "reply", To: "END"},
+		},
+	}
+	a := &AstonishAgent{Config: cfg}
+	state := NewMockState()
+
+	turn := func(message string) []string {
+		t.Helper()
+		ctx := &chatTurnContext{MockInvocationContext: &MockInvocationContext{Context: context.Background(), StateVal: state}, message: message}
+		var nodes []string
+		for ev, err := range a.Run(ctx) {
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if node, ok := ev.Actions.StateDelta["node_type"]; ok && node != "END" {
+				nodes = append(nodes, ev.Actions.StateDelta["current_node"].(string))
+			}
+		}
+		return nodes
+	}
+
+	if nodes := turn("hi"); len(nodes) != 2 || nodes[0] != "greet" {
+		t.Errorf("first turn visited %v, want [greet reply]", nodes)
+	}
+	if nodes := turn("again"); len(nodes) != 1 || nodes[0] != "reply" {
+		t.Errorf("second turn visited %v, want [reply]", nodes)
+	}
+
+	if state.Data["current_node"] != "END" {
+		t.Errorf("current_node = %v, want END", state.Data["current_node"])
+	}
+	want := "User: hi\nAssistant: hi\n\nUser: again\nAssistant: again"
+	if got := state.Data[ChatHistoryStateKey]; got != want {
+		t.Errorf("chat_history = %q, want %q", got, want)
+	}
+
+	if got := a.chatHistoryInstruction("Answer {chat_message}", "", state); got != "Conversation so far:\n\n"+want {
+		t.Errorf("history instruction = %q", got)
+	}
+	if got := a.chatHistoryInstruction("{chat_history}\n\n{chat_message}", "", state); got != "" {
+		t.Errorf("nodes placing chat_history get no extra instruction, got %q", got)
+	}
+}
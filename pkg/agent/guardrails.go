@@ -0,0 +1,202 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+)
+
+// GuardrailError reports LLM output that violated the node's guardrails when
+// their action is block. It is not retried: the same prompt is likely to
+// produce the same kind of answer.
+type GuardrailError struct {
+	Node       string
+	Violations []string
+}
+
+func (e *GuardrailError) Error() string {
+	return fmt.Sprintf("output of node '%s' blocked by guardrails: %s", e.Node, strings.Join(e.Violations, "; "))
+}
+
+// piiPatterns detect the personal data kinds of config.PIIKinds.
+var piiPatterns = map[string]*regexp.Regexp{
+	"email":       regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	"phone":       regexp.MustCompile(`\+\d{1,3}[ .-]?\(?\d{1,4}\)?(?:[ .-]?\d{2,4}){2,4}\b|\(?\b\d{3}\)?[ .-]\d{3}[ .-]\d{4}\b`),
+	"credit_card": regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+	"ssn":         regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),
+	"iban":        regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,4})?\b`),
+	"ip_address":  regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`),
+}
+
+// classifierPrompt asks the classifier model for a one-line verdict.
+const classifierPrompt = `You are a content safety classifier. Decide whether the text below violates this policy:
+
+%s
+
+Answer with exactly one line: SAFE, or UNSAFE: <short reason>.
+
+Text:
+<<<
+%s
+>>>`
+
+// guardrailResult is the outcome of the checks on one output.
+type guardrailResult struct {
+	Violations []string
+	Redacted   string // The output with deny and PII matches replaced
+	Classified bool   // The classifier found a violation, which cannot be redacted
+}
+
+// checkGuardrails runs the deny patterns and PII detection on text.
+// Patterns are validated when the flow is loaded; invalid ones are skipped.
+func checkGuardrails(g *config.GuardrailsConfig, text string) guardrailResult {
+	res := guardrailResult{Redacted: text}
+	for _, pattern := range g.Deny {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			continue
+		}
+		if re.MatchString(res.Redacted) {
+			res.Violations = append(res.Violations, fmt.Sprintf("matches denied pattern %q", pattern))
+			res.Redacted = re.ReplaceAllString(res.Redacted, "[REDACTED:deny]")
+		}
+	}
+	for _, kind := range g.PII {
+		re := piiPatterns[kind]
+		if re == nil {
+			continue
+		}
+		count := 0
+		res.Redacted = re.ReplaceAllStringFunc(res.Redacted, func(match string) string {
+			if kind == "credit_card" && !luhnValid(match) {
+				return match
+			}
+			count++
+			return "[REDACTED:" + kind + "]"
+		})
+		if count > 0 {
+			res.Violations = append(res.Violations, fmt.Sprintf("contains PII (%s, %d found)", kind, count))
+		}
+	}
+	return res
+}
+
+// luhnValid reports whether the digits of s pass the Luhn checksum used by
+// payment card numbers, so order numbers and the like are not flagged.
+func luhnValid(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// classifyOutput asks the guardrails classifier whether text violates its
+// policy, returning the reason when it does.
+func (a *AstonishAgent) classifyOutput(ctx context.Context, node *config.Node, g *config.GuardrailsConfig, llm model.LLM, text string) (string, error) {
+	if m := g.Classifier.Model; m != "" {
+		classifierNode := config.Node{Name: node.Name, Model: m}
+		if prefix, rest, ok := strings.Cut(m, "/"); ok && a.AppConfig != nil && a.hasProvider(prefix) {
+			classifierNode.Provider, classifierNode.Model = prefix, rest
+		}
+		var err error
+		if llm, err = a.nodeLLM(ctx, &classifierNode); err != nil {
+			return "", err
+		}
+	}
+
+	verdict, err := llmTextFunc(llm)(ctx, fmt.Sprintf(classifierPrompt, g.Classifier.Policy, text))
+	if err != nil {
+		return "", err
+	}
+	verdict = strings.TrimSpace(verdict)
+	if !strings.HasPrefix(strings.ToUpper(verdict), "UNSAFE") {
+		return "", nil
+	}
+	reason := strings.TrimSpace(strings.TrimLeft(verdict[len("UNSAFE"):], ":- "))
+	if reason == "" {
+		reason = "unsafe"
+	}
+	return reason, nil
+}
+
+// applyGuardrails checks the output of an LLM node against its guardrails
+// before it is stored or shown. It returns the output to use (redacted with
+// action redact), or routed when the flow continues at the guardrails' route
+// node. Violations are reported with a _guardrail event; with action block
+// the node fails with a GuardrailError. ok is false when the consumer
+// stopped the run.
+func (a *AstonishAgent) applyGuardrails(ctx context.Context, node *config.Node, nodeName string, llm model.LLM, text string, state session.State, yield func(*session.Event, error) bool) (output string, routed, ok bool, err error) {
+	g := a.Config.GuardrailsFor(node)
+	if g == nil || strings.TrimSpace(text) == "" {
+		return text, false, true, nil
+	}
+
+	res := checkGuardrails(g, text)
+	if g.Classifier != nil && g.Classifier.Policy != "" {
+		reason, err := a.classifyOutput(ctx, node, g, llm, text)
+		if err != nil {
+			return "", false, true, fmt.Errorf("guardrails classifier failed: %w", err)
+		}
+		if reason != "" {
+			res.Violations = append(res.Violations, "classifier: "+reason)
+			res.Classified = true
+		}
+	}
+	if len(res.Violations) == 0 {
+		return text, false, true, nil
+	}
+
+	action := g.Action
+	if action == "" || (action == "redact" && res.Classified) {
+		action = "block"
+	}
+	if !yield(guardrailEvent(nodeName, action, res.Violations), nil) {
+		return "", false, false, nil
+	}
+
+	switch action {
+	case "redact":
+		return res.Redacted, false, true, nil
+	case "route":
+		blocked := &GuardrailError{Node: nodeName, Violations: res.Violations}
+		state.Set("_last_error", blocked.Error())
+		state.Set("_error_node", nodeName)
+		state.Set("_error_class", guardrailErrorClass)
+		state.Set("_route_to", g.Node)
+		return "", true, true, nil
+	}
+	return "", false, true, &GuardrailError{Node: nodeName, Violations: res.Violations}
+}
+
+// guardrailEvent reports output that violated a node's guardrails and the
+// action taken.
+func guardrailEvent(nodeName, action string, violations []string) *session.Event {
+	return &session.Event{
+		Actions: session.EventActions{
+			StateDelta: map[string]any{
+				"_guardrail": map[string]any{
+					"node":       nodeName,
+					"action":     action,
+					"violations": violations,
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,87 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+func TestCheckGuardrails(t *testing.T) {
+	g := &config.GuardrailsConfig{
+		Deny: []string{`(?i)internal use only`},
+		PII:  []string{"email", "credit_card", "ssn"},
+	}
+	text := "Contact jane.doe@example.com, card 4111 1111 1111 1111, order 1234 5678 9012 3456, SSN 123-45-6789. Internal use only."
+	res := checkGuardrails(g, text)
+
+	if len(res.Violations) != 4 {
+		t.Fatalf("violations = %v", res.Violations)
+	}
+	for _, leaked := range []string{"jane.doe@example.com", "4111 1111 1111 1111", "123-45-6789", "Internal use only"} {
+		if strings.Contains(res.Redacted, leaked) {
+			t.Errorf("%q not redacted: %s", leaked, res.Redacted)
+		}
+	}
+	// Numbers that fail the Luhn check are not card numbers
+	if !strings.Contains(res.Redacted, "1234 5678 9012 3456") {
+		t.Errorf("order number redacted: %s", res.Redacted)
+	}
+
+	if res := checkGuardrails(g, "All systems nominal."); len(res.Violations) != 0 {
+		t.Errorf("clean output flagged: %v", res.Violations)
+	}
+}
+
+func TestApplyGuardrails(t *testing.T) {
+	state := NewMockState()
+	var events []*session.Event
+	yield := func(ev *session.Event, err error) bool {
+		events = append(events, ev)
+		return true
+	}
+	unsafe := &ADKMockModel{Responses: []*genai.Content{
+		genai.NewContentFromText("UNSAFE: gives medical advice", genai.RoleModel),
+		genai.NewContentFromText("UNSAFE: gives medical advice", genai.RoleModel),
+	}}
+	classifier := &config.GuardrailClassifier{Policy: "No medical advice."}
+
+	// Classifier violations cannot be redacted, so redact blocks
+	a := NewAstonishAgent(&config.AgentConfig{Guardrails: &config.GuardrailsConfig{Classifier: classifier, Action: "redact"}}, nil, nil)
+	node := &config.Node{Name: "answer", Type: "llm"}
+	_, _, ok, err := a.applyGuardrails(context.Background(), node, node.Name, unsafe, "Take two pills.", state, yield)
+	var guardErr *GuardrailError
+	if !ok || !errors.As(err, &guardErr) {
+		t.Fatalf("expected a GuardrailError, got ok=%v err=%v", ok, err)
+	}
+	if errorClass(err) != "guardrail" {
+		t.Errorf("error class = %s", errorClass(err))
+	}
+	if info := events[0].Actions.StateDelta["_guardrail"].(map[string]any); info["action"] != "block" {
+		t.Errorf("event = %v", info)
+	}
+
+	// A node's own guardrails replace the flow's
+	node.Guardrails = &config.GuardrailsConfig{Classifier: classifier, Action: "route", Node: "review"}
+	_, routed, _, err := a.applyGuardrails(context.Background(), node, node.Name, unsafe, "Take two pills.", state, yield)
+	if err != nil || !routed {
+		t.Fatalf("route: routed=%v err=%v", routed, err)
+	}
+	if got := takeRoute(state); got != "review" {
+		t.Errorf("route target = %q", got)
+	}
+	if class, _ := state.Get("_error_class"); class != "guardrail" {
+		t.Errorf("_error_class = %v", class)
+	}
+
+	// Redaction keeps the rest of the answer
+	node.Guardrails = &config.GuardrailsConfig{PII: []string{"email"}, Action: "redact"}
+	output, _, _, err := a.applyGuardrails(context.Background(), node, node.Name, nil, "Mail bob@example.org today.", state, yield)
+	if err != nil || output != "Mail [REDACTED:email] today." {
+		t.Errorf("redacted output = %q, err = %v", output, err)
+	}
+}
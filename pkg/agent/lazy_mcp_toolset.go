@@ -126,16 +126,17 @@ func (l *LazyMCPToolset) CloneForFleet(client *sandbox.LazyNodeClient, backend s
 // isSandboxMode returns true if the MCP server should run inside a container/pod.
 // True when a sandbox backend is set AND the transport is stdio.
 func (l *LazyMCPToolset) isSandboxMode() bool {
-	if l.isSSETransport() {
+	if l.isRemoteTransport() {
 		return false
 	}
 	return l.sandboxBackend != nil
 }
 
-// isSSETransport returns true if the MCP server uses SSE transport (remote URL).
-// SSE servers always stay on the host — only stdio servers get containerized.
-func (l *LazyMCPToolset) isSSETransport() bool {
-	return l.serverCfg.Transport == "sse"
+// isRemoteTransport returns true if the MCP server is reached over HTTP (SSE
+// or Streamable HTTP). Remote servers always stay on the host — only stdio
+// servers get containerized.
+func (l *LazyMCPToolset) isRemoteTransport() bool {
+	return l.serverCfg.IsRemote()
 }
 
 // Name returns the MCP server name.
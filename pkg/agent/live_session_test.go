@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/adk/session"
+)
+
+func TestNodeWindowStart(t *testing.T) {
+	a := &AstonishAgent{Config: &config.AgentConfig{Nodes: []config.Node{
+		{Name: "ask", Type: "input"},
+		{Name: "plan", Type: "llm"},
+		{Name: "act", Type: "llm"},
+	}}}
+	transition := func(node string, resumed bool) *session.Event {
+		var ev *session.Event
+		a.emitNodeTransition(node, resumed, NewMockState(), func(e *session.Event, _ error) bool {
+			ev = e
+			return true
+		})
+		return ev
+	}
+	event := func(author string, delta map[string]any) *session.Event {
+		ev := session.NewEvent("inv")
+		ev.Author = author
+		ev.Actions.StateDelta = delta
+		return ev
+	}
+
+	tests := []struct {
+		name   string
+		events []*session.Event
+		want   int
+	}{
+		{"no markers", []*session.Event{event("user", nil), event("plan", nil)}, 0},
+		{"after the last node start", []*session.Event{
+			transition("plan", false), event("user", nil), event("plan", nil),
+			transition("act", false), event("user", nil), event("act", nil),
+		}, 4},
+		{"input answer before the next node", []*session.Event{
+			transition("ask", false), event("ask", map[string]any{"current_node": "ask", "waiting_for_input": true}),
+			event("user", nil), event("", map[string]any{"current_node": "plan"}),
+			transition("plan", false), event("user", nil),
+		}, 5},
+		{"resumed after an approval", []*session.Event{
+			transition("plan", false), event("user", nil), event("plan", map[string]any{"awaiting_approval": true}),
+			event("user", nil), transition("plan", true), event("plan", nil),
+		}, 1},
+		{"node looping to itself", []*session.Event{
+			transition("plan", false), event("plan", nil), transition("plan", false), event("plan", nil),
+		}, 3},
+		{"end", []*session.Event{transition("act", false), event("act", nil), transition("END", false)}, 3},
+	}
+	for _, tt := range tests {
+		if got := nodeWindowStart(eventList(tt.events)); got != tt.want {
+			t.Errorf("%s: window starts at %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	if node, ok := NodeStart(transition("act", false)); !ok || node != "act" {
+		t.Errorf("NodeStart = %q, %v", node, ok)
+	}
+}
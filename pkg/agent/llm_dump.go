@@ -0,0 +1,110 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// llmDumpRequest is the request file of a model call written by
+// --dump-llm-io: what the provider is sent after event filtering.
+type llmDumpRequest struct {
+	Seq      int64                        `json:"seq"`
+	Node     string                       `json:"node"`
+	Model    string                       `json:"model"`
+	Stream   bool                         `json:"stream"`
+	Contents []*genai.Content             `json:"contents"`
+	Config   *genai.GenerateContentConfig `json:"config,omitempty"` // System instruction, tool declarations, schema, generation settings
+}
+
+// llmDumpResponse is the response file of a model call. Streamed chunks
+// are counted; the final responses are kept.
+type llmDumpResponse struct {
+	Seq           int64                `json:"seq"`
+	Node          string               `json:"node"`
+	Model         string               `json:"model"`
+	DurationMs    int64                `json:"duration_ms"`
+	PartialChunks int                  `json:"partial_chunks,omitempty"`
+	Responses     []*model.LLMResponse `json:"responses"`
+	Error         string               `json:"error,omitempty"`
+}
+
+// llmDumpLLM writes every request a node sends to its model, and the
+// model's answer, to the --dump-llm-io directory as a numbered pair of
+// JSON files (0001-<node>-request.json, 0001-<node>-response.json). The
+// numbers follow the order of the calls.
+type llmDumpLLM struct {
+	model.LLM
+	dir  string
+	node string
+}
+
+// llmDumpSeq numbers the dumped calls. It is shared by the runs of the
+// process, so their files never overwrite each other.
+var llmDumpSeq atomic.Int64
+
+func (m *llmDumpLLM) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		seq := llmDumpSeq.Add(1)
+		m.write(seq, "request", llmDumpRequest{
+			Seq:      seq,
+			Node:     m.node,
+			Model:    m.Name(),
+			Stream:   stream,
+			Contents: req.Contents,
+			Config:   req.Config,
+		})
+
+		out := llmDumpResponse{Seq: seq, Node: m.node, Model: m.Name()}
+		start := time.Now()
+		defer func() {
+			out.DurationMs = time.Since(start).Milliseconds()
+			m.write(seq, "response", out)
+		}()
+		for resp, err := range m.LLM.GenerateContent(ctx, req, stream) {
+			switch {
+			case err != nil:
+				out.Error = err.Error()
+			case resp != nil && resp.Partial:
+				out.PartialChunks++
+			case resp != nil:
+				out.Responses = append(out.Responses, resp)
+			}
+			if !yield(resp, err) {
+				return
+			}
+		}
+	}
+}
+
+// unsafeFileChars matches the characters of a node name that are replaced
+// in dump file names.
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// write stores one file of a call. Failures are logged: a dump must never
+// fail the run it inspects.
+func (m *llmDumpLLM) write(seq int64, kind string, v any) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		slog.Warn("failed to encode LLM dump", "node", m.node, "error", err)
+		return
+	}
+	if err := os.MkdirAll(m.dir, 0700); err != nil {
+		slog.Warn("failed to create LLM dump directory", "dir", m.dir, "error", err)
+		return
+	}
+	name := fmt.Sprintf("%04d-%s-%s.json", seq, unsafeFileChars.ReplaceAllString(m.node, "_"), kind)
+	if err := os.WriteFile(filepath.Join(m.dir, name), data, 0600); err != nil {
+		slog.Warn("failed to write LLM dump", "file", name, "error", err)
+	}
+}
@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+func TestLLMDump(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "llm-io")
+	mock := &ADKMockModel{Responses: []*genai.Content{genai.NewContentFromText("looks good", genai.RoleModel)}}
+	llm := &llmDumpLLM{LLM: mock, dir: dir, node: "review code"}
+
+	req := &model.LLMRequest{
+		Contents: []*genai.Content{genai.NewContentFromText("review this diff", genai.RoleUser)},
+		Config:   &genai.GenerateContentConfig{SystemInstruction: genai.NewContentFromText("You are a reviewer", genai.RoleUser)},
+	}
+	for _, err := range llm.GenerateContent(context.Background(), req, false) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*-review_code-*.json"))
+	if len(files) != 2 || !strings.HasSuffix(files[0], "-request.json") || !strings.HasSuffix(files[1], "-response.json") {
+		t.Fatalf("dump files = %v, want a request/response pair", files)
+	}
+
+	var request llmDumpRequest
+	data, _ := os.ReadFile(files[0])
+	if err := json.Unmarshal(data, &request); err != nil {
+		t.Fatal(err)
+	}
+	if request.Contents[0].Parts[0].Text != "review this diff" || request.Config.SystemInstruction.Parts[0].Text != "You are a reviewer" {
+		t.Errorf("request = %s, want the contents and the system instruction", data)
+	}
+
+	var response llmDumpResponse
+	data, _ = os.ReadFile(files[1])
+	if err := json.Unmarshal(data, &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Seq != request.Seq || len(response.Responses) != 1 || response.Responses[0].Content.Parts[0].Text != "looks good" {
+		t.Errorf("response = %s, want the model's answer under the request's number", data)
+	}
+}
@@ -0,0 +1,82 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/adk/session"
+)
+
+// defaultMaxNodeVisits bounds how often a node may run between two waits
+// for input, so a loop whose exit condition never holds stops with a
+// diagnostic instead of running on behind a spinner.
+const defaultMaxNodeVisits = 100
+
+// LoopDetectedKey is the state delta key of the event that reports a run
+// stopped by max_node_visits, with the node, its visits and the loop.
+const LoopDetectedKey = "_loop_detected"
+
+// maxNodeVisits returns the flow's limit of runs per node, or 0 when the
+// flow turned it off.
+func (a *AstonishAgent) maxNodeVisits() int {
+	switch limit := a.Config.MaxNodeVisits; {
+	case limit < 0:
+		return 0
+	case limit == 0:
+		return defaultMaxNodeVisits
+	default:
+		return limit
+	}
+}
+
+// visits returns how often node ran since the run last waited for input.
+// A loop through an input node goes at the user's pace (e.g. a REPL), so
+// each answer starts the count again.
+func (s *RunSummary) visits(node string) int {
+	n := 0
+	for i := len(s.Path) - 1; i >= 0 && !s.Path[i].WaitedForInput; i-- {
+		if s.Path[i].Node == node {
+			n++
+		}
+	}
+	return n
+}
+
+// loopTo returns the nodes the run went through since node last ran,
+// starting and ending with node: the loop it keeps going around.
+func (s *RunSummary) loopTo(node string) []string {
+	for i := len(s.Path) - 1; i >= 0; i-- {
+		if s.Path[i].Node != node {
+			continue
+		}
+		loop := []string{node}
+		for _, p := range s.Path[i+1:] {
+			loop = append(loop, p.Node)
+		}
+		return append(loop, node)
+	}
+	return []string{node}
+}
+
+// loopDetectedEvent reports a run stopped because node reached its visit
+// limit. The failure info shows it like a failed node.
+func loopDetectedEvent(node string, visits int, loop []string) *session.Event {
+	return &session.Event{
+		Actions: session.EventActions{
+			StateDelta: map[string]any{
+				LoopDetectedKey: map[string]any{
+					"node":   node,
+					"visits": visits,
+					"loop":   loop,
+				},
+				"_failure_info": map[string]any{
+					"title":          "Loop Detected",
+					"reason":         fmt.Sprintf("Node '%s' already ran %d times in this run, going around %s. The conditions that leave the loop never held.", node, visits, strings.Join(loop, " → ")),
+					"suggestion":     "Check the conditions of the edges that leave the loop, or raise max_node_visits if the flow needs more rounds.",
+					"original_error": fmt.Sprintf("max_node_visits (%d) reached at node '%s'", visits, node),
+				},
+				"_processing_info": true,
+			},
+		},
+	}
+}
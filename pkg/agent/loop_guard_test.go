@@ -0,0 +1,107 @@
+package agent
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/adk/agent"
+	"google.golang.org/genai"
+)
+
+func TestMaxNodeVisits(t *testing.T) {
+	a := &AstonishAgent{Config: &config.AgentConfig{
+		MaxNodeVisits: 3,
+		Nodes: []config.Node{
+			{Name: "poll", Type: "script", Script: `x["polls"] = x.get("polls", 0) + 1`},
+			{Name: "check", Type: "script", Script: `x["ready"] = False`},
+		},
+		Flow: []config.FlowItem{
+			{From: "START", To: "poll"},
+			{From: "poll", To: "check"},
+			{From: "check", Edges: []config.Edge{{To: "END", Condition: "lambda x: x['ready']"}, {To: "poll", Condition: "true"}}},
+		},
+	}}
+	state := NewMockState()
+	ctx := &MockInvocationContext{Context: context.Background(), StateVal: state}
+	var loop map[string]any
+	var reachedEnd bool
+	for ev, err := range a.Run(ctx) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info, ok := ev.Actions.StateDelta[LoopDetectedKey].(map[string]any); ok {
+			loop = info
+		}
+		if ev.Actions.StateDelta["current_node"] == "END" {
+			reachedEnd = true
+		}
+	}
+	if loop == nil || !reachedEnd {
+		t.Fatalf("loop = %v, reached END = %v", loop, reachedEnd)
+	}
+	if loop["node"] != "poll" || loop["visits"] != 3 || !slices.Equal(loop["loop"].([]string), []string{"poll", "check", "poll"}) {
+		t.Errorf("loop = %v", loop)
+	}
+	if polls, _ := state.Get("polls"); toInt(polls) != 3 {
+		t.Errorf("polls = %v, want 3", polls)
+	}
+}
+
+func TestMaxNodeVisits_InputLoop(t *testing.T) {
+	a := &AstonishAgent{Config: &config.AgentConfig{
+		MaxNodeVisits: 2,
+		Nodes: []config.Node{
+			{Name: "ask", Type: "input", Prompt: "Command?", OutputModel: map[string]string{"command": "str"}},
+			{Name: "echo", Type: "script", Script: `x["rounds"] = x.get("rounds", 0) + 1`},
+		},
+		Flow: []config.FlowItem{
+			{From: "START", To: "ask"},
+			{From: "ask", To: "echo"},
+			{From: "echo", To: "ask"},
+		},
+	}}
+	state := NewMockState()
+	mock := &MockInvocationContext{Context: context.Background(), StateVal: state}
+
+	// A REPL: each round waits for the user, so it may run past the limit
+	for round := 0; round <= 4; round++ {
+		var ctx agent.InvocationContext = mock
+		if round > 0 {
+			ctx = &answerContext{MockInvocationContext: mock, content: genai.NewContentFromText("status", genai.RoleUser)}
+		}
+		for ev, err := range a.Run(ctx) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := ev.Actions.StateDelta[LoopDetectedKey]; ok {
+				t.Fatalf("round %d: loop detected in a loop through an input node", round)
+			}
+		}
+	}
+	if rounds, _ := state.Get("rounds"); toInt(rounds) != 4 {
+		t.Errorf("rounds = %v, want 4", rounds)
+	}
+}
+
+func TestRunSummaryVisits(t *testing.T) {
+	s := &RunSummary{Path: []NodeRunSummary{
+		{Node: "poll"}, {Node: "check"}, {Node: "poll"},
+		{Node: "ask", WaitedForInput: true},
+		{Node: "poll"}, {Node: "check"},
+	}}
+	tests := []struct {
+		node string
+		want int
+	}{
+		{"poll", 1}, // Runs before the input wait don't count
+		{"check", 1},
+		{"ask", 0},
+	}
+	for _, tt := range tests {
+		if got := s.visits(tt.node); got != tt.want {
+			t.Errorf("visits(%q) = %d, want %d", tt.node, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package agent
+
+import (
+	"log/slog"
+
+	"google.golang.org/adk/session"
+)
+
+// refreshMCPHealth copies the health flags of the flow's MCP servers into
+// the state (mcp:<server>:healthy), so conditions can route around a server
+// that is down, e.g. x['mcp:github:healthy'] == False. Only changed flags
+// are written; they ride along with the next event.
+func (a *AstonishAgent) refreshMCPHealth(state session.State, pendingStateDelta map[string]any) {
+	if a.MCPHealth == nil {
+		return
+	}
+	for key, healthy := range a.MCPHealth() {
+		if current, err := state.Get(key); err == nil && current == healthy {
+			continue
+		}
+		if err := state.Set(key, healthy); err != nil {
+			slog.Warn("failed to set MCP health flag", "key", key, "error", err)
+			continue
+		}
+		pendingStateDelta[key] = healthy
+	}
+}
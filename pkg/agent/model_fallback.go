@@ -0,0 +1,154 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/provider/llmerror"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+)
+
+// modelFallbacks returns the failover chain for a node: the node's own
+// model_fallbacks, else the flow-level list.
+func (a *AstonishAgent) modelFallbacks(node *config.Node) []string {
+	if len(node.ModelFallbacks) > 0 {
+		return node.ModelFallbacks
+	}
+	if a.Config != nil {
+		return a.Config.ModelFallbacks
+	}
+	return nil
+}
+
+// recordModelUsed stores which model produced a node's final answer in
+// _model_used when the node ran with a failover chain.
+func recordModelUsed(state session.State, llm model.LLM) {
+	if cached, ok := llm.(*cachedResponseLLM); ok {
+		llm = cached.LLM
+	}
+	if dump, ok := llm.(*llmDumpLLM); ok {
+		llm = dump.LLM
+	}
+	fb, ok := llm.(*fallbackLLM)
+	if !ok {
+		return
+	}
+	if used := fb.usedModel(); used != "" {
+		state.Set("_model_used", used)
+	}
+}
+
+// resolveFallbackModel turns a model_fallbacks entry into a client. Entries
+// are "provider/model" when the first segment names a configured provider,
+// otherwise a model on the agent's own provider (so OpenRouter-style names
+// like "openai/gpt-4o" keep their slash).
+func (a *AstonishAgent) resolveFallbackModel(ctx context.Context, entry string) (model.LLM, string, error) {
+	providerName, modelName := a.ProviderName, entry
+	if prefix, rest, ok := strings.Cut(entry, "/"); ok {
+		if a.hasProvider(prefix) {
+			providerName, modelName = prefix, rest
+		}
+	}
+	if providerName == "" {
+		providerName = a.AppConfig.General.DefaultProvider
+	}
+	llm, err := a.cachedLLM(ctx, providerName, modelName)
+	return llm, a.modelLabel(providerName, modelName), err
+}
+
+// fallbackLLM calls the primary model and, when it fails with a provider
+// error (5xx, rate limit, auth) before streaming anything, retries the same
+// request on each model_fallbacks entry in turn.
+type fallbackLLM struct {
+	model.LLM
+	primaryName string
+	chain       []string
+	resolve     func(ctx context.Context, entry string) (model.LLM, string, error)
+
+	mu   sync.Mutex
+	used string
+}
+
+func (m *fallbackLLM) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		var lastErr error
+		for i := 0; i <= len(m.chain); i++ {
+			llm, name := m.LLM, m.primaryName
+			if i > 0 {
+				var err error
+				llm, name, err = m.resolve(ctx, m.chain[i-1])
+				if err != nil {
+					slog.Warn("skipping fallback model", "component", "fallback", "model", m.chain[i-1], "error", err)
+					continue
+				}
+			}
+
+			r := *req
+			r.Model = llm.Name()
+			emitted := false
+			var failErr error
+			for resp, err := range llm.GenerateContent(ctx, &r, stream) {
+				if err != nil && !emitted && i < len(m.chain) && isFailoverError(err) {
+					failErr = err
+					break
+				}
+				if err == nil {
+					emitted = true
+					m.setUsed(name)
+				}
+				if !yield(resp, err) {
+					return
+				}
+			}
+			if failErr == nil {
+				return
+			}
+
+			lastErr = failErr
+			slog.Warn("model failed, falling back", "component", "fallback", "model", name, "error", failErr)
+		}
+		if lastErr != nil {
+			yield(nil, fmt.Errorf("all fallback models failed: %w", lastErr))
+		}
+	}
+}
+
+func (m *fallbackLLM) setUsed(name string) {
+	m.mu.Lock()
+	m.used = name
+	m.mu.Unlock()
+}
+
+func (m *fallbackLLM) usedModel() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.used
+}
+
+// isFailoverError reports whether err is a provider-side failure another
+// model might not hit: server errors, rate limits and auth rejections.
+func isFailoverError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if code := llmerror.StatusCode(err); code != 0 {
+		return code >= 500 || code == 429 || code == 401 || code == 403
+	}
+	if isRateLimitError(err) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, pattern := range []string{"internal server error", "bad gateway", "service unavailable", "gateway timeout", "overloaded", "unauthorized", "forbidden", "invalid api key"} {
+		if strings.Contains(msg, pattern) {
+			return true
+		}
+	}
+	return false
+}
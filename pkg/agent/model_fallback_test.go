@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/provider/llmerror"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+func failingLLM(err error) *MockLLM {
+	return &MockLLM{GenerateContentFunc: func(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+		return func(yield func(*model.LLMResponse, error) bool) { yield(nil, err) }
+	}}
+}
+
+func TestFallbackLLM_FailsOverOnProviderError(t *testing.T) {
+	var resolved []string
+	fb := &fallbackLLM{
+		LLM:         failingLLM(&llmerror.LLMError{StatusCode: 503, Provider: "primary"}),
+		primaryName: "primary/big",
+		chain:       []string{"broken/model", "backup/model"},
+		resolve: func(ctx context.Context, entry string) (model.LLM, string, error) {
+			resolved = append(resolved, entry)
+			if entry == "broken/model" {
+				return nil, "", errors.New("provider instance 'broken' not found")
+			}
+			return &MockLLM{}, entry, nil
+		},
+	}
+
+	var text string
+	for resp, err := range fb.GenerateContent(context.Background(), &model.LLMRequest{}, false) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		text = resp.Content.Parts[0].Text
+	}
+	if text != "Mock response" {
+		t.Errorf("text = %q, want fallback response", text)
+	}
+	if got := fb.usedModel(); got != "backup/model" {
+		t.Errorf("usedModel() = %q, want backup/model", got)
+	}
+
+	state := NewMockState()
+	recordModelUsed(state, fb)
+	if got, _ := state.Get("_model_used"); got != "backup/model" {
+		t.Errorf("_model_used = %v, want backup/model", got)
+	}
+}
+
+func TestFallbackLLM_DoesNotFailOverOnRequestError(t *testing.T) {
+	called := false
+	fb := &fallbackLLM{
+		LLM:   failingLLM(&llmerror.LLMError{StatusCode: 400, Provider: "primary"}),
+		chain: []string{"backup/model"},
+		resolve: func(ctx context.Context, entry string) (model.LLM, string, error) {
+			called = true
+			return &MockLLM{}, entry, nil
+		},
+	}
+
+	var gotErr error
+	for _, err := range fb.GenerateContent(context.Background(), &model.LLMRequest{}, false) {
+		gotErr = err
+	}
+	if gotErr == nil || called {
+		t.Errorf("err = %v, fallback called = %v; want the 400 surfaced without failover", gotErr, called)
+	}
+}
+
+func TestFallbackLLM_KeepsPrimaryAfterPartialStream(t *testing.T) {
+	primary := &MockLLM{GenerateContentFunc: func(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+		return func(yield func(*model.LLMResponse, error) bool) {
+			if !yield(&model.LLMResponse{Content: genai.NewContentFromText("partial", "model")}, nil) {
+				return
+			}
+			yield(nil, &llmerror.LLMError{StatusCode: 502})
+		}
+	}}
+	fb := &fallbackLLM{
+		LLM:   primary,
+		chain: []string{"backup/model"},
+		resolve: func(ctx context.Context, entry string) (model.LLM, string, error) {
+			t.Fatal("must not fail over after output was streamed")
+			return nil, "", nil
+		},
+	}
+	for range fb.GenerateContent(context.Background(), &model.LLMRequest{}, true) {
+	}
+}
@@ -0,0 +1,105 @@
+package agent
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/adk/session"
+)
+
+// AbortKey is the reserved state key any node can set to stop the run:
+// true, a message, or a map with message and exit_code. The run goes to
+// END with a failure summary instead of following its edges.
+const AbortKey = "_abort"
+
+// RunAbortedKey is the state delta key of the event that reports an
+// aborted run, with the node that stopped it, the message and the exit code.
+const RunAbortedKey = "_run_aborted"
+
+// defaultAbortExitCode is the exit code of aborted runs that set none.
+const defaultAbortExitCode = 1
+
+// runAbort is a request to stop the run.
+type runAbort struct {
+	message  string
+	exitCode int
+}
+
+// parseAbort reads a value of the _abort key. false, "" and "false" (as
+// update_state renders it) do not abort.
+func parseAbort(v any) (runAbort, bool) {
+	r := runAbort{exitCode: defaultAbortExitCode}
+	switch v := v.(type) {
+	case bool:
+		return r, v
+	case string:
+		v = strings.TrimSpace(v)
+		if v == "" || strings.EqualFold(v, "false") {
+			return r, false
+		}
+		if !strings.EqualFold(v, "true") {
+			r.message = v
+		}
+		return r, true
+	case map[string]any:
+		r.message, _ = v["message"].(string)
+		if code := toInt(v["exit_code"]); code > 0 {
+			r.exitCode = code
+		}
+		return r, true
+	}
+	return r, false
+}
+
+// takeAbort returns the abort a node requested through the _abort key and
+// clears the key, so a later run of the session is not stopped by it.
+func takeAbort(state session.State, pendingStateDelta map[string]any) (runAbort, bool) {
+	v, _ := state.Get(AbortKey)
+	r, ok := parseAbort(v)
+	if !ok {
+		return r, false
+	}
+	state.Set(AbortKey, nil)
+	pendingStateDelta[AbortKey] = nil
+	return r, true
+}
+
+// nodeAbort returns the abort of an abort node, with its message rendered
+// from state.
+func (a *AstonishAgent) nodeAbort(node *config.Node, state session.State) runAbort {
+	r := runAbort{message: strings.TrimSpace(a.renderNodeString(node, node.Message, state)), exitCode: node.ExitCode}
+	if r.exitCode <= 0 {
+		r.exitCode = defaultAbortExitCode
+	}
+	return r
+}
+
+// abortEvent reports the run stopped by nodeName. The failure info shows it
+// like a failed node; the run then transitions to END.
+func abortEvent(nodeName string, r runAbort) *session.Event {
+	slog.Info("run aborted", "node", nodeName, "message", r.message, "exit_code", r.exitCode)
+	reason := r.message
+	if reason == "" {
+		reason = fmt.Sprintf("Node '%s' stopped the run.", nodeName)
+	}
+	return &session.Event{
+		Actions: session.EventActions{
+			StateDelta: map[string]any{
+				RunAbortedKey: map[string]any{
+					"node":      nodeName,
+					"message":   r.message,
+					"exit_code": r.exitCode,
+				},
+				"_failure_info": map[string]any{
+					"title":          "Run Aborted",
+					"reason":         reason,
+					"suggestion":     "",
+					"original_error": fmt.Sprintf("aborted by node '%s' (exit code %d)", nodeName, r.exitCode),
+				},
+				"_processing_info": true,
+			},
+		},
+	}
+}
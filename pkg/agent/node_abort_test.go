@@ -0,0 +1,60 @@
+package agent
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/config"
+)
+
+func TestAbort(t *testing.T) {
+	run := func(t *testing.T, nodes []config.Node, flow []config.FlowItem) ([]string, map[string]any) {
+		a := &AstonishAgent{Config: &config.AgentConfig{Nodes: nodes, Flow: flow}}
+		state := NewMockState()
+		ctx := &MockInvocationContext{Context: context.Background(), StateVal: state}
+		var path []string
+		var aborted map[string]any
+		for ev, err := range a.Run(ctx) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			if node, ok := ev.Actions.StateDelta["current_node"].(string); ok && ev.Actions.StateDelta["node_type"] != nil {
+				path = append(path, node)
+			}
+			if info, ok := ev.Actions.StateDelta[RunAbortedKey].(map[string]any); ok {
+				aborted = info
+			}
+		}
+		if v, _ := state.Get(AbortKey); v != nil {
+			t.Errorf("_abort not cleared: %v", v)
+		}
+		return path, aborted
+	}
+
+	t.Run("state key", func(t *testing.T) {
+		path, aborted := run(t, []config.Node{
+			{Name: "check", Type: "script", Script: `x["_abort"] = {"message": "quota exceeded", "exit_code": 3}`},
+			{Name: "deploy", Type: "script", Script: `x["done"] = True`},
+		}, []config.FlowItem{{From: "START", To: "check"}, {From: "check", To: "deploy"}, {From: "deploy", To: "END"}})
+		if !slices.Equal(path, []string{"check", "END"}) {
+			t.Errorf("path = %v, want the run to stop after check", path)
+		}
+		if aborted["node"] != "check" || aborted["message"] != "quota exceeded" || aborted["exit_code"] != 3 {
+			t.Errorf("aborted = %v", aborted)
+		}
+	})
+
+	t.Run("abort node", func(t *testing.T) {
+		path, aborted := run(t, []config.Node{
+			{Name: "setup", Type: "update_state", Updates: map[string]string{"repo": "astonish"}},
+			{Name: "stop", Type: "abort", Message: "No access to {repo}"},
+		}, []config.FlowItem{{From: "START", To: "setup"}, {From: "setup", To: "stop"}})
+		if !slices.Equal(path, []string{"setup", "stop", "END"}) {
+			t.Errorf("path = %v", path)
+		}
+		if aborted["node"] != "stop" || aborted["message"] != "No access to astonish" || aborted["exit_code"] != defaultAbortExitCode {
+			t.Errorf("aborted = %v", aborted)
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// maxSummaryValueLen caps how much of each value a display summary shows.
+const maxSummaryValueLen = 120
+
+// emitDisplaySummary yields a compact, one-line-per-key summary of the node's
+// output_model values. It is used for nodes with display: summary.
+func (a *AstonishAgent) emitDisplaySummary(node *config.Node, state session.State, yield func(*session.Event, error) bool) bool {
+	keys := make([]string, 0, len(node.OutputModel))
+	for key := range node.OutputModel {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var lines []string
+	for _, key := range keys {
+		val, err := state.Get(key)
+		if err != nil {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", key, summarizeValue(val)))
+	}
+	if len(lines) == 0 {
+		return true
+	}
+
+	return yield(&session.Event{
+		LLMResponse: model.LLMResponse{
+			Content: &genai.Content{
+				Parts: []*genai.Part{{Text: strings.Join(lines, "\n")}},
+				Role:  "model",
+			},
+		},
+		Actions: session.EventActions{
+			StateDelta: map[string]any{
+				"_user_message_display": true, // Shown like a user_message by web clients
+				"_display_summary":      true, // Printed verbatim by console/headless runners
+			},
+		},
+	}, nil)
+}
+
+// summarizeValue renders a state value as a short single line.
+func summarizeValue(val any) string {
+	switch v := val.(type) {
+	case []any:
+		return fmt.Sprintf("[%d items]", len(v))
+	case []string:
+		return fmt.Sprintf("[%d items]", len(v))
+	case map[string]any:
+		return fmt.Sprintf("{%d fields}", len(v))
+	}
+
+	s := strings.TrimSpace(fmt.Sprintf("%v", val))
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i] + " ..."
+	}
+	if len(s) > maxSummaryValueLen {
+		s = s[:maxSummaryValueLen] + "..."
+	}
+	return s
+}
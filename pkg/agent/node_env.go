@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/credentials"
+	"github.com/SAP/astonish/pkg/store"
+	"google.golang.org/adk/session"
+	"google.golang.org/adk/tool"
+)
+
+// nodeEnv returns the env of node rendered with state, with its
+// {{CREDENTIAL:name:field}} placeholders resolved, or nil.
+func (a *AstonishAgent) nodeEnv(ctx context.Context, node *config.Node, state session.State) map[string]string {
+	if len(node.Env) == 0 {
+		return nil
+	}
+	values := make(map[string]any, len(node.Env))
+	for k, v := range node.Env {
+		values[k] = a.renderNodeString(node, v, state)
+	}
+	if resolver := a.credentialResolver(ctx); resolver != nil {
+		credentials.SubstituteAndRestore(values, resolver)
+	}
+	env := make(map[string]string, len(values))
+	for k, v := range values {
+		env[k] = fmt.Sprint(v)
+	}
+	return env
+}
+
+// credentialResolver returns the credential store of the run: the one of
+// the request context (platform mode), else the agent's.
+func (a *AstonishAgent) credentialResolver(ctx context.Context) credentials.CredentialResolver {
+	if cs := store.CredentialStoreFromContext(ctx); cs != nil {
+		return credentials.NewStoreAdapter(cs)
+	}
+	return a.CredentialStore
+}
+
+// nodeToolsets returns the MCP toolsets for the node running in ctx: the
+// servers started with its env when it has one, else the flow's.
+func (a *AstonishAgent) nodeToolsets(ctx context.Context) ([]tool.Toolset, error) {
+	env := NodeEnv(ctx)
+	if len(env) == 0 {
+		return a.Toolsets, nil
+	}
+	if a.ToolsetsWithEnv == nil {
+		if len(a.Toolsets) == 0 {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("MCP servers cannot be started with the node's env here")
+	}
+	toolsets, err := a.ToolsetsWithEnv(ctx, env)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start MCP servers with the node's env: %w", err)
+	}
+	return toolsets, nil
+}
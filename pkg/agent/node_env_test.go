@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/credentials"
+	adkagent "google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+	"google.golang.org/adk/tool"
+)
+
+// staticToolset is a toolset with fixed tools.
+type staticToolset struct {
+	tools []tool.Tool
+}
+
+func (s *staticToolset) Name() string { return "static" }
+func (s *staticToolset) Tools(adkagent.ReadonlyContext) ([]tool.Tool, error) {
+	return s.tools, nil
+}
+
+func TestToolNodeEnv(t *testing.T) {
+	creds, err := credentials.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := creds.Set("gh-acme", &credentials.Credential{Type: credentials.CredBearer, Token: "tok-acme"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var seen map[string]string
+	mcpTool := func(server string) tool.Tool {
+		return &MockTool{
+			NameFunc: func() string { return "list_issues" },
+			RunFunc: func(ctx tool.Context, args any) (map[string]any, error) {
+				seen = map[string]string{"server": server, "token": NodeEnv(ctx)["GITHUB_TOKEN"]}
+				return map[string]any{"ok": true}, nil
+			},
+		}
+	}
+	var startedWith map[string]string
+	a := &AstonishAgent{
+		AutoApprove:     true,
+		CredentialStore: creds,
+		Toolsets:        []tool.Toolset{&staticToolset{tools: []tool.Tool{mcpTool("shared")}}},
+		ToolsetsWithEnv: func(ctx context.Context, env map[string]string) ([]tool.Toolset, error) {
+			startedWith = env
+			return []tool.Toolset{&staticToolset{tools: []tool.Tool{mcpTool("scoped")}}}, nil
+		},
+	}
+	state := NewMockState()
+	state.Data["org"] = "acme"
+	node := &config.Node{
+		Name:           "issues",
+		Type:           "tool",
+		ToolsSelection: []string{"list_issues"},
+		Env:            map[string]string{"GITHUB_TOKEN": "{{CREDENTIAL:gh-{org}:token}}"},
+	}
+	run := func() bool {
+		return a.handleToolNode(context.Background(), node, state, func(_ *session.Event, err error) bool {
+			if err != nil {
+				t.Logf("node error: %v", err)
+			}
+			return err == nil
+		})
+	}
+
+	if !run() {
+		t.Fatal("tool node failed")
+	}
+	if startedWith["GITHUB_TOKEN"] != "tok-acme" {
+		t.Errorf("MCP servers started with %v, want the resolved credential", startedWith)
+	}
+	if seen["server"] != "scoped" || seen["token"] != "tok-acme" {
+		t.Errorf("tool ran with %v, want the scoped server and the node's env in its context", seen)
+	}
+
+	// Nodes without env use the flow's servers
+	node.Env = nil
+	if !run() || seen["server"] != "shared" || seen["token"] != "" {
+		t.Errorf("tool ran with %v, want the shared server without env", seen)
+	}
+
+	// Without a way to start scoped servers, the node fails rather than
+	// using the shared ones
+	node.Env = map[string]string{"GITHUB_TOKEN": "x"}
+	a.ToolsetsWithEnv = nil
+	if run() {
+		t.Error("a node with env ran on the shared MCP servers")
+	}
+}
@@ -23,7 +23,7 @@ func (a *AstonishAgent) handleUpdateStateNode(ctx agent.InvocationContext, node
 	if node.Action == "" && len(node.Updates) > 0 {
 		stateDelta := make(map[string]any)
 		for key, valueTemplate := range node.Updates {
-			value := a.renderString(valueTemplate, state)
+			value := a.renderNodeString(node, valueTemplate, state)
 			if err := state.Set(key, value); err != nil {
 				yield(nil, fmt.Errorf("failed to set state key %s: %w", key, err))
 				return false
@@ -67,7 +67,7 @@ func (a *AstonishAgent) handleUpdateStateNode(ctx agent.InvocationContext, node
 
 	// Render string values
 	if strVal, ok := valueToUse.(string); ok {
-		valueToUse = a.renderString(strVal, state)
+		valueToUse = a.renderNodeString(node, strVal, state)
 	}
 
 	stateDelta := make(map[string]any)
@@ -198,9 +198,10 @@ func (a *AstonishAgent) handleToolApproval(ctx agent.InvocationContext, state se
 
 	// Strip the timestamp prefix injected by NewTimestampedUserContent
 	// (format: "[2026-03-20 14:30:05 UTC]\n") before checking approval.
-	responseText = strings.ToLower(strings.TrimSpace(StripTimestamp(responseText)))
+	answer := strings.TrimSpace(StripTimestamp(responseText))
+	responseText = strings.ToLower(answer)
 
-	approved := responseText == "yes" || responseText == "y" || responseText == "approve"
+	approved := ApprovesCall(responseText)
 
 	if approved {
 		// Get current node for node-scoped approval
@@ -211,26 +212,32 @@ func (a *AstonishAgent) handleToolApproval(ctx agent.InvocationContext, state se
 			}
 		}
 
-		// Grant approval using the node-scoped key
-		approvalKey := fmt.Sprintf("approval:%s:%s", currentNode, toolName)
-		state.Set(approvalKey, true)
+		// Grant approval using the node-scoped key, bound to the approved args,
+		// plus the standing approval the answer asks for
+		runHooksFrom(ctx).toolDecision(state, toolName, true)
+		grantApproval(state, currentNode, toolName)
+		grants := grantStandingApproval(state, currentNode, toolName, responseText)
 		state.Set("awaiting_approval", false)
 		state.Set("approval_tool", "")
 		state.Set("approval_args", nil)
 
 		// Emit state delta event so history fallback sees approval was resolved
+		delta := map[string]any{
+			"awaiting_approval": false,
+		}
+		if grants != nil {
+			delta[approvalGrantsKey] = grants
+		}
 		yield(&session.Event{
 			Actions: session.EventActions{
-				StateDelta: map[string]any{
-					"awaiting_approval": false,
-				},
+				StateDelta: delta,
 			},
 		}, nil)
 
 		// [MCP-SPECIFIC FIX] Inject a very specific instruction for MCP tools
 		// MCP tools are sensitive to exact arguments - must retry with same args
 		retryPrompt := fmt.Sprintf(
-			"User approved execution. IMMEDIATELY call the function '%s' again with the exact same arguments as before.",
+			"User approved execution. IMMEDIATELY call the function '%s' again with the exact same arguments as before. The approval only covers those arguments; a call with different arguments needs approval again.",
 			toolName,
 		)
 
@@ -241,7 +248,24 @@ func (a *AstonishAgent) handleToolApproval(ctx agent.InvocationContext, state se
 
 		return true // Continue execution with retry prompt
 	} else {
-		// User denied - move to next node
+		// User denied - nodes with on_tool_denied: feedback continue and
+		// let the model find another way, up to maxToolDenials times
+		runStats(state).ApprovalsDenied++
+		runHooksFrom(ctx).toolDecision(state, toolName, false)
+		deniedNode := ""
+		if nodeVal, _ := state.Get("current_node"); nodeVal != nil {
+			deniedNode, _ = nodeVal.(string)
+		}
+		deniedArgs, _ := state.Get("approval_args")
+		a.auditDenial(ctx, deniedNode, toolName, deniedArgs, a.auditApprover())
+		if node, found := a.getNode(deniedNode); found && node.OnToolDenied == config.ToolDeniedFeedback {
+			if denials := nextToolDenial(state); denials <= maxToolDenials {
+				return a.feedToolDenial(ctx, state, toolName, answer, denials, yield)
+			}
+			slog.Debug("tool denial limit reached, skipping node", "node", deniedNode, "max_denials", maxToolDenials)
+		}
+
+		// Move to next node
 		state.Set("awaiting_approval", false)
 		state.Set("approval_tool", "")
 		state.Set("approval_args", nil)
@@ -292,9 +316,12 @@ func (a *AstonishAgent) handleParallelNode(ctx agent.InvocationContext, node *co
 		return false
 	}
 
-	// Convert to slice
+	// Convert to slice; a map is iterated entry by entry
 	var items []any
-	if l, ok := listVal.([]any); ok {
+	entries, isMap := parallelMapEntries(listVal)
+	if isMap {
+		items = entries
+	} else if l, ok := listVal.([]any); ok {
 		items = l
 	} else if l, ok := listVal.([]string); ok {
 		for _, v := range l {
@@ -314,7 +341,7 @@ func (a *AstonishAgent) handleParallelNode(ctx agent.InvocationContext, node *co
 		} else if l, ok := listVal.([]interface{}); ok {
 			items = l
 		} else {
-			yield(nil, fmt.Errorf("variable '%s' is not a list (type: %T)", listKey, listVal))
+			yield(nil, fmt.Errorf("variable '%s' is not a list or map (type: %T)", listKey, listVal))
 			return false
 		}
 	}
@@ -322,6 +349,9 @@ func (a *AstonishAgent) handleParallelNode(ctx agent.InvocationContext, node *co
 	if len(items) == 0 {
 		return true
 	}
+	if !a.guardParallelCost(node, state, len(items), yield) {
+		return false
+	}
 
 	// 2. Prepare for aggregation
 	if len(node.OutputModel) != 1 {
@@ -334,12 +364,208 @@ func (a *AstonishAgent) handleParallelNode(ctx agent.InvocationContext, node *co
 		break
 	}
 
-	// 3. Execute in Parallel
+	// 3. Execute in chunks of max_items (one chunk unless the list is
+	// larger), reducing each chunk when reduce instructions are set
+	chunks := chunkParallelItems(items, pConfig.MaxItems)
+	var finalResults, partials []any
+	offset := 0
+	for c, chunk := range chunks {
+		title := node.Name
+		if len(chunks) > 1 {
+			title = fmt.Sprintf("%s (chunk %d/%d)", node.Name, c+1, len(chunks))
+			if !yield(parallelChunkEvent(node.Name, c+1, len(chunks), "process"), nil) {
+				return false
+			}
+		}
+		results, ok := a.runParallelBatch(ctx, node, state, chunk, offset, len(items), title, outputKey, yield)
+		if !ok {
+			return false
+		}
+		offset += len(chunk)
+
+		if pConfig.Reduce == "" {
+			finalResults = append(finalResults, results...)
+			continue
+		}
+		if len(chunks) > 1 && !yield(parallelChunkEvent(node.Name, c+1, len(chunks), "reduce"), nil) {
+			return false
+		}
+		if isMap {
+			results = parallelEntryResults(results)
+		}
+		partial, err := a.reduceParallelResults(ctx, node, outputKey, results)
+		if err != nil {
+			yield(nil, err)
+			return false
+		}
+		partials = append(partials, partial)
+	}
+
+	if pConfig.Reduce != "" {
+		merged := partials[0]
+		if len(partials) > 1 {
+			var err error
+			if merged, err = a.reduceParallelResults(ctx, node, outputKey, partials); err != nil {
+				yield(nil, err)
+				return false
+			}
+		}
+		state.Set(outputKey, merged)
+		yield(&session.Event{
+			Actions: session.EventActions{
+				StateDelta: map[string]any{
+					outputKey: merged,
+				},
+			},
+		}, nil)
+		return true
+	}
+
+	// 4. Update Parent State with Aggregated Results
+
+	existingVal, _ := state.Get(outputKey)
+
+	// Results of map entries are keyed by the original keys
+	if isMap {
+		final := a.parallelResultMap(existingVal, finalResults, outputKey)
+		state.Set(outputKey, final)
+		yield(&session.Event{
+			Actions: session.EventActions{
+				StateDelta: map[string]any{
+					outputKey: final,
+				},
+			},
+		}, nil)
+		return true
+	}
+	var final []any
+
+	if existingVal != nil {
+		if l, ok := existingVal.([]any); ok {
+			final = l
+		} else {
+			final = []any{}
+		}
+	} else {
+		final = []any{}
+	}
+
+	// Aggregate results based on output_action
+	// If output_action is "append", flatten lists; otherwise keep as-is
+	outputAction := "append" // Default behavior
+	if node.OutputAction != "" {
+		outputAction = node.OutputAction
+	}
+
+	for _, res := range finalResults {
+		if outputAction == "append" {
+			// Check if res is a JSON string that needs parsing
+			if strRes, ok := res.(string); ok {
+				// Try to parse as JSON
+				cleaned := a.cleanAndFixJson(strRes)
+				var parsed any
+				if err := json.Unmarshal([]byte(cleaned), &parsed); err == nil {
+					// Successfully parsed - check if it's a map with the output key
+					if parsedMap, ok := parsed.(map[string]any); ok {
+						// Check if the map contains the output key (e.g., "review_comment_validated")
+						if val, ok := parsedMap[outputKey]; ok {
+							// Extract the value from the nested structure
+							if l, ok := val.([]any); ok {
+								// It's a list - flatten it
+								final = append(final, l...)
+								continue
+							} else {
+								// It's a single value - append it
+								final = append(final, val)
+								continue
+							}
+						}
+					}
+					// If parsed but doesn't match expected structure, treat as regular value
+					res = parsed
+				}
+				// If parsing failed, continue with string as-is
+			}
+
+			// Flatten lists when appending
+			if l, ok := res.([]any); ok {
+				// Flatten the list - append all items from the list
+				final = append(final, l...)
+			} else {
+				// Not a list, append as-is
+				final = append(final, res)
+			}
+		} else {
+			// Keep results as-is (don't flatten)
+			final = append(final, res)
+		}
+	}
+
+	state.Set(outputKey, final)
+
+	yield(&session.Event{
+		Actions: session.EventActions{
+			StateDelta: map[string]any{
+				outputKey: final,
+			},
+		},
+	}, nil)
+
+	return true
+}
+
+// parallelItemEvent reports the n-th completed item (at index idx) of a
+// parallel node with stream_results: the _parallel_item event for clients
+// and the line the console prints above the progress bar.
+func parallelItemEvent(nodeName string, n, total, idx int, ok bool, val any) (*session.Event, string) {
+	status, output := "done", ""
+	if ok {
+		output = summarizeValue(val)
+	} else {
+		status = "failed"
+	}
+
+	line := fmt.Sprintf("[%d/%d] item %d %s", n, total, idx, status)
+	if output != "" {
+		line += ": " + output
+	}
+	return &session.Event{
+		Actions: session.EventActions{
+			StateDelta: map[string]any{
+				"_parallel_item": map[string]any{
+					"node":   nodeName,
+					"index":  idx,
+					"total":  total,
+					"status": status,
+					"output": output,
+				},
+			},
+		},
+	}, line
+}
+
+// runParallelBatch runs the node once per item with up to maxConcurrency
+// branches and returns the successful results in item order. offset is the
+// index of the first item in the full list and total its length, so indexes,
+// branch sessions and progress stay global across chunks. It returns false
+// if the run was cancelled.
+func (a *AstonishAgent) runParallelBatch(ctx agent.InvocationContext, node *config.Node, state session.State, items []any, offset, total int, title, outputKey string, yield func(*session.Event, error) bool) ([]any, bool) {
+	pConfig := node.Parallel
 	maxConcurrency := 1
 	if pConfig.MaxConcurrency > 0 {
 		maxConcurrency = pConfig.MaxConcurrency
 	}
 
+	// Shared pacing for provider rate limits. Branches run on a copy of the
+	// agent whose model goes through the throttle.
+	throttle := newParallelThrottle(pConfig)
+	branch := a
+	if throttle != nil {
+		throttled := *a
+		throttled.LLM = &throttledLLM{LLM: a.LLM, throttle: throttle}
+		branch = &throttled
+	}
+
 	// Semaphore to limit concurrency
 	sem := make(chan struct{}, maxConcurrency)
 	var wg sync.WaitGroup
@@ -351,7 +577,7 @@ func (a *AstonishAgent) handleParallelNode(ctx agent.InvocationContext, node *co
 	successes := make([]bool, len(items))
 
 	// Initialize progress bar UI
-	prog := ui.NewParallelProgram(len(items), node.Name)
+	prog := ui.NewParallelProgram(len(items), title)
 
 	// Channel to signal UI completion
 	uiDone := make(chan struct{})
@@ -410,6 +636,25 @@ func (a *AstonishAgent) handleParallelNode(ctx agent.InvocationContext, node *co
 		return true
 	}
 
+	// With stream_results, report each item as it completes: a line above
+	// the progress bar for the console and a _parallel_item event for
+	// other clients. Returns the console line.
+	completed := int32(offset)
+	streamItem := func(idx int, ok bool, val any) string {
+		n := int(atomic.AddInt32(&completed, 1))
+		event, line := parallelItemEvent(node.Name, n, total, idx, ok, val)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if yieldCancelled {
+			return line
+		}
+		if !yield(event, nil) {
+			yieldCancelled = true
+		}
+		return line
+	}
+
 	// Track active workers
 	var activeWorkers int32
 
@@ -417,9 +662,14 @@ func (a *AstonishAgent) handleParallelNode(ctx agent.InvocationContext, node *co
 		wg.Add(1)
 		go func(idx int, it any) {
 			defer wg.Done()
+			index := offset + idx // position in the full list
 
-			// Acquire semaphore
-			sem <- struct{}{}
+			// Acquire semaphore, unless the run is cancelled while waiting
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
 
 			// Update active count
 			atomic.AddInt32(&activeWorkers, 1)
@@ -451,9 +701,9 @@ func (a *AstonishAgent) handleParallelNode(ctx agent.InvocationContext, node *co
 				Local:  make(map[string]any),
 			}
 
-			scopedState.Local[pConfig.As] = it
+			bindParallelItem(pConfig, scopedState.Local, it)
 			if pConfig.IndexAs != "" {
-				scopedState.Local[pConfig.IndexAs] = idx
+				scopedState.Local[pConfig.IndexAs] = index
 			}
 
 			// Workaround for "Severity" template error in ADK llmagent
@@ -464,7 +714,7 @@ func (a *AstonishAgent) handleParallelNode(ctx agent.InvocationContext, node *co
 			// Create ephemeral session for isolation
 			// This ensures each parallel branch has its own history
 			// Include node name to avoid collisions between different parallel nodes in the same flow
-			newSessionID := fmt.Sprintf("%s:%s:parallel-%d", ctx.Session().ID(), node.Name, idx)
+			newSessionID := fmt.Sprintf("%s:%s:parallel-%d", ctx.Session().ID(), node.Name, index)
 
 			// Create a session using the service
 			// We need to pass AppName and UserID if possible
@@ -494,25 +744,40 @@ func (a *AstonishAgent) handleParallelNode(ctx agent.InvocationContext, node *co
 
 			success := false
 			if node.Type == "tool" {
-				success = a.handleToolNode(scopedCtx, node, scopedState, safeYield)
+				if throttle != nil {
+					if err := throttle.Wait(ctx); err != nil {
+						return
+					}
+				}
+				success = branch.handleToolNode(scopedCtx, node, scopedState, safeYield)
 			} else if node.Type == "llm" {
-				success = a.executeLLMNode(scopedCtx, node, node.Name, scopedState, safeYield)
+				success = branch.executeLLMNode(scopedCtx, node, node.Name, scopedState, safeYield)
 			} else {
 				safeYield(nil, fmt.Errorf("unsupported type for parallel node: %s", node.Type))
 				return
 			}
 
-			// Signal UI that item is finished
-			prog.Send(ui.ItemFinishedMsg{})
+			// If execution failed the error has already been yielded
+			// and there is no result to collect.
+			var val any
+			hasResult := false
+			if success {
+				if v, err := scopedState.Get(outputKey); err == nil {
+					val, hasResult = v, true
+				}
+			}
 
-			if !success {
-				// If execution failed, don't try to get the result
-				// Just return - the error has already been yielded
-				return
+			// Signal UI that item is finished
+			finished := ui.ItemFinishedMsg{}
+			if pConfig.StreamResults {
+				finished.Result = streamItem(index, hasResult, val)
 			}
+			prog.Send(finished)
 
-			val, err := scopedState.Get(outputKey)
-			if err == nil {
+			if entry, ok := it.(parallelEntry); ok && hasResult {
+				val = parallelEntry{key: entry.key, value: val}
+			}
+			if hasResult {
 				mu.Lock()
 				results[idx] = val
 				successes[idx] = true
@@ -523,17 +788,21 @@ func (a *AstonishAgent) handleParallelNode(ctx agent.InvocationContext, node *co
 
 	wg.Wait()
 
+	// Cancelled items never finish, so the UI does not quit on its own
+	if ctx.Err() != nil {
+		prog.Quit()
+	}
+
 	// Ensure UI is done (though model handles auto-quit)
 	// We can wait a tiny bit to ensure the final render happens if needed,
 	// but usually wg.Wait() + the model's logic is enough.
 	<-uiDone
 
 	// Check if cancelled during execution (includes errors)
-	if yieldCancelled {
-		return false
+	if yieldCancelled || ctx.Err() != nil {
+		return nil, false
 	}
 
-	// 4. Update Parent State with Aggregated Results
 	// Filter results based on success to maintain density if needed,
 	// OR keep them sparse?
 	// The original sequential logic appended only on success.
@@ -544,82 +813,7 @@ func (a *AstonishAgent) handleParallelNode(ctx agent.InvocationContext, node *co
 			finalResults = append(finalResults, results[i])
 		}
 	}
-
-	existingVal, _ := state.Get(outputKey)
-	var final []any
-
-	if existingVal != nil {
-		if l, ok := existingVal.([]any); ok {
-			final = l
-		} else {
-			final = []any{}
-		}
-	} else {
-		final = []any{}
-	}
-
-	// Aggregate results based on output_action
-	// If output_action is "append", flatten lists; otherwise keep as-is
-	outputAction := "append" // Default behavior
-	if node.OutputAction != "" {
-		outputAction = node.OutputAction
-	}
-
-	for _, res := range finalResults {
-		if outputAction == "append" {
-			// Check if res is a JSON string that needs parsing
-			if strRes, ok := res.(string); ok {
-				// Try to parse as JSON
-				cleaned := a.cleanAndFixJson(strRes)
-				var parsed any
-				if err := json.Unmarshal([]byte(cleaned), &parsed); err == nil {
-					// Successfully parsed - check if it's a map with the output key
-					if parsedMap, ok := parsed.(map[string]any); ok {
-						// Check if the map contains the output key (e.g., "review_comment_validated")
-						if val, ok := parsedMap[outputKey]; ok {
-							// Extract the value from the nested structure
-							if l, ok := val.([]any); ok {
-								// It's a list - flatten it
-								final = append(final, l...)
-								continue
-							} else {
-								// It's a single value - append it
-								final = append(final, val)
-								continue
-							}
-						}
-					}
-					// If parsed but doesn't match expected structure, treat as regular value
-					res = parsed
-				}
-				// If parsing failed, continue with string as-is
-			}
-
-			// Flatten lists when appending
-			if l, ok := res.([]any); ok {
-				// Flatten the list - append all items from the list
-				final = append(final, l...)
-			} else {
-				// Not a list, append as-is
-				final = append(final, res)
-			}
-		} else {
-			// Keep results as-is (don't flatten)
-			final = append(final, res)
-		}
-	}
-
-	state.Set(outputKey, final)
-
-	yield(&session.Event{
-		Actions: session.EventActions{
-			StateDelta: map[string]any{
-				outputKey: final,
-			},
-		},
-	}, nil)
-
-	return true
+	return finalResults, true
 }
 
 // handleOutputNode handles output nodes
@@ -629,6 +823,8 @@ func (a *AstonishAgent) handleOutputNode(ctx agent.InvocationContext, node *conf
 		// Check if part is a state variable
 		if val, err := state.Get(msgPart); err == nil {
 			parts = append(parts, ui.FormatAsYamlLike(val, 0))
+		} else if node.Templating == TemplatingRich {
+			parts = append(parts, a.renderNodeString(node, msgPart, state))
 		} else {
 			// Not a state variable, use as literal
 			parts = append(parts, msgPart)
@@ -637,8 +833,46 @@ func (a *AstonishAgent) handleOutputNode(ctx agent.InvocationContext, node *conf
 
 	message := strings.Join(parts, "\n")
 
+	// The diff is shown before the message; reports and artifacts include it
+	content := message
+	if node.Diff != nil {
+		path, diff := a.diffPreview(node, state)
+		diffText := diffMessage(path, diff)
+		content = strings.Join(append(parts, diffText), "\n")
+		if !yield(&session.Event{
+			LLMResponse: model.LLMResponse{
+				Content: &genai.Content{
+					Parts: []*genai.Part{{Text: diffText}},
+					Role:  "model",
+				},
+			},
+			Actions: session.EventActions{
+				StateDelta: map[string]any{
+					"_output_node": true,
+					DiffPreviewKey: map[string]any{"path": path, "style": node.Diff.Style, "diff": diff},
+				},
+			},
+		}, nil) {
+			return false
+		}
+	}
+
 	// Emit message event with marker for frontend to preserve whitespace
 
+	stateDelta := map[string]any{
+		"_output_node": true, // Marker for frontend to apply pre-wrap styling
+	}
+	if node.Report.Enabled() {
+		if path, err := a.writeReport(node, state, content); err != nil {
+			slog.Warn("failed to write report", "node", node.Name, "error", err)
+			message += "\n\n(Report could not be written: " + err.Error() + ")"
+		} else {
+			state.Set(ReportStateKey, path)
+			stateDelta[ReportStateKey] = path
+			message += "\n\nReport saved to " + path
+		}
+	}
+
 	evt := &session.Event{
 		LLMResponse: model.LLMResponse{
 			Content: &genai.Content{
@@ -647,11 +881,15 @@ func (a *AstonishAgent) handleOutputNode(ctx agent.InvocationContext, node *conf
 			},
 		},
 		Actions: session.EventActions{
-			StateDelta: map[string]any{
-				"_output_node": true, // Marker for frontend to apply pre-wrap styling
-			},
+			StateDelta: stateDelta,
 		},
 	}
+	if node.Artifact != "" {
+		name := a.renderNodeString(node, node.Artifact, state)
+		if err := saveOutputArtifact(ctx, name, content, state, &evt.Actions); err != nil {
+			slog.Warn("failed to save output artifact", "node", node.Name, "artifact", name, "error", err)
+		}
+	}
 
 	return yield(evt, nil)
 }
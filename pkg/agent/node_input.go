@@ -0,0 +1,292 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// inputPromptEvent returns the event that asks the question of an input
+// node, or whether to accept the changes an output node with confirm showed.
+// inputErr, when set, tells why the previous answer was rejected.
+// Besides the options, the event carries the node's input_type, rendered
+// default and whether several options can be chosen, for the console.
+func (a *AstonishAgent) inputPromptEvent(node *config.Node, state session.State, inputErr string) *session.Event {
+	prompt := a.renderNodeString(node, node.Prompt, state)
+	if prompt == "" && node.Type == "output" {
+		prompt = "Accept these changes?"
+	}
+	if inputErr != "" {
+		prompt = inputErr + "\n\n" + prompt
+	}
+	inputOptions := a.inputOptions(node, state)
+	if node.Type == "output" {
+		inputOptions = []string{"Accept", "Reject"}
+	} else if len(inputOptions) == 0 && node.InputType == config.InputBoolean {
+		inputOptions = []string{"Yes", "No"}
+	}
+
+	delta := map[string]any{
+		"current_node":       node.Name,
+		"input_options":      inputOptions,
+		"input_type":         node.InputType,
+		"input_default":      a.renderNodeString(node, node.Default, state),
+		"input_error":        inputErr,
+		"input_multi_select": node.MultiSelect,
+		"input_mode":         node.InputMode,
+		"waiting_for_input":  true,
+	}
+	if node.InputMode == config.InputModeFile {
+		delta["input_file_root"] = a.fileRoot(node, state)
+		delta["input_file_pattern"] = node.FilePattern
+	}
+
+	return &session.Event{
+		LLMResponse: model.LLMResponse{
+			Content: &genai.Content{
+				Parts: []*genai.Part{{Text: prompt}},
+				Role:  "model",
+			},
+		},
+		Actions: session.EventActions{
+			StateDelta: delta,
+		},
+	}
+}
+
+// inputOptions resolves the options of an input node: a state key expands
+// to its list (or single string) value, anything else is a literal option.
+func (a *AstonishAgent) inputOptions(node *config.Node, state session.State) []string {
+	var inputOptions []string
+	for _, opt := range node.Options {
+		// Check if option is a state variable
+		if val, err := state.Get(opt); err == nil {
+			// If it's a list of strings, expand it
+			if list, ok := val.([]string); ok {
+				inputOptions = append(inputOptions, list...)
+				continue
+			}
+			// If it's a generic list, try to convert elements to strings
+			if list, ok := val.([]interface{}); ok {
+				for _, item := range list {
+					inputOptions = append(inputOptions, fmt.Sprintf("%v", item))
+				}
+				continue
+			}
+			// If it's a single string (LLM returned one item as string instead of array),
+			// treat it as a single option
+			if strVal, ok := val.(string); ok && strings.TrimSpace(strVal) != "" {
+				inputOptions = append(inputOptions, strings.TrimSpace(strVal))
+				continue
+			}
+		}
+		// Otherwise treat as literal option
+		inputOptions = append(inputOptions, opt)
+	}
+	return inputOptions
+}
+
+// parseInput converts the answer to an input node to the node's
+// input_type and checks its validation. An empty answer takes the node's
+// default. The error is the message the question is asked again with.
+func (a *AstonishAgent) parseInput(node *config.Node, answer string, state session.State) (any, error) {
+	if answer == "" && node.Default != "" {
+		answer = a.renderNodeString(node, node.Default, state)
+	}
+
+	var value any = answer
+	switch {
+	case node.Type == "output":
+		switch strings.ToLower(answer) {
+		case "accept", "yes", "y", "true":
+			value = true
+		case "reject", "no", "n", "false":
+			value = false
+		default:
+			return nil, inputError(node, "Please answer Accept or Reject.")
+		}
+	case node.InputMode == config.InputModeFile:
+		paths, err := a.parseFiles(node, answer, state)
+		if err != nil {
+			return nil, err
+		}
+		value = paths
+		if !node.MultiSelect {
+			value = paths[0]
+		}
+	case node.MultiSelect:
+		items, err := a.parseSelection(node, answer, state)
+		if err != nil {
+			return nil, err
+		}
+		value = items
+	case node.InputType == config.InputNumber:
+		if n, err := strconv.Atoi(answer); err == nil {
+			value = n
+		} else if f, err := strconv.ParseFloat(answer, 64); err == nil {
+			value = f
+		} else {
+			return nil, inputError(node, "Please enter a number.")
+		}
+	case node.InputType == config.InputBoolean:
+		switch strings.ToLower(answer) {
+		case "yes", "y", "true", "1":
+			value = true
+		case "no", "n", "false", "0":
+			value = false
+		default:
+			return nil, inputError(node, "Please answer yes or no.")
+		}
+	case node.InputType == config.InputRegex:
+		if _, err := regexp.Compile(answer); err != nil {
+			return nil, inputError(node, fmt.Sprintf("Not a valid regular expression: %v", err))
+		}
+	}
+
+	v := node.Validation
+	if v == nil {
+		return value, nil
+	}
+	if v.Regex != "" {
+		re, err := regexp.Compile(v.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid validation regex of input node %s: %w", node.Name, err)
+		}
+		checked := []string{answer}
+		if items, ok := value.([]string); ok {
+			checked = items
+		} else if path, ok := value.(string); ok {
+			checked = []string{path}
+		}
+		for _, item := range checked {
+			if !re.MatchString(item) {
+				return nil, inputError(node, fmt.Sprintf("The answer must match %s.", v.Regex))
+			}
+		}
+	}
+	if v.Expr != "" {
+		vars := a.stateToMap(state)
+		vars["value"] = value
+		ok, err := EvaluateExpression(v.Expr, vars)
+		if err != nil {
+			return nil, inputError(node, fmt.Sprintf("The answer could not be checked: %v", err))
+		}
+		if ok != true {
+			return nil, inputError(node, "The answer is not valid.")
+		}
+	}
+	return value, nil
+}
+
+// parseSelection splits the answer to a multi_select node into the chosen
+// options. The console sends a JSON list; typed answers list the options,
+// or their 1-based numbers, separated by commas. An empty answer chooses
+// none.
+func (a *AstonishAgent) parseSelection(node *config.Node, answer string, state session.State) ([]string, error) {
+	options := a.inputOptions(node, state)
+	var items []string
+	if err := json.Unmarshal([]byte(answer), &items); err != nil {
+		items = nil
+		if slices.Contains(options, answer) {
+			items = []string{answer}
+		} else {
+			for _, item := range strings.Split(answer, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+		}
+	}
+	if len(options) == 0 {
+		return items, nil
+	}
+	for i, item := range items {
+		if slices.Contains(options, item) {
+			continue
+		}
+		if n, err := strconv.Atoi(item); err == nil && n >= 1 && n <= len(options) {
+			items[i] = options[n-1]
+			continue
+		}
+		return nil, inputError(node, fmt.Sprintf("Unknown option %q. Choose from: %s.", item, strings.Join(options, ", ")))
+	}
+	return items, nil
+}
+
+// inputStateKey returns the state key the answer to node is stored under:
+// the confirm key of output nodes, else the output_model key.
+func inputStateKey(node *config.Node) string {
+	if node.Type == "output" {
+		return node.Confirm
+	}
+	for key := range node.OutputModel {
+		return key
+	}
+	return ""
+}
+
+// fileRoot returns the absolute directory an input_mode file node picks
+// from: its rendered file_root, or the working directory.
+func (a *AstonishAgent) fileRoot(node *config.Node, state session.State) string {
+	root := a.renderNodeString(node, node.FileRoot, state)
+	if root == "" {
+		root = "."
+	}
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	return root
+}
+
+// parseFiles resolves the paths chosen at an input_mode file node against
+// its root and checks that they are files matching its file_pattern. Paths
+// are returned absolute; at least one is required.
+func (a *AstonishAgent) parseFiles(node *config.Node, answer string, state session.State) ([]string, error) {
+	items := []string{answer}
+	if node.MultiSelect {
+		var err error
+		if items, err = a.parseSelection(node, answer, state); err != nil {
+			return nil, err
+		}
+	}
+	if len(items) == 0 || items[0] == "" {
+		return nil, inputError(node, "Please choose a file.")
+	}
+
+	root := a.fileRoot(node, state)
+	paths := make([]string, 0, len(items))
+	for _, item := range items {
+		path := item
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(root, path)
+		}
+		if info, err := os.Stat(path); err != nil || info.IsDir() {
+			return nil, inputError(node, fmt.Sprintf("File not found: %s", item))
+		}
+		if node.FilePattern != "" {
+			if ok, _ := filepath.Match(node.FilePattern, filepath.Base(path)); !ok {
+				return nil, inputError(node, fmt.Sprintf("%s does not match %s.", item, node.FilePattern))
+			}
+		}
+		paths = append(paths, filepath.Clean(path))
+	}
+	return paths, nil
+}
+
+// inputError returns the node's validation message, or fallback.
+func inputError(node *config.Node, fallback string) error {
+	if node.Validation != nil && node.Validation.Message != "" {
+		return fmt.Errorf("%s", node.Validation.Message)
+	}
+	return fmt.Errorf("%s", fallback)
+}
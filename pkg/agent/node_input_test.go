@@ -0,0 +1,128 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/config"
+)
+
+func TestParseInput(t *testing.T) {
+	a := &AstonishAgent{}
+	state := NewMockState()
+	state.Set("max_replicas", 10)
+
+	replicas := &config.Node{
+		Name: "ask", Type: "input", InputType: config.InputNumber, Default: "3",
+		Validation: &config.InputValidation{Expr: "value >= 1 and value <= max_replicas", Message: "Enter 1 to 10."},
+	}
+	tests := []struct {
+		node    *config.Node
+		answer  string
+		want    any
+		wantErr string
+	}{
+		{replicas, "", 3, ""},
+		{replicas, "4", 4, ""},
+		{replicas, "2.5", 2.5, ""},
+		{replicas, "11", nil, "Enter 1 to 10."},
+		{replicas, "many", nil, "Enter 1 to 10."},
+		{&config.Node{InputType: config.InputNumber}, "many", nil, "Please enter a number."},
+		{&config.Node{InputType: config.InputBoolean}, "Yes", true, ""},
+		{&config.Node{InputType: config.InputBoolean}, "0", false, ""},
+		{&config.Node{InputType: config.InputBoolean}, "maybe", nil, "Please answer yes or no."},
+		{&config.Node{InputType: config.InputRegex}, "a(b", nil, "Not a valid regular expression: error parsing regexp: missing closing ): `a(b`"},
+		{&config.Node{Validation: &config.InputValidation{Regex: `^[a-z-]+$`}}, "my-app", "my-app", ""},
+		{&config.Node{Validation: &config.InputValidation{Regex: `^[a-z-]+$`}}, "My App", nil, "The answer must match ^[a-z-]+$."},
+	}
+	for _, tt := range tests {
+		got, err := a.parseInput(tt.node, tt.answer, state)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("parseInput(%q) error = %v, want %q", tt.answer, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseInput(%q) = %v (%T), %v; want %v (%T)", tt.answer, got, got, err, tt.want, tt.want)
+		}
+	}
+
+	event := a.inputPromptEvent(&config.Node{Name: "confirm", Prompt: "Deploy?", InputType: config.InputBoolean}, state, "Please answer yes or no.")
+	delta := event.Actions.StateDelta
+	if opts, _ := delta["input_options"].([]string); len(opts) != 2 || delta["input_error"] != "Please answer yes or no." {
+		t.Errorf("prompt delta = %v, want Yes/No options and the error", delta)
+	}
+	if text := event.LLMResponse.Content.Parts[0].Text; text != "Please answer yes or no.\n\nDeploy?" {
+		t.Errorf("prompt = %q", text)
+	}
+}
+
+func TestParseInput_MultiSelect(t *testing.T) {
+	a := &AstonishAgent{}
+	state := NewMockState()
+	state.Set("comments", []any{"Rename x", "Add tests, docs", "Fix typo"})
+	node := &config.Node{
+		Name: "pick", Type: "input", Options: []string{"comments"}, MultiSelect: true,
+		Validation: &config.InputValidation{Expr: "len(value) > 0", Message: "Pick at least one."},
+	}
+
+	for answer, want := range map[string][]string{
+		`["Add tests, docs","Fix typo"]`: {"Add tests, docs", "Fix typo"},
+		"Rename x, Fix typo":             {"Rename x", "Fix typo"},
+		"Add tests, docs":                {"Add tests, docs"},
+		"1,3":                            {"Rename x", "Fix typo"},
+	} {
+		got, err := a.parseInput(node, answer, state)
+		if err != nil || !slices.Equal(got.([]string), want) {
+			t.Errorf("parseInput(%q) = %v, %v; want %v", answer, got, err, want)
+		}
+	}
+	if _, err := a.parseInput(node, "", state); err == nil || err.Error() != "Pick at least one." {
+		t.Errorf("empty selection error = %v", err)
+	}
+	node.Validation = nil
+	if _, err := a.parseInput(node, "Rename y", state); err == nil || !strings.Contains(err.Error(), `Unknown option "Rename y"`) {
+		t.Errorf("unknown option error = %v", err)
+	}
+}
+
+func TestParseInput_File(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"README.md", "docs/guide.md", "main.go"} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a := &AstonishAgent{}
+	state := NewMockState()
+	state.Set("repo", root)
+	node := &config.Node{Name: "doc", Type: "input", InputMode: config.InputModeFile, FileRoot: "{repo}", FilePattern: "*.md"}
+
+	if got, err := a.parseInput(node, "docs/guide.md", state); err != nil || got != filepath.Join(root, "docs", "guide.md") {
+		t.Errorf("single file = %v, %v", got, err)
+	}
+	for _, answer := range []string{"", "main.go", "missing.md", "docs"} {
+		if _, err := a.parseInput(node, answer, state); err == nil {
+			t.Errorf("parseInput(%q) was accepted", answer)
+		}
+	}
+
+	node.MultiSelect = true
+	got, err := a.parseInput(node, `["README.md","docs/guide.md"]`, state)
+	if want := []string{filepath.Join(root, "README.md"), filepath.Join(root, "docs", "guide.md")}; err != nil || !slices.Equal(got.([]string), want) {
+		t.Errorf("multiple files = %v, %v; want %v", got, err, want)
+	}
+
+	delta := a.inputPromptEvent(node, state, "").Actions.StateDelta
+	if delta["input_mode"] != config.InputModeFile || delta["input_file_root"] != root || delta["input_file_pattern"] != "*.md" {
+		t.Errorf("prompt delta = %v", delta)
+	}
+}
@@ -5,8 +5,12 @@ import (
 	"fmt"
 	"log/slog"
 	"strings"
+	"sync"
 
 	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/credentials"
+	persistentsession "github.com/SAP/astonish/pkg/session"
+	"github.com/SAP/astonish/pkg/store"
 	"google.golang.org/adk/agent"
 	"google.golang.org/adk/agent/llmagent"
 	"google.golang.org/adk/model"
@@ -17,8 +21,9 @@ import (
 
 // buildApprovalCallback creates the BeforeToolCallback for approval-gated tools.
 // Events are buffered in cbBuf (not yielded directly) because ADK may invoke
-// this callback from a goroutine, and yield is not goroutine-safe.
-func (a *AstonishAgent) buildApprovalCallback(node *config.Node, state session.State, cbBuf *callbackEventBuffer) llmagent.BeforeToolCallback {
+// this callback from a goroutine, and yield is not goroutine-safe. The
+// request shows the model's reasoning for the call.
+func (a *AstonishAgent) buildApprovalCallback(node *config.Node, state session.State, cbBuf *callbackEventBuffer, reasoning *toolCallReasoning) llmagent.BeforeToolCallback {
 	return func(ctx tool.Context, t tool.Tool, args map[string]any) (map[string]any, error) {
 		toolName := t.Name()
 
@@ -28,19 +33,18 @@ func (a *AstonishAgent) buildApprovalCallback(node *config.Node, state session.S
 			slog.Debug("tool execution attempt", "tool", toolName, "arguments", string(argsJSON))
 		}
 
-		// Node-scoped approval key
-		approvalKey := fmt.Sprintf("approval:%s:%s", node.Name, toolName)
-
-		// Check if we already have approval for this tool
-		approvedVal, _ := state.Get(approvalKey)
-
-		approved := false
-		if b, ok := approvedVal.(bool); ok && b {
-			approved = true
+		// The approval profile may approve the call without asking
+		if a.ApprovalPolicy.Decide(toolName, args) == config.ApprovalApprove {
+			a.bufferAutoApproval(cbBuf, toolName, args, reasoning)
+			return nil, nil
 		}
 
+		// Check if we already have approval for this exact call (node-scoped,
+		// bound to the args the user approved)
+		approved := hasApproval(state, node.Name, toolName, args)
+
 		if a.DebugMode {
-			slog.Debug("approval check", "approved", approved, "approval_key", approvalKey)
+			slog.Debug("approval check", "approved", approved, "approval_key", approvalKey(node.Name, toolName))
 		}
 
 		// Fallback: Check history for explicit user approval if state is missing
@@ -72,9 +76,10 @@ func (a *AstonishAgent) buildApprovalCallback(node *config.Node, state session.S
 						if prevEvent.Actions.StateDelta != nil {
 							if val, ok := prevEvent.Actions.StateDelta["awaiting_approval"]; ok {
 								if b, ok := val.(bool); ok && b {
-									// Check if it was for THIS tool
+									// Check if it was for THIS tool call (same tool and args)
 									if toolVal, ok := prevEvent.Actions.StateDelta["approval_tool"]; ok {
-										if tName, ok := toolVal.(string); ok && tName == toolName {
+										sameArgs := approvalArgsHash(prevEvent.Actions.StateDelta["approval_args"]) == approvalArgsHash(args)
+										if tName, ok := toolVal.(string); ok && tName == toolName && sameArgs {
 											approved = true
 											if a.DebugMode {
 												slog.Debug("approved via history check")
@@ -91,7 +96,7 @@ func (a *AstonishAgent) buildApprovalCallback(node *config.Node, state session.S
 
 		if approved {
 			// Consume approval - each execution requires new approval
-			state.Set(approvalKey, false)
+			revokeApproval(state, node.Name, toolName)
 			if a.DebugMode {
 				slog.Debug("tool approved, allowing execution to proceed")
 			}
@@ -127,7 +132,15 @@ func (a *AstonishAgent) buildApprovalCallback(node *config.Node, state session.S
 		state.Set("approval_args", args)
 
 		// Buffer approval request event (NOT yield — runs in ADK goroutine)
-		prompt := a.formatToolApprovalRequest(toolName, args)
+		reason := reasoning.get()
+		prompt := a.formatToolApprovalRequest(toolName, args, reason)
+		delta := map[string]any{
+			"awaiting_approval": true,
+			"approval_tool":     toolName,
+			"approval_args":     args,
+			"approval_options":  approvalOptions,
+		}
+		addApprovalReason(delta, reason)
 		cbBuf.append(&session.Event{
 			LLMResponse: model.LLMResponse{
 				Content: &genai.Content{
@@ -135,13 +148,7 @@ func (a *AstonishAgent) buildApprovalCallback(node *config.Node, state session.S
 					Role:  "model",
 				},
 			},
-			Actions: session.EventActions{
-				StateDelta: map[string]any{
-					"awaiting_approval": true,
-					"approval_tool":     toolName,
-					"approval_options":  []string{"Yes", "No"},
-				},
-			},
+			Actions: session.EventActions{StateDelta: delta},
 		})
 
 		// Return a placeholder result
@@ -161,10 +168,30 @@ func (a *AstonishAgent) buildApprovalCallback(node *config.Node, state session.S
 	}
 }
 
+// bufferAutoApproval buffers the notice of a call that runs without asking
+// (NOT yield — callbacks run in an ADK goroutine).
+func (a *AstonishAgent) bufferAutoApproval(cbBuf *callbackEventBuffer, toolName string, args map[string]any, reasoning *toolCallReasoning) {
+	prompt := a.formatToolApprovalRequest(toolName, args, reasoning.get())
+	cbBuf.append(&session.Event{
+		LLMResponse: model.LLMResponse{
+			Content: &genai.Content{
+				Parts: []*genai.Part{{Text: prompt}},
+				Role:  "model",
+			},
+		},
+		Actions: session.EventActions{
+			StateDelta: map[string]any{
+				"auto_approved": true,
+			},
+		},
+	})
+}
+
 // buildAfterToolCallback creates the AfterToolCallback for debugging and raw_tool_output handling.
 // Events are buffered in cbBuf (not yielded directly) because ADK may invoke
 // this callback from a goroutine, and yield is not goroutine-safe.
 func (a *AstonishAgent) buildAfterToolCallback(node *config.Node, state session.State, cbBuf *callbackEventBuffer) llmagent.AfterToolCallback {
+	limiter := a.newToolOutputLimiter(node)
 	return func(ctx tool.Context, t tool.Tool, args map[string]any, result map[string]any, err error) (map[string]any, error) {
 		// Redact credential values from all tool outputs before the LLM sees them.
 		// resolve_credential now returns {{CREDENTIAL:...}} placeholders instead
@@ -186,98 +213,252 @@ func (a *AstonishAgent) buildAfterToolCallback(node *config.Node, state session.
 			slog.Debug("after tool callback", "tool", toolName, "result", string(resultJSON))
 		}
 
-		// Handle raw_tool_output: Store actual result in state, return sanitized message to LLM
-		// This prevents large tool outputs from polluting the LLM's context window
-		// Handle raw_tool_output: Store actual result in state, return sanitized message to LLM
-		// This prevents large tool outputs from polluting the LLM's context window
-		if len(node.RawToolOutput) == 1 {
-			// IMPORTANT: Skip storing if this is an approval message, not actual tool output
-			// When a tool requires approval, the "result" contains pending_approval status
-			// We should NOT store this as the raw_tool_output - wait for actual tool result
-			if status, hasStatus := result["status"]; hasStatus {
-				if statusStr, ok := status.(string); ok && statusStr == "pending_approval" {
-					// This is an approval message, not the actual tool result
-					// Skip storing and let the approval flow handle things
-					if a.DebugMode {
-						slog.Debug("raw_tool_output: skipping storage, result is pending_approval message")
-					}
-					return result, nil
+		// Handle raw_tool_output: store the actual result in state, return a
+		// sanitized message to the LLM
+		sanitized, stored, err := a.storeRawToolOutput(node, toolName, result, func(delta map[string]any) error {
+			return a.persistCallbackState(ctx, state, cbBuf, delta)
+		})
+		if err != nil {
+			return result, err
+		}
+		if stored {
+			return sanitized, nil
+		}
+
+		// Keep oversized results out of the context: the model gets a
+		// truncated or summarized version, state the full result
+		if limiter != nil {
+			limited, delta := limiter.apply(ctx, toolName, result)
+			if delta != nil {
+				if err := a.persistCallbackState(ctx, state, cbBuf, delta); err != nil {
+					return result, err
 				}
 			}
+			return limited, nil
+		}
 
-			// Get the state key to store the raw output
-			var stateKey string
-			for k := range node.RawToolOutput {
-				stateKey = k
-				break
-			}
+		return result, nil
+	}
+}
 
-			if a.DebugMode {
-				resultSummary := "empty"
-				if len(result) > 0 {
-					keys := make([]string, 0, len(result))
-					for k := range result {
-						keys = append(keys, k)
-					}
-					resultSummary = fmt.Sprintf("keys=%v", keys)
+// storeRawToolOutput stores a tool result of a node with one raw_tool_output
+// key in state through persist, and returns the sanitized message the LLM
+// gets instead of the data. This prevents large tool outputs from polluting
+// the LLM's context window. stored is false when the node keeps no raw
+// output or the result is only a pending approval.
+func (a *AstonishAgent) storeRawToolOutput(node *config.Node, toolName string, result map[string]any, persist func(delta map[string]any) error) (sanitized map[string]any, stored bool, err error) {
+	if len(node.RawToolOutput) != 1 {
+		return nil, false, nil
+	}
+
+	// When a tool requires approval, the "result" contains pending_approval
+	// status: wait for the actual tool result
+	if status, ok := result["status"].(string); ok && status == "pending_approval" {
+		if a.DebugMode {
+			slog.Debug("raw_tool_output: skipping storage, result is pending_approval message")
+		}
+		return nil, false, nil
+	}
+
+	var stateKey string
+	for k := range node.RawToolOutput {
+		stateKey = k
+	}
+
+	if a.DebugMode {
+		resultSummary := "empty"
+		if len(result) > 0 {
+			resultSummary = fmt.Sprintf("keys=%v", getKeys(result))
+		}
+		slog.Debug("raw_tool_output: storing result", "state_key", stateKey, "result_summary", resultSummary)
+	}
+
+	if err := persist(map[string]any{stateKey: result}); err != nil {
+		return nil, false, fmt.Errorf("failed to set raw_tool_output state key %s: %w", stateKey, err)
+	}
+
+	// This matches Python's behavior: LLM doesn't see the actual data
+	sanitized = map[string]any{
+		"status":  "success",
+		"message": fmt.Sprintf("Tool '%s' executed successfully. Its output has been directly stored in the agent's state under the key '%s'.", toolName, stateKey),
+	}
+	if a.DebugMode {
+		slog.Debug("raw_tool_output: returning sanitized result to llm", "result", sanitized)
+	}
+	return sanitized, true, nil
+}
+
+// persistCallbackState applies a state delta produced inside a tool callback:
+// it sets the keys in state, buffers the delta event (callbacks run in ADK
+// goroutines and must not yield) and also appends it to the session service
+// through the shared appender, so the state is available in subsequent Run
+// invocations even if async event processing hasn't completed.
+func (a *AstonishAgent) persistCallbackState(ctx tool.Context, state session.State, cbBuf *callbackEventBuffer, delta map[string]any) error {
+	for k, v := range delta {
+		if err := state.Set(k, v); err != nil {
+			return err
+		}
+	}
+
+	stateEvent := &session.Event{
+		Actions: session.EventActions{
+			StateDelta: delta,
+		},
+	}
+	cbBuf.append(stateEvent)
+
+	if a.SessionService != nil {
+		if invCtx, ok := ctx.(agent.InvocationContext); ok {
+			// This is a fallback, the buffered event should still work
+			if appendErr := persistentsession.SharedAppender(a.SessionService).Append(ctx, invCtx.Session(), stateEvent); appendErr != nil {
+				if a.DebugMode {
+					slog.Debug("failed to directly append callback state", "error", appendErr)
 				}
-				slog.Debug("raw_tool_output: storing result", "state_key", stateKey, "result_summary", resultSummary)
+			} else if a.DebugMode {
+				slog.Debug("directly appended callback state to session service", "keys", len(delta))
 			}
+		}
+	}
+	return nil
+}
 
-			// Store the actual tool result in state (in-memory)
-			if err := state.Set(stateKey, result); err != nil {
-				return result, fmt.Errorf("failed to set raw_tool_output state key %s: %w", stateKey, err)
-			}
+// buildToolCallbacks assembles the tool callbacks of an LLM node, in order:
+// approval profile denials, the result cache lookup, approval (or the
+// auto-approval notice), the audit record, credential and pending secret
+// substitution and the worker pool slot before a call; the slot release,
+// the audit log append, the result cache store, and placeholder restore
+// wrapping buildAfterToolCallback after it.
+func (a *AstonishAgent) buildToolCallbacks(node *config.Node, state session.State, cbBuf *callbackEventBuffer, reasoning *toolCallReasoning) ([]llmagent.BeforeToolCallback, []llmagent.AfterToolCallback) {
+	var beforeToolCallbacks []llmagent.BeforeToolCallback
+	var afterToolCallbacks []llmagent.AfterToolCallback
+
+	if !node.ToolsAutoApproval && !a.AutoApprove {
+		beforeToolCallbacks = []llmagent.BeforeToolCallback{
+			a.buildApprovalCallback(node, state, cbBuf, reasoning),
+		}
+	} else {
+		// Auto-approval enabled: buffer the visual event, then let the tool
+		// execute normally
+		beforeToolCallbacks = []llmagent.BeforeToolCallback{
+			func(ctx tool.Context, t tool.Tool, args map[string]any) (map[string]any, error) {
+				a.bufferAutoApproval(cbBuf, t.Name(), args, reasoning)
+				return nil, nil
+			},
+		}
+	}
 
-			// Create the StateDelta event
-			stateEvent := &session.Event{
-				Actions: session.EventActions{
-					StateDelta: map[string]any{
-						stateKey: result,
-					},
-				},
-			}
+	// Serve repeated calls from the tool result cache. The lookup goes
+	// first so a hit needs no approval and keys use the unsubstituted args.
+	resultCache := a.newToolResultCache(node)
+	if resultCache != nil {
+		beforeToolCallbacks = append([]llmagent.BeforeToolCallback{resultCache.lookup()}, beforeToolCallbacks...)
+	}
 
-			// Buffer StateDelta event (NOT yield — runs in ADK goroutine)
-			cbBuf.append(stateEvent)
-
-			// ALSO: Directly append event to session service for SYNCHRONOUS persistence
-			// This ensures the state is available in subsequent Run invocations
-			// even if async event processing hasn't completed
-			if a.SessionService != nil {
-				// Get session from context
-				if invCtx, ok := ctx.(agent.InvocationContext); ok {
-					sess := invCtx.Session()
-					// Try to append synchronously
-					if appendErr := a.SessionService.AppendEvent(ctx, sess, stateEvent); appendErr != nil {
-						// Log warning only in DebugMode - this is a fallback, yield should still work
-						if a.DebugMode {
-							slog.Debug("raw_tool_output: failed to directly append event", "error", appendErr)
-						}
-					} else if a.DebugMode {
-						slog.Debug("raw_tool_output: directly appended event to session service", "state_key", stateKey)
-					}
+	// Calls the approval profile denies are refused first, even when
+	// auto-approved
+	if policy := a.ApprovalPolicy; policy != nil {
+		beforeToolCallbacks = append([]llmagent.BeforeToolCallback{
+			func(ctx tool.Context, t tool.Tool, args map[string]any) (map[string]any, error) {
+				if policy.Decide(t.Name(), args) != config.ApprovalDeny {
+					return nil, nil
 				}
-			}
+				a.auditDenial(ctx, node.Name, t.Name(), args, policy.approver())
+				return policy.profileDenial(t.Name()), nil
+			},
+		}, beforeToolCallbacks...)
+	}
 
-			if a.DebugMode {
-				slog.Debug("raw_tool_output: emitted state delta", "state_key", stateKey)
-			}
+	// Record approved calls in the audit log, hashing the args before
+	// credentials are substituted
+	trail := a.newToolAudit(node)
+	if trail != nil {
+		beforeToolCallbacks = append(beforeToolCallbacks, trail.begin())
+	}
 
-			// Return sanitized message to LLM instead of raw data
-			// This matches Python's behavior: LLM doesn't see the actual data
-			sanitizedResult := map[string]any{
-				"status":  "success",
-				"message": fmt.Sprintf("Tool '%s' executed successfully. Its output has been directly stored in the agent's state under the key '%s'.", toolName, stateKey),
-			}
+	// Credential placeholder substitution uses SubstituteAndRestore so the
+	// AfterToolCallback can undo the in-place mutation, keeping placeholders
+	// in the session event. Restore functions are keyed by FunctionCallID so
+	// parallel tool calls don't clobber each other's.
+	var restoreFuncs sync.Map // map[string]func()
+	addRestore := func(callID string, restore func()) {
+		if prev, loaded := restoreFuncs.Load(callID); loaded {
+			prevFn := prev.(func())
+			restoreFuncs.Store(callID, func() { restore(); prevFn() })
+		} else {
+			restoreFuncs.Store(callID, restore)
+		}
+	}
 
-			if a.DebugMode {
-				slog.Debug("raw_tool_output: returning sanitized result to llm", "result", sanitizedResult)
-			}
+	agentResolver := a.CredentialStore // may be nil if file-based store failed
+	beforeToolCallbacks = append(beforeToolCallbacks, func(ctx tool.Context, t tool.Tool, args map[string]any) (map[string]any, error) {
+		// In platform mode, prefer the tenant-scoped PG credential store
+		// injected into the context. Fall back to agent-level store.
+		var resolver credentials.CredentialResolver
+		if cs := store.CredentialStoreFromContext(ctx); cs != nil {
+			resolver = credentials.NewStoreAdapter(cs)
+		} else if agentResolver != nil {
+			resolver = agentResolver
+		}
 
-			return sanitizedResult, nil
+		if resolver == nil {
+			// No credential store available — unresolved placeholders are
+			// treated as literal text (e.g., documentation examples).
+			return nil, nil
 		}
 
-		return result, nil
+		// Use shell-safe env-var injection for shell_command tools.
+		var shellFields []string
+		if t.Name() == "shell_command" || t.Name() == "process_write" {
+			shellFields = []string{"command"}
+		}
+		credRestore := credentials.SubstituteAndRestore(args, resolver, shellFields...)
+
+		// Unresolved placeholders are left as literal text — this handles
+		// the case where the LLM generates documentation or code that
+		// describes the placeholder format without intending to use a
+		// real credential. Downstream auth failures will surface naturally.
+		if unresolved := credentials.UnresolvedCredentialNames(args); len(unresolved) > 0 {
+			slog.Debug("credential placeholders remain unresolved (treating as literal text)",
+				"component", "credentials", "tool", t.Name(), "unresolved", unresolved)
+		}
+
+		addRestore(ctx.FunctionCallID(), credRestore)
+		return nil, nil
+	})
+
+	// Resolve <<<SECRET_N>>> tokens in tool args to real values.
+	if a.PendingSecrets != nil {
+		vault := a.PendingSecrets
+		beforeToolCallbacks = append(beforeToolCallbacks, func(ctx tool.Context, t tool.Tool, args map[string]any) (map[string]any, error) {
+			addRestore(ctx.FunctionCallID(), vault.SubstituteAndRestore(args))
+			return nil, nil
+		})
+	}
+
+	// Bound the concurrent tool calls of one turn. Slots are taken last,
+	// so calls waiting for approval do not hold one.
+	pool := newToolWorkerPool(node)
+	beforeToolCallbacks = append(beforeToolCallbacks, pool.acquire())
+	if trail != nil {
+		beforeToolCallbacks = append(beforeToolCallbacks, trail.start())
 	}
+
+	innerAfterTool := a.buildAfterToolCallback(node, state, cbBuf)
+	afterToolCallbacks = []llmagent.AfterToolCallback{pool.release()}
+	if trail != nil {
+		afterToolCallbacks = append(afterToolCallbacks, trail.finish())
+	}
+	if resultCache != nil {
+		afterToolCallbacks = append(afterToolCallbacks, resultCache.store())
+	}
+	afterToolCallbacks = append(afterToolCallbacks,
+		func(ctx tool.Context, t tool.Tool, args map[string]any, result map[string]any, err error) (map[string]any, error) {
+			if fn, ok := restoreFuncs.LoadAndDelete(ctx.FunctionCallID()); ok {
+				fn.(func())()
+			}
+			return innerAfterTool(ctx, t, args, result, err)
+		},
+	)
+
+	return beforeToolCallbacks, afterToolCallbacks
 }
@@ -0,0 +1,244 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/provider/llmerror"
+	"google.golang.org/adk/session"
+)
+
+// defaultMaxToolCalls bounds the tool calls of one LLM node attempt when
+// the node sets no max_tool_calls, to stop models that loop on their tools.
+const defaultMaxToolCalls = 20
+
+// ToolCallWarningKey is the state delta key of the warning emitted when a
+// node attempt has made 80% of the tool calls it may make.
+const ToolCallWarningKey = "_tool_call_warning"
+
+// llmEventStage processes the events of an LLM node's llmagent run: it
+// accumulates the answer, counts tool calls, detects failed tools and
+// decides which events are shown to the user.
+type llmEventStage struct {
+	a        *AstonishAgent
+	node     *config.Node
+	nodeName string
+	guarded  bool // Output is held back until the guardrails checked it
+
+	response     strings.Builder
+	debugText    strings.Builder
+	toolCalls    int
+	maxToolCalls int
+	warned       bool // The tool call warning was emitted
+
+	// toolErr is the failure of a tool in the last event. The event still
+	// reaches the model, so the attempt ends after it is forwarded.
+	toolErr error
+
+	// turnText and turnThoughts hold what the model wrote since the last
+	// tool response; they become the reasoning of its next tool calls.
+	turnText     strings.Builder
+	turnThoughts strings.Builder
+	reasoning    toolCallReasoning
+}
+
+// toolCallReasoning is the text the model wrote before its latest tool
+// calls. The event stage sets it when the calls arrive, before ADK runs
+// them, and the approval callbacks read it from ADK's goroutines.
+type toolCallReasoning struct {
+	mu   sync.Mutex
+	text string
+}
+
+func (r *toolCallReasoning) set(text string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.text = text
+}
+
+func (r *toolCallReasoning) get() string {
+	if r == nil {
+		return ""
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.text
+}
+
+func (a *AstonishAgent) newLLMEventStage(node *config.Node, nodeName string) *llmEventStage {
+	limit := node.MaxToolCalls
+	if limit <= 0 {
+		limit = defaultMaxToolCalls
+	}
+	return &llmEventStage{
+		a:            a,
+		node:         node,
+		nodeName:     nodeName,
+		guarded:      a.Config.GuardrailsFor(node) != nil,
+		maxToolCalls: limit,
+	}
+}
+
+// process handles one event of the run and reports whether to forward it
+// to the user. An error ends the attempt at once.
+func (s *llmEventStage) process(ctx context.Context, state session.State, event *session.Event) (bool, error) {
+	s.toolErr = nil
+
+	// A blocked response is not an empty answer: report it as a content
+	// filter error so on_content_filter can handle it
+	if isContentFilterFinish(event.LLMResponse.FinishReason) {
+		providerName := s.node.Provider
+		if providerName == "" {
+			providerName = s.a.ProviderName
+		}
+		return false, &llmerror.ContentFilterError{Provider: providerName, FinishReason: string(event.LLMResponse.FinishReason)}
+	}
+
+	// Tools called by the model may have saved artifacts
+	if len(event.Actions.ArtifactDelta) > 0 {
+		if event.Actions.StateDelta == nil {
+			event.Actions.StateDelta = make(map[string]any)
+		}
+		recordArtifacts(ctx, state, event.Actions.ArtifactDelta, event.Actions.StateDelta)
+	}
+
+	if event.LLMResponse.Content == nil || len(event.LLMResponse.Content.Parts) == 0 {
+		return true, nil
+	}
+
+	s.trackReasoning(event)
+
+	textOnly := true
+	for _, part := range event.LLMResponse.Content.Parts {
+		switch {
+		case part.FunctionCall != nil:
+			textOnly = false
+			s.toolCalls++
+			if s.a.DebugMode {
+				slog.Debug("function call", "name", part.FunctionCall.Name)
+			}
+		case part.FunctionResponse != nil:
+			textOnly = false
+			if s.a.DebugMode {
+				respJSON, _ := json.MarshalIndent(part.FunctionResponse.Response, "", "  ")
+				slog.Debug("tool execution result", "tool", part.FunctionResponse.Name, "response", string(respJSON))
+			}
+			// Let the error response flow to the LLM so it sees the tool
+			// failed; the attempt stops once it is forwarded
+			if errVal, hasError := part.FunctionResponse.Response["error"]; hasError && errVal != nil {
+				var errorStr string
+				if errObj, ok := errVal.(error); ok {
+					errorStr = errObj.Error()
+				} else {
+					errBytes, _ := json.Marshal(errVal)
+					errorStr = string(errBytes)
+				}
+				if s.a.DebugMode {
+					slog.Debug("tool failed with error", "tool", part.FunctionResponse.Name, "error", errorStr)
+				}
+				s.toolErr = fmt.Errorf("tool '%s' failed: %s", part.FunctionResponse.Name, errorStr)
+			}
+		}
+		if part.Text != "" {
+			s.response.WriteString(part.Text)
+			if s.a.DebugMode {
+				s.debugText.WriteString(part.Text)
+			}
+		}
+	}
+
+	if s.toolCalls > s.maxToolCalls {
+		if s.a.DebugMode {
+			slog.Debug("tool call limit exceeded", "max_tool_calls", s.maxToolCalls, "node", s.nodeName)
+		}
+		return false, fmt.Errorf("tool call limit exceeded (%d) for node '%s'", s.maxToolCalls, s.nodeName)
+	}
+
+	// Warn once at 80% of the limit, so a loop shows before the node fails.
+	// The event carries tool calls, so it is forwarded.
+	if !s.warned && s.toolCalls*10 >= s.maxToolCalls*8 {
+		s.warned = true
+		if event.Actions.StateDelta == nil {
+			event.Actions.StateDelta = make(map[string]any)
+		}
+		event.Actions.StateDelta[ToolCallWarningKey] = map[string]any{
+			"tool_calls":     s.toolCalls,
+			"max_tool_calls": s.maxToolCalls,
+			"message": fmt.Sprintf("Node '%s' has used %d of %d tool calls (max_tool_calls); it fails when it makes more",
+				s.nodeName, s.toolCalls, s.maxToolCalls),
+		}
+	}
+
+	// Only text of stream nodes is shown as it arrives, so raw JSON answers
+	// are not displayed: their values are distributed to state, and
+	// user_message/summary modes display content from there
+	if textOnly && (s.node.DisplayMode() != config.DisplayStream || s.guarded) {
+		return false, nil
+	}
+	return true, nil
+}
+
+// trackReasoning records the model's text of the current turn and hands it
+// to the turn's tool calls. Partial events are skipped: the complete event
+// that follows them carries the same text.
+func (s *llmEventStage) trackReasoning(event *session.Event) {
+	if event.LLMResponse.Partial {
+		return
+	}
+	hasCall := false
+	for _, part := range event.LLMResponse.Content.Parts {
+		switch {
+		case part.FunctionResponse != nil:
+			s.turnText.Reset()
+			s.turnThoughts.Reset()
+		case part.FunctionCall != nil:
+			hasCall = true
+		case part.Thought:
+			s.turnThoughts.WriteString(part.Text + " ")
+		default:
+			s.turnText.WriteString(part.Text + " ")
+		}
+	}
+	if !hasCall {
+		return
+	}
+	// Visible text explains the call better than a thought summary
+	reasoning := s.turnText.String()
+	if strings.TrimSpace(reasoning) == "" {
+		reasoning = s.turnThoughts.String()
+	}
+	s.reasoning.set(s.a.approvalReason(reasoning))
+}
+
+// answer returns the text the model answered with.
+func (s *llmEventStage) answer() string {
+	if s.a.DebugMode && s.debugText.Len() > 0 {
+		slog.Debug("full llm response", "response", s.debugText.String())
+	}
+	return s.response.String()
+}
+
+// toolCallingUnsupported reports whether err says the model cannot call
+// tools natively, in which case the node falls back to the ReAct planner.
+// Providers registered with a static Tools capability (lm_studio, litellm,
+// openai_compat, ...) only assume it, so their models can still say so.
+func toolCallingUnsupported(err error) bool {
+	msg := err.Error()
+	for _, s := range []string{
+		"Tool calling is not supported",
+		"No endpoints found that support tool use", // OpenRouter
+		"Function calling is not enabled",
+		"does not support tools",
+		"`tool calling` is not supported",
+	} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
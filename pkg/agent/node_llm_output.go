@@ -0,0 +1,222 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/planner"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// llmOutputParser turns the final answer of an LLM node into the values of
+// its output_model.
+type llmOutputParser interface {
+	parseOutput(ctx context.Context, answer string) (map[string]any, error)
+}
+
+// jsonOutputParser parses an answer that is the JSON object itself. With an
+// output_schema the object is validated, with one repair round trip before
+// the error goes to the node's retry loop.
+type jsonOutputParser struct {
+	a        *AstonishAgent
+	node     *config.Node
+	nodeName string
+	llm      model.LLM
+}
+
+func (p jsonOutputParser) parseOutput(ctx context.Context, answer string) (map[string]any, error) {
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return nil, fmt.Errorf("LLM returned empty response but output_model requires JSON output with keys: %v", getKeysStr(p.node.OutputModel))
+	}
+
+	cleaned := p.a.cleanAndFixJson(answer)
+	if p.a.DebugMode {
+		slog.Debug("cleaned json", "json", cleaned)
+	}
+
+	var parsed map[string]any
+	err := json.Unmarshal([]byte(cleaned), &parsed)
+	if len(p.node.OutputSchema) > 0 {
+		schema, schemaErr := resolveOutputSchema(p.node)
+		if schemaErr != nil {
+			return nil, schemaErr
+		}
+		problem := err
+		if problem == nil {
+			problem = schema.Validate(parsed)
+		}
+		if problem != nil {
+			slog.Debug("structured output invalid, requesting repair", "node", p.nodeName, "error", problem)
+			repaired, repairErr := p.a.repairStructuredOutput(ctx, p.node, p.llm, schema, answer, problem)
+			if repairErr != nil {
+				return nil, fmt.Errorf("LLM output does not match output_schema: %v (%v)", problem, repairErr)
+			}
+			parsed, err = repaired, nil
+		}
+	}
+	if err != nil {
+		// A descriptive error helps the intelligent retry
+		preview := cleaned
+		if len(preview) > 200 {
+			preview = preview[:200] + "..."
+		}
+		return nil, fmt.Errorf("failed to parse LLM output as JSON for output_model extraction: %v. Response preview: %s", err, preview)
+	}
+	return parsed, nil
+}
+
+// reactOutputParser has the ReAct planner format its free-text answer as
+// the output_model JSON object first.
+type reactOutputParser struct {
+	planner     *planner.ReActPlanner
+	instruction string
+	json        jsonOutputParser
+}
+
+func (p reactOutputParser) parseOutput(ctx context.Context, answer string) (map[string]any, error) {
+	formatted, err := p.planner.FormatOutput(ctx, answer, p.json.node.OutputModel, p.instruction)
+	if err != nil {
+		return nil, fmt.Errorf("failed to format ReAct output: %w", err)
+	}
+	return p.json.parseOutput(ctx, formatted)
+}
+
+// llmOutput is the final answer of an LLM node attempt.
+type llmOutput struct {
+	answer   string
+	streamed bool // The answer was shown to the user as it was generated
+	llm      model.LLM
+	parser   llmOutputParser
+}
+
+// completeLLMOutput is the output stage of an LLM node, shared by the ADK
+// path and the ReAct fallback: it checks the answer against the guardrails,
+// shows it on stream nodes, distributes output_model values and persists
+// raw_tool_output values as state deltas, and displays user_message or
+// summary content. It returns false when the node must not continue.
+func (a *AstonishAgent) completeLLMOutput(ctx context.Context, node *config.Node, nodeName string, state session.State, yield func(*session.Event, error) bool, out llmOutput) (bool, error) {
+	// Check the answer before it reaches state or the user
+	if a.Config.GuardrailsFor(node) != nil {
+		answer, routed, ok, err := a.applyGuardrails(ctx, node, nodeName, out.llm, out.answer, state, yield)
+		if err != nil || !ok {
+			return false, err
+		}
+		if routed {
+			return true, nil
+		}
+		out.answer = answer
+	}
+
+	if !out.streamed && node.DisplayMode() == config.DisplayStream && out.answer != "" {
+		if !yield(textEvent(out.answer), nil) {
+			return false, nil
+		}
+	}
+
+	// ADK's OutputSchema doesn't work reliably with tool-enabled nodes, so
+	// output_model values are parsed from the answer
+	if len(node.OutputModel) > 0 {
+		parsed, err := out.parser.parseOutput(ctx, out.answer)
+		if err != nil {
+			if a.DebugMode {
+				slog.Debug("failed to extract output_model", "node", nodeName, "error", err)
+			}
+			return false, err
+		}
+		delta := make(map[string]any)
+		for key, typeName := range node.OutputModel {
+			val, ok := parsed[key]
+			if !ok {
+				if a.DebugMode {
+					slog.Debug("key not found in parsed output", "key", key)
+				}
+				continue
+			}
+			val = coerceOutputValue(parseOutputType(typeName), val)
+			state.Set(key, val)
+			delta[key] = val
+		}
+		if len(delta) > 0 {
+			if a.DebugMode {
+				slog.Debug("emitting state delta", "keys", getKeys(delta))
+			}
+			yield(&session.Event{Actions: session.EventActions{StateDelta: delta}}, nil)
+		}
+	}
+
+	// The AfterToolCallback stores raw_tool_output data in state; emitting
+	// it as a StateDelta too preserves it in the event history when the
+	// session pauses for user input
+	if len(node.RawToolOutput) > 0 {
+		delta := make(map[string]any)
+		for stateKey := range node.RawToolOutput {
+			val, err := state.Get(stateKey)
+			if err != nil || val == nil {
+				continue
+			}
+			// Empty string means not yet populated
+			if strVal, ok := val.(string); ok && strVal == "" {
+				continue
+			}
+			delta[stateKey] = val
+		}
+		if len(delta) > 0 {
+			if a.DebugMode {
+				slog.Debug("emitting raw_tool_output state delta", "keys", getKeys(delta))
+			}
+			yield(&session.Event{Actions: session.EventActions{StateDelta: delta}}, nil)
+		}
+	}
+
+	switch node.DisplayMode() {
+	case config.DisplayUserMessage:
+		return a.emitUserMessage(node, state, yield), nil
+	case config.DisplaySummary:
+		return a.emitDisplaySummary(node, state, yield), nil
+	}
+	return true, nil
+}
+
+// emitUserMessage displays the state values named by the node's
+// user_message. The event carries the text and a _user_message_display
+// marker telling the console to print the "Agent:" prefix; the values
+// themselves are already in the output_model StateDelta.
+func (a *AstonishAgent) emitUserMessage(node *config.Node, state session.State, yield func(*session.Event, error) bool) bool {
+	var textParts []string
+	for _, msgPart := range node.UserMessage {
+		if val, err := state.Get(msgPart); err == nil {
+			textParts = append(textParts, fmt.Sprintf("%v", val))
+			if a.DebugMode {
+				slog.Debug("resolved user message part", "part", msgPart, "value", val)
+			}
+		}
+	}
+	if len(textParts) == 0 {
+		return true
+	}
+
+	event := textEvent(strings.Join(textParts, " "))
+	event.Actions.StateDelta = map[string]any{
+		"_user_message_display": true,
+	}
+	return yield(event, nil)
+}
+
+// textEvent is a model event showing text.
+func textEvent(text string) *session.Event {
+	return &session.Event{
+		LLMResponse: model.LLMResponse{
+			Content: &genai.Content{
+				Parts: []*genai.Part{{Text: text}},
+				Role:  "model",
+			},
+		},
+	}
+}
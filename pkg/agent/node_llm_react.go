@@ -2,7 +2,6 @@ package agent
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log/slog"
 	"strings"
@@ -11,12 +10,23 @@ import (
 	"github.com/SAP/astonish/pkg/planner"
 	"google.golang.org/adk/model"
 	"google.golang.org/adk/session"
-	"google.golang.org/adk/tool"
 	"google.golang.org/genai"
 )
 
-// executeReActFallback runs the ReAct planner as a fallback when native tool calling is not supported
-func (a *AstonishAgent) executeReActFallback(ctx context.Context, node *config.Node, nodeName string, state session.State, yield func(*session.Event, error) bool, internalTools []tool.Tool, instruction string, userPrompt string) (bool, error) {
+// reactFallbackOptions is what an LLM node attempt hands to the ReAct
+// fallback: the node's model, tools and rendered request.
+type reactFallbackOptions struct {
+	llm   model.LLM
+	tools nodeToolSet
+	req   *llmRequest
+}
+
+// runReActFallback runs the ReAct planner as a fallback when native tool
+// calling is not supported, both when the node is known to need it and when
+// the model rejects a tool call. It runs the same request, tools and output
+// stage as the ADK path, with approvals and raw_tool_output handled as the
+// ADK tool callbacks do.
+func (a *AstonishAgent) runReActFallback(ctx context.Context, node *config.Node, nodeName string, state session.State, yield func(*session.Event, error) bool, opts reactFallbackOptions) (bool, error) {
 	// Emit a message indicating we're using the fallback via spinner update
 	yield(&session.Event{
 		Actions: session.EventActions{
@@ -26,126 +36,24 @@ func (a *AstonishAgent) executeReActFallback(ctx context.Context, node *config.N
 		},
 	}, nil)
 
-	// Collect all tools (internal + MCP) for ReAct planner
-	allTools := make([]tool.Tool, 0, len(internalTools))
-	allTools = append(allTools, internalTools...)
-
-	// Add MCP tools
-	if len(a.Toolsets) > 0 {
-		minimalCtx := &minimalReadonlyContext{Context: ctx}
-		for _, ts := range a.Toolsets {
-			tsTools, err := ts.Tools(minimalCtx)
-			if err != nil {
-				continue
-			}
-			// Filter by tools_selection if specified
-			if len(node.ToolsSelection) > 0 {
-				for _, t := range tsTools {
-					for _, selected := range node.ToolsSelection {
-						if t.Name() == selected {
-							allTools = append(allTools, t)
-							break
-						}
-					}
-				}
-			} else {
-				allTools = append(allTools, tsTools...)
-			}
-		}
-	}
-
-	// Create approval callback if tools_auto_approval is false
-	var approvalCallback planner.ApprovalCallback
-	if !node.ToolsAutoApproval {
-		approvalCallback = func(toolName string, args map[string]any) (bool, error) {
-			// Node-scoped approval key
-			approvalKey := fmt.Sprintf("approval:%s:%s", node.Name, toolName)
-
-			// Check if we already have approval for this tool
-			approvedVal, _ := state.Get(approvalKey)
-			approved := false
-			if b, ok := approvedVal.(bool); ok && b {
-				approved = true
-			}
-
-			if approved {
-				// Consume approval - each execution requires new approval
-				state.Set(approvalKey, false)
-				if a.DebugMode {
-					slog.Debug("tool approved, allowing execution", "component", "react", "tool", toolName)
-				}
-				return true, nil
-			}
-
-			if a.DebugMode {
-				slog.Debug("tool not approved, requesting approval", "component", "react", "tool", toolName)
-			}
-
-			// No approval - request it
-			state.Set("force_pause", true)
-			state.Set("awaiting_approval", true)
-			state.Set("approval_tool", toolName)
-			state.Set("approval_args", args)
-
-			// Emit approval request event
-			prompt := a.formatToolApprovalRequest(toolName, args)
-			yield(&session.Event{
-				LLMResponse: model.LLMResponse{
-					Content: &genai.Content{
-						Parts: []*genai.Part{{Text: prompt}},
-						Role:  "model",
-					},
-				},
-				Actions: session.EventActions{
-					StateDelta: map[string]any{
-						"awaiting_approval": true,
-						"approval_tool":     toolName,
-						"approval_options":  []string{"Yes", "No"},
-					},
-				},
-			}, nil)
-
-			return false, nil
-		}
+	// The planner takes the tools of the toolsets as plain tools
+	reactPlanner := planner.NewReActPlanner(opts.llm, opts.tools.all(ctx))
+	reactPlanner.State = state
+	reactPlanner.DebugMode = a.DebugMode
+	reactPlanner.Generation = generateContentConfig(node)
+	if !node.ToolsAutoApproval && !a.AutoApprove {
+		reactPlanner.ApprovalCallback = a.reactApprovalCallback(node, state, yield, reactPlanner)
 	}
+	reactPlanner.ToolResult = a.reactToolResultCallback(node, state, yield)
 
-	// Use manual ReAct planner with all tools and approval callback
-	var reactPlanner *planner.ReActPlanner
-	if approvalCallback != nil {
-		reactPlanner = planner.NewReActPlannerWithApproval(a.LLM, allTools, approvalCallback, state, a.DebugMode)
-	} else {
-		reactPlanner = planner.NewReActPlanner(a.LLM, allTools)
-	}
-
-	// Strip output_model instructions from system instruction for ReAct
-	// The ReAct loop should focus on tool usage, not output formatting
-	cleanInstruction := instruction
-	if len(node.OutputModel) > 0 {
-		// Remove lines mentioning output_model fields
-		lines := strings.Split(instruction, "\n")
-		var cleanLines []string
-		for _, line := range lines {
-			// Skip lines that mention the output model fields or JSON formatting
-			skipLine := false
-			for key := range node.OutputModel {
-				if strings.Contains(line, key) || strings.Contains(line, "JSON") || strings.Contains(line, "json") {
-					skipLine = true
-					break
-				}
-			}
-			if !skipLine {
-				cleanLines = append(cleanLines, line)
-			}
-		}
-		cleanInstruction = strings.Join(cleanLines, "\n")
-	}
-
-	result, err := reactPlanner.Run(ctx, userPrompt, cleanInstruction) // Pass cleaned instruction
+	// The ReAct loop focuses on tool usage, not output formatting:
+	// FormatOutput produces the output_model JSON afterwards
+	result, err := reactPlanner.Run(ctx, opts.req.userPrompt, stripOutputModelLines(opts.req.instruction, node.OutputModel))
+	recordModelUsed(state, opts.llm)
 	if err != nil {
-		// Check if this is an approval required error
+		// Approval is needed - the callback has already emitted the approval
+		// request; pause execution
 		if strings.HasPrefix(err.Error(), "APPROVAL_REQUIRED:") {
-			// Approval is needed - the callback has already emitted the approval request
-			// Just return false to pause execution
 			if a.DebugMode {
 				slog.Debug("pausing for tool approval", "component", "react")
 			}
@@ -154,63 +62,104 @@ func (a *AstonishAgent) executeReActFallback(ctx context.Context, node *config.N
 		return false, fmt.Errorf("ReAct planner failed: %w", err)
 	}
 
-	// Format output according to output_model if specified
-	if len(node.OutputModel) > 0 {
-		formattedResult, formatErr := reactPlanner.FormatOutput(ctx, result, node.OutputModel, instruction)
-		if formatErr != nil {
-			return false, fmt.Errorf("failed to format ReAct output: %w", formatErr)
-		}
-		result = formattedResult
+	return a.completeLLMOutput(ctx, node, nodeName, state, yield, llmOutput{
+		answer: result,
+		llm:    opts.llm,
+		parser: reactOutputParser{
+			planner:     reactPlanner,
+			instruction: opts.req.instruction,
+			json:        jsonOutputParser{a: a, node: node, nodeName: nodeName, llm: opts.llm},
+		},
+	})
+}
 
-		// Parse the formatted result and store in state
-		var resultMap map[string]any
-		if err := json.Unmarshal([]byte(result), &resultMap); err == nil {
-			for key, value := range resultMap {
-				state.Set(key, value)
+// reactApprovalCallback asks for the approval of each tool call the
+// planner makes, with the step's Thought as its reason, consuming the
+// approval given when the node resumes.
+func (a *AstonishAgent) reactApprovalCallback(node *config.Node, state session.State, yield func(*session.Event, error) bool, reactPlanner *planner.ReActPlanner) planner.ApprovalCallback {
+	return func(toolName string, args map[string]any) (bool, error) {
+		// Check if we already have approval for this exact call
+		if hasApproval(state, node.Name, toolName, args) {
+			// Consume approval - each execution requires new approval
+			revokeApproval(state, node.Name, toolName)
+			if a.DebugMode {
+				slog.Debug("tool approved, allowing execution", "component", "react", "tool", toolName)
 			}
+			return true, nil
 		}
-	}
 
-	// Handle user_message if defined
-	if len(node.UserMessage) > 0 {
-		var textParts []string
-		for _, msgPart := range node.UserMessage {
-			if val, err := state.Get(msgPart); err == nil {
-				textParts = append(textParts, fmt.Sprintf("%v", val))
-				if a.DebugMode {
-					slog.Debug("resolved user message part", "component", "react", "part", msgPart, "value", val)
-				}
-			}
+		if a.DebugMode {
+			slog.Debug("tool not approved, requesting approval", "component", "react", "tool", toolName)
 		}
 
-		if len(textParts) > 0 {
-			// Emit user_message event
-			userMessageEvent := &session.Event{
-				LLMResponse: model.LLMResponse{
-					Content: &genai.Content{
-						Parts: []*genai.Part{{Text: strings.Join(textParts, " ")}},
-						Role:  "model",
-					},
-				},
-				Actions: session.EventActions{
-					StateDelta: map[string]any{
-						"_user_message_display": true,
-					},
-				},
-			}
-			yield(userMessageEvent, nil)
+		// No approval - request it
+		state.Set("force_pause", true)
+		state.Set("awaiting_approval", true)
+		state.Set("approval_tool", toolName)
+		state.Set("approval_args", args)
+
+		reason := a.approvalReason(reactPlanner.Thought)
+		prompt := a.formatToolApprovalRequest(toolName, args, reason)
+		delta := map[string]any{
+			"awaiting_approval": true,
+			"approval_tool":     toolName,
+			"approval_args":     args,
+			"approval_options":  approvalOptions,
 		}
-	} else {
-		// No user_message - yield the full result
+		addApprovalReason(delta, reason)
 		yield(&session.Event{
 			LLMResponse: model.LLMResponse{
 				Content: &genai.Content{
-					Parts: []*genai.Part{{Text: result}},
+					Parts: []*genai.Part{{Text: prompt}},
 					Role:  "model",
 				},
 			},
+			Actions: session.EventActions{StateDelta: delta},
 		}, nil)
+
+		return false, nil
 	}
+}
 
-	return true, nil
+// reactToolResultCallback stores raw_tool_output results in state like the
+// ADK AfterToolCallback. The planner runs on the goroutine that owns yield,
+// so the state delta is yielded directly.
+func (a *AstonishAgent) reactToolResultCallback(node *config.Node, state session.State, yield func(*session.Event, error) bool) planner.ToolResultCallback {
+	return func(toolName string, args map[string]any, result map[string]any) (map[string]any, error) {
+		if a.Redactor != nil && result != nil {
+			result = a.Redactor.RedactMap(result)
+		}
+		sanitized, stored, err := a.storeRawToolOutput(node, toolName, result, func(delta map[string]any) error {
+			for k, v := range delta {
+				if err := state.Set(k, v); err != nil {
+					return err
+				}
+			}
+			yield(&session.Event{Actions: session.EventActions{StateDelta: delta}}, nil)
+			return nil
+		})
+		if err != nil || !stored {
+			return result, err
+		}
+		return sanitized, nil
+	}
+}
+
+// stripOutputModelLines removes the lines of instruction that mention an
+// output_model field or JSON formatting.
+func stripOutputModelLines(instruction string, outputModel map[string]string) string {
+	if len(outputModel) == 0 {
+		return instruction
+	}
+	var kept []string
+	for _, line := range strings.Split(instruction, "\n") {
+		skip := strings.Contains(line, "JSON") || strings.Contains(line, "json")
+		for key := range outputModel {
+			skip = skip || strings.Contains(line, key)
+		}
+		if !skip {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
 }
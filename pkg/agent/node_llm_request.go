@@ -0,0 +1,223 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/credentials"
+	"google.golang.org/adk/session"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+)
+
+// llmRequest is what an LLM node attempt sends to the model: the rendered
+// prompt, the system instruction with the node's directives appended, and
+// the structured output schema. The ADK path and the ReAct fallback run
+// the same request.
+type llmRequest struct {
+	userPrompt   string
+	instruction  string
+	outputSchema *genai.Schema
+}
+
+// buildLLMRequest renders the node's prompt and system instruction against
+// state and appends the directives for its tools and outputs.
+func (a *AstonishAgent) buildLLMRequest(node *config.Node, state session.State) *llmRequest {
+	req := &llmRequest{userPrompt: a.renderNodeString(node, node.Prompt, state)}
+	req.instruction = a.systemInstruction(node, state)
+
+	// If no system instruction, use a generic one; the prompt stays the
+	// user message
+	if req.instruction == "" {
+		req.instruction = "You are a helpful AI assistant."
+	}
+
+	// If the instruction contains {{CREDENTIAL:...}} placeholders, append a
+	// directive telling the LLM to preserve them verbatim in tool arguments.
+	// The placeholders are resolved at the tool execution boundary (BeforeToolCallback)
+	// AFTER the LLM emits the tool call — the LLM must NOT interpret or strip them.
+	if credentials.ContainsPlaceholder(req.instruction) {
+		req.instruction += "\n\n" +
+			"IMPORTANT: The text above contains {{CREDENTIAL:...}} placeholder tokens (e.g. " +
+			"{{CREDENTIAL:openstack:username}}). These are NOT literal values — they are " +
+			"secure credential references that will be resolved automatically at execution time. " +
+			"When you use these values in tool calls (e.g. shell_command), you MUST copy them " +
+			"EXACTLY as-is, including the double curly braces. Do NOT replace them with the " +
+			"credential name, do NOT strip the braces, and do NOT attempt to resolve them yourself."
+	}
+
+	if a.DebugMode {
+		slog.Debug("final user prompt", "prompt", req.userPrompt)
+		slog.Debug("final system instruction", "instruction", req.instruction)
+	}
+
+	if node.Tools {
+		req.instruction += "\n\nIMPORTANT: You have access to tools that you MUST use to complete this task. Do not describe what you would do or say you are waiting for results. Instead, immediately call the appropriate tool with the required parameters. The tools are available and ready to use right now."
+	}
+
+	if len(node.RawToolOutput) > 0 {
+		req.instruction += "\n\nIMPORTANT: The tool will return the raw content directly to the state. Your final task for this step is to confirm its retrieval."
+	}
+
+	if len(node.OutputSchema) > 0 {
+		// Full JSON Schema: nested objects and arrays are passed through and
+		// the parsed output is validated against it
+		req.instruction += outputSchemaInstruction(node)
+		req.outputSchema = genaiSchemaFromJSON(node.OutputSchema)
+	} else if len(node.OutputModel) > 0 {
+		req.instruction += "\n\nIMPORTANT: Your response MUST be a valid JSON object with the following structure:\n"
+		req.instruction += "{\n"
+		for key, typeName := range node.OutputModel {
+			req.instruction += fmt.Sprintf("  \"%s\": <%s>,\n", key, typeName)
+		}
+		req.instruction += "}\n"
+		req.instruction += "Do not include any other text, explanations, or markdown formatting. Return ONLY the JSON object."
+
+		properties := make(map[string]*genai.Schema)
+		required := []string{}
+		for key, typeName := range node.OutputModel {
+			// list[int], list[dict] and inline {field: type} schemas keep
+			// their item types; plain list still means list of strings
+			properties[key] = outputTypeSchema(parseOutputType(typeName))
+			required = append(required, key)
+		}
+		req.outputSchema = &genai.Schema{
+			Type:       genai.TypeObject,
+			Properties: properties,
+			Required:   required,
+		}
+	}
+
+	if node.Tools {
+		// Prevent repeating completed work; this helps models like GPT that
+		// may not correctly interpret conversation history
+		req.instruction += "\n\nIMPORTANT: When executing tools, check the conversation history first. " +
+			"If a tool has already been called and returned a successful result (not 'pending_approval'), " +
+			"do NOT call that tool again. Proceed only with tools that haven't completed successfully yet."
+	}
+
+	return req
+}
+
+// systemInstruction renders the node's system prompt and appends its
+// verbatim context: raw_context, the flow glossary and the chat history.
+func (a *AstonishAgent) systemInstruction(node *config.Node, state session.State) string {
+	instruction := a.renderNodeString(node, node.System, state)
+	appendSection := func(section string) {
+		if section == "" {
+			return
+		}
+		if instruction != "" {
+			instruction += "\n\n"
+		}
+		instruction += section
+	}
+
+	// raw_context is not rendered: it holds reference scripts with shell
+	// syntax ({}, ${}, awk, jq) that interpolation would corrupt. Only the
+	// state variables nested inside credential placeholders are resolved,
+	// {{CREDENTIAL:{state_var}:field}} → {{CREDENTIAL:resolved:field}}, so
+	// flows can use dynamic credential names.
+	if node.RawContext != "" {
+		appendSection(a.resolveCredentialVarsInRawContext(node.RawContext, state))
+	}
+
+	// The glossary is appended verbatim too: definitions are not templates
+	appendSection(a.Config.GlossaryFor(node))
+
+	// Chat-mode flows carry the earlier turns of the conversation
+	appendSection(a.chatHistoryInstruction(node.Prompt, node.System, state))
+
+	return instruction
+}
+
+// nodeToolSet is the tools an LLM node may call: internal tools, and MCP
+// toolsets narrowed to the node's tools_selection.
+type nodeToolSet struct {
+	tools    []tool.Tool
+	toolsets []tool.Toolset
+}
+
+// selectNodeTools returns the tools of node, or an empty set when the node
+// does not use tools. A tools_selection naming a tool that neither the
+// internal tools nor the toolsets provide is an error. ctx carries the
+// node's env, if any.
+func (a *AstonishAgent) selectNodeTools(ctx context.Context, node *config.Node) (nodeToolSet, error) {
+	var set nodeToolSet
+	if !node.Tools {
+		return set, nil
+	}
+	toolsets, err := a.nodeToolsets(ctx)
+	if err != nil {
+		return set, err
+	}
+	if len(node.ToolsSelection) == 0 {
+		set.tools = append(set.tools, a.Tools...)
+		set.toolsets = append(set.toolsets, toolsets...)
+		return set, nil
+	}
+
+	selected := make(map[string]bool, len(node.ToolsSelection))
+	for _, name := range node.ToolsSelection {
+		selected[name] = true
+	}
+	found := make(map[string]bool)
+
+	for _, t := range a.Tools {
+		found[t.Name()] = true
+		if selected[t.Name()] {
+			set.tools = append(set.tools, t)
+		}
+	}
+
+	// Only keep the toolsets providing a selected tool, filtered down to
+	// the selected tools
+	minimalCtx := &minimalReadonlyContext{Context: ctx}
+	for _, ts := range toolsets {
+		tsTools, err := ts.Tools(minimalCtx)
+		if err != nil {
+			continue
+		}
+		matches := false
+		for _, t := range tsTools {
+			found[t.Name()] = true
+			matches = matches || selected[t.Name()]
+		}
+		if matches {
+			set.toolsets = append(set.toolsets, &FilteredToolset{
+				underlying:   ts,
+				allowedTools: node.ToolsSelection,
+			})
+		}
+	}
+
+	var missing []string
+	for _, name := range node.ToolsSelection {
+		if !found[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nodeToolSet{}, fmt.Errorf("configured tools not found: %s", strings.Join(missing, ", "))
+	}
+	return set, nil
+}
+
+// all returns the internal tools followed by the tools of the toolsets, for
+// callers that cannot take toolsets, like the ReAct planner. Toolsets that
+// fail to list their tools are skipped.
+func (s nodeToolSet) all(ctx context.Context) []tool.Tool {
+	tools := append([]tool.Tool(nil), s.tools...)
+	minimalCtx := &minimalReadonlyContext{Context: ctx}
+	for _, ts := range s.toolsets {
+		tsTools, err := ts.Tools(minimalCtx)
+		if err != nil {
+			continue
+		}
+		tools = append(tools, tsTools...)
+	}
+	return tools
+}
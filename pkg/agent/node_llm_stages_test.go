@@ -0,0 +1,297 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+	"google.golang.org/genai"
+)
+
+func TestBuildLLMRequest(t *testing.T) {
+	a := &AstonishAgent{Config: &config.AgentConfig{}}
+	state := NewMockState()
+	state.Set("topic", "billing")
+	node := &config.Node{
+		Name:        "classify",
+		Prompt:      "Classify {topic}",
+		System:      "You triage tickets.",
+		RawContext:  "awk '{print $1}'",
+		OutputModel: map[string]string{"label": "str"},
+	}
+
+	req := a.buildLLMRequest(node, state)
+	if req.userPrompt != "Classify billing" {
+		t.Errorf("prompt = %q, want the rendered prompt", req.userPrompt)
+	}
+	if !strings.HasPrefix(req.instruction, "You triage tickets.\n\nawk '{print $1}'") {
+		t.Errorf("instruction does not start with the system prompt and verbatim raw_context:\n%s", req.instruction)
+	}
+	if !strings.Contains(req.instruction, `"label": <str>`) {
+		t.Errorf("instruction does not describe the output_model:\n%s", req.instruction)
+	}
+	if req.outputSchema == nil || req.outputSchema.Properties["label"] == nil {
+		t.Errorf("output schema = %+v, want a label property", req.outputSchema)
+	}
+
+	if got := a.buildLLMRequest(&config.Node{Prompt: "Hi"}, state).instruction; got != "You are a helpful AI assistant." {
+		t.Errorf("instruction without system prompt = %q", got)
+	}
+}
+
+func TestSelectNodeTools(t *testing.T) {
+	newTool := func(name string) tool.Tool {
+		tl, err := functiontool.New(functiontool.Config{Name: name, Description: name},
+			func(_ tool.Context, _ map[string]any) (map[string]any, error) { return nil, nil })
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tl
+	}
+	a := &AstonishAgent{Tools: []tool.Tool{newTool("read_file"), newTool("shell_command")}}
+	ctx := context.Background()
+
+	set, err := a.selectNodeTools(ctx, &config.Node{Tools: true, ToolsSelection: []string{"shell_command"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(set.tools) != 1 || set.tools[0].Name() != "shell_command" {
+		t.Errorf("selected %v, want shell_command only", set.all(ctx))
+	}
+
+	if set, _ := a.selectNodeTools(ctx, &config.Node{Tools: true}); len(set.tools) != 2 {
+		t.Errorf("selected %d tools without tools_selection, want all 2", len(set.tools))
+	}
+	if set, _ := a.selectNodeTools(ctx, &config.Node{ToolsSelection: []string{"read_file"}}); len(set.all(ctx)) != 0 {
+		t.Error("a node without tools: true got tools")
+	}
+
+	_, err = a.selectNodeTools(ctx, &config.Node{Tools: true, ToolsSelection: []string{"read_file", "deploy"}})
+	if err == nil || !strings.Contains(err.Error(), "deploy") {
+		t.Errorf("err = %v, want the missing tool reported", err)
+	}
+}
+
+func TestLLMEventStage(t *testing.T) {
+	a := &AstonishAgent{Config: &config.AgentConfig{}}
+	ctx := context.Background()
+	state := NewMockState()
+	event := func(parts ...*genai.Part) *session.Event {
+		return &session.Event{LLMResponse: model.LLMResponse{Content: &genai.Content{Role: "model", Parts: parts}}}
+	}
+
+	// JSON answers of output_model nodes are held back
+	stage := a.newLLMEventStage(&config.Node{OutputModel: map[string]string{"label": "str"}}, "classify")
+	show, err := stage.process(ctx, state, event(&genai.Part{Text: `{"label":`}, &genai.Part{Text: ` "bug"}`}))
+	if err != nil || show {
+		t.Errorf("process = %v, %v; want the text held back", show, err)
+	}
+	if got := stage.answer(); got != `{"label": "bug"}` {
+		t.Errorf("answer = %q", got)
+	}
+
+	// Text of stream nodes is shown, tool calls always
+	stream := a.newLLMEventStage(&config.Node{}, "chat")
+	if show, _ := stream.process(ctx, state, event(&genai.Part{Text: "Hello"})); !show {
+		t.Error("stream node text was held back")
+	}
+	call := event(&genai.Part{FunctionCall: &genai.FunctionCall{Name: "read_file"}})
+	if show, _ := stage.process(ctx, state, call); !show {
+		t.Error("tool call was held back")
+	}
+
+	// A failed tool is reported after its event
+	failed := event(&genai.Part{FunctionResponse: &genai.FunctionResponse{Name: "read_file", Response: map[string]any{"error": "no such file"}}})
+	if show, err := stage.process(ctx, state, failed); !show || err != nil {
+		t.Errorf("process = %v, %v; want the failed tool response forwarded", show, err)
+	}
+	if stage.toolErr == nil || !strings.Contains(stage.toolErr.Error(), "no such file") {
+		t.Errorf("toolErr = %v", stage.toolErr)
+	}
+
+	// Too many tool calls end the attempt
+	var lastErr error
+	for range defaultMaxToolCalls {
+		_, lastErr = stage.process(ctx, state, call)
+	}
+	if lastErr == nil || !strings.Contains(lastErr.Error(), "tool call limit exceeded") {
+		t.Errorf("err = %v, want the tool call limit", lastErr)
+	}
+	if !toolCallingUnsupported(errors.New("model llama2 does not support tools")) || toolCallingUnsupported(errors.New("rate limited")) {
+		t.Error("toolCallingUnsupported misclassified an error")
+	}
+
+	// max_tool_calls sets the limit, with a warning once at 80%
+	limited := a.newLLMEventStage(&config.Node{MaxToolCalls: 5}, "search")
+	for i := 1; i <= 6; i++ {
+		call := event(&genai.Part{FunctionCall: &genai.FunctionCall{Name: "web_search"}})
+		_, err := limited.process(ctx, state, call)
+		_, warned := call.Actions.StateDelta[ToolCallWarningKey]
+		if warned != (i == 4) {
+			t.Errorf("call %d: warning = %v", i, warned)
+		}
+		if (err != nil) != (i == 6) {
+			t.Errorf("call %d: err = %v", i, err)
+		}
+	}
+}
+
+func TestToolCallReasoning(t *testing.T) {
+	a := &AstonishAgent{Config: &config.AgentConfig{}, IsWebMode: true}
+	ctx := context.Background()
+	state := NewMockState()
+	event := func(partial bool, parts ...*genai.Part) *session.Event {
+		return &session.Event{LLMResponse: model.LLMResponse{Partial: partial, Content: &genai.Content{Role: "model", Parts: parts}}}
+	}
+	call := &genai.Part{FunctionCall: &genai.FunctionCall{Name: "shell_command"}}
+
+	stage := a.newLLMEventStage(&config.Node{}, "cleanup")
+	stage.process(ctx, state, event(true, &genai.Part{Text: "The disk is"}))
+	stage.process(ctx, state, event(false, &genai.Part{Text: "The disk is\n full,  so I will\nremove old logs."}, call))
+	if got := stage.reasoning.get(); got != "The disk is full, so I will remove old logs." {
+		t.Errorf("reasoning = %q, want the turn's text collapsed", got)
+	}
+
+	// A turn without text falls back to its thoughts
+	stage.process(ctx, state, event(false, &genai.Part{FunctionResponse: &genai.FunctionResponse{Name: "shell_command"}}))
+	stage.process(ctx, state, event(false, &genai.Part{Text: "Check the space freed.", Thought: true}, call))
+	if got := stage.reasoning.get(); got != "Check the space freed." {
+		t.Errorf("reasoning = %q, want the thought summary", got)
+	}
+
+	prompt := a.formatToolApprovalRequest("shell_command", map[string]any{"command": "df -h"}, stage.reasoning.get())
+	if !strings.Contains(prompt, "*Why:* Check the space freed.") {
+		t.Errorf("approval request does not show the reason:\n%s", prompt)
+	}
+
+	long := a.approvalReason(strings.Repeat("a", 400) + " end")
+	if !strings.HasPrefix(long, "…") || !strings.HasSuffix(long, " end") || len([]rune(long)) > maxApprovalReasonLen+1 {
+		t.Errorf("long reason = %q, want its end kept", long)
+	}
+}
+
+// fixedParser returns the same output for every answer.
+type fixedParser map[string]any
+
+func (p fixedParser) parseOutput(context.Context, string) (map[string]any, error) { return p, nil }
+
+func TestCompleteLLMOutput(t *testing.T) {
+	a := &AstonishAgent{Config: &config.AgentConfig{}}
+	node := &config.Node{
+		Name:          "classify",
+		OutputModel:   map[string]string{"label": "str", "score": "int"},
+		RawToolOutput: map[string]string{"ticket": "body"},
+		UserMessage:   []string{"label"},
+	}
+	state := NewMockState()
+	state.Set("ticket", "The app crashes")
+
+	var events []*session.Event
+	yield := func(e *session.Event, _ error) bool { events = append(events, e); return true }
+	ok, err := a.completeLLMOutput(context.Background(), node, node.Name, state, yield, llmOutput{
+		answer: "ignored",
+		parser: fixedParser{"label": "bug", "score": 7.0, "extra": "dropped"},
+	})
+	if err != nil || !ok {
+		t.Fatalf("completeLLMOutput = %v, %v", ok, err)
+	}
+
+	if v, _ := state.Get("score"); v != 7 {
+		t.Errorf("score = %#v, want the int 7", v)
+	}
+	if _, err := state.Get("extra"); err == nil {
+		t.Error("a key outside output_model reached state")
+	}
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want output_model delta, raw_tool_output delta and user message", len(events))
+	}
+	if events[0].Actions.StateDelta["label"] != "bug" || events[1].Actions.StateDelta["ticket"] != "The app crashes" {
+		t.Errorf("state deltas = %v, %v", events[0].Actions.StateDelta, events[1].Actions.StateDelta)
+	}
+	if events[2].Actions.StateDelta["_user_message_display"] != true || events[2].LLMResponse.Content.Parts[0].Text != "bug" {
+		t.Errorf("user message event = %+v", events[2])
+	}
+}
+
+func TestJSONOutputParser(t *testing.T) {
+	a := &AstonishAgent{}
+	p := jsonOutputParser{a: a, node: &config.Node{OutputModel: map[string]string{"label": "str"}}, nodeName: "classify"}
+
+	got, err := p.parseOutput(context.Background(), "```json\n{\"label\": \"bug\"}\n```")
+	if err != nil || got["label"] != "bug" {
+		t.Errorf("parseOutput = %v, %v", got, err)
+	}
+	if _, err := p.parseOutput(context.Background(), "  "); err == nil || !strings.Contains(err.Error(), "empty response") {
+		t.Errorf("err = %v, want the empty response reported", err)
+	}
+	if _, err := p.parseOutput(context.Background(), "not json"); err == nil || !strings.Contains(err.Error(), "failed to parse") {
+		t.Errorf("err = %v, want a parse error", err)
+	}
+}
+
+func TestReActFallbackStoresRawToolOutput(t *testing.T) {
+	fetch, err := functiontool.New(functiontool.Config{Name: "fetch_ticket", Description: "Fetches a ticket"},
+		func(_ tool.Context, _ map[string]any) (map[string]any, error) {
+			return map[string]any{"body": "The app crashes"}, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	llm := &ADKMockModel{Responses: []*genai.Content{
+		genai.NewContentFromText("Thought: fetch it\nAction: fetch_ticket\nAction Input: {}", genai.RoleModel),
+		genai.NewContentFromText("Final Answer: fetched", genai.RoleModel),
+	}}
+	a := &AstonishAgent{Config: &config.AgentConfig{}, Tools: []tool.Tool{fetch}}
+	node := &config.Node{
+		Name:              "fetch",
+		Prompt:            "Fetch the ticket",
+		Tools:             true,
+		ToolsAutoApproval: true,
+		RawToolOutput:     map[string]string{"ticket": "any"},
+		Display:           config.DisplayNone,
+	}
+	state := NewMockState()
+	tools, _ := a.selectNodeTools(context.Background(), node)
+
+	var deltas []map[string]any
+	yield := func(e *session.Event, _ error) bool {
+		if e != nil && e.Actions.StateDelta != nil {
+			deltas = append(deltas, e.Actions.StateDelta)
+		}
+		return true
+	}
+	ok, err := a.runReActFallback(context.Background(), node, node.Name, state, yield, reactFallbackOptions{
+		llm: llm, tools: tools, req: a.buildLLMRequest(node, state),
+	})
+	if err != nil || !ok {
+		t.Fatalf("runReActFallback = %v, %v", ok, err)
+	}
+
+	ticket, _ := state.Get("ticket")
+	if m, _ := ticket.(map[string]any); m["body"] != "The app crashes" {
+		t.Errorf("ticket = %v, want the raw tool result", ticket)
+	}
+	stored := false
+	for _, d := range deltas {
+		_, has := d["ticket"]
+		stored = stored || has
+	}
+	if !stored {
+		t.Error("raw_tool_output was not emitted as a state delta")
+	}
+	// The model only saw the confirmation
+	last := llm.Requests[len(llm.Requests)-1]
+	for _, c := range last.Contents {
+		for _, p := range c.Parts {
+			if strings.Contains(p.Text, "The app crashes") {
+				t.Error("the raw tool result reached the model")
+			}
+		}
+	}
+}
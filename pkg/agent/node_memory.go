@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/memory"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+)
+
+const (
+	// defaultMemoryRecallLimit is the number of facts a recall returns when
+	// the node sets no limit.
+	defaultMemoryRecallLimit = 5
+	// defaultMemoryOutputKey receives recalled facts when the node declares
+	// no output_model key.
+	defaultMemoryOutputKey = "memories"
+)
+
+// handleMemoryNode stores a fact in long-term memory or recalls the facts
+// matching a query into state. Recalled facts are written as a list of
+// strings, most relevant first.
+func (a *AstonishAgent) handleMemoryNode(ctx agent.InvocationContext, node *config.Node, state session.State, yield func(*session.Event, error) bool) bool {
+	mc := node.Memory
+	if mc == nil {
+		yield(nil, fmt.Errorf("memory node %s: missing memory configuration", node.Name))
+		return false
+	}
+	facts, err := a.factStore()
+	if err != nil {
+		yield(nil, fmt.Errorf("memory node %s: %w", node.Name, err))
+		return false
+	}
+	namespace := a.memoryNamespace(ctx, node, state)
+
+	switch mc.Action {
+	case "store":
+		content := a.renderNodeString(node, mc.Content, state)
+		if _, err := facts.Remember(ctx, namespace, content); err != nil {
+			yield(nil, fmt.Errorf("memory node %s: %w", node.Name, err))
+			return false
+		}
+		if a.DebugMode {
+			slog.Debug("memory node stored fact", "node", node.Name, "namespace", namespace)
+		}
+		return yield(&session.Event{Actions: session.EventActions{StateDelta: map[string]any{}}}, nil)
+
+	case "recall":
+		limit := mc.Limit
+		if limit <= 0 {
+			limit = defaultMemoryRecallLimit
+		}
+		query := a.renderNodeString(node, mc.Query, state)
+		found, err := facts.Recall(ctx, namespace, query, limit)
+		if err != nil {
+			yield(nil, fmt.Errorf("memory node %s: %w", node.Name, err))
+			return false
+		}
+		recalled := make([]any, 0, len(found))
+		for _, f := range found {
+			recalled = append(recalled, f.Content)
+		}
+		key := memoryOutputKey(node)
+		if err := state.Set(key, recalled); err != nil {
+			yield(nil, fmt.Errorf("failed to set state key %s: %w", key, err))
+			return false
+		}
+		if a.DebugMode {
+			slog.Debug("memory node recalled facts", "node", node.Name, "namespace", namespace, "count", len(recalled))
+		}
+		return yield(&session.Event{Actions: session.EventActions{StateDelta: map[string]any{key: recalled}}}, nil)
+	}
+
+	yield(nil, fmt.Errorf("memory node %s: unknown action %q (use store or recall)", node.Name, mc.Action))
+	return false
+}
+
+// factStore returns the agent's fact store, or else the one configured in
+// AppConfig.
+func (a *AstonishAgent) factStore() (memory.FactStore, error) {
+	if a.Memory != nil {
+		return a.Memory, nil
+	}
+	return memory.FlowFactStore(a.AppConfig, a.DebugMode)
+}
+
+// memoryNamespace returns the fact namespace of a memory node: the flow's
+// or the session user's, narrowed by the node's rendered key.
+func (a *AstonishAgent) memoryNamespace(ctx agent.InvocationContext, node *config.Node, state session.State) string {
+	var namespace string
+	if node.Memory.Scope == "user" {
+		namespace = memory.UserNamespace(ctx.Session().UserID())
+	} else {
+		namespace = memory.FlowNamespace(a.flowName())
+	}
+	if node.Memory.Key != "" {
+		if key := strings.TrimSpace(a.renderNodeString(node, node.Memory.Key, state)); key != "" {
+			namespace += "/" + key
+		}
+	}
+	return namespace
+}
+
+// flowName identifies the flow for flow-scoped memory. Without a FlowName
+// from the launcher, the node names stand in, so a flow keeps its memory as
+// long as its structure is unchanged.
+func (a *AstonishAgent) flowName() string {
+	if a.FlowName != "" {
+		return a.FlowName
+	}
+	names := make([]string, 0, len(a.Config.Nodes))
+	for _, n := range a.Config.Nodes {
+		names = append(names, n.Name)
+	}
+	sum := sha256.Sum256([]byte(strings.Join(names, "\n")))
+	return "unnamed-" + hex.EncodeToString(sum[:6])
+}
+
+// memoryOutputKey is the state key recalled facts are written to: the
+// node's single output_model key, or "memories".
+func memoryOutputKey(node *config.Node) string {
+	for key := range node.OutputModel {
+		return key
+	}
+	return defaultMemoryOutputKey
+}
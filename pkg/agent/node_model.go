@@ -0,0 +1,139 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/provider"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// nodeLLM returns the model an LLM node should call: a cached client for the
+// node's provider/model override, else the agent's model. With
+// model_fallbacks configured, it is wrapped so provider errors fail over to
+// the next entry in the chain; with cache_response, so identical requests
+// are answered from the response cache. With DumpLLMIO, the calls that
+// reach the provider are written to that directory.
+func (a *AstonishAgent) nodeLLM(ctx context.Context, node *config.Node) (model.LLM, error) {
+	base, name := a.LLM, a.modelLabel(a.ProviderName, a.ModelName)
+	if node.Provider != "" || node.Model != "" {
+		if a.AppConfig == nil {
+			return nil, fmt.Errorf("node '%s' sets provider/model but no provider configuration is available", node.Name)
+		}
+		providerName, modelName := a.nodeProviderModel(node)
+		if !strings.EqualFold(providerName, a.ProviderName) || modelName != a.ModelName {
+			llm, err := a.cachedLLM(ctx, providerName, modelName)
+			if err != nil {
+				return nil, fmt.Errorf("node '%s': %w", node.Name, err)
+			}
+			// Keep parallel branches on the shared throttle
+			if t, ok := a.LLM.(*throttledLLM); ok {
+				llm = &throttledLLM{LLM: llm, throttle: t.throttle}
+			}
+			base, name = llm, a.modelLabel(providerName, modelName)
+		}
+	}
+
+	llm := base
+	if chain := a.modelFallbacks(node); len(chain) > 0 {
+		if a.AppConfig == nil {
+			slog.Warn("model_fallbacks ignored: no provider configuration available", "node", node.Name)
+		} else {
+			llm = &fallbackLLM{
+				LLM:         base,
+				primaryName: name,
+				chain:       chain,
+				resolve:     a.resolveFallbackModel,
+			}
+		}
+	}
+	if a.DumpLLMIO != "" {
+		llm = &llmDumpLLM{LLM: llm, dir: a.DumpLLMIO, node: node.Name}
+	}
+	if a.cachesResponse(node) {
+		llm = &cachedResponseLLM{LLM: llm}
+	}
+	return llm, nil
+}
+
+// nodeProviderModel returns the provider instance and model a node calls:
+// its own provider/model, else the agent's.
+func (a *AstonishAgent) nodeProviderModel(node *config.Node) (string, string) {
+	providerName, modelName := node.Provider, node.Model
+	if providerName == "" {
+		providerName = a.ProviderName
+	}
+	if providerName == "" && a.AppConfig != nil {
+		providerName = a.AppConfig.General.DefaultProvider
+	}
+	if modelName == "" && strings.EqualFold(providerName, a.ProviderName) {
+		modelName = a.ModelName
+	}
+	return providerName, modelName
+}
+
+// nodeCallsTools reports whether the node's model calls tools natively,
+// from the capabilities its provider registered. Models of unknown
+// providers are assumed to.
+func (a *AstonishAgent) nodeCallsTools(ctx context.Context, node *config.Node) bool {
+	providerName, modelName := a.nodeProviderModel(node)
+	if providerName == "" {
+		return true
+	}
+	caps, ok := provider.CapabilitiesFor(ctx, providerName, modelName, a.AppConfig)
+	if ok && !caps.Tools {
+		slog.Info("model has no native tool calling, using the ReAct planner", "node", node.Name, "provider", providerName, "model", modelName)
+		return false
+	}
+	return true
+}
+
+// hasProvider reports whether name is a configured provider instance.
+func (a *AstonishAgent) hasProvider(name string) bool {
+	for key := range a.AppConfig.Providers {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// cachedLLM returns a client for provider/model from NewModel when set, else
+// reusing clients across nodes and runs when the agent was built with a pool.
+func (a *AstonishAgent) cachedLLM(ctx context.Context, providerName, modelName string) (model.LLM, error) {
+	if a.NewModel != nil {
+		return a.NewModel(ctx, providerName, modelName)
+	}
+	if a.llmPool != nil {
+		return a.llmPool.Get(ctx, providerName, modelName, a.AppConfig)
+	}
+	return provider.GetProvider(ctx, providerName, modelName, a.AppConfig)
+}
+
+// modelLabel formats a provider/model pair for logs and state.
+func (a *AstonishAgent) modelLabel(providerName, modelName string) string {
+	if providerName == "" {
+		return modelName
+	}
+	return providerName + "/" + modelName
+}
+
+// generateContentConfig converts a node's generation settings to the genai
+// config passed with every LLM request. It returns nil when the node sets
+// none, leaving the provider defaults.
+func generateContentConfig(node *config.Node) *genai.GenerateContentConfig {
+	g := node.Generation
+	if g == nil {
+		return nil
+	}
+	return &genai.GenerateContentConfig{
+		Temperature:     g.Temperature,
+		TopP:            g.TopP,
+		MaxOutputTokens: g.MaxOutputTokens,
+		StopSequences:   g.StopSequences,
+	}
+}
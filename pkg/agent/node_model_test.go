@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/provider"
+	"google.golang.org/adk/model"
+)
+
+func TestNodeLLM_PerNodeOverride(t *testing.T) {
+	primary := &MockLLM{}
+	a := &AstonishAgent{
+		LLM:          primary,
+		ProviderName: "openai",
+		ModelName:    "gpt-4o",
+		AppConfig: &config.AppConfig{Providers: map[string]config.ProviderConfig{
+			"openai":    {"api_key": "test"},
+			"anthropic": {"api_key": "test"},
+		}},
+		llmPool: provider.NewPool(),
+	}
+	ctx := context.Background()
+
+	if llm, err := a.nodeLLM(ctx, &config.Node{Name: "plain"}); err != nil || llm != primary {
+		t.Fatalf("node without override: llm = %v, err = %v; want agent model", llm, err)
+	}
+	if llm, err := a.nodeLLM(ctx, &config.Node{Name: "same", Model: "gpt-4o"}); err != nil || llm != primary {
+		t.Fatalf("override matching the agent model: llm = %v, err = %v; want agent model", llm, err)
+	}
+
+	node := &config.Node{Name: "reason", Provider: "anthropic", Model: "claude-big"}
+	first, err := a.nodeLLM(ctx, node)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first == primary {
+		t.Fatal("expected a dedicated client for the node override")
+	}
+	second, _ := a.nodeLLM(ctx, node)
+	if first != second {
+		t.Error("expected the node client to be cached across calls")
+	}
+
+	noCfg := &AstonishAgent{LLM: primary}
+	if _, err := noCfg.nodeLLM(ctx, node); err == nil {
+		t.Error("expected an error when overriding without provider configuration")
+	}
+}
+
+func init() {
+	// A provider whose models cannot call tools, except "tool-model"
+	provider.Register(provider.Registration{
+		Type:         "agent_test_no_tools",
+		Capabilities: provider.Capabilities{Streaming: true},
+		New: func(context.Context, provider.Params) (model.LLM, error) {
+			return &MockLLM{}, nil
+		},
+		ListModels: func(context.Context, config.ProviderConfig) ([]string, error) { return nil, nil },
+		ModelCapabilities: func(_ context.Context, p provider.Params) (provider.Capabilities, bool) {
+			return provider.Capabilities{Tools: true}, p.Model == "tool-model"
+		},
+	})
+}
+
+func TestNodeCallsTools(t *testing.T) {
+	a := &AstonishAgent{
+		ProviderName: "openai",
+		ModelName:    "gpt-4o",
+		AppConfig: &config.AppConfig{Providers: map[string]config.ProviderConfig{
+			"openai": {"api_key": "test"},
+			"local":  {"type": "agent_test_no_tools"},
+		}},
+	}
+	ctx := context.Background()
+	for _, tt := range []struct {
+		node *config.Node
+		want bool
+	}{
+		{&config.Node{Name: "default"}, true},
+		{&config.Node{Name: "local", Provider: "local", Model: "small"}, false},
+		{&config.Node{Name: "local_tools", Provider: "local", Model: "tool-model"}, true},
+		{&config.Node{Name: "unknown", Provider: "missing"}, true},
+	} {
+		if got := a.nodeCallsTools(ctx, tt.node); got != tt.want {
+			t.Errorf("nodeCallsTools(%s) = %v, want %v", tt.node.Name, got, tt.want)
+		}
+	}
+	if !(&AstonishAgent{}).nodeCallsTools(ctx, &config.Node{Name: "bare"}) {
+		t.Error("an agent without provider configuration was assumed to lack tools")
+	}
+}
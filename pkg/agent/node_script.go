@@ -0,0 +1,45 @@
+package agent
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+)
+
+// handleScriptNode runs the Starlark program of a script node. The program
+// reads state through x (and its top-level keys as globals), writes it with
+// x["key"] = value, and can define globals named in output_model instead.
+// Only the keys it changes are written back, in one state delta.
+func (a *AstonishAgent) handleScriptNode(ctx agent.InvocationContext, node *config.Node, state session.State, yield func(*session.Event, error) bool) bool {
+	exports := make([]string, 0, len(node.OutputModel))
+	for key := range node.OutputModel {
+		exports = append(exports, key)
+	}
+	sort.Strings(exports)
+
+	printFn := func(msg string) {
+		slog.Info("script output", "node", node.Name, "message", msg)
+	}
+	updates, err := ExecuteScript(ctx, node.Script, a.stateToMap(state), exports, printFn)
+	if err != nil {
+		yield(nil, fmt.Errorf("script node %s: %w", node.Name, err))
+		return false
+	}
+
+	for key, value := range updates {
+		if err := state.Set(key, value); err != nil {
+			yield(nil, fmt.Errorf("failed to set state variable %s: %w", key, err))
+			return false
+		}
+	}
+	if a.DebugMode {
+		slog.Debug("script node updated state", "node", node.Name, "keys", len(updates))
+	}
+
+	// Emit event with state delta (no LLMResponse text - state updates are internal)
+	return yield(&session.Event{Actions: session.EventActions{StateDelta: updates}}, nil)
+}
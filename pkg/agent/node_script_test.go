@@ -0,0 +1,94 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/adk/session"
+)
+
+func TestExecuteScript(t *testing.T) {
+	state := map[string]any{
+		"issues": []any{
+			map[string]any{"title": "a", "state": "open"},
+			map[string]any{"title": "b", "state": "closed"},
+			map[string]any{"title": "c", "state": "open"},
+		},
+		"unchanged": "same",
+	}
+	script := `
+open = [i for i in issues if i["state"] == "open"]
+x["open_count"] = len(open)
+x["unchanged"] = "same"
+open_titles = [i["title"] for i in open]
+x["payload"] = json.decode(json.encode({"n": len(open)}))
+`
+	updates, err := ExecuteScript(context.Background(), script, state, []string{"open_titles", "missing"}, nil)
+	if err != nil {
+		t.Fatalf("ExecuteScript: %v", err)
+	}
+
+	if updates["open_count"] != 2 {
+		t.Errorf("open_count = %v, want 2", updates["open_count"])
+	}
+	titles, _ := updates["open_titles"].([]any)
+	if len(titles) != 2 || titles[0] != "a" || titles[1] != "c" {
+		t.Errorf("open_titles = %v", updates["open_titles"])
+	}
+	if payload, _ := updates["payload"].(map[string]any); payload["n"] != 2 {
+		t.Errorf("payload = %v", updates["payload"])
+	}
+	for _, key := range []string{"unchanged", "issues", "missing"} {
+		if _, ok := updates[key]; ok {
+			t.Errorf("unexpected update for %q", key)
+		}
+	}
+}
+
+func TestExecuteScriptErrors(t *testing.T) {
+	if _, err := ExecuteScript(context.Background(), `x["a"] = undefined_name`, nil, nil, nil); err == nil || !strings.Contains(err.Error(), "undefined_name") {
+		t.Errorf("expected undefined name error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ExecuteScript(ctx, "while True:\n    pass\n", nil, nil, nil); err == nil {
+		t.Error("expected cancelled script to fail")
+	}
+
+	if err := ParseScript("def f(:\n"); err == nil {
+		t.Error("expected syntax error")
+	}
+}
+
+func TestHandleScriptNode(t *testing.T) {
+	state := NewMockState()
+	state.Data["counter"] = 1
+	a := &AstonishAgent{}
+	node := &config.Node{
+		Name:        "bump",
+		Type:        "script",
+		Script:      "x[\"counter\"] = counter + 1\nprint(\"bumped\")\nlabel = \"n=%d\" % x[\"counter\"]",
+		OutputModel: map[string]string{"label": "str"},
+	}
+
+	var events []*session.Event
+	ok := a.handleScriptNode(&MockInvocationContext{Context: context.Background(), StateVal: state}, node, state, func(ev *session.Event, err error) bool {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		events = append(events, ev)
+		return true
+	})
+	if !ok {
+		t.Fatal("handleScriptNode returned false")
+	}
+	if state.Data["counter"] != 2 || state.Data["label"] != "n=2" {
+		t.Errorf("state = %v", state.Data)
+	}
+	if len(events) != 1 || len(events[0].Actions.StateDelta) != 2 {
+		t.Errorf("expected one event with two changed keys, got %+v", events)
+	}
+}
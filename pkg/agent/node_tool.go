@@ -9,9 +9,10 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/SAP/astonish/pkg/audit"
+	"github.com/SAP/astonish/pkg/cache"
 	"github.com/SAP/astonish/pkg/config"
 	"github.com/SAP/astonish/pkg/credentials"
-	"github.com/SAP/astonish/pkg/store"
 	"google.golang.org/adk/agent"
 	"google.golang.org/adk/model"
 	"google.golang.org/adk/session"
@@ -19,57 +20,208 @@ import (
 	"google.golang.org/genai"
 )
 
+// handleToolNode executes a tool node, retrying failed tool calls up to
+// max_retries (default: 1, i.e. no retry). Args are re-rendered from state
+// before every attempt, so templates may reference {_last_error} and
+// {_retry_attempt}. With the intelligent strategy, error recovery decides
+// whether to retry and may propose corrected args, which are applied only
+// when the node's tools are auto-approved.
 func (a *AstonishAgent) handleToolNode(ctx context.Context, node *config.Node, state session.State, yield func(*session.Event, error) bool) bool {
-	// 1. Resolve arguments
-	resolvedArgs := make(map[string]interface{})
-	for key, val := range node.Args {
-		if strVal, ok := val.(string); ok {
-			resolvedArgs[key] = a.renderString(strVal, state)
-		} else if mapVal, ok := val.(map[string]interface{}); ok && len(mapVal) == 1 {
-			// Handle map arguments (e.g. owner: {owner: str}) -> resolve from state
-			var stateKey string
-			for k := range mapVal {
-				stateKey = k
-				break
+	if len(node.ToolsSelection) == 0 {
+		yield(nil, fmt.Errorf("tool node '%s' missing tools_selection", node.Name))
+		return false
+	}
+	toolName := node.ToolsSelection[0]
+
+	// The node's env reaches the tool through the context
+	ctx = withNodeEnv(ctx, a.nodeEnv(ctx, node, state))
+
+	maxRetries := 1
+	if node.MaxRetries > 0 {
+		maxRetries = node.MaxRetries
+	}
+	useIntelligentRetry := node.RetryStrategy != "simple"
+	autoApproved := node.ToolsAutoApproval || a.AutoApprove
+
+	var errorHistory []string
+	var argOverrides map[string]any
+
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		isLastAttempt := attempt >= maxRetries-1
+
+		if maxRetries > 1 {
+			state.Set("_retry_attempt", attempt+1)
+		}
+
+		// 1. Resolve arguments
+		resolvedArgs := a.resolveToolArgs(node, state)
+		for k, v := range argOverrides {
+			resolvedArgs[k] = v
+		}
+		// Snapshot before execution substitutes credentials in place
+		attemptArgs := make(map[string]any, len(resolvedArgs))
+		for k, v := range resolvedArgs {
+			attemptArgs[k] = v
+		}
+
+		success, err := a.executeToolNodeAttempt(ctx, node, state, resolvedArgs, node.ContinueOnError && isLastAttempt, yield)
+		if success {
+			if maxRetries > 1 {
+				state.Set("_error_context", nil)
+				state.Set("_has_error", false)
 			}
+			return true
+		}
+		if err == nil {
+			// Paused for approval or already reported
+			return false
+		}
 
-			if stateVal, err := state.Get(stateKey); err == nil {
-				resolvedArgs[key] = stateVal
-			} else {
-				if a.DebugMode {
-					slog.Warn("state key not found", "stateKey", stateKey, "arg", key)
-				}
-				resolvedArgs[key] = nil
+		errCtx := ErrorContext{
+			NodeName:       node.Name,
+			NodeType:       "tool",
+			ErrorType:      "tool_execution_error",
+			ErrorMessage:   err.Error(),
+			AttemptCount:   attempt + 1,
+			MaxRetries:     maxRetries,
+			PreviousErrors: errorHistory,
+			ToolName:       toolName,
+			ToolArgs:       attemptArgs,
+		}
+		state.Set("_last_error", err.Error())
+
+		var decision *RecoveryDecision
+		if useIntelligentRetry {
+			// Use LLM-based error recovery (same as LLM nodes)
+			recovery := NewErrorRecoveryNode(a.LLM, a.DebugMode)
+			if d, recoveryErr := recovery.Decide(ctx, errCtx); recoveryErr == nil {
+				decision = d
+			} else if a.DebugMode {
+				slog.Warn("error recovery failed", "component", "retry", "node", node.Name, "error", recoveryErr)
 			}
-		} else {
-			resolvedArgs[key] = val
+		}
+
+		if isLastAttempt || (decision != nil && !decision.ShouldRetry) {
+			a.reportToolFailure(node, toolName, state, err, decision, attempt+1, yield)
+			// Return false to end the node gracefully (flow will transition to next node or END)
+			return false
+		}
+
+		oneLiner := fmt.Sprintf("Attempt %d/%d", attempt+2, maxRetries)
+		if decision != nil && decision.OneLiner != "" {
+			oneLiner = decision.OneLiner
+		}
+		retryDelay := nodeRetryDelay(node, attempt, err)
+		if !yield(&session.Event{
+			Actions: session.EventActions{
+				StateDelta: map[string]any{
+					"_retry_info": map[string]any{
+						"attempt":      attempt + 1,
+						"max_retries":  maxRetries,
+						"reason":       oneLiner,
+						"wait_seconds": retryDelay.Seconds(),
+					},
+					"_processing_info": true,
+				},
+			},
+		}, nil) {
+			return false
+		}
+
+		argOverrides = nil
+		if decision != nil && len(decision.RetryArgs) > 0 {
+			if autoApproved {
+				argOverrides = decision.RetryArgs
+			} else if a.DebugMode {
+				slog.Debug("ignoring recovery retry_args for node requiring approval", "component", "retry", "node", node.Name)
+			}
+		}
+		errorHistory = append(errorHistory, err.Error())
+
+		if err := waitRetryDelay(ctx, retryDelay); err != nil {
+			state.Set("_last_error", err.Error())
+			state.Set("_error_node", node.Name)
+			state.Set("_has_error", true)
+			return false
 		}
 	}
 
-	// 2. Identify Tool
-	if len(node.ToolsSelection) == 0 {
-		yield(nil, fmt.Errorf("tool node '%s' missing tools_selection", node.Name))
-		return false
+	return false
+}
+
+// reportToolFailure emits _failure_info for a tool node that will not be
+// retried and records the error in state for error handler nodes.
+func (a *AstonishAgent) reportToolFailure(node *config.Node, toolName string, state session.State, err error, decision *RecoveryDecision, attempts int, yield func(*session.Event, error) bool) {
+	title := "Tool Execution Failed"
+	reason := fmt.Sprintf("Tool '%s' failed to execute", toolName)
+	suggestion := ""
+	if decision != nil {
+		title = decision.Title
+		reason = decision.Reason
+		suggestion = decision.Suggestion
 	}
+	if attempts > 1 {
+		reason = fmt.Sprintf("%s (failed after %d attempts)", reason, attempts)
+	}
+
+	// Emit failure info with LLM analysis
+	yield(&session.Event{
+		Actions: session.EventActions{
+			StateDelta: map[string]any{
+				"_failure_info": map[string]any{
+					"title":          title,
+					"reason":         reason,
+					"suggestion":     suggestion,
+					"original_error": err.Error(),
+					"node":           node.Name,
+					"tool":           toolName,
+				},
+				"_processing_info": true,
+			},
+		},
+	}, nil)
+
+	// Store error details in state for error handler nodes
+	state.Set("_last_error", err.Error())
+	state.Set("_error_node", node.Name)
+	state.Set("_has_error", true)
+	state.Set("_error_analysis", reason)
+}
+
+// executeToolNodeAttempt runs a single attempt of a tool node with already
+// resolved args. It returns the tool error (if any) so the caller can retry;
+// pauses and setup failures return (false, nil) after yielding. When
+// captureErrors is set, a tool error is stored as the result instead.
+func (a *AstonishAgent) executeToolNodeAttempt(ctx context.Context, node *config.Node, state session.State, resolvedArgs map[string]interface{}, captureErrors bool, yield func(*session.Event, error) bool) (bool, error) {
+	// 2. Identify Tool
 	toolName := node.ToolsSelection[0]
 
+	// A fresh cached result of the same call needs neither approval nor
+	// execution.
+	var cacheKey string
+	var cachedResult map[string]any
+	cached := false
+	if ttl := toolResultTTL(node); ttl > 0 {
+		cacheKey = cache.ToolResultKey(toolName, resolvedArgs)
+		cachedResult, cached = cache.GetToolResult(cacheKey, ttl)
+	}
+
 	// 3. Approval Workflow — match llm-node semantics: per-node
 	// tools_auto_approval OR global AutoApprove (headless / run_flow).
+	// The approval profile denies calls even when they are auto-approved.
 	approved := false
-	if node.ToolsAutoApproval || a.AutoApprove {
+	policyAction := a.ApprovalPolicy.Decide(toolName, resolvedArgs)
+	if policyAction == config.ApprovalDeny {
+		a.auditDenial(ctx, node.Name, toolName, resolvedArgs, a.ApprovalPolicy.approver())
+		return false, fmt.Errorf("tool '%s' was denied by the approval profile '%s'", toolName, a.ApprovalPolicy.Name)
+	}
+	if cached || node.ToolsAutoApproval || a.AutoApprove || policyAction == config.ApprovalApprove {
 		approved = true
 	} else {
-		// Check if we already have approval for this specific tool execution
-		// Node-scoped approval key
-		approvalKey := fmt.Sprintf("approval:%s:%s", node.Name, toolName)
-		val, _ := state.Get(approvalKey)
-		if isApproved, ok := val.(bool); ok && isApproved {
-			approved = true
-			// Clear approval so we don't loop forever if we come back here?
-			// Actually, for a linear flow, it's fine. For a loop, we might want to clear it.
-			// But clearing it might break if we crash and resume?
-			// Let's clear it after execution.
-		}
+		// Check if we already have approval for this specific tool execution.
+		// Approvals are bound to the args, so re-rendered args (loops, retries)
+		// prompt again. The approval is cleared after execution.
+		approved = hasApproval(state, node.Name, toolName, resolvedArgs)
 	}
 
 	if !approved {
@@ -79,7 +231,7 @@ func (a *AstonishAgent) handleToolNode(ctx context.Context, node *config.Node, s
 		state.Set("approval_args", resolvedArgs)
 
 		// Emit approval request
-		approvalText := a.formatToolApprovalRequest(toolName, resolvedArgs)
+		approvalText := a.formatToolApprovalRequest(toolName, resolvedArgs, "")
 		approvalEvent := &session.Event{
 			LLMResponse: model.LLMResponse{
 				Content: &genai.Content{
@@ -93,157 +245,28 @@ func (a *AstonishAgent) handleToolNode(ctx context.Context, node *config.Node, s
 					"current_node":      node.Name,
 					"approval_tool":     toolName,
 					"approval_args":     resolvedArgs,
-					"approval_options":  []string{"Yes", "No"}, // Trigger interactive selection
+					"approval_options":  approvalOptions, // Trigger interactive selection
 				},
 			},
 		}
 		// Yield and return false to pause execution
 		yield(approvalEvent, nil)
-		return false
+		return false, nil
 	}
 
 	// 4. Execute Tool
-	// Find the tool in a.Tools
-	var selectedTool tool.Tool
-	for _, t := range a.Tools {
-		if t.Name() == toolName {
-			selectedTool = t
-			break
-		}
-	}
-
-	// If not found in internal tools, check Toolsets (MCP)
-	if selectedTool == nil && a.Toolsets != nil {
-		roCtx := &minimalReadonlyContext{Context: ctx}
-		for _, ts := range a.Toolsets {
-			tools, err := ts.Tools(roCtx)
-			if err == nil {
-				for _, t := range tools {
-					if t.Name() == toolName {
-						selectedTool = t
-						break
-					}
-				}
-			}
-			if selectedTool != nil {
-				break
-			}
-		}
+	selectedTool, err := a.findTool(ctx, toolName)
+	if err != nil {
+		yield(nil, err)
+		return false, nil
 	}
 	if selectedTool == nil {
 		yield(nil, fmt.Errorf("tool '%s' not found", toolName))
-		return false
+		return false, nil
 	}
 
 	// 5. Type Conversion based on Schema
-	if declTool, ok := selectedTool.(ToolWithDeclaration); ok {
-		if a.DebugMode {
-			slog.Debug("tool implements ToolWithDeclaration", "tool", toolName)
-		}
-		decl := declTool.Declaration()
-		if decl != nil && decl.ParametersJsonSchema != nil {
-			if a.DebugMode {
-				slog.Debug("parameters json schema", "type", fmt.Sprintf("%T", decl.ParametersJsonSchema))
-			}
-			if schema, ok := decl.ParametersJsonSchema.(*genai.Schema); ok {
-				if a.DebugMode {
-					slog.Debug("schema type check", "schemaType", schema.Type, "expected", genai.TypeObject)
-				}
-				if schema.Type == genai.TypeObject {
-					for key, val := range resolvedArgs {
-						if strVal, ok := val.(string); ok {
-							if prop, ok := schema.Properties[key]; ok {
-								if prop.Type == genai.TypeNumber || prop.Type == genai.TypeInteger {
-									if num, err := strconv.ParseFloat(strVal, 64); err == nil {
-										resolvedArgs[key] = num
-										if a.DebugMode {
-											slog.Debug("converted arg to number", "arg", key, "value", num)
-										}
-									} else {
-										// Fallback: Try to extract leading number (e.g. "709: Title" -> 709)
-										// This handles cases where the selection includes the title
-										re := regexp.MustCompile(`^(\d+)`)
-										if match := re.FindStringSubmatch(strVal); len(match) > 1 {
-											if num, err := strconv.ParseFloat(match[1], 64); err == nil {
-												resolvedArgs[key] = num
-												if a.DebugMode {
-													slog.Debug("extracted number from arg", "arg", key, "value", num, "original", strVal)
-												}
-											}
-										}
-									}
-								} else if prop.Type == genai.TypeBoolean {
-									// Try to convert to boolean
-									if b, err := strconv.ParseBool(strVal); err == nil {
-										resolvedArgs[key] = b
-										if a.DebugMode {
-											slog.Debug("converted arg to boolean", "arg", key, "value", b)
-										}
-									}
-								}
-							}
-						}
-					}
-				}
-			} else if schemaMap, ok := decl.ParametersJsonSchema.(map[string]interface{}); ok {
-				// Handle map[string]interface{} schema (common in MCP or other providers)
-
-				if typeVal, ok := schemaMap["type"].(string); ok && typeVal == "object" {
-					if props, ok := schemaMap["properties"].(map[string]interface{}); ok {
-						for key, val := range resolvedArgs {
-							if strVal, ok := val.(string); ok {
-								if prop, ok := props[key].(map[string]interface{}); ok {
-									propType, _ := prop["type"].(string)
-
-									if propType == "number" || propType == "integer" {
-										// Try to convert string to float64
-										if num, err := strconv.ParseFloat(strVal, 64); err == nil {
-											resolvedArgs[key] = num
-										} else {
-											// Fallback: Try to extract leading number (e.g. "709: Title" -> 709)
-											re := regexp.MustCompile(`^(\d+)`)
-											if match := re.FindStringSubmatch(strVal); len(match) > 1 {
-												if num, err := strconv.ParseFloat(match[1], 64); err == nil {
-													resolvedArgs[key] = num
-												}
-											}
-										}
-									} else if propType == "boolean" {
-										// Try to convert string to boolean
-										if b, err := strconv.ParseBool(strVal); err == nil {
-											resolvedArgs[key] = b
-										}
-									}
-								}
-							}
-							// Also handle int to float64 if needed
-							if intVal, ok := val.(int); ok {
-								// Check if schema expects number/integer
-								if prop, ok := props[key].(map[string]interface{}); ok {
-									propType, _ := prop["type"].(string)
-									if propType == "number" {
-										resolvedArgs[key] = float64(intVal)
-									}
-								}
-							}
-						}
-					}
-				}
-			} else {
-				if a.DebugMode {
-					slog.Debug("parameters json schema is not *genai.Schema or map[string]interface{}")
-				}
-			}
-		} else {
-			if a.DebugMode {
-				slog.Debug("declaration or parameters json schema is nil")
-			}
-		}
-	} else {
-		if a.DebugMode {
-			slog.Debug("tool does not implement ToolWithDeclaration", "tool", toolName)
-		}
-	}
+	a.convertToolArgs(selectedTool, toolName, resolvedArgs)
 
 	// Execute using RunnableTool interface
 	// Create tool context with session ID for sandbox routing
@@ -260,109 +283,51 @@ func (a *AstonishAgent) handleToolNode(ctx context.Context, node *config.Node, s
 		state:     state,
 		sessionID: sessID,
 	}
+	if arts := runArtifacts(ctx); arts != nil {
+		toolCtx.artifacts = &trackedArtifacts{Artifacts: arts, actions: toolCtx.actions}
+	}
 
 	runnable, ok := selectedTool.(RunnableTool)
 	if !ok {
 		yield(nil, fmt.Errorf("tool '%s' does not implement Run method", toolName))
-		return false
+		return false, nil
 	}
 
-	// Resolve {{CREDENTIAL:name:field}} placeholders in tool args.
-	// Tool-type nodes bypass ADK's tool lifecycle (no BeforeToolCallback),
-	// so we must substitute credentials here before execution.
-	var resolver credentials.CredentialResolver
-	if cs := store.CredentialStoreFromContext(ctx); cs != nil {
-		resolver = credentials.NewStoreAdapter(cs)
-	} else if a.CredentialStore != nil {
-		resolver = a.CredentialStore
-	}
-	if resolver != nil {
-		var shellFields []string
-		if toolName == "shell_command" || toolName == "process_write" {
-			shellFields = []string{"command"}
+	var toolResult map[string]any
+	if cached {
+		slog.Debug("tool result served from cache", "node", node.Name, "tool", toolName)
+		toolResult = cachedResult
+	} else {
+		var record audit.Record
+		auditLog := a.auditLog()
+		if auditLog != nil {
+			record = a.auditRecord(ctx, node, toolName, resolvedArgs)
 		}
-		credentials.SubstituteAndRestore(resolvedArgs, resolver, shellFields...)
-		// Note: we don't restore — tool nodes don't need to redact args
-		// back into session history (they emit structured results, not raw args).
-	}
+		a.substituteToolArgSecrets(ctx, toolName, resolvedArgs)
 
-	// Unresolved credential placeholders are left as literal text — this
-	// handles documentation/code that describes the placeholder format.
-	if unresolved := credentials.UnresolvedCredentialNames(resolvedArgs); len(unresolved) > 0 {
-		slog.Debug("credential placeholders remain unresolved in flow tool (treating as literal text)",
-			"component", "credentials", "tool", toolName, "unresolved", unresolved)
-	}
-
-	// Resolve <<<SECRET_N>>> tokens (pending secrets from interactive capture).
-	if a.PendingSecrets != nil {
-		a.PendingSecrets.SubstituteAndRestore(resolvedArgs)
+		toolResult, err = runnable.Run(toolCtx, resolvedArgs)
+		if err == nil && node.RetryOnEmpty != nil && toolResultIsEmpty(node, toolResult) {
+			toolResult, err = a.retryEmptyToolResult(ctx, toolCtx, node, runnable, toolName, resolvedArgs, toolResult, yield)
+		}
+		if auditLog != nil {
+			// One record per node execution, retries of empty results included
+			appendAudit(auditLog, record, toolResult, err)
+		}
+		if err == nil && cacheKey != "" && toolResult["error"] == nil {
+			storeToolResult(a.Redactor, node.Name, cacheKey, toolName, toolResult)
+		}
 	}
-
-	toolResult, err := runnable.Run(toolCtx, resolvedArgs)
 	if err != nil {
-		if node.ContinueOnError {
-			// Capture error as result instead of failing
-			if a.DebugMode {
-				slog.Debug("tool execution failed, continuing", "error", err)
-			}
-			toolResult = map[string]any{
-				"error":   err.Error(),
-				"success": false,
-			}
-		} else {
-			// Use LLM-based error recovery (same as LLM nodes)
-			errCtx := ErrorContext{
-				NodeName:     node.Name,
-				NodeType:     "tool",
-				ErrorType:    "tool_execution_error",
-				ErrorMessage: err.Error(),
-				AttemptCount: 1,
-				MaxRetries:   1, // Tool nodes don't retry by default
-				ToolName:     toolName,
-				ToolArgs:     resolvedArgs,
-			}
-
-			// Use ErrorRecoveryNode to get intelligent analysis
-			recovery := NewErrorRecoveryNode(a.LLM, a.DebugMode)
-			decision, recoveryErr := recovery.Decide(ctx, errCtx)
-
-			var title, reason, suggestion string
-			if recoveryErr != nil {
-				// LLM analysis failed, use basic error info
-				title = "Tool Execution Failed"
-				reason = fmt.Sprintf("Tool '%s' failed to execute", toolName)
-				suggestion = ""
-			} else {
-				title = decision.Title
-				reason = decision.Reason
-				suggestion = decision.Suggestion
-			}
-
-			// Emit failure info with LLM analysis
-			yield(&session.Event{
-				Actions: session.EventActions{
-					StateDelta: map[string]any{
-						"_failure_info": map[string]any{
-							"title":          title,
-							"reason":         reason,
-							"suggestion":     suggestion,
-							"original_error": err.Error(),
-							"node":           node.Name,
-							"tool":           toolName,
-						},
-						"_processing_info": true,
-					},
-				},
-			}, nil)
-
-			// Store error details in state for error handler nodes
-			state.Set("_last_error", err.Error())
-			state.Set("_error_node", node.Name)
-			state.Set("_has_error", true)
-			state.Set("_error_analysis", reason)
-
-			// Return false to end the node gracefully (flow will transition to next node or END)
-			return false
+		if !captureErrors {
+			return false, err
+		}
+		// Capture error as result instead of failing
+		if a.DebugMode {
+			slog.Debug("tool execution failed, continuing", "error", err)
+		}
+		toolResult = map[string]any{
+			"error":   err.Error(),
+			"success": false,
 		}
 	} else if node.ContinueOnError {
 		// Add success indicator when continue_on_error is enabled
@@ -412,7 +377,7 @@ func (a *AstonishAgent) handleToolNode(ctx context.Context, node *config.Node, s
 		found := false
 
 		// Priority list of keys to check
-		keysToCheck := []string{"stdout", "output", "content", "formatted_diff", "result"}
+		keysToCheck := outputResultKeys
 
 		// 1. Check explicit mapping first (if any) - though output_model doesn't define mapping usually
 
@@ -433,12 +398,13 @@ func (a *AstonishAgent) handleToolNode(ctx context.Context, node *config.Node, s
 		}
 
 		if found {
-			if typeName == "list" {
+			if outType := parseOutputType(typeName); outType.IsList() {
 				// Check if val is already a slice
 				switch v := val.(type) {
 				case []interface{}:
-					stateDelta[key] = v
-					state.Set(key, v)
+					list := coerceOutputValue(outType, v)
+					stateDelta[key] = list
+					state.Set(key, list)
 					continue
 				case []string:
 					// Convert to []interface{}
@@ -455,8 +421,9 @@ func (a *AstonishAgent) handleToolNode(ctx context.Context, node *config.Node, s
 				valStr := fmt.Sprintf("%v", val)
 				var list []any
 				if err := json.Unmarshal([]byte(valStr), &list); err == nil {
-					stateDelta[key] = list
-					state.Set(key, list)
+					typed := coerceOutputValue(outType, list)
+					stateDelta[key] = typed
+					state.Set(key, typed)
 				} else {
 					// Fallback: Split by newline
 					lines := strings.Split(strings.TrimSpace(valStr), "\n")
@@ -494,16 +461,232 @@ func (a *AstonishAgent) handleToolNode(ctx context.Context, node *config.Node, s
 	// This is critical for circular flows where the same tool node is executed multiple
 	// times with different parameters (e.g., paginated API calls)
 	if !node.ToolsAutoApproval {
-		approvalKey := fmt.Sprintf("approval:%s:%s", node.Name, toolName)
-		state.Set(approvalKey, false)
+		revokeApproval(state, node.Name, toolName)
 	}
 
+	// References to the artifacts the tool saved
+	recordArtifacts(ctx, state, toolCtx.actions.ArtifactDelta, stateDelta)
+
 	// Yield result event
 	yield(&session.Event{
 		Actions: session.EventActions{
-			StateDelta: stateDelta,
+			StateDelta:    stateDelta,
+			ArtifactDelta: toolCtx.actions.ArtifactDelta,
 		},
 	}, nil)
 
-	return true
+	if node.DisplayMode() == config.DisplaySummary {
+		a.emitDisplaySummary(node, state, yield)
+	}
+
+	return true, nil
+}
+
+// resolveToolArgs renders a tool node's args against state. String values are
+// templates; single-key maps (e.g. owner: {owner: str}) read the named state key.
+func (a *AstonishAgent) resolveToolArgs(node *config.Node, state session.State) map[string]interface{} {
+	resolvedArgs := make(map[string]interface{})
+	for key, val := range node.Args {
+		if strVal, ok := val.(string); ok {
+			resolvedArgs[key] = a.renderNodeString(node, strVal, state)
+		} else if mapVal, ok := val.(map[string]interface{}); ok && len(mapVal) == 1 {
+			// Handle map arguments (e.g. owner: {owner: str}) -> resolve from state
+			var stateKey string
+			for k := range mapVal {
+				stateKey = k
+				break
+			}
+
+			if stateVal, err := state.Get(stateKey); err == nil {
+				resolvedArgs[key] = stateVal
+			} else {
+				if a.DebugMode {
+					slog.Warn("state key not found", "stateKey", stateKey, "arg", key)
+				}
+				resolvedArgs[key] = nil
+			}
+		} else {
+			resolvedArgs[key] = val
+		}
+	}
+	return resolvedArgs
+}
+
+// findTool looks up a tool by name in the internal tools, then the toolsets
+// (MCP). It fails only when the MCP servers of the node's env cannot start.
+func (a *AstonishAgent) findTool(ctx context.Context, toolName string) (tool.Tool, error) {
+	// Find the tool in a.Tools
+	var selectedTool tool.Tool
+	for _, t := range a.Tools {
+		if t.Name() == toolName {
+			selectedTool = t
+			break
+		}
+	}
+
+	// If not found in internal tools, check Toolsets (MCP)
+	if selectedTool == nil {
+		toolsets, err := a.nodeToolsets(ctx)
+		if err != nil {
+			return nil, err
+		}
+		roCtx := &minimalReadonlyContext{Context: ctx}
+		for _, ts := range toolsets {
+			tools, err := ts.Tools(roCtx)
+			if err == nil {
+				for _, t := range tools {
+					if t.Name() == toolName {
+						selectedTool = t
+						break
+					}
+				}
+			}
+			if selectedTool != nil {
+				break
+			}
+		}
+	}
+	return selectedTool, nil
+}
+
+// convertToolArgs converts string args in place to the number/boolean types
+// declared by the tool's parameter schema.
+func (a *AstonishAgent) convertToolArgs(selectedTool tool.Tool, toolName string, resolvedArgs map[string]interface{}) {
+	if declTool, ok := selectedTool.(ToolWithDeclaration); ok {
+		if a.DebugMode {
+			slog.Debug("tool implements ToolWithDeclaration", "tool", toolName)
+		}
+		decl := declTool.Declaration()
+		if decl != nil && decl.ParametersJsonSchema != nil {
+			if a.DebugMode {
+				slog.Debug("parameters json schema", "type", fmt.Sprintf("%T", decl.ParametersJsonSchema))
+			}
+			if schema, ok := decl.ParametersJsonSchema.(*genai.Schema); ok {
+				if a.DebugMode {
+					slog.Debug("schema type check", "schemaType", schema.Type, "expected", genai.TypeObject)
+				}
+				if schema.Type == genai.TypeObject {
+					for key, val := range resolvedArgs {
+						if strVal, ok := val.(string); ok {
+							if prop, ok := schema.Properties[key]; ok {
+								if prop.Type == genai.TypeNumber || prop.Type == genai.TypeInteger {
+									if num, err := strconv.ParseFloat(strVal, 64); err == nil {
+										resolvedArgs[key] = num
+										if a.DebugMode {
+											slog.Debug("converted arg to number", "arg", key, "value", num)
+										}
+									} else {
+										// Fallback: Try to extract leading number (e.g. "709: Title" -> 709)
+										// This handles cases where the selection includes the title
+										re := regexp.MustCompile(`^(\d+)`)
+										if match := re.FindStringSubmatch(strVal); len(match) > 1 {
+											if num, err := strconv.ParseFloat(match[1], 64); err == nil {
+												resolvedArgs[key] = num
+												if a.DebugMode {
+													slog.Debug("extracted number from arg", "arg", key, "value", num, "original", strVal)
+												}
+											}
+										}
+									}
+								} else if prop.Type == genai.TypeBoolean {
+									// Try to convert to boolean
+									if b, err := strconv.ParseBool(strVal); err == nil {
+										resolvedArgs[key] = b
+										if a.DebugMode {
+											slog.Debug("converted arg to boolean", "arg", key, "value", b)
+										}
+									}
+								}
+							}
+						}
+					}
+				}
+			} else if schemaMap, ok := decl.ParametersJsonSchema.(map[string]interface{}); ok {
+				// Handle map[string]interface{} schema (common in MCP or other providers)
+
+				if typeVal, ok := schemaMap["type"].(string); ok && typeVal == "object" {
+					if props, ok := schemaMap["properties"].(map[string]interface{}); ok {
+						for key, val := range resolvedArgs {
+							if strVal, ok := val.(string); ok {
+								if prop, ok := props[key].(map[string]interface{}); ok {
+									propType, _ := prop["type"].(string)
+
+									if propType == "number" || propType == "integer" {
+										// Try to convert string to float64
+										if num, err := strconv.ParseFloat(strVal, 64); err == nil {
+											resolvedArgs[key] = num
+										} else {
+											// Fallback: Try to extract leading number (e.g. "709: Title" -> 709)
+											re := regexp.MustCompile(`^(\d+)`)
+											if match := re.FindStringSubmatch(strVal); len(match) > 1 {
+												if num, err := strconv.ParseFloat(match[1], 64); err == nil {
+													resolvedArgs[key] = num
+												}
+											}
+										}
+									} else if propType == "boolean" {
+										// Try to convert string to boolean
+										if b, err := strconv.ParseBool(strVal); err == nil {
+											resolvedArgs[key] = b
+										}
+									}
+								}
+							}
+							// Also handle int to float64 if needed
+							if intVal, ok := val.(int); ok {
+								// Check if schema expects number/integer
+								if prop, ok := props[key].(map[string]interface{}); ok {
+									propType, _ := prop["type"].(string)
+									if propType == "number" {
+										resolvedArgs[key] = float64(intVal)
+									}
+								}
+							}
+						}
+					}
+				}
+			} else {
+				if a.DebugMode {
+					slog.Debug("parameters json schema is not *genai.Schema or map[string]interface{}")
+				}
+			}
+		} else {
+			if a.DebugMode {
+				slog.Debug("declaration or parameters json schema is nil")
+			}
+		}
+	} else {
+		if a.DebugMode {
+			slog.Debug("tool does not implement ToolWithDeclaration", "tool", toolName)
+		}
+	}
+}
+
+// substituteToolArgSecrets resolves {{CREDENTIAL:name:field}} placeholders and
+// <<<SECRET_N>>> tokens in tool args in place.
+func (a *AstonishAgent) substituteToolArgSecrets(ctx context.Context, toolName string, resolvedArgs map[string]interface{}) {
+	// Resolve {{CREDENTIAL:name:field}} placeholders in tool args.
+	// Tool-type nodes bypass ADK's tool lifecycle (no BeforeToolCallback),
+	// so we must substitute credentials here before execution.
+	if resolver := a.credentialResolver(ctx); resolver != nil {
+		var shellFields []string
+		if toolName == "shell_command" || toolName == "process_write" {
+			shellFields = []string{"command"}
+		}
+		credentials.SubstituteAndRestore(resolvedArgs, resolver, shellFields...)
+		// Note: we don't restore — tool nodes don't need to redact args
+		// back into session history (they emit structured results, not raw args).
+	}
+
+	// Unresolved credential placeholders are left as literal text — this
+	// handles documentation/code that describes the placeholder format.
+	if unresolved := credentials.UnresolvedCredentialNames(resolvedArgs); len(unresolved) > 0 {
+		slog.Debug("credential placeholders remain unresolved in flow tool (treating as literal text)",
+			"component", "credentials", "tool", toolName, "unresolved", unresolved)
+	}
+
+	// Resolve <<<SECRET_N>>> tokens (pending secrets from interactive capture).
+	if a.PendingSecrets != nil {
+		a.PendingSecrets.SubstituteAndRestore(resolvedArgs)
+	}
 }
@@ -101,3 +101,54 @@ func TestHandleToolNode_NoAutoApproveRequestsApproval(t *testing.T) {
 		t.Fatal("expected tool NOT to run before approval")
 	}
 }
+
+func TestHandleToolNode_StaleApprovalRePrompts(t *testing.T) {
+	state := NewMockState()
+	var ran []string
+	mockTool := &MockTool{
+		NameFunc: func() string { return "shell_command" },
+		RunFunc: func(ctx tool.Context, args any) (map[string]any, error) {
+			cmd, _ := args.(map[string]any)["command"].(string)
+			ran = append(ran, cmd)
+			return map[string]any{"stdout": "ok"}, nil
+		},
+	}
+	a := &AstonishAgent{Tools: []tool.Tool{mockTool}}
+	node := &config.Node{
+		Name:           "run",
+		Type:           "tool",
+		ToolsSelection: []string{"shell_command"},
+		Args:           map[string]interface{}{"command": "echo {target}"},
+	}
+	run := func() (ok, prompted bool) {
+		ok = a.handleToolNode(context.Background(), node, state, func(ev *session.Event, err error) bool {
+			if ev != nil && ev.Actions.StateDelta["awaiting_approval"] == true {
+				prompted = true
+			}
+			return true
+		})
+		return ok, prompted
+	}
+
+	// User approves "echo a"
+	state.Set("target", "a")
+	if ok, prompted := run(); ok || !prompted {
+		t.Fatalf("first run: ok = %v, prompted = %v; want pause for approval", ok, prompted)
+	}
+	grantApproval(state, "run", "shell_command")
+
+	// Args changed before the approved call ran: must prompt again
+	state.Set("target", "b")
+	if ok, prompted := run(); ok || !prompted {
+		t.Fatalf("changed args: ok = %v, prompted = %v; want re-prompt", ok, prompted)
+	}
+	if len(ran) != 0 {
+		t.Fatalf("tool ran with unapproved args: %v", ran)
+	}
+
+	// Approving the new call lets exactly that call through
+	grantApproval(state, "run", "shell_command")
+	if ok, _ := run(); !ok || len(ran) != 1 || ran[0] != "echo b" {
+		t.Fatalf("approved run: ok = %v, ran = %v; want echo b", ok, ran)
+	}
+}
@@ -0,0 +1,190 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/adk/session"
+	"google.golang.org/adk/tool"
+)
+
+const (
+	defaultEmptyRetryAttempts = 2
+	defaultEmptyRetryDelay    = time.Second
+)
+
+// outputResultKeys are the tool result fields output_model values are read
+// from, in priority order.
+var outputResultKeys = []string{"stdout", "output", "content", "formatted_diff", "result"}
+
+// retryEmptyToolResult re-runs a tool whose result was empty according to the
+// node's retry_on_empty config, then falls back to the alternate tools. It
+// returns the first non-empty result, or the last result if all were empty.
+func (a *AstonishAgent) retryEmptyToolResult(ctx context.Context, toolCtx tool.Context, node *config.Node, runnable RunnableTool, toolName string, args map[string]any, result map[string]any, yield func(*session.Event, error) bool) (map[string]any, error) {
+	cfg := node.RetryOnEmpty
+
+	attempts := defaultEmptyRetryAttempts
+	if cfg.Attempts > 0 {
+		attempts = cfg.Attempts
+	}
+	delay := defaultEmptyRetryDelay
+	if cfg.Delay != "" {
+		if d, err := time.ParseDuration(cfg.Delay); err == nil {
+			delay = d
+		} else {
+			slog.Warn("invalid retry_on_empty delay, using default", "node", node.Name, "delay", cfg.Delay, "error", err)
+		}
+	}
+
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if !yieldEmptyRetryInfo(yield, attempt, attempts, fmt.Sprintf("Empty result from %s", toolName)) {
+			return result, context.Canceled
+		}
+		if err := waitRetryDelay(ctx, delay); err != nil {
+			return result, err
+		}
+
+		next, err := runnable.Run(toolCtx, args)
+		if err != nil || !toolResultIsEmpty(node, next) {
+			return next, err
+		}
+		result = next
+	}
+
+	if len(cfg.AlternateTools) == 0 {
+		return result, nil
+	}
+	if !node.ToolsAutoApproval && !a.AutoApprove {
+		slog.Warn("skipping retry_on_empty alternate tools: node tools are not auto-approved", "node", node.Name, "alternates", cfg.AlternateTools)
+		return result, nil
+	}
+
+	for i, altName := range cfg.AlternateTools {
+		altTool, err := a.findTool(ctx, altName)
+		if err != nil {
+			slog.Warn("retry_on_empty alternate tool unavailable", "node", node.Name, "tool", altName, "error", err)
+			continue
+		}
+		if altTool == nil {
+			slog.Warn("retry_on_empty alternate tool not found", "node", node.Name, "tool", altName)
+			continue
+		}
+		altRunnable, ok := altTool.(RunnableTool)
+		if !ok {
+			slog.Warn("retry_on_empty alternate tool is not runnable", "node", node.Name, "tool", altName)
+			continue
+		}
+
+		if !yieldEmptyRetryInfo(yield, i+1, len(cfg.AlternateTools), fmt.Sprintf("Trying alternate tool %s", altName)) {
+			return result, context.Canceled
+		}
+
+		altArgs := make(map[string]any, len(args))
+		for k, v := range args {
+			altArgs[k] = v
+		}
+		a.convertToolArgs(altTool, altName, altArgs)
+
+		next, err := altRunnable.Run(toolCtx, altArgs)
+		if err != nil {
+			slog.Warn("retry_on_empty alternate tool failed", "node", node.Name, "tool", altName, "error", err)
+			continue
+		}
+		if !toolResultIsEmpty(node, next) {
+			return next, nil
+		}
+	}
+
+	return result, nil
+}
+
+// yieldEmptyRetryInfo emits a _retry_info badge for an empty-result retry.
+func yieldEmptyRetryInfo(yield func(*session.Event, error) bool, attempt, maxRetries int, reason string) bool {
+	return yield(&session.Event{
+		Actions: session.EventActions{
+			StateDelta: map[string]any{
+				"_retry_info": map[string]any{
+					"attempt":     attempt,
+					"max_retries": maxRetries,
+					"reason":      reason,
+				},
+				"_processing_info": true,
+			},
+		},
+	}, nil)
+}
+
+// waitRetryDelay sleeps for d or until ctx is done.
+func waitRetryDelay(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// toolResultIsEmpty reports whether a tool result carries nothing the node can
+// store. Only the fields the node reads (raw_tool_output mappings and the
+// output_model source key) are checked; without either, any non-blank string
+// or collection counts as content.
+func toolResultIsEmpty(node *config.Node, result map[string]any) bool {
+	if len(result) == 0 {
+		return true
+	}
+
+	var fields []any
+	for _, mapping := range node.RawToolOutput {
+		fields = append(fields, result[mapping])
+	}
+	if len(node.OutputModel) > 0 {
+		for _, k := range outputResultKeys {
+			if v, ok := result[k]; ok {
+				fields = append(fields, v)
+				break
+			}
+		}
+	}
+	if len(fields) == 0 {
+		for _, v := range result {
+			switch v.(type) {
+			case string, []any, []string, map[string]any:
+				fields = append(fields, v)
+			}
+		}
+	}
+
+	for _, v := range fields {
+		if !isBlankValue(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// isBlankValue reports whether v is nil, a whitespace-only string, or an
+// empty collection.
+func isBlankValue(v any) bool {
+	switch val := v.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(val) == ""
+	case []any:
+		return len(val) == 0
+	case []string:
+		return len(val) == 0
+	case map[string]any:
+		return len(val) == 0
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/provider/llmerror"
+	"google.golang.org/adk/session"
+	"google.golang.org/adk/tool"
+)
+
+func TestToolResultIsEmpty(t *testing.T) {
+	tests := []struct {
+		name   string
+		node   *config.Node
+		result map[string]any
+		want   bool
+	}{
+		{"nil result", &config.Node{}, nil, true},
+		{"blank stdout with exit code", &config.Node{}, map[string]any{"stdout": "  ", "exit_code": 0}, true},
+		{"stdout content", &config.Node{}, map[string]any{"stdout": "ok"}, false},
+		{"output_model reads first known key", &config.Node{OutputModel: map[string]string{"out": "str"}}, map[string]any{"stdout": "", "content": "ignored"}, true},
+		{"raw_tool_output mapping", &config.Node{RawToolOutput: map[string]string{"items": "items"}}, map[string]any{"items": []any{}, "note": "x"}, true},
+		{"raw_tool_output with content", &config.Node{RawToolOutput: map[string]string{"items": "items"}}, map[string]any{"items": []any{1}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := toolResultIsEmpty(tt.node, tt.result); got != tt.want {
+				t.Errorf("toolResultIsEmpty() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleToolNode_RetryOnEmptyFallsBackToAlternate(t *testing.T) {
+	state := NewMockState()
+	primaryCalls := 0
+	primary := &MockTool{
+		NameFunc: func() string { return "flaky_api" },
+		RunFunc: func(ctx tool.Context, args any) (map[string]any, error) {
+			primaryCalls++
+			return map[string]any{"stdout": ""}, nil
+		},
+	}
+	alternate := &MockTool{
+		NameFunc: func() string { return "mirror_api" },
+		RunFunc: func(ctx tool.Context, args any) (map[string]any, error) {
+			return map[string]any{"stdout": "from mirror"}, nil
+		},
+	}
+	a := &AstonishAgent{Tools: []tool.Tool{primary, alternate}}
+	node := &config.Node{
+		Name:              "fetch",
+		Type:              "tool",
+		ToolsSelection:    []string{"flaky_api"},
+		ToolsAutoApproval: true,
+		OutputModel:       map[string]string{"data": "str"},
+		RetryOnEmpty: &config.RetryOnEmptyConfig{
+			Attempts:       2,
+			Delay:          "1ms",
+			AlternateTools: []string{"missing_tool", "mirror_api"},
+		},
+	}
+
+	retries := 0
+	ok := a.handleToolNode(context.Background(), node, state, func(ev *session.Event, err error) bool {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if ev != nil && ev.Actions.StateDelta["_retry_info"] != nil {
+			retries++
+		}
+		return true
+	})
+	if !ok {
+		t.Fatal("expected handleToolNode to succeed")
+	}
+	if primaryCalls != 3 {
+		t.Errorf("primary calls = %d, want 3 (1 + 2 retries)", primaryCalls)
+	}
+	if retries != 3 {
+		t.Errorf("retry events = %d, want 3 (2 retries + 1 alternate)", retries)
+	}
+	if got, _ := state.Get("data"); got != "from mirror" {
+		t.Errorf("data = %v, want %q", got, "from mirror")
+	}
+}
+
+func TestHandleToolNode_RetriesWithReRenderedArgs(t *testing.T) {
+	state := NewMockState()
+	var commands []string
+	mockTool := &MockTool{
+		NameFunc: func() string { return "shell_command" },
+		RunFunc: func(ctx tool.Context, args any) (map[string]any, error) {
+			cmd, _ := args.(map[string]any)["command"].(string)
+			commands = append(commands, cmd)
+			if len(commands) == 1 {
+				return nil, &llmerror.LLMError{StatusCode: 503, Provider: "test", RetryAfter: time.Millisecond}
+			}
+			return map[string]any{"stdout": "ok"}, nil
+		},
+	}
+	a := &AstonishAgent{Tools: []tool.Tool{mockTool}}
+	node := &config.Node{
+		Name:              "run",
+		Type:              "tool",
+		ToolsSelection:    []string{"shell_command"},
+		ToolsAutoApproval: true,
+		MaxRetries:        2,
+		RetryStrategy:     "simple",
+		Args:              map[string]interface{}{"command": "echo attempt {_retry_attempt}"},
+		OutputModel:       map[string]string{"out": "str"},
+	}
+
+	ok := a.handleToolNode(context.Background(), node, state, func(ev *session.Event, err error) bool {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if ev != nil && ev.Actions.StateDelta["_failure_info"] != nil {
+			t.Fatal("did not expect failure info")
+		}
+		return true
+	})
+	if !ok {
+		t.Fatal("expected handleToolNode to succeed on retry")
+	}
+	want := []string{"echo attempt 1", "echo attempt 2"}
+	if len(commands) != 2 || commands[0] != want[0] || commands[1] != want[1] {
+		t.Errorf("commands = %v, want %v", commands, want)
+	}
+}
+
+func TestHandleToolNode_DefaultDoesNotRetry(t *testing.T) {
+	state := NewMockState()
+	calls := 0
+	mockTool := &MockTool{
+		NameFunc: func() string { return "shell_command" },
+		RunFunc: func(ctx tool.Context, args any) (map[string]any, error) {
+			calls++
+			return nil, errors.New("boom")
+		},
+	}
+	a := &AstonishAgent{Tools: []tool.Tool{mockTool}}
+	node := &config.Node{
+		Name:              "run",
+		Type:              "tool",
+		ToolsSelection:    []string{"shell_command"},
+		ToolsAutoApproval: true,
+		RetryStrategy:     "simple",
+	}
+
+	failed := false
+	ok := a.handleToolNode(context.Background(), node, state, func(ev *session.Event, err error) bool {
+		if ev != nil && ev.Actions.StateDelta["_failure_info"] != nil {
+			failed = true
+		}
+		return true
+	})
+	if ok || !failed || calls != 1 {
+		t.Errorf("ok = %v, failure reported = %v, calls = %d; want false, true, 1", ok, failed, calls)
+	}
+	if hasErr, _ := state.Get("_has_error"); hasErr != true {
+		t.Error("expected _has_error to be set")
+	}
+}
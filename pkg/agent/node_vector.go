@@ -0,0 +1,202 @@
+package agent
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/memory"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+)
+
+const (
+	// defaultVectorSearchLimit and defaultVectorMinScore apply when neither
+	// the node nor memory.search sets them.
+	defaultVectorSearchLimit = 6
+	defaultVectorMinScore    = 0.35
+	// defaultVectorOutputKey receives search results when the node declares
+	// no output_model key.
+	defaultVectorOutputKey = "documents"
+)
+
+// vectorText is a text to index with the name it is stored under.
+type vectorText struct {
+	source string
+	text   string
+}
+
+// handleVectorNode indexes text into a vector store collection or searches a
+// collection. Search results are written as a list of {content, source,
+// score} maps, most similar first.
+func (a *AstonishAgent) handleVectorNode(ctx agent.InvocationContext, node *config.Node, state session.State, yield func(*session.Event, error) bool) bool {
+	vc := node.Vector
+	if vc == nil {
+		yield(nil, fmt.Errorf("vector node %s: missing vector configuration", node.Name))
+		return false
+	}
+	retriever, err := a.retriever()
+	if err != nil {
+		yield(nil, fmt.Errorf("vector node %s: %w", node.Name, err))
+		return false
+	}
+	collection := strings.TrimSpace(a.renderNodeString(node, vc.Collection, state))
+	if collection == "" {
+		collection = a.flowName()
+	}
+
+	switch vc.Action {
+	case "index":
+		texts, err := a.vectorTexts(node, state)
+		if err != nil {
+			yield(nil, fmt.Errorf("vector node %s: %w", node.Name, err))
+			return false
+		}
+		var chunks int
+		for _, t := range texts {
+			n, err := retriever.Index(ctx, collection, t.source, t.text)
+			if err != nil {
+				yield(nil, fmt.Errorf("vector node %s: %w", node.Name, err))
+				return false
+			}
+			chunks += n
+		}
+		if a.DebugMode {
+			slog.Debug("vector node indexed texts", "node", node.Name, "collection", collection, "texts", len(texts), "chunks", chunks)
+		}
+		delta := map[string]any{}
+		if len(node.OutputModel) > 0 {
+			key := vectorOutputKey(node)
+			if err := state.Set(key, chunks); err != nil {
+				yield(nil, fmt.Errorf("failed to set state key %s: %w", key, err))
+				return false
+			}
+			delta[key] = chunks
+		}
+		return yield(&session.Event{Actions: session.EventActions{StateDelta: delta}}, nil)
+
+	case "search":
+		query := strings.TrimSpace(a.renderNodeString(node, vc.Query, state))
+		if query == "" {
+			yield(nil, fmt.Errorf("vector node %s: query is empty", node.Name))
+			return false
+		}
+		limit, minScore := a.vectorSearchDefaults()
+		if vc.Limit > 0 {
+			limit = vc.Limit
+		}
+		if vc.MinScore > 0 {
+			minScore = vc.MinScore
+		}
+		docs, err := retriever.Search(ctx, collection, query, limit, minScore)
+		if err != nil {
+			yield(nil, fmt.Errorf("vector node %s: %w", node.Name, err))
+			return false
+		}
+		results := make([]any, 0, len(docs))
+		for _, d := range docs {
+			source, _ := d.Metadata["source"].(string)
+			results = append(results, map[string]any{"content": d.Content, "source": source, "score": d.Score})
+		}
+		key := vectorOutputKey(node)
+		if err := state.Set(key, results); err != nil {
+			yield(nil, fmt.Errorf("failed to set state key %s: %w", key, err))
+			return false
+		}
+		if a.DebugMode {
+			slog.Debug("vector node found documents", "node", node.Name, "collection", collection, "count", len(results))
+		}
+		return yield(&session.Event{Actions: session.EventActions{StateDelta: map[string]any{key: results}}}, nil)
+	}
+
+	yield(nil, fmt.Errorf("vector node %s: unknown action %q (use index or search)", node.Name, vc.Action))
+	return false
+}
+
+// vectorTexts collects the texts an index action stores: the rendered text
+// and the contents of source_variable, which may be a string, a list of
+// strings, or a list of maps with "content" (or "text") and optionally
+// "source".
+func (a *AstonishAgent) vectorTexts(node *config.Node, state session.State) ([]vectorText, error) {
+	vc := node.Vector
+	source := strings.TrimSpace(a.renderNodeString(node, vc.Source, state))
+	if source == "" {
+		source = node.Name
+	}
+
+	var texts []vectorText
+	if vc.Text != "" {
+		if text := a.renderNodeString(node, vc.Text, state); strings.TrimSpace(text) != "" {
+			texts = append(texts, vectorText{source: source, text: text})
+		}
+	}
+	if vc.SourceVariable != "" {
+		value, err := state.Get(vc.SourceVariable)
+		if err != nil {
+			return nil, fmt.Errorf("source_variable %s is not set", vc.SourceVariable)
+		}
+		switch v := value.(type) {
+		case string:
+			texts = append(texts, vectorText{source: source, text: v})
+		case []any:
+			for i, item := range v {
+				t := vectorText{source: fmt.Sprintf("%s[%d]", source, i)}
+				switch item := item.(type) {
+				case string:
+					t.text = item
+				case map[string]any:
+					if t.text, _ = item["content"].(string); t.text == "" {
+						t.text, _ = item["text"].(string)
+					}
+					if s, _ := item["source"].(string); s != "" {
+						t.source = s
+					}
+				}
+				if strings.TrimSpace(t.text) == "" {
+					return nil, fmt.Errorf("item %d of %s has no text", i, vc.SourceVariable)
+				}
+				texts = append(texts, t)
+			}
+		default:
+			return nil, fmt.Errorf("source_variable %s must be a string or a list, got %T", vc.SourceVariable, value)
+		}
+	}
+	if len(texts) == 0 {
+		return nil, fmt.Errorf("nothing to index: text and source_variable are empty")
+	}
+	return texts, nil
+}
+
+// retriever returns the agent's retriever, or else the one configured in
+// AppConfig.
+func (a *AstonishAgent) retriever() (*memory.Retriever, error) {
+	if a.Retriever != nil {
+		return a.Retriever, nil
+	}
+	return memory.FlowRetriever(a.AppConfig, a.DebugMode)
+}
+
+// vectorSearchDefaults returns the search limit and minimum score of
+// memory.search, or the built-in defaults.
+func (a *AstonishAgent) vectorSearchDefaults() (int, float64) {
+	limit, minScore := defaultVectorSearchLimit, defaultVectorMinScore
+	if a.AppConfig != nil {
+		if s := a.AppConfig.Memory.Search; s.MaxResults > 0 {
+			limit = s.MaxResults
+		}
+		if s := a.AppConfig.Memory.Search; s.MinScore > 0 {
+			minScore = s.MinScore
+		}
+	}
+	return limit, minScore
+}
+
+// vectorOutputKey is the state key a vector node writes to: its single
+// output_model key, or "documents".
+func vectorOutputKey(node *config.Node) string {
+	for key := range node.OutputModel {
+		return key
+	}
+	return defaultVectorOutputKey
+}
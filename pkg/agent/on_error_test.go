@@ -0,0 +1,60 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/config"
+)
+
+func TestOnErrorRoutesToHandler(t *testing.T) {
+	cfg := &config.AgentConfig{
+		OnError: "report",
+		Nodes: []config.Node{
+			{Name: "compute", Type: "script", Script: `fail("no data")`, OnError: "fallback"},
+			{Name: "fallback", Type: "script", Script: `x["answer"] = 42`},
+			{Name: "broken", Type: "script", Script: `fail("still broken")`},
+			{Name: "report", Type: "output", UserMessage: []string{"_last_error"}},
+		},
+		Flow: []config.FlowItem{
+			{From: "START", To: "compute"},
+			{From: "compute", To: "END"},
+			{From: "fallback", To: "broken"},
+			{From: "broken", To: "END"},
+			{From: "report", To: "END"},
+		},
+	}
+	a := &AstonishAgent{Config: cfg}
+	state := NewMockState()
+	ctx := &MockInvocationContext{Context: context.Background(), StateVal: state}
+
+	var nodes []string
+	for ev, err := range a.Run(ctx) {
+		if err != nil {
+			t.Fatalf("failures with a handler should not end the run: %v", err)
+		}
+		if node, ok := ev.Actions.StateDelta["current_node"].(string); ok && ev.Actions.StateDelta["node_type"] != nil {
+			nodes = append(nodes, node)
+		}
+	}
+
+	// compute uses its own handler, broken the flow's
+	if got := strings.Join(nodes, " "); got != "compute fallback broken report END" {
+		t.Errorf("visited %q", got)
+	}
+	if state.Data["answer"] != 42 {
+		t.Errorf("answer = %v", state.Data["answer"])
+	}
+	if node := state.Data["_error_node"]; node != "broken" {
+		t.Errorf("_error_node = %v, want broken", node)
+	}
+	if msg, _ := state.Data["_last_error"].(string); !strings.Contains(msg, "still broken") {
+		t.Errorf("_last_error = %q", msg)
+	}
+
+	// The flow-level handler does not handle its own failures
+	if got := cfg.ErrorHandlerFor(&cfg.Nodes[3]); got != "" {
+		t.Errorf("handler of the handler = %q", got)
+	}
+}
@@ -0,0 +1,150 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/google/jsonschema-go/jsonschema"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// resolveOutputSchema compiles a node's output_schema for validation.
+func resolveOutputSchema(node *config.Node) (*jsonschema.Resolved, error) {
+	data, err := json.Marshal(node.OutputSchema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output_schema on node '%s': %w", node.Name, err)
+	}
+	var schema jsonschema.Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("invalid output_schema on node '%s': %w", node.Name, err)
+	}
+	resolved, err := schema.Resolve(nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output_schema on node '%s': %w", node.Name, err)
+	}
+	return resolved, nil
+}
+
+// genaiSchemaFromJSON converts a JSON Schema (as decoded from YAML) to the
+// genai schema passed to the model for native structured output. Keywords
+// genai has no equivalent for are dropped; validation still enforces them.
+func genaiSchemaFromJSON(m map[string]any) *genai.Schema {
+	s := &genai.Schema{}
+	switch t := m["type"].(type) {
+	case string:
+		s.Type = genaiType(t)
+	case []any:
+		// ["string", "null"] style unions: first non-null type, nullable
+		for _, v := range t {
+			if name, _ := v.(string); name == "null" {
+				s.Nullable = genai.Ptr(true)
+			} else if s.Type == "" {
+				s.Type = genaiType(name)
+			}
+		}
+	}
+	if desc, ok := m["description"].(string); ok {
+		s.Description = desc
+	}
+	if enum, ok := m["enum"].([]any); ok {
+		for _, v := range enum {
+			s.Enum = append(s.Enum, fmt.Sprint(v))
+		}
+	}
+	if props, ok := m["properties"].(map[string]any); ok {
+		s.Properties = make(map[string]*genai.Schema, len(props))
+		for key, p := range props {
+			if pm, ok := p.(map[string]any); ok {
+				s.Properties[key] = genaiSchemaFromJSON(pm)
+			}
+		}
+		if s.Type == "" {
+			s.Type = genai.TypeObject
+		}
+	}
+	if req, ok := m["required"].([]any); ok {
+		for _, v := range req {
+			if key, ok := v.(string); ok {
+				s.Required = append(s.Required, key)
+			}
+		}
+	}
+	if items, ok := m["items"].(map[string]any); ok {
+		s.Items = genaiSchemaFromJSON(items)
+	}
+	if s.Type == genai.TypeArray && s.Items == nil {
+		s.Items = &genai.Schema{Type: genai.TypeString}
+	}
+	return s
+}
+
+func genaiType(name string) genai.Type {
+	switch name {
+	case "object":
+		return genai.TypeObject
+	case "array":
+		return genai.TypeArray
+	case "integer":
+		return genai.TypeInteger
+	case "number":
+		return genai.TypeNumber
+	case "boolean":
+		return genai.TypeBoolean
+	default:
+		return genai.TypeString
+	}
+}
+
+// outputSchemaInstruction tells the model to answer with JSON matching the
+// node's output_schema.
+func outputSchemaInstruction(node *config.Node) string {
+	data, _ := json.MarshalIndent(node.OutputSchema, "", "  ")
+	return "\n\nIMPORTANT: Your response MUST be a valid JSON object matching this JSON Schema:\n" +
+		string(data) + "\n" +
+		"Do not include any other text, explanations, or markdown formatting. Return ONLY the JSON object."
+}
+
+// repairStructuredOutput asks the model once to fix output that failed to
+// parse or validate against the node's output_schema, and returns the
+// repaired object if it now validates.
+func (a *AstonishAgent) repairStructuredOutput(ctx context.Context, node *config.Node, llm model.LLM, schema *jsonschema.Resolved, response string, problem error) (map[string]any, error) {
+	schemaJSON, _ := json.MarshalIndent(node.OutputSchema, "", "  ")
+	prompt := fmt.Sprintf("Your previous response did not match the required JSON Schema.\n\n"+
+		"Problem: %v\n\nJSON Schema:\n%s\n\nPrevious response:\n%s\n\n"+
+		"Return ONLY the corrected JSON object, keeping the original content where it is valid.",
+		problem, schemaJSON, response)
+
+	req := &model.LLMRequest{
+		Model:    llm.Name(),
+		Contents: []*genai.Content{genai.NewContentFromText(prompt, genai.RoleUser)},
+		Config:   &genai.GenerateContentConfig{ResponseMIMEType: "application/json"},
+	}
+
+	var text strings.Builder
+	for resp, err := range llm.GenerateContent(ctx, req, false) {
+		if err != nil {
+			return nil, fmt.Errorf("output repair failed: %w", err)
+		}
+		if resp.Content == nil {
+			continue
+		}
+		for _, part := range resp.Content.Parts {
+			if !part.Thought {
+				text.WriteString(part.Text)
+			}
+		}
+	}
+
+	var repaired map[string]any
+	if err := json.Unmarshal([]byte(a.cleanAndFixJson(text.String())), &repaired); err != nil {
+		return nil, fmt.Errorf("repaired output is not valid JSON: %w", err)
+	}
+	if err := schema.Validate(repaired); err != nil {
+		return nil, fmt.Errorf("repaired output does not match output_schema: %w", err)
+	}
+	return repaired, nil
+}
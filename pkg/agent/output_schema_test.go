@@ -0,0 +1,84 @@
+package agent
+
+import (
+	"context"
+	"iter"
+	"strings"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+const invoiceFlow = `
+description: invoice
+nodes:
+  - name: extract
+    type: llm
+    prompt: "extract"
+    output_schema:
+      type: object
+      required: [invoice]
+      properties:
+        invoice:
+          type: object
+          required: [number, lines]
+          properties:
+            number: {type: string}
+            lines:
+              type: array
+              items:
+                type: object
+                properties:
+                  amount: {type: number}
+flow:
+  - from: START
+    to: extract
+`
+
+func TestOutputSchema_NestedSchemaAndRepair(t *testing.T) {
+	cfg, err := config.LoadAgentFromBytes([]byte(invoiceFlow))
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := &cfg.Nodes[0]
+	if node.OutputModel["invoice"] != "dict" {
+		t.Fatalf("output_model = %v, want invoice derived from output_schema", node.OutputModel)
+	}
+
+	gs := genaiSchemaFromJSON(node.OutputSchema)
+	lines := gs.Properties["invoice"].Properties["lines"]
+	if lines.Type != genai.TypeArray || lines.Items.Properties["amount"].Type != genai.TypeNumber {
+		t.Fatalf("nested schema not converted: %+v", lines)
+	}
+
+	schema, err := resolveOutputSchema(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	invalid := map[string]any{"invoice": map[string]any{"number": "A-1"}}
+	problem := schema.Validate(invalid)
+	if problem == nil {
+		t.Fatal("expected missing lines to fail validation")
+	}
+
+	var prompt string
+	llm := &MockLLM{GenerateContentFunc: func(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+		prompt = req.Contents[0].Parts[0].Text
+		return func(yield func(*model.LLMResponse, error) bool) {
+			yield(&model.LLMResponse{Content: genai.NewContentFromText(`{"invoice": {"number": "A-1", "lines": [{"amount": 12.5}]}}`, "model")}, nil)
+		}
+	}}
+	a := NewAstonishAgent(cfg, llm, nil)
+	repaired, err := a.repairStructuredOutput(context.Background(), node, llm, schema, `{"invoice": {"number": "A-1"}}`, problem)
+	if err != nil {
+		t.Fatalf("repair failed: %v", err)
+	}
+	if _, ok := repaired["invoice"].(map[string]any)["lines"]; !ok {
+		t.Errorf("repaired = %v, want lines", repaired)
+	}
+	if !strings.Contains(prompt, "Previous response") || !strings.Contains(prompt, `"number": "A-1"`) {
+		t.Errorf("repair prompt should include the previous answer: %q", prompt)
+	}
+}
@@ -0,0 +1,123 @@
+package agent
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/genai"
+)
+
+// outputTypeSchema converts an output_model type to the genai schema sent
+// to the model. Untyped lists keep the historical string items.
+func outputTypeSchema(t *config.OutputType) *genai.Schema {
+	switch t.Kind {
+	case "int":
+		return &genai.Schema{Type: genai.TypeInteger}
+	case "float":
+		return &genai.Schema{Type: genai.TypeNumber}
+	case "bool":
+		return &genai.Schema{Type: genai.TypeBoolean}
+	case "list":
+		items := &genai.Schema{Type: genai.TypeString}
+		if t.Items != nil {
+			items = outputTypeSchema(t.Items)
+		}
+		return &genai.Schema{Type: genai.TypeArray, Items: items}
+	case "dict", "any":
+		s := &genai.Schema{Type: genai.TypeObject}
+		if len(t.Fields) > 0 {
+			s.Properties = make(map[string]*genai.Schema, len(t.Fields))
+			for key, field := range t.Fields {
+				s.Properties[key] = outputTypeSchema(field)
+				s.Required = append(s.Required, key)
+			}
+		}
+		return s
+	default:
+		return &genai.Schema{Type: genai.TypeString}
+	}
+}
+
+// parseOutputType parses an output_model type, falling back to str for
+// types the loader let through but the parser rejects.
+func parseOutputType(typeName string) *config.OutputType {
+	t, err := config.ParseOutputType(typeName)
+	if err != nil {
+		return &config.OutputType{Kind: "str"}
+	}
+	return t
+}
+
+// coerceOutputValue converts a value parsed from the model's JSON to its
+// declared output_model type, so state keeps real types: whole numbers
+// become ints, numeric strings are parsed, and list or object items that
+// the model returned as JSON strings are decoded. Values that cannot be
+// converted are returned unchanged.
+func coerceOutputValue(t *config.OutputType, v any) any {
+	switch t.Kind {
+	case "int":
+		switch n := v.(type) {
+		case float64:
+			if n == math.Trunc(n) {
+				return int(n)
+			}
+		case string:
+			if i, err := strconv.Atoi(strings.TrimSpace(n)); err == nil {
+				return i
+			}
+		}
+	case "float":
+		if s, ok := v.(string); ok {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+				return f
+			}
+		}
+	case "bool":
+		if s, ok := v.(string); ok {
+			if b, err := strconv.ParseBool(strings.TrimSpace(s)); err == nil {
+				return b
+			}
+		}
+	case "list":
+		if s, ok := v.(string); ok {
+			var list []any
+			if err := json.Unmarshal([]byte(s), &list); err != nil {
+				return v
+			}
+			v = list
+		}
+		list, ok := v.([]any)
+		if !ok || t.Items == nil {
+			return v
+		}
+		out := make([]any, len(list))
+		for i, item := range list {
+			out[i] = coerceOutputValue(t.Items, item)
+		}
+		return out
+	case "dict", "any":
+		if s, ok := v.(string); ok && t.Kind == "dict" {
+			var m map[string]any
+			if err := json.Unmarshal([]byte(s), &m); err != nil {
+				return v
+			}
+			v = m
+		}
+		m, ok := v.(map[string]any)
+		if !ok || len(t.Fields) == 0 {
+			return v
+		}
+		out := make(map[string]any, len(m))
+		for key, val := range m {
+			if field, ok := t.Fields[key]; ok {
+				val = coerceOutputValue(field, val)
+			}
+			out[key] = val
+		}
+		return out
+	}
+	return v
+}
@@ -0,0 +1,35 @@
+package agent
+
+import (
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestOutputType_SchemaAndCoercion(t *testing.T) {
+	typ := parseOutputType("list[{severity: str, line: int}]")
+
+	schema := outputTypeSchema(typ)
+	if schema.Type != genai.TypeArray || schema.Items.Type != genai.TypeObject || schema.Items.Properties["line"].Type != genai.TypeInteger {
+		t.Fatalf("schema = %+v, want array of objects with integer line", schema)
+	}
+	if plain := outputTypeSchema(parseOutputType("list")); plain.Items.Type != genai.TypeString {
+		t.Errorf("untyped list items = %v, want string", plain.Items.Type)
+	}
+
+	// Items returned as JSON strings and float line numbers are restored
+	got := coerceOutputValue(typ, []any{`{"severity": "high", "line": 12}`, map[string]any{"severity": "low", "line": float64(3)}})
+	items := got.([]any)
+	first, ok := items[0].(map[string]any)
+	if !ok || first["severity"] != "high" || first["line"] != 12 {
+		t.Errorf("items[0] = %#v", items[0])
+	}
+	if items[1].(map[string]any)["line"] != 3 {
+		t.Errorf("items[1] line = %#v, want int 3", items[1].(map[string]any)["line"])
+	}
+
+	ints := coerceOutputValue(parseOutputType("list[int]"), []any{float64(1), "2"}).([]any)
+	if ints[0] != 1 || ints[1] != 2 {
+		t.Errorf("list[int] = %#v", ints)
+	}
+}
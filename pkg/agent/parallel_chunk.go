@@ -0,0 +1,98 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// chunkParallelItems splits a parallel list into chunks of at most maxItems.
+// A maxItems of 0 (or a list that fits) yields a single chunk.
+func chunkParallelItems(items []any, maxItems int) [][]any {
+	if maxItems <= 0 || len(items) <= maxItems {
+		return [][]any{items}
+	}
+	chunks := make([][]any, 0, (len(items)+maxItems-1)/maxItems)
+	for start := 0; start < len(items); start += maxItems {
+		end := min(start+maxItems, len(items))
+		chunks = append(chunks, items[start:end])
+	}
+	return chunks
+}
+
+// parallelChunkEvent reports chunk progress of a parallel node. stage is
+// "process" when a chunk starts and "reduce" when its results are merged.
+func parallelChunkEvent(nodeName string, chunk, chunks int, stage string) *session.Event {
+	return &session.Event{
+		Actions: session.EventActions{
+			StateDelta: map[string]any{
+				"_parallel_chunk": map[string]any{
+					"node":   nodeName,
+					"chunk":  chunk,
+					"chunks": chunks,
+					"stage":  stage,
+				},
+			},
+		},
+	}
+}
+
+// reduceParallelResults asks the node's model to merge results into one
+// value following the parallel reduce instructions. JSON answers are parsed
+// (unwrapping {outputKey: ...}); anything else is kept as text.
+func (a *AstonishAgent) reduceParallelResults(ctx agent.InvocationContext, node *config.Node, outputKey string, results []any) (any, error) {
+	llm, err := a.nodeLLM(ctx, node)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode results for reduce: %w", err)
+	}
+	prompt := fmt.Sprintf("Merge the following %d results into a single result.\n"+
+		"Respond with the merged result only, as JSON if the results are JSON.\n\nResults:\n%s", len(results), data)
+
+	genCfg := generateContentConfig(node)
+	if genCfg == nil {
+		genCfg = &genai.GenerateContentConfig{}
+	}
+	genCfg.SystemInstruction = genai.NewContentFromText(node.Parallel.Reduce, genai.RoleUser)
+	req := &model.LLMRequest{
+		Model:    llm.Name(),
+		Contents: []*genai.Content{genai.NewContentFromText(prompt, genai.RoleUser)},
+		Config:   genCfg,
+	}
+
+	var text strings.Builder
+	for resp, err := range llm.GenerateContent(ctx, req, false) {
+		if err != nil {
+			return nil, fmt.Errorf("reduce failed for node '%s': %w", node.Name, err)
+		}
+		if resp.Content == nil {
+			continue
+		}
+		for _, part := range resp.Content.Parts {
+			if !part.Thought {
+				text.WriteString(part.Text)
+			}
+		}
+	}
+
+	var parsed any
+	if err := json.Unmarshal([]byte(a.cleanAndFixJson(text.String())), &parsed); err != nil {
+		return strings.TrimSpace(text.String()), nil
+	}
+	if m, ok := parsed.(map[string]any); ok && len(m) == 1 {
+		if v, ok := m[outputKey]; ok {
+			return v, nil
+		}
+	}
+	return parsed, nil
+}
@@ -0,0 +1,55 @@
+package agent
+
+import (
+	"context"
+	"iter"
+	"strings"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+func TestChunkParallelItems(t *testing.T) {
+	items := []any{1, 2, 3, 4, 5}
+	if got := chunkParallelItems(items, 0); len(got) != 1 || len(got[0]) != 5 {
+		t.Errorf("max_items 0: got %v, want one chunk", got)
+	}
+	got := chunkParallelItems(items, 2)
+	if len(got) != 3 || len(got[0]) != 2 || len(got[2]) != 1 || got[2][0] != 5 {
+		t.Errorf("max_items 2: got %v, want [[1 2] [3 4] [5]]", got)
+	}
+}
+
+func TestReduceParallelResults(t *testing.T) {
+	var req *model.LLMRequest
+	llm := &MockLLM{GenerateContentFunc: func(ctx context.Context, r *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+		req = r
+		return func(yield func(*model.LLMResponse, error) bool) {
+			yield(&model.LLMResponse{Content: genai.NewContentFromText("```json\n{\"summary\": [\"a\", \"b\"]}\n```", "model")}, nil)
+		}
+	}}
+	a := NewAstonishAgent(&config.AgentConfig{}, llm, nil)
+	node := &config.Node{
+		Name:     "review",
+		Type:     "llm",
+		Parallel: &config.ParallelConfig{Reduce: "Deduplicate the findings."},
+	}
+	ctx := &MockInvocationContext{Context: context.Background(), StateVal: NewMockState()}
+
+	merged, err := a.reduceParallelResults(ctx, node, "summary", []any{"a", "b", "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, ok := merged.([]any)
+	if !ok || len(list) != 2 {
+		t.Fatalf("merged = %#v, want the unwrapped summary list", merged)
+	}
+	if got := req.Config.SystemInstruction.Parts[0].Text; got != "Deduplicate the findings." {
+		t.Errorf("system instruction = %q, want the reduce instructions", got)
+	}
+	if !strings.Contains(req.Contents[0].Parts[0].Text, "3 results") {
+		t.Errorf("prompt should include the results: %q", req.Contents[0].Parts[0].Text)
+	}
+}
@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParallelItemEvent(t *testing.T) {
+	tests := []struct {
+		name     string
+		ok       bool
+		val      any
+		wantLine string
+		wantItem map[string]any
+	}{
+		{
+			name:     "done with a text result",
+			ok:       true,
+			val:      "first line\nsecond line",
+			wantLine: "[2/5] item 3 done: first line ...",
+			wantItem: map[string]any{"node": "review", "index": 3, "total": 5, "status": "done", "output": "first line ..."},
+		},
+		{
+			name:     "done with a list result",
+			ok:       true,
+			val:      []any{"a", "b"},
+			wantLine: "[2/5] item 3 done: [2 items]",
+			wantItem: map[string]any{"node": "review", "index": 3, "total": 5, "status": "done", "output": "[2 items]"},
+		},
+		{
+			name:     "done with an empty result",
+			ok:       true,
+			val:      "",
+			wantLine: "[2/5] item 3 done",
+			wantItem: map[string]any{"node": "review", "index": 3, "total": 5, "status": "done", "output": ""},
+		},
+		{
+			name:     "failed",
+			ok:       false,
+			val:      "ignored",
+			wantLine: "[2/5] item 3 failed",
+			wantItem: map[string]any{"node": "review", "index": 3, "total": 5, "status": "failed", "output": ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ev, line := parallelItemEvent("review", 2, 5, 3, tt.ok, tt.val)
+			if line != tt.wantLine {
+				t.Errorf("line = %q, want %q", line, tt.wantLine)
+			}
+			if got := ev.Actions.StateDelta["_parallel_item"]; !reflect.DeepEqual(got, tt.wantItem) {
+				t.Errorf("_parallel_item = %v, want %v", got, tt.wantItem)
+			}
+		})
+	}
+}
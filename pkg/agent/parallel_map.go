@@ -0,0 +1,105 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/SAP/astonish/pkg/config"
+)
+
+// parallelEntry is one entry of a map a parallel node iterates over. The
+// branch result keeps the entry's key, so results aggregate into a map.
+type parallelEntry struct {
+	key   string
+	value any
+}
+
+// parallelMapEntries returns the entries of v sorted by key when v is a
+// map, so branches and chunks are stable across runs.
+func parallelMapEntries(v any) ([]any, bool) {
+	var m map[string]any
+	switch v := v.(type) {
+	case map[string]any:
+		m = v
+	case map[string]string:
+		m = make(map[string]any, len(v))
+		for k, val := range v {
+			m[k] = val
+		}
+	case map[any]any:
+		m = make(map[string]any, len(v))
+		for k, val := range v {
+			m[fmt.Sprint(k)] = val
+		}
+	default:
+		return nil, false
+	}
+	entries := make([]any, 0, len(m))
+	for _, k := range slices.Sorted(maps.Keys(m)) {
+		entries = append(entries, parallelEntry{key: k, value: m[k]})
+	}
+	return entries, true
+}
+
+// bindParallelItem sets the variables of the branch that processes item:
+// as for list items; key_as and value_as (or as) for map entries.
+func bindParallelItem(p *config.ParallelConfig, local map[string]any, item any) {
+	entry, ok := item.(parallelEntry)
+	if !ok {
+		local[p.As] = item
+		return
+	}
+	if p.KeyAs != "" {
+		local[p.KeyAs] = entry.key
+	}
+	if p.ValueAs != "" {
+		local[p.ValueAs] = entry.value
+	} else if p.As != "" {
+		local[p.As] = entry.value
+	}
+}
+
+// parallelResultMap aggregates the results of map entries into a map keyed
+// by the entries' keys, on top of the existing value of the output key. A
+// JSON answer holding the output key contributes that key's value.
+func (a *AstonishAgent) parallelResultMap(existing any, results []any, outputKey string) map[string]any {
+	final := make(map[string]any)
+	if m, ok := existing.(map[string]any); ok {
+		maps.Copy(final, m)
+	}
+	for _, res := range results {
+		entry, ok := res.(parallelEntry)
+		if !ok {
+			continue
+		}
+		val := entry.value
+		if s, ok := val.(string); ok {
+			var parsed any
+			if err := json.Unmarshal([]byte(a.cleanAndFixJson(s)), &parsed); err == nil {
+				val = parsed
+				if m, ok := parsed.(map[string]any); ok {
+					if v, ok := m[outputKey]; ok {
+						val = v
+					}
+				}
+			}
+		}
+		final[entry.key] = val
+	}
+	return final
+}
+
+// parallelEntryResults turns the results of map entries into key/value
+// pairs for the reduce prompt.
+func parallelEntryResults(results []any) []any {
+	out := make([]any, len(results))
+	for i, res := range results {
+		if entry, ok := res.(parallelEntry); ok {
+			res = map[string]any{"key": entry.key, "value": entry.value}
+		}
+		out[i] = res
+	}
+	return out
+}
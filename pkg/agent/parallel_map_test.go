@@ -0,0 +1,42 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/config"
+)
+
+func TestParallelMapEntries(t *testing.T) {
+	if _, ok := parallelMapEntries([]any{"a"}); ok {
+		t.Error("a list was taken for a map")
+	}
+	entries, ok := parallelMapEntries(map[string]any{"b.go": "package b", "a.go": "package a"})
+	if !ok || len(entries) != 2 || entries[0].(parallelEntry).key != "a.go" {
+		t.Fatalf("entries = %v, want sorted by key", entries)
+	}
+
+	p := &config.ParallelConfig{As: "file", KeyAs: "path"}
+	local := make(map[string]any)
+	bindParallelItem(p, local, entries[1])
+	if local["path"] != "b.go" || local["file"] != "package b" {
+		t.Errorf("bindings = %v, want the key and the value under as", local)
+	}
+	p.ValueAs = "content"
+	local = make(map[string]any)
+	bindParallelItem(p, local, entries[0])
+	if local["content"] != "package a" || local["file"] != nil {
+		t.Errorf("bindings = %v, want the value under value_as", local)
+	}
+
+	a := &AstonishAgent{}
+	results := []any{
+		parallelEntry{key: "a.go", value: `{"review": "ok"}`},
+		parallelEntry{key: "b.go", value: "needs tests"},
+	}
+	got := a.parallelResultMap(map[string]any{"c.go": "done earlier"}, results, "review")
+	want := map[string]any{"a.go": "ok", "b.go": "needs tests", "c.go": "done earlier"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,192 @@
+package agent
+
+import (
+	"context"
+	"iter"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/provider/llmerror"
+	"google.golang.org/adk/model"
+)
+
+const (
+	// maxRateLimitRetries bounds how many times a single LLM call is retried
+	// after a 429 when adaptive throttling is enabled.
+	maxRateLimitRetries = 5
+	// maxThrottleInterval caps how far adaptive backoff can slow the call rate.
+	maxThrottleInterval = 30 * time.Second
+	// adaptiveStartInterval is the pacing applied after the first 429 when no
+	// explicit rate_limit was configured.
+	adaptiveStartInterval = 500 * time.Millisecond
+)
+
+// parallelThrottle paces calls shared by all branches of a parallel node.
+// Calls are spaced by interval; when adaptive, 429 responses widen the
+// interval and pause every branch, and successes gradually restore it.
+type parallelThrottle struct {
+	mu           sync.Mutex
+	baseInterval time.Duration
+	interval     time.Duration
+	next         time.Time
+	pausedUntil  time.Time
+	adaptive     bool
+}
+
+// newParallelThrottle returns nil when the config requests no throttling.
+func newParallelThrottle(cfg *config.ParallelConfig) *parallelThrottle {
+	if cfg == nil || (cfg.RateLimit <= 0 && !cfg.Adaptive) {
+		return nil
+	}
+	var interval time.Duration
+	if cfg.RateLimit > 0 {
+		interval = time.Duration(float64(time.Second) / cfg.RateLimit)
+	}
+	return &parallelThrottle{
+		baseInterval: interval,
+		interval:     interval,
+		adaptive:     cfg.Adaptive,
+	}
+}
+
+// Wait blocks until the caller may issue its next call or ctx is done.
+func (t *parallelThrottle) Wait(ctx context.Context) error {
+	t.mu.Lock()
+	now := time.Now()
+	start := now
+	if t.next.After(start) {
+		start = t.next
+	}
+	if t.pausedUntil.After(start) {
+		start = t.pausedUntil
+	}
+	t.next = start.Add(t.interval)
+	t.mu.Unlock()
+
+	delay := start.Sub(now)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// backoff records a rate-limit response, pauses all branches and slows the
+// pacing. It returns the pause applied.
+func (t *parallelThrottle) backoff(attempt int, err error) time.Duration {
+	delay := retryBackoff(attempt, err)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if until := time.Now().Add(delay); until.After(t.pausedUntil) {
+		t.pausedUntil = until
+	}
+	if t.interval == 0 {
+		t.interval = adaptiveStartInterval
+	} else {
+		t.interval *= 2
+	}
+	if t.interval > maxThrottleInterval {
+		t.interval = maxThrottleInterval
+	}
+	return delay
+}
+
+// success relaxes adaptive pacing back toward the configured rate.
+func (t *parallelThrottle) success() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.interval <= t.baseInterval {
+		return
+	}
+	t.interval -= (t.interval - t.baseInterval) / 10
+	if t.interval-t.baseInterval < 10*time.Millisecond {
+		t.interval = t.baseInterval
+	}
+}
+
+// currentInterval returns the spacing currently applied between calls.
+func (t *parallelThrottle) currentInterval() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.interval
+}
+
+// throttledLLM wraps a model so every GenerateContent call goes through a
+// shared parallelThrottle. With adaptive throttling, a 429 returned before
+// any response was streamed is retried after backing off.
+type throttledLLM struct {
+	model.LLM
+	throttle *parallelThrottle
+}
+
+func (m *throttledLLM) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		for attempt := 0; ; attempt++ {
+			if err := m.throttle.Wait(ctx); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			emitted := false
+			var lastErr, rateLimitErr error
+			for resp, err := range m.LLM.GenerateContent(ctx, req, stream) {
+				if err != nil && !emitted && m.throttle.adaptive && attempt < maxRateLimitRetries && isRateLimitError(err) {
+					rateLimitErr = err
+					break
+				}
+				if err != nil {
+					lastErr = err
+				} else {
+					emitted = true
+				}
+				if !yield(resp, err) {
+					return
+				}
+			}
+
+			if rateLimitErr == nil {
+				if lastErr == nil {
+					m.throttle.success()
+				}
+				return
+			}
+
+			delay := m.throttle.backoff(attempt, rateLimitErr)
+			slog.Warn("rate limited, backing off",
+				"component", "parallel",
+				"attempt", attempt+1,
+				"delay", delay,
+				"interval", m.throttle.currentInterval(),
+				"error", rateLimitErr)
+		}
+	}
+}
+
+// isRateLimitError reports whether err is a provider rate-limit response.
+// Structured LLMErrors are checked by status; other providers only expose
+// the condition in the error text.
+func isRateLimitError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if llmerror.IsRateLimited(err) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, pattern := range []string{"429", "rate limit", "ratelimit", "too many requests", "resource_exhausted", "resource exhausted"} {
+		if strings.Contains(msg, pattern) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"testing"
+	"time"
+
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/provider/llmerror"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+func TestNewParallelThrottle(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      *config.ParallelConfig
+		wantNil  bool
+		interval time.Duration
+	}{
+		{"nil config", nil, true, 0},
+		{"no limits", &config.ParallelConfig{MaxConcurrency: 4}, true, 0},
+		{"rate limit", &config.ParallelConfig{RateLimit: 4}, false, 250 * time.Millisecond},
+		{"adaptive only", &config.ParallelConfig{Adaptive: true}, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			th := newParallelThrottle(tt.cfg)
+			if (th == nil) != tt.wantNil {
+				t.Fatalf("newParallelThrottle() nil = %v, want %v", th == nil, tt.wantNil)
+			}
+			if th != nil && th.interval != tt.interval {
+				t.Errorf("interval = %v, want %v", th.interval, tt.interval)
+			}
+		})
+	}
+}
+
+func TestIsRateLimitError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"structured 429", llmerror.NewLLMError("openai", 429, "", ""), true},
+		{"structured 500", llmerror.NewLLMError("openai", 500, "", ""), false},
+		{"gemini text", errors.New("Error 429, RESOURCE_EXHAUSTED"), true},
+		{"generic text", errors.New("Too Many Requests"), true},
+		{"unrelated", errors.New("connection refused"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRateLimitError(tt.err); got != tt.want {
+				t.Errorf("isRateLimitError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThrottledLLMRetriesRateLimit(t *testing.T) {
+	calls := 0
+	inner := &MockLLM{
+		GenerateContentFunc: func(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+			return func(yield func(*model.LLMResponse, error) bool) {
+				calls++
+				if calls < 3 {
+					yield(nil, &llmerror.LLMError{StatusCode: 429, Provider: "test", RetryAfter: time.Millisecond})
+					return
+				}
+				yield(&model.LLMResponse{Content: genai.NewContentFromText("ok", "model")}, nil)
+			}
+		},
+	}
+
+	th := newParallelThrottle(&config.ParallelConfig{Adaptive: true})
+	llm := &throttledLLM{LLM: inner, throttle: th}
+
+	var texts []string
+	for resp, err := range llm.GenerateContent(context.Background(), &model.LLMRequest{}, false) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		texts = append(texts, resp.Content.Parts[0].Text)
+	}
+
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+	if len(texts) != 1 || texts[0] != "ok" {
+		t.Errorf("responses = %v, want [ok]", texts)
+	}
+	if th.currentInterval() == 0 {
+		t.Error("adaptive throttle should slow down after a 429")
+	}
+}
+
+func TestThrottledLLMNonAdaptivePassesError(t *testing.T) {
+	calls := 0
+	inner := &MockLLM{
+		GenerateContentFunc: func(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+			return func(yield func(*model.LLMResponse, error) bool) {
+				calls++
+				yield(nil, llmerror.NewLLMError("test", 429, "", ""))
+			}
+		},
+	}
+
+	llm := &throttledLLM{LLM: inner, throttle: newParallelThrottle(&config.ParallelConfig{RateLimit: 1000})}
+
+	var gotErr error
+	for _, err := range llm.GenerateContent(context.Background(), &model.LLMRequest{}, false) {
+		gotErr = err
+	}
+	if gotErr == nil || calls != 1 {
+		t.Errorf("calls = %d, err = %v; want 1 call with the 429 passed through", calls, gotErr)
+	}
+}
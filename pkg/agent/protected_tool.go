@@ -139,7 +139,6 @@ func (p *ProtectedTool) Run(ctx tool.Context, args any) (map[string]any, error)
 			currentNode = nodeName
 		}
 	}
-	approvalKey := fmt.Sprintf("approval:%s:%s", currentNode, toolName)
 
 	// 1. Check if we already have approval OR if global auto-approve is enabled
 	if p.Agent.AutoApprove {
@@ -153,9 +152,18 @@ func (p *ProtectedTool) Run(ctx tool.Context, args any) (map[string]any, error)
 		return nil, fmt.Errorf("underlying tool does not implement Run")
 	}
 
-	if approved, _ := p.State.Get(approvalKey); approved == true {
+	// Normalize arguments: approvals are bound to them, and they're shown to the user
+	var argsMap map[string]any
+	if m, ok := args.(map[string]any); ok {
+		argsMap = m
+	} else {
+		// If args is a struct (common in MCP), wrap it for display
+		argsMap = map[string]any{"arguments": args}
+	}
+
+	if hasApproval(p.State, currentNode, toolName, argsMap) {
 		// Consume approval - each execution requires new approval
-		p.State.Set(approvalKey, false)
+		revokeApproval(p.State, currentNode, toolName)
 
 		// We use a broader interface check here to be safe
 		if rt, ok := p.Tool.(interface {
@@ -166,22 +174,13 @@ func (p *ProtectedTool) Run(ctx tool.Context, args any) (map[string]any, error)
 		return nil, fmt.Errorf("underlying tool does not implement Run")
 	}
 
-	// 2. Format arguments for display
-	var argsMap map[string]any
-	if m, ok := args.(map[string]any); ok {
-		argsMap = m
-	} else {
-		// If args is a struct (common in MCP), wrap it for display
-		argsMap = map[string]any{"arguments": args}
-	}
-
-	// 3. Set the approval state
+	// 2. Set the approval state
 	p.State.Set("awaiting_approval", true)
 	p.State.Set("approval_tool", toolName)
 	p.State.Set("approval_args", argsMap)
 
-	// 4. Emit the UI Event
-	prompt := p.Agent.formatToolApprovalRequest(toolName, argsMap)
+	// 3. Emit the UI Event
+	prompt := p.Agent.formatToolApprovalRequest(toolName, argsMap, "")
 
 	p.YieldFunc(&session.Event{
 		LLMResponse: model.LLMResponse{
@@ -198,17 +197,25 @@ func (p *ProtectedTool) Run(ctx tool.Context, args any) (map[string]any, error)
 		},
 	}, nil)
 
-	// 5. Return error to stop execution and wait for approval
+	// 4. Return error to stop execution and wait for approval
 	// This prevents the LLM from seeing a tool result before approval
 	return nil, ErrWaitingForApproval
 }
 
-// formatToolApprovalRequest formats a tool approval request
-func (a *AstonishAgent) formatToolApprovalRequest(toolName string, args map[string]interface{}) string {
+// formatToolApprovalRequest formats a tool approval request. reason is the
+// model's explanation of the call, shown above the arguments when known.
+func (a *AstonishAgent) formatToolApprovalRequest(toolName string, args map[string]interface{}, reason string) string {
+	if a.Redactor != nil && len(a.sensitiveKeys()) > 0 {
+		// Args often carry values of sensitive state keys (tokens, PII)
+		args = a.Redactor.RedactMap(args)
+	}
 	if a.IsWebMode {
 		// Return plain text / markdown for Web UI
 		var sb strings.Builder
 		sb.WriteString(fmt.Sprintf("**Requesting approval to execute tool: `%s`**\n\n", toolName))
+		if reason != "" {
+			sb.WriteString(fmt.Sprintf("*Why:* %s\n\n", reason))
+		}
 		sb.WriteString("Arguments:\n")
 		sb.WriteString("```json\n")
 		enc := json.NewEncoder(&sb)
@@ -218,5 +225,31 @@ func (a *AstonishAgent) formatToolApprovalRequest(toolName string, args map[stri
 		return sb.String()
 	}
 	// Return ANSI formatted box for CLI
-	return ui.RenderToolBox(toolName, args)
+	return ui.RenderToolBoxWithReason(toolName, args, reason)
+}
+
+// maxApprovalReasonLen bounds the reasoning shown with an approval request.
+const maxApprovalReasonLen = 300
+
+// approvalReason condenses the text a model wrote before a tool call into
+// the reason shown with its approval request: whitespace is collapsed,
+// secrets are redacted and long text keeps its end, which is closest to
+// the call.
+func (a *AstonishAgent) approvalReason(text string) string {
+	reason := strings.Join(strings.Fields(text), " ")
+	if a.Redactor != nil {
+		reason = a.Redactor.Redact(reason)
+	}
+	if runes := []rune(reason); len(runes) > maxApprovalReasonLen {
+		reason = "…" + strings.TrimSpace(string(runes[len(runes)-maxApprovalReasonLen:]))
+	}
+	return reason
+}
+
+// addApprovalReason adds a non-empty reason to an approval request's
+// state delta, for the web UI and JSON event consumers.
+func addApprovalReason(delta map[string]any, reason string) {
+	if reason != "" {
+		delta["approval_reason"] = reason
+	}
 }
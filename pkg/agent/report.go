@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/pdfgen"
+	"google.golang.org/adk/session"
+)
+
+// ReportStateKey is the state key an output node with report: stores the
+// path of the HTML report it wrote, so run history can link to it.
+const ReportStateKey = "_report_file"
+
+// writeReport renders an output node's message as a standalone HTML report
+// in the workspace and returns its path.
+func (a *AstonishAgent) writeReport(node *config.Node, state session.State, markdown string) (string, error) {
+	cfg := node.Report
+	title := a.renderString(cfg.Title, state)
+	if title == "" && a.Config != nil {
+		title = a.Config.Description
+	}
+
+	file := a.renderString(cfg.File, state)
+	if file == "" {
+		file = filepath.Join("reports", fmt.Sprintf("%s-%s.html", node.Name, time.Now().Format("20060102-150405")))
+	}
+	if !filepath.IsAbs(file) {
+		dir := a.WorkspaceDir
+		if dir == "" {
+			dir = "."
+		}
+		file = filepath.Join(dir, file)
+	}
+
+	var artifacts []pdfgen.ReportArtifact
+	for _, ref := range cfg.Artifacts {
+		path := ref
+		if v, err := state.Get(ref); err == nil {
+			if s, ok := v.(string); ok && s != "" {
+				path = s
+			}
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			slog.Warn("report artifact not embedded", "node", node.Name, "artifact", ref, "error", err)
+			continue
+		}
+		artifacts = append(artifacts, pdfgen.ReportArtifact{Name: filepath.Base(path), Data: data})
+	}
+
+	html, err := pdfgen.RenderHTMLReport(pdfgen.Report{
+		Title:       title,
+		Subtitle:    "Astonish flow · " + node.Name,
+		Markdown:    markdown,
+		GeneratedAt: time.Now(),
+		Artifacts:   artifacts,
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create report directory: %w", err)
+	}
+	if err := os.WriteFile(file, html, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write report: %w", err)
+	}
+	if abs, err := filepath.Abs(file); err == nil {
+		file = abs
+	}
+	return file, nil
+}
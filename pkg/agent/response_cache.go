@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+	"log/slog"
+
+	"github.com/SAP/astonish/pkg/cache"
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// cachesResponse reports whether the model calls of node go through the
+// response cache: cache_response on a node that is deterministic, i.e.
+// without tools and at temperature 0.
+func (a *AstonishAgent) cachesResponse(node *config.Node) bool {
+	if !node.CacheResponse || a.NoCache {
+		return false
+	}
+	if gen := node.Generation; node.Tools || gen == nil || gen.Temperature == nil || *gen.Temperature != 0 {
+		slog.Warn("cache_response ignored: the node needs tools off and generation.temperature: 0", "node", node.Name)
+		return false
+	}
+	return true
+}
+
+// cachedResponseLLM answers a request from pkg/cache when the same model
+// answered an identical request before, so re-running a flow during
+// development does not pay for the same completion twice. New answers are
+// stored once they completed without error.
+type cachedResponseLLM struct {
+	model.LLM
+}
+
+func (m *cachedResponseLLM) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		key, cacheable := responseCacheKey(m.Name(), req)
+		if cacheable {
+			if responses, ok := cachedResponses(key); ok {
+				slog.Debug("LLM response served from cache", "model", m.Name())
+				for _, resp := range responses {
+					if !yield(resp, nil) {
+						return
+					}
+				}
+				return
+			}
+		}
+
+		var final []json.RawMessage
+		for resp, err := range m.LLM.GenerateContent(ctx, req, stream) {
+			if err != nil {
+				cacheable = false
+			} else if resp != nil && !resp.Partial {
+				data, err := json.Marshal(resp)
+				if err != nil {
+					cacheable = false
+				} else {
+					final = append(final, data)
+				}
+			}
+			if !yield(resp, err) {
+				return
+			}
+		}
+		if cacheable && len(final) > 0 {
+			if err := cache.PutLLMResponse(key, m.Name(), final); err != nil {
+				slog.Warn("failed to cache LLM response", "model", m.Name(), "error", err)
+			}
+		}
+	}
+}
+
+// responseCacheKey returns the cache key of req: the model, the rendered
+// contents and the generation config with its system instruction and
+// response schema.
+func responseCacheKey(modelName string, req *model.LLMRequest) (string, bool) {
+	data, err := json.Marshal(struct {
+		Model    string                       `json:"model"`
+		Contents []*genai.Content             `json:"contents"`
+		Config   *genai.GenerateContentConfig `json:"config"`
+	}{req.Model, req.Contents, req.Config})
+	if err != nil {
+		return "", false
+	}
+	return cache.LLMResponseKey(modelName, data), true
+}
+
+// cachedResponses decodes the responses cached under key. They carry no
+// token usage: serving them cost nothing.
+func cachedResponses(key string) ([]*model.LLMResponse, bool) {
+	raw, ok := cache.GetLLMResponse(key)
+	if !ok {
+		return nil, false
+	}
+	responses := make([]*model.LLMResponse, 0, len(raw))
+	for _, data := range raw {
+		var resp model.LLMResponse
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return nil, false
+		}
+		resp.UsageMetadata = nil
+		responses = append(responses, &resp)
+	}
+	return responses, true
+}
@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/cache"
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+func TestCachedResponseLLM(t *testing.T) {
+	cache.SetCacheDir(t.TempDir())
+
+	zero := float32(0)
+	node := &config.Node{Name: "summarize", CacheResponse: true, Generation: &config.GenerationConfig{Temperature: &zero}}
+	a := &AstonishAgent{}
+	if !a.cachesResponse(node) {
+		t.Fatal("deterministic node with cache_response is not cached")
+	}
+	if a.NoCache = true; a.cachesResponse(node) {
+		t.Error("--no-cache did not turn the cache off")
+	}
+	if a.NoCache, node.Tools = false, true; a.cachesResponse(node) {
+		t.Error("node with tools was cached")
+	}
+
+	// The mock has one answer: the second identical request must not reach it
+	mock := &ADKMockModel{Responses: []*genai.Content{genai.NewContentFromText("cached summary", genai.RoleModel)}}
+	llm := &cachedResponseLLM{LLM: mock}
+	req := func(prompt string) *model.LLMRequest {
+		return &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText(prompt, genai.RoleUser)}}
+	}
+	for i := range 2 {
+		var text string
+		for resp, err := range llm.GenerateContent(context.Background(), req("summarize the diff"), false) {
+			if err != nil {
+				t.Fatalf("call %d: %v", i, err)
+			}
+			text += resp.Content.Parts[0].Text
+		}
+		if text != "cached summary" {
+			t.Errorf("call %d = %q, want the model's answer", i, text)
+		}
+	}
+	if len(mock.Requests) != 1 {
+		t.Errorf("model called %d times, want 1", len(mock.Requests))
+	}
+
+	for range llm.GenerateContent(context.Background(), req("summarize another diff"), false) {
+	}
+	if len(mock.Requests) != 2 {
+		t.Error("a different prompt was served from the cache")
+	}
+}
@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/provider/llmerror"
+)
+
+const (
+	defaultRetryInitialDelay = 2 * time.Second
+	defaultRetryMaxDelay     = 30 * time.Second
+	// maxRetryAfter caps provider Retry-After hints to avoid absurd waits.
+	maxRetryAfter = 60 * time.Second
+	// retryJitter spreads waits by ±20% so parallel branches don't retry in lockstep.
+	retryJitter = 0.2
+)
+
+// nodeRetryDelay returns how long to wait before retrying a node after the
+// given 0-indexed failed attempt. A provider Retry-After hint wins; otherwise
+// the node's retry_backoff, initial_delay and max_delay apply, with jitter.
+func nodeRetryDelay(node *config.Node, attempt int, err error) time.Duration {
+	if ra := llmerror.GetRetryAfter(err); ra > 0 {
+		return min(ra, maxRetryAfter)
+	}
+
+	initial := parseRetryDuration(node, "initial_delay", node.InitialDelay, defaultRetryInitialDelay)
+	maxDelay := parseRetryDuration(node, "max_delay", node.MaxDelay, defaultRetryMaxDelay)
+
+	var delay time.Duration
+	switch node.RetryBackoff {
+	case "none":
+		return 0
+	case "constant":
+		delay = initial
+	default: // exponential
+		delay = initial
+		for i := 0; i < attempt && delay < maxDelay; i++ {
+			delay *= 2
+		}
+	}
+
+	delay = time.Duration(float64(delay) * (1 - retryJitter + 2*retryJitter*rand.Float64()))
+	return min(delay, maxDelay)
+}
+
+// parseRetryDuration parses a node duration field, falling back to def when
+// unset or invalid.
+func parseRetryDuration(node *config.Node, field, value string, def time.Duration) time.Duration {
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		slog.Warn("invalid retry duration, using default", "node", node.Name, "field", field, "value", value)
+		return def
+	}
+	return d
+}
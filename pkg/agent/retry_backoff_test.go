@@ -0,0 +1,38 @@
+package agent
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/provider/llmerror"
+)
+
+func TestNodeRetryDelay(t *testing.T) {
+	plain := errors.New("boom")
+	tests := []struct {
+		name     string
+		node     *config.Node
+		attempt  int
+		err      error
+		min, max time.Duration
+	}{
+		{"default first attempt", &config.Node{}, 0, plain, 1600 * time.Millisecond, 2400 * time.Millisecond},
+		{"exponential doubles", &config.Node{InitialDelay: "1s"}, 2, plain, 3200 * time.Millisecond, 4800 * time.Millisecond},
+		{"capped at max_delay", &config.Node{InitialDelay: "1s", MaxDelay: "3s"}, 5, plain, 2400 * time.Millisecond, 3 * time.Second},
+		{"constant", &config.Node{RetryBackoff: "constant", InitialDelay: "500ms"}, 4, plain, 400 * time.Millisecond, 600 * time.Millisecond},
+		{"none", &config.Node{RetryBackoff: "none"}, 3, plain, 0, 0},
+		{"invalid initial_delay falls back", &config.Node{InitialDelay: "soon"}, 0, plain, 1600 * time.Millisecond, 2400 * time.Millisecond},
+		{"retry-after wins", &config.Node{RetryBackoff: "none"}, 0, &llmerror.LLMError{StatusCode: 429, RetryAfter: 7 * time.Second}, 7 * time.Second, 7 * time.Second},
+		{"retry-after capped", &config.Node{}, 0, &llmerror.LLMError{StatusCode: 429, RetryAfter: 10 * time.Minute}, maxRetryAfter, maxRetryAfter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := nodeRetryDelay(tt.node, tt.attempt, tt.err)
+			if got < tt.min || got > tt.max {
+				t.Errorf("nodeRetryDelay() = %v, want within [%v, %v]", got, tt.min, tt.max)
+			}
+		})
+	}
+}
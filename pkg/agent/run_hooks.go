@@ -0,0 +1,146 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/hooks"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+)
+
+// runHooksKey carries the hooks of the running flow to the approval
+// handling.
+type runHooksKey struct{}
+
+// runHooks turns the events of a run into the lifecycle events of the
+// hooks of the flow and the app config. A nil *runHooks ignores events.
+type runHooks struct {
+	a          *AstonishAgent
+	dispatcher *hooks.Dispatcher
+	flow       string
+	sessionID  string
+	failure    string // Reason of the failure the run reported, until END
+}
+
+// newRunHooks starts the delivery of the hooks of the flow and the app
+// config for one call of Run, or returns nil when there are none.
+func (a *AstonishAgent) newRunHooks(ctx agent.InvocationContext) *runHooks {
+	var appHooks, flowHooks []config.HookConfig
+	if a.Config != nil {
+		flowHooks = a.Config.Hooks
+	}
+	if a.AppConfig != nil {
+		appHooks = a.AppConfig.Hooks
+	}
+	dispatcher := hooks.New(appHooks, flowHooks)
+	if dispatcher == nil {
+		return nil
+	}
+	return &runHooks{
+		a:          a,
+		dispatcher: dispatcher,
+		flow:       a.FlowName,
+		sessionID:  ctx.Session().ID(),
+	}
+}
+
+// runHooksFrom returns the hooks of the run in ctx, or nil.
+func runHooksFrom(ctx context.Context) *runHooks {
+	h, _ := ctx.Value(runHooksKey{}).(*runHooks)
+	return h
+}
+
+func (h *runHooks) send(e hooks.Event) {
+	e.Flow, e.SessionID = h.flow, h.sessionID
+	h.dispatcher.Send(e)
+}
+
+// close delivers the remaining events. The run's turn ends after them.
+func (h *runHooks) close() {
+	if h != nil {
+		h.dispatcher.Close()
+	}
+}
+
+// observe derives lifecycle events from an event of the run. finished is
+// the node the event's node start closed, and stats the run's summary
+// after the event.
+func (h *runHooks) observe(event *session.Event, err error, finished *NodeRunSummary, stats *RunSummary) {
+	if h == nil {
+		return
+	}
+	if err != nil {
+		h.send(hooks.Event{Event: hooks.RunFailed, Error: err.Error(), Summary: stats.snapshot()})
+		return
+	}
+	if event == nil {
+		return
+	}
+	if node, ok := NodeStart(event); ok {
+		if finished != nil {
+			h.send(hooks.Event{Event: hooks.NodeExit, Node: finished.Node, DurationMs: finished.DurationMs})
+		}
+		if node != "END" {
+			if len(stats.Path) == 1 {
+				h.send(hooks.Event{Event: hooks.RunStart, Node: node})
+			}
+			nodeType := ""
+			if n, found := h.a.getNode(node); found {
+				nodeType = n.Type
+			}
+			h.send(hooks.Event{Event: hooks.NodeEnter, Node: node, NodeType: nodeType})
+		}
+	}
+
+	delta := event.Actions.StateDelta
+	if info, ok := delta["_failure_info"]; ok {
+		h.failure = failureReason(info)
+	}
+	if summary, ok := delta[RunSummaryKey]; ok {
+		if h.failure != "" {
+			h.send(hooks.Event{Event: hooks.RunFailed, Error: h.failure, Summary: summary})
+		} else {
+			h.send(hooks.Event{Event: hooks.RunEnd, Summary: summary})
+		}
+		h.failure = ""
+	}
+}
+
+// toolDecision reports the user's answer to the approval of a tool call.
+func (h *runHooks) toolDecision(state session.State, tool string, approved bool) {
+	if h == nil {
+		return
+	}
+	e := hooks.Event{Event: hooks.ToolDenied, Tool: tool}
+	if approved {
+		e.Event = hooks.ToolApproved
+	}
+	if v, _ := state.Get("current_node"); v != nil {
+		e.Node, _ = v.(string)
+	}
+	if v, _ := state.Get("approval_args"); v != nil {
+		e.Args, _ = v.(map[string]any)
+	}
+	h.send(e)
+}
+
+// failureReason returns the text of a _failure_info delta.
+func failureReason(info any) string {
+	m, ok := info.(map[string]any)
+	if !ok {
+		return fmt.Sprint(info)
+	}
+	title, _ := m["title"].(string)
+	reason, _ := m["reason"].(string)
+	switch {
+	case title != "" && reason != "":
+		return title + ": " + reason
+	case reason != "":
+		return reason
+	case title != "":
+		return title
+	}
+	return "run failed"
+}
@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/hooks"
+)
+
+func TestRunHooks(t *testing.T) {
+	var mu sync.Mutex
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e hooks.Event
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		mu.Lock()
+		got = append(got, e.Event+" "+e.Node)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	a := &AstonishAgent{Config: &config.AgentConfig{
+		Nodes: []config.Node{
+			{Name: "first", Type: "script", Script: `x["n"] = 1`},
+			{Name: "second", Type: "script", Script: `x["n"] = 2`},
+		},
+		Flow: []config.FlowItem{
+			{From: "START", To: "first"},
+			{From: "first", To: "second"},
+			{From: "second", To: "END"},
+		},
+		Hooks: []config.HookConfig{{URL: srv.URL}},
+	}}
+	ctx := &MockInvocationContext{Context: context.Background(), StateVal: NewMockState()}
+	for _, err := range a.Run(ctx) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{
+		"run_start first", "node_enter first", "node_exit first",
+		"node_enter second", "node_exit second", "run_end ",
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !slices.Equal(got, want) {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+}
+
+func TestRunHooksCommandsOnlyFromAppConfig(t *testing.T) {
+	ctx := &MockInvocationContext{Context: context.Background(), StateVal: NewMockState()}
+	command := []config.HookConfig{{Command: "cat > /dev/null"}}
+
+	flow := &AstonishAgent{Config: &config.AgentConfig{Hooks: command}}
+	if h := flow.newRunHooks(ctx); h != nil {
+		h.close()
+		t.Error("a command hook of the flow was registered")
+	}
+
+	app := &AstonishAgent{Config: &config.AgentConfig{}, AppConfig: &config.AppConfig{Hooks: command}}
+	h := app.newRunHooks(ctx)
+	if h == nil {
+		t.Fatal("the command hook of the app config was not registered")
+	}
+	h.close()
+}
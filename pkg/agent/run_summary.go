@@ -0,0 +1,189 @@
+package agent
+
+import (
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/adk/session"
+)
+
+// RunSummaryKey is the state delta key of the summary of a run, emitted
+// when the flow reaches END.
+const RunSummaryKey = "_run_summary"
+
+// runStatsKey holds the summary collected so far, persisted at node starts
+// and pauses so it continues across the turns of a run.
+const runStatsKey = "_run_stats"
+
+// RunSummary is what a run did: the nodes it went through with their wall
+// time, and the totals of the run.
+type RunSummary struct {
+	Path               []NodeRunSummary `json:"path"`
+	StartedAt          time.Time        `json:"started_at"`
+	DurationMs         int64            `json:"duration_ms"`
+	Retries            int              `json:"retries"`
+	ToolCalls          int              `json:"tool_calls"`
+	ApprovalsRequested int              `json:"approvals_requested"`
+	ApprovalsDenied    int              `json:"approvals_denied"`
+	InputTokens        int              `json:"input_tokens"`
+	OutputTokens       int              `json:"output_tokens"`
+	CostUSD            float64          `json:"cost_usd,omitempty"` // Unset when a model's price is unknown
+}
+
+// NodeRunSummary is one execution of a node in a RunSummary.
+type NodeRunSummary struct {
+	Node         string    `json:"node"`
+	StartedAt    time.Time `json:"started_at"`
+	DurationMs   int64     `json:"duration_ms"`
+	Retries      int       `json:"retries,omitempty"`
+	ToolCalls    int       `json:"tool_calls,omitempty"`
+	InputTokens  int       `json:"input_tokens,omitempty"`
+	OutputTokens int       `json:"output_tokens,omitempty"`
+	// WaitedForInput is set when the run paused at the node for user input.
+	WaitedForInput bool `json:"waited_for_input,omitempty"`
+}
+
+// runStats returns the summary the run collects, started now on the first
+// call of a run. A summary decoded from a persisted session is converted
+// back once.
+func runStats(state session.State) *RunSummary {
+	v, _ := state.Get(runStatsKey)
+	if s, ok := v.(*RunSummary); ok {
+		return s
+	}
+	s := &RunSummary{StartedAt: time.Now()}
+	if v != nil {
+		data, err := json.Marshal(v)
+		if err == nil {
+			err = json.Unmarshal(data, s)
+		}
+		if err != nil {
+			slog.Warn("discarding unreadable run summary", "error", err)
+			*s = RunSummary{StartedAt: s.StartedAt}
+		}
+	}
+	if err := state.Set(runStatsKey, s); err != nil {
+		slog.Warn("failed to store run summary", "error", err)
+	}
+	return s
+}
+
+// observe adds an event of the run to the summary. It returns true when
+// the summary should be persisted with the event: at node starts and when
+// the run pauses for the user.
+func (s *RunSummary) observe(event *session.Event, now time.Time) bool {
+	persist := false
+	if node, ok := NodeStart(event); ok {
+		s.closeNode(now)
+		if node != "END" {
+			s.Path = append(s.Path, NodeRunSummary{Node: node, StartedAt: now})
+		}
+		persist = true
+	}
+
+	delta := event.Actions.StateDelta
+	if awaiting, _ := delta["awaiting_approval"].(bool); awaiting {
+		s.ApprovalsRequested++
+		persist = true
+	}
+	if waiting, _ := delta["waiting_for_input"].(bool); waiting {
+		persist = true
+	}
+	current := s.current()
+	if current == nil {
+		return persist
+	}
+	if _, ok := delta["_retry_info"]; ok {
+		current.Retries++
+	}
+	if waiting, _ := delta["waiting_for_input"].(bool); waiting {
+		current.WaitedForInput = true
+	}
+	if event.Partial {
+		return persist
+	}
+	if event.LLMResponse.Content != nil {
+		for _, part := range event.LLMResponse.Content.Parts {
+			if part.FunctionCall != nil {
+				current.ToolCalls++
+			}
+		}
+	}
+	if usage := event.LLMResponse.UsageMetadata; usage != nil {
+		current.InputTokens += int(usage.PromptTokenCount)
+		current.OutputTokens += int(usage.CandidatesTokenCount)
+	}
+	return persist
+}
+
+// current returns the running node of the path, or nil.
+func (s *RunSummary) current() *NodeRunSummary {
+	if n := len(s.Path); n > 0 && s.Path[n-1].DurationMs == 0 {
+		return &s.Path[n-1]
+	}
+	return nil
+}
+
+// closeNode records the wall time of the running node.
+func (s *RunSummary) closeNode(now time.Time) {
+	if current := s.current(); current != nil {
+		current.DurationMs = max(now.Sub(current.StartedAt).Milliseconds(), 1)
+	}
+}
+
+// snapshot returns a copy of the summary, safe to persist in an event.
+func (s *RunSummary) snapshot() RunSummary {
+	c := *s
+	c.Path = append([]NodeRunSummary(nil), s.Path...)
+	return c
+}
+
+// runSummaryEvent totals the summary s of the run that reached END and
+// prices its tokens with the models of its nodes. The collected summary is
+// reset, so the next chat turn starts a new one.
+func (a *AstonishAgent) runSummaryEvent(state session.State, s *RunSummary, now time.Time) *session.Event {
+	s.closeNode(now)
+	s.DurationMs = now.Sub(s.StartedAt).Milliseconds()
+	s.Retries, s.ToolCalls, s.InputTokens, s.OutputTokens = 0, 0, 0, 0
+	cost, priced := 0.0, true
+	for _, n := range s.Path {
+		s.Retries += n.Retries
+		s.ToolCalls += n.ToolCalls
+		s.InputTokens += n.InputTokens
+		s.OutputTokens += n.OutputTokens
+		if n.InputTokens == 0 && n.OutputTokens == 0 {
+			continue
+		}
+		node, _ := a.getNode(n.Node)
+		price, ok := a.modelPrice(a.nodeModelName(node))
+		priced = priced && ok
+		cost += (float64(n.InputTokens)*price.Input + float64(n.OutputTokens)*price.Output) / 1e6
+	}
+	if priced {
+		s.CostUSD = cost
+	}
+	if err := state.Set(runStatsKey, nil); err != nil {
+		slog.Warn("failed to clear run summary", "error", err)
+	}
+	return &session.Event{
+		Actions: session.EventActions{
+			StateDelta: map[string]any{
+				RunSummaryKey: s.snapshot(),
+				runStatsKey:   nil,
+			},
+		},
+	}
+}
+
+// nodeModelName returns the model a node runs with: its own, or the flow's.
+func (a *AstonishAgent) nodeModelName(node *config.Node) string {
+	if node != nil && node.Model != "" {
+		return node.Model
+	}
+	if a.ModelName == "" && a.LLM != nil {
+		return a.LLM.Name()
+	}
+	return a.ModelName
+}
@@ -0,0 +1,75 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+func TestRunSummary(t *testing.T) {
+	a := &AstonishAgent{
+		Config:    &config.AgentConfig{Nodes: []config.Node{{Name: "fetch", Type: "tool"}, {Name: "review", Type: "llm"}}},
+		ModelName: "gpt-4o",
+	}
+	state := NewMockState()
+	stats := runStats(state)
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(sec int) time.Time { return start.Add(time.Duration(sec) * time.Second) }
+	nodeStart := func(node string) *session.Event {
+		event := &session.Event{Actions: session.EventActions{StateDelta: map[string]any{"current_node": node, "node_type": "llm"}}}
+		markNodeStart(event, node)
+		return event
+	}
+	stats.StartedAt = start
+
+	if !stats.observe(nodeStart("fetch"), at(0)) {
+		t.Error("node start was not persisted")
+	}
+	stats.observe(&session.Event{LLMResponse: model.LLMResponse{Content: &genai.Content{Parts: []*genai.Part{
+		{FunctionCall: &genai.FunctionCall{Name: "http_get"}},
+	}}}}, at(1))
+	stats.observe(&session.Event{Actions: session.EventActions{StateDelta: map[string]any{"awaiting_approval": true}}}, at(1))
+	stats.observe(nodeStart("review"), at(3))
+	stats.observe(&session.Event{Actions: session.EventActions{StateDelta: map[string]any{"_retry_info": map[string]any{"attempt": 1}}}}, at(4))
+	stats.observe(&session.Event{LLMResponse: model.LLMResponse{
+		Partial:       true,
+		UsageMetadata: &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 999},
+	}}, at(5))
+	stats.observe(&session.Event{LLMResponse: model.LLMResponse{
+		UsageMetadata: &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 1000, CandidatesTokenCount: 200},
+	}}, at(5))
+
+	// A run resumed in a new process continues the persisted summary
+	persisted := map[string]any{"path": []any{}, "approvals_denied": 1}
+	if err := state.Set(runStatsKey, persisted); err != nil {
+		t.Fatal(err)
+	}
+	if got := runStats(state); got.ApprovalsDenied != 1 {
+		t.Errorf("decoded summary = %+v", got)
+	}
+	stats.ApprovalsDenied = 1
+
+	stats.observe(nodeStart("END"), at(6))
+	event := a.runSummaryEvent(state, stats, at(6))
+	summary, ok := event.Actions.StateDelta[RunSummaryKey].(RunSummary)
+	if !ok {
+		t.Fatalf("summary event delta = %v", event.Actions.StateDelta)
+	}
+	if len(summary.Path) != 2 || summary.Path[0].DurationMs != 3000 || summary.Path[1].DurationMs != 3000 {
+		t.Errorf("path = %+v", summary.Path)
+	}
+	if summary.DurationMs != 6000 || summary.ToolCalls != 1 || summary.Retries != 1 ||
+		summary.ApprovalsRequested != 1 || summary.ApprovalsDenied != 1 {
+		t.Errorf("totals = %+v", summary)
+	}
+	if summary.InputTokens != 1000 || summary.OutputTokens != 200 || summary.CostUSD != 0.0045 {
+		t.Errorf("tokens = %d/%d, cost = %v", summary.InputTokens, summary.OutputTokens, summary.CostUSD)
+	}
+	if v, _ := state.Get(runStatsKey); v != nil {
+		t.Errorf("collected summary not reset: %v", v)
+	}
+}
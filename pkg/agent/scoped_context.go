@@ -4,6 +4,7 @@ import (
 	"context"
 	"iter"
 
+	"github.com/SAP/astonish/pkg/config"
 	"google.golang.org/adk/agent"
 	"google.golang.org/adk/memory"
 	"google.golang.org/adk/session"
@@ -17,6 +18,7 @@ type minimalReadonlyContext struct {
 	actions   *session.EventActions
 	state     session.State
 	sessionID string
+	artifacts agent.Artifacts
 }
 
 func (m *minimalReadonlyContext) AgentName() string                    { return "astonish-agent" }
@@ -37,7 +39,7 @@ func (m *minimalReadonlyContext) SearchMemory(ctx context.Context, query string)
 	return nil, nil
 }
 func (m *minimalReadonlyContext) FunctionCallID() string     { return "" }
-func (m *minimalReadonlyContext) Artifacts() agent.Artifacts { return nil }
+func (m *minimalReadonlyContext) Artifacts() agent.Artifacts { return m.artifacts }
 func (m *minimalReadonlyContext) State() session.State       { return m.state }
 func (m *minimalReadonlyContext) RequestConfirmation(hint string, payload any) error {
 	return nil
@@ -125,3 +127,31 @@ type ScopedSession struct {
 func (s *ScopedSession) State() session.State {
 	return s.state
 }
+
+// flowShellKey carries the shell section of the running flow to the tools.
+type flowShellKey struct{}
+
+// FlowShell returns the shell section of the flow whose node runs in ctx,
+// or nil. shell_command applies it on top of security.shell.
+func FlowShell(ctx context.Context) *config.FlowShellConfig {
+	shell, _ := ctx.Value(flowShellKey{}).(*config.FlowShellConfig)
+	return shell
+}
+
+// nodeEnvKey carries the env of the running node to the tools.
+type nodeEnvKey struct{}
+
+// NodeEnv returns the env of the node whose tools run in ctx, or nil.
+// shell_command adds it to the environment of its commands.
+func NodeEnv(ctx context.Context) map[string]string {
+	env, _ := ctx.Value(nodeEnvKey{}).(map[string]string)
+	return env
+}
+
+// withNodeEnv returns ctx carrying env, or ctx itself when env is empty.
+func withNodeEnv(ctx context.Context, env map[string]string) context.Context {
+	if len(env) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, nodeEnvKey{}, env)
+}
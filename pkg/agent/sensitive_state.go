@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"fmt"
+	"maps"
+
+	"github.com/SAP/astonish/pkg/credentials"
+	"google.golang.org/adk/session"
+)
+
+// sensitiveMask replaces the values of sensitive state keys in displays.
+const sensitiveMask = "********"
+
+// sensitiveKeys returns the state keys the flow marks sensitive.
+func (a *AstonishAgent) sensitiveKeys() map[string]bool {
+	if a.Config == nil {
+		return nil
+	}
+	return a.Config.SensitiveKeys()
+}
+
+// protectSensitiveState registers the current values of sensitive state
+// keys with the redactor, so they are masked wherever they are echoed (LLM
+// text, tool output, approval prompts). Flows with sensitive keys always get
+// a redactor, even without a credential store.
+func (a *AstonishAgent) protectSensitiveState(state session.State) {
+	keys := a.sensitiveKeys()
+	if len(keys) == 0 {
+		return
+	}
+	if a.Redactor == nil {
+		a.Redactor = credentials.NewRedactor()
+	}
+	for key := range keys {
+		if val, err := state.Get(key); err == nil {
+			registerSensitiveValue(a.Redactor, key, val)
+		}
+	}
+}
+
+// protectSensitiveDelta registers sensitive values set by an event.
+func (a *AstonishAgent) protectSensitiveDelta(delta map[string]any) {
+	if a.Redactor == nil || len(delta) == 0 {
+		return
+	}
+	keys := a.sensitiveKeys()
+	for key, val := range delta {
+		if keys[key] {
+			registerSensitiveValue(a.Redactor, key, val)
+		}
+	}
+}
+
+func registerSensitiveValue(r *credentials.Redactor, key string, val any) {
+	switch v := val.(type) {
+	case nil:
+	case string:
+		r.AddSecret("state/"+key, v)
+	case []any:
+		for _, item := range v {
+			registerSensitiveValue(r, key, item)
+		}
+	case map[string]any:
+		for _, item := range v {
+			registerSensitiveValue(r, key, item)
+		}
+	default:
+		r.AddSecret("state/"+key, fmt.Sprint(v))
+	}
+}
+
+// MaskSensitiveState returns a copy of delta for display in consoles and
+// web UIs: values of sensitive keys are replaced by a mask and the args of
+// a pending tool approval are redacted. Flows without sensitive keys get
+// delta back unchanged.
+func (a *AstonishAgent) MaskSensitiveState(delta map[string]any) map[string]any {
+	keys := a.sensitiveKeys()
+	if len(keys) == 0 || len(delta) == 0 {
+		return delta
+	}
+	masked := maps.Clone(delta)
+	for key, val := range delta {
+		if keys[key] && val != nil {
+			masked[key] = sensitiveMask
+		}
+	}
+	if args, ok := delta["approval_args"].(map[string]any); ok && a.Redactor != nil {
+		masked["approval_args"] = a.Redactor.RedactMap(args)
+	}
+	return masked
+}
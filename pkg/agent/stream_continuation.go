@@ -0,0 +1,154 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// maxStreamContinuations bounds how often one answer is continued after
+// its stream dropped.
+const maxStreamContinuations = 2
+
+// continuationTailChars is how much of the text received so far the
+// continuation prompt quotes, so the model knows where to pick up.
+const continuationTailChars = 200
+
+// continuingLLM recovers from provider streams that drop mid-generation.
+// When a streamed text answer fails after some of it arrived, the model is
+// asked to continue from where it stopped instead of the node retrying the
+// whole generation, and the parts are returned as one answer. Requests with
+// tools or a response schema are passed through: a function call or JSON
+// document cannot be resumed reliably.
+type continuingLLM struct {
+	model.LLM
+}
+
+func (m *continuingLLM) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	if !stream || !isPureTextRequest(req) {
+		return m.LLM.GenerateContent(ctx, req, stream)
+	}
+	return func(yield func(*model.LLMResponse, error) bool) {
+		var text strings.Builder
+		var usage *genai.GenerateContentResponseUsageMetadata
+		attempt := req
+		for round := 0; ; round++ {
+			var final *model.LLMResponse
+			var received strings.Builder
+			var streamErr error
+			for resp, err := range m.LLM.GenerateContent(ctx, attempt, stream) {
+				if err != nil {
+					streamErr = err
+					break
+				}
+				if resp == nil {
+					continue
+				}
+				if !resp.Partial {
+					// Held back until the stream completed: it is merged
+					// with the text of earlier rounds.
+					final = resp
+					continue
+				}
+				received.WriteString(responseText(resp))
+				if !yield(resp, nil) {
+					return
+				}
+			}
+			if final != nil {
+				usage = addUsage(usage, final.UsageMetadata)
+				// The aggregated answer is the reference when the provider
+				// sent one, even before failing.
+				received.Reset()
+				received.WriteString(responseText(final))
+			}
+
+			if streamErr == nil {
+				if final == nil {
+					return
+				}
+				if round > 0 {
+					final = joinedResponse(final, text.String()+received.String(), usage)
+				}
+				yield(final, nil)
+				return
+			}
+
+			text.WriteString(received.String())
+			if text.Len() == 0 || round == maxStreamContinuations || ctx.Err() != nil {
+				yield(nil, streamErr)
+				return
+			}
+			slog.Warn("model stream interrupted, continuing from the partial answer", "model", m.Name(), "received_chars", text.Len(), "error", streamErr)
+			attempt = continuationRequest(req, text.String())
+		}
+	}
+}
+
+// isPureTextRequest reports whether req asks for plain text: no tools and
+// no response schema.
+func isPureTextRequest(req *model.LLMRequest) bool {
+	if len(req.Tools) > 0 {
+		return false
+	}
+	cfg := req.Config
+	return cfg == nil || (len(cfg.Tools) == 0 && cfg.ResponseSchema == nil && cfg.ResponseJsonSchema == nil && cfg.ResponseMIMEType != "application/json")
+}
+
+// responseText returns the answer text of resp, without thoughts.
+func responseText(resp *model.LLMResponse) string {
+	if resp.Content == nil {
+		return ""
+	}
+	var sb strings.Builder
+	for _, part := range resp.Content.Parts {
+		if part != nil && !part.Thought {
+			sb.WriteString(part.Text)
+		}
+	}
+	return sb.String()
+}
+
+// continuationRequest returns req extended with the partial answer and a
+// request to write the rest of it.
+func continuationRequest(req *model.LLMRequest, partial string) *model.LLMRequest {
+	tail := partial
+	if r := []rune(partial); len(r) > continuationTailChars {
+		tail = string(r[len(r)-continuationTailChars:])
+	}
+	r := *req
+	r.Contents = append(slices.Clone(req.Contents),
+		genai.NewContentFromText(partial, genai.RoleModel),
+		genai.NewContentFromText(fmt.Sprintf("Your answer was cut off. Continue from: %q\nWrite only the rest of the answer, starting exactly where it stops, without repeating anything.", tail), genai.RoleUser),
+	)
+	return &r
+}
+
+// joinedResponse returns the final response of a continued answer: the
+// last round's response carrying the full text and the usage of all rounds.
+func joinedResponse(final *model.LLMResponse, text string, usage *genai.GenerateContentResponseUsageMetadata) *model.LLMResponse {
+	joined := *final
+	joined.Content = genai.NewContentFromText(text, genai.RoleModel)
+	joined.UsageMetadata = usage
+	return &joined
+}
+
+// addUsage adds the token counts of u to total.
+func addUsage(total, u *genai.GenerateContentResponseUsageMetadata) *genai.GenerateContentResponseUsageMetadata {
+	if u == nil {
+		return total
+	}
+	if total == nil {
+		total = &genai.GenerateContentResponseUsageMetadata{}
+	}
+	total.PromptTokenCount += u.PromptTokenCount
+	total.CandidatesTokenCount += u.CandidatesTokenCount
+	total.TotalTokenCount += u.TotalTokenCount
+	return total
+}
@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"strings"
+	"testing"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+func TestContinuingLLM(t *testing.T) {
+	chunk := func(text string) *model.LLMResponse {
+		return &model.LLMResponse{Content: genai.NewContentFromText(text, genai.RoleModel), Partial: true}
+	}
+	var requests []*model.LLMRequest
+	mock := &MockLLM{GenerateContentFunc: func(_ context.Context, req *model.LLMRequest, _ bool) iter.Seq2[*model.LLMResponse, error] {
+		requests = append(requests, req)
+		return func(yield func(*model.LLMResponse, error) bool) {
+			if len(requests) == 1 {
+				yield(chunk("The report covers "), nil)
+				yield(nil, errors.New("stream error: connection reset"))
+				return
+			}
+			yield(chunk("three findings."), nil)
+			yield(&model.LLMResponse{
+				Content:       genai.NewContentFromText("three findings.", genai.RoleModel),
+				UsageMetadata: &genai.GenerateContentResponseUsageMetadata{CandidatesTokenCount: 3},
+			}, nil)
+		}
+	}}
+	llm := &continuingLLM{LLM: mock}
+
+	req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("write the report", genai.RoleUser)}}
+	var streamed string
+	var final *model.LLMResponse
+	for resp, err := range llm.GenerateContent(context.Background(), req, true) {
+		if err != nil {
+			t.Fatalf("continued answer failed: %v", err)
+		}
+		if resp.Partial {
+			streamed += responseText(resp)
+		} else {
+			final = resp
+		}
+	}
+	if len(requests) != 2 {
+		t.Fatalf("model called %d times, want the first call and one continuation", len(requests))
+	}
+	cont := requests[1].Contents
+	if len(cont) != 3 || responseText(&model.LLMResponse{Content: cont[1]}) != "The report covers " || !strings.Contains(cont[2].Parts[0].Text, "Continue from") {
+		t.Errorf("continuation contents = %+v, want the partial answer and a continue prompt", cont)
+	}
+	if streamed != "The report covers three findings." {
+		t.Errorf("streamed = %q", streamed)
+	}
+	if final == nil || responseText(final) != "The report covers three findings." || final.UsageMetadata.CandidatesTokenCount != 3 {
+		t.Errorf("final = %+v, want the joined answer", final)
+	}
+
+	// Requests with tools are not continued
+	requests = nil
+	req.Config = &genai.GenerateContentConfig{Tools: []*genai.Tool{{FunctionDeclarations: []*genai.FunctionDeclaration{{Name: "search"}}}}}
+	var lastErr error
+	for _, err := range llm.GenerateContent(context.Background(), req, true) {
+		lastErr = err
+	}
+	if lastErr == nil || len(requests) != 1 {
+		t.Errorf("tool request: err = %v after %d calls, want the stream error without continuing", lastErr, len(requests))
+	}
+}
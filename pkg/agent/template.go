@@ -0,0 +1,568 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/ui"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"google.golang.org/adk/session"
+)
+
+// Templating modes of a node (templating:).
+const (
+	TemplatingBasic = "basic" // {expr} substitution only (default)
+	TemplatingRich  = "rich"  // adds {expr | filter}, {% if %} and {% for %}
+)
+
+var (
+	// templateTokenRe matches {% tag %} blocks and {expr} placeholders. Tags
+	// come first so {% ... %} is never read as an expression.
+	templateTokenRe = regexp.MustCompile(`\{%\s*(.*?)\s*%\}|\{([^{}]+)\}`)
+	// credentialPlaceholder matches {{CREDENTIAL:...}}, which templates
+	// must pass through untouched.
+	credentialPlaceholder = regexp.MustCompile(`\{\{CREDENTIAL:[^}]+\}\}`)
+	forClause             = regexp.MustCompile(`^([A-Za-z_]\w*)(?:\s*,\s*([A-Za-z_]\w*))?\s+in\s+(.+)$`)
+)
+
+// renderNodeString renders a template of node with the node's templating mode.
+// Rich templates that fail to parse fall back to basic rendering (the flow
+// validator reports them before a run).
+func (a *AstonishAgent) renderNodeString(node *config.Node, tmpl string, state session.State) string {
+	if node == nil || node.Templating != TemplatingRich {
+		return a.renderString(tmpl, state)
+	}
+	out, err := RenderTemplate(tmpl, a.stateToMap(state))
+	if err != nil {
+		slog.Warn("template error, using basic rendering", "node", node.Name, "error", err)
+		return a.renderString(tmpl, state)
+	}
+	return out
+}
+
+// protectCredentials swaps {{CREDENTIAL:...}} placeholders for markers that no
+// template syntax matches. The returned function puts them back. The
+// placeholders are resolved later at the tool execution boundary.
+func protectCredentials(tmpl string) (string, func(string) string) {
+	var holes []string
+	tmpl = credentialPlaceholder.ReplaceAllStringFunc(tmpl, func(m string) string {
+		holes = append(holes, m)
+		return fmt.Sprintf("\x00CRED_%d\x00", len(holes)-1)
+	})
+	return tmpl, func(s string) string {
+		for i, orig := range holes {
+			s = strings.Replace(s, fmt.Sprintf("\x00CRED_%d\x00", i), orig, 1)
+		}
+		return s
+	}
+}
+
+// --- Parsing ---
+
+type templateNode interface{}
+
+type textNode string
+
+// exprNode is a placeholder: an expression followed by optional filters.
+type exprNode string
+
+type ifNode struct {
+	conds    []string
+	bodies   [][]templateNode
+	elseBody []templateNode
+}
+
+type forNode struct {
+	keyVar, valueVar string // valueVar is set for "for k, v in ..."
+	iterable         string
+	body, elseBody   []templateNode
+}
+
+type templateParser struct {
+	tokens []templateToken
+	pos    int
+}
+
+type templateToken struct {
+	text string // literal text, or the tag/expression source
+	kind byte   // 't' text, 'e' expression, '%' tag
+}
+
+// ParseTemplate reports syntax errors of a rich template, such as unclosed
+// or unexpected {% %} tags.
+func ParseTemplate(tmpl string) error {
+	tmpl, _ = protectCredentials(tmpl)
+	_, err := parseTemplate(tmpl)
+	return err
+}
+
+func parseTemplate(tmpl string) ([]templateNode, error) {
+	p := &templateParser{tokens: tokenizeTemplate(tmpl)}
+	nodes, end, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	if end != "" {
+		return nil, fmt.Errorf("unexpected {%% %s %%}", end)
+	}
+	return nodes, nil
+}
+
+// tokenizeTemplate splits a template into text, expressions and tags. A tag
+// alone on its line takes the whole line, so block tags leave no blank lines.
+func tokenizeTemplate(tmpl string) []templateToken {
+	var tokens []templateToken
+	last := 0
+	for _, m := range templateTokenRe.FindAllStringSubmatchIndex(tmpl, -1) {
+		start, end := m[0], m[1]
+		if m[2] < 0 {
+			tokens = append(tokens, templateToken{text: tmpl[last:start], kind: 't'})
+			tokens = append(tokens, templateToken{text: strings.TrimSpace(tmpl[m[4]:m[5]]), kind: 'e'})
+			last = end
+			continue
+		}
+		text := tmpl[last:start]
+		lineStart := strings.LastIndexByte(text, '\n') + 1
+		if strings.TrimLeft(text[lineStart:], " \t") == "" && (lineStart > 0 || last == 0 || strings.HasSuffix(tmpl[:last], "\n")) {
+			text = text[:lineStart]
+			if strings.HasPrefix(tmpl[end:], "\n") {
+				end++
+			} else if strings.HasPrefix(tmpl[end:], "\r\n") {
+				end += 2
+			}
+		}
+		tokens = append(tokens, templateToken{text: text, kind: 't'})
+		tokens = append(tokens, templateToken{text: tmpl[m[2]:m[3]], kind: '%'})
+		last = end
+	}
+	return append(tokens, templateToken{text: tmpl[last:], kind: 't'})
+}
+
+// parse reads nodes until a tag that closes or continues the enclosing block
+// and returns that tag (empty at the end of the template).
+func (p *templateParser) parse() ([]templateNode, string, error) {
+	var nodes []templateNode
+	for p.pos < len(p.tokens) {
+		tok := p.tokens[p.pos]
+		p.pos++
+		switch tok.kind {
+		case 't':
+			if tok.text != "" {
+				nodes = append(nodes, textNode(tok.text))
+			}
+		case 'e':
+			nodes = append(nodes, exprNode(tok.text))
+		case '%':
+			keyword, rest, _ := strings.Cut(tok.text, " ")
+			rest = strings.TrimSpace(rest)
+			switch keyword {
+			case "if":
+				node, err := p.parseIf(rest)
+				if err != nil {
+					return nil, "", err
+				}
+				nodes = append(nodes, node)
+			case "for":
+				node, err := p.parseFor(rest)
+				if err != nil {
+					return nil, "", err
+				}
+				nodes = append(nodes, node)
+			case "elif", "else", "endif", "endfor":
+				return nodes, tok.text, nil
+			default:
+				return nil, "", fmt.Errorf("unknown tag {%% %s %%}", tok.text)
+			}
+		}
+	}
+	return nodes, "", nil
+}
+
+func (p *templateParser) parseIf(cond string) (*ifNode, error) {
+	if cond == "" {
+		return nil, fmt.Errorf("{%% if %%} needs a condition")
+	}
+	node := &ifNode{}
+	for {
+		body, end, err := p.parse()
+		if err != nil {
+			return nil, err
+		}
+		node.conds = append(node.conds, cond)
+		node.bodies = append(node.bodies, body)
+		switch {
+		case strings.HasPrefix(end, "elif "):
+			cond = strings.TrimSpace(strings.TrimPrefix(end, "elif "))
+		case end == "else":
+			elseBody, end, err := p.parse()
+			if err != nil {
+				return nil, err
+			}
+			if end != "endif" {
+				return nil, fmt.Errorf("{%% if %s %%} is not closed with {%% endif %%}", node.conds[0])
+			}
+			node.elseBody = elseBody
+			return node, nil
+		case end == "endif":
+			return node, nil
+		default:
+			return nil, fmt.Errorf("{%% if %s %%} is not closed with {%% endif %%}", node.conds[0])
+		}
+	}
+}
+
+func (p *templateParser) parseFor(clause string) (*forNode, error) {
+	m := forClause.FindStringSubmatch(clause)
+	if m == nil {
+		return nil, fmt.Errorf("invalid {%% for %s %%}: use 'for item in list' or 'for key, value in dict'", clause)
+	}
+	node := &forNode{keyVar: m[1], valueVar: m[2], iterable: strings.TrimSpace(m[3])}
+	body, end, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	node.body = body
+	if end == "else" {
+		if node.elseBody, end, err = p.parse(); err != nil {
+			return nil, err
+		}
+	}
+	if end != "endfor" {
+		return nil, fmt.Errorf("{%% for %s %%} is not closed with {%% endfor %%}", clause)
+	}
+	return node, nil
+}
+
+// --- Rendering ---
+
+// RenderTemplate renders a rich template against state. Placeholders are
+// {expr} with optional filters ({items | join(", ")}); blocks are
+// {% if %}/{% elif %}/{% else %}/{% endif %} and {% for %}/{% else %}/{% endfor %}.
+// Expressions use the same evaluator as basic templates, and placeholders
+// that cannot be resolved render as <expr>, like in basic templates.
+func RenderTemplate(tmpl string, state map[string]interface{}) (string, error) {
+	tmpl, restore := protectCredentials(tmpl)
+	nodes, err := parseTemplate(tmpl)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	renderTemplateNodes(&sb, nodes, state)
+	return restore(sb.String()), nil
+}
+
+func renderTemplateNodes(sb *strings.Builder, nodes []templateNode, scope map[string]interface{}) {
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case textNode:
+			sb.WriteString(string(n))
+		case exprNode:
+			sb.WriteString(renderTemplateExpr(string(n), scope))
+		case *ifNode:
+			body := n.elseBody
+			for i, cond := range n.conds {
+				if val, err := EvaluateExpression(cond, scope); err == nil && templateTruthy(val) {
+					body = n.bodies[i]
+					break
+				}
+			}
+			renderTemplateNodes(sb, body, scope)
+		case *forNode:
+			renderTemplateFor(sb, n, scope)
+		}
+	}
+}
+
+func renderTemplateFor(sb *strings.Builder, n *forNode, scope map[string]interface{}) {
+	val, err := EvaluateExpression(n.iterable, scope)
+	if err != nil {
+		val = nil
+	}
+
+	// Each iteration binds the loop variables (key/value pairs for dicts)
+	var keys, values []interface{}
+	switch v := val.(type) {
+	case []interface{}:
+		for i, item := range v {
+			keys = append(keys, item)
+			if n.valueVar != "" {
+				pair, ok := item.([]interface{})
+				if !ok || len(pair) != 2 {
+					slog.Debug("template for: item is not a pair", "iterable", n.iterable, "index", i)
+					return
+				}
+				keys[i], values = pair[0], append(values, pair[1])
+			}
+		}
+	case map[string]interface{}:
+		sorted := make([]string, 0, len(v))
+		for k := range v {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+		for _, k := range sorted {
+			keys = append(keys, k)
+			values = append(values, v[k])
+		}
+	default:
+		if val != nil {
+			slog.Debug("template for: value is not a list or dict", "iterable", n.iterable)
+		}
+	}
+
+	if len(keys) == 0 {
+		renderTemplateNodes(sb, n.elseBody, scope)
+		return
+	}
+	for i, key := range keys {
+		inner := maps.Clone(scope)
+		inner[n.keyVar] = key
+		if n.valueVar != "" {
+			inner[n.valueVar] = values[i]
+		}
+		inner["loop"] = starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+			"index":  starlark.MakeInt(i + 1),
+			"index0": starlark.MakeInt(i),
+			"first":  starlark.Bool(i == 0),
+			"last":   starlark.Bool(i == len(keys)-1),
+			"length": starlark.MakeInt(len(keys)),
+		})
+		renderTemplateNodes(sb, n.body, inner)
+	}
+}
+
+// renderTemplateExpr evaluates a placeholder and applies its filters.
+func renderTemplateExpr(src string, scope map[string]interface{}) string {
+	parts := splitTemplateFilters(src)
+	val, err := EvaluateExpression(parts[0], scope)
+	if err != nil {
+		// A missing value is fine when a default filter supplies one
+		hasDefault := false
+		for _, f := range parts[1:] {
+			name, _, _ := strings.Cut(f, "(")
+			hasDefault = hasDefault || strings.TrimSpace(name) == "default"
+		}
+		if !hasDefault {
+			return "<" + src + ">"
+		}
+		val = nil
+	}
+
+	for _, f := range parts[1:] {
+		name, argSrc, hasArgs := strings.Cut(f, "(")
+		name = strings.TrimSpace(name)
+		var args []interface{}
+		if hasArgs {
+			argSrc = strings.TrimSuffix(strings.TrimSpace(argSrc), ")")
+			if strings.TrimSpace(argSrc) != "" {
+				evaluated, err := EvaluateExpression("["+argSrc+"]", scope)
+				if err != nil {
+					slog.Debug("template filter arguments", "filter", name, "error", err)
+					return "<" + src + ">"
+				}
+				args, _ = evaluated.([]interface{})
+			}
+		}
+		filter, ok := templateFilters[name]
+		if !ok {
+			slog.Debug("unknown template filter", "filter", name)
+			return "<" + src + ">"
+		}
+		if val, err = filter(val, args); err != nil {
+			slog.Debug("template filter failed", "filter", name, "error", err)
+			return "<" + src + ">"
+		}
+	}
+
+	if val == nil {
+		return "<" + src + ">"
+	}
+	return templateText(val)
+}
+
+// splitTemplateFilters splits a placeholder on the | characters outside
+// strings and brackets: the expression first, then one entry per filter.
+func splitTemplateFilters(src string) []string {
+	var parts []string
+	depth, start := 0, 0
+	var quote rune
+	for i, r := range src {
+		switch {
+		case quote != 0:
+			if r == quote && (i == 0 || src[i-1] != '\\') {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '(' || r == '[' || r == '{':
+			depth++
+		case r == ')' || r == ']' || r == '}':
+			depth--
+		case r == '|' && depth == 0:
+			parts = append(parts, strings.TrimSpace(src[start:i]))
+			start = i + 1
+		}
+	}
+	return append(parts, strings.TrimSpace(src[start:]))
+}
+
+// templateText formats a value like basic templates do, with strings as-is.
+func templateText(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int, int64, float64, bool:
+		return fmt.Sprint(v)
+	default:
+		return ui.FormatAsYamlLike(v, 0)
+	}
+}
+
+// templateTruthy follows Python truthiness for values read from state.
+func templateTruthy(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case int:
+		return v != 0
+	case int64:
+		return v != 0
+	case float64:
+		return v != 0
+	case []interface{}:
+		return len(v) > 0
+	case map[string]interface{}:
+		return len(v) > 0
+	default:
+		return true
+	}
+}
+
+type templateFilter func(v interface{}, args []interface{}) (interface{}, error)
+
+// templateFilters are the filters available in rich templates.
+var templateFilters = map[string]templateFilter{
+	"join": func(v interface{}, args []interface{}) (interface{}, error) {
+		sep := ", "
+		if len(args) > 0 {
+			sep = templateText(args[0])
+		}
+		list, ok := v.([]interface{})
+		if !ok {
+			return templateText(v), nil
+		}
+		items := make([]string, len(list))
+		for i, item := range list {
+			items[i] = templateText(item)
+		}
+		return strings.Join(items, sep), nil
+	},
+	"truncate": func(v interface{}, args []interface{}) (interface{}, error) {
+		if len(args) == 0 {
+			return nil, fmt.Errorf("truncate needs a length")
+		}
+		n, ok := args[0].(int)
+		if !ok || n < 0 {
+			return nil, fmt.Errorf("truncate length must be a non-negative integer")
+		}
+		suffix := "..."
+		if len(args) > 1 {
+			suffix = templateText(args[1])
+		}
+		s := templateText(v)
+		if utf8.RuneCountInString(s) <= n {
+			return s, nil
+		}
+		return string([]rune(s)[:n]) + suffix, nil
+	},
+	"upper": func(v interface{}, _ []interface{}) (interface{}, error) {
+		return strings.ToUpper(templateText(v)), nil
+	},
+	"lower": func(v interface{}, _ []interface{}) (interface{}, error) {
+		return strings.ToLower(templateText(v)), nil
+	},
+	"trim": func(v interface{}, _ []interface{}) (interface{}, error) {
+		return strings.TrimSpace(templateText(v)), nil
+	},
+	"default": func(v interface{}, args []interface{}) (interface{}, error) {
+		if templateTruthy(v) || len(args) == 0 {
+			return v, nil
+		}
+		return args[0], nil
+	},
+	"length": func(v interface{}, _ []interface{}) (interface{}, error) {
+		switch v := v.(type) {
+		case []interface{}:
+			return len(v), nil
+		case map[string]interface{}:
+			return len(v), nil
+		case string:
+			return utf8.RuneCountInString(v), nil
+		case nil:
+			return 0, nil
+		}
+		return nil, fmt.Errorf("length of %T", v)
+	},
+	"json": func(v interface{}, args []interface{}) (interface{}, error) {
+		var data []byte
+		var err error
+		if len(args) > 0 {
+			data, err = json.MarshalIndent(v, "", strings.Repeat(" ", max(0, toInt(args[0]))))
+		} else {
+			data, err = json.Marshal(v)
+		}
+		return string(data), err
+	},
+	"first": func(v interface{}, _ []interface{}) (interface{}, error) {
+		if list, ok := v.([]interface{}); ok && len(list) > 0 {
+			return list[0], nil
+		}
+		return nil, nil
+	},
+	"last": func(v interface{}, _ []interface{}) (interface{}, error) {
+		if list, ok := v.([]interface{}); ok && len(list) > 0 {
+			return list[len(list)-1], nil
+		}
+		return nil, nil
+	},
+	"map": func(v interface{}, args []interface{}) (interface{}, error) {
+		if len(args) == 0 {
+			return nil, fmt.Errorf("map needs a key")
+		}
+		key := templateText(args[0])
+		list, _ := v.([]interface{})
+		out := make([]interface{}, 0, len(list))
+		for _, item := range list {
+			if m, ok := item.(map[string]interface{}); ok {
+				out = append(out, m[key])
+			}
+		}
+		return out, nil
+	},
+}
+
+func toInt(v interface{}) int {
+	switch v := v.(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return 0
+}
@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	state := map[string]interface{}{
+		"items": []interface{}{"a", "b", "c"},
+		"text":  "abcdefghij",
+		"issues": []interface{}{
+			map[string]interface{}{"title": "Crash", "state": "open"},
+			map[string]interface{}{"title": "Typo", "state": "closed"},
+		},
+		"labels": map[string]interface{}{"bug": 3, "docs": 1},
+		"empty":  []interface{}{},
+		"token":  "{{CREDENTIAL:github:token}}",
+	}
+
+	tests := []struct {
+		name, tmpl, want string
+	}{
+		{"plain", "Items: {items[0]}", "Items: a"},
+		{"join", `{items | join(", ")}`, "a, b, c"},
+		{"truncate", "{text | truncate(4)}", "abcd..."},
+		{"chain", `{issues | map("title") | join("/") | upper}`, "CRASH/TYPO"},
+		{"length", "{issues | length}", "2"},
+		{"default missing", `{nope | default("n/a")}`, "n/a"},
+		{"missing", "{nope}", "<nope>"},
+		{"unknown filter", "{items | shout}", "<items | shout>"},
+		{"json", `{labels | json}`, `{"bug":3,"docs":1}`},
+		{"pipe in string", `{items | join(" | ")}`, "a | b | c"},
+		{
+			"if elif else",
+			"{% if len(items) > 5 %}many{% elif items %}some{% else %}none{% endif %}",
+			"some",
+		},
+		{
+			"for with loop vars",
+			"{% for i in issues %}{loop.index}. {i[\"title\"]}{% if not loop.last %}, {% endif %}{% endfor %}",
+			"1. Crash, 2. Typo",
+		},
+		{"for over dict", "{% for k, v in labels %}{k}={v};{% endfor %}", "bug=3;docs=1;"},
+		{"for else", "{% for i in empty %}x{% else %}nothing{% endfor %}", "nothing"},
+		{"nested", "{% for i in issues %}{% if i[\"state\"] == \"open\" %}{i[\"title\"]}{% endif %}{% endfor %}", "Crash"},
+		{"credential kept", "Auth: {{CREDENTIAL:github:token}}", "Auth: {{CREDENTIAL:github:token}}"},
+		{
+			"block lines removed",
+			"Open issues:\n{% for i in issues %}\n- {i[\"title\"]}\n{% endfor %}\nEnd",
+			"Open issues:\n- Crash\n- Typo\nEnd",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderTemplate(tt.tmpl, state)
+			if err != nil {
+				t.Fatalf("RenderTemplate: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTemplateErrors(t *testing.T) {
+	for tmpl, want := range map[string]string{
+		"{% if x %}open":               "not closed",
+		"{% for x in y %}a{% endif %}": "not closed",
+		"{% endfor %}":                 "unexpected",
+		"{% for in y %}{% endfor %}":   "invalid",
+		"{% while x %}":                "unknown tag",
+	} {
+		err := ParseTemplate(tmpl)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ParseTemplate(%q) = %v, want error containing %q", tmpl, err, want)
+		}
+	}
+	if err := ParseTemplate("{% if a %}{% for b in c %}{b}{% endfor %}{% endif %}"); err != nil {
+		t.Errorf("valid template rejected: %v", err)
+	}
+}
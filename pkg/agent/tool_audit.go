@@ -0,0 +1,161 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/SAP/astonish/pkg/audit"
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/tool"
+)
+
+// auditLog returns the log of tool executions and approval decisions
+// configured in security.audit_log, or nil.
+func (a *AstonishAgent) auditLog() *audit.Log {
+	if a.AppConfig == nil || !a.AppConfig.Security.AuditLog.Enabled {
+		return nil
+	}
+	path, err := a.AppConfig.Security.AuditLog.GetPath()
+	if err != nil {
+		slog.Warn("audit log disabled", "error", err)
+		return nil
+	}
+	return audit.Open(path)
+}
+
+// auditRecord starts the record of a call of toolName by node. The call
+// runs, so it was approved: by the approval profile, a setting, or the
+// user in the CLI or the web UI.
+func (a *AstonishAgent) auditRecord(ctx context.Context, node *config.Node, toolName string, args map[string]any) audit.Record {
+	r := audit.Record{
+		Time:     time.Now().UTC(),
+		Flow:     a.FlowName,
+		Node:     node.Name,
+		Tool:     toolName,
+		ArgsHash: approvalArgsHash(args),
+		Decision: audit.Approved,
+		Approver: a.auditApprover(),
+	}
+	switch {
+	case a.ApprovalPolicy.Decide(toolName, args) == config.ApprovalApprove:
+		r.Decision, r.Approver = audit.AutoApproved, a.ApprovalPolicy.approver()
+	case a.AutoApprove:
+		r.Decision, r.Approver = audit.AutoApproved, "auto_approve"
+	case node.ToolsAutoApproval:
+		r.Decision, r.Approver = audit.AutoApproved, "tools_auto_approval"
+	}
+	r.SessionID, r.User = auditSession(ctx)
+	return r
+}
+
+// auditApprover names the interface the user answers approvals in.
+func (a *AstonishAgent) auditApprover() string {
+	if a.IsWebMode {
+		return "web"
+	}
+	return "cli"
+}
+
+// auditSession returns the session and user a tool runs for.
+func auditSession(ctx context.Context) (sessionID, user string) {
+	switch c := ctx.(type) {
+	case tool.Context:
+		return c.SessionID(), c.UserID()
+	case agent.InvocationContext:
+		if s := c.Session(); s != nil {
+			return s.ID(), s.UserID()
+		}
+	}
+	return "", ""
+}
+
+// appendAudit completes r with the outcome of the call and appends it.
+// A log that cannot be written is reported and does not fail the call.
+func appendAudit(log *audit.Log, r audit.Record, result map[string]any, err error) {
+	r.DurationMs = time.Since(r.Time).Milliseconds()
+	r.Status = audit.StatusOK
+	if err != nil {
+		r.Status, r.Error = audit.StatusError, err.Error()
+	} else if e, ok := result["error"]; ok && e != nil {
+		r.Status = audit.StatusError
+	}
+	if err := log.Append(r); err != nil {
+		slog.Warn("failed to write audit log", "path", log.Path(), "error", err)
+	}
+}
+
+// auditDenial records a call of toolName by nodeName that approver
+// denied.
+func (a *AstonishAgent) auditDenial(ctx context.Context, nodeName, toolName string, args any, approver string) {
+	log := a.auditLog()
+	if log == nil {
+		return
+	}
+	r := audit.Record{
+		Flow:     a.FlowName,
+		Node:     nodeName,
+		Tool:     toolName,
+		ArgsHash: approvalArgsHash(args),
+		Decision: audit.Denied,
+		Approver: approver,
+		Status:   audit.StatusNotRun,
+	}
+	r.SessionID, r.User = auditSession(ctx)
+	if err := log.Append(r); err != nil {
+		slog.Warn("failed to write audit log", "path", log.Path(), "error", err)
+	}
+}
+
+// toolAudit records the tool calls of an LLM node in the audit log.
+type toolAudit struct {
+	a     *AstonishAgent
+	node  *config.Node
+	log   *audit.Log
+	calls sync.Map // FunctionCallID -> audit.Record
+}
+
+// newToolAudit returns the recorder of a node's tool calls, or nil when
+// the audit log is off.
+func (a *AstonishAgent) newToolAudit(node *config.Node) *toolAudit {
+	log := a.auditLog()
+	if log == nil {
+		return nil
+	}
+	return &toolAudit{a: a, node: node, log: log}
+}
+
+// begin is the BeforeToolCallback of an approved call. It goes before
+// credential substitution, so the args hash matches the approved args.
+func (t *toolAudit) begin() llmagent.BeforeToolCallback {
+	return func(ctx tool.Context, tl tool.Tool, args map[string]any) (map[string]any, error) {
+		t.calls.Store(ctx.FunctionCallID(), t.a.auditRecord(ctx, t.node, tl.Name(), args))
+		return nil, nil
+	}
+}
+
+// start is the BeforeToolCallback right before the tool runs, so the
+// duration leaves out the wait for a worker slot.
+func (t *toolAudit) start() llmagent.BeforeToolCallback {
+	return func(ctx tool.Context, tl tool.Tool, args map[string]any) (map[string]any, error) {
+		if v, ok := t.calls.Load(ctx.FunctionCallID()); ok {
+			r := v.(audit.Record)
+			r.Time = time.Now().UTC()
+			t.calls.Store(ctx.FunctionCallID(), r)
+		}
+		return nil, nil
+	}
+}
+
+// finish is the AfterToolCallback appending the record of the call.
+func (t *toolAudit) finish() llmagent.AfterToolCallback {
+	return func(ctx tool.Context, tl tool.Tool, args, result map[string]any, err error) (map[string]any, error) {
+		if v, ok := t.calls.LoadAndDelete(ctx.FunctionCallID()); ok {
+			appendAudit(t.log, v.(audit.Record), result, err)
+		}
+		return nil, nil
+	}
+}
@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/audit"
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/adk/session"
+	"google.golang.org/adk/tool"
+)
+
+func TestToolAudit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	appCfg := &config.AppConfig{}
+	appCfg.Security.AuditLog = config.AuditLogConfig{Enabled: true, Path: path}
+	a := &AstonishAgent{
+		AppConfig: appCfg,
+		FlowName:  "deploy",
+		Tools: []tool.Tool{&MockTool{
+			NameFunc: func() string { return "shell_command" },
+			RunFunc: func(ctx tool.Context, args any) (map[string]any, error) {
+				return nil, errors.New("exit status 1")
+			},
+		}},
+	}
+	node := &config.Node{
+		Name:              "run",
+		Type:              "tool",
+		ToolsSelection:    []string{"shell_command"},
+		ToolsAutoApproval: true,
+		ContinueOnError:   true,
+		Args:              map[string]any{"command": "make deploy"},
+	}
+	state := NewMockState()
+	a.handleToolNode(context.Background(), node, state, func(*session.Event, error) bool { return true })
+
+	a.auditDenial(context.Background(), "run", "shell_command", map[string]any{"command": "rm -rf build"}, "cli")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []audit.Record
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var r audit.Record
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+		records = append(records, r)
+	}
+	if len(records) != 2 {
+		t.Fatalf("records = %+v, want the execution and the denial", records)
+	}
+	ran, denied := records[0], records[1]
+	if ran.Flow != "deploy" || ran.Node != "run" || ran.Decision != audit.AutoApproved || ran.Approver != "tools_auto_approval" ||
+		ran.Status != audit.StatusError || ran.Error != "exit status 1" || ran.ArgsHash != approvalArgsHash(map[string]any{"command": "make deploy"}) {
+		t.Errorf("execution = %+v", ran)
+	}
+	if denied.Decision != audit.Denied || denied.Approver != "cli" || denied.Status != audit.StatusNotRun || denied.ArgsHash == ran.ArgsHash {
+		t.Errorf("denial = %+v", denied)
+	}
+	if info, err := os.Stat(path); err != nil {
+		t.Error(err)
+	} else if info.Mode().Perm() != 0o600 {
+		t.Errorf("log mode = %v, want 0600", info.Mode().Perm())
+	}
+}
@@ -11,6 +11,8 @@ var SafeTools = map[string]bool{
 	"read_file":                 true,
 	"file_tree":                 true,
 	"find_files":                true,
+	"list_directory":            true,
+	"glob":                      true,
 	"grep_search":               true,
 	"git_diff_add_line_numbers": true,
 	"filter_json":               true,
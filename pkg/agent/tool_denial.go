@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/SAP/astonish/pkg/planner"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// toolDenialsKey counts the denied tool calls of the current node
+// execution, for nodes with on_tool_denied: feedback.
+const toolDenialsKey = "_tool_denials"
+
+// maxToolDenials bounds the denials a node is told about before the flow
+// moves on as with on_tool_denied: skip, so a model that keeps proposing
+// denied calls (or a headless run that denies everything) cannot loop.
+const maxToolDenials = 3
+
+// nextToolDenial returns the number of a new denial in the current node
+// execution, counting from 1.
+func nextToolDenial(state session.State) int {
+	execID, _ := stateString(state, nodeExecutionIDKey)
+	if v, _ := state.Get(toolDenialsKey); v != nil {
+		if prev, ok := v.(map[string]any); ok && prev["node_execution_id"] == execID {
+			return toInt(prev["count"]) + 1
+		}
+	}
+	return 1
+}
+
+// feedToolDenial continues the current node after the user denied a tool
+// call, telling the model the call did not run so it can propose another
+// approach. denials is the number of the denial, from nextToolDenial.
+func (a *AstonishAgent) feedToolDenial(ctx agent.InvocationContext, state session.State, toolName, answer string, denials int, yield func(*session.Event, error) bool) bool {
+	execID, _ := stateString(state, nodeExecutionIDKey)
+	args, _ := state.Get("approval_args")
+	feedback := toolDenialFeedback(toolName, args, answer)
+	counter := map[string]any{"node_execution_id": execID, "count": denials}
+	state.Set("awaiting_approval", false)
+	state.Set("approval_tool", "")
+	state.Set("approval_args", nil)
+	state.Set(toolDenialsKey, counter)
+
+	// The ReAct fallback resumes from its saved history; the ADK path sees
+	// the feedback as the user's turn
+	planner.DenyPendingAction(state, feedback)
+	ctx.UserContent().Parts = []*genai.Part{{Text: feedback}}
+
+	return yield(&session.Event{
+		LLMResponse: model.LLMResponse{
+			Content: &genai.Content{
+				Parts: []*genai.Part{{
+					Text: "[ℹ️ Info] Tool execution denied by user. Asking the model for another approach.\n",
+				}},
+				Role: "model",
+			},
+		},
+		Actions: session.EventActions{
+			StateDelta: map[string]any{
+				"awaiting_approval": false,
+				toolDenialsKey:      counter,
+			},
+		},
+	}, nil)
+}
+
+// toolDenialFeedback is the message telling the model its tool call was
+// denied. An answer other than a plain no is passed on, since users often
+// say what to do instead.
+func toolDenialFeedback(toolName string, args any, answer string) string {
+	argsJSON, err := json.Marshal(args)
+	if err != nil {
+		argsJSON = fmt.Appendf(nil, "%v", args)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "The user DENIED the call to the tool '%s' with arguments %s, so it did not run. ", toolName, argsJSON)
+	sb.WriteString("A 'pending_approval' result of that call does not mean it was approved. ")
+	sb.WriteString("Do not repeat the same call. Complete the task another way - different arguments, another tool, or an answer without it - and briefly say what you do instead.")
+	switch strings.ToLower(answer) {
+	case "", "no", "n":
+	default:
+		fmt.Fprintf(&sb, " The user said: %q", answer)
+	}
+	return sb.String()
+}
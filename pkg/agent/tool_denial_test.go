@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+func TestToolDenialFeedback(t *testing.T) {
+	cfg := &config.AgentConfig{
+		Nodes: []config.Node{{Name: "cleanup", Type: "llm", Tools: true, OnToolDenied: config.ToolDeniedFeedback}},
+		Flow: []config.FlowItem{
+			{From: "START", To: "cleanup"},
+			{From: "cleanup", To: "END"},
+		},
+	}
+	a := NewAstonishAgent(cfg, nil, nil)
+	state := NewMockState()
+	state.Set("current_node", "cleanup")
+	state.Set(nodeExecutionIDKey, "abc:cleanup:1")
+	yield := func(*session.Event, error) bool { return true }
+	deny := func(answer string) *answerContext {
+		state.Set("awaiting_approval", true)
+		state.Set("approval_tool", "shell_command")
+		state.Set("approval_args", map[string]any{"command": "rm -rf /var/log"})
+		ctx := &answerContext{
+			MockInvocationContext: &MockInvocationContext{Context: context.Background(), StateVal: state},
+			content:               genai.NewContentFromText(answer, genai.RoleUser),
+		}
+		if !a.handleToolApproval(ctx, state, yield) {
+			t.Fatalf("denial %q stopped the run", answer)
+		}
+		return ctx
+	}
+
+	// The node continues and the model is told what was denied
+	ctx := deny("No, only delete files older than a week")
+	if node, _ := state.Get("current_node"); node != "cleanup" {
+		t.Errorf("current_node = %v, want the node to continue", node)
+	}
+	feedback := ctx.content.Parts[0].Text
+	for _, want := range []string{"DENIED", "shell_command", "rm -rf /var/log", `"No, only delete files older than a week"`} {
+		if !strings.Contains(feedback, want) {
+			t.Errorf("feedback does not contain %q:\n%s", want, feedback)
+		}
+	}
+	if strings.Contains(toolDenialFeedback("shell_command", nil, "no"), "The user said") {
+		t.Error("a plain no was quoted back")
+	}
+
+	// After maxToolDenials the flow moves on
+	for range maxToolDenials - 1 {
+		deny("no")
+	}
+	if node, _ := state.Get("current_node"); node != "cleanup" {
+		t.Fatalf("current_node = %v before the denial limit", node)
+	}
+	deny("no")
+	if node, _ := state.Get("current_node"); node != "END" {
+		t.Errorf("current_node = %v, want the flow to move on after %d denials", node, maxToolDenials)
+	}
+
+	// A new execution of the node starts counting again
+	state.Set("current_node", "cleanup")
+	state.Set(nodeExecutionIDKey, "abc:cleanup:2")
+	if n := nextToolDenial(state); n != 1 {
+		t.Errorf("nextToolDenial = %d in a new execution, want 1", n)
+	}
+}
@@ -0,0 +1,124 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// maxSummarizeInputChars caps the tool output sent to a summarization pass,
+// so summarizing cannot itself overflow the model's context.
+const maxSummarizeInputChars = 400_000
+
+var toolOutputKeyInvalid = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// toolOutputLimit returns the max_tool_output_tokens of a node, falling back
+// to the flow-level setting. 0 means no limit.
+func (a *AstonishAgent) toolOutputLimit(node *config.Node) int {
+	if node.MaxToolOutputTokens > 0 {
+		return node.MaxToolOutputTokens
+	}
+	if a.Config != nil {
+		return a.Config.MaxToolOutputTokens
+	}
+	return 0
+}
+
+// estimateResultTokens estimates the tokens of a tool result with the same
+// ~3 characters per token heuristic as session compaction.
+func estimateResultTokens(data []byte) int {
+	return len(data) / 3
+}
+
+// toolOutputLimiter cuts down the tool results of one LLM node execution
+// that exceed max_tool_output_tokens. The full result is kept in state under
+// <node>_<tool>_output_<n> and the model gets a truncated or summarized
+// version that names the key.
+type toolOutputLimiter struct {
+	a     *AstonishAgent
+	node  *config.Node
+	limit int
+	seq   atomic.Int32
+}
+
+// newToolOutputLimiter returns the limiter of a node, or nil without a limit.
+func (a *AstonishAgent) newToolOutputLimiter(node *config.Node) *toolOutputLimiter {
+	limit := a.toolOutputLimit(node)
+	if limit <= 0 {
+		return nil
+	}
+	return &toolOutputLimiter{a: a, node: node, limit: limit}
+}
+
+// apply returns result unchanged when it fits. Otherwise it returns the
+// replacement for the model and the state delta holding the full result.
+func (l *toolOutputLimiter) apply(ctx context.Context, toolName string, result map[string]any) (map[string]any, map[string]any) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return result, nil
+	}
+	tokens := estimateResultTokens(data)
+	if tokens <= l.limit {
+		return result, nil
+	}
+
+	key := fmt.Sprintf("%s_%s_output_%d", l.node.Name, toolName, l.seq.Add(1))
+	key = strings.Trim(toolOutputKeyInvalid.ReplaceAllString(key, "_"), "_")
+	notice := fmt.Sprintf("Output of about %d tokens exceeds max_tool_output_tokens (%d). The full result is stored in state under '%s'.", tokens, l.limit, key)
+
+	replacement := map[string]any{"_truncated": notice}
+	summarized := false
+	if l.node.ToolOutputOverflow == "summarize" {
+		if summary, err := l.summarize(ctx, toolName, data); err == nil && summary != "" {
+			replacement["summary"] = summary
+			summarized = true
+		} else {
+			slog.Warn("tool output summarization failed, truncating", "node", l.node.Name, "tool", toolName, "error", err)
+		}
+	}
+	if !summarized {
+		replacement["output"] = strings.ToValidUTF8(string(data[:l.limit*3]), "")
+	}
+	slog.Info("limited tool output", "node", l.node.Name, "tool", toolName, "tokens", tokens, "limit", l.limit, "summarized", summarized, "state_key", key)
+	return replacement, map[string]any{key: result}
+}
+
+// summarize asks the flow's model to condense a tool result to the limit.
+func (l *toolOutputLimiter) summarize(ctx context.Context, toolName string, data []byte) (string, error) {
+	if l.a.LLM == nil {
+		return "", fmt.Errorf("no model available")
+	}
+	payload := string(data)
+	if len(payload) > maxSummarizeInputChars {
+		payload = payload[:maxSummarizeInputChars]
+	}
+	prompt := fmt.Sprintf("Summarize the following output of the tool '%s' in at most %d words. "+
+		"Keep identifiers, numbers, names, URLs and error messages that a follow-up step may need. "+
+		"Reply with the summary only.\n\n%s", toolName, l.limit*3/4, payload)
+
+	req := &model.LLMRequest{
+		Contents: []*genai.Content{{Parts: []*genai.Part{{Text: prompt}}, Role: "user"}},
+	}
+	var sb strings.Builder
+	for resp, err := range l.a.LLM.GenerateContent(ctx, req, false) {
+		if err != nil {
+			return "", err
+		}
+		if resp.Content != nil {
+			for _, part := range resp.Content.Parts {
+				if !part.Thought {
+					sb.WriteString(part.Text)
+				}
+			}
+		}
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
@@ -0,0 +1,45 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/genai"
+)
+
+func TestToolOutputLimiter(t *testing.T) {
+	big := map[string]any{"stdout": strings.Repeat("line of log output\n", 200)}
+	small := map[string]any{"stdout": "ok"}
+
+	a := &AstonishAgent{Config: &config.AgentConfig{MaxToolOutputTokens: 100}}
+	node := &config.Node{Name: "triage"}
+	l := a.newToolOutputLimiter(node)
+	if l == nil || l.limit != 100 {
+		t.Fatalf("flow-level limit not applied: %+v", l)
+	}
+	if out, delta := l.apply(context.Background(), "shell_command", small); delta != nil || out["stdout"] != "ok" {
+		t.Errorf("small result changed: %v %v", out, delta)
+	}
+
+	out, delta := l.apply(context.Background(), "shell_command", big)
+	full, ok := delta["triage_shell_command_output_1"]
+	if !ok || full.(map[string]any)["stdout"] != big["stdout"] {
+		t.Fatalf("full result not stored: %v", delta)
+	}
+	if s, _ := out["output"].(string); len(s) != 300 {
+		t.Errorf("truncated output is %d chars, want 300", len(s))
+	}
+	if !strings.Contains(out["_truncated"].(string), "triage_shell_command_output_1") {
+		t.Errorf("notice does not name the state key: %v", out["_truncated"])
+	}
+
+	model := &ADKMockModel{Responses: []*genai.Content{genai.NewContentFromText("200 log lines, no errors", "model")}}
+	a = &AstonishAgent{LLM: model}
+	l = a.newToolOutputLimiter(&config.Node{Name: "triage", MaxToolOutputTokens: 100, ToolOutputOverflow: "summarize"})
+	out, delta = l.apply(context.Background(), "shell_command", big)
+	if out["summary"] != "200 log lines, no errors" || out["output"] != nil || len(delta) != 1 {
+		t.Errorf("summarized result = %v, delta keys %d", out, len(delta))
+	}
+}
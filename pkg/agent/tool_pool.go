@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"sync"
+
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/tool"
+)
+
+// defaultMaxParallelTools bounds the tool calls of one LLM turn that run at
+// the same time when the node sets no max_parallel_tools.
+const defaultMaxParallelTools = 4
+
+// toolWorkerPool bounds how many tool calls of an LLM node run at once.
+//
+// ADK runs the function calls of one model response in their own goroutines
+// and merges the responses in call order. The pool turns that into a bounded
+// worker pool: a call takes a slot in the last BeforeToolCallback, once
+// approval and credential callbacks have let it through, and frees it in the
+// first AfterToolCallback. Calls short-circuited by an earlier callback never
+// take a slot.
+type toolWorkerPool struct {
+	slots chan struct{}
+	held  sync.Map // FunctionCallID -> struct{}
+}
+
+// newToolWorkerPool returns the pool of a node.
+func newToolWorkerPool(node *config.Node) *toolWorkerPool {
+	size := node.MaxParallelTools
+	if size <= 0 {
+		size = defaultMaxParallelTools
+	}
+	return &toolWorkerPool{slots: make(chan struct{}, size)}
+}
+
+// acquire is the BeforeToolCallback waiting for a free slot.
+func (p *toolWorkerPool) acquire() llmagent.BeforeToolCallback {
+	return func(ctx tool.Context, t tool.Tool, args map[string]any) (map[string]any, error) {
+		select {
+		case p.slots <- struct{}{}:
+			p.held.Store(ctx.FunctionCallID(), struct{}{})
+			return nil, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// release is the AfterToolCallback freeing the call's slot.
+func (p *toolWorkerPool) release() llmagent.AfterToolCallback {
+	return func(ctx tool.Context, t tool.Tool, args, result map[string]any, err error) (map[string]any, error) {
+		if _, ok := p.held.LoadAndDelete(ctx.FunctionCallID()); ok {
+			<-p.slots
+		}
+		return nil, nil
+	}
+}
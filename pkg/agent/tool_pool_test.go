@@ -0,0 +1,63 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SAP/astonish/pkg/config"
+)
+
+type callToolContext struct {
+	stubToolContext
+	id string
+}
+
+func (c callToolContext) FunctionCallID() string { return c.id }
+
+func TestToolWorkerPool_BoundsConcurrentCalls(t *testing.T) {
+	pool := newToolWorkerPool(&config.Node{MaxParallelTools: 2})
+	acquire, release := pool.acquire(), pool.release()
+
+	var running, peak atomic.Int32
+	var wg sync.WaitGroup
+	for i := range 6 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := callToolContext{stubToolContext{context.Background()}, fmt.Sprint("call-", i)}
+			if _, err := acquire(ctx, nil, nil); err != nil {
+				t.Error(err)
+				return
+			}
+			n := running.Add(1)
+			for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+			}
+			time.Sleep(10 * time.Millisecond)
+			running.Add(-1)
+			release(ctx, nil, nil, nil, nil)
+		}()
+	}
+	wg.Wait()
+	if got := peak.Load(); got != 2 {
+		t.Errorf("peak concurrent calls = %d, want 2", got)
+	}
+
+	// A call short-circuited before acquiring (e.g. pending approval) must
+	// not free a slot it never took.
+	release(callToolContext{stubToolContext{context.Background()}, "skipped"}, nil, nil, nil, nil)
+	if len(pool.slots) != 0 {
+		t.Errorf("%d slots still held", len(pool.slots))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	full := newToolWorkerPool(&config.Node{MaxParallelTools: 1})
+	full.slots <- struct{}{}
+	cancel()
+	if _, err := full.acquire()(callToolContext{stubToolContext{ctx}, "late"}, nil, nil); err == nil {
+		t.Error("acquire should give up when the context is cancelled")
+	}
+}
@@ -0,0 +1,85 @@
+package agent
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/SAP/astonish/pkg/cache"
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/credentials"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/tool"
+)
+
+// toolResultTTL returns how long a node reuses cached tool results, or 0
+// when cache_tool_results is unset or invalid.
+func toolResultTTL(node *config.Node) time.Duration {
+	if node.CacheToolResults == "" {
+		return 0
+	}
+	ttl, err := time.ParseDuration(node.CacheToolResults)
+	if err != nil || ttl <= 0 {
+		slog.Warn("invalid cache_tool_results, caching disabled", "node", node.Name, "value", node.CacheToolResults)
+		return 0
+	}
+	return ttl
+}
+
+// toolResultCache serves the tool calls of an LLM node from pkg/cache.
+//
+// lookup is the first BeforeToolCallback, so it sees the args as the model
+// sent them (before credential substitution) and a hit skips approval and
+// execution. store runs right after the worker pool's release and saves the
+// redacted result of calls that missed under the key computed by lookup.
+type toolResultCache struct {
+	node     string
+	ttl      time.Duration
+	redactor *credentials.Redactor
+	keys     sync.Map // FunctionCallID -> cache key of a miss
+}
+
+// newToolResultCache returns the cache of a node, or nil when the node does
+// not cache tool results.
+func (a *AstonishAgent) newToolResultCache(node *config.Node) *toolResultCache {
+	ttl := toolResultTTL(node)
+	if ttl == 0 {
+		return nil
+	}
+	return &toolResultCache{node: node.Name, ttl: ttl, redactor: a.Redactor}
+}
+
+// lookup is the BeforeToolCallback returning a fresh cached result.
+func (c *toolResultCache) lookup() llmagent.BeforeToolCallback {
+	return func(ctx tool.Context, t tool.Tool, args map[string]any) (map[string]any, error) {
+		key := cache.ToolResultKey(t.Name(), args)
+		if result, ok := cache.GetToolResult(key, c.ttl); ok {
+			slog.Debug("tool result served from cache", "node", c.node, "tool", t.Name())
+			return result, nil
+		}
+		c.keys.Store(ctx.FunctionCallID(), key)
+		return nil, nil
+	}
+}
+
+// store is the AfterToolCallback caching successful results.
+func (c *toolResultCache) store() llmagent.AfterToolCallback {
+	return func(ctx tool.Context, t tool.Tool, args, result map[string]any, err error) (map[string]any, error) {
+		key, ok := c.keys.LoadAndDelete(ctx.FunctionCallID())
+		if !ok || err != nil || result == nil || result["error"] != nil {
+			return nil, nil
+		}
+		storeToolResult(c.redactor, c.node, key.(string), t.Name(), result)
+		return nil, nil
+	}
+}
+
+// storeToolResult saves a tool result with credential values redacted.
+func storeToolResult(redactor *credentials.Redactor, node, key, toolName string, result map[string]any) {
+	if redactor != nil {
+		result = redactor.RedactMap(result)
+	}
+	if err := cache.PutToolResult(key, toolName, result); err != nil {
+		slog.Warn("failed to cache tool result", "node", node, "tool", toolName, "error", err)
+	}
+}
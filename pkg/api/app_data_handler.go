@@ -12,9 +12,9 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
-	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/SAP/astonish/pkg/apps"
 	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/mcp"
 	"github.com/SAP/astonish/pkg/sandbox"
 	"github.com/SAP/astonish/pkg/store"
 	"google.golang.org/adk/agent"
@@ -354,8 +354,8 @@ func resolveMCPSource(r *http.Request, serverTool string, args map[string]any) (
 	}
 
 	// SSE/remote servers: direct network connection (no local exec)
-	if serverCfg.Transport == "sse" || serverCfg.URL != "" {
-		return invokeMCPToolSSE(r.Context(), serverName, toolName, serverCfg, args)
+	if serverCfg.IsRemote() || serverCfg.URL != "" {
+		return invokeMCPToolRemote(r.Context(), serverName, toolName, serverCfg, args)
 	}
 
 	// Stdio servers: MUST run inside a sandbox container
@@ -444,28 +444,33 @@ func invokeMCPToolInContainer(r *http.Request, userID, serverName, toolName stri
 	return result, nil
 }
 
-// invokeMCPToolSSE invokes an MCP tool on a remote SSE server (no local exec).
-func invokeMCPToolSSE(ctx context.Context, serverName, toolName string, serverCfg config.MCPServerConfig, args map[string]any) (any, error) {
+// invokeMCPToolRemote invokes an MCP tool on a remote SSE or Streamable HTTP
+// server (no local exec). A URL without a transport is treated as SSE.
+func invokeMCPToolRemote(ctx context.Context, serverName, toolName string, serverCfg config.MCPServerConfig, args map[string]any) (any, error) {
 	if serverCfg.URL == "" {
-		return nil, fmt.Errorf("MCP server %q has SSE transport but no URL configured", serverName)
+		return nil, fmt.Errorf("MCP server %q has %s transport but no URL configured", serverName, serverCfg.Transport)
+	}
+	if !serverCfg.IsRemote() {
+		serverCfg.Transport = "sse"
 	}
 
-	transport := &mcpsdk.SSEClientTransport{
-		Endpoint: serverCfg.URL,
+	transport, err := mcp.NewTransport(serverCfg)
+	if err != nil {
+		return nil, fmt.Errorf("MCP server %q: %w", serverName, err)
 	}
 
 	toolset, err := mcptoolset.New(mcptoolset.Config{
 		Transport: transport,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to SSE MCP server %q at %s: %w", serverName, serverCfg.URL, err)
+		return nil, fmt.Errorf("failed to connect to remote MCP server %q at %s: %w", serverName, serverCfg.URL, err)
 	}
 
 	// Get tools
 	toolCtx := &appMCPToolContext{Context: ctx}
 	tools, err := toolset.Tools(toolCtx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list tools from SSE server %q: %w", serverName, err)
+		return nil, fmt.Errorf("failed to list tools from remote server %q: %w", serverName, err)
 	}
 
 	// Find and invoke
@@ -482,7 +487,7 @@ func invokeMCPToolSSE(ctx context.Context, serverName, toolName string, serverCf
 		}
 	}
 	if targetTool == nil {
-		return nil, fmt.Errorf("tool %q not found on SSE server %q", toolName, serverName)
+		return nil, fmt.Errorf("tool %q not found on remote server %q", toolName, serverName)
 	}
 
 	runner, ok := targetTool.(interface {
@@ -492,10 +497,10 @@ func invokeMCPToolSSE(ctx context.Context, serverName, toolName string, serverCf
 		return nil, fmt.Errorf("tool %q on %q does not implement Run", toolName, serverName)
 	}
 
-	slog.Debug("app MCP SSE invoke", "server", serverName, "tool", toolName, "url", serverCfg.URL)
+	slog.Debug("app MCP remote invoke", "server", serverName, "tool", toolName, "url", serverCfg.URL)
 	result, err := runner.Run(toolCtx, args)
 	if err != nil {
-		return nil, fmt.Errorf("SSE MCP tool %q returned error: %w", toolName, err)
+		return nil, fmt.Errorf("remote MCP tool %q returned error: %w", toolName, err)
 	}
 	return result, nil
 }
@@ -975,6 +975,7 @@ func (cr *ChatRunner) processStateDelta(delta map[string]any) {
 				"reason":        failureInfo["reason"],
 				"suggestion":    failureInfo["suggestion"],
 				"originalError": failureInfo["original_error"],
+				"errorClass":    failureInfo["error_class"],
 			})
 		}
 	}
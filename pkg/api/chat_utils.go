@@ -1074,7 +1074,7 @@ func persistRunError(ctx context.Context, svc session.Service, userID, sessionID
 		},
 	}
 
-	if err := svc.AppendEvent(ctx, resp.Session, errorEvent); err != nil {
+	if err := persistentsession.SharedAppender(svc).Append(ctx, resp.Session, errorEvent); err != nil {
 		slog.Error("failed to append error event to session", "component", "persistRunError", "session_id", sessionID, "error", err)
 	}
 }
@@ -1113,7 +1113,7 @@ func persistSessionMessage(ctx context.Context, svc session.Service, userID, ses
 		},
 	}
 
-	if err := svc.AppendEvent(ctx, resp.Session, event); err != nil {
+	if err := persistentsession.SharedAppender(svc).Append(ctx, resp.Session, event); err != nil {
 		slog.Error("failed to append event to session", "component", "persistSessionMessage", "session_id", sessionID, "role", role, "error", err)
 	}
 }
@@ -0,0 +1,202 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+const (
+	// maxGenerateAttempts bounds the draft/validate/fix rounds of GenerateFlow.
+	maxGenerateAttempts = 3
+	// toolSelectionThreshold is the number of available tools above which
+	// GenerateFlow first asks the model which ones the flow needs, so large
+	// MCP setups do not flood the generation prompt.
+	toolSelectionThreshold = 40
+)
+
+// GeneratedFlow is the result of GenerateFlow.
+type GeneratedFlow struct {
+	YAML     string   // Last drafted flow (empty if the model never produced one)
+	Errors   []string // Validation errors of YAML; empty when it is valid
+	Attempts int      // Number of drafts requested
+	Tools    []string // Tools offered to the model
+}
+
+// GenerateFlow drafts a complete flow for description with llm, validates it
+// against the flow schema and availableTools, and feeds validation errors
+// back to the model until the flow is valid or the attempts run out. status,
+// if set, receives progress messages.
+func GenerateFlow(ctx context.Context, llm model.LLM, description string, availableTools []ToolInfo, status func(string)) (*GeneratedFlow, error) {
+	if status == nil {
+		status = func(string) {}
+	}
+
+	offered := availableTools
+	if len(availableTools) > toolSelectionThreshold {
+		status(fmt.Sprintf("Selecting tools from %d available...", len(availableTools)))
+		offered = selectToolsForFlow(ctx, llm, description, availableTools)
+	}
+	result := &GeneratedFlow{}
+	for _, t := range offered {
+		result.Tools = append(result.Tools, t.Name)
+	}
+
+	req := &model.LLMRequest{
+		Contents: []*genai.Content{{
+			Role:  "user",
+			Parts: []*genai.Part{genai.NewPartFromText("Create a flow that does the following:\n" + description)},
+		}},
+		Config: &genai.GenerateContentConfig{
+			SystemInstruction: &genai.Content{
+				Parts: []*genai.Part{genai.NewPartFromText(generateFlowPrompt(offered))},
+			},
+			Temperature: genai.Ptr(float32(0.3)),
+		},
+	}
+
+	for result.Attempts < maxGenerateAttempts {
+		result.Attempts++
+		status(fmt.Sprintf("Drafting flow (attempt %d/%d)...", result.Attempts, maxGenerateAttempts))
+
+		response, err := generateText(ctx, llm, req)
+		if err != nil {
+			return result, fmt.Errorf("LLM request failed: %w", err)
+		}
+		req.Contents = append(req.Contents, &genai.Content{
+			Role:  "model",
+			Parts: []*genai.Part{genai.NewPartFromText(response)},
+		})
+
+		draft := extractYAML(response)
+		if draft == "" {
+			req.Contents = append(req.Contents, &genai.Content{
+				Role:  "user",
+				Parts: []*genai.Part{genai.NewPartFromText("Reply with the complete flow in a single ```yaml code block.")},
+			})
+			continue
+		}
+		result.YAML = draft
+
+		// Validation errors are checked against every available tool, not
+		// just the offered ones, so a model that knows a better tool is not
+		// penalized for using it.
+		validation := ValidateFlowYAML(draft, availableTools)
+		result.Errors = validation.Errors
+		if validation.Valid {
+			return result, nil
+		}
+		status(fmt.Sprintf("Draft has %d validation error(s), asking for a fix...", len(validation.Errors)))
+		req.Contents = append(req.Contents, &genai.Content{
+			Role:  "user",
+			Parts: []*genai.Part{genai.NewPartFromText(FormatValidationErrors(validation.Errors))},
+		})
+	}
+
+	if result.YAML == "" {
+		return result, fmt.Errorf("the model did not produce a flow after %d attempts", maxGenerateAttempts)
+	}
+	return result, nil
+}
+
+// generateFlowPrompt is the system instruction for one-shot flow generation:
+// the flow schema, the tools the flow may use and the expected answer format.
+func generateFlowPrompt(tools []ToolInfo) string {
+	var toolsList strings.Builder
+	for _, t := range tools {
+		toolsList.WriteString("- " + t.Name + ": " + t.Description + " (source: " + t.Source + ")\n")
+	}
+	if len(tools) == 0 {
+		toolsList.WriteString("(none - design the flow without tools)\n")
+	}
+
+	return GetFlowSchema() + `
+
+# Available Tools
+ONLY use tools from this list. Do NOT invent or hallucinate tool names.
+` + toolsList.String() + `
+# Your Task
+Design a complete, minimal flow for the user's request. There is no
+conversation: do not ask questions, make reasonable assumptions instead.
+- Give the flow a snake_case name and a one-sentence description
+- Prefer LLM nodes with tools: true and tools_selection over tool nodes
+- Add input nodes for data the tools need that the request does not provide
+- Add user_message to every LLM node whose result the user should see
+- Branch only on input node options, never on LLM output
+
+Reply with the complete flow YAML in a single ` + "```yaml" + ` code block.`
+}
+
+// selectToolsForFlow asks the model which of the available tools a flow for
+// description needs. LLM-returned names are matched back to the list the
+// same way as store search results; on any failure all tools are offered.
+func selectToolsForFlow(ctx context.Context, llm model.LLM, description string, availableTools []ToolInfo) []ToolInfo {
+	var toolList strings.Builder
+	names := make([]string, len(availableTools))
+	for i, t := range availableTools {
+		names[i] = t.Name
+		toolList.WriteString("- " + t.Name + ": " + t.Description + "\n")
+	}
+
+	prompt := fmt.Sprintf(`You are choosing the tools an automation workflow needs.
+
+WORKFLOW: %s
+
+AVAILABLE TOOLS:
+%s
+Pick every tool the workflow could reasonably use (when in doubt, include it).
+
+Respond in JSON format:
+{"tools": ["EXACT tool name from the list above"]}`, description, toolList.String())
+
+	response, err := generateText(ctx, llm, &model.LLMRequest{
+		Contents: []*genai.Content{{Role: "user", Parts: []*genai.Part{genai.NewPartFromText(prompt)}}},
+		Config:   &genai.GenerateContentConfig{Temperature: genai.Ptr(float32(0.1))},
+	})
+	if err != nil {
+		return availableTools
+	}
+
+	start, end := strings.Index(response, "{"), strings.LastIndex(response, "}")
+	if start == -1 || end <= start {
+		return availableTools
+	}
+	var parsed struct {
+		Tools []string `json:"tools"`
+	}
+	if err := json.Unmarshal([]byte(response[start:end+1]), &parsed); err != nil {
+		return availableTools
+	}
+
+	var selected []ToolInfo
+	seen := make(map[int]bool)
+	for _, name := range parsed.Tools {
+		if idx := bestNameMatch(name, names); idx >= 0 && !seen[idx] {
+			seen[idx] = true
+			selected = append(selected, availableTools[idx])
+		}
+	}
+	return selected
+}
+
+// generateText runs req and returns the concatenated text of the response.
+func generateText(ctx context.Context, llm model.LLM, req *model.LLMRequest) (string, error) {
+	var text strings.Builder
+	for resp, err := range llm.GenerateContent(ctx, req, false) {
+		if err != nil {
+			return "", err
+		}
+		if resp != nil && resp.Content != nil {
+			for _, part := range resp.Content.Parts {
+				if part.Text != "" && !part.Thought {
+					text.WriteString(part.Text)
+				}
+			}
+		}
+	}
+	return text.String(), nil
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const generatedFlowYAML = "```yaml\nname: review_pr\ndescription: Review a PR\nnodes:\n  - name: review\n    type: llm\n    prompt: Review the PR\n    tools: true\n    tools_selection:\n      - %s\n    user_message:\n      - summary\n    output_model:\n      summary: str\nflow:\n  - from: START\n    to: review\n  - from: review\n    to: END\n```"
+
+func TestGenerateFlowRetriesOnValidationErrors(t *testing.T) {
+	tools := []ToolInfo{{Name: "get_pull_request_diff", Description: "Get a PR diff", Source: "github"}}
+	llm := NewMockLLM(
+		TextTurn("Here you go:\n"+fmt.Sprintf(generatedFlowYAML, "get_pr_diff")),
+		TextTurn(fmt.Sprintf(generatedFlowYAML, "get_pull_request_diff")),
+	)
+
+	var statuses []string
+	result, err := GenerateFlow(context.Background(), llm, "review a PR", tools, func(s string) { statuses = append(statuses, s) })
+	if err != nil {
+		t.Fatalf("GenerateFlow: %v", err)
+	}
+	if result.Attempts != 2 || len(result.Errors) != 0 {
+		t.Fatalf("expected a valid flow on the second attempt, got %+v", result)
+	}
+	if !strings.Contains(result.YAML, "get_pull_request_diff") {
+		t.Errorf("unexpected YAML:\n%s", result.YAML)
+	}
+
+	// The retry carries the first draft and its validation errors
+	retry := llm.Calls[1].Contents
+	if len(retry) < 3 || retry[1].Role != "model" || !strings.Contains(retry[2].Parts[0].Text, "get_pr_diff") {
+		t.Errorf("expected validation feedback naming the unknown tool, got %+v", retry)
+	}
+	if len(statuses) == 0 {
+		t.Error("expected status messages")
+	}
+}
+
+func TestGenerateFlowGivesUpWithoutYAML(t *testing.T) {
+	llm := NewMockLLM(TextTurn("I need more details."), TextTurn("Still no."), TextTurn("Sorry."))
+	result, err := GenerateFlow(context.Background(), llm, "do something", nil, nil)
+	if err == nil || result.Attempts != maxGenerateAttempts {
+		t.Errorf("expected failure after %d attempts, got %+v, %v", maxGenerateAttempts, result, err)
+	}
+}
+
+func TestSelectToolsForFlow(t *testing.T) {
+	var tools []ToolInfo
+	for i := 0; i < toolSelectionThreshold+1; i++ {
+		tools = append(tools, ToolInfo{Name: fmt.Sprintf("tool_%d", i)})
+	}
+	tools = append(tools, ToolInfo{Name: "list_pull_requests"})
+
+	llm := NewMockLLM(TextTurn(`{"tools": ["List-Pull-Requests", "tool_3", "made_up_tool_xyz"]}`))
+	selected := selectToolsForFlow(context.Background(), llm, "triage PRs", tools)
+	if len(selected) != 2 || selected[0].Name != "list_pull_requests" || selected[1].Name != "tool_3" {
+		t.Errorf("unexpected selection %+v", selected)
+	}
+
+	// Unparseable answers fall back to offering every tool
+	llm = NewMockLLM(TextTurn("no idea"))
+	if selected := selectToolsForFlow(context.Background(), llm, "triage PRs", tools); len(selected) != len(tools) {
+		t.Errorf("expected all tools on fallback, got %d", len(selected))
+	}
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// flowLoopErrors finds the loops of a flow that can never be left: groups
+// of nodes that reach each other and have no edge, conditional or not, to
+// a node outside the group or to END. A run entering one would go on until
+// max_node_visits stops it. Loops through an input node wait for the user
+// on every round and are allowed (e.g. a REPL), as are loops through a
+// script node, which can stop the run with _abort.
+func flowLoopErrors(nodes []interface{}, flowEdges []interface{}) []string {
+	nodeTypes := make(map[string]string)
+	var order []string
+	for _, n := range nodes {
+		node, _ := n.(map[string]interface{})
+		name, _ := node["name"].(string)
+		if name == "" {
+			continue
+		}
+		nodeType, _ := node["type"].(string)
+		nodeTypes[name] = nodeType
+		order = append(order, name)
+	}
+
+	next := make(map[string][]string)
+	for _, e := range flowEdges {
+		edge, _ := e.(map[string]interface{})
+		from, _ := edge["from"].(string)
+		if to, _ := edge["to"].(string); to != "" {
+			next[from] = append(next[from], to)
+		}
+		conditional, _ := edge["edges"].([]interface{})
+		for _, ce := range conditional {
+			condEdge, _ := ce.(map[string]interface{})
+			if to, _ := condEdge["to"].(string); to != "" {
+				next[from] = append(next[from], to)
+			}
+		}
+	}
+
+	var errs []string
+	for _, group := range stronglyConnected(order, next) {
+		if len(group) == 1 && !slices.Contains(next[group[0]], group[0]) {
+			continue // Not a loop
+		}
+		inGroup := make(map[string]bool, len(group))
+		for _, name := range group {
+			inGroup[name] = true
+		}
+		exits := false
+		for _, name := range group {
+			if t := nodeTypes[name]; t == "input" || t == "script" {
+				exits = true
+			}
+			for _, to := range next[name] {
+				if !inGroup[to] {
+					exits = true
+				}
+			}
+		}
+		if !exits {
+			errs = append(errs, fmt.Sprintf("Nodes %s form a loop with no edge leaving it, so the run would never end. Add a conditional edge out of the loop (e.g. to END)", strings.Join(group, ", ")))
+		}
+	}
+	return errs
+}
+
+// stronglyConnected returns the groups of nodes that reach each other
+// (Tarjan's algorithm), each in the flow's node order.
+func stronglyConnected(order []string, next map[string][]string) [][]string {
+	index := make(map[string]int)
+	low := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var groups [][]string
+
+	var visit func(string)
+	visit = func(v string) {
+		index[v] = len(index)
+		low[v] = index[v]
+		stack = append(stack, v)
+		onStack[v] = true
+		for _, w := range next[v] {
+			if _, seen := index[w]; !seen {
+				visit(w)
+				low[v] = min(low[v], low[w])
+			} else if onStack[w] {
+				low[v] = min(low[v], index[w])
+			}
+		}
+		if low[v] != index[v] {
+			return
+		}
+		var group []string
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			group = append(group, w)
+			if w == v {
+				break
+			}
+		}
+		slices.SortFunc(group, func(a, b string) int {
+			return slices.Index(order, a) - slices.Index(order, b)
+		})
+		groups = append(groups, group)
+	}
+	for _, v := range order {
+		if _, seen := index[v]; !seen {
+			visit(v)
+		}
+	}
+	return groups
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestFlowLoopErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		flow  string
+		loops []string // Node lists of the reported loops
+	}{
+		{
+			name: "loop with a conditional exit",
+			flow: `
+nodes: [{name: fetch, type: llm}, {name: check, type: llm}]
+flow:
+  - {from: START, to: fetch}
+  - {from: fetch, to: check}
+  - from: check
+    edges: [{to: END, condition: "lambda x: x['done']"}, {to: fetch, condition: "true"}]`,
+		},
+		{
+			name: "loop without an exit",
+			flow: `
+nodes: [{name: fetch, type: llm}, {name: check, type: llm}, {name: report, type: output}]
+flow:
+  - {from: START, to: fetch}
+  - {from: fetch, to: check}
+  - from: check
+    edges: [{to: fetch, condition: "lambda x: x['retry']"}, {to: check, condition: "true"}]
+  - {from: report, to: END}`,
+			loops: []string{"fetch, check"},
+		},
+		{
+			name: "node looping on itself",
+			flow: `
+nodes: [{name: poll, type: tool}]
+flow:
+  - {from: START, to: poll}
+  - {from: poll, to: poll}`,
+			loops: []string{"poll"},
+		},
+		{
+			name: "loop waiting for the user",
+			flow: `
+nodes: [{name: ask, type: input}, {name: answer, type: llm}]
+flow:
+  - {from: START, to: ask}
+  - {from: ask, to: answer}
+  - {from: answer, to: ask}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var flow map[string]interface{}
+			if err := yaml.Unmarshal([]byte(tt.flow), &flow); err != nil {
+				t.Fatal(err)
+			}
+			nodes, _ := flow["nodes"].([]interface{})
+			edges, _ := flow["flow"].([]interface{})
+			errs := flowLoopErrors(nodes, edges)
+			if len(errs) != len(tt.loops) {
+				t.Fatalf("errors = %v, want loops %v", errs, tt.loops)
+			}
+			for i, loop := range tt.loops {
+				if !strings.Contains(errs[i], "Nodes "+loop+" form a loop") {
+					t.Errorf("error = %q, want loop %s", errs[i], loop)
+				}
+			}
+		})
+	}
+}
@@ -4,15 +4,19 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/SAP/astonish/pkg/agent"
+	"github.com/SAP/astonish/pkg/artifacts"
 	"github.com/SAP/astonish/pkg/config"
 	"github.com/SAP/astonish/pkg/credentials"
+	"github.com/SAP/astonish/pkg/mcp"
 	"github.com/SAP/astonish/pkg/provider"
 	"github.com/SAP/astonish/pkg/sandbox"
+	persistentsession "github.com/SAP/astonish/pkg/session"
 	"github.com/SAP/astonish/pkg/store"
 	"github.com/SAP/astonish/pkg/tools"
 	adkagent "google.golang.org/adk/agent"
@@ -35,7 +39,7 @@ type FlowRunRequest struct {
 // SSE events emitted:
 //
 //	event: text   data: {"text": "..."}
-//	event: node   data: {"node": "node_name"}
+//	event: node   data: {"node": "node_name", "nodeExecutionId": "..."}
 //	event: error  data: {"error": "..."}
 //	event: done   data: {"result": "ok"}
 func FlowRunHandler(w http.ResponseWriter, r *http.Request) {
@@ -123,6 +127,9 @@ func FlowRunHandler(w http.ResponseWriter, r *http.Request) {
 		SendErrorSSE(w, flusher, fmt.Sprintf("failed to parse agent config: %v", cfgErr))
 		return
 	}
+	for _, d := range cfg.SchemaWarnings {
+		slog.Warn("flow schema warning", "agent", agentName, "problem", d.String())
+	}
 
 	// 2. Determine Provider/Model
 	appCfg := effectiveAppConfig(r)
@@ -198,17 +205,29 @@ func FlowRunHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	requiredServers := getRequiredMCPServers(cfg, teamMCPStore, orgMCPStore, platformMCPStore)
 
+	var mcpManager *mcp.Manager
 	var mcpToolsets []tool.Toolset
 	if len(requiredServers) > 0 {
-		_, mcpToolsets = sm.GetOrCreateMCPManager(ctx, sessionID, requiredServers, teamMCPStore, orgMCPStore, platformMCPStore)
+		mcpManager, mcpToolsets = sm.GetOrCreateMCPManager(ctx, sessionID, requiredServers, teamMCPStore, orgMCPStore, platformMCPStore)
 	}
 
 	// 5. Create Agent
 	astonishAgent := agent.NewAstonishAgentWithToolsets(cfg, llm, internalTools, mcpToolsets)
+	astonishAgent.AppConfig = appCfg // per-node models and model_fallbacks resolve providers from here
+	astonishAgent.ProviderName = providerName
+	astonishAgent.ModelName = modelName
 	astonishAgent.DebugMode = false
+	if mcpManager != nil {
+		astonishAgent.MCPHealth = mcpManager.HealthState
+		astonishAgent.ToolsetsWithEnv = mcpManager.ToolsetsWithEnv
+	}
 	astonishAgent.IsWebMode = true // Disable ANSI colors
 	astonishAgent.AutoApprove = true
-	astonishAgent.SessionService = session.InMemoryService()
+	astonishAgent.SessionService, err = persistentsession.NewSensitiveService(session.InMemoryService(), cfg.SensitiveKeys(), tools.GetCredentialStore().DeriveKey("session-state"))
+	if err != nil {
+		SendErrorSSE(w, flusher, fmt.Sprintf("failed to create session service: %v", err))
+		return
+	}
 
 	// Wire credential store for {{CREDENTIAL:...}} placeholder resolution.
 	// File-based store (personal mode) + context-injected PG store (platform mode).
@@ -264,9 +283,10 @@ func FlowRunHandler(w http.ResponseWriter, r *http.Request) {
 
 	// 7. Create Runner
 	rnr, err := runner.New(runner.Config{
-		AppName:        "astonish",
-		Agent:          adkAgent,
-		SessionService: sessionService,
+		AppName:         "astonish",
+		Agent:           adkAgent,
+		SessionService:  sessionService,
+		ArtifactService: artifacts.RunnerService(),
 	})
 	if err != nil {
 		SendErrorSSE(w, flusher, fmt.Sprintf("failed to create runner: %v", err))
@@ -324,7 +344,8 @@ func runFlowHeadlessSSE(
 						userMessageFields = nil
 						isInputNode = false
 
-						SendSSE(w, flusher, "node", map[string]string{"node": currentNodeName})
+						nodeExecutionID, _ := agent.EventIDs(event)
+						SendSSE(w, flusher, "node", map[string]string{"node": currentNodeName, "nodeExecutionId": nodeExecutionID})
 
 						// Determine node type for streaming control
 						for _, n := range cfg.Nodes {
@@ -336,10 +357,11 @@ func runFlowHeadlessSSE(
 								case "output":
 									suppressStreaming = false
 								default:
-									if len(n.UserMessage) > 0 {
+									switch n.DisplayMode() {
+									case config.DisplayUserMessage:
 										suppressStreaming = true
 										userMessageFields = n.UserMessage
-									} else if len(n.OutputModel) > 0 {
+									case config.DisplayNone, config.DisplaySummary:
 										suppressStreaming = true
 									}
 								}
@@ -366,6 +388,8 @@ func runFlowHeadlessSSE(
 					waitingForInput = true
 				}
 
+				forwardParallelProgress(event.Actions.StateDelta, func(name string, data any) { SendSSE(w, flusher, name, data) })
+
 				// Capture user_message fields for output
 				if len(userMessageFields) > 0 && suppressStreaming && !nodeJustChanged {
 					for _, field := range userMessageFields {
@@ -382,10 +406,11 @@ func runFlowHeadlessSSE(
 				}
 			}
 
-			// Stream LLM text
+			// Stream LLM text (display: summary output bypasses suppression)
+			_, isSummary := event.Actions.StateDelta["_display_summary"]
 			if event.LLMResponse.Content != nil {
 				for _, part := range event.LLMResponse.Content.Parts {
-					if part.Text != "" && !suppressStreaming {
+					if part.Text != "" && (!suppressStreaming || isSummary) {
 						SendSSE(w, flusher, "text", map[string]string{"text": part.Text})
 					}
 				}
@@ -425,6 +450,23 @@ func runFlowHeadlessSSE(
 	SendSSE(w, flusher, "done", map[string]string{"result": "ok"})
 }
 
+// parallelProgressEvents maps the state delta keys of parallel node
+// progress to the SSE events they are sent as.
+var parallelProgressEvents = []struct{ key, event string }{
+	{"_parallel_item", "parallel_item"},   // An item finished (stream_results)
+	{"_parallel_chunk", "parallel_chunk"}, // A chunk finished (chunk_size)
+}
+
+// forwardParallelProgress sends the parallel node progress in delta as SSE
+// events, so chat and headless clients both see per-item results.
+func forwardParallelProgress(delta map[string]any, send func(event string, data any)) {
+	for _, p := range parallelProgressEvents {
+		if val, ok := delta[p.key]; ok {
+			send(p.event, val)
+		}
+	}
+}
+
 // mapKeys returns the keys of a map as a slice.
 func mapKeys(m map[string]string) []string {
 	if m == nil {
@@ -0,0 +1,216 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/google/uuid"
+)
+
+// maxFinishedFlowRuns bounds the finished runs kept for the status API.
+const maxFinishedFlowRuns = 200
+
+// FlowRunStatus is the state of a triggered flow run.
+type FlowRunStatus string
+
+const (
+	FlowRunQueued    FlowRunStatus = "queued"
+	FlowRunRunning   FlowRunStatus = "running"
+	FlowRunSucceeded FlowRunStatus = "succeeded"
+	FlowRunFailed    FlowRunStatus = "failed"
+	FlowRunTimedOut  FlowRunStatus = "timed_out"
+)
+
+// FlowRun is a flow run started by POST /api/flows/{name}/trigger.
+type FlowRun struct {
+	ID     string        `json:"run_id"`
+	Flow   string        `json:"flow"`
+	Status FlowRunStatus `json:"status"`
+	// QueuePosition is the 1-based position among waiting runs; 0 once the
+	// run has started.
+	QueuePosition int        `json:"queue_position,omitempty"`
+	Output        string     `json:"output,omitempty"`
+	Error         string     `json:"error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	StartedAt     *time.Time `json:"started_at,omitempty"`
+	FinishedAt    *time.Time `json:"finished_at,omitempty"`
+
+	tenant string // Org and team that triggered the run in platform mode
+}
+
+// limitKey names the flow of the run for the per-flow limits: flows of
+// different tenants can share a name, so the tenant is part of the key.
+func (r *FlowRun) limitKey() string {
+	if r.tenant == "" {
+		return r.Flow
+	}
+	return r.tenant + "/" + r.Flow
+}
+
+// errFlowRunQueueFull is returned when no more runs can be queued.
+var errFlowRunQueueFull = errors.New("run queue is full")
+
+// flowRunJob is a queued run with the function that executes it.
+type flowRunJob struct {
+	run  *FlowRun
+	ctx  context.Context
+	exec func(ctx context.Context) (string, error)
+}
+
+// flowRunQueue executes triggered runs within the limits of a
+// config.RunQueueConfig: runs over the total or per-flow limit wait in
+// FIFO order, and a run that is blocked by its flow's limit does not hold
+// up runs of other flows.
+type flowRunQueue struct {
+	mu       sync.Mutex
+	limits   config.RunQueueConfig
+	pending  []flowRunJob
+	running  int
+	perFlow  map[string]int // Running runs by limitKey
+	runs     map[string]*FlowRun
+	finished []string // run IDs in completion order, oldest first
+}
+
+func newFlowRunQueue(limits config.RunQueueConfig) *flowRunQueue {
+	return &flowRunQueue{
+		limits:  limits,
+		perFlow: make(map[string]int),
+		runs:    make(map[string]*FlowRun),
+	}
+}
+
+var (
+	flowRunQueueMu       sync.Mutex
+	flowRunQueueInstance *flowRunQueue
+)
+
+// getFlowRunQueue returns the process-wide run queue, with default limits
+// until ConfigureFlowRunQueue is called.
+func getFlowRunQueue() *flowRunQueue {
+	flowRunQueueMu.Lock()
+	defer flowRunQueueMu.Unlock()
+	if flowRunQueueInstance == nil {
+		flowRunQueueInstance = newFlowRunQueue(config.RunQueueConfig{})
+	}
+	return flowRunQueueInstance
+}
+
+// ConfigureFlowRunQueue sets the limits of triggered flow runs.
+// Called by the daemon at startup; new limits apply to runs not yet started.
+func ConfigureFlowRunQueue(limits config.RunQueueConfig) {
+	q := getFlowRunQueue()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.limits = limits
+	q.dispatchLocked()
+}
+
+// Enqueue queues a run of flow for tenant and returns a snapshot of it.
+// exec runs with ctx, bounded by the run timeout, once the limits allow.
+func (q *flowRunQueue) Enqueue(ctx context.Context, tenant, flow string, exec func(ctx context.Context) (string, error)) (FlowRun, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.pending) >= q.limits.GetQueueDepth() {
+		return FlowRun{}, errFlowRunQueueFull
+	}
+	run := &FlowRun{ID: uuid.New().String(), Flow: flow, Status: FlowRunQueued, CreatedAt: time.Now().UTC(), tenant: tenant}
+	q.runs[run.ID] = run
+	q.pending = append(q.pending, flowRunJob{run: run, ctx: ctx, exec: exec})
+	q.dispatchLocked()
+	return q.snapshotLocked(run), nil
+}
+
+// Get returns a snapshot of the run with the given ID.
+func (q *flowRunQueue) Get(id string) (FlowRun, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	run, ok := q.runs[id]
+	if !ok {
+		return FlowRun{}, false
+	}
+	return q.snapshotLocked(run), true
+}
+
+func (q *flowRunQueue) snapshotLocked(run *FlowRun) FlowRun {
+	snap := *run
+	if run.Status == FlowRunQueued {
+		for i, job := range q.pending {
+			if job.run == run {
+				snap.QueuePosition = i + 1
+				break
+			}
+		}
+	}
+	return snap
+}
+
+// dispatchLocked starts the oldest waiting runs the limits allow.
+func (q *flowRunQueue) dispatchLocked() {
+	waiting := q.pending[:0]
+	for _, job := range q.pending {
+		key := job.run.limitKey()
+		if q.running >= q.limits.GetMaxConcurrent() ||
+			(q.limits.GetFlowLimit(key) > 0 && q.perFlow[key] >= q.limits.GetFlowLimit(key)) {
+			waiting = append(waiting, job)
+			continue
+		}
+		q.running++
+		q.perFlow[key]++
+		started := time.Now().UTC()
+		job.run.Status, job.run.StartedAt = FlowRunRunning, &started
+		go q.execute(job, q.limits.GetTimeout())
+	}
+	// Clear the tail so finished jobs are not retained by the backing array
+	for i := len(waiting); i < len(q.pending); i++ {
+		q.pending[i] = flowRunJob{}
+	}
+	q.pending = waiting
+}
+
+// execute runs a job and records its result. A panic fails the run instead
+// of the daemon.
+func (q *flowRunQueue) execute(job flowRunJob, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(job.ctx, timeout)
+	defer cancel()
+
+	output, err := func() (output string, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("run panicked: %v", r)
+			}
+		}()
+		return job.exec(ctx)
+	}()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	finished := time.Now().UTC()
+	run := job.run
+	run.FinishedAt, run.Output = &finished, output
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		run.Status, run.Error = FlowRunTimedOut, fmt.Sprintf("run exceeded the timeout of %s", timeout)
+	case err != nil:
+		run.Status, run.Error = FlowRunFailed, err.Error()
+	default:
+		run.Status = FlowRunSucceeded
+	}
+
+	q.running--
+	key := run.limitKey()
+	if q.perFlow[key]--; q.perFlow[key] <= 0 {
+		delete(q.perFlow, key)
+	}
+	q.finished = append(q.finished, run.ID)
+	if n := len(q.finished) - maxFinishedFlowRuns; n > 0 {
+		for _, id := range q.finished[:n] {
+			delete(q.runs, id)
+		}
+		q.finished = append([]string(nil), q.finished[n:]...)
+	}
+	q.dispatchLocked()
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SAP/astonish/pkg/config"
+)
+
+func waitFlowRun(t *testing.T, q *flowRunQueue, id string, status FlowRunStatus) FlowRun {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if run, ok := q.Get(id); ok && run.Status == status {
+			return run
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	run, _ := q.Get(id)
+	t.Fatalf("run %s is %s, want %s", id, run.Status, status)
+	return FlowRun{}
+}
+
+// blockingRun returns a run function that waits until release is closed.
+func blockingRun(release <-chan struct{}, output string) func(context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		<-release
+		return output, nil
+	}
+}
+
+func TestFlowRunQueueLimits(t *testing.T) {
+	q := newFlowRunQueue(config.RunQueueConfig{MaxConcurrent: 2, PerFlow: 1, QueueDepth: 2})
+	release := make(chan struct{})
+	ctx := context.Background()
+
+	a1, _ := q.Enqueue(ctx, "", "a", blockingRun(release, "a1"))
+	a2, _ := q.Enqueue(ctx, "", "a", blockingRun(release, "a2"))
+	b1, _ := q.Enqueue(ctx, "", "b", blockingRun(release, "b1"))
+	waitFlowRun(t, q, a1.ID, FlowRunRunning)
+	// a2 waits for a's per-flow limit, without holding up b1
+	waitFlowRun(t, q, b1.ID, FlowRunRunning)
+	if run := waitFlowRun(t, q, a2.ID, FlowRunQueued); run.QueuePosition != 1 {
+		t.Errorf("a2 queue position = %d, want 1", run.QueuePosition)
+	}
+
+	c1, err := q.Enqueue(ctx, "", "c", blockingRun(release, "c1"))
+	if err != nil || c1.QueuePosition != 2 {
+		t.Fatalf("c1 = %+v, %v", c1, err)
+	}
+	if _, err := q.Enqueue(ctx, "", "c", nil); !errors.Is(err, errFlowRunQueueFull) {
+		t.Fatalf("expected a full queue, got %v", err)
+	}
+
+	close(release)
+	for _, id := range []string{a1.ID, a2.ID, b1.ID, c1.ID} {
+		if run := waitFlowRun(t, q, id, FlowRunSucceeded); run.StartedAt == nil || run.FinishedAt == nil || run.QueuePosition != 0 {
+			t.Errorf("run = %+v", run)
+		}
+	}
+}
+
+func TestFlowRunQueueTenantLimits(t *testing.T) {
+	q := newFlowRunQueue(config.RunQueueConfig{PerFlow: 1, FlowLimits: map[string]int{"acme/red/triage": 2}})
+	release := make(chan struct{})
+	defer close(release)
+	ctx := context.Background()
+
+	// Each tenant's triage flow has its own slots and limit
+	red1, _ := q.Enqueue(ctx, "acme/red", "triage", blockingRun(release, "red1"))
+	red2, _ := q.Enqueue(ctx, "acme/red", "triage", blockingRun(release, "red2"))
+	blue1, _ := q.Enqueue(ctx, "acme/blue", "triage", blockingRun(release, "blue1"))
+	blue2, _ := q.Enqueue(ctx, "acme/blue", "triage", blockingRun(release, "blue2"))
+	for _, id := range []string{red1.ID, red2.ID, blue1.ID} {
+		waitFlowRun(t, q, id, FlowRunRunning)
+	}
+	waitFlowRun(t, q, blue2.ID, FlowRunQueued)
+}
+
+func TestFlowRunQueueFailures(t *testing.T) {
+	q := newFlowRunQueue(config.RunQueueConfig{})
+	ctx := context.Background()
+
+	failed, _ := q.Enqueue(ctx, "", "a", func(ctx context.Context) (string, error) {
+		return "partial", errors.New("boom")
+	})
+	if run := waitFlowRun(t, q, failed.ID, FlowRunFailed); run.Error != "boom" || run.Output != "partial" {
+		t.Errorf("failed run = %+v", run)
+	}
+
+	panicked, _ := q.Enqueue(ctx, "", "a", func(ctx context.Context) (string, error) {
+		panic("bad flow")
+	})
+	waitFlowRun(t, q, panicked.ID, FlowRunFailed)
+}
+
+func TestFlowRunQueueTimeout(t *testing.T) {
+	q := newFlowRunQueue(config.RunQueueConfig{})
+	job := flowRunJob{run: &FlowRun{ID: "r1", Flow: "slow", Status: FlowRunRunning}, ctx: context.Background(), exec: func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}}
+	q.runs["r1"] = job.run
+	q.running, q.perFlow["slow"] = 1, 1
+
+	// Timeouts are configured in minutes; run the job with a short one
+	q.execute(job, 10*time.Millisecond)
+
+	got, _ := q.Get("r1")
+	if got.Status != FlowRunTimedOut || got.Error == "" {
+		t.Errorf("run = %+v", got)
+	}
+	if q.running != 0 || len(q.perFlow) != 0 {
+		t.Errorf("slots not released: running=%d perFlow=%v", q.running, q.perFlow)
+	}
+}
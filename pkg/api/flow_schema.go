@@ -14,7 +14,7 @@ description: What this agent does
 
 nodes:
   - name: node_name
-    type: llm|input|tool|output|update_state
+    type: llm|input|tool|output|update_state|script|memory|vector|abort
     # type-specific fields...
 
 flow:
@@ -26,13 +26,85 @@ flow:
     to: END
 ` + "```" + `
 
+## Flow Variables (optional)
+Declare the flow's fixed inputs with defaults in a top-level ` + "`" + `variables:` + "`" + ` section instead of asking for them with input nodes. They are in state from the start (use ` + "`" + `{repo}` + "`" + ` in prompts) and can be set with ` + "`" + `astonish flows run --var name=value` + "`" + `.
+` + "```yaml" + `
+variables:
+  repo:
+    type: str           # str (default), int, float, bool, list, dict
+    default: SAP/astonish
+    description: Repository to analyze
+  limit:
+    type: int
+    default: 20
+` + "```" + `
+
+## Exporting the Final State (optional)
+Set a top-level ` + "`" + `export_state: results/state.json` + "`" + ` to write the final state (without internal and sensitive keys) to a JSON file when the flow reaches END, e.g. for scripts that use the results.
+
+## Node Defaults (optional)
+Settings repeated on many nodes go in a top-level ` + "`" + `defaults:` + "`" + ` section; every node inherits them unless it sets them itself:
+` + "```yaml" + `
+defaults:
+  system: "You are a careful release engineer. Answer concisely."  # prepended to each LLM node's system prompt
+  tools_auto_approval: true    # a node can still set tools_auto_approval: false
+  max_retries: 2
+  max_tool_calls: 30           # LLM nodes: tool calls per attempt (default: 20)
+  generation:
+    temperature: 0.2           # a node's generation overrides single fields
+` + "```" + `
+
+## Error Handling (optional)
+By default a node that fails (after its retries) ends the run. Set ` + "`" + `on_error: <node>` + "`" + ` on a node, or at the top level of the flow for all nodes, to continue at a handler node instead. The handler sees the failure in {_last_error}, {_error_node} and {_error_class}, e.g. to notify someone, fall back to a simpler approach or produce a partial report:
+` + "```yaml" + `
+on_error: report_failure     # flow-wide default
+nodes:
+  - name: fetch_data
+    type: tool
+    on_error: use_cached     # this node's own handler
+` + "```" + `
+
+## Tests (optional)
+A top-level ` + "`" + `tests:` + "`" + ` list holds unit tests run by ` + "`" + `astonish test <flow>` + "`" + ` with canned model responses per LLM node (one per call), answers for input nodes, canned tool results, and the expected node path, state or error:
+` + "```yaml" + `
+tests:
+  - name: bug_is_labelled
+    inputs: { ask_issue: "42" }
+    tools: { get_issue: { title: "Crash on start" } }
+    llm: { classify: ['{"label": "bug"}'] }
+    expect:
+      path: [ask_issue, fetch_issue, classify, show_label]
+      state: { label: bug }
+` + "```" + `
+
+## Chat Mode (optional)
+Set a top-level ` + "`" + `chat_mode: true` + "`" + ` for a conversational flow: each user message runs the flow, and at END it waits for the next message instead of finishing. ` + "`" + `loop_to: <node>` + "`" + ` (implies chat_mode) makes later turns start at that node instead of the first one. The message is in {chat_message}; LLM nodes automatically see the earlier turns, or place them with {chat_history}. End each turn with an LLM node with user_message or an output node: its text is the reply recorded in the history.
+
 ## Node Types
 
 ### 1. LLM Node (PREFERRED for tool usage)
 AI processing with optional tool use.
-- output_model: saves result to state for later nodes
+- output_model: saves result to state for later nodes. Types: str, int, float, bool, dict, list (of strings), list[int], list[dict] or inline item schemas like list[{severity: str, line: int}]. For secrets or PII write the entry as a mapping, e.g. api_token: {type: str, sensitive: true}, so the value is encrypted at rest and masked in output
 - user_message: DISPLAY result to user (use this when user needs to see the response!)
 - raw_context: verbatim text appended to system instruction WITHOUT state variable interpolation
+- display: optional visibility override - none, user_message, stream or summary (default: user_message if set, none with output_model, else stream)
+- preview: true shows a dimmed live preview of the text while a node whose output is not streamed (e.g. output_model) generates; the final JSON stays hidden
+- provider / model: optional per-node override of the flow's provider and model (e.g. a big model for reasoning, a cheap one for extraction)
+- output_schema: optional full JSON Schema (type: object) for nested structured output, used instead of output_model. Top-level properties become state keys; the output is validated against the schema and the model is asked once to repair it before the node retries
+- generation: optional sampling parameters - temperature (0-2), top_p (0-1), max_output_tokens, stop_sequences. Use a low temperature for extraction, higher for creative writing
+- on_content_filter: optional fallback when the provider's safety filter blocks the response - {action: rephrase} (retry with a rephrasing instruction, optional instruction), {action: switch_model, model: provider/model} or {action: route, node: <review node>}
+- guardrails: optional checks on the node's output before it is stored or shown (also allowed at the top level of the flow for all LLM nodes; a node's own guardrails replace the flow's) - deny: [regex, ...], pii: [email, phone, credit_card, ssn, iban, ip_address], classifier: {policy: "...", model: provider/model}, and action: block (default, the node fails), redact (matches replaced by [REDACTED:<kind>]; classifier violations still block) or route with node: <handler> ({_last_error} describes the violation, {_error_class} is guardrail)
+- model_fallbacks: optional list of models tried in order when the provider fails (5xx, rate limit, auth), e.g. [openrouter/gpt-4o, gemini-2.0-flash]. Also allowed at the top level of the flow for all LLM nodes. The model that answered is stored in _model_used
+- max_tool_output_tokens: optional limit on the size of each tool result the model sees (estimated tokens; also allowed at the top level of the flow for all LLM nodes). Larger results are stored in full in state under <node>_<tool>_output_<n> and the model gets the first part, or with tool_output_overflow: summarize an LLM summary. Use it for tools that can return huge outputs (logs, diffs, search dumps) when raw_tool_output does not fit
+- max_tool_calls: optional limit on the tool calls of one attempt (default: 20, also allowed in defaults:). A warning shows at 80%; beyond the limit the node fails. Raise it for nodes that legitimately work through many items with tools
+- context_budget: optional token budget for the conversation history the node sends (also allowed at the top level of the flow). Beyond it, older events are replaced by a summary; by default the budget is half the model's context window, -1 turns it off
+- env: optional map of environment variables for the node's tools (also on tool nodes), templated from state and allowing {{CREDENTIAL:name:field}}. The flow's MCP servers are started again with them (stdio: environment, remote: ${VAR} in headers/auth) and shell_command gets them too. Use it when nodes need different credentials for the same server, e.g. GITHUB_TOKEN per repository
+- cache_tool_results: optional duration (e.g. 10m) for which identical tool calls (same tool and args) reuse the stored result instead of calling the tool again, across runs. Use it for slow read-only lookups such as fetching the same PR diff; leave it off for tools with side effects
+- cache_response: optional boolean for llm nodes without tools and with generation.temperature: 0. Identical requests (same model, rendered prompt and settings) are answered from a local cache instead of calling the model again; --no-cache turns it off for a run
+- on_tool_denied: feedback: optional; when the user denies a tool call, the node continues and the model is told the call was denied (with what the user said) so it can try other arguments, another tool or answer without it. By default (skip) the flow moves on to the next node. Use it for nodes whose task has alternatives, e.g. a shell command the user may prefer done differently
+- max_parallel_tools: optional bound on the tool calls of one model turn that run at the same time (default 4). Independent lookups requested together run concurrently and their results are returned in call order; set 1 when the tools must run one after the other
+- glossary / glossary_terms: the flow's top-level glossary (term: definition map) is appended to the system prompt of every LLM node. Set glossary: false on a node to leave it out, or glossary_terms: [term, ...] to inject only some terms. Define recurring domain terms once in the glossary instead of repeating them in each prompt
+- templating: rich: optional richer templates in prompt, system, args and user_message (see "Rich Templating" below). Use it to format lists into a prompt instead of adding an LLM node just to format context
 ` + "```yaml" + `
 # LLM that shows answer to user (most common pattern)
 - name: answer_question
@@ -149,6 +221,13 @@ Collect user input. output_model is REQUIRED to store input in state.
 
 **DO NOT use options if you want the user to type free text!**
 
+**Typed and validated input (optional):**
+- ` + "`" + `input_type` + "`" + `: text (default), number, boolean, regex, multiline or secret (masked, stored encrypted)
+- ` + "`" + `default: "3"` + "`" + `: used when the user enters nothing
+- ` + "`" + `validation: {expr: "value > 0", message: "Must be positive"}` + "`" + ` (or ` + "`" + `regex:` + "`" + `) asks again until the answer passes
+- ` + "`" + `multi_select: true` + "`" + ` (with options): the user can choose several options; output_model stores a list (e.g. ` + "`" + `selected: list` + "`" + `)
+- ` + "`" + `input_mode: file` + "`" + ` (no options): the user picks a file under ` + "`" + `file_root` + "`" + ` (optionally filtered by ` + "`" + `file_pattern: "*.md"` + "`" + `); its absolute path is stored
+
 ### 3. Tool Node (RARELY USED)
 Execute a tool directly WITHOUT LLM intelligence. Only use when you need deterministic tool execution.
 In most cases, prefer LLM node with tools: true instead.
@@ -180,6 +259,23 @@ When ` + "`" + `continue_on_error` + "`" + ` is true, the result will include:
 - On success: ` + "`" + `{..., "success": true}` + "`" + `
 - On failure: ` + "`" + `{"error": "...", "success": false}` + "`" + `
 
+#### Retrying failed tool calls
+Tool nodes run once by default. Set ` + "`" + `max_retries` + "`" + ` (and optionally ` + "`" + `retry_strategy: simple` + "`" + `) to retry failures with the same error analysis as LLM nodes. Args are re-rendered before each attempt and can use ` + "`" + `{_retry_attempt}` + "`" + ` and ` + "`" + `{_last_error}` + "`" + `.
+Waits between retries follow ` + "`" + `retry_backoff` + "`" + ` (exponential, constant or none), ` + "`" + `initial_delay` + "`" + ` (default: 2s) and ` + "`" + `max_delay` + "`" + ` (default: 30s); this applies to LLM nodes too.
+
+#### Caching results with cache_tool_results
+Set ` + "`" + `cache_tool_results: 10m` + "`" + ` on a tool (or llm) node to reuse the result of an identical call (same tool and resolved args) made within that duration, including by earlier runs. Cached calls are not executed and need no approval, so only cache read-only tools.
+
+#### Retrying empty results with retry_on_empty
+For flaky or rate-limited endpoints that sometimes return nothing:
+` + "```yaml" + `
+  retry_on_empty:
+    attempts: 3             # Extra attempts with the same tool (default: 2)
+    delay: 2s               # Wait between attempts (default: 1s)
+    alternate_tools:        # Optional fallbacks, tried in order (auto-approved nodes only)
+      - backup_search
+` + "```" + `
+
 ### 4. Output Node
 Display messages to user. Use user_message array with strings and state variable names.
 Each item is displayed as a separate paragraph.
@@ -190,6 +286,12 @@ Each item is displayed as a separate paragraph.
 - Multi-line text is preserved in each item
 
 Note: LLM responses are shown automatically, so output nodes are mainly for formatting/labeling.
+
+**Reports:** add report: true (or a map with title, file and artifacts) to also render the message, as markdown, into a standalone HTML file in the workspace (default reports/<node>-<timestamp>.html). Linked URLs are listed as numbered sources; artifacts are files (paths or state keys holding paths) embedded in the report. Use it for the final node of research or analysis flows whose results are shared with others.
+
+**Diff preview:** add diff: {old: state_key, new: state_key, path: "{file}", style: unified|side-by-side} to show proposed changes to a text as a diff (user_message is then optional). Add confirm: apply_changes to ask Accept/Reject afterwards; true or false is stored under that key for the next edges to route on.
+
+**Artifacts:** add artifact: "summary-{repo}.md" to also save the message as a run artifact. Tools can save files (screenshots, PDFs) as artifacts with the internal save_artifact tool (path or content, optional name). References to saved artifacts are kept in state under _artifacts, and artifacts can be downloaded after the run with ` + "`" + `astonish flows artifacts <session_id>` + "`" + `.
 ` + "```yaml" + `
 - name: show_result
   type: output
@@ -197,6 +299,15 @@ Note: LLM responses are shown automatically, so output nodes are mainly for form
     - "Here is your answer:"   # Literal text
     - answer                   # State variable (resolved at runtime)
     - "Thank you for using our service!"  # More literal text
+
+- name: publish_report
+  type: output
+  user_message:
+    - report_markdown
+  report:
+    title: "Competitor analysis"
+    file: "reports/competitors-{company}.html"
+    artifacts: [chart_path]
 ` + "```" + `
 
 ### 5. Update State Node
@@ -269,6 +380,98 @@ Mode 2: **Action mode** (uses ` + "`" + `action` + "`" + ` + ` + "`" + `value` +
 
 **DO NOT use update_state just to copy or overwrite variables** - that's what output_model does automatically.
 
+### Rich Templating
+By default templates only substitute ` + "`" + `{expr}` + "`" + `. With ` + "`" + `templating: rich` + "`" + ` on a node, placeholders take filters and blocks are available:
+- Filters: ` + "`" + `{items | join(", ")}` + "`" + `, ` + "`" + `{text | truncate(500)}` + "`" + `, ` + "`" + `{issues | map("title") | join("\n")}` + "`" + `, ` + "`" + `{name | default("unknown")}` + "`" + `, plus upper, lower, trim, length, json, first, last
+- Conditionals: ` + "`" + `{% if cond %}...{% elif cond %}...{% else %}...{% endif %}` + "`" + `
+- Loops: ` + "`" + `{% for item in list %}...{% else %}(empty){% endfor %}` + "`" + ` and ` + "`" + `{% for key, value in dict %}` + "`" + `, with loop.index, loop.first and loop.last
+` + "```yaml" + `
+- name: triage
+  type: llm
+  templating: rich
+  system: "You are a triage assistant."
+  prompt: |
+    Open issues ({issues | length}):
+    {% for issue in issues %}
+    {loop.index}. {issue["title"]}: {issue["body"] | truncate(300)}
+    {% endfor %}
+    {% if labels %}Known labels: {labels | join(", ")}{% endif %}
+  output_model:
+    triage: str
+` + "```" + `
+
+### 6. Script Node
+Run a Starlark (Python-like) program for deterministic transformations, without an LLM call: filtering lists, reshaping JSON, computing differences between variables. State is available as the dict ` + "`" + `x` + "`" + ` (top-level keys are also readable by name). Write results with ` + "`" + `x["key"] = value` + "`" + `, or define a variable named in ` + "`" + `output_model` + "`" + `. The ` + "`" + `json` + "`" + ` module (json.encode, json.decode) is available.
+
+` + "```yaml" + `
+- name: pick_open_issues
+  type: script
+  script: |
+    open = [i for i in issues if i["state"] == "open"]
+    x["open_count"] = len(open)
+    open_titles = [i["title"] for i in open]
+  output_model:
+    open_titles: list
+` + "```" + `
+
+Use a script node instead of an LLM node whenever the result can be computed exactly.
+
+### 7. Memory Node
+Store facts that later runs of the flow can recall. ` + "`" + `scope` + "`" + ` is ` + "`" + `flow` + "`" + ` (default, shared by all runs of this flow) or ` + "`" + `user` + "`" + ` (shared by all flows of the user); ` + "`" + `key` + "`" + ` narrows the namespace, e.g. per repository. A recall writes a list of strings, most relevant first, to its single ` + "`" + `output_model` + "`" + ` key (default ` + "`" + `memories` + "`" + `).
+
+` + "```yaml" + `
+- name: recall_notes
+  type: memory
+  memory:
+    action: recall
+    key: "{repo}"
+    query: "{question}"
+    limit: 5
+  output_model:
+    notes: list
+
+- name: remember_answer
+  type: memory
+  memory:
+    action: store
+    key: "{repo}"
+    content: "{summary}"
+` + "```" + `
+
+### 8. Vector Node
+Retrieval-augmented generation without an MCP server: ` + "`" + `action: index` + "`" + ` chunks, embeds and stores ` + "`" + `text` + "`" + ` and/or the state key ` + "`" + `source_variable` + "`" + ` (a string, a list of strings, or a list of dicts with ` + "`" + `content` + "`" + ` and ` + "`" + `source` + "`" + `) in a ` + "`" + `collection` + "`" + ` (default: the flow name); indexing the same text again does not duplicate it. ` + "`" + `action: search` + "`" + ` writes the chunks most similar to ` + "`" + `query` + "`" + ` to its single ` + "`" + `output_model` + "`" + ` key (default ` + "`" + `documents` + "`" + `) as a list of dicts with ` + "`" + `content` + "`" + `, ` + "`" + `source` + "`" + ` and ` + "`" + `score` + "`" + `.
+
+` + "```yaml" + `
+- name: index_docs
+  type: vector
+  vector:
+    action: index
+    collection: "{project}"
+    source_variable: doc_files
+
+- name: find_context
+  type: vector
+  vector:
+    action: search
+    collection: "{project}"
+    query: "{question}"
+    limit: 4
+  output_model:
+    context: list
+` + "```" + `
+
+Then reference ` + "`" + `{context}` + "`" + ` in the prompt of the LLM node that answers.
+
+### 9. Abort Node
+Stops the run: the flow goes to END with a failure summary, and ` + "`" + `flows run` + "`" + ` exits with ` + "`" + `exit_code` + "`" + ` (default 1). Route to it from a conditional edge instead of wiring every branch to END. Any node can also stop the run by setting the reserved state key ` + "`" + `_abort` + "`" + ` (true, a message, or {message, exit_code}), e.g. from update_state or a script.
+
+` + "```yaml" + `
+- name: stop_no_access
+  type: abort
+  message: "No access to {repo}; check the token"
+  exit_code: 2
+` + "```" + `
+
 ## Flow Edges
 
 ### Simple Edge
@@ -297,6 +500,7 @@ IMPORTANT: Loops must point to actual nodes, NEVER to START!
     - to: END
       condition: "lambda x: x['continue'] == 'no'"
 ` + "```" + `
+Every loop needs an edge that leaves it (a loop through an input or script node is also accepted). A node may run at most 100 times per run between two waits for input; set a top-level ` + "`" + `max_node_visits` + "`" + ` for loops that need more rounds (-1 turns the limit off).
 
 ## State & Templating
 - Use {variable_name} to reference state (single braces, no spaces)
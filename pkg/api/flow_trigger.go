@@ -0,0 +1,179 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/scheduler"
+	"github.com/SAP/astonish/pkg/store"
+	"github.com/gorilla/mux"
+)
+
+// FlowTriggerHandler handles POST /api/flows/{name}/trigger. The JSON body
+// is mapped to the flow's variables: keys that name a declared variable set
+// it, other keys are ignored, and a declared "payload" variable that is not
+// a key of the body receives the whole body. The run is queued and executed
+// headlessly; the response carries its ID for GET /api/flows/runs/{id}.
+// The optional "provider" and "model" query parameters pick the model.
+func FlowTriggerHandler(w http.ResponseWriter, r *http.Request) {
+	runHeadless := GetRunHeadlessFunc()
+	if runHeadless == nil {
+		respondError(w, http.StatusServiceUnavailable, "flow runner not available")
+		return
+	}
+
+	flowName := mux.Vars(r)["name"]
+	runCfg, cfg, err := loadTriggerFlow(r, flowName)
+	if err != nil {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	payload := map[string]any{}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "failed to read request body: "+err.Error())
+		return
+	}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &payload); err != nil {
+			respondError(w, http.StatusBadRequest, "request body must be a JSON object: "+err.Error())
+			return
+		}
+	}
+	vars, err := triggerVariables(cfg, payload, body)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if _, err := cfg.ResolveVariables(vars); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	appCfg := effectiveAppConfig(r)
+	injectProviderSecrets(appCfg)
+	runCfg.AppConfig = appCfg
+	runCfg.Variables = vars
+	runCfg.ProviderName = r.URL.Query().Get("provider")
+	if runCfg.ProviderName == "" {
+		runCfg.ProviderName = appCfg.General.DefaultProvider
+	}
+	runCfg.ModelName = r.URL.Query().Get("model")
+	if runCfg.ModelName == "" {
+		runCfg.ModelName = appCfg.General.DefaultModel
+	}
+	// Nobody answers approvals of a triggered run: calls the flow does not
+	// auto-approve are denied unless the config approves them all
+	runCfg.DenyApprovals = !appCfg.Daemon.TriggerAutoApprove
+
+	// The run outlives the request but keeps its tenant and policy values
+	ctx := withRuntimeNetworkPolicyContext(r.Context(), r, appCfg)
+	ctx = context.WithoutCancel(withRuntimeSandboxContext(ctx, r))
+
+	run, err := getFlowRunQueue().Enqueue(ctx, flowRunTenant(r), flowName, func(ctx context.Context) (string, error) {
+		return runHeadless(ctx, runCfg)
+	})
+	if err != nil {
+		respondError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	slog.Info("flow run triggered", "flow", flowName, "run_id", run.ID)
+
+	w.Header().Set("Location", "/api/flows/runs/"+run.ID)
+	respondJSON(w, http.StatusAccepted, run)
+}
+
+// FlowRunStatusHandler handles GET /api/flows/runs/{id}. In platform mode
+// only runs triggered by the same org and team are found.
+func FlowRunStatusHandler(w http.ResponseWriter, r *http.Request) {
+	run, ok := getFlowRunQueue().Get(mux.Vars(r)["id"])
+	if !ok || run.tenant != flowRunTenant(r) {
+		respondError(w, http.StatusNotFound, "run not found")
+		return
+	}
+	respondJSON(w, http.StatusOK, run)
+}
+
+// flowRunTenant returns the org and team of a platform mode request, which
+// own the runs it triggers, and "" in personal mode.
+func flowRunTenant(r *http.Request) string {
+	if !isPlatformMode(r) {
+		return ""
+	}
+	tc := store.TenantContextFrom(r.Context())
+	if tc == nil {
+		return ""
+	}
+	return tc.OrgSlug + "/" + tc.TeamSlug
+}
+
+// loadTriggerFlow resolves a flow for a triggered run: from the flow store
+// in platform mode (personal first, team fallback) and from the flows
+// directory in personal mode.
+func loadTriggerFlow(r *http.Request, name string) (*scheduler.HeadlessRunConfig, *config.AgentConfig, error) {
+	if svc := store.FromRequest(r); svc != nil && (svc.PersonalFlows != nil || svc.Flows != nil) {
+		var yamlContent string
+		if svc.PersonalFlows != nil {
+			if y, err := svc.PersonalFlows.GetFlow(r.Context(), name); err == nil && y != "" {
+				yamlContent = y
+			}
+		}
+		if yamlContent == "" && svc.Flows != nil {
+			if y, err := svc.Flows.GetFlow(r.Context(), name); err == nil {
+				yamlContent = y
+			}
+		}
+		if yamlContent == "" {
+			return nil, nil, fmt.Errorf("flow not found: %s", name)
+		}
+		cfg, err := config.LoadAgentFromBytes([]byte(yamlContent))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse flow %s: %w", name, err)
+		}
+		return &scheduler.HeadlessRunConfig{FlowYAML: yamlContent}, cfg, nil
+	}
+
+	path, _, err := findAgentPath(name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("flow not found: %s", name)
+	}
+	cfg, err := config.LoadAgent(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse flow %s: %w", name, err)
+	}
+	return &scheduler.HeadlessRunConfig{FlowPath: path}, cfg, nil
+}
+
+// triggerVariables maps a trigger payload to --var style values of the
+// flow's declared variables. Strings are used as they are; other values are
+// passed as JSON, which the variable types parse.
+func triggerVariables(cfg *config.AgentConfig, payload map[string]any, body []byte) (map[string]string, error) {
+	vars := make(map[string]string)
+	for name := range cfg.Variables {
+		value, ok := payload[name]
+		if !ok {
+			if name == "payload" && len(body) > 0 {
+				vars[name] = string(body)
+			}
+			continue
+		}
+		switch v := value.(type) {
+		case nil:
+		case string:
+			vars[name] = v
+		default:
+			data, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("variable %q: %w", name, err)
+			}
+			vars[name] = string(data)
+		}
+	}
+	return vars, nil
+}
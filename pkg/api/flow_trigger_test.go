@@ -0,0 +1,116 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/scheduler"
+	"github.com/SAP/astonish/pkg/store"
+	"github.com/gorilla/mux"
+)
+
+func TestTriggerVariables(t *testing.T) {
+	cfg := &config.AgentConfig{Variables: map[string]config.FlowVariable{
+		"repo":    {},
+		"limit":   {Type: "int"},
+		"labels":  {Type: "list"},
+		"payload": {Type: "dict"},
+		"unset":   {},
+	}}
+	body := []byte(`{"repo":"SAP/astonish","limit":5,"labels":["bug"],"action":"opened"}`)
+	payload := map[string]any{"repo": "SAP/astonish", "limit": 5.0, "labels": []any{"bug"}, "action": "opened"}
+
+	vars, err := triggerVariables(cfg, payload, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"repo": "SAP/astonish", "limit": "5", "labels": `["bug"]`, "payload": string(body)}
+	if len(vars) != len(want) {
+		t.Fatalf("vars = %v, want %v", vars, want)
+	}
+	for k, v := range want {
+		if vars[k] != v {
+			t.Errorf("vars[%q] = %q, want %q", k, vars[k], v)
+		}
+	}
+
+	values, err := cfg.ResolveVariables(vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["limit"] != 5 {
+		t.Errorf("limit = %#v", values["limit"])
+	}
+	if p, _ := values["payload"].(map[string]any); p["action"] != "opened" {
+		t.Errorf("payload = %#v", values["payload"])
+	}
+}
+
+func TestFlowTriggerDeniesApprovals(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	flow := "nodes:\n  - name: greet\n    type: llm\n    prompt: Hello\nflow:\n  - from: START\n    to: greet\n  - from: greet\n    to: END\n"
+	if err := os.MkdirAll(filepath.Join(dir, "astonish", "agents"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "astonish", "agents", "greet.yaml"), []byte(flow), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	denied := make(chan bool, 1)
+	prev := GetRunHeadlessFunc()
+	SetRunHeadlessFunc(func(ctx context.Context, cfg *scheduler.HeadlessRunConfig) (string, error) {
+		denied <- cfg.DenyApprovals
+		return "done", nil
+	})
+	t.Cleanup(func() { SetRunHeadlessFunc(prev) })
+
+	trigger := func() bool {
+		t.Helper()
+		r := mux.SetURLVars(httptest.NewRequest(http.MethodPost, "/api/flows/greet/trigger", nil), map[string]string{"name": "greet"})
+		w := httptest.NewRecorder()
+		FlowTriggerHandler(w, r)
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("status = %d: %s", w.Code, w.Body)
+		}
+		return <-denied
+	}
+	if !trigger() {
+		t.Error("a triggered run approved tool calls by default")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "astonish", "config.yaml"), []byte("daemon:\n  trigger_auto_approve: true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if trigger() {
+		t.Error("daemon.trigger_auto_approve did not approve tool calls")
+	}
+}
+
+func TestFlowRunStatusTenant(t *testing.T) {
+	run, err := getFlowRunQueue().Enqueue(context.Background(), "acme/red", "greet", func(ctx context.Context) (string, error) {
+		return "done", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	status := func(team string) int {
+		r := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/api/flows/runs/"+run.ID, nil), map[string]string{"id": run.ID})
+		ctx := store.WithServices(r.Context(), &store.Services{Mode: store.ModePlatform})
+		ctx = store.WithTenantContext(ctx, &store.TenantContext{OrgSlug: "acme", TeamSlug: team})
+		w := httptest.NewRecorder()
+		FlowRunStatusHandler(w, r.WithContext(ctx))
+		return w.Code
+	}
+	if code := status("red"); code != http.StatusOK {
+		t.Errorf("status for the triggering team = %d, want 200", code)
+	}
+	if code := status("blue"); code != http.StatusNotFound {
+		t.Errorf("status for another team = %d, want 404", code)
+	}
+}
@@ -2,8 +2,15 @@ package api
 
 import (
 	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/SAP/astonish/pkg/agent"
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/hooks"
 	"gopkg.in/yaml.v3"
 )
 
@@ -40,12 +47,117 @@ func ValidateFlowYAML(yamlStr string, availableTools []ToolInfo) FlowValidationR
 		result.Errors = append(result.Errors, "Missing required top-level field: description")
 	}
 
+	// The glossary maps terms to definitions injected into LLM system prompts
+	glossary, isMap := flow["glossary"].(map[string]interface{})
+	if _, ok := flow["glossary"]; ok && !isMap {
+		result.Errors = append(result.Errors, "Invalid 'glossary' - must be a map of term: definition")
+	}
+	for term, def := range glossary {
+		if s, isStr := def.(string); !isStr || strings.TrimSpace(s) == "" {
+			result.Errors = append(result.Errors, fmt.Sprintf("Glossary term '%s': definition must be a non-empty string", term))
+		}
+	}
+
+	if v, ok := flow["max_tool_output_tokens"]; ok {
+		if f, isNum := numberValue(v); !isNum || f < 1 || f != float64(int(f)) {
+			result.Errors = append(result.Errors, "Invalid 'max_tool_output_tokens' - must be a positive integer")
+		}
+	}
+
+	if v, ok := flow["context_budget"]; ok {
+		if f, isNum := numberValue(v); !isNum || (f < 1 && f != -1) || f != float64(int(f)) {
+			result.Errors = append(result.Errors, "Invalid 'context_budget' - must be a positive number of tokens, or -1 to turn it off")
+		}
+	}
+
+	if v, ok := flow["export_state"]; ok {
+		if s, isStr := v.(string); !isStr || strings.TrimSpace(s) == "" {
+			result.Errors = append(result.Errors, "Invalid 'export_state' - must be a file path")
+		}
+	}
+
+	if v, ok := flow["shell"]; ok {
+		result.Errors = append(result.Errors, flowShellErrors(v)...)
+	}
+
+	if v, ok := flow["chat_mode"]; ok {
+		if _, isBool := v.(bool); !isBool {
+			result.Errors = append(result.Errors, "Invalid 'chat_mode' - must be true or false")
+		}
+	}
+
+	// Defaults are inherited by every node that does not set them itself
+	defaults, isMap := flow["defaults"].(map[string]interface{})
+	if _, ok := flow["defaults"]; ok && !isMap {
+		result.Errors = append(result.Errors, "Invalid 'defaults' - must be a map with system, tools_auto_approval, max_retries, max_tool_calls or generation")
+	}
+	for key, v := range defaults {
+		switch key {
+		case "system":
+			if _, isStr := v.(string); !isStr {
+				result.Errors = append(result.Errors, "Invalid 'defaults.system' - must be a string")
+			}
+		case "tools_auto_approval":
+			if _, isBool := v.(bool); !isBool {
+				result.Errors = append(result.Errors, "Invalid 'defaults.tools_auto_approval' - must be true or false")
+			}
+		case "max_retries":
+			if f, isNum := numberValue(v); !isNum || f < 1 || f != float64(int(f)) {
+				result.Errors = append(result.Errors, "Invalid 'defaults.max_retries' - must be a positive integer")
+			}
+		case "max_tool_calls":
+			if f, isNum := numberValue(v); !isNum || f < 1 || f != float64(int(f)) {
+				result.Errors = append(result.Errors, "Invalid 'defaults.max_tool_calls' - must be a positive integer")
+			}
+		case "generation":
+			gen, isGen := v.(map[string]interface{})
+			if !isGen {
+				result.Errors = append(result.Errors, "Invalid 'defaults.generation' - must be a map of sampling parameters")
+				continue
+			}
+			result.Errors = append(result.Errors, generationErrors("defaults", gen)...)
+		default:
+			result.Errors = append(result.Errors, fmt.Sprintf("Unknown key 'defaults.%s'. Valid keys: system, tools_auto_approval, max_retries, max_tool_calls, generation", key))
+		}
+	}
+
+	// Guardrails check LLM output; nodes can declare their own
+	flowGuardrailRoute := ""
+	if g, ok := flow["guardrails"]; ok {
+		var errs []string
+		errs, flowGuardrailRoute = guardrailsErrors("guardrails", g)
+		result.Errors = append(result.Errors, errs...)
+	}
+
+	// Variables are typed flow inputs with optional defaults
+	variables, isMap := flow["variables"].(map[string]interface{})
+	if _, ok := flow["variables"]; ok && !isMap {
+		result.Errors = append(result.Errors, "Invalid 'variables' - must be a map of name: {type, default, description}")
+	}
+	for name, v := range variables {
+		def, isDef := v.(map[string]interface{})
+		if !isDef {
+			result.Errors = append(result.Errors, fmt.Sprintf("Variable '%s': must be a map with type, default, description or required", name))
+			continue
+		}
+		if t, ok := def["type"]; ok {
+			if s, _ := t.(string); !config.ValidateVariableType(s) {
+				result.Errors = append(result.Errors, fmt.Sprintf("Variable '%s': invalid type '%v'. Valid types: str, int, float, bool, list, dict", name, t))
+			}
+		}
+	}
+
 	// Validate nodes
 	nodes, ok := flow["nodes"].([]interface{})
 	if !ok {
 		result.Errors = append(result.Errors, "Missing or invalid 'nodes' section - must be an array")
 	} else {
 		nodeNames := make(map[string]bool)
+		routeTargets := make(map[string]string)    // on_content_filter routes, checked once all nodes are known
+		guardrailRoutes := make(map[string]string) // guardrails routes by where they are declared
+		if flowGuardrailRoute != "" {
+			guardrailRoutes["guardrails"] = flowGuardrailRoute
+		}
 		for i, n := range nodes {
 			node, ok := n.(map[string]interface{})
 			if !ok {
@@ -66,6 +178,235 @@ func ValidateFlowYAML(yamlStr string, availableTools []ToolInfo) FlowValidationR
 				continue
 			}
 
+			if display, ok := node["display"].(string); ok {
+				switch display {
+				case "none", "user_message", "stream", "summary":
+				default:
+					result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': invalid display '%s'. Valid values: none, user_message, stream, summary", nodeName, display))
+				}
+			}
+			if _, ok := node["preview"]; ok && nodeType != "llm" {
+				result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': preview only applies to llm nodes", nodeName))
+			}
+
+			if backoff, ok := node["retry_backoff"].(string); ok {
+				switch backoff {
+				case "exponential", "constant", "none":
+				default:
+					result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': invalid retry_backoff '%s'. Valid values: exponential, constant, none", nodeName, backoff))
+				}
+			}
+			for _, field := range []string{"initial_delay", "max_delay"} {
+				if v, ok := node[field].(string); ok {
+					if _, err := time.ParseDuration(v); err != nil {
+						result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': invalid %s '%s' (use a duration like 2s or 500ms)", nodeName, field, v))
+					}
+				}
+			}
+
+			if v, ok := node["env"]; ok {
+				env, isMap := v.(map[string]interface{})
+				if !isMap {
+					result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': env must be a map of variable names to values", nodeName))
+				}
+				for name, value := range env {
+					if _, isString := value.(string); !isString || name == "" || strings.ContainsAny(name, "= ") {
+						result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': invalid env entry '%s' (use NAME: \"value\")", nodeName, name))
+					}
+				}
+			}
+
+			if v, ok := node["cache_tool_results"]; ok {
+				s, _ := v.(string)
+				if d, err := time.ParseDuration(s); err != nil || d <= 0 {
+					result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': invalid cache_tool_results '%v' (use a duration like 10m or 1h)", nodeName, v))
+				}
+			}
+
+			if v, ok := node["cache_response"]; ok && v == true {
+				temperature, hasTemperature := 0.0, false
+				for _, gen := range []interface{}{defaults["generation"], node["generation"]} {
+					if g, isMap := gen.(map[string]interface{}); isMap {
+						if t, isSet := g["temperature"]; isSet {
+							temperature, hasTemperature = numberValue(t)
+						}
+					}
+				}
+				switch {
+				case nodeType != "llm":
+					result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': cache_response is only supported on llm nodes", nodeName))
+				case node["tools"] == true:
+					result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': cache_response needs a node without tools, whose answer depends on the prompt alone", nodeName))
+				case !hasTemperature || temperature != 0:
+					result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': cache_response needs generation.temperature: 0, so the cached answer is the one the model would give", nodeName))
+				}
+			}
+
+			if schema, ok := node["output_schema"]; ok {
+				if m, isMap := schema.(map[string]interface{}); !isMap || m["type"] != "object" {
+					result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': output_schema must be a JSON Schema with type: object", nodeName))
+				} else if _, hasProps := m["properties"].(map[string]interface{}); !hasProps {
+					result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': output_schema must declare properties (each becomes a state key)", nodeName))
+				}
+			}
+
+			if parallel, ok := node["parallel"].(map[string]interface{}); ok {
+				// A branch runs only the node itself, so parallel work
+				// cannot nest; other node types cannot fan out at all
+				if nodeType != "llm" && nodeType != "tool" {
+					result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': parallel is only supported on llm and tool nodes", nodeName))
+				}
+				if v, ok := parallel["max_items"]; ok {
+					if f, isNum := numberValue(v); !isNum || f < 1 || f != float64(int(f)) {
+						result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': parallel.max_items must be a positive integer", nodeName))
+					}
+				}
+				if v, ok := parallel["reduce"]; ok {
+					if s, isStr := v.(string); !isStr || strings.TrimSpace(s) == "" {
+						result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': parallel.reduce must be non-empty merge instructions", nodeName))
+					}
+				}
+			}
+
+			guardrailRoute := flowGuardrailRoute
+			if g, ok := node["guardrails"]; ok {
+				where := fmt.Sprintf("Node '%s': guardrails", nodeName)
+				var errs []string
+				errs, guardrailRoute = guardrailsErrors(where, g)
+				result.Errors = append(result.Errors, errs...)
+				if guardrailRoute != "" {
+					guardrailRoutes[where] = guardrailRoute
+				}
+			}
+			if _, isParallel := node["parallel"]; isParallel && guardrailRoute != "" {
+				result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': guardrails action 'route' is not supported on parallel nodes", nodeName))
+			}
+
+			if cf, ok := node["on_content_filter"].(map[string]interface{}); ok {
+				switch action, _ := cf["action"].(string); action {
+				case "rephrase":
+				case "switch_model":
+					if m, _ := cf["model"].(string); m == "" {
+						result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': on_content_filter action 'switch_model' requires 'model'", nodeName))
+					}
+				case "route":
+					target, _ := cf["node"].(string)
+					routeTargets[nodeName] = target
+					if _, isParallel := node["parallel"]; isParallel {
+						result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': on_content_filter action 'route' is not supported on parallel nodes", nodeName))
+					}
+				default:
+					result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': on_content_filter action must be 'rephrase', 'switch_model' or 'route'", nodeName))
+				}
+			}
+
+			if model, ok := node["output_model"].(map[string]interface{}); ok {
+				for key, v := range model {
+					if entry, isMap := v.(map[string]interface{}); isMap {
+						// Long form: {type: str, sensitive: true}
+						if s, ok := entry["sensitive"]; ok {
+							if _, isBool := s.(bool); !isBool {
+								result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': output_model.%s.sensitive must be true or false", nodeName, key))
+							}
+						}
+						v = entry["type"]
+					}
+					if typeName, isStr := v.(string); isStr {
+						if _, err := config.ParseOutputType(typeName); err != nil {
+							result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': output_model.%s: %v", nodeName, key, err))
+						}
+					}
+				}
+			}
+
+			if gen, ok := node["generation"].(map[string]interface{}); ok {
+				result.Errors = append(result.Errors, generationErrors(fmt.Sprintf("Node '%s'", nodeName), gen)...)
+			}
+
+			if v, ok := node["max_parallel_tools"]; ok {
+				if f, isNum := numberValue(v); !isNum || f < 1 || f != float64(int(f)) {
+					result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': max_parallel_tools must be a positive integer", nodeName))
+				}
+			}
+
+			if v, ok := node["context_budget"]; ok {
+				if f, isNum := numberValue(v); !isNum || (f < 1 && f != -1) || f != float64(int(f)) {
+					result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': context_budget must be a positive number of tokens, or -1 to turn it off", nodeName))
+				}
+			}
+			if v, ok := node["max_tool_output_tokens"]; ok {
+				if f, isNum := numberValue(v); !isNum || f < 1 || f != float64(int(f)) {
+					result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': max_tool_output_tokens must be a positive integer", nodeName))
+				}
+			}
+			if v, ok := node["max_tool_calls"]; ok {
+				if f, isNum := numberValue(v); !isNum || f < 1 || f != float64(int(f)) {
+					result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': max_tool_calls must be a positive integer", nodeName))
+				}
+			}
+			if v, ok := node["on_tool_denied"]; ok {
+				if mode, _ := v.(string); mode != "skip" && mode != "feedback" {
+					result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': invalid on_tool_denied '%v'. Valid values: skip, feedback", nodeName, v))
+				}
+			}
+			if v, ok := node["tool_output_overflow"]; ok {
+				if mode, _ := v.(string); mode != "truncate" && mode != "summarize" {
+					result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': invalid tool_output_overflow '%v'. Valid values: truncate, summarize", nodeName, v))
+				}
+			}
+
+			if v, ok := node["glossary"]; ok {
+				if _, isBool := v.(bool); !isBool {
+					result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': glossary must be true or false", nodeName))
+				}
+			}
+			if terms, ok := node["glossary_terms"].([]interface{}); ok {
+				for _, t := range terms {
+					if term, _ := t.(string); glossary[term] == nil {
+						result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': glossary_terms references unknown term '%v'", nodeName, t))
+					}
+				}
+			}
+
+			if v, ok := node["templating"]; ok {
+				if mode, _ := v.(string); mode != agent.TemplatingBasic && mode != agent.TemplatingRich {
+					result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': invalid templating '%v'. Valid values: basic, rich", nodeName, v))
+				} else if mode == agent.TemplatingRich {
+					templates := []interface{}{node["prompt"], node["system"]}
+					if msgs, ok := node["user_message"].([]interface{}); ok {
+						templates = append(templates, msgs...)
+					}
+					if args, ok := node["args"].(map[string]interface{}); ok {
+						for _, a := range args {
+							templates = append(templates, a)
+						}
+					}
+					for _, t := range templates {
+						if s, ok := t.(string); ok {
+							if err := agent.ParseTemplate(s); err != nil {
+								result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': invalid template: %v", nodeName, err))
+							}
+						}
+					}
+				}
+			}
+
+			for _, field := range []string{"input_type", "default", "validation", "multi_select", "input_mode", "file_root", "file_pattern"} {
+				if _, ok := node[field]; ok && nodeType != "input" {
+					result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': %s is only supported on input nodes", nodeName, field))
+				}
+			}
+
+			for _, field := range []string{"diff", "confirm"} {
+				if _, ok := node[field]; ok && nodeType != "output" {
+					result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': %s is only supported on output nodes", nodeName, field))
+				}
+			}
+
+			if _, ok := node["report"]; ok && nodeType != "output" {
+				result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': report is only supported on output nodes", nodeName))
+			}
+
 			// Validate node type specific fields
 			switch nodeType {
 			case "input":
@@ -73,6 +414,49 @@ func ValidateFlowYAML(yamlStr string, availableTools []ToolInfo) FlowValidationR
 				if _, ok := node["output_model"]; !ok {
 					result.Errors = append(result.Errors, fmt.Sprintf("Node '%s' (input): missing required field 'output_model'", nodeName))
 				}
+				if v, ok := node["input_type"]; ok {
+					if t, _ := v.(string); !slices.Contains(config.InputTypes, t) {
+						result.Errors = append(result.Errors, fmt.Sprintf("Node '%s' (input): invalid input_type '%v'. Valid values: %s", nodeName, v, strings.Join(config.InputTypes, ", ")))
+					}
+				}
+				if multi, _ := node["multi_select"].(bool); multi {
+					if _, ok := node["options"]; !ok && node["input_mode"] != config.InputModeFile {
+						result.Errors = append(result.Errors, fmt.Sprintf("Node '%s' (input): multi_select needs 'options'", nodeName))
+					}
+					if t, _ := node["input_type"].(string); t != "" && t != config.InputText {
+						result.Errors = append(result.Errors, fmt.Sprintf("Node '%s' (input): multi_select cannot be combined with input_type '%s'", nodeName, t))
+					}
+				}
+				mode, _ := node["input_mode"].(string)
+				if v, ok := node["input_mode"]; ok && mode != config.InputModeText && mode != config.InputModeFile {
+					result.Errors = append(result.Errors, fmt.Sprintf("Node '%s' (input): invalid input_mode '%v'. Valid values: text, file", nodeName, v))
+				}
+				if mode == config.InputModeFile {
+					if _, ok := node["options"]; ok {
+						result.Errors = append(result.Errors, fmt.Sprintf("Node '%s' (input): options cannot be used with input_mode file", nodeName))
+					}
+					if pattern, ok := node["file_pattern"].(string); ok {
+						if _, err := filepath.Match(pattern, ""); err != nil {
+							result.Errors = append(result.Errors, fmt.Sprintf("Node '%s' (input): invalid file_pattern: %v", nodeName, err))
+						}
+					}
+				} else {
+					for _, field := range []string{"file_root", "file_pattern"} {
+						if _, ok := node[field]; ok {
+							result.Errors = append(result.Errors, fmt.Sprintf("Node '%s' (input): %s needs input_mode file", nodeName, field))
+						}
+					}
+				}
+				if v, ok := node["validation"].(map[string]interface{}); ok {
+					if pattern, ok := v["regex"].(string); ok {
+						if _, err := regexp.Compile(pattern); err != nil {
+							result.Errors = append(result.Errors, fmt.Sprintf("Node '%s' (input): invalid validation regex: %v", nodeName, err))
+						}
+					}
+					if v["regex"] == nil && v["expr"] == nil {
+						result.Errors = append(result.Errors, fmt.Sprintf("Node '%s' (input): validation needs 'expr' or 'regex'", nodeName))
+					}
+				}
 			case "llm":
 				// LLM nodes should have prompt
 				if _, ok := node["prompt"]; !ok {
@@ -90,10 +474,38 @@ func ValidateFlowYAML(yamlStr string, availableTools []ToolInfo) FlowValidationR
 					}
 				}
 			case "output":
-				// output nodes require user_message
-				if _, ok := node["user_message"]; !ok {
+				// output nodes require user_message, or a diff to show
+				diff, hasDiff := node["diff"].(map[string]interface{})
+				if _, ok := node["user_message"]; !ok && !hasDiff {
 					result.Errors = append(result.Errors, fmt.Sprintf("Node '%s' (output): missing required field 'user_message' (should be an array)", nodeName))
 				}
+				if v, ok := node["report"]; ok {
+					if _, isBool := v.(bool); !isBool {
+						if _, isMap := v.(map[string]interface{}); !isMap {
+							result.Errors = append(result.Errors, fmt.Sprintf("Node '%s' (output): report must be true, false or a map with title, file and artifacts", nodeName))
+						}
+					}
+				}
+				if hasDiff {
+					if n, _ := diff["new"].(string); n == "" {
+						result.Errors = append(result.Errors, fmt.Sprintf("Node '%s' (output): diff needs 'new'", nodeName))
+					}
+					if style, ok := diff["style"]; ok && style != config.DiffUnified && style != config.DiffSideBySide {
+						result.Errors = append(result.Errors, fmt.Sprintf("Node '%s' (output): invalid diff style '%v'. Valid values: unified, side-by-side", nodeName, style))
+					}
+				} else if _, ok := node["diff"]; ok {
+					result.Errors = append(result.Errors, fmt.Sprintf("Node '%s' (output): diff must be a map with old, new, path and style", nodeName))
+				}
+				if v, ok := node["confirm"]; ok {
+					if key, _ := v.(string); key == "" {
+						result.Errors = append(result.Errors, fmt.Sprintf("Node '%s' (output): confirm must be the state key to store the answer under", nodeName))
+					}
+				}
+				if v, ok := node["artifact"]; ok {
+					if name, isStr := v.(string); !isStr || strings.TrimSpace(name) == "" || strings.ContainsAny(name, `/\`) {
+						result.Errors = append(result.Errors, fmt.Sprintf("Node '%s' (output): artifact must be a file name without directories", nodeName))
+					}
+				}
 			case "tool":
 				// tool nodes require tools_selection
 				if _, ok := node["tools_selection"]; !ok {
@@ -119,11 +531,122 @@ func ValidateFlowYAML(yamlStr string, availableTools []ToolInfo) FlowValidationR
 				if !hasUpdates && !(hasAction && hasOutputModel && (hasSourceVar || hasValue)) {
 					result.Errors = append(result.Errors, fmt.Sprintf("Node '%s' (update_state): requires either 'updates' field OR 'action' + 'output_model' + ('source_variable' OR 'value')", nodeName))
 				}
+			case "script":
+				script, _ := node["script"].(string)
+				if strings.TrimSpace(script) == "" {
+					result.Errors = append(result.Errors, fmt.Sprintf("Node '%s' (script): missing required field 'script'", nodeName))
+				} else if err := agent.ParseScript(script); err != nil {
+					result.Errors = append(result.Errors, fmt.Sprintf("Node '%s' (script): invalid Starlark: %v", nodeName, err))
+				}
+			case "memory":
+				mem, ok := node["memory"].(map[string]interface{})
+				if !ok {
+					result.Errors = append(result.Errors, fmt.Sprintf("Node '%s' (memory): missing required field 'memory'", nodeName))
+					break
+				}
+				action, _ := mem["action"].(string)
+				switch action {
+				case "store":
+					if content, _ := mem["content"].(string); strings.TrimSpace(content) == "" {
+						result.Errors = append(result.Errors, fmt.Sprintf("Node '%s' (memory): action 'store' requires 'content'", nodeName))
+					}
+				case "recall":
+					if om, ok := node["output_model"].(map[string]interface{}); ok && len(om) > 1 {
+						result.Errors = append(result.Errors, fmt.Sprintf("Node '%s' (memory): recall writes a single output_model key", nodeName))
+					}
+				default:
+					result.Errors = append(result.Errors, fmt.Sprintf("Node '%s' (memory): action must be 'store' or 'recall'", nodeName))
+				}
+				if scope, _ := mem["scope"].(string); scope != "" && scope != "flow" && scope != "user" {
+					result.Errors = append(result.Errors, fmt.Sprintf("Node '%s' (memory): scope must be 'flow' or 'user'", nodeName))
+				}
+				if limit, ok := mem["limit"].(int); ok && limit < 0 {
+					result.Errors = append(result.Errors, fmt.Sprintf("Node '%s' (memory): limit must not be negative", nodeName))
+				}
+			case "vector":
+				vec, ok := node["vector"].(map[string]interface{})
+				if !ok {
+					result.Errors = append(result.Errors, fmt.Sprintf("Node '%s' (vector): missing required field 'vector'", nodeName))
+					break
+				}
+				action, _ := vec["action"].(string)
+				switch action {
+				case "index":
+					text, _ := vec["text"].(string)
+					sourceVar, _ := vec["source_variable"].(string)
+					if strings.TrimSpace(text) == "" && sourceVar == "" {
+						result.Errors = append(result.Errors, fmt.Sprintf("Node '%s' (vector): action 'index' requires 'text' or 'source_variable'", nodeName))
+					}
+				case "search":
+					if query, _ := vec["query"].(string); strings.TrimSpace(query) == "" {
+						result.Errors = append(result.Errors, fmt.Sprintf("Node '%s' (vector): action 'search' requires 'query'", nodeName))
+					}
+				default:
+					result.Errors = append(result.Errors, fmt.Sprintf("Node '%s' (vector): action must be 'index' or 'search'", nodeName))
+				}
+				if om, ok := node["output_model"].(map[string]interface{}); ok && len(om) > 1 {
+					result.Errors = append(result.Errors, fmt.Sprintf("Node '%s' (vector): output_model takes a single key", nodeName))
+				}
+			case "abort":
+				if v, ok := node["exit_code"]; ok {
+					if f, isNum := numberValue(v); !isNum || f < 1 || f > 255 || f != float64(int(f)) {
+						result.Errors = append(result.Errors, fmt.Sprintf("Node '%s' (abort): exit_code must be an integer from 1 to 255", nodeName))
+					}
+				}
 			default:
-				result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': unknown node type '%s'. Valid types: input, llm, output, tool, update_state", nodeName, nodeType))
+				result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': unknown node type '%s'. Valid types: input, llm, output, tool, update_state, script, memory, vector, abort", nodeName, nodeType))
 			}
 		}
 
+		for nodeName, target := range routeTargets {
+			if !nodeNames[target] {
+				result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': on_content_filter action 'route' requires 'node' naming an existing node", nodeName))
+			}
+		}
+		if v, ok := flow["on_error"]; ok {
+			if target, _ := v.(string); !nodeNames[target] {
+				result.Errors = append(result.Errors, fmt.Sprintf("Invalid 'on_error' - '%v' is not a node of the flow", v))
+			}
+		}
+		for _, n := range nodes {
+			node, _ := n.(map[string]interface{})
+			v, ok := node["on_error"]
+			if !ok {
+				continue
+			}
+			nodeName, _ := node["name"].(string)
+			if target, _ := v.(string); !nodeNames[target] {
+				result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': on_error '%v' is not a node of the flow", nodeName, v))
+			} else if target == nodeName {
+				result.Errors = append(result.Errors, fmt.Sprintf("Node '%s': on_error cannot name the node itself", nodeName))
+			}
+		}
+		for where, target := range guardrailRoutes {
+			if !nodeNames[target] {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: 'node' references unknown node '%s'", where, target))
+			}
+		}
+
+		if v, ok := flow["loop_to"]; ok {
+			if s, _ := v.(string); !nodeNames[s] {
+				result.Errors = append(result.Errors, fmt.Sprintf("Invalid 'loop_to' - '%v' is not a node of the flow", v))
+			}
+		}
+
+		if v, ok := flow["tests"]; ok {
+			result.Errors = append(result.Errors, flowTestErrors(v, nodes)...)
+		}
+
+		if v, ok := flow["max_node_visits"]; ok {
+			if f, isNum := numberValue(v); !isNum || (f < 1 && f != -1) || f != float64(int(f)) {
+				result.Errors = append(result.Errors, "Invalid 'max_node_visits' - must be a positive integer, or -1 to turn it off")
+			}
+		}
+
+		if v, ok := flow["hooks"]; ok {
+			result.Errors = append(result.Errors, flowHookErrors(v)...)
+		}
+
 		// Validate flow edges
 		flowEdges, ok := flow["flow"].([]interface{})
 		if !ok {
@@ -170,6 +693,7 @@ func ValidateFlowYAML(yamlStr string, availableTools []ToolInfo) FlowValidationR
 					}
 				}
 			}
+			result.Errors = append(result.Errors, flowLoopErrors(nodes, flowEdges)...)
 		}
 	}
 
@@ -179,6 +703,67 @@ func ValidateFlowYAML(yamlStr string, availableTools []ToolInfo) FlowValidationR
 	return result
 }
 
+// flowTestErrors checks a tests: section: each test has a unique name, and
+// the nodes its inputs, llm responses and expected path name exist and have
+// the right type.
+func flowTestErrors(v interface{}, nodes []interface{}) []string {
+	tests, ok := v.([]interface{})
+	if !ok {
+		return []string{"Invalid 'tests' - must be a list of tests"}
+	}
+	nodeTypes := make(map[string]string)
+	for _, n := range nodes {
+		node, _ := n.(map[string]interface{})
+		name, _ := node["name"].(string)
+		nodeType, _ := node["type"].(string)
+		nodeTypes[name] = nodeType
+	}
+
+	var errs []string
+	seen := make(map[string]bool)
+	for i, t := range tests {
+		test, ok := t.(map[string]interface{})
+		if !ok {
+			errs = append(errs, fmt.Sprintf("Test %d: invalid test format", i))
+			continue
+		}
+		name, _ := test["name"].(string)
+		where := fmt.Sprintf("Test '%s'", name)
+		switch {
+		case name == "":
+			where = fmt.Sprintf("Test %d", i)
+			errs = append(errs, where+": missing 'name'")
+		case seen[name]:
+			errs = append(errs, where+": duplicate test name")
+		}
+		seen[name] = true
+
+		checkNodes := func(field, wantType string) {
+			entries, _ := test[field].(map[string]interface{})
+			for node := range entries {
+				nodeType, ok := nodeTypes[node]
+				switch {
+				case !ok:
+					errs = append(errs, fmt.Sprintf("%s: %s references unknown node '%s'", where, field, node))
+				case nodeType != wantType:
+					errs = append(errs, fmt.Sprintf("%s: %s node '%s' is not a %s node", where, field, node, wantType))
+				}
+			}
+		}
+		checkNodes("inputs", "input")
+		checkNodes("llm", "llm")
+
+		expect, _ := test["expect"].(map[string]interface{})
+		path, _ := expect["path"].([]interface{})
+		for _, p := range path {
+			if node, _ := p.(string); nodeTypes[node] == "" {
+				errs = append(errs, fmt.Sprintf("%s: expected path references unknown node '%v'", where, p))
+			}
+		}
+	}
+	return errs
+}
+
 // FormatValidationErrors formats validation errors for LLM feedback
 func FormatValidationErrors(errors []string) string {
 	var sb strings.Builder
@@ -189,3 +774,177 @@ func FormatValidationErrors(errors []string) string {
 	sb.WriteString("\nPlease fix these errors and regenerate the YAML.")
 	return sb.String()
 }
+
+// generationErrors checks the sampling parameters of a generation: block;
+// where names the node or section in the messages.
+func generationErrors(where string, gen map[string]interface{}) []string {
+	var errs []string
+	for field, max := range map[string]float64{"temperature": 2, "top_p": 1} {
+		if v, ok := gen[field]; ok {
+			if f, isNum := numberValue(v); !isNum || f < 0 || f > max {
+				errs = append(errs, fmt.Sprintf("%s: generation.%s must be a number between 0 and %g", where, field, max))
+			}
+		}
+	}
+	if v, ok := gen["max_output_tokens"]; ok {
+		if f, isNum := numberValue(v); !isNum || f < 1 || f != float64(int(f)) {
+			errs = append(errs, fmt.Sprintf("%s: generation.max_output_tokens must be a positive integer", where))
+		}
+	}
+	return errs
+}
+
+// guardrailsErrors checks a guardrails: block; where names the flow or node
+// in the messages. route is the node named by action route, checked by the
+// caller once all nodes are known.
+func guardrailsErrors(where string, v interface{}) (errs []string, route string) {
+	g, isMap := v.(map[string]interface{})
+	if !isMap {
+		return []string{where + " must be a map with deny, pii, classifier, action or node"}, ""
+	}
+	checks := 0
+	if deny, ok := g["deny"]; ok {
+		patterns, isList := deny.([]interface{})
+		if !isList {
+			errs = append(errs, where+": deny must be a list of regular expressions")
+		}
+		for _, p := range patterns {
+			pattern, _ := p.(string)
+			if _, err := regexp.Compile(pattern); err != nil || pattern == "" {
+				errs = append(errs, fmt.Sprintf("%s: invalid deny pattern '%v'", where, p))
+			}
+		}
+		checks += len(patterns)
+	}
+	if pii, ok := g["pii"]; ok {
+		kinds, isList := pii.([]interface{})
+		if !isList {
+			errs = append(errs, where+": pii must be a list of kinds")
+		}
+		for _, k := range kinds {
+			if kind, _ := k.(string); !slices.Contains(config.PIIKinds, kind) {
+				errs = append(errs, fmt.Sprintf("%s: unknown pii kind '%v'. Valid kinds: %s", where, k, strings.Join(config.PIIKinds, ", ")))
+			}
+		}
+		checks += len(kinds)
+	}
+	if c, ok := g["classifier"]; ok {
+		classifier, isMap := c.(map[string]interface{})
+		if policy, _ := classifier["policy"].(string); !isMap || strings.TrimSpace(policy) == "" {
+			errs = append(errs, where+": classifier requires a 'policy'")
+		}
+		checks++
+	}
+	if checks == 0 {
+		errs = append(errs, where+": declares no checks (deny, pii or classifier)")
+	}
+
+	action := "block"
+	if a, ok := g["action"]; ok {
+		action, _ = a.(string)
+	}
+	if !slices.Contains(config.GuardrailActions, action) {
+		errs = append(errs, fmt.Sprintf("%s: action must be %s", where, strings.Join(config.GuardrailActions, ", ")))
+	}
+	if action == "route" {
+		if route, _ = g["node"].(string); route == "" {
+			errs = append(errs, where+": action 'route' requires 'node'")
+		}
+	}
+	return errs, route
+}
+
+// flowShellErrors checks a flow's shell: block, which can set the
+// container of shell_command and deny further commands.
+func flowShellErrors(v interface{}) (errs []string) {
+	shell, isMap := v.(map[string]interface{})
+	if !isMap {
+		return []string{"Invalid 'shell' - must be a map with container or deny_commands"}
+	}
+	for key, val := range shell {
+		switch key {
+		case "container":
+			container, isMap := val.(map[string]interface{})
+			if image, _ := container["image"].(string); !isMap || strings.TrimSpace(image) == "" {
+				errs = append(errs, "Invalid 'shell.container' - requires an 'image'")
+			}
+			if runtime, ok := container["runtime"]; ok && runtime != "docker" && runtime != "podman" {
+				errs = append(errs, fmt.Sprintf("Invalid 'shell.container.runtime' '%v'. Valid values: docker, podman", runtime))
+			}
+		case "deny_commands":
+			patterns, isList := val.([]interface{})
+			if !isList {
+				errs = append(errs, "Invalid 'shell.deny_commands' - must be a list of regular expressions")
+			}
+			for _, p := range patterns {
+				pattern, _ := p.(string)
+				if _, err := regexp.Compile(pattern); err != nil || pattern == "" {
+					errs = append(errs, fmt.Sprintf("Invalid 'shell.deny_commands' pattern '%v'", p))
+				}
+			}
+		default:
+			errs = append(errs, fmt.Sprintf("Invalid 'shell.%s' - a flow can only set container and deny_commands; other limits belong in security.shell of config.yaml", key))
+		}
+	}
+	return errs
+}
+
+// numberValue returns v as a float64 if it is a YAML/JSON number.
+func numberValue(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// flowHookErrors checks the hooks section: each hook has a url, and
+// registers for known events. Command hooks run unapproved shell commands
+// and $VAR in headers would send the host's secrets to the flow's URL, so
+// only the app config may use them.
+func flowHookErrors(v interface{}) []string {
+	list, ok := v.([]interface{})
+	if !ok {
+		return []string{"Invalid 'hooks' - must be a list of hooks"}
+	}
+	var errs []string
+	for i, h := range list {
+		hook, ok := h.(map[string]interface{})
+		if !ok {
+			errs = append(errs, fmt.Sprintf("Hook %d: invalid hook format", i))
+			continue
+		}
+		url, _ := hook["url"].(string)
+		command, _ := hook["command"].(string)
+		switch {
+		case command != "":
+			errs = append(errs, fmt.Sprintf("Hook %d: 'command' hooks are only allowed in the app config, flow hooks must use 'url'", i))
+		case url == "":
+			errs = append(errs, fmt.Sprintf("Hook %d: missing required field 'url'", i))
+		}
+		if headers, ok := hook["headers"].(map[string]interface{}); ok {
+			for name, value := range headers {
+				if v, _ := value.(string); strings.Contains(v, "$") {
+					errs = append(errs, fmt.Sprintf("Hook %d: header '%s': environment variables are only read for app config hooks", i, name))
+				}
+			}
+		}
+		if events, ok := hook["events"]; ok {
+			names, ok := events.([]interface{})
+			if !ok {
+				errs = append(errs, fmt.Sprintf("Hook %d: 'events' must be a list", i))
+				continue
+			}
+			for _, e := range names {
+				if name, _ := e.(string); !slices.Contains(hooks.Events, name) {
+					errs = append(errs, fmt.Sprintf("Hook %d: unknown event '%v'. Valid events: %s", i, e, strings.Join(hooks.Events, ", ")))
+				}
+			}
+		}
+	}
+	return errs
+}
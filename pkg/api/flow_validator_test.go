@@ -0,0 +1,95 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestValidateFlowYAMLParallelNodeTypes(t *testing.T) {
+	const parallelErr = "parallel is only supported on llm and tool nodes"
+	tests := []struct {
+		name    string
+		node    string
+		wantErr bool
+	}{
+		{
+			name: "llm node",
+			node: `{name: review, type: llm, prompt: "Review {item}", output_model: {review: str},
+  parallel: {forEach: "{files}", as: item}}`,
+		},
+		{
+			name: "tool node",
+			node: `{name: review, type: tool, tools_selection: [read_file], output_model: {review: str},
+  parallel: {forEach: "{files}", as: item}}`,
+		},
+		{
+			name:    "input node",
+			node:    `{name: review, type: input, prompt: "Next?", output_model: {review: str}, parallel: {forEach: "{files}", as: item}}`,
+			wantErr: true,
+		},
+		{
+			name:    "script node",
+			node:    `{name: review, type: script, script: "print(1)", parallel: {forEach: "{files}", as: item}}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flow := `
+name: review
+description: Review files
+nodes:
+  - ` + tt.node + `
+flow:
+  - {from: START, to: review}
+  - {from: review, to: END}`
+			result := ValidateFlowYAML(flow, []ToolInfo{{Name: "read_file"}})
+			got := false
+			for _, e := range result.Errors {
+				got = got || strings.Contains(e, parallelErr)
+			}
+			if got != tt.wantErr {
+				t.Errorf("parallel error = %v, want %v; errors: %v", got, tt.wantErr, result.Errors)
+			}
+		})
+	}
+}
+
+func TestValidateFlowYAMLHooks(t *testing.T) {
+	tests := []struct {
+		name    string
+		hook    string
+		wantErr string
+	}{
+		{name: "webhook", hook: `{url: "https://hooks.example.com/astonish", events: [run_end]}`},
+		{name: "command", hook: `{command: "jq -c . >> audit.jsonl"}`, wantErr: "only allowed in the app config"},
+		{name: "neither", hook: `{events: [run_end]}`, wantErr: "missing required field 'url'"},
+		{name: "environment in headers", hook: `{url: "https://hooks.example.com/astonish", headers: {Authorization: "Bearer ${OPENAI_API_KEY}"}}`, wantErr: "only read for app config hooks"},
+		{name: "unknown event", hook: `{url: "https://hooks.example.com/astonish", events: [run_done]}`, wantErr: "unknown event 'run_done'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := flowHookErrors([]interface{}{mustParseYAML(t, tt.hook)})
+			if tt.wantErr == "" {
+				if len(errs) > 0 {
+					t.Errorf("unexpected errors: %v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || !strings.Contains(errs[0], tt.wantErr) {
+				t.Errorf("errors = %v, want one containing %q", errs, tt.wantErr)
+			}
+		})
+	}
+}
+
+func mustParseYAML(t *testing.T, s string) interface{} {
+	t.Helper()
+	var v interface{}
+	if err := yaml.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
@@ -1030,6 +1030,9 @@ func RegisterRoutes(router *mux.Router, svc *store.Services, backend store.Platf
 	router.HandleFunc("/api/agents/{name}/publish", FlowPublishToTeamHandler).Methods("POST")
 	router.HandleFunc("/api/agents/{name}/fork", FlowForkToPersonalHandler).Methods("POST")
 	router.HandleFunc("/api/agents/{name:.*}/copy-to-local", CopyAgentToLocalHandler).Methods("POST")
+	// Webhook trigger: queue a headless run, poll its status by run ID
+	router.HandleFunc("/api/flows/runs/{id}", FlowRunStatusHandler).Methods("GET")
+	router.HandleFunc("/api/flows/{name}/trigger", FlowTriggerHandler).Methods("POST")
 	router.HandleFunc("/api/tools", ListToolsHandler).Methods("GET")
 	router.HandleFunc("/api/tools/web-capable", WebCapableToolsHandler).Methods("GET")
 	router.HandleFunc("/api/ai/chat", AIChatHandler).Methods("POST")
@@ -1186,9 +1189,19 @@ func RegisterRoutes(router *mux.Router, svc *store.Services, backend store.Platf
 
 	// Execution endpoints
 	router.HandleFunc("/api/chat", HandleChat).Methods("POST")
+	router.HandleFunc("/api/chat/ws", HandleRunWebSocket).Methods("GET")
 	router.HandleFunc("/api/session/{id}/stop", HandleStopSession).Methods("POST")
+	router.HandleFunc("/api/session/{id}/cancel", HandleCancelRun).Methods("POST")
 	router.HandleFunc("/api/session/{id}/keepalive", HandleSessionKeepalive).Methods("POST")
 
+	// Run history endpoints (past Studio flow runs, read-only replay)
+	router.HandleFunc("/api/runs", ListRunsHandler).Methods("GET")
+	router.HandleFunc("/api/runs/{id}", GetRunHandler).Methods("GET")
+	router.HandleFunc("/api/runs/{id}/replay", ReplayRunHandler).Methods("GET")
+	router.HandleFunc("/api/runs/{id}/report", GetRunReportHandler).Methods("GET")
+	router.HandleFunc("/api/runs/{id}/artifacts", ListRunArtifactsHandler).Methods("GET")
+	router.HandleFunc("/api/runs/{id}/artifacts/{name}", GetRunArtifactHandler).Methods("GET")
+
 	// Channels endpoints
 	router.HandleFunc("/api/channels/status", ChannelsStatusHandler).Methods("GET")
 	router.HandleFunc("/api/channels/reload", ChannelsReloadHandler).Methods("POST")
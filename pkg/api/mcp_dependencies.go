@@ -209,6 +209,49 @@ func CollectToolsFromNodes(nodes []config.Node) []string {
 	return tools
 }
 
+// MCPServerInstall is an MCP server a flow depends on that is not
+// configured yet, with the config to add it under Server.
+type MCPServerInstall struct {
+	Server string
+	Config config.MCPServerConfig
+	Source string // Where Config comes from: inline (the flow itself), or the store server's source (official or a tap name)
+}
+
+// PlanMCPDependencyInstall resolves the dependencies mcpConfig lacks to the
+// configs to install: inline ones from their config, store and tap ones from
+// the store server with their store_id, or else their name. Dependencies
+// that cannot be resolved are returned by server name.
+func PlanMCPDependencyInstall(deps []config.MCPDependency, mcpConfig *config.MCPConfig, storeServers []mcpstore.Server) (install []MCPServerInstall, unresolved []string) {
+	for _, dep := range sanitizeMCPDependencies(deps) {
+		if _, ok := mcpConfig.MCPServers[dep.Server]; ok {
+			continue
+		}
+		if dep.Config != nil {
+			install = append(install, MCPServerInstall{Server: dep.Server, Config: *dep.Config, Source: "inline"})
+			continue
+		}
+		var srv *mcpstore.Server
+		if dep.StoreID != "" {
+			srv = mcpstore.GetServer(storeServers, dep.StoreID)
+		}
+		if srv == nil {
+			srv = mcpstore.GetServerByName(storeServers, dep.Server)
+		}
+		if srv == nil || srv.Config == nil {
+			unresolved = append(unresolved, dep.Server)
+			continue
+		}
+		install = append(install, MCPServerInstall{Server: dep.Server, Config: config.MCPServerConfig{
+			Command:   srv.Config.Command,
+			Args:      srv.Config.Args,
+			Env:       srv.Config.Env,
+			Transport: srv.Config.Transport,
+			URL:       srv.Config.URL,
+		}, Source: srv.Source})
+	}
+	return install, unresolved
+}
+
 // MCPDependencyStatus represents the status of a single MCP dependency
 type MCPDependencyStatus struct {
 	Server    string                  `json:"server"`
@@ -544,3 +544,37 @@ func TestCheckMCPDependenciesResponse_AllInstalled(t *testing.T) {
 		})
 	}
 }
+
+// TestPlanMCPDependencyInstall verifies that only missing dependencies are
+// installed, resolved from their inline config or the store.
+func TestPlanMCPDependencyInstall(t *testing.T) {
+	deps := []config.MCPDependency{
+		{Server: "github", Tools: []string{"list_issues"}, Source: "store", StoreID: "official/github"},
+		{Server: "jira", Tools: []string{"get_issue"}, Source: "tap"},
+		{Server: "local", Tools: []string{"query"}, Source: "inline", Config: &config.MCPServerConfig{Command: "local-mcp"}},
+		{Server: "installed", Tools: []string{"run"}, Source: "inline"},
+		{Server: "unknown", Tools: []string{"x"}, Source: "store", StoreID: "official/unknown"},
+	}
+	mcpConfig := &config.MCPConfig{MCPServers: map[string]config.MCPServerConfig{"installed": {Command: "run-mcp"}}}
+	storeServers := []mcpstore.Server{
+		{McpId: "official/github", Name: "GitHub", Source: "official", Config: &mcpstore.ServerConfig{Command: "npx", Args: []string{"github-mcp"}, Env: map[string]string{"GITHUB_TOKEN": ""}}},
+		{McpId: "team/jira", Name: "jira", Source: "team", Config: &mcpstore.ServerConfig{Transport: "sse", URL: "https://jira.example.com/sse"}},
+	}
+
+	install, unresolved := PlanMCPDependencyInstall(deps, mcpConfig, storeServers)
+	if len(install) != 3 {
+		t.Fatalf("install = %+v, want github, jira and local", install)
+	}
+	if install[0].Server != "github" || install[0].Config.Command != "npx" || len(install[0].Config.Env) != 1 || install[0].Source != "official" {
+		t.Errorf("github = %+v", install[0])
+	}
+	if install[1].Server != "jira" || install[1].Config.URL != "https://jira.example.com/sse" || install[1].Source != "team" {
+		t.Errorf("jira = %+v", install[1])
+	}
+	if install[2].Server != "local" || install[2].Config.Command != "local-mcp" || install[2].Source != "inline" {
+		t.Errorf("local = %+v", install[2])
+	}
+	if len(unresolved) != 1 || unresolved[0] != "unknown" {
+		t.Errorf("unresolved = %v", unresolved)
+	}
+}
@@ -129,8 +129,8 @@ func CreateMCPPlatformServerHandler(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusBadRequest, "Command is required for stdio transport")
 		return
 	}
-	if req.Transport == "sse" && req.URL == "" {
-		respondError(w, http.StatusBadRequest, "URL is required for SSE transport")
+	if req.Transport != "stdio" && req.URL == "" {
+		respondError(w, http.StatusBadRequest, "URL is required for "+req.Transport+" transport")
 		return
 	}
 
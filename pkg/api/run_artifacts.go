@@ -0,0 +1,71 @@
+package api
+
+import (
+	"errors"
+	"io/fs"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/SAP/astonish/pkg/artifacts"
+	"github.com/gorilla/mux"
+)
+
+// ListRunArtifactsHandler handles GET /api/runs/{id}/artifacts: the latest
+// version of each artifact the run saved.
+func ListRunArtifactsHandler(w http.ResponseWriter, r *http.Request) {
+	if _, _, ok := loadRun(w, r); !ok {
+		return
+	}
+	svc, err := artifacts.Default()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	saved, err := svc.SessionArtifacts(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if saved == nil {
+		saved = []artifacts.Info{}
+	}
+	respondJSON(w, http.StatusOK, map[string]any{"artifacts": saved})
+}
+
+// GetRunArtifactHandler handles GET /api/runs/{id}/artifacts/{name}: the
+// content of an artifact, as a download. ?version=N selects an older
+// version.
+func GetRunArtifactHandler(w http.ResponseWriter, r *http.Request) {
+	if _, _, ok := loadRun(w, r); !ok {
+		return
+	}
+	var version int64
+	if v := r.URL.Query().Get("version"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 {
+			respondError(w, http.StatusBadRequest, "version must be a positive integer")
+			return
+		}
+		version = n
+	}
+	svc, err := artifacts.Default()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	vars := mux.Vars(r)
+	info, err := svc.SessionArtifact(vars["id"], vars["name"], version)
+	if errors.Is(err, fs.ErrNotExist) {
+		respondError(w, http.StatusNotFound, "artifact not found")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", info.MimeType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": info.Name}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeFile(w, r, info.Path)
+}
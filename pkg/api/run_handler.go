@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/SAP/astonish/pkg/agent"
+	"github.com/SAP/astonish/pkg/artifacts"
 	"github.com/SAP/astonish/pkg/browser"
 	"github.com/SAP/astonish/pkg/cache"
 	"github.com/SAP/astonish/pkg/common"
@@ -22,6 +23,7 @@ import (
 	"github.com/SAP/astonish/pkg/provider"
 	"github.com/SAP/astonish/pkg/sandbox"
 	incus "github.com/SAP/astonish/pkg/sandbox/incus"
+	persistentsession "github.com/SAP/astonish/pkg/session"
 	"github.com/SAP/astonish/pkg/store"
 	"github.com/SAP/astonish/pkg/tools"
 	adkagent "google.golang.org/adk/agent"
@@ -48,13 +50,21 @@ type SessionManager struct {
 	service         session.Service
 	sessions        map[string]session.Session
 	mcpManagers     map[string]*mcp.Manager // MCP manager per session
-	lastActivity    map[string]time.Time    // Last activity time per session
+	lastActivity    map[string]time.Time    // Last activity time per session (runs and keepalives)
+	lastInteraction map[string]time.Time    // Last flow run per session (keepalives excluded)
+	activeRuns      map[string]int          // In-flight runs per session (never parked)
+	runCancels      map[string]func()       // Cancels the in-flight run per session
+	parked          map[string]bool         // Sessions whose MCP/sandbox resources were released
+	expiryNotified  map[string]bool         // Sessions already announced to the expiry webhook
 	sandboxCleanups map[string]func()       // Per-session sandbox cleanup (flow containers)
 	sandboxTools    map[string][]tool.Tool  // Per-session sandbox-wrapped tools (reused across resumes)
-	mu              sync.RWMutex
+	// Parked sessions on disk (nil keeps them in memory)
+	parkStore *persistentsession.FileStore
+	idle      config.SessionIdleConfig
+	mu        sync.RWMutex
 }
 
-// Session timeout - cleanup sessions with no activity for this duration
+// Session timeout - park sessions whose UI stopped sending keepalives for this duration
 const sessionTimeout = 2 * time.Minute
 
 var globalSessionManager *SessionManager
@@ -294,54 +304,45 @@ func wireSandboxBrowserCallbacks(mgr *browser.Manager, cfg browser.BrowserConfig
 // GetSessionManager returns the singleton session manager
 func GetSessionManager() *SessionManager {
 	sessionOnce.Do(func() {
-		baseService := session.InMemoryService()
-		globalSessionManager = &SessionManager{
-			service:         common.NewAutoInitService(baseService),
-			sessions:        make(map[string]session.Session),
-			mcpManagers:     make(map[string]*mcp.Manager),
-			lastActivity:    make(map[string]time.Time),
-			sandboxCleanups: make(map[string]func()),
-			sandboxTools:    make(map[string][]tool.Tool),
+		var idle config.SessionIdleConfig
+		var sessCfg *config.SessionConfig
+		if appCfg, err := config.LoadAppConfig(); err == nil && appCfg != nil {
+			idle = appCfg.Sessions.Idle
+			sessCfg = &appCfg.Sessions
 		}
+		globalSessionManager = newSessionManager(idle)
+		globalSessionManager.openParkStore(sessCfg)
 		// Start background cleanup goroutine
 		go globalSessionManager.cleanupStaleSessionsLoop()
 	})
 	return globalSessionManager
 }
 
-// cleanupStaleSessionsLoop periodically cleans up sessions with no recent activity
+// newSessionManager creates an empty session manager with the given idle policy.
+func newSessionManager(idle config.SessionIdleConfig) *SessionManager {
+	return &SessionManager{
+		service:         common.NewAutoInitService(session.InMemoryService()),
+		sessions:        make(map[string]session.Session),
+		mcpManagers:     make(map[string]*mcp.Manager),
+		lastActivity:    make(map[string]time.Time),
+		lastInteraction: make(map[string]time.Time),
+		activeRuns:      make(map[string]int),
+		runCancels:      make(map[string]func()),
+		parked:          make(map[string]bool),
+		expiryNotified:  make(map[string]bool),
+		sandboxCleanups: make(map[string]func()),
+		sandboxTools:    make(map[string][]tool.Tool),
+		idle:            idle,
+	}
+}
+
+// cleanupStaleSessionsLoop periodically parks and expires idle sessions
 func (sm *SessionManager) cleanupStaleSessionsLoop() {
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
 
 	for range ticker.C {
-		sm.cleanupStaleSessions()
-	}
-}
-
-// cleanupStaleSessions removes sessions that have been inactive for too long
-func (sm *SessionManager) cleanupStaleSessions() {
-	sm.mu.Lock()
-	defer sm.mu.Unlock()
-
-	now := time.Now()
-	for sessionID, lastActive := range sm.lastActivity {
-		if now.Sub(lastActive) > sessionTimeout {
-			// Cleanup MCP manager
-			if mgr, exists := sm.mcpManagers[sessionID]; exists {
-				mgr.Cleanup()
-				delete(sm.mcpManagers, sessionID)
-			}
-			// Cleanup sandbox container
-			if cleanup, exists := sm.sandboxCleanups[sessionID]; exists {
-				cleanup()
-				delete(sm.sandboxCleanups, sessionID)
-			}
-			delete(sm.sandboxTools, sessionID)
-			delete(sm.sessions, sessionID)
-			delete(sm.lastActivity, sessionID)
-			slog.Info("cleaned up stale session", "session_id", sessionID, "inactive_for", now.Sub(lastActive))
-		}
+		sm.sweepIdleSessions(time.Now())
 	}
 }
 
@@ -391,6 +392,8 @@ func (sm *SessionManager) GetOrCreateMCPManager(ctx context.Context, sessionID s
 		slog.Warn("failed to initialize mcp toolsets", "error", err)
 		return nil, nil
 	}
+	// Restart crashed servers while the session lives (stopped by Cleanup)
+	mgr.StartHealthChecks(ctx, mcp.HealthConfig{})
 
 	sm.mcpManagers[sessionID] = mgr
 	return mgr, mgr.GetToolsets()
@@ -455,15 +458,7 @@ func (sm *SessionManager) CleanupSession(sessionID string) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
-	if mgr, exists := sm.mcpManagers[sessionID]; exists {
-		mgr.Cleanup()
-		delete(sm.mcpManagers, sessionID)
-	}
-	if cleanup, exists := sm.sandboxCleanups[sessionID]; exists {
-		cleanup()
-		delete(sm.sandboxCleanups, sessionID)
-	}
-	delete(sm.sandboxTools, sessionID)
+	sm.releaseSessionResourcesLocked(sessionID)
 	delete(sm.sessions, sessionID)
 }
 
@@ -487,6 +482,48 @@ func HandleStopSession(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]bool{"stopped": true})
 }
 
+// trackRunCancel registers cancel as the way to stop the session's in-flight
+// run and returns a function that unregisters it.
+func (sm *SessionManager) trackRunCancel(sessionID string, cancel func()) func() {
+	sm.mu.Lock()
+	sm.runCancels[sessionID] = cancel
+	sm.mu.Unlock()
+	return func() {
+		sm.mu.Lock()
+		delete(sm.runCancels, sessionID)
+		sm.mu.Unlock()
+	}
+}
+
+// CancelRun cancels the session's in-flight run. The flow stops at the node
+// it was running and the session is kept, so a later run without a message
+// resumes there. It returns false when no run is in flight.
+func (sm *SessionManager) CancelRun(sessionID string) bool {
+	sm.mu.RLock()
+	cancel, ok := sm.runCancels[sessionID]
+	sm.mu.RUnlock()
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+// HandleCancelRun handles POST /api/session/{id}/cancel - stops the running
+// flow but, unlike stop, keeps the session so the run can be resumed
+func HandleCancelRun(w http.ResponseWriter, r *http.Request) {
+	// Expected format: /api/session/{sessionId}/cancel
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 4 {
+		respondError(w, http.StatusBadRequest, "Invalid path")
+		return
+	}
+	sessionID := parts[3]
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]bool{"cancelled": GetSessionManager().CancelRun(sessionID)})
+}
+
 // HandleSessionKeepalive handles POST /api/session/{id}/keepalive - extends session lifetime
 // The UI should call this every 30 seconds while a flow is active to prevent timeout
 func HandleSessionKeepalive(w http.ResponseWriter, r *http.Request) {
@@ -677,11 +714,16 @@ func HandleChat(w http.ResponseWriter, r *http.Request) {
 	// Send ping
 	SendSSE(w, flusher, "ping", map[string]string{"status": "connected"})
 
-	ctx := r.Context()
+	// The run can be cancelled through POST /api/session/{id}/cancel; the
+	// stream stays open for the final cancelled event
+	ctx, cancelRun := context.WithCancel(r.Context())
+	defer cancelRun()
 	sm := GetSessionManager()
 
-	// Update session activity timestamp
-	sm.TouchSession(req.SessionID)
+	// Mark the run in progress so the idle sweep leaves the session alone
+	endRun := sm.BeginRun(req.SessionID)
+	defer endRun()
+	defer sm.trackRunCancel(req.SessionID, cancelRun)()
 
 	// 1. Load Agent Config
 	// "team:" prefix signals the user explicitly selected the team version.
@@ -733,6 +775,9 @@ func HandleChat(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	for _, d := range cfg.SchemaWarnings {
+		slog.Warn("flow schema warning", "agent", req.AgentID, "problem", d.String())
+	}
 
 	// 2. Determine Provider/Model
 	appCfg := effectiveAppConfig(r)
@@ -825,13 +870,30 @@ func HandleChat(w http.ResponseWriter, r *http.Request) {
 		platformMCPStore = svc.PlatformMCPServers
 	}
 	requiredServers := getRequiredMCPServers(cfg, teamMCPStore, orgMCPStore, platformMCPStore)
-	_, mcpToolsets := sm.GetOrCreateMCPManager(ctx, req.SessionID, requiredServers, teamMCPStore, orgMCPStore, platformMCPStore)
+	mcpManager, mcpToolsets := sm.GetOrCreateMCPManager(ctx, req.SessionID, requiredServers, teamMCPStore, orgMCPStore, platformMCPStore)
+
+	// Values of sensitive state keys are encrypted before they reach the store
+	sessionService, err := persistentsession.NewSensitiveService(sm.service, cfg.SensitiveKeys(), tools.GetCredentialStore().DeriveKey("session-state"))
+	if err != nil {
+		SendErrorSSE(w, flusher, fmt.Sprintf("Failed to create session service: %v", err))
+		return
+	}
 
 	// 5. Create Astonish Agent & ADK Agent
 	astonishAgent := agent.NewAstonishAgentWithToolsets(cfg, llm, internalTools, mcpToolsets)
+	astonishAgent.AppConfig = appCfg // per-node models and model_fallbacks resolve providers from here
+	astonishAgent.ProviderName = providerName
+	astonishAgent.ModelName = modelName
 	astonishAgent.DebugMode = req.Debug // Enable verbose debug output when requested
+	if appCfg != nil {
+		astonishAgent.WorkspaceDir = appCfg.Chat.WorkspaceDir // reports are written next to chat files
+	}
+	if mcpManager != nil {
+		astonishAgent.MCPHealth = mcpManager.HealthState
+		astonishAgent.ToolsetsWithEnv = mcpManager.ToolsetsWithEnv
+	}
 	astonishAgent.IsWebMode = !req.CLIMode // CLI mode renders ANSI tool boxes; web mode uses markdown
-	astonishAgent.SessionService = sm.service
+	astonishAgent.SessionService = sessionService
 	astonishAgent.AutoApprove = req.AutoApprove
 
 	// Wire credential redactor so secrets are masked in SSE output
@@ -873,9 +935,12 @@ func HandleChat(w http.ResponseWriter, r *http.Request) {
 	// 6. Manage Session
 	sm.mu.Lock()
 	sess, exists := sm.sessions[req.SessionID]
+	if !exists {
+		sess, exists = sm.unparkSessionLocked(ctx, req.SessionID)
+	}
 	if !exists {
 		// Create new session
-		resp, err := sm.service.Create(ctx, &session.CreateRequest{
+		resp, err := sessionService.Create(ctx, &session.CreateRequest{
 			AppName: "astonish",
 			UserID:  req.SessionID,
 		})
@@ -889,17 +954,25 @@ func HandleChat(w http.ResponseWriter, r *http.Request) {
 	}
 	sm.mu.Unlock()
 
+	// Record the run (summary and SSE timeline) for the run history
+	rec := runHistoryFor(r).begin(req.AgentID, req.SessionID)
+	defer rec.finish()
+	if req.Message != "" {
+		rec.record("user_input", map[string]string{"text": req.Message})
+	}
+
 	// Overlap sandbox cold start with the first LLM/tool work in this run.
 	sandbox.WarmFlowSession(ctx, internalTools, sess.ID())
 
 	// 7. Create Runner
 	rnr, err := runner.New(runner.Config{
-		AppName:        "astonish",
-		Agent:          adkAgent,
-		SessionService: sm.service,
+		AppName:         "astonish",
+		Agent:           adkAgent,
+		SessionService:  sessionService,
+		ArtifactService: artifacts.RunnerService(),
 	})
 	if err != nil {
-		SendErrorSSE(w, flusher, fmt.Sprintf("Failed to create runner: %v", err))
+		rec.sendError(w, flusher, fmt.Sprintf("Failed to create runner: %v", err))
 		return
 	}
 
@@ -936,11 +1009,11 @@ func HandleChat(w http.ResponseWriter, r *http.Request) {
 		userMsg = agent.NewTimestampedUserContent(req.Message)
 	}
 
-	SendSSE(w, flusher, "status", map[string]string{"status": "running"})
+	rec.send(w, flusher, "status", map[string]string{"status": "running"})
 
 	// Emit debug initialization info
 	if req.Debug {
-		SendSSE(w, flusher, "debug", map[string]any{
+		rec.send(w, flusher, "debug", map[string]any{
 			"type":     "init",
 			"provider": req.Provider,
 			"model":    req.Model,
@@ -950,19 +1023,20 @@ func HandleChat(w http.ResponseWriter, r *http.Request) {
 	}
 	var lastNodeName string
 	var currentNodeType string // Track node type for conditional streaming
-	var hasOutputModel bool    // Track if current node has output_model
+	var suppressText bool      // Track if current node hides LLM text (display mode other than stream)
 	var toolCallCount int      // Track tool calls for text suppression
 
 	for event, err := range rnr.Run(ctx, req.SessionID, sess.ID(), userMsg, adkagent.RunConfig{}) {
 		// Break early if the SSE client disconnected.
-		if ctx.Err() != nil {
+		if r.Context().Err() != nil {
 			return
 		}
 
 		if err != nil {
-			SendErrorSSE(w, flusher, err.Error())
+			rec.sendError(w, flusher, err.Error())
 			return
 		}
+		rec.observe(event)
 
 		// Check for _user_message_display marker - this event has proper display content
 		isUserMessageDisplay := event.Actions.StateDelta != nil && event.Actions.StateDelta["_user_message_display"] != nil
@@ -976,10 +1050,10 @@ func HandleChat(w http.ResponseWriter, r *http.Request) {
 		isApprovalRequest := event.Actions.StateDelta != nil && event.Actions.StateDelta["approval_options"] != nil
 		shouldStream := currentNodeType == "" || currentNodeType == "llm" || currentNodeType == "output" || currentNodeType == "input" || isApprovalRequest || isUserMessageDisplay
 
-		// For output_model nodes, suppress ALL text (raw JSON will be parsed and displayed via user_message)
+		// For non-streaming display modes, suppress ALL text (raw JSON will be parsed and displayed via user_message)
 		// Only allow _user_message_display events, approval requests, and input prompts
 		isInputRequest := event.Actions.StateDelta != nil && event.Actions.StateDelta["input_options"] != nil
-		if hasOutputModel && !isUserMessageDisplay && !isApprovalRequest && !isInputRequest {
+		if suppressText && !isUserMessageDisplay && !isApprovalRequest && !isInputRequest {
 			shouldStream = false
 		}
 
@@ -995,7 +1069,7 @@ func HandleChat(w http.ResponseWriter, r *http.Request) {
 					if isOutputNode || isUserMessageDisplay {
 						payload["preserveWhitespace"] = true
 					}
-					SendSSE(w, flusher, "text", payload)
+					rec.send(w, flusher, "text", payload)
 				}
 			}
 		}
@@ -1009,7 +1083,7 @@ func HandleChat(w http.ResponseWriter, r *http.Request) {
 					// Emit debug event for tool call args
 					if req.Debug {
 						argsJSON, _ := json.Marshal(part.FunctionCall.Args)
-						SendSSE(w, flusher, "debug", map[string]any{
+						rec.send(w, flusher, "debug", map[string]any{
 							"type": "tool_call",
 							"tool": part.FunctionCall.Name,
 							"args": string(argsJSON),
@@ -1018,7 +1092,7 @@ func HandleChat(w http.ResponseWriter, r *http.Request) {
 				}
 				if part.FunctionResponse != nil && req.Debug {
 					respJSON, _ := json.Marshal(part.FunctionResponse.Response)
-					SendSSE(w, flusher, "debug", map[string]any{
+					rec.send(w, flusher, "debug", map[string]any{
 						"type":   "tool_response",
 						"tool":   part.FunctionResponse.Name,
 						"result": string(respJSON),
@@ -1043,18 +1117,18 @@ func HandleChat(w http.ResponseWriter, r *http.Request) {
 					// Reset tool call count for new node
 					toolCallCount = 0
 
-					// Check if this node has output_model (from config)
-					hasOutputModel = false
+					// Check if this node hides its LLM text (from config)
+					suppressText = false
 					for _, node := range cfg.Nodes {
-						if node.Name == nodeName && len(node.OutputModel) > 0 {
-							hasOutputModel = true
+						if node.Name == nodeName && node.DisplayMode() != config.DisplayStream {
+							suppressText = true
 							break
 						}
 					}
 
 					// Always send node event, include silent flag for frontend filtering
 					isSilent, _ := delta["silent"].(bool)
-					SendSSE(w, flusher, "node", map[string]any{
+					rec.send(w, flusher, "node", map[string]any{
 						"node":   nodeName,
 						"type":   nodeType,
 						"silent": isSilent,
@@ -1062,6 +1136,16 @@ func HandleChat(w http.ResponseWriter, r *http.Request) {
 				}
 			}
 
+			// Summary of the run that reached END
+			if summary, ok := delta[agent.RunSummaryKey]; ok {
+				rec.send(w, flusher, "summary", summary)
+			}
+
+			// A node nearing its max_tool_calls
+			if warning, ok := delta[agent.ToolCallWarningKey].(map[string]interface{}); ok {
+				rec.send(w, flusher, "warning", map[string]interface{}{"message": warning["message"]})
+			}
+
 			// Check for Retry Info (smart error handling)
 			if retryInfoVal, ok := delta["_retry_info"]; ok {
 				if retryInfo, ok := retryInfoVal.(map[string]interface{}); ok {
@@ -1085,10 +1169,13 @@ func HandleChat(w http.ResponseWriter, r *http.Request) {
 						reason = r
 					}
 
-					SendSSE(w, flusher, "retry", map[string]interface{}{
-						"attempt":    attempt,
-						"maxRetries": maxRetries,
-						"reason":     reason,
+					waitSeconds, _ := retryInfo["wait_seconds"].(float64)
+
+					rec.send(w, flusher, "retry", map[string]interface{}{
+						"attempt":     attempt,
+						"maxRetries":  maxRetries,
+						"reason":      reason,
+						"waitSeconds": waitSeconds,
 					})
 				}
 			}
@@ -1100,54 +1187,97 @@ func HandleChat(w http.ResponseWriter, r *http.Request) {
 					reason, _ := failureInfo["reason"].(string)
 					originalError, _ := failureInfo["original_error"].(string)
 					suggestion, _ := failureInfo["suggestion"].(string)
+					errorClass, _ := failureInfo["error_class"].(string)
 
-					SendSSE(w, flusher, "error_info", map[string]interface{}{
+					rec.send(w, flusher, "error_info", map[string]interface{}{
 						"title":         title,
 						"reason":        reason,
 						"suggestion":    suggestion,
 						"originalError": originalError,
+						"errorClass":    errorClass,
 					})
 				}
 			}
 
-			// Capture input request from approval_options (tool approval)
+			forwardParallelProgress(delta, func(name string, data any) { rec.send(w, flusher, name, data) })
+			if filterVal, ok := delta["_content_filter"]; ok {
+				rec.send(w, flusher, "content_filter", filterVal)
+			}
+			if recoveryVal, ok := delta["_context_recovery"]; ok {
+				rec.send(w, flusher, "context_recovery", recoveryVal)
+			}
+			if guardrailVal, ok := delta["_guardrail"]; ok {
+				rec.send(w, flusher, "guardrail", guardrailVal)
+			}
+			if cancelledVal, ok := delta["_cancelled"]; ok {
+				rec.send(w, flusher, "cancelled", cancelledVal)
+			}
+
+			// Capture input request from approval_options (tool approval),
+			// with the model's reason for the call when known
+			var approvalRequest map[string]interface{}
 			if options, ok := delta["approval_options"].([]string); ok {
-				SendSSE(w, flusher, "input_request", map[string]interface{}{
+				approvalRequest = map[string]interface{}{
 					"options": options,
-				})
+				}
 			} else if optionsRaw, ok := delta["approval_options"].([]interface{}); ok {
-				SendSSE(w, flusher, "input_request", map[string]interface{}{
+				approvalRequest = map[string]interface{}{
 					"options": optionsRaw,
-				})
+				}
+			}
+			if approvalRequest != nil {
+				if reason, ok := delta["approval_reason"].(string); ok && reason != "" {
+					approvalRequest["reason"] = reason
+				}
+				rec.send(w, flusher, "input_request", approvalRequest)
 			}
 
-			// Capture input request from input_options (input node)
-			if options, ok := delta["input_options"].([]string); ok && len(options) > 0 {
+			// Capture input request from input_options (input node). Multi-select
+			// nodes are answered with comma-separated options in free text.
+			if multi, _ := delta["input_multi_select"].(bool); multi && delta["input_options"] != nil {
+				var options []string
+				switch opts := delta["input_options"].(type) {
+				case []string:
+					options = opts
+				case []interface{}:
+					for _, o := range opts {
+						options = append(options, fmt.Sprintf("%v", o))
+					}
+				}
+				rec.send(w, flusher, "text", map[string]string{
+					"text": "\n\nChoose any of (separate them with commas): " + strings.Join(options, ", ") + "\n",
+				})
+				rec.send(w, flusher, "input_request", map[string]interface{}{
+					"options":      []string{},
+					"multi_select": true,
+				})
+			} else if options, ok := delta["input_options"].([]string); ok && len(options) > 0 {
 				// Input node with predefined options
-				SendSSE(w, flusher, "input_request", map[string]interface{}{
+				rec.send(w, flusher, "input_request", map[string]interface{}{
 					"options": options,
 				})
 			} else if optionsRaw, ok := delta["input_options"].([]interface{}); ok && len(optionsRaw) > 0 {
 				// Handle []interface{} case
-				SendSSE(w, flusher, "input_request", map[string]interface{}{
+				rec.send(w, flusher, "input_request", map[string]interface{}{
 					"options": optionsRaw,
 				})
 			} else if waiting, ok := delta["waiting_for_input"].(bool); ok && waiting {
 				// Free-text input (no options) - send empty options to enable input
-				SendSSE(w, flusher, "input_request", map[string]interface{}{
+				rec.send(w, flusher, "input_request", map[string]interface{}{
 					"options": []string{},
 				})
 			}
 
 			// Send full state delta for UI variables view
-			SendSSE(w, flusher, "state", delta)
+			rec.send(w, flusher, "state", astonishAgent.MaskSensitiveState(delta))
 		}
 	}
 
-	// Check if flow reached END node - cleanup MCP for this session
-	if lastNodeName == "END" {
+	// Check if flow reached END node - cleanup MCP for this session.
+	// Chat-mode flows continue with the next message, so they keep it.
+	if lastNodeName == "END" && !cfg.ChatEnabled() {
 		sm.CleanupSession(req.SessionID)
 	}
 
-	SendSSE(w, flusher, "done", map[string]bool{"done": true})
+	rec.send(w, flusher, "done", map[string]bool{"done": true})
 }
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"reflect"
 	"testing"
 
 	"google.golang.org/adk/session"
@@ -146,3 +147,34 @@ func TestSilentModeSkipsNodeEvent(t *testing.T) {
 		})
 	}
 }
+
+func TestForwardParallelProgress(t *testing.T) {
+	item := map[string]any{"node": "review", "index": 0}
+	chunk := map[string]any{"node": "review", "chunk": 1}
+	tests := []struct {
+		name  string
+		delta map[string]any
+		want  []string
+	}{
+		{name: "item", delta: map[string]any{"_parallel_item": item}, want: []string{"parallel_item"}},
+		{name: "chunk", delta: map[string]any{"_parallel_chunk": chunk}, want: []string{"parallel_chunk"}},
+		{name: "both", delta: map[string]any{"_parallel_item": item, "_parallel_chunk": chunk}, want: []string{"parallel_item", "parallel_chunk"}},
+		{name: "neither", delta: map[string]any{"result": "x"}, want: nil},
+		{name: "nil delta", delta: nil, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			forwardParallelProgress(tt.delta, func(event string, data any) {
+				got = append(got, event)
+				key := "_" + event
+				if !reflect.DeepEqual(data, tt.delta[key]) {
+					t.Errorf("%s data = %v, want %v", event, data, tt.delta[key])
+				}
+			})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,406 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SAP/astonish/pkg/agent"
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/gorilla/mux"
+	"google.golang.org/adk/session"
+)
+
+// Run statuses recorded in RunSummary.Status.
+const (
+	RunStatusRunning   = "running"
+	RunStatusWaiting   = "waiting" // Paused for user input or tool approval
+	RunStatusCompleted = "completed"
+	RunStatusFailed    = "failed"
+)
+
+// RunSummary is the compact record of a Studio flow run. A run spans every
+// /api/chat request of one session, so a run paused for input is resumed
+// into the same record.
+type RunSummary struct {
+	ID        string     `json:"id"` // Studio session ID
+	Flow      string     `json:"flow"`
+	StartedAt time.Time  `json:"startedAt"`
+	EndedAt   *time.Time `json:"endedAt,omitempty"`
+	Status    string     `json:"status"`
+	NodePath  []string   `json:"nodePath"`
+	Usage     RunUsage   `json:"usage"`
+	Report    string     `json:"report,omitempty"` // HTML report written by an output node
+}
+
+// RunUsage is the token usage of a run, summed over all model responses.
+type RunUsage struct {
+	InputTokens  int64 `json:"inputTokens"`
+	OutputTokens int64 `json:"outputTokens"`
+	TotalTokens  int64 `json:"totalTokens"`
+}
+
+// RunEvent is one SSE event of a run's timeline, stored as it was sent so
+// a replay renders exactly like the live run. EventID and NodeExecutionID
+// are the stable IDs of the flow event it was derived from.
+type RunEvent struct {
+	Time            time.Time       `json:"time"`
+	Event           string          `json:"event"`
+	EventID         string          `json:"eventId,omitempty"`
+	NodeExecutionID string          `json:"nodeExecutionId,omitempty"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// RunHistory stores run summaries and event timelines on disk:
+// <dir>/<flow>/<run id>.json and <dir>/<flow>/<run id>.events.jsonl.
+type RunHistory struct {
+	dir string
+}
+
+// runHistoryFor returns the run history of the request's scope: the local
+// runs directory in personal mode, a per-team directory in platform mode.
+// It returns nil when the runs directory cannot be resolved.
+func runHistoryFor(r *http.Request) *RunHistory {
+	dir, err := config.GetRunsDir()
+	if err != nil {
+		slog.Warn("run history disabled", "error", err)
+		return nil
+	}
+	if isPlatformMode(r) {
+		dir = filepath.Join(dir, "teams", url.PathEscape(effectiveTeamSlug(r)))
+	}
+	return &RunHistory{dir: dir}
+}
+
+func (h *RunHistory) paths(flow, id string) (summary, events string) {
+	base := filepath.Join(h.dir, url.PathEscape(flow), url.PathEscape(id))
+	return base + ".json", base + ".events.jsonl"
+}
+
+// List returns the summaries of past runs, newest first. An empty flow
+// lists the runs of all flows.
+func (h *RunHistory) List(flow string) ([]RunSummary, error) {
+	pattern := filepath.Join(h.dir, "*", "*.json")
+	if flow != "" {
+		pattern = filepath.Join(h.dir, url.PathEscape(flow), "*.json")
+	}
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]RunSummary, 0, len(files))
+	for _, file := range files {
+		var run RunSummary
+		if err := readJSONFile(file, &run); err != nil {
+			slog.Warn("skipping unreadable run summary", "file", file, "error", err)
+			continue
+		}
+		runs = append(runs, run)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].StartedAt.After(runs[j].StartedAt) })
+	return runs, nil
+}
+
+// Get returns a run's summary and its event timeline.
+func (h *RunHistory) Get(id string) (*RunSummary, []RunEvent, error) {
+	files, err := filepath.Glob(filepath.Join(h.dir, "*", url.PathEscape(id)+".json"))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(files) == 0 {
+		return nil, nil, os.ErrNotExist
+	}
+	var run RunSummary
+	if err := readJSONFile(files[0], &run); err != nil {
+		return nil, nil, err
+	}
+
+	_, eventsPath := h.paths(run.Flow, run.ID)
+	f, err := os.Open(eventsPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return &run, []RunEvent{}, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	events := []RunEvent{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var ev RunEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			continue // skip a torn last line
+		}
+		events = append(events, ev)
+	}
+	return &run, events, scanner.Err()
+}
+
+// begin starts recording a /api/chat request of a run, continuing the
+// existing record when the run was paused. Without a history the recorder
+// still stamps event IDs but records nothing.
+func (h *RunHistory) begin(flow, id string) *runRecorder {
+	rec := &runRecorder{}
+	if h == nil {
+		return rec
+	}
+	summaryPath, eventsPath := h.paths(flow, id)
+	if err := readJSONFile(summaryPath, &rec.summary); err != nil {
+		rec.summary = RunSummary{ID: id, Flow: flow, StartedAt: time.Now(), NodePath: []string{}}
+	}
+	rec.summary.Status = RunStatusRunning
+	rec.summary.EndedAt = nil
+
+	if err := os.MkdirAll(filepath.Dir(eventsPath), 0o700); err != nil {
+		slog.Warn("run history disabled for this run", "run", id, "error", err)
+		return rec
+	}
+	f, err := os.OpenFile(eventsPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		slog.Warn("run history disabled for this run", "run", id, "error", err)
+		return rec
+	}
+	rec.summaryPath = summaryPath
+	rec.events = f
+	rec.save()
+	return rec
+}
+
+// runRecorder records the SSE events of one /api/chat request into the
+// run history while sending them. A recorder without an events file only
+// sends.
+type runRecorder struct {
+	summaryPath string
+	summary     RunSummary
+	events      *os.File
+
+	// IDs of the flow event being sent, see observe
+	eventID         string
+	nodeExecutionID string
+}
+
+// send sends an SSE event and appends it to the run's timeline. Object
+// payloads derived from a flow event carry its eventId and nodeExecutionId.
+func (rec *runRecorder) send(w io.Writer, flusher http.Flusher, eventType string, data any) {
+	if m, ok := data.(map[string]any); ok && rec.eventID != "" {
+		stamped := make(map[string]any, len(m)+2)
+		for k, v := range m {
+			stamped[k] = v
+		}
+		stamped["eventId"] = rec.eventID
+		stamped["nodeExecutionId"] = rec.nodeExecutionID
+		data = stamped
+	}
+	SendSSE(w, flusher, eventType, data)
+	rec.record(eventType, data)
+}
+
+// record appends an event to the run's timeline without sending it, e.g.
+// the user's replies, which the UI shows itself.
+func (rec *runRecorder) record(eventType string, data any) {
+	if rec.events == nil {
+		return
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	ev := RunEvent{Time: time.Now(), Event: eventType, Data: payload}
+	if m, ok := data.(map[string]any); ok {
+		ev.EventID, _ = m["eventId"].(string)
+		ev.NodeExecutionID, _ = m["nodeExecutionId"].(string)
+	}
+	line, _ := json.Marshal(ev)
+	if _, err := rec.events.Write(append(line, '\n')); err != nil {
+		slog.Warn("failed to record run event", "run", rec.summary.ID, "error", err)
+	}
+
+	switch eventType {
+	case "node":
+		if m, ok := data.(map[string]any); ok {
+			if node, _ := m["node"].(string); node != "" {
+				rec.summary.NodePath = append(rec.summary.NodePath, node)
+				if node == "END" {
+					rec.summary.Status = RunStatusCompleted
+				}
+			}
+		}
+	case "error", "error_info":
+		rec.summary.Status = RunStatusFailed
+	}
+}
+
+// sendError sends an error event and records it.
+func (rec *runRecorder) sendError(w io.Writer, flusher http.Flusher, msg string) {
+	rec.send(w, flusher, "error", map[string]string{"error": msg})
+}
+
+// observe takes the IDs of the flow event whose SSE events are sent next,
+// notes a written report and adds the token usage of a final model response
+// to the run.
+func (rec *runRecorder) observe(event *session.Event) {
+	rec.nodeExecutionID, rec.eventID = agent.EventIDs(event)
+	if event == nil {
+		return
+	}
+	if report, ok := event.Actions.StateDelta[agent.ReportStateKey].(string); ok {
+		rec.summary.Report = report
+	}
+	if event.Partial || event.UsageMetadata == nil {
+		return
+	}
+	usage := event.UsageMetadata
+	rec.summary.Usage.InputTokens += int64(usage.PromptTokenCount)
+	rec.summary.Usage.OutputTokens += int64(usage.CandidatesTokenCount)
+	rec.summary.Usage.TotalTokens += int64(usage.TotalTokenCount)
+}
+
+// finish closes the request's recording. A run that neither completed nor
+// failed is waiting for the user.
+func (rec *runRecorder) finish() {
+	if rec.events == nil {
+		return
+	}
+	if rec.summary.Status == RunStatusRunning {
+		rec.summary.Status = RunStatusWaiting
+	}
+	now := time.Now()
+	rec.summary.EndedAt = &now
+	rec.save()
+	rec.events.Close()
+}
+
+func (rec *runRecorder) save() {
+	data, err := json.MarshalIndent(rec.summary, "", "  ")
+	if err == nil {
+		err = os.WriteFile(rec.summaryPath, data, 0o600)
+	}
+	if err != nil {
+		slog.Warn("failed to save run summary", "run", rec.summary.ID, "error", err)
+	}
+}
+
+func readJSONFile(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// ListRunsHandler handles GET /api/runs?flow=<name>
+func ListRunsHandler(w http.ResponseWriter, r *http.Request) {
+	h := runHistoryFor(r)
+	if h == nil {
+		respondJSON(w, http.StatusOK, map[string]any{"runs": []RunSummary{}})
+		return
+	}
+	runs, err := h.List(strings.TrimPrefix(r.URL.Query().Get("flow"), "team:"))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{"runs": runs})
+}
+
+// GetRunHandler handles GET /api/runs/{id}: the run summary and its full
+// event timeline.
+func GetRunHandler(w http.ResponseWriter, r *http.Request) {
+	run, events, ok := loadRun(w, r)
+	if !ok {
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{"run": run, "events": events})
+}
+
+// ReplayRunHandler handles GET /api/runs/{id}/replay. It streams the
+// recorded events as SSE, in the same format as a live run, so the web UI
+// can render a past run read-only. Nothing is executed. The optional
+// "speed" parameter replays with the original pacing divided by speed;
+// without it all events are sent at once.
+func ReplayRunHandler(w http.ResponseWriter, r *http.Request) {
+	run, events, ok := loadRun(w, r)
+	if !ok {
+		return
+	}
+	var speed float64
+	if s := r.URL.Query().Get("speed"); s != "" {
+		if _, err := fmt.Sscanf(s, "%g", &speed); err != nil || speed <= 0 {
+			respondError(w, http.StatusBadRequest, "speed must be a positive number")
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	flusher, _ := w.(http.Flusher)
+
+	SendSSE(w, flusher, "replay", run)
+	for i, ev := range events {
+		if ev.Event == "ping" || ev.Event == "done" {
+			continue
+		}
+		if speed > 0 && i > 0 {
+			select {
+			case <-time.After(time.Duration(float64(ev.Time.Sub(events[i-1].Time)) / speed)):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		SendSSE(w, flusher, ev.Event, ev.Data)
+	}
+	SendSSE(w, flusher, "done", map[string]bool{"done": true, "replay": true})
+}
+
+// GetRunReportHandler handles GET /api/runs/{id}/report: the HTML report
+// written by the run, served for viewing in the browser.
+func GetRunReportHandler(w http.ResponseWriter, r *http.Request) {
+	run, _, ok := loadRun(w, r)
+	if !ok {
+		return
+	}
+	if run.Report == "" {
+		respondError(w, http.StatusNotFound, "run has no report")
+		return
+	}
+	data, err := os.ReadFile(run.Report)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "report file is no longer available")
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", "sandbox allow-scripts")
+	w.Write(data)
+}
+
+func loadRun(w http.ResponseWriter, r *http.Request) (*RunSummary, []RunEvent, bool) {
+	h := runHistoryFor(r)
+	if h == nil {
+		respondError(w, http.StatusNotFound, "run history is not available")
+		return nil, nil, false
+	}
+	run, events, err := h.Get(mux.Vars(r)["id"])
+	if errors.Is(err, os.ErrNotExist) {
+		respondError(w, http.StatusNotFound, "run not found")
+		return nil, nil, false
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return nil, nil, false
+	}
+	return run, events, true
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"io"
+	"testing"
+
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+func TestRunHistory_RecordAndResume(t *testing.T) {
+	h := &RunHistory{dir: t.TempDir()}
+
+	rec := h.begin("my_flow", "sess-1")
+	if rec == nil {
+		t.Fatal("begin() returned nil")
+	}
+	rec.send(io.Discard, nil, "node", map[string]any{"node": "ask"})
+	rec.send(io.Discard, nil, "text", map[string]any{"text": "Which repo?"})
+	usage := &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 10, CandidatesTokenCount: 5, TotalTokenCount: 15}
+	partial := &session.Event{}
+	partial.Partial = true
+	partial.UsageMetadata = usage
+	rec.observe(partial) // streaming chunks are not counted
+	final := &session.Event{}
+	final.UsageMetadata = usage
+	rec.observe(final)
+	rec.finish()
+
+	runs, err := h.List("my_flow")
+	if err != nil || len(runs) != 1 {
+		t.Fatalf("List() = %v, %v; want one run", runs, err)
+	}
+	if runs[0].Status != RunStatusWaiting {
+		t.Errorf("status after pause = %q, want %q", runs[0].Status, RunStatusWaiting)
+	}
+
+	// The user's reply resumes the same run
+	rec = h.begin("my_flow", "sess-1")
+	rec.record("user_input", map[string]string{"text": "astonish"})
+	rec.send(io.Discard, nil, "node", map[string]any{"node": "END"})
+	rec.finish()
+
+	run, events, err := h.Get("sess-1")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if run.Status != RunStatusCompleted {
+		t.Errorf("status = %q, want %q", run.Status, RunStatusCompleted)
+	}
+	if len(run.NodePath) != 2 || run.NodePath[0] != "ask" || run.NodePath[1] != "END" {
+		t.Errorf("node path = %v, want [ask END]", run.NodePath)
+	}
+	if run.Usage.TotalTokens != 15 || run.Usage.InputTokens != 10 {
+		t.Errorf("usage = %+v, want 10 in / 15 total", run.Usage)
+	}
+	var kinds []string
+	for _, ev := range events {
+		kinds = append(kinds, ev.Event)
+	}
+	if len(kinds) != 4 || kinds[2] != "user_input" {
+		t.Errorf("events = %v, want node, text, user_input, node", kinds)
+	}
+
+	if runs, _ := h.List("other_flow"); len(runs) != 0 {
+		t.Errorf("List(other_flow) = %v, want none", runs)
+	}
+}
+
+type discardWriter struct{}
+
+func (discardWriter) Write(p []byte) (int, error) { return len(p), nil }
@@ -0,0 +1,342 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// runWSUpgrader upgrades HTTP connections to WebSocket for interactive runs.
+var runWSUpgrader = websocket.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: 16 * 1024,
+	CheckOrigin: func(r *http.Request) bool {
+		return true // Same-origin enforced by auth middleware
+	},
+}
+
+const (
+	// runWSBufferSize is the number of events kept per session for clients
+	// that reconnect and resume.
+	runWSBufferSize = 1000
+	// runWSKeepalive is how often a connected socket keeps its session alive
+	// and pings the client.
+	runWSKeepalive = 30 * time.Second
+	// runWSIdleExpiry drops the event buffer of a session nobody is connected
+	// to and that has not run for this long.
+	runWSIdleExpiry = time.Hour
+)
+
+// RunWSMessage is a message from the client. "message" starts a flow or
+// answers its pending input node or tool approval (the same text /api/chat
+// takes); agentId, provider and model are remembered from the first message
+// of the session. "keepalive" and "stop" match the session endpoints.
+type RunWSMessage struct {
+	Type string `json:"type"` // "message", "keepalive" or "stop"
+	ChatRequest
+}
+
+// RunWSEvent is an event sent to the client. Events of runs carry a
+// sequence number; a client reconnecting with ?after=<seq> receives the
+// events it missed. Connection-level events (session, busy errors) have
+// seq 0 and are not replayed.
+type RunWSEvent struct {
+	Seq   int64           `json:"seq,omitempty"`
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// runWSSession holds the state of a session shared by its successive
+// connections: the buffered events, the current socket and the run.
+type runWSSession struct {
+	mu       sync.Mutex
+	id       string
+	events   []RunWSEvent
+	lastSeq  int64
+	conn     *websocket.Conn
+	running  bool
+	req      ChatRequest // agent, provider and model of the session
+	lastUsed time.Time
+}
+
+var runWSSessions = struct {
+	sync.Mutex
+	byID map[string]*runWSSession
+}{byID: make(map[string]*runWSSession)}
+
+// getRunWSSession returns the state of a session, creating it when needed,
+// and drops abandoned sessions.
+func getRunWSSession(id string) *runWSSession {
+	runWSSessions.Lock()
+	defer runWSSessions.Unlock()
+	now := time.Now()
+	for sid, s := range runWSSessions.byID {
+		s.mu.Lock()
+		idle := s.conn == nil && !s.running && now.Sub(s.lastUsed) > runWSIdleExpiry
+		s.mu.Unlock()
+		if idle {
+			delete(runWSSessions.byID, sid)
+		}
+	}
+	s, ok := runWSSessions.byID[id]
+	if !ok {
+		s = &runWSSession{id: id, lastUsed: now}
+		runWSSessions.byID[id] = s
+	}
+	return s
+}
+
+// emit buffers a run event and sends it to the connected client, if any.
+func (s *runWSSession) emit(event string, data json.RawMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastSeq++
+	ev := RunWSEvent{Seq: s.lastSeq, Event: event, Data: data}
+	s.events = append(s.events, ev)
+	if len(s.events) > runWSBufferSize {
+		s.events = s.events[len(s.events)-runWSBufferSize:]
+	}
+	s.lastUsed = time.Now()
+	s.writeLocked(ev)
+}
+
+// notify sends a connection-level event that is not buffered.
+func (s *runWSSession) notify(event string, data any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writeLocked(RunWSEvent{Event: event, Data: payload})
+}
+
+// writeLocked sends an event to the current socket. A failed write detaches
+// the socket; the run keeps going and the client can resume.
+func (s *runWSSession) writeLocked(ev RunWSEvent) {
+	if s.conn == nil {
+		return
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	if err := s.conn.WriteJSON(ev); err != nil {
+		slog.Debug("run websocket write failed, detaching", "session", s.id, "error", err)
+		s.conn.Close()
+		s.conn = nil
+	}
+}
+
+// attach makes conn the session's socket, replacing an older one, and
+// replays the buffered events after the given sequence number.
+func (s *runWSSession) attach(conn *websocket.Conn, after int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		s.conn.WriteMessage(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "replaced by a newer connection"))
+		s.conn.Close()
+	}
+	s.conn = conn
+	s.lastUsed = time.Now()
+
+	payload, _ := json.Marshal(map[string]any{"sessionId": s.id, "running": s.running, "lastSeq": s.lastSeq})
+	s.writeLocked(RunWSEvent{Event: "session", Data: payload})
+	for _, ev := range s.events {
+		if ev.Seq > after {
+			s.writeLocked(ev)
+		}
+	}
+}
+
+// detach forgets conn if it is still the session's socket.
+func (s *runWSSession) detach(conn *websocket.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == conn {
+		s.conn = nil
+	}
+	s.lastUsed = time.Now()
+}
+
+// HandleRunWebSocket handles GET /api/chat/ws?sessionId=<id>&after=<seq>
+// (WebSocket upgrade), an alternative to POST /api/chat for browsers. Runs
+// stream their events over the socket and user responses (input answers,
+// tool approvals) are sent back on it, so one connection carries a whole
+// interactive run. Runs continue when the socket drops: reconnecting with
+// the same session ID and the last seq received resumes the stream.
+//
+// Each "message" is executed by HandleChat, so both transports behave the
+// same; the SSE frames it writes are forwarded as RunWSEvent messages.
+func HandleRunWebSocket(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.URL.Query().Get("sessionId")
+	if sessionID == "" {
+		sessionID = fmt.Sprintf("session-%d", time.Now().UnixNano())
+	}
+	var after int64
+	if v := r.URL.Query().Get("after"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			respondError(w, http.StatusBadRequest, "after must be a sequence number")
+			return
+		}
+		after = n
+	}
+
+	conn, err := runWSUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		slog.Error("run WebSocket upgrade failed", "session", sessionID, "error", err)
+		return
+	}
+	defer conn.Close()
+
+	s := getRunWSSession(sessionID)
+	s.attach(conn, after)
+	defer s.detach(conn)
+
+	sm := GetSessionManager()
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		ticker := time.NewTicker(runWSKeepalive)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				sm.TouchSession(sessionID)
+				s.mu.Lock()
+				if s.conn == conn {
+					conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second))
+				}
+				s.mu.Unlock()
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	for {
+		var msg RunWSMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				slog.Debug("run websocket closed", "session", sessionID, "error", err)
+			}
+			return
+		}
+		switch msg.Type {
+		case "message":
+			s.startRun(r, msg.ChatRequest)
+		case "keepalive":
+			sm.TouchSession(sessionID)
+		case "stop":
+			sm.CleanupSession(sessionID)
+			s.notify("stopped", map[string]bool{"stopped": true})
+		default:
+			s.notify("error", map[string]string{"error": fmt.Sprintf("unknown message type %q", msg.Type)})
+		}
+	}
+}
+
+// startRun executes a message through HandleChat in the background. The
+// run is detached from the socket's lifetime so a reconnecting client can
+// resume it; only one run per session is active at a time.
+func (s *runWSSession) startRun(r *http.Request, req ChatRequest) {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		s.notify("error", map[string]string{"error": "a run is already in progress for this session"})
+		return
+	}
+	if req.AgentID == "" {
+		req.AgentID = s.req.AgentID
+	}
+	if req.Provider == "" {
+		req.Provider = s.req.Provider
+	}
+	if req.Model == "" {
+		req.Model = s.req.Model
+	}
+	req.SessionID = s.id
+	s.req = req
+	s.running = true
+	s.mu.Unlock()
+
+	body, _ := json.Marshal(req)
+	runReq := r.Clone(context.WithoutCancel(r.Context()))
+	runReq.Method = http.MethodPost
+	runReq.Body = io.NopCloser(bytes.NewReader(body))
+	runReq.ContentLength = int64(len(body))
+
+	go func() {
+		defer func() {
+			s.mu.Lock()
+			s.running = false
+			s.lastUsed = time.Now()
+			s.mu.Unlock()
+		}()
+		HandleChat(&wsEventWriter{header: make(http.Header), emit: s.emit}, runReq)
+	}()
+}
+
+// wsEventWriter is the http.ResponseWriter HandleChat writes to for a
+// WebSocket run. It splits the SSE stream into events; an error response
+// written before the stream started becomes an "error" event.
+type wsEventWriter struct {
+	header http.Header
+	status int
+	buf    bytes.Buffer
+	emit   func(event string, data json.RawMessage)
+}
+
+func (w *wsEventWriter) Header() http.Header { return w.header }
+
+func (w *wsEventWriter) WriteHeader(status int) { w.status = status }
+
+func (w *wsEventWriter) Flush() {}
+
+func (w *wsEventWriter) Write(p []byte) (int, error) {
+	if w.status >= http.StatusBadRequest {
+		var body struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(p, &body) != nil || body.Error == "" {
+			body.Error = http.StatusText(w.status)
+		}
+		payload, _ := json.Marshal(body)
+		w.emit("error", payload)
+		return len(p), nil
+	}
+
+	w.buf.Write(p)
+	rest := w.buf.String()
+	for {
+		frame, after, ok := strings.Cut(rest, "\n\n")
+		if !ok {
+			break
+		}
+		rest = after
+		var event, data string
+		for _, line := range strings.Split(frame, "\n") {
+			if v, ok := strings.CutPrefix(line, "event: "); ok {
+				event = v
+			} else if v, ok := strings.CutPrefix(line, "data: "); ok {
+				data = v
+			}
+		}
+		if event == "" || event == "ping" || !json.Valid([]byte(data)) {
+			continue
+		}
+		w.emit(event, json.RawMessage(data))
+	}
+	w.buf.Reset()
+	w.buf.WriteString(rest)
+	return len(p), nil
+}
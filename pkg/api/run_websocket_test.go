@@ -0,0 +1,76 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestWSEventWriter_SplitsSSEFrames(t *testing.T) {
+	var got []string
+	w := &wsEventWriter{header: make(http.Header), emit: func(event string, data json.RawMessage) {
+		got = append(got, event+" "+string(data))
+	}}
+
+	SendSSE(w, w, "ping", map[string]string{"status": "connected"})
+	w.Write([]byte("event: text\ndata: {\"text\":"))
+	w.Write([]byte("\"hi\"}\n\nevent: node\ndata: {\"node\":\"ask\"}\n\n"))
+
+	want := []string{`text {"text":"hi"}`, `node {"node":"ask"}`}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+
+	got = nil
+	w = &wsEventWriter{header: make(http.Header), emit: func(event string, data json.RawMessage) {
+		got = append(got, event+" "+string(data))
+	}}
+	respondError(w, http.StatusBadRequest, "AgentID is required")
+	if len(got) != 1 || got[0] != `error {"error":"AgentID is required"}` {
+		t.Errorf("error response = %v, want one error event", got)
+	}
+}
+
+func TestHandleRunWebSocket_Resume(t *testing.T) {
+	s := getRunWSSession("ws-resume-test")
+	for _, text := range []string{"one", "two", "three"} {
+		data, _ := json.Marshal(map[string]string{"text": text})
+		s.emit("text", data)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(HandleRunWebSocket))
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "?sessionId=ws-resume-test&after=1"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var hello RunWSEvent
+	if err := conn.ReadJSON(&hello); err != nil || hello.Event != "session" {
+		t.Fatalf("first event = %+v, %v; want session", hello, err)
+	}
+	for _, want := range []int64{2, 3} {
+		var ev RunWSEvent
+		if err := conn.ReadJSON(&ev); err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		if ev.Seq != want || ev.Event != "text" {
+			t.Errorf("replayed event = %+v, want seq %d", ev, want)
+		}
+	}
+
+	// Unknown message types are rejected without closing the socket
+	conn.WriteJSON(map[string]string{"type": "bogus"})
+	var ev RunWSEvent
+	if err := conn.ReadJSON(&ev); err != nil || ev.Event != "error" || ev.Seq != 0 {
+		t.Errorf("reply = %+v, %v; want an unbuffered error", ev, err)
+	}
+}
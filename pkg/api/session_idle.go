@@ -0,0 +1,400 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"maps"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/SAP/astonish/pkg/config"
+	persistentsession "github.com/SAP/astonish/pkg/session"
+	"google.golang.org/adk/session"
+)
+
+// parkEventAuthor marks the last event of a session in the park store. It
+// carries the session's full state, and its timestamp is when the session
+// went idle.
+const parkEventAuthor = "astonish_park"
+
+// sessionExpiryNotice is the JSON body POSTed to sessions.idle.notify_webhook
+// before an idle flow session expires.
+type sessionExpiryNotice struct {
+	Event       string    `json:"event"`
+	SessionID   string    `json:"session_id"`
+	IdleSince   time.Time `json:"idle_since"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	CurrentNode string    `json:"current_node,omitempty"`
+	WaitingFor  string    `json:"waiting_for,omitempty"` // "input" or "approval"
+}
+
+// BeginRun marks a flow run as in progress for the session so it is never
+// parked or expired mid-stream. The returned func ends the run; idle time is
+// measured from then.
+func (sm *SessionManager) BeginRun(sessionID string) func() {
+	sm.mu.Lock()
+	now := time.Now()
+	sm.activeRuns[sessionID]++
+	sm.lastActivity[sessionID] = now
+	sm.lastInteraction[sessionID] = now
+	delete(sm.parked, sessionID)
+	delete(sm.expiryNotified, sessionID)
+	sm.mu.Unlock()
+
+	return func() {
+		sm.mu.Lock()
+		defer sm.mu.Unlock()
+		if sm.activeRuns[sessionID]--; sm.activeRuns[sessionID] <= 0 {
+			delete(sm.activeRuns, sessionID)
+		}
+		sm.lastInteraction[sessionID] = time.Now()
+	}
+}
+
+// idleSession is a session the sweep parks or expires. It is taken from the
+// manager under sm.mu, and the slow part (stopping its servers and sandbox,
+// writing or deleting files) runs after the lock is released.
+type idleSession struct {
+	id        string
+	idleSince time.Time
+	sess      session.Session // nil when the session is only in the park store
+	release   func()          // Stops its MCP servers and sandbox
+	expire    bool            // Delete the session instead of parking it
+}
+
+// sweepIdleSessions parks sessions idle past park_after (or whose UI stopped
+// sending keepalives), notifies the webhook ahead of expiry, and deletes
+// sessions idle past expire_after. Keepalives don't reset idle time, so a
+// flow left open on an input or approval prompt is still reclaimed.
+func (sm *SessionManager) sweepIdleSessions(now time.Time) {
+	parkAfter := sm.idle.GetParkAfter()
+	expireAfter := sm.idle.GetExpireAfter()
+	notifyBefore := sm.idle.GetNotifyBefore()
+
+	var swept []idleSession
+	var notices []sessionExpiryNotice
+	var onDisk []int // Notices of parked sessions, whose state is on disk
+
+	sm.mu.Lock()
+	parkStore := sm.parkStore
+	for sessionID, lastActive := range sm.lastActivity {
+		if sm.activeRuns[sessionID] > 0 {
+			continue
+		}
+		idleSince := lastActive
+		if t, ok := sm.lastInteraction[sessionID]; ok {
+			idleSince = t
+		}
+		idle := now.Sub(idleSince)
+
+		if expireAfter > 0 && idle >= expireAfter {
+			swept = append(swept, idleSession{
+				id:      sessionID,
+				sess:    sm.sessions[sessionID],
+				release: sm.takeSessionResourcesLocked(sessionID),
+				expire:  true,
+			})
+			delete(sm.sessions, sessionID)
+			delete(sm.lastActivity, sessionID)
+			delete(sm.lastInteraction, sessionID)
+			delete(sm.parked, sessionID)
+			delete(sm.expiryNotified, sessionID)
+			slog.Info("expired idle session", "session_id", sessionID, "idle_for", idle)
+			continue
+		}
+
+		if !sm.parked[sessionID] && (idle >= parkAfter || now.Sub(lastActive) > sessionTimeout) {
+			// The session stays in memory until it is written to the park
+			// store, so requests in between still find it
+			swept = append(swept, idleSession{
+				id:        sessionID,
+				idleSince: idleSince,
+				sess:      sm.sessions[sessionID],
+				release:   sm.takeSessionResourcesLocked(sessionID),
+			})
+			sm.parked[sessionID] = true
+			slog.Info("parked idle session", "session_id", sessionID, "idle_for", idle)
+		}
+
+		if sm.idle.NotifyWebhook != "" && expireAfter > 0 && !sm.expiryNotified[sessionID] && idle >= expireAfter-notifyBefore {
+			sm.expiryNotified[sessionID] = true
+			notice := sessionExpiryNotice{
+				Event:     "session.expiring",
+				SessionID: sessionID,
+				IdleSince: idleSince,
+				ExpiresAt: idleSince.Add(expireAfter),
+			}
+			if sess, ok := sm.sessions[sessionID]; ok {
+				notice.CurrentNode, notice.WaitingFor = pausedAt(sess.State())
+			} else {
+				onDisk = append(onDisk, len(notices))
+			}
+			notices = append(notices, notice)
+		}
+	}
+	sm.mu.Unlock()
+
+	for _, s := range swept {
+		s.release()
+		switch {
+		case s.expire:
+			sm.deleteIdleSession(parkStore, s)
+		case s.sess != nil && parkStore != nil:
+			sm.parkSession(parkStore, s)
+		}
+	}
+
+	for _, i := range onDisk {
+		if state := parkedState(parkStore, notices[i].SessionID); state != nil {
+			notices[i].CurrentNode, notices[i].WaitingFor = pausedAt(state)
+		}
+	}
+	for _, notice := range notices {
+		go sm.sendExpiryNotice(notice)
+	}
+}
+
+// takeSessionResourcesLocked removes the session's MCP servers and sandbox
+// from the manager and returns the func that stops them. Session state is
+// kept. Caller must hold sm.mu.
+func (sm *SessionManager) takeSessionResourcesLocked(sessionID string) func() {
+	mgr := sm.mcpManagers[sessionID]
+	cleanup := sm.sandboxCleanups[sessionID]
+	delete(sm.mcpManagers, sessionID)
+	delete(sm.sandboxCleanups, sessionID)
+	delete(sm.sandboxTools, sessionID)
+	return func() {
+		if mgr != nil {
+			mgr.Cleanup()
+		}
+		if cleanup != nil {
+			cleanup()
+		}
+	}
+}
+
+// releaseSessionResourcesLocked stops the session's MCP servers and sandbox.
+// Session state is kept. Caller must hold sm.mu.
+func (sm *SessionManager) releaseSessionResourcesLocked(sessionID string) {
+	sm.takeSessionResourcesLocked(sessionID)()
+}
+
+// deleteIdleSession deletes an expired session from memory or from the
+// park store.
+func (sm *SessionManager) deleteIdleSession(parkStore *persistentsession.FileStore, s idleSession) {
+	if s.sess != nil {
+		err := sm.service.Delete(context.Background(), &session.DeleteRequest{
+			AppName:   s.sess.AppName(),
+			UserID:    s.sess.UserID(),
+			SessionID: s.sess.ID(),
+		})
+		if err != nil {
+			slog.Warn("failed to delete expired session", "session_id", s.id, "error", err)
+		}
+		return
+	}
+	if parkStore == nil {
+		return
+	}
+	if meta, err := parkStore.GetSessionMeta(s.id); err == nil {
+		deleteParked(parkStore, meta.AppName, meta.UserID, s.id)
+	}
+}
+
+// openParkStore keeps parked sessions in <sessions dir>/parked, so a parked
+// session holds no memory and survives a restart. Sessions already parked
+// there are tracked again for resume and expiry.
+func (sm *SessionManager) openParkStore(cfg *config.SessionConfig) {
+	dir, err := config.GetSessionsDir(cfg)
+	if err != nil {
+		slog.Warn("parked sessions stay in memory", "error", err)
+		return
+	}
+	parkStore, err := persistentsession.NewFileStore(filepath.Join(dir, "parked"))
+	if err != nil {
+		slog.Warn("parked sessions stay in memory", "error", err)
+		return
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.parkStore = parkStore
+	metas, err := parkStore.ListSessionMetas("astonish", "")
+	if err != nil {
+		slog.Warn("failed to list parked sessions", "error", err)
+		return
+	}
+	for _, meta := range metas {
+		// The park event is the last one, so UpdatedAt is the idle start
+		sm.lastActivity[meta.ID] = meta.UpdatedAt
+		sm.lastInteraction[meta.ID] = meta.UpdatedAt
+		sm.parked[meta.ID] = true
+	}
+}
+
+// parkSession moves a session from memory to the park store. A session
+// that was resumed while it was written stays in memory, and a session
+// that could not be written stays in memory parked.
+func (sm *SessionManager) parkSession(parkStore *persistentsession.FileStore, s idleSession) {
+	if err := writeParked(parkStore, s.sess, s.id, s.idleSince); err != nil {
+		slog.Warn("failed to store parked session, keeping it in memory", "session_id", s.id, "error", err)
+		return
+	}
+
+	sm.mu.Lock()
+	resumed := !sm.parked[s.id] || sm.sessions[s.id] != s.sess
+	if !resumed {
+		delete(sm.sessions, s.id)
+	}
+	sm.mu.Unlock()
+
+	if resumed {
+		deleteParked(parkStore, s.sess.AppName(), s.sess.UserID(), s.id)
+		return
+	}
+	err := sm.service.Delete(context.Background(), &session.DeleteRequest{
+		AppName:   s.sess.AppName(),
+		UserID:    s.sess.UserID(),
+		SessionID: s.sess.ID(),
+	})
+	if err != nil {
+		slog.Warn("failed to drop parked session from memory", "session_id", s.id, "error", err)
+	}
+}
+
+// writeParked stores sess in the park store under sessionID.
+func writeParked(parkStore *persistentsession.FileStore, sess session.Session, sessionID string, idleSince time.Time) error {
+	ctx := context.Background()
+	// Stored under the manager's key, which the restored session takes as
+	// its ID
+	resp, err := parkStore.Create(ctx, &session.CreateRequest{
+		AppName:   sess.AppName(),
+		UserID:    sess.UserID(),
+		SessionID: sessionID,
+	})
+	if err != nil {
+		return err
+	}
+
+	// The store rebuilds state from event deltas, so the park event carries
+	// all of it, including values set outside events
+	park := session.NewEvent("")
+	park.Author = parkEventAuthor
+	park.Timestamp = idleSince
+	park.Actions.StateDelta = maps.Collect(sess.State().All())
+	events := append(slices.Collect(sess.Events().All()), park)
+	if _, err := parkStore.AppendEvents(ctx, resp.Session, events); err != nil {
+		deleteParked(parkStore, sess.AppName(), sess.UserID(), sessionID)
+		return err
+	}
+	return nil
+}
+
+// unparkSessionLocked moves a session from the park store back to memory.
+// It returns false when the session is not parked there. Caller must hold
+// sm.mu.
+func (sm *SessionManager) unparkSessionLocked(ctx context.Context, sessionID string) (session.Session, bool) {
+	if sm.parkStore == nil {
+		return nil, false
+	}
+	meta, err := sm.parkStore.GetSessionMeta(sessionID)
+	if err != nil {
+		return nil, false
+	}
+	resp, err := sm.parkStore.Get(ctx, &session.GetRequest{AppName: meta.AppName, UserID: meta.UserID, SessionID: sessionID})
+	if err != nil {
+		slog.Warn("failed to load parked session", "session_id", sessionID, "error", err)
+		return nil, false
+	}
+	parked := resp.Session
+
+	created, err := sm.service.Create(ctx, &session.CreateRequest{
+		AppName:   meta.AppName,
+		UserID:    meta.UserID,
+		SessionID: sessionID,
+		State:     maps.Collect(parked.State().All()),
+	})
+	if err != nil {
+		slog.Warn("failed to restore parked session", "session_id", sessionID, "error", err)
+		return nil, false
+	}
+	for event := range parked.Events().All() {
+		if event.Author == parkEventAuthor {
+			continue
+		}
+		restored := *event
+		restored.Actions.StateDelta = nil // The state was restored as a whole
+		if err := sm.service.AppendEvent(ctx, created.Session, &restored); err != nil {
+			slog.Warn("failed to restore parked session event", "session_id", sessionID, "error", err)
+		}
+	}
+
+	deleteParked(sm.parkStore, meta.AppName, meta.UserID, sessionID)
+	sm.sessions[sessionID] = created.Session
+	slog.Info("restored parked session", "session_id", sessionID)
+	return created.Session, true
+}
+
+// deleteParked removes a session from the park store.
+func deleteParked(parkStore *persistentsession.FileStore, appName, userID, sessionID string) {
+	err := parkStore.Delete(context.Background(), &session.DeleteRequest{
+		AppName:   appName,
+		UserID:    userID,
+		SessionID: sessionID,
+	})
+	if err != nil {
+		slog.Warn("failed to delete parked session", "session_id", sessionID, "error", err)
+	}
+}
+
+// parkedState returns the state of a session in the park store, or nil
+// when it is not there.
+func parkedState(parkStore *persistentsession.FileStore, sessionID string) session.State {
+	if parkStore == nil {
+		return nil
+	}
+	meta, err := parkStore.GetSessionMeta(sessionID)
+	if err != nil {
+		return nil
+	}
+	resp, err := parkStore.Get(context.Background(), &session.GetRequest{AppName: meta.AppName, UserID: meta.UserID, SessionID: sessionID})
+	if err != nil {
+		return nil
+	}
+	return resp.Session.State()
+}
+
+// pausedAt reports the node a flow session is paused on and what it waits for.
+func pausedAt(state session.State) (node, waitingFor string) {
+	if v, err := state.Get("current_node"); err == nil {
+		node, _ = v.(string)
+	}
+	if v, err := state.Get("awaiting_approval"); err == nil && v == true {
+		waitingFor = "approval"
+	} else if v, err := state.Get("waiting_for_input"); err == nil && v == true {
+		waitingFor = "input"
+	}
+	return node, waitingFor
+}
+
+// sendExpiryNotice POSTs an expiry notice to the configured webhook.
+func (sm *SessionManager) sendExpiryNotice(notice sessionExpiryNotice) {
+	body, err := json.Marshal(notice)
+	if err != nil {
+		return
+	}
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Post(sm.idle.NotifyWebhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		slog.Warn("session expiry webhook failed", "session_id", notice.SessionID, "error", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		slog.Warn("session expiry webhook rejected", "session_id", notice.SessionID, "status", resp.StatusCode)
+	}
+}
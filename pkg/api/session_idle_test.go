@@ -0,0 +1,175 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+func TestSweepIdleSessions_ParkNotifyExpire(t *testing.T) {
+	notices := make(chan sessionExpiryNotice, 1)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n sessionExpiryNotice
+		json.NewDecoder(r.Body).Decode(&n)
+		notices <- n
+	}))
+	defer webhook.Close()
+
+	sm := newSessionManager(config.SessionIdleConfig{
+		ParkAfter:     "10m",
+		ExpireAfter:   "1h",
+		NotifyBefore:  "15m",
+		NotifyWebhook: webhook.URL,
+	})
+	resp, err := sm.service.Create(context.Background(), &session.CreateRequest{AppName: "astonish", UserID: "s1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Session.State().Set("current_node", "confirm")
+	resp.Session.State().Set("awaiting_approval", true)
+	sm.sessions["s1"] = resp.Session
+
+	released, unlocked := false, false
+	sm.sandboxCleanups["s1"] = func() {
+		released = true
+		// The sweep stops sandboxes after releasing the manager's lock
+		if unlocked = sm.mu.TryLock(); unlocked {
+			sm.mu.Unlock()
+		}
+	}
+	sm.BeginRun("s1")()
+	start := sm.lastInteraction["s1"]
+
+	// Keepalives don't count as interaction
+	sm.TouchSession("s1")
+	sm.sweepIdleSessions(start.Add(11 * time.Minute))
+	if !released || !sm.parked["s1"] {
+		t.Fatal("expected session to be parked after park_after")
+	}
+	if !unlocked {
+		t.Error("the sandbox was stopped while holding the session lock")
+	}
+	if _, ok := sm.sessions["s1"]; !ok {
+		t.Fatal("parked session must keep its state")
+	}
+
+	sm.sweepIdleSessions(start.Add(50 * time.Minute))
+	select {
+	case n := <-notices:
+		if n.SessionID != "s1" || n.CurrentNode != "confirm" || n.WaitingFor != "approval" {
+			t.Errorf("unexpected notice: %+v", n)
+		}
+		if !n.ExpiresAt.Equal(start.Add(time.Hour)) {
+			t.Errorf("expires_at = %v, want %v", n.ExpiresAt, start.Add(time.Hour))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected expiry webhook")
+	}
+
+	sm.sweepIdleSessions(start.Add(61 * time.Minute))
+	if _, ok := sm.sessions["s1"]; ok {
+		t.Fatal("expected session to expire after expire_after")
+	}
+	if _, ok := sm.lastActivity["s1"]; ok {
+		t.Fatal("expected expired session to be forgotten")
+	}
+}
+
+func TestSweepIdleSessions_SkipsActiveRuns(t *testing.T) {
+	sm := newSessionManager(config.SessionIdleConfig{ParkAfter: "1m", ExpireAfter: "2m"})
+	endRun := sm.BeginRun("s1")
+	sm.sweepIdleSessions(time.Now().Add(time.Hour))
+	if sm.parked["s1"] {
+		t.Fatal("session with an in-flight run must not be parked")
+	}
+	if _, ok := sm.lastActivity["s1"]; !ok {
+		t.Fatal("session with an in-flight run must not expire")
+	}
+	endRun()
+}
+
+func TestSweepIdleSessions_ParkedSessionSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	idle := config.SessionIdleConfig{ParkAfter: "10m", ExpireAfter: "1h"}
+	ctx := context.Background()
+
+	sm := newSessionManager(idle)
+	sm.openParkStore(&config.SessionConfig{BaseDir: dir})
+	resp, err := sm.service.Create(ctx, &session.CreateRequest{AppName: "astonish", UserID: "s1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := session.NewEvent("inv-1")
+	event.Author = "astonish_agent"
+	event.LLMResponse.Content = genai.NewContentFromText("Which environment?", genai.RoleModel)
+	event.Actions.StateDelta = map[string]any{"current_node": "ask_env"}
+	if err := sm.service.AppendEvent(ctx, resp.Session, event); err != nil {
+		t.Fatal(err)
+	}
+	resp.Session.State().Set("waiting_for_input", true)
+	sm.sessions["s1"] = resp.Session
+	sm.BeginRun("s1")()
+	start := sm.lastInteraction["s1"]
+
+	sm.sweepIdleSessions(start.Add(11 * time.Minute))
+	if _, ok := sm.sessions["s1"]; ok {
+		t.Fatal("parked session must leave memory")
+	}
+
+	// A new manager over the same directory picks the session up again
+	restarted := newSessionManager(idle)
+	restarted.openParkStore(&config.SessionConfig{BaseDir: dir})
+	if !restarted.parked["s1"] || !restarted.lastInteraction["s1"].Equal(start) {
+		t.Fatalf("parked = %v, idle since %v; want parked since %v", restarted.parked["s1"], restarted.lastInteraction["s1"], start)
+	}
+	sess, ok := restarted.unparkSessionLocked(ctx, "s1")
+	if !ok {
+		t.Fatal("expected the parked session to be restored")
+	}
+	if node, waitingFor := pausedAt(sess.State()); node != "ask_env" || waitingFor != "input" {
+		t.Errorf("restored at %q waiting for %q, want ask_env waiting for input", node, waitingFor)
+	}
+	if n := sess.Events().Len(); n != 1 {
+		t.Fatalf("restored %d events, want 1", n)
+	}
+	if got := sess.Events().At(0).LLMResponse.Content.Parts[0].Text; got != "Which environment?" {
+		t.Errorf("restored event text = %q", got)
+	}
+	if _, ok := restarted.unparkSessionLocked(ctx, "s1"); ok {
+		t.Error("a restored session must leave the park store")
+	}
+}
+
+func TestSweepIdleSessions_ExpiresParkedSessionOnDisk(t *testing.T) {
+	dir := t.TempDir()
+	idle := config.SessionIdleConfig{ParkAfter: "10m", ExpireAfter: "1h"}
+	ctx := context.Background()
+
+	sm := newSessionManager(idle)
+	sm.openParkStore(&config.SessionConfig{BaseDir: dir})
+	resp, err := sm.service.Create(ctx, &session.CreateRequest{AppName: "astonish", UserID: "s1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm.sessions["s1"] = resp.Session
+	sm.BeginRun("s1")()
+	start := sm.lastInteraction["s1"]
+	sm.sweepIdleSessions(start.Add(11 * time.Minute))
+
+	restarted := newSessionManager(idle)
+	restarted.openParkStore(&config.SessionConfig{BaseDir: dir})
+	restarted.sweepIdleSessions(start.Add(61 * time.Minute))
+	if restarted.parked["s1"] {
+		t.Fatal("expected the parked session to expire")
+	}
+	if _, ok := restarted.unparkSessionLocked(ctx, "s1"); ok {
+		t.Fatal("an expired session must be deleted from the park store")
+	}
+}
@@ -80,6 +80,31 @@ func normalizeToolName(name string) string {
 	return normalized
 }
 
+// bestNameMatch returns the index of the candidate an LLM-returned name refers
+// to, or -1. Exact and normalized matches win; otherwise the last candidate
+// that contains the name (or is contained in it) is used.
+func bestNameMatch(name string, candidates []string) int {
+	nameLower := strings.ToLower(name)
+	nameNormalized := normalizeToolName(name)
+
+	best := -1
+	for i, candidate := range candidates {
+		candidateLower := strings.ToLower(candidate)
+
+		// Exact match, or normalized match (removes spaces, hyphens, underscores)
+		if candidateLower == nameLower || normalizeToolName(candidate) == nameNormalized {
+			return i
+		}
+
+		// Contains match (for partial matches)
+		if strings.Contains(candidateLower, nameLower) || strings.Contains(nameLower, candidateLower) {
+			best = i
+			// Don't break - keep looking for better match
+		}
+	}
+	return best
+}
+
 // AIToolSearchHandler handles POST /api/ai/tool-search
 // Uses AI to semantically evaluate which store tools can fulfill the requirement
 func AIToolSearchHandler(w http.ResponseWriter, r *http.Request) {
@@ -134,13 +159,41 @@ func AIToolSearchHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// findToolsWithAI uses the LLM to semantically match tools to requirements
+// findToolsWithAI matches store tools to a requirement. With an embedding
+// provider, the tools most similar to the requirement are found locally
+// and the LLM only re-ranks them; without one, or when the LLM is not
+// available, the whole catalog goes to the LLM or the similar tools are
+// returned as they are.
 func findToolsWithAI(ctx context.Context, requirement string, toolSummaries []string, servers []mcpstore.Server, appCfg *config.AppConfig) []ToolSearchResult {
 	if appCfg == nil {
 		return nil
 	}
 	injectProviderSecrets(appCfg)
 
+	candidates, scores, ok := semanticToolCandidates(ctx, requirement, toolSummaries, appCfg)
+	if !ok {
+		results, _ := matchToolsWithLLM(ctx, requirement, toolSummaries, servers, appCfg)
+		return results
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	candidateSummaries := make([]string, len(candidates))
+	candidateServers := make([]mcpstore.Server, len(candidates))
+	for i, idx := range candidates {
+		candidateSummaries[i] = toolSummaries[idx]
+		candidateServers[i] = servers[idx]
+	}
+	results, err := matchToolsWithLLM(ctx, requirement, candidateSummaries, candidateServers, appCfg)
+	if err == nil {
+		return results
+	}
+	slog.Debug("tool search re-ranking failed, returning similar tools", "error", err)
+	return similarToolResults(candidateServers, scores)
+}
+
+// matchToolsWithLLM asks the LLM which of the tools match a requirement.
+func matchToolsWithLLM(ctx context.Context, requirement string, toolSummaries []string, servers []mcpstore.Server, appCfg *config.AppConfig) ([]ToolSearchResult, error) {
 	providerName := appCfg.General.DefaultProvider
 	modelName := appCfg.General.DefaultModel
 	if providerName == "" {
@@ -152,7 +205,7 @@ func findToolsWithAI(ctx context.Context, requirement string, toolSummaries []st
 
 	llm, err := provider.GetProvider(ctx, providerName, modelName, appCfg)
 	if err != nil {
-		return nil
+		return nil, err
 	}
 
 	// Build prompt for tool matching
@@ -198,7 +251,7 @@ CRITICAL: The "name" field MUST be copied EXACTLY from the AVAILABLE TOOLS list.
 	var responseText strings.Builder
 	for resp, err := range llm.GenerateContent(ctx, llmReq, true) {
 		if err != nil {
-			return nil
+			return nil, err
 		}
 		if resp != nil && resp.Content != nil {
 			for _, part := range resp.Content.Parts {
@@ -216,7 +269,7 @@ CRITICAL: The "name" field MUST be copied EXACTLY from the AVAILABLE TOOLS list.
 	jsonStart := strings.Index(response, "{")
 	jsonEnd := strings.LastIndex(response, "}")
 	if jsonStart == -1 || jsonEnd == -1 || jsonEnd <= jsonStart {
-		return nil
+		return nil, fmt.Errorf("no JSON in the model's answer")
 	}
 	jsonStr := response[jsonStart : jsonEnd+1]
 
@@ -227,42 +280,19 @@ CRITICAL: The "name" field MUST be copied EXACTLY from the AVAILABLE TOOLS list.
 		} `json:"matches"`
 	}
 	if err := json.Unmarshal([]byte(jsonStr), &parsed); err != nil {
-		return nil
+		return nil, err
 	}
 
 	// Build results by matching names back to servers
 	// Use fuzzy matching to handle LLM returning slightly different names
 	var results []ToolSearchResult
+	serverNames := make([]string, len(servers))
+	for i, srv := range servers {
+		serverNames[i] = srv.Name
+	}
 	for _, match := range parsed.Matches {
-		matchNameLower := strings.ToLower(match.Name)
-		matchNameNormalized := normalizeToolName(match.Name)
-
-		var bestMatch *mcpstore.Server
-		for i := range servers {
-			srv := &servers[i]
-			srvNameLower := strings.ToLower(srv.Name)
-			srvNameNormalized := normalizeToolName(srv.Name)
-
-			// Exact match
-			if srvNameLower == matchNameLower {
-				bestMatch = srv
-				break
-			}
-
-			// Normalized match (removes spaces, hyphens, underscores)
-			if srvNameNormalized == matchNameNormalized {
-				bestMatch = srv
-				break
-			}
-
-			// Contains match (for partial matches)
-			if strings.Contains(srvNameLower, matchNameLower) || strings.Contains(matchNameLower, srvNameLower) {
-				bestMatch = srv
-				// Don't break - keep looking for better match
-			}
-		}
-
-		if bestMatch != nil {
+		if idx := bestNameMatch(match.Name, serverNames); idx >= 0 {
+			bestMatch := &servers[idx]
 			// Check if already added (avoid duplicates)
 			alreadyAdded := false
 			for _, r := range results {
@@ -275,27 +305,32 @@ CRITICAL: The "name" field MUST be copied EXACTLY from the AVAILABLE TOOLS list.
 				continue
 			}
 
-			// Extract env vars from config if present
-			var envVars map[string]string
-			if bestMatch.Config != nil && len(bestMatch.Config.Env) > 0 {
-				envVars = bestMatch.Config.Env
-			}
-
-			results = append(results, ToolSearchResult{
-				ID:             bestMatch.McpId,
-				Name:           bestMatch.Name,
-				Description:    bestMatch.Description,
-				Source:         bestMatch.Source,
-				Tags:           bestMatch.Tags,
-				Installable:    true,
-				Reason:         match.Reason,
-				RequiresApiKey: bestMatch.RequiresApiKey,
-				EnvVars:        envVars,
-			})
+			results = append(results, toolSearchResult(bestMatch, match.Reason))
 		}
 	}
 
-	return results
+	return results, nil
+}
+
+// toolSearchResult returns the search result of an installable server.
+func toolSearchResult(srv *mcpstore.Server, reason string) ToolSearchResult {
+	// Extract env vars from config if present
+	var envVars map[string]string
+	if srv.Config != nil && len(srv.Config.Env) > 0 {
+		envVars = srv.Config.Env
+	}
+
+	return ToolSearchResult{
+		ID:             srv.McpId,
+		Name:           srv.Name,
+		Description:    srv.Description,
+		Source:         srv.Source,
+		Tags:           srv.Tags,
+		Installable:    true,
+		Reason:         reason,
+		RequiresApiKey: srv.RequiresApiKey,
+		EnvVars:        envVars,
+	}
 }
 
 // ExtractMissingToolsFromResponse parses the AI response to detect missing tools
@@ -598,16 +633,7 @@ func URLExtractHandler(w http.ResponseWriter, r *http.Request) {
 	slog.Debug("starting url extraction", "component", "url-extract", "url", req.URL)
 
 	// Check if web extract tool is configured
-	// Check if web extract tool is configured
-	configured, serverName, extractToolName := isWebExtractConfiguredWith(effectiveAppConfig(r))
-	if !configured {
-		// Fall back to web search tool
-		var searchConfigured bool
-		searchConfigured, serverName, _ = isWebSearchConfiguredWith(effectiveAppConfig(r))
-		extractToolName = "" // No specific tool name, will fallback to "extract" search
-		configured = searchConfigured
-	}
-
+	configured, serverName, extractToolName := webExtractToolWith(effectiveAppConfig(r))
 	if !configured {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(URLExtractResponse{
@@ -655,6 +681,37 @@ func URLExtractHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// webExtractToolWith returns the MCP tool that extracts web pages: the web
+// extract tool, or else the web search server (whose "extract" tool is found
+// by name).
+func webExtractToolWith(appCfg *config.AppConfig) (configured bool, serverName, toolName string) {
+	configured, serverName, toolName = isWebExtractConfiguredWith(appCfg)
+	if !configured {
+		configured, serverName, _ = isWebSearchConfiguredWith(appCfg)
+		toolName = ""
+	}
+	return configured, serverName, toolName
+}
+
+// ExtractMCPServerFromURL reads the page at url with the configured web
+// extract tool and returns the MCP server it describes, or nil if none.
+func ExtractMCPServerFromURL(ctx context.Context, url string, appCfg *config.AppConfig) (*InternetMCPResult, error) {
+	configured, serverName, extractToolName := webExtractToolWith(appCfg)
+	if !configured {
+		return nil, fmt.Errorf("no web extract tool configured (set general.web_extract_tool)")
+	}
+	return extractMCPServerFromURL(ctx, url, serverName, extractToolName, appCfg)
+}
+
+// SanitizeMCPServerName turns a display name into an MCP config key:
+// lowercase letters, digits and hyphens.
+func SanitizeMCPServerName(name string) string {
+	serverName := strings.ToLower(strings.ReplaceAll(name, " ", "-"))
+	return mcpServerNameInvalid.ReplaceAllString(serverName, "")
+}
+
+var mcpServerNameInvalid = regexp.MustCompile(`[^a-z0-9-]`)
+
 // extractMCPServerFromURL uses the configured MCP tool to extract content and parse for MCP server config
 func extractMCPServerFromURL(ctx context.Context, url string, serverName string, extractToolName string, appCfg *config.AppConfig) (*InternetMCPResult, error) {
 	if appCfg == nil {
@@ -931,8 +988,7 @@ func InternetMCPInstallHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Sanitize server name
-	serverName := strings.ToLower(strings.ReplaceAll(req.Name, " ", "-"))
-	serverName = regexp.MustCompile(`[^a-z0-9-]`).ReplaceAllString(serverName, "")
+	serverName := SanitizeMCPServerName(req.Name)
 	if serverName == "" {
 		serverName = "internet-mcp-server"
 	}
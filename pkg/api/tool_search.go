@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+
+	"github.com/SAP/astonish/pkg/cache"
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/mcpstore"
+	"github.com/SAP/astonish/pkg/provider"
+)
+
+const (
+	// toolSearchCandidates is how many of the most similar tools the LLM
+	// re-ranks.
+	toolSearchCandidates = 15
+	// minToolSimilarity is the similarity below which a tool is not a
+	// candidate.
+	minToolSimilarity = 0.2
+)
+
+// semanticToolCandidates returns the indexes of the tools whose summaries
+// are most similar to requirement, best first, with their similarities.
+// ok is false when no embedding provider is configured or embedding fails.
+func semanticToolCandidates(ctx context.Context, requirement string, toolSummaries []string, appCfg *config.AppConfig) (candidates []int, scores []float32, ok bool) {
+	instance := appCfg.General.EmbeddingProvider
+	if instance == "" {
+		instance = appCfg.General.DefaultProvider
+	}
+	if instance == "" {
+		return nil, nil, false
+	}
+	embedder, err := provider.GetEmbeddingProvider(ctx, instance, appCfg.General.EmbeddingModel, appCfg)
+	if err != nil {
+		slog.Debug("semantic tool search unavailable", "provider", instance, "error", err)
+		return nil, nil, false
+	}
+
+	vectors, err := embedToolCatalog(ctx, embedder, instance, toolSummaries)
+	if err != nil {
+		slog.Warn("embedding the tool catalog failed", "provider", instance, "error", err)
+		return nil, nil, false
+	}
+	query, err := provider.EmbedText(ctx, embedder, requirement)
+	if err != nil {
+		slog.Warn("embedding the tool search failed", "provider", instance, "error", err)
+		return nil, nil, false
+	}
+	candidates, scores = rankBySimilarity(query, vectors, toolSearchCandidates, minToolSimilarity)
+	return candidates, scores, true
+}
+
+// embedToolCatalog returns the vectors of texts. Vectors are kept in the
+// persistent tools cache, so only new or changed tools are embedded.
+func embedToolCatalog(ctx context.Context, embedder provider.EmbeddingProvider, instance string, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	keys := make([]string, len(texts))
+	var missing []string
+	var missingAt []int
+	for i, text := range texts {
+		keys[i] = cache.EmbeddingKey(instance, embedder.Name(), text)
+		if v, ok := cache.GetEmbedding(keys[i]); ok {
+			vectors[i] = v
+			continue
+		}
+		missing = append(missing, text)
+		missingAt = append(missingAt, i)
+	}
+	if len(missing) == 0 {
+		return vectors, nil
+	}
+
+	embedded, err := embedder.Embed(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	if len(embedded) != len(missing) {
+		return nil, fmt.Errorf("got %d vectors for %d tools", len(embedded), len(missing))
+	}
+	for j, i := range missingAt {
+		vectors[i] = embedded[j]
+	}
+
+	catalog := make(map[string][]float32, len(texts))
+	for i, key := range keys {
+		catalog[key] = vectors[i]
+	}
+	cache.SetEmbeddings(catalog)
+	if err := cache.SaveCache(); err != nil {
+		slog.Warn("failed to save tool embeddings", "error", err)
+	}
+	return vectors, nil
+}
+
+// rankBySimilarity returns the indexes of up to k vectors with a cosine
+// similarity to query of at least minScore, most similar first.
+func rankBySimilarity(query []float32, vectors [][]float32, k int, minScore float32) ([]int, []float32) {
+	type scored struct {
+		idx   int
+		score float32
+	}
+	var ranked []scored
+	for i, v := range vectors {
+		if score := cosineSimilarity(query, v); score >= minScore {
+			ranked = append(ranked, scored{i, score})
+		}
+	}
+	sort.SliceStable(ranked, func(a, b int) bool { return ranked[a].score > ranked[b].score })
+	if len(ranked) > k {
+		ranked = ranked[:k]
+	}
+	idx := make([]int, len(ranked))
+	scores := make([]float32, len(ranked))
+	for i, r := range ranked {
+		idx[i], scores[i] = r.idx, r.score
+	}
+	return idx, scores
+}
+
+// similarToolResults returns the results for servers found by similarity
+// alone.
+func similarToolResults(servers []mcpstore.Server, scores []float32) []ToolSearchResult {
+	results := make([]ToolSearchResult, len(servers))
+	for i := range servers {
+		results[i] = toolSearchResult(&servers[i], fmt.Sprintf("Similar to your request (%.0f%% match)", scores[i]*100))
+	}
+	return results
+}
+
+func cosineSimilarity(a, b []float32) float32 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return float32(dot / (math.Sqrt(normA) * math.Sqrt(normB)))
+}
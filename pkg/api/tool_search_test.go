@@ -0,0 +1,70 @@
+package api
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/cache"
+)
+
+// keywordEmbedder embeds a text by which of a few keywords it contains.
+type keywordEmbedder struct {
+	embedded []string
+}
+
+func (e *keywordEmbedder) Name() string { return "keywords" }
+
+func (e *keywordEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	e.embedded = append(e.embedded, texts...)
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		for _, kw := range []string{"browser", "screenshot", "database", "sql"} {
+			if strings.Contains(strings.ToLower(text), kw) {
+				vectors[i] = append(vectors[i], 1)
+			} else {
+				vectors[i] = append(vectors[i], 0)
+			}
+		}
+	}
+	return vectors, nil
+}
+
+func TestSemanticToolRanking(t *testing.T) {
+	cache.SetCacheDir(t.TempDir())
+	t.Cleanup(func() { cache.SetCacheDir("") })
+
+	catalog := []string{
+		"- postgres: Query a SQL database",
+		"- playwright: Drive a browser and take a screenshot",
+		"- weather: Forecasts",
+	}
+	e := &keywordEmbedder{}
+	vectors, err := embedToolCatalog(context.Background(), e, "local", catalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query, _ := e.Embed(context.Background(), []string{"take a screenshot of a page"})
+	candidates, scores := rankBySimilarity(query[0], vectors, 5, minToolSimilarity)
+	if !slices.Equal(candidates, []int{1}) || scores[0] < 0.5 {
+		t.Errorf("candidates = %v (%v), want only playwright", candidates, scores)
+	}
+
+	// The catalog is embedded once; a new tool is embedded on its own
+	cache.InvalidateCache()
+	e.embedded = nil
+	if _, err := embedToolCatalog(context.Background(), e, "local", append(catalog, "- mysql: SQL database client")); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(e.embedded, []string{"- mysql: SQL database client"}) {
+		t.Errorf("embedded %v, want only the new tool after reloading the cache", e.embedded)
+	}
+
+	// Another provider instance does not reuse the vectors
+	e.embedded = nil
+	embedToolCatalog(context.Background(), e, "remote", catalog[:1])
+	if len(e.embedded) != 1 {
+		t.Errorf("embedded %v for another instance, want the tool embedded again", e.embedded)
+	}
+}
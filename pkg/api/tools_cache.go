@@ -211,8 +211,7 @@ func RefreshMCPServerHandler(w http.ResponseWriter, r *http.Request) {
 // Call this BEFORE saving the server config to the DB. Returns nil if the
 // server can be installed, or an error describing why not.
 func checkStdioMCPInstallable(transport string) error {
-	t := strings.ToLower(transport)
-	if t == "sse" || t == "streamable-http" {
+	if (&config.MCPServerConfig{Transport: transport}).IsRemote() {
 		return nil // network-based, no sandbox needed
 	}
 
@@ -248,8 +247,7 @@ func discoverMCPToolsForPlatform(ctx context.Context, serverName string, servers
 	}
 
 	// SSE/streamable-http servers: connect over the network (no sandbox)
-	transport := strings.ToLower(serverCfg.Transport)
-	if transport == "sse" || transport == "streamable-http" {
+	if serverCfg.IsRemote() {
 		return discoverMCPToolsOnHost(ctx, serverName, servers)
 	}
 
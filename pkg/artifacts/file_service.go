@@ -0,0 +1,370 @@
+// Package artifacts stores the files flows produce (screenshots, reports,
+// exports) so that they can be referenced from state and downloaded after
+// the run.
+package artifacts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/adk/artifact"
+	"google.golang.org/genai"
+)
+
+// userScopePrefix marks artifacts shared by all sessions of a user, as in
+// ADK's in-memory artifact service.
+const userScopePrefix = "user:"
+
+// FileService implements ADK's artifact.Service in a local directory.
+// Session artifacts are stored by session ID alone, so that a run's
+// artifacts can be found from its session ID:
+//
+//	<dir>/sessions/<session>/<file name>/<version>       content
+//	<dir>/sessions/<session>/<file name>/<version>.json  metadata
+//	<dir>/users/<app>/<user>/<file name>/...             "user:" artifacts
+type FileService struct {
+	dir string
+	mu  sync.Mutex
+}
+
+var _ artifact.Service = (*FileService)(nil)
+
+// Info describes a stored artifact version.
+type Info struct {
+	Name      string    `json:"name"`
+	Version   int64     `json:"version"`
+	MimeType  string    `json:"mime_type"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+	// Path is the file holding the content.
+	Path string `json:"-"`
+}
+
+// meta is the stored metadata of an artifact version.
+type meta struct {
+	MimeType  string    `json:"mime_type"`
+	Text      bool      `json:"text,omitempty"` // content was a text part
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NewFileService returns an artifact service storing artifacts in dir.
+func NewFileService(dir string) (*FileService, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create artifact directory: %w", err)
+	}
+	return &FileService{dir: dir}, nil
+}
+
+// DefaultDir returns the default artifact directory,
+// ~/.config/astonish/artifacts.
+func DefaultDir() (string, error) {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "artifacts"), nil
+}
+
+var (
+	defaultOnce    sync.Once
+	defaultService *FileService
+	defaultErr     error
+)
+
+// Default returns the process-wide artifact service in DefaultDir.
+func Default() (*FileService, error) {
+	defaultOnce.Do(func() {
+		dir, err := DefaultDir()
+		if err != nil {
+			defaultErr = err
+			return
+		}
+		defaultService, defaultErr = NewFileService(dir)
+	})
+	return defaultService, defaultErr
+}
+
+// RunnerService returns Default for a flow runner's ArtifactService, or nil
+// (artifacts disabled) when the artifact directory cannot be created.
+func RunnerService() artifact.Service {
+	svc, err := Default()
+	if err != nil {
+		slog.Warn("artifact storage disabled", "error", err)
+		return nil
+	}
+	return svc
+}
+
+// Save implements artifact.Service.
+func (s *FileService) Save(ctx context.Context, req *artifact.SaveRequest) (*artifact.SaveResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("request validation failed: %w", err)
+	}
+	dir, err := s.fileDir(req.AppName, req.UserID, req.SessionID, req.FileName)
+	if err != nil {
+		return nil, err
+	}
+
+	m := meta{MimeType: "text/plain", Text: true, CreatedAt: time.Now().UTC()}
+	data := []byte(req.Part.Text)
+	if req.Part.InlineData != nil {
+		m = meta{MimeType: req.Part.InlineData.MIMEType, CreatedAt: m.CreatedAt}
+		data = req.Part.InlineData.Data
+	}
+	metaData, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	version := req.Version
+	if version <= 0 {
+		versions, _ := versionsIn(dir)
+		version = 1
+		if len(versions) > 0 {
+			version = versions[0] + 1
+		}
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create artifact directory: %w", err)
+	}
+	base := filepath.Join(dir, strconv.FormatInt(version, 10))
+	if err := os.WriteFile(base, data, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write artifact: %w", err)
+	}
+	if err := os.WriteFile(base+".json", metaData, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write artifact metadata: %w", err)
+	}
+	return &artifact.SaveResponse{Version: version}, nil
+}
+
+// Load implements artifact.Service.
+func (s *FileService) Load(ctx context.Context, req *artifact.LoadRequest) (*artifact.LoadResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("request validation failed: %w", err)
+	}
+	dir, err := s.fileDir(req.AppName, req.UserID, req.SessionID, req.FileName)
+	if err != nil {
+		return nil, err
+	}
+	info, m, err := s.stat(dir, req.FileName, req.Version)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(info.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifact: %w", err)
+	}
+	if m.Text {
+		return &artifact.LoadResponse{Part: genai.NewPartFromText(string(data))}, nil
+	}
+	return &artifact.LoadResponse{Part: genai.NewPartFromBytes(data, m.MimeType)}, nil
+}
+
+// Delete implements artifact.Service. Without a version, all versions are
+// deleted.
+func (s *FileService) Delete(ctx context.Context, req *artifact.DeleteRequest) error {
+	if err := req.Validate(); err != nil {
+		return fmt.Errorf("request validation failed: %w", err)
+	}
+	dir, err := s.fileDir(req.AppName, req.UserID, req.SessionID, req.FileName)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if req.Version <= 0 {
+		return os.RemoveAll(dir)
+	}
+	base := filepath.Join(dir, strconv.FormatInt(req.Version, 10))
+	for _, p := range []string{base, base + ".json"} {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// List implements artifact.Service: the session's artifacts and the
+// user's "user:" artifacts.
+func (s *FileService) List(ctx context.Context, req *artifact.ListRequest) (*artifact.ListResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("request validation failed: %w", err)
+	}
+	var names []string
+	for _, dir := range []string{s.sessionDir(req.SessionID), s.userDir(req.AppName, req.UserID)} {
+		entries, err := os.ReadDir(dir)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		for _, e := range entries {
+			if name, err := url.PathUnescape(e.Name()); err == nil && e.IsDir() {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return &artifact.ListResponse{FileNames: names}, nil
+}
+
+// Versions implements artifact.Service, newest version first.
+func (s *FileService) Versions(ctx context.Context, req *artifact.VersionsRequest) (*artifact.VersionsResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("request validation failed: %w", err)
+	}
+	dir, err := s.fileDir(req.AppName, req.UserID, req.SessionID, req.FileName)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := versionsIn(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &artifact.VersionsResponse{Versions: versions}, nil
+}
+
+// GetArtifactVersion implements artifact.Service.
+func (s *FileService) GetArtifactVersion(ctx context.Context, req *artifact.GetArtifactVersionRequest) (*artifact.GetArtifactVersionResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("request validation failed: %w", err)
+	}
+	dir, err := s.fileDir(req.AppName, req.UserID, req.SessionID, req.FileName)
+	if err != nil {
+		return nil, err
+	}
+	info, _, err := s.stat(dir, req.FileName, req.Version)
+	if err != nil {
+		return nil, err
+	}
+	return &artifact.GetArtifactVersionResponse{ArtifactVersion: &artifact.ArtifactVersion{
+		Version:      info.Version,
+		CanonicalURI: (&url.URL{Scheme: "file", Path: filepath.ToSlash(info.Path)}).String(),
+		CreateTime:   float64(info.CreatedAt.UnixNano()) / 1e9,
+		MimeType:     info.MimeType,
+	}}, nil
+}
+
+// SessionArtifacts returns the latest version of each artifact saved in a
+// session, by session ID alone.
+func (s *FileService) SessionArtifacts(sessionID string) ([]Info, error) {
+	dir := s.sessionDir(sessionID)
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var infos []Info
+	for _, e := range entries {
+		name, err := url.PathUnescape(e.Name())
+		if err != nil || !e.IsDir() {
+			continue
+		}
+		if info, _, err := s.stat(filepath.Join(dir, e.Name()), name, 0); err == nil {
+			infos = append(infos, info)
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos, nil
+}
+
+// SessionArtifact returns a version (0 = latest) of an artifact saved in a
+// session, by session ID alone.
+func (s *FileService) SessionArtifact(sessionID, name string, version int64) (Info, error) {
+	if err := validName(name); err != nil {
+		return Info{}, err
+	}
+	info, _, err := s.stat(filepath.Join(s.sessionDir(sessionID), escape(name)), name, version)
+	return info, err
+}
+
+// stat returns the info of a version (0 = latest) of the artifact in dir.
+func (s *FileService) stat(dir, name string, version int64) (Info, meta, error) {
+	if version <= 0 {
+		versions, err := versionsIn(dir)
+		if err != nil {
+			return Info{}, meta{}, err
+		}
+		version = versions[0]
+	}
+	base := filepath.Join(dir, strconv.FormatInt(version, 10))
+	var m meta
+	metaData, err := os.ReadFile(base + ".json")
+	if err == nil {
+		err = json.Unmarshal(metaData, &m)
+	}
+	if err != nil {
+		return Info{}, meta{}, fmt.Errorf("artifact %s version %d not found: %w", name, version, fs.ErrNotExist)
+	}
+	fi, err := os.Stat(base)
+	if err != nil {
+		return Info{}, meta{}, fmt.Errorf("artifact %s version %d not found: %w", name, version, fs.ErrNotExist)
+	}
+	return Info{Name: name, Version: version, MimeType: m.MimeType, Size: fi.Size(), CreatedAt: m.CreatedAt, Path: base}, m, nil
+}
+
+// versionsIn returns the versions stored in dir, newest first.
+func versionsIn(dir string) ([]int64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	var versions []int64
+	for _, e := range entries {
+		if v, err := strconv.ParseInt(e.Name(), 10, 64); err == nil {
+			versions = append(versions, v)
+		}
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("artifact not found: %w", fs.ErrNotExist)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+	return versions, nil
+}
+
+func (s *FileService) fileDir(app, user, sessionID, name string) (string, error) {
+	if err := validName(name); err != nil {
+		return "", err
+	}
+	if strings.HasPrefix(name, userScopePrefix) {
+		return filepath.Join(s.userDir(app, user), escape(name)), nil
+	}
+	return filepath.Join(s.sessionDir(sessionID), escape(name)), nil
+}
+
+func (s *FileService) sessionDir(sessionID string) string {
+	return filepath.Join(s.dir, "sessions", escape(sessionID))
+}
+
+func (s *FileService) userDir(app, user string) string {
+	return filepath.Join(s.dir, "users", escape(app), escape(user))
+}
+
+func validName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid artifact name %q", name)
+	}
+	return nil
+}
+
+// escape makes a name safe as a single path element on all platforms.
+func escape(name string) string {
+	if name == "." || name == ".." {
+		return strings.ReplaceAll(name, ".", "%2E")
+	}
+	return strings.ReplaceAll(url.PathEscape(name), ":", "%3A")
+}
@@ -0,0 +1,101 @@
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"testing"
+
+	"google.golang.org/adk/artifact"
+	"google.golang.org/genai"
+)
+
+func TestFileService(t *testing.T) {
+	ctx := context.Background()
+	svc, err := NewFileService(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	save := func(session, name string, part *genai.Part) int64 {
+		t.Helper()
+		resp, err := svc.Save(ctx, &artifact.SaveRequest{AppName: "astonish", UserID: "u1", SessionID: session, FileName: name, Part: part})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Version
+	}
+
+	if v := save("s1", "report.md", genai.NewPartFromText("# v1")); v != 1 {
+		t.Errorf("first version = %d", v)
+	}
+	if v := save("s1", "report.md", genai.NewPartFromText("# v2")); v != 2 {
+		t.Errorf("second version = %d", v)
+	}
+	save("s1", "shot.png", genai.NewPartFromBytes([]byte{0x89, 'P', 'N', 'G'}, "image/png"))
+	save("s2", "user:profile.json", genai.NewPartFromText("{}"))
+	save("s2", "other.txt", genai.NewPartFromText("s2 only"))
+
+	load := func(name string, version int64) *genai.Part {
+		t.Helper()
+		resp, err := svc.Load(ctx, &artifact.LoadRequest{AppName: "astonish", UserID: "u1", SessionID: "s1", FileName: name, Version: version})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Part
+	}
+	if p := load("report.md", 0); p.Text != "# v2" {
+		t.Errorf("latest = %q", p.Text)
+	}
+	if p := load("report.md", 1); p.Text != "# v1" {
+		t.Errorf("version 1 = %q", p.Text)
+	}
+	if p := load("shot.png", 0); p.InlineData == nil || p.InlineData.MIMEType != "image/png" || len(p.InlineData.Data) != 4 {
+		t.Errorf("binary artifact = %+v", p)
+	}
+	// user: artifacts are shared by the user's sessions
+	if p := load("user:profile.json", 0); p.Text != "{}" {
+		t.Errorf("user artifact = %q", p.Text)
+	}
+
+	list, err := svc.List(ctx, &artifact.ListRequest{AppName: "astonish", UserID: "u1", SessionID: "s1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"report.md", "shot.png", "user:profile.json"}; len(list.FileNames) != 3 || list.FileNames[0] != want[0] || list.FileNames[1] != want[1] || list.FileNames[2] != want[2] {
+		t.Errorf("list = %v, want %v", list.FileNames, want)
+	}
+	versions, err := svc.Versions(ctx, &artifact.VersionsRequest{AppName: "astonish", UserID: "u1", SessionID: "s1", FileName: "report.md"})
+	if err != nil || len(versions.Versions) != 2 || versions.Versions[0] != 2 {
+		t.Errorf("versions = %+v, %v", versions, err)
+	}
+
+	// Downloads find a run's artifacts by session ID alone
+	saved, err := svc.SessionArtifacts("s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved) != 2 || saved[0].Name != "report.md" || saved[0].Version != 2 || saved[1].MimeType != "image/png" {
+		t.Errorf("session artifacts = %+v", saved)
+	}
+	info, err := svc.SessionArtifact("s1", "report.md", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(info.Path); string(data) != "# v1" {
+		t.Errorf("version 1 content = %q", data)
+	}
+	if _, err := svc.SessionArtifact("s1", "missing.txt", 0); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing artifact: %v", err)
+	}
+	if _, err := svc.SessionArtifact("s1", "../s2/other.txt", 0); err == nil {
+		t.Error("expected an error for a name with a path")
+	}
+
+	if err := svc.Delete(ctx, &artifact.DeleteRequest{AppName: "astonish", UserID: "u1", SessionID: "s1", FileName: "report.md", Version: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if p := load("report.md", 0); p.Text != "# v1" {
+		t.Errorf("latest after deleting v2 = %q", p.Text)
+	}
+}
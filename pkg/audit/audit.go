@@ -0,0 +1,93 @@
+// Package audit appends a record of every tool execution and approval
+// decision of flow runs to a JSONL file. Records are only ever appended;
+// the file is created readable by its owner only.
+package audit
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Approval decisions of a record.
+const (
+	Approved     = "approved"      // The user approved the call
+	AutoApproved = "auto_approved" // tools_auto_approval or --auto-approve let the call run
+	Denied       = "denied"        // The user denied the call; it did not run
+)
+
+// Execution statuses of a record.
+const (
+	StatusOK     = "ok"      // The tool ran and returned a result
+	StatusError  = "error"   // The tool ran and failed
+	StatusNotRun = "not_run" // The call was denied
+)
+
+// Record is one line of the audit log. Args are recorded as a hash, so the
+// log holds no arguments (and no secrets in them) but a call can be matched
+// against a known one.
+type Record struct {
+	Time       time.Time `json:"time"`
+	Flow       string    `json:"flow,omitempty"`
+	SessionID  string    `json:"session_id,omitempty"`
+	User       string    `json:"user,omitempty"`
+	Node       string    `json:"node,omitempty"`
+	Tool       string    `json:"tool"`
+	ArgsHash   string    `json:"args_hash"`
+	Decision   string    `json:"decision"`
+	Approver   string    `json:"approver"` // cli or web for decisions of the user; the setting for auto approvals
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+// mu serializes appends of the process, so concurrent tool calls never
+// interleave their lines.
+var mu sync.Mutex
+
+// Log is an append-only audit log file. A nil *Log records nothing.
+type Log struct {
+	path string
+}
+
+// Open returns the log at path. The file is created on the first record.
+func Open(path string) *Log {
+	return &Log{path: path}
+}
+
+// Path returns the file of the log.
+func (l *Log) Path() string {
+	return l.path
+}
+
+// Append adds r to the log, stamped with the current time if it has none.
+func (l *Log) Append(r Record) error {
+	if l == nil {
+		return nil
+	}
+	if r.Time.IsZero() {
+		r.Time = time.Now().UTC()
+	}
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	mu.Lock()
+	defer mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// EmbeddingKey returns the cache key of the vector of text, embedded by
+// model on a provider instance.
+func EmbeddingKey(instance, model, text string) string {
+	sum := sha256.Sum256([]byte(instance + "\x00" + model + "\x00" + text))
+	return hex.EncodeToString(sum[:])
+}
+
+// GetEmbedding returns the cached vector of key.
+func GetEmbedding(key string) ([]float32, bool) {
+	cacheMu.RLock()
+	if memoryCache == nil {
+		cacheMu.RUnlock()
+		LoadCache() //nolint:errcheck
+		cacheMu.RLock()
+	}
+	defer cacheMu.RUnlock()
+
+	if memoryCache == nil {
+		return nil, false
+	}
+	v, ok := memoryCache.Embeddings[key]
+	return v, ok
+}
+
+// SetEmbeddings replaces the cached vectors; SaveCache persists them.
+// Vectors not in embeddings are dropped, so the cache holds those of the
+// current catalog and model only.
+func SetEmbeddings(embeddings map[string][]float32) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+
+	if memoryCache == nil {
+		memoryCache = &PersistentToolsCache{
+			Version:         cacheVersion,
+			Tools:           []ToolEntry{},
+			ServerChecksums: make(map[string]string),
+			ServerStatuses:  make(map[string]ServerStatus),
+		}
+	}
+	memoryCache.Embeddings = embeddings
+}
@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// llmResponsesDirName is the directory next to the tools cache holding one
+// file per cached LLM response.
+const llmResponsesDirName = "llm_responses"
+
+// llmResponseEntry is a cached LLM response on disk.
+type llmResponseEntry struct {
+	Model     string            `json:"model"`
+	StoredAt  time.Time         `json:"storedAt"`
+	Responses []json.RawMessage `json:"responses"`
+}
+
+var llmResponsesMu sync.Mutex
+
+// LLMResponseKey returns the cache key of a model call: a hash of the
+// model name and the encoded request (prompt, system instruction, schema
+// and generation settings).
+func LLMResponseKey(modelName string, request []byte) string {
+	sum := sha256.Sum256(append([]byte(modelName+"\x00"), request...))
+	return hex.EncodeToString(sum[:])
+}
+
+// llmResponsePath returns the file of a cached LLM response.
+func llmResponsePath(key string) (string, error) {
+	cachePath, err := getCachePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(cachePath), llmResponsesDirName, key+".json"), nil
+}
+
+// GetLLMResponse returns the encoded responses cached for key. Entries do
+// not expire; run with --no-cache (or delete the llm_responses directory)
+// to call the model again.
+func GetLLMResponse(key string) ([]json.RawMessage, bool) {
+	path, err := llmResponsePath(key)
+	if err != nil {
+		return nil, false
+	}
+
+	llmResponsesMu.Lock()
+	defer llmResponsesMu.Unlock()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var entry llmResponseEntry
+	if err := json.Unmarshal(data, &entry); err != nil || len(entry.Responses) == 0 {
+		os.Remove(path)
+		return nil, false
+	}
+	return entry.Responses, true
+}
+
+// PutLLMResponse stores the encoded responses of a model call under key.
+func PutLLMResponse(key, modelName string, responses []json.RawMessage) error {
+	path, err := llmResponsePath(key)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(llmResponseEntry{Model: modelName, StoredAt: time.Now(), Responses: responses})
+	if err != nil {
+		return fmt.Errorf("failed to marshal LLM response: %w", err)
+	}
+
+	llmResponsesMu.Lock()
+	defer llmResponsesMu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create LLM responses directory: %w", err)
+	}
+	// Write via a temp file so concurrent readers never see a partial entry
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write LLM response: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
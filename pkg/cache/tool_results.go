@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// toolResultsDirName is the directory next to the tools cache holding one
+// file per cached tool result.
+const toolResultsDirName = "tool_results"
+
+// toolResultEntry is a cached tool result on disk.
+type toolResultEntry struct {
+	Tool     string         `json:"tool"`
+	StoredAt time.Time      `json:"storedAt"`
+	Result   map[string]any `json:"result"`
+}
+
+var toolResultsMu sync.Mutex
+
+// ToolResultKey returns the cache key of a tool call: a hash of the tool
+// name and its resolved args. Map keys are marshalled in sorted order, so
+// equal args give equal keys.
+func ToolResultKey(toolName string, args map[string]any) string {
+	data, _ := json.Marshal(args)
+	sum := sha256.Sum256(append([]byte(toolName+"\x00"), data...))
+	return hex.EncodeToString(sum[:])
+}
+
+// toolResultPath returns the file of a cached tool result.
+func toolResultPath(key string) (string, error) {
+	cachePath, err := getCachePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(cachePath), toolResultsDirName, key+".json"), nil
+}
+
+// GetToolResult returns the cached result for key if it was stored less than
+// ttl ago. Expired entries are removed.
+func GetToolResult(key string, ttl time.Duration) (map[string]any, bool) {
+	path, err := toolResultPath(key)
+	if err != nil {
+		return nil, false
+	}
+
+	toolResultsMu.Lock()
+	defer toolResultsMu.Unlock()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var entry toolResultEntry
+	if err := json.Unmarshal(data, &entry); err != nil || time.Since(entry.StoredAt) >= ttl {
+		os.Remove(path)
+		return nil, false
+	}
+	return entry.Result, true
+}
+
+// PutToolResult stores the result of a tool call under key.
+func PutToolResult(key, toolName string, result map[string]any) error {
+	path, err := toolResultPath(key)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(toolResultEntry{Tool: toolName, StoredAt: time.Now(), Result: result})
+	if err != nil {
+		return fmt.Errorf("failed to marshal tool result: %w", err)
+	}
+
+	toolResultsMu.Lock()
+	defer toolResultsMu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create tool results directory: %w", err)
+	}
+	// Write via a temp file so concurrent readers never see a partial entry
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write tool result: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
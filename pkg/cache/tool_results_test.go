@@ -0,0 +1,38 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestToolResults(t *testing.T) {
+	_, cleanup := testSetup(t)
+	defer cleanup()
+
+	key := ToolResultKey("get_pr_diff", map[string]any{"repo": "astonish", "pr": 42})
+	if key != ToolResultKey("get_pr_diff", map[string]any{"pr": 42, "repo": "astonish"}) {
+		t.Error("key should not depend on arg order")
+	}
+	if key == ToolResultKey("get_pr_diff", map[string]any{"repo": "astonish", "pr": 43}) {
+		t.Error("different args should give different keys")
+	}
+
+	if _, ok := GetToolResult(key, time.Minute); ok {
+		t.Fatal("empty cache returned a result")
+	}
+	if err := PutToolResult(key, "get_pr_diff", map[string]any{"diff": "+added"}); err != nil {
+		t.Fatalf("PutToolResult: %v", err)
+	}
+	result, ok := GetToolResult(key, time.Minute)
+	if !ok || result["diff"] != "+added" {
+		t.Fatalf("GetToolResult = %v, %v", result, ok)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := GetToolResult(key, time.Millisecond); ok {
+		t.Error("expired entry was returned")
+	}
+	if _, ok := GetToolResult(key, time.Minute); ok {
+		t.Error("expired entry was not removed")
+	}
+}
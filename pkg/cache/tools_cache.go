@@ -40,8 +40,9 @@ type PersistentToolsCache struct {
 	Version         int                     `json:"version"`
 	LastUpdated     time.Time               `json:"lastUpdated"`
 	Tools           []ToolEntry             `json:"tools"`
-	ServerChecksums map[string]string       `json:"serverChecksums"` // server name -> config checksum
-	ServerStatuses  map[string]ServerStatus `json:"serverStatuses"`  // server name -> status
+	ServerChecksums map[string]string       `json:"serverChecksums"`      // server name -> config checksum
+	ServerStatuses  map[string]ServerStatus `json:"serverStatuses"`       // server name -> status
+	Embeddings      map[string][]float32    `json:"embeddings,omitempty"` // content hash -> vector, for semantic tool search
 }
 
 // Global in-memory copy for fast access
@@ -370,7 +371,12 @@ func ValidateChecksums(verbose bool) (needsRefresh []string, removed []string) {
 		}
 		return nil, nil
 	}
-	if mcpCfg.MCPServers == nil {
+	return ValidateServerChecksums(mcpCfg, verbose)
+}
+
+// ValidateServerChecksums is ValidateChecksums against an already loaded MCP config
+func ValidateServerChecksums(mcpCfg *config.MCPConfig, verbose bool) (needsRefresh []string, removed []string) {
+	if mcpCfg == nil || mcpCfg.MCPServers == nil {
 		return nil, nil
 	}
 
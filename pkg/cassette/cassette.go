@@ -0,0 +1,229 @@
+// Package cassette records the model calls and tool calls of a run into a
+// cassette file, and replays a cassette so the run can be re-executed
+// deterministically without calling providers or tool servers.
+package cassette
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// Version is the cassette format written by this package.
+const Version = 1
+
+// Kinds of interactions.
+const (
+	KindLLM      = "llm"
+	KindTool     = "tool"
+	KindInput    = "input"    // Answer to an input node
+	KindApproval = "approval" // Answer to a tool approval
+)
+
+// Cassette is the recording of a run.
+type Cassette struct {
+	Version      int                                   `json:"version"`
+	Flow         string                                `json:"flow,omitempty"`
+	RecordedAt   time.Time                             `json:"recorded_at"`
+	Tools        map[string]*genai.FunctionDeclaration `json:"tools,omitempty"` // Tools offered to the model or called, by name
+	Interactions []Interaction                         `json:"interactions"`
+}
+
+// Interaction is one model call, tool call or user answer, in the order they
+// completed.
+type Interaction struct {
+	Kind string `json:"kind"`
+
+	// Model calls
+	Model     string     `json:"model,omitempty"`
+	Request   *Request   `json:"request,omitempty"`
+	Responses []Response `json:"responses,omitempty"` // Several when streamed
+
+	// Tool calls
+	Tool   string         `json:"tool,omitempty"`
+	Args   any            `json:"args,omitempty"`
+	Result map[string]any `json:"result,omitempty"`
+
+	// User answers
+	Node  string `json:"node,omitempty"`
+	Value string `json:"value,omitempty"`
+
+	Error string `json:"error,omitempty"` // The call failed with this error
+}
+
+// Request is what a model call sent.
+type Request struct {
+	System   *genai.Content   `json:"system,omitempty"`
+	Contents []*genai.Content `json:"contents"`
+}
+
+// Response is one response of a model call.
+type Response struct {
+	Content       *genai.Content                              `json:"content,omitempty"`
+	UsageMetadata *genai.GenerateContentResponseUsageMetadata `json:"usage,omitempty"`
+	Partial       bool                                        `json:"partial,omitempty"`
+	TurnComplete  bool                                        `json:"turn_complete,omitempty"`
+	FinishReason  genai.FinishReason                          `json:"finish_reason,omitempty"`
+	ErrorCode     string                                      `json:"error_code,omitempty"`
+	ErrorMessage  string                                      `json:"error_message,omitempty"`
+}
+
+// Load reads a cassette file.
+func Load(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+	if c.Version > Version {
+		return nil, fmt.Errorf("cassette %s has version %d; this build reads up to version %d", path, c.Version, Version)
+	}
+	return &c, nil
+}
+
+// Save writes the cassette to path, creating its directory.
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %w", err)
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func newRequest(req *model.LLMRequest) *Request {
+	r := &Request{Contents: req.Contents}
+	if req.Config != nil {
+		r.System = req.Config.SystemInstruction
+	}
+	return r
+}
+
+func newResponse(resp *model.LLMResponse) Response {
+	return Response{
+		Content:       resp.Content,
+		UsageMetadata: resp.UsageMetadata,
+		Partial:       resp.Partial,
+		TurnComplete:  resp.TurnComplete,
+		FinishReason:  resp.FinishReason,
+		ErrorCode:     resp.ErrorCode,
+		ErrorMessage:  resp.ErrorMessage,
+	}
+}
+
+func (r Response) llmResponse() *model.LLMResponse {
+	return &model.LLMResponse{
+		Content:       r.Content,
+		UsageMetadata: r.UsageMetadata,
+		Partial:       r.Partial,
+		TurnComplete:  r.TurnComplete,
+		FinishReason:  r.FinishReason,
+		ErrorCode:     r.ErrorCode,
+		ErrorMessage:  r.ErrorMessage,
+	}
+}
+
+// timestampRe matches the timestamp user messages start with.
+var timestampRe = regexp.MustCompile(`^\[\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2} [A-Z]+\]\n`)
+
+// fingerprint identifies a model request by its system instruction and
+// contents. Function call IDs and the timestamps of user messages are left
+// out: they change from run to run.
+func fingerprint(r *Request) string {
+	var b strings.Builder
+	write := func(role string, c *genai.Content) {
+		if c == nil {
+			return
+		}
+		b.WriteString(role + ":" + c.Role + "\n")
+		for _, p := range c.Parts {
+			switch {
+			case p == nil:
+			case p.FunctionCall != nil:
+				fmt.Fprintf(&b, "call %s %s\n", p.FunctionCall.Name, canonical(p.FunctionCall.Args))
+			case p.FunctionResponse != nil:
+				fmt.Fprintf(&b, "response %s %s\n", p.FunctionResponse.Name, canonical(p.FunctionResponse.Response))
+			default:
+				b.WriteString(timestampRe.ReplaceAllString(p.Text, "") + "\n")
+			}
+		}
+	}
+	if r == nil {
+		return ""
+	}
+	write("system", r.System)
+	for _, c := range r.Contents {
+		write("content", c)
+	}
+	return b.String()
+}
+
+// canonical returns v as JSON after a round trip, so values recorded in a
+// cassette and values of a live run compare equal.
+func canonical(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	var out any
+	if err := json.Unmarshal(data, &out); err != nil {
+		return string(data)
+	}
+	data, _ = json.Marshal(out)
+	return string(data)
+}
+
+// toolDeclarations returns the function declarations offered in a request.
+func toolDeclarations(req *model.LLMRequest) []*genai.FunctionDeclaration {
+	if req.Config == nil {
+		return nil
+	}
+	var decls []*genai.FunctionDeclaration
+	for _, t := range req.Config.Tools {
+		if t != nil {
+			decls = append(decls, t.FunctionDeclarations...)
+		}
+	}
+	return decls
+}
+
+// packTool registers t and its declaration with a model request, as ADK
+// does for function tools.
+func packTool(t interface{ Name() string }, decl *genai.FunctionDeclaration, req *model.LLMRequest) error {
+	if req.Tools == nil {
+		req.Tools = make(map[string]any)
+	}
+	name := t.Name()
+	if _, ok := req.Tools[name]; ok {
+		return fmt.Errorf("duplicate tool: %q", name)
+	}
+	req.Tools[name] = t
+	if decl == nil {
+		return nil
+	}
+	if req.Config == nil {
+		req.Config = &genai.GenerateContentConfig{}
+	}
+	for _, gt := range req.Config.Tools {
+		if gt != nil && gt.FunctionDeclarations != nil {
+			gt.FunctionDeclarations = append(gt.FunctionDeclarations, decl)
+			return nil
+		}
+	}
+	req.Config.Tools = append(req.Config.Tools, &genai.Tool{FunctionDeclarations: []*genai.FunctionDeclaration{decl}})
+	return nil
+}
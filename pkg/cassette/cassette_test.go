@@ -0,0 +1,176 @@
+package cassette
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"path/filepath"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/agent"
+	"github.com/SAP/astonish/pkg/config"
+	adkagent "google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+	"google.golang.org/genai"
+	"gopkg.in/yaml.v3"
+)
+
+const countFlow = `
+nodes:
+  - name: fetch
+    type: tool
+    tools_selection: [next_ticket]
+    args:
+      queue: support
+    raw_tool_output:
+      ticket_id: id
+  - name: classify
+    type: llm
+    prompt: "Classify ticket {ticket_id}"
+    output_model:
+      label: str
+flow:
+  - from: START
+    to: fetch
+  - from: fetch
+    to: classify
+  - from: classify
+    to: END
+`
+
+// countingModel answers with a label that changes on every call, like a
+// model that is not deterministic.
+type countingModel struct{ calls int }
+
+func (m *countingModel) Name() string { return "counting" }
+
+func (m *countingModel) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		m.calls++
+		text := fmt.Sprintf(`{"label": "label-%d"}`, m.calls)
+		yield(&model.LLMResponse{Content: genai.NewContentFromText(text, genai.RoleModel), TurnComplete: true}, nil)
+	}
+}
+
+func runCountFlow(t *testing.T, llm model.LLM, tools []tool.Tool) map[string]any {
+	t.Helper()
+	var flow config.AgentConfig
+	if err := yaml.Unmarshal([]byte(countFlow), &flow); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	sessionService := session.InMemoryService()
+	a := agent.NewAstonishAgent(&flow, llm, tools)
+	a.AutoApprove = true
+	a.SessionService = sessionService
+	adkAgent, err := adkagent.New(adkagent.Config{Name: "cassette_test", Run: a.Run})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := sessionService.Create(ctx, &session.CreateRequest{AppName: "astonish", UserID: "test", State: map[string]any{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := runner.New(runner.Config{AppName: "astonish", Agent: adkAgent, SessionService: sessionService})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, err := range r.Run(ctx, "test", resp.Session.ID(), nil, adkagent.RunConfig{}) {
+		if err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+	}
+	got, err := sessionService.Get(ctx, &session.GetRequest{AppName: "astonish", UserID: "test", SessionID: resp.Session.ID()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := make(map[string]any)
+	for k, v := range got.Session.State().All() {
+		state[k] = v
+	}
+	return state
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	llm := &countingModel{}
+	tickets := 0
+	nextTicket, err := functiontool.New(functiontool.Config{Name: "next_ticket", Description: "Returns the next ticket"},
+		func(_ tool.Context, args map[string]any) (map[string]any, error) {
+			tickets++
+			return map[string]any{"id": tickets, "queue": args["queue"]}, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Record a first run
+	rec := NewRecorder("count")
+	recorded := runCountFlow(t, rec.Model(llm), rec.Tools([]tool.Tool{nextTicket}))
+	if recorded["label"] != "label-1" {
+		t.Fatalf("recorded label = %v, want label-1", recorded["label"])
+	}
+	if rec.Len() != 2 {
+		t.Fatalf("recorded %d interactions, want 2 (one tool call, one model call)", rec.Len())
+	}
+	path := filepath.Join(t.TempDir(), "run.cassette.json")
+	if err := rec.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// A live run gives other values
+	if live := runCountFlow(t, llm, []tool.Tool{nextTicket}); live["label"] != "label-2" {
+		t.Fatalf("live label = %v, want label-2", live["label"])
+	}
+
+	// The replay reproduces the recorded run without calling the model or the tool
+	tape, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	player := NewPlayer(tape)
+	replayed := runCountFlow(t, player.Model(), player.Tools())
+	if replayed["label"] != "label-1" {
+		t.Errorf("replayed label = %v, want label-1", replayed["label"])
+	}
+	if fmt.Sprint(replayed["ticket_id"]) != "1" {
+		t.Errorf("replayed ticket = %v, want the recorded ticket 1", replayed["ticket_id"])
+	}
+	if llm.calls != 2 || tickets != 2 {
+		t.Errorf("replay called the model or tool: %d model calls, %d tool calls", llm.calls, tickets)
+	}
+	if player.Remaining() != 0 {
+		t.Errorf("%d recorded interactions were not replayed", player.Remaining())
+	}
+
+	// A run that goes past the recording fails instead of calling out
+	if _, _, ok := player.next(KindLLM, func(int) bool { return true }, func(int) bool { return true }); ok {
+		t.Error("expected no recorded model call left")
+	}
+}
+
+func TestReplayAnswers(t *testing.T) {
+	rec := NewRecorder("answers")
+	rec.Answer(KindInput, "ask", "first")
+	rec.Answer(KindApproval, "fetch", "Yes")
+	rec.Answer(KindInput, "ask", "second")
+
+	player := NewPlayer(&rec.c)
+	for _, want := range []string{"first", "second"} {
+		if got, ok := player.Answer(KindInput, "ask"); !ok || got != want {
+			t.Errorf("input answer = %q, %v; want %q", got, ok, want)
+		}
+	}
+	if _, ok := player.Answer(KindInput, "ask"); ok {
+		t.Error("expected no input answer left")
+	}
+	if _, ok := player.Answer(KindApproval, "other"); ok {
+		t.Error("an approval recorded at another node was replayed")
+	}
+	if got, ok := player.Answer(KindApproval, "fetch"); !ok || got != "Yes" {
+		t.Errorf("approval answer = %q, %v; want Yes", got, ok)
+	}
+}
@@ -0,0 +1,159 @@
+package cassette
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"sync"
+	"time"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+)
+
+// Recorder captures the model and tool calls of a run. Wrap the run's
+// models and tools with it, then Save the cassette when the run ends.
+type Recorder struct {
+	mu sync.Mutex
+	c  Cassette
+}
+
+// NewRecorder starts a cassette for flow.
+func NewRecorder(flow string) *Recorder {
+	return &Recorder{c: Cassette{
+		Version:    Version,
+		Flow:       flow,
+		RecordedAt: time.Now().UTC(),
+		Tools:      make(map[string]*genai.FunctionDeclaration),
+	}}
+}
+
+// Model returns llm with its calls recorded.
+func (r *Recorder) Model(llm model.LLM) model.LLM {
+	return &recordingModel{LLM: llm, r: r}
+}
+
+// Tools returns tools with their calls recorded.
+func (r *Recorder) Tools(tools []tool.Tool) []tool.Tool {
+	wrapped := make([]tool.Tool, len(tools))
+	for i, t := range tools {
+		wrapped[i] = &recordingTool{Tool: t, r: r}
+	}
+	return wrapped
+}
+
+// Toolsets returns toolsets whose tools have their calls recorded.
+func (r *Recorder) Toolsets(toolsets []tool.Toolset) []tool.Toolset {
+	wrapped := make([]tool.Toolset, len(toolsets))
+	for i, ts := range toolsets {
+		wrapped[i] = &recordingToolset{Toolset: ts, r: r}
+	}
+	return wrapped
+}
+
+// Answer records the user's answer to an input node or tool approval
+// (KindInput or KindApproval) at node. A nil Recorder records nothing.
+func (r *Recorder) Answer(kind, node, value string) {
+	if r == nil {
+		return
+	}
+	r.add(Interaction{Kind: kind, Node: node, Value: value})
+}
+
+// Len returns the number of interactions recorded so far.
+func (r *Recorder) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.c.Interactions)
+}
+
+// Save writes the cassette recorded so far to path.
+func (r *Recorder) Save(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.c.Save(path)
+}
+
+func (r *Recorder) add(in Interaction, decls ...*genai.FunctionDeclaration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, d := range decls {
+		if d != nil && d.Name != "" {
+			r.c.Tools[d.Name] = d
+		}
+	}
+	r.c.Interactions = append(r.c.Interactions, in)
+}
+
+type recordingModel struct {
+	model.LLM
+	r *Recorder
+}
+
+func (m *recordingModel) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		in := Interaction{Kind: KindLLM, Model: m.LLM.Name(), Request: newRequest(req)}
+		defer func() { m.r.add(in, toolDeclarations(req)...) }()
+		for resp, err := range m.LLM.GenerateContent(ctx, req, stream) {
+			if err != nil {
+				in.Error = err.Error()
+			} else if resp != nil {
+				in.Responses = append(in.Responses, newResponse(resp))
+			}
+			if !yield(resp, err) {
+				return
+			}
+		}
+	}
+}
+
+type recordingTool struct {
+	tool.Tool
+	r *Recorder
+}
+
+func (t *recordingTool) Declaration() *genai.FunctionDeclaration {
+	if dt, ok := t.Tool.(interface {
+		Declaration() *genai.FunctionDeclaration
+	}); ok {
+		return dt.Declaration()
+	}
+	return nil
+}
+
+// ProcessRequest registers the wrapper, not the underlying tool, so the
+// model's calls reach Run.
+func (t *recordingTool) ProcessRequest(_ tool.Context, req *model.LLMRequest) error {
+	return packTool(t, t.Declaration(), req)
+}
+
+func (t *recordingTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	runner, ok := t.Tool.(interface {
+		Run(tool.Context, any) (map[string]any, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("tool '%s' does not implement Run", t.Name())
+	}
+	result, err := runner.Run(ctx, args)
+	in := Interaction{Kind: KindTool, Tool: t.Name(), Args: args, Result: result}
+	if err != nil {
+		in.Error = err.Error()
+	}
+	t.r.add(in, t.Declaration())
+	return result, err
+}
+
+type recordingToolset struct {
+	tool.Toolset
+	r *Recorder
+}
+
+func (s *recordingToolset) Tools(ctx agent.ReadonlyContext) ([]tool.Tool, error) {
+	tools, err := s.Toolset.Tools(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.r.Tools(tools), nil
+}
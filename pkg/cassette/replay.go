@@ -0,0 +1,159 @@
+package cassette
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+)
+
+// Player answers model and tool calls from a cassette. Each recorded
+// interaction is used once: a model call gets the first unused recording
+// with the same request, a tool call the first unused call of the tool
+// with the same arguments. When none matches (e.g. the prompt contains the
+// time) the next unused recording in order is used, and a warning logged.
+type Player struct {
+	c *Cassette
+
+	mu           sync.Mutex
+	used         []bool
+	fingerprints []string
+}
+
+// NewPlayer returns a player for c.
+func NewPlayer(c *Cassette) *Player {
+	p := &Player{c: c, used: make([]bool, len(c.Interactions)), fingerprints: make([]string, len(c.Interactions))}
+	for i, in := range c.Interactions {
+		if in.Kind == KindLLM {
+			p.fingerprints[i] = fingerprint(in.Request)
+		}
+	}
+	return p
+}
+
+// Flow returns the name of the flow the cassette was recorded for.
+func (p *Player) Flow() string {
+	return p.c.Flow
+}
+
+// Remaining returns the number of recorded interactions not replayed yet.
+func (p *Player) Remaining() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := 0
+	for _, used := range p.used {
+		if !used {
+			n++
+		}
+	}
+	return n
+}
+
+// Model returns a model that answers from the cassette.
+func (p *Player) Model() model.LLM {
+	return &replayModel{p: p}
+}
+
+// Tools returns a stub for each tool of the cassette.
+func (p *Player) Tools() []tool.Tool {
+	var stubs []tool.Tool
+	for _, name := range slices.Sorted(maps.Keys(p.c.Tools)) {
+		stubs = append(stubs, &replayTool{p: p, decl: p.c.Tools[name]})
+	}
+	return stubs
+}
+
+// Answer returns the recorded answer of kind (KindInput or KindApproval)
+// at node.
+func (p *Player) Answer(kind, node string) (string, bool) {
+	in, _, ok := p.next(kind,
+		func(i int) bool { return p.c.Interactions[i].Node == node },
+		func(int) bool { return false })
+	return in.Value, ok
+}
+
+// next marks and returns the first unused interaction of kind for which
+// match holds (exact is true), else the first for which fallback holds.
+func (p *Player) next(kind string, match, fallback func(i int) bool) (in Interaction, exact, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for pass, cond := range []func(int) bool{match, fallback} {
+		for i, in := range p.c.Interactions {
+			if p.used[i] || in.Kind != kind || !cond(i) {
+				continue
+			}
+			p.used[i] = true
+			return in, pass == 0, true
+		}
+	}
+	return Interaction{}, false, false
+}
+
+type replayModel struct {
+	p *Player
+}
+
+func (m *replayModel) Name() string { return "cassette" }
+
+func (m *replayModel) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		fp := fingerprint(newRequest(req))
+		in, matched, ok := m.p.next(KindLLM,
+			func(i int) bool { return m.p.fingerprints[i] == fp },
+			func(int) bool { return true })
+		if !ok {
+			yield(nil, fmt.Errorf("cassette has no recorded model call left"))
+			return
+		}
+		if !matched {
+			slog.Warn("replaying a model call whose request differs from the recording", "model", in.Model)
+		}
+		for _, resp := range in.Responses {
+			if !yield(resp.llmResponse(), nil) {
+				return
+			}
+		}
+		if in.Error != "" {
+			yield(nil, fmt.Errorf("%s", in.Error))
+		}
+	}
+}
+
+type replayTool struct {
+	p    *Player
+	decl *genai.FunctionDeclaration
+}
+
+func (t *replayTool) Name() string                            { return t.decl.Name }
+func (t *replayTool) Description() string                     { return t.decl.Description }
+func (t *replayTool) IsLongRunning() bool                     { return false }
+func (t *replayTool) Declaration() *genai.FunctionDeclaration { return t.decl }
+
+func (t *replayTool) ProcessRequest(_ tool.Context, req *model.LLMRequest) error {
+	return packTool(t, t.decl, req)
+}
+
+func (t *replayTool) Run(_ tool.Context, args any) (map[string]any, error) {
+	name, want := t.decl.Name, canonical(args)
+	interactions := t.p.c.Interactions
+	in, matched, ok := t.p.next(KindTool,
+		func(i int) bool { return interactions[i].Tool == name && canonical(interactions[i].Args) == want },
+		func(i int) bool { return interactions[i].Tool == name })
+	if !ok {
+		return nil, fmt.Errorf("cassette has no recorded call of tool '%s' left", name)
+	}
+	if !matched {
+		slog.Warn("replaying a tool call whose arguments differ from the recording", "tool", name)
+	}
+	if in.Error != "" {
+		return in.Result, fmt.Errorf("%s", in.Error)
+	}
+	return maps.Clone(in.Result), nil
+}
@@ -176,7 +176,7 @@ func LoginWithSSO(serverURL string, providerID string, onStatus func(status stri
 	if onStatus != nil {
 		onStatus("opening_browser")
 	}
-	if err := openBrowser(initResp.VerifyURL); err != nil {
+	if err := OpenBrowser(initResp.VerifyURL); err != nil {
 		// Don't fail — user can open manually
 		if onStatus != nil {
 			onStatus("browser_failed")
@@ -396,8 +396,8 @@ func getMeWithToken(c *Client, accessToken string) *meResponse {
 	}
 }
 
-// openBrowser opens a URL in the default browser.
-func openBrowser(url string) error {
+// OpenBrowser opens a URL (or local file) in the default browser.
+func OpenBrowser(url string) error {
 	var cmd *exec.Cmd
 	switch runtime.GOOS {
 	case "linux":
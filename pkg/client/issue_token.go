@@ -116,7 +116,7 @@ func IssueTokenSSO(serverURL, providerID string, onStatus func(status string)) (
 	if onStatus != nil {
 		onStatus("opening_browser")
 	}
-	if err := openBrowser(initResp.VerifyURL); err != nil {
+	if err := OpenBrowser(initResp.VerifyURL); err != nil {
 		if onStatus != nil {
 			onStatus("browser_failed")
 		}
@@ -120,7 +120,7 @@ func Open(path string) (*library, error) {
 		}
 	}
 
-	handle, err := purego.Dlopen(path, purego.RTLD_NOW|purego.RTLD_GLOBAL)
+	handle, err := openLibrary(path)
 	if err != nil {
 		return nil, fmt.Errorf("load tree-sitter library %q: %w", path, err)
 	}
@@ -0,0 +1,12 @@
+//go:build windows
+
+package treesitter
+
+import "syscall"
+
+// openLibrary loads the tree-sitter DLL; purego resolves its symbols with
+// GetProcAddress on the returned handle.
+func openLibrary(path string) (uintptr, error) {
+	handle, err := syscall.LoadLibrary(path)
+	return uintptr(handle), err
+}
@@ -14,23 +14,28 @@ import (
 )
 
 type AppConfig struct {
-	General       GeneralConfig              `yaml:"general"`
-	WebServers    map[string]WebServerConfig `yaml:"web_servers,omitempty" json:"web_servers,omitempty"`
-	Providers     map[string]ProviderConfig  `yaml:"providers"`
-	Chat          ChatConfig                 `yaml:"chat,omitempty"`
-	Sessions      SessionConfig              `yaml:"sessions,omitempty"`
-	Memory        MemoryConfig               `yaml:"memory,omitempty"`
-	Storage       StorageConfig              `yaml:"storage,omitempty"`
-	Daemon        DaemonConfig               `yaml:"daemon,omitempty"`
-	Channels      ChannelsConfig             `yaml:"channels,omitempty"`
-	Scheduler     SchedulerConfig            `yaml:"scheduler,omitempty"`
-	Browser       BrowserAppConfig           `yaml:"browser,omitempty"`
-	SubAgents     SubAgentAppConfig          `yaml:"sub_agents,omitempty"`
-	Skills        SkillsConfig               `yaml:"skills,omitempty"`
-	AgentIdentity AgentIdentityConfig        `yaml:"agent_identity,omitempty"`
-	CodeIntel     CodeIntelConfig            `yaml:"codeintel,omitempty" json:"codeintel,omitempty"`
-	Sandbox       SandboxConfig              `yaml:"sandbox,omitempty"`
-	Security      SecurityConfig             `yaml:"security,omitempty"`
+	General          GeneralConfig              `yaml:"general"`
+	WebServers       map[string]WebServerConfig `yaml:"web_servers,omitempty" json:"web_servers,omitempty"`
+	Providers        map[string]ProviderConfig  `yaml:"providers"`
+	Chat             ChatConfig                 `yaml:"chat,omitempty"`
+	Sessions         SessionConfig              `yaml:"sessions,omitempty"`
+	Memory           MemoryConfig               `yaml:"memory,omitempty"`
+	Storage          StorageConfig              `yaml:"storage,omitempty"`
+	Daemon           DaemonConfig               `yaml:"daemon,omitempty"`
+	Channels         ChannelsConfig             `yaml:"channels,omitempty"`
+	Scheduler        SchedulerConfig            `yaml:"scheduler,omitempty"`
+	Browser          BrowserAppConfig           `yaml:"browser,omitempty"`
+	SubAgents        SubAgentAppConfig          `yaml:"sub_agents,omitempty"`
+	Skills           SkillsConfig               `yaml:"skills,omitempty"`
+	AgentIdentity    AgentIdentityConfig        `yaml:"agent_identity,omitempty"`
+	CodeIntel        CodeIntelConfig            `yaml:"codeintel,omitempty" json:"codeintel,omitempty"`
+	Sandbox          SandboxConfig              `yaml:"sandbox,omitempty"`
+	Security         SecurityConfig             `yaml:"security,omitempty"`
+	HTTP             HTTPClientConfig           `yaml:"http,omitempty" json:"http,omitempty"`
+	CostGuard        CostGuardConfig            `yaml:"cost_guard,omitempty" json:"cost_guard,omitempty"`
+	UI               UIConfig                   `yaml:"ui,omitempty" json:"ui,omitempty"`
+	Hooks            []HookConfig               `yaml:"hooks,omitempty" json:"hooks,omitempty"`                         // Lifecycle hooks of every flow run, in addition to the flow's own
+	ApprovalProfiles map[string]ApprovalProfile `yaml:"approval_profiles,omitempty" json:"approval_profiles,omitempty"` // Named approval profiles for --approval-profile
 }
 
 type CodeIntelConfig struct {
@@ -394,9 +399,120 @@ func (c *SandboxOpenShellConfig) OpenShellIdleTimeout() time.Duration {
 	return time.Duration(*c.IdleTimeoutMinutes) * time.Minute
 }
 
+// CostGuardConfig controls the confirmation asked before flow runs (and
+// parallel fan-outs) whose estimated LLM cost exceeds a threshold.
+type CostGuardConfig struct {
+	Enabled   *bool                 `yaml:"enabled,omitempty" json:"enabled,omitempty"`     // Default: true (nil means true)
+	Threshold float64               `yaml:"threshold,omitempty" json:"threshold,omitempty"` // USD. Default: 5
+	Pricing   map[string]ModelPrice `yaml:"pricing,omitempty" json:"pricing,omitempty"`     // Per-model prices, overriding the built-in table
+}
+
+// ModelPrice is a model's price in USD per million tokens.
+type ModelPrice struct {
+	Input  float64 `yaml:"input" json:"input"`
+	Output float64 `yaml:"output" json:"output"`
+}
+
+// IsEnabled returns true if the cost guard should run.
+func (c *CostGuardConfig) IsEnabled() bool {
+	return c.Enabled == nil || *c.Enabled
+}
+
+// GetThreshold returns the estimated cost (USD) above which a run needs
+// confirmation.
+func (c *CostGuardConfig) GetThreshold() float64 {
+	if c.Threshold > 0 {
+		return c.Threshold
+	}
+	return 5
+}
+
+// HTTPClientConfig controls the network path of outbound HTTP clients:
+// LLM providers, MCP servers and built-in tools. The top-level http section
+// is the default; providers override it with proxy, ca_bundle and
+// insecure_skip_verify keys, MCP servers with the same fields.
+type HTTPClientConfig struct {
+	Proxy              string `yaml:"proxy,omitempty" json:"proxy,omitempty"`                               // Proxy URL; empty uses HTTP(S)_PROXY
+	CABundle           string `yaml:"ca_bundle,omitempty" json:"ca_bundle,omitempty"`                       // PEM file trusted in addition to system roots
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify,omitempty" json:"insecure_skip_verify,omitempty"` // Disable TLS verification (testing only)
+}
+
+// UIConfig controls how the console renders tool boxes, badges and error
+// boxes.
+type UIConfig struct {
+	Theme string `yaml:"theme,omitempty" json:"theme,omitempty"` // default, light, high-contrast or mono; ASTONISH_THEME overrides it
+	ASCII bool   `yaml:"ascii,omitempty" json:"ascii,omitempty"` // ASCII-only boxes and icons
+	Width int    `yaml:"width,omitempty" json:"width,omitempty"` // Wrap width of boxes (0 = the terminal width)
+}
+
 // SecurityConfig controls security features like proactive secret detection.
 type SecurityConfig struct {
 	SecretScanner SecretScannerConfig `yaml:"secret_scanner,omitempty" json:"secret_scanner,omitempty"`
+	FileAccess    FileAccessConfig    `yaml:"file_access,omitempty" json:"file_access,omitempty"`
+	HTTPRequest   HTTPRequestConfig   `yaml:"http_request,omitempty" json:"http_request,omitempty"`
+	Shell         ShellSandboxConfig  `yaml:"shell,omitempty" json:"shell,omitempty"`
+	AuditLog      AuditLogConfig      `yaml:"audit_log,omitempty" json:"audit_log,omitempty"`
+}
+
+// AuditLogConfig controls the append-only log of the tool executions and
+// approval decisions of flow runs.
+type AuditLogConfig struct {
+	Enabled bool   `yaml:"enabled,omitempty" json:"enabled,omitempty"` // Default: false
+	Path    string `yaml:"path,omitempty" json:"path,omitempty"`       // JSONL file (default: audit.jsonl in the config directory); ~ is expanded
+}
+
+// GetPath returns the file of the audit log.
+func (c *AuditLogConfig) GetPath() (string, error) {
+	if c.Path == "" {
+		dir, err := GetConfigDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, "audit.jsonl"), nil
+	}
+	if rest, ok := strings.CutPrefix(c.Path, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, rest), nil
+	}
+	return c.Path, nil
+}
+
+// ShellSandboxConfig confines the commands of the shell_command tool. The
+// zero value leaves them unrestricted, with the user's full privileges.
+type ShellSandboxConfig struct {
+	WorkingDir     string                `yaml:"working_dir,omitempty" json:"working_dir,omitempty"`           // Commands run in this directory or below it; ~ is expanded
+	ScrubEnv       bool                  `yaml:"scrub_env,omitempty" json:"scrub_env,omitempty"`               // Pass only basic variables (PATH, HOME, locale, ...) and env_allow to commands
+	EnvAllow       []string              `yaml:"env_allow,omitempty" json:"env_allow,omitempty"`               // Further variables passed with scrub_env, e.g. GOPATH
+	AllowCommands  []string              `yaml:"allow_commands,omitempty" json:"allow_commands,omitempty"`     // Regexes; when set, a command must match one of them
+	DenyCommands   []string              `yaml:"deny_commands,omitempty" json:"deny_commands,omitempty"`       // Regexes; a command matching one of them is refused
+	Container      *ShellContainerConfig `yaml:"container,omitempty" json:"container,omitempty"`               // Run commands in a throwaway container instead of on the host
+	MaxRuntime     string                `yaml:"max_runtime,omitempty" json:"max_runtime,omitempty"`           // Go duration capping each command's timeout, e.g. 5m
+	MaxOutputBytes int                   `yaml:"max_output_bytes,omitempty" json:"max_output_bytes,omitempty"` // Output beyond this many bytes is cut (default: no limit)
+	Interpreter    string                `yaml:"interpreter,omitempty" json:"interpreter,omitempty"`           // sh, bash, cmd, powershell or pwsh (default: sh; powershell, else cmd, on Windows)
+}
+
+// ShellContainerConfig runs shell_command commands with docker or podman.
+// The working directory is mounted at the same path in the container.
+type ShellContainerConfig struct {
+	Runtime string `yaml:"runtime,omitempty" json:"runtime,omitempty"` // "docker" (default) or "podman"
+	Image   string `yaml:"image,omitempty" json:"image,omitempty"`     // Image the commands run in, e.g. alpine:3.20
+	Network string `yaml:"network,omitempty" json:"network,omitempty"` // --network of the container, e.g. none (default: the runtime's)
+}
+
+// HTTPRequestConfig restricts the hosts the http_request tool may call. An
+// empty list allows every public host.
+type HTTPRequestConfig struct {
+	AllowedDomains []string `yaml:"allowed_domains,omitempty" json:"allowed_domains,omitempty"` // e.g. api.github.com, *.example.com
+}
+
+// FileAccessConfig restricts the built-in file tools (read_file, write_file,
+// edit_file, list_directory, glob, find_files, file_tree, grep_search) to a
+// set of directories. An empty list leaves them unrestricted.
+type FileAccessConfig struct {
+	AllowedPaths []string `yaml:"allowed_paths,omitempty" json:"allowed_paths,omitempty"` // Directories the file tools may access; ~ is expanded
 }
 
 // SecretScannerConfig controls the proactive secret detection engine that
@@ -459,10 +575,10 @@ func (c *SQLiteConfig) GetDataDir() string {
 		return envDir
 	}
 	if xdg := os.Getenv("XDG_DATA_HOME"); xdg != "" {
-		return xdg + "/astonish"
+		return filepath.Join(xdg, "astonish")
 	}
 	home, _ := os.UserHomeDir()
-	return home + "/.local/share/astonish"
+	return filepath.Join(home, ".local", "share", "astonish")
 }
 
 // PostgresConfig holds PostgreSQL connection parameters for platform mode.
@@ -812,13 +928,48 @@ type SandboxPruneConfig struct {
 
 // MemoryConfig controls the semantic memory / RAG system.
 type MemoryConfig struct {
-	Enabled   *bool           `yaml:"enabled,omitempty" json:"enabled,omitempty"` // Default: true (nil means true)
-	MemoryDir string          `yaml:"memory_dir,omitempty" json:"memory_dir,omitempty"`
-	VectorDir string          `yaml:"vector_dir,omitempty" json:"vector_dir,omitempty"`
-	Embedding EmbeddingConfig `yaml:"embedding,omitempty" json:"embedding,omitempty"`
-	Chunking  ChunkingConfig  `yaml:"chunking,omitempty" json:"chunking,omitempty"`
-	Search    SearchConfig    `yaml:"search,omitempty" json:"search,omitempty"`
-	Sync      SyncConfig      `yaml:"sync,omitempty" json:"sync,omitempty"`
+	Enabled   *bool            `yaml:"enabled,omitempty" json:"enabled,omitempty"` // Default: true (nil means true)
+	MemoryDir string           `yaml:"memory_dir,omitempty" json:"memory_dir,omitempty"`
+	VectorDir string           `yaml:"vector_dir,omitempty" json:"vector_dir,omitempty"`
+	Embedding EmbeddingConfig  `yaml:"embedding,omitempty" json:"embedding,omitempty"`
+	Chunking  ChunkingConfig   `yaml:"chunking,omitempty" json:"chunking,omitempty"`
+	Search    SearchConfig     `yaml:"search,omitempty" json:"search,omitempty"`
+	Sync      SyncConfig       `yaml:"sync,omitempty" json:"sync,omitempty"`
+	Flows     FlowMemoryConfig `yaml:"flows,omitempty" json:"flows,omitempty"`
+}
+
+// FlowMemoryConfig controls the long-term memory of flow memory nodes.
+type FlowMemoryConfig struct {
+	Backend string `yaml:"backend,omitempty" json:"backend,omitempty"` // "file" (default, keyword recall) or "vector" (embedding recall)
+	Dir     string `yaml:"dir,omitempty" json:"dir,omitempty"`         // Default: ~/.config/astonish/flow_memory/
+	// VectorDB is the SQLite database of vector nodes' document collections.
+	VectorDB string `yaml:"vector_db,omitempty" json:"vector_db,omitempty"` // Default: <dir>/vectors.db
+}
+
+// GetDir returns the flow memory directory, defaulting to
+// ~/.config/astonish/flow_memory/.
+func (c *FlowMemoryConfig) GetDir() (string, error) {
+	if c.Dir != "" {
+		return c.Dir, nil
+	}
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "flow_memory"), nil
+}
+
+// GetVectorDB returns the path of the vector node database, defaulting to
+// vectors.db in the flow memory directory.
+func (c *FlowMemoryConfig) GetVectorDB() (string, error) {
+	if c.VectorDB != "" {
+		return c.VectorDB, nil
+	}
+	dir, err := c.GetDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "vectors.db"), nil
 }
 
 // EmbeddingConfig controls the embedding provider for memory search.
@@ -909,6 +1060,53 @@ type SessionConfig struct {
 	Compaction CompactionConfig `yaml:"compaction,omitempty" json:"compaction,omitempty"`
 	// Cleanup controls automatic session expiry.
 	Cleanup SessionCleanupConfig `yaml:"cleanup,omitempty" json:"cleanup,omitempty"`
+	// Idle controls parking and expiry of flow sessions paused in server mode.
+	Idle SessionIdleConfig `yaml:"idle,omitempty" json:"idle,omitempty"`
+}
+
+// SessionIdleConfig controls what happens to server-mode flow sessions left
+// waiting on input or approval. All durations use Go syntax (e.g. "30m").
+type SessionIdleConfig struct {
+	// ParkAfter releases a session's MCP connections and sandbox after this
+	// much idle time, and moves its state to <sessions dir>/parked. The flow
+	// can still resume, also after a restart. Default: 10m.
+	ParkAfter string `yaml:"park_after,omitempty" json:"park_after,omitempty"`
+	// ExpireAfter deletes a session after this much idle time. "0" disables
+	// expiry. Default: 24h.
+	ExpireAfter string `yaml:"expire_after,omitempty" json:"expire_after,omitempty"`
+	// NotifyWebhook, when set, receives a JSON POST before a session expires.
+	NotifyWebhook string `yaml:"notify_webhook,omitempty" json:"notify_webhook,omitempty"`
+	// NotifyBefore is how long before expiry the webhook fires. Default: 15m.
+	NotifyBefore string `yaml:"notify_before,omitempty" json:"notify_before,omitempty"`
+}
+
+// GetParkAfter returns the idle time before a session is parked (default 10m).
+func (c *SessionIdleConfig) GetParkAfter() time.Duration {
+	return parseIdleDuration(c.ParkAfter, 10*time.Minute)
+}
+
+// GetExpireAfter returns the idle time before a session expires (default 24h).
+// Returns 0 if expiry is disabled.
+func (c *SessionIdleConfig) GetExpireAfter() time.Duration {
+	return parseIdleDuration(c.ExpireAfter, 24*time.Hour)
+}
+
+// GetNotifyBefore returns how long before expiry to notify (default 15m).
+func (c *SessionIdleConfig) GetNotifyBefore() time.Duration {
+	return parseIdleDuration(c.NotifyBefore, 15*time.Minute)
+}
+
+// parseIdleDuration parses a duration setting, falling back to def when the
+// value is empty or invalid. An explicit "0" yields 0.
+func parseIdleDuration(value string, def time.Duration) time.Duration {
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return def
+	}
+	return d
 }
 
 // SessionCleanupConfig controls automatic deletion of old sessions.
@@ -983,6 +1181,11 @@ type GeneralConfig struct {
 	WebExtractTool  string `yaml:"web_extract_tool" json:"web_extract_tool"`
 	ContextLength   int    `yaml:"context_length,omitempty" json:"context_length,omitempty"` // Override context window size (tokens)
 	Timezone        string `yaml:"timezone,omitempty" json:"timezone,omitempty"`             // IANA timezone (e.g. "America/New_York")
+
+	// Provider instance and model for embeddings, e.g. semantic tool search
+	// (default: default_provider with its default embedding model)
+	EmbeddingProvider string `yaml:"embedding_provider,omitempty" json:"embedding_provider,omitempty"`
+	EmbeddingModel    string `yaml:"embedding_model,omitempty" json:"embedding_model,omitempty"`
 }
 
 // DaemonConfig controls the background daemon service.
@@ -995,6 +1198,68 @@ type DaemonConfig struct {
 	// Auth controls device-based authentication for the Studio web UI.
 	// Auth is enabled by default in daemon mode.
 	Auth StudioAuthConfig `yaml:"auth,omitempty" json:"auth,omitempty"`
+	// RunQueue limits flow runs started over HTTP (webhook triggers).
+	RunQueue RunQueueConfig `yaml:"run_queue,omitempty" json:"run_queue,omitempty"`
+	// TriggerAutoApprove approves the tool calls of flow runs started over
+	// HTTP. Default: false, calls are denied unless the flow's
+	// tools_auto_approval approves them.
+	TriggerAutoApprove bool `yaml:"trigger_auto_approve,omitempty" json:"trigger_auto_approve,omitempty"`
+}
+
+// RunQueueConfig limits the flow runs the daemon executes for webhook
+// triggers, so bursts of requests cannot exhaust provider quotas or MCP
+// processes. Runs over a limit wait in the queue.
+type RunQueueConfig struct {
+	// MaxConcurrent is the number of runs executed at once. Default: 4.
+	MaxConcurrent int `yaml:"max_concurrent,omitempty" json:"max_concurrent,omitempty"`
+	// PerFlow is the number of runs of one flow executed at once.
+	// Default: 0 (only max_concurrent applies).
+	PerFlow int `yaml:"per_flow,omitempty" json:"per_flow,omitempty"`
+	// FlowLimits overrides PerFlow for individual flows, by flow name; in
+	// platform mode by "org/team/flow".
+	FlowLimits map[string]int `yaml:"flow_limits,omitempty" json:"flow_limits,omitempty"`
+	// QueueDepth is the number of runs that may wait; further triggers are
+	// rejected. Default: 100.
+	QueueDepth int `yaml:"queue_depth,omitempty" json:"queue_depth,omitempty"`
+	// TimeoutMinutes cancels a run that executes longer. Default: 30.
+	TimeoutMinutes int `yaml:"timeout_minutes,omitempty" json:"timeout_minutes,omitempty"`
+}
+
+// GetMaxConcurrent returns the number of concurrent runs, defaulting to 4.
+func (c *RunQueueConfig) GetMaxConcurrent() int {
+	if c.MaxConcurrent <= 0 {
+		return 4
+	}
+	return c.MaxConcurrent
+}
+
+// GetFlowLimit returns the number of concurrent runs of flow (a flow name,
+// or "org/team/flow" in platform mode), or 0 when only max_concurrent
+// applies.
+func (c *RunQueueConfig) GetFlowLimit(flow string) int {
+	if n, ok := c.FlowLimits[flow]; ok && n > 0 {
+		return n
+	}
+	if c.PerFlow > 0 {
+		return c.PerFlow
+	}
+	return 0
+}
+
+// GetQueueDepth returns the number of runs that may wait, defaulting to 100.
+func (c *RunQueueConfig) GetQueueDepth() int {
+	if c.QueueDepth <= 0 {
+		return 100
+	}
+	return c.QueueDepth
+}
+
+// GetTimeout returns the maximum duration of a run, defaulting to 30 minutes.
+func (c *RunQueueConfig) GetTimeout() time.Duration {
+	if c.TimeoutMinutes <= 0 {
+		return 30 * time.Minute
+	}
+	return time.Duration(c.TimeoutMinutes) * time.Minute
 }
 
 // GetPort returns the daemon port, defaulting to 9393.
@@ -1058,6 +1323,9 @@ type SchedulerConfig struct {
 	// Enabled controls whether the scheduler is active. Default: true (nil means true).
 	// Set to false to explicitly disable the scheduler.
 	Enabled *bool `yaml:"enabled,omitempty" json:"enabled,omitempty"`
+	// FailureWebhook receives a JSON POST whenever a scheduled job fails,
+	// in addition to the job's normal delivery.
+	FailureWebhook string `yaml:"failure_webhook,omitempty" json:"failure_webhook,omitempty"`
 }
 
 // IsSchedulerEnabled returns true if the scheduler is enabled.
@@ -1375,6 +1643,16 @@ func (c *SlackConfig) GetMode() string {
 
 type ProviderConfig map[string]string
 
+// HTTPClientConfig returns the provider's proxy, ca_bundle and
+// insecure_skip_verify keys.
+func (p ProviderConfig) HTTPClientConfig() HTTPClientConfig {
+	return HTTPClientConfig{
+		Proxy:              p["proxy"],
+		CABundle:           p["ca_bundle"],
+		InsecureSkipVerify: p["insecure_skip_verify"] == "true",
+	}
+}
+
 // GetProviderType returns the provider type for a given provider instance.
 // For new format: checks explicit "type" field
 // For old format (backward compatible): uses instance name if it matches known provider type
@@ -1389,7 +1667,7 @@ func GetProviderType(instanceName string, instance ProviderConfig) string {
 	}
 
 	knownTypes := []string{
-		"anthropic", "gemini", "groq", "litellm", "lm_studio",
+		"anthropic", "azure", "bedrock", "gemini", "groq", "litellm", "lm_studio",
 		"ollama", "openai", "openrouter", "poe", "sap_ai_core", "xai",
 	}
 
@@ -1483,6 +1761,16 @@ func GetReportsDir() (string, error) {
 	return filepath.Join(configDir, "reports"), nil
 }
 
+// GetRunsDir returns the directory for the Studio flow run history.
+// Defaults to ~/.config/astonish/runs/.
+func GetRunsDir() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "runs"), nil
+}
+
 // GetSessionsDir returns the session storage directory.
 // If the config specifies a custom base_dir, that is used; otherwise
 // it defaults to ~/.config/astonish/sessions/.
@@ -0,0 +1,26 @@
+package config
+
+// ApprovalProfile is a named set of rules that decide tool calls before the
+// user is asked, selected per run with --approval-profile. The first rule
+// matching a call decides it.
+type ApprovalProfile struct {
+	Rules   []ApprovalRule `yaml:"rules,omitempty" json:"rules,omitempty"`
+	Default string         `yaml:"default,omitempty" json:"default,omitempty"` // Action for calls no rule matches (default: prompt)
+}
+
+// ApprovalRule matches tool calls by tool name and arguments.
+type ApprovalRule struct {
+	Tools  []string          `yaml:"tools" json:"tools"`                   // Tool name patterns, e.g. read_file or git_* (* matches any tool)
+	Args   map[string]string `yaml:"args,omitempty" json:"args,omitempty"` // Regular expressions the named arguments must match
+	Action string            `yaml:"action" json:"action"`                 // approve, prompt or deny
+}
+
+// Actions of approval profile rules.
+const (
+	ApprovalApprove = "approve" // The call runs without asking
+	ApprovalPrompt  = "prompt"  // The user is asked, unless auto approval is on
+	ApprovalDeny    = "deny"    // The call is refused, even with auto approval
+)
+
+// ApprovalActions are the valid actions of approval profile rules.
+var ApprovalActions = []string{ApprovalApprove, ApprovalPrompt, ApprovalDeny}
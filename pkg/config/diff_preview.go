@@ -0,0 +1,16 @@
+package config
+
+// Styles of diff previews (diff.style).
+const (
+	DiffUnified    = "unified"      // One column with - and + lines (default)
+	DiffSideBySide = "side-by-side" // Old and new next to each other
+)
+
+// DiffPreview makes an output node show the changes between two texts kept
+// in state, e.g. a file and the edit an LLM node proposes for it.
+type DiffPreview struct {
+	Old   string `yaml:"old" json:"old"`                         // State key or {template} of the original text (empty for a new file)
+	New   string `yaml:"new" json:"new"`                         // State key or {template} of the changed text
+	Path  string `yaml:"path,omitempty" json:"path,omitempty"`   // File name shown in the header (templated); its extension selects the syntax highlighting
+	Style string `yaml:"style,omitempty" json:"style,omitempty"` // "unified" (default) or "side-by-side"
+}
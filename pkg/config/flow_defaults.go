@@ -0,0 +1,110 @@
+package config
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// NodeDefaults are the settings declared once in the top-level defaults:
+// section that every node inherits unless it sets them itself.
+type NodeDefaults struct {
+	System            string            `yaml:"system,omitempty" json:"system,omitempty"`                           // Prepended to the system prompt of every LLM node
+	ToolsAutoApproval *bool             `yaml:"tools_auto_approval,omitempty" json:"tools_auto_approval,omitempty"` // Default for nodes that do not set tools_auto_approval
+	MaxRetries        int               `yaml:"max_retries,omitempty" json:"max_retries,omitempty"`                 // Default for nodes that do not set max_retries
+	MaxToolCalls      int               `yaml:"max_tool_calls,omitempty" json:"max_tool_calls,omitempty"`           // Default for LLM nodes that do not set max_tool_calls
+	Generation        *GenerationConfig `yaml:"generation,omitempty" json:"generation,omitempty"`                   // Sampling parameters; a node's generation overrides them field by field
+	Provider          string            `yaml:"provider,omitempty" json:"provider,omitempty"`                       // Provider instance or type:profile of LLM nodes that do not set one
+	Model             string            `yaml:"model,omitempty" json:"model,omitempty"`                             // Model of LLM nodes that do not set a provider or model
+}
+
+// applyNodeDefaults fills the settings nodes leave unset from defaults.
+// explicit holds, per node, the keys its YAML sets, so an explicit
+// tools_auto_approval: false is kept.
+func applyNodeDefaults(nodes []Node, defaults *NodeDefaults, explicit []map[string]bool) {
+	if defaults == nil {
+		return
+	}
+	for i := range nodes {
+		n := &nodes[i]
+		var keys map[string]bool
+		if i < len(explicit) {
+			keys = explicit[i]
+		}
+
+		// A flow that is loaded, saved and loaded again already carries the prefix
+		if defaults.System != "" && n.Type == "llm" && !strings.HasPrefix(n.System, defaults.System) {
+			if n.System == "" {
+				n.System = defaults.System
+			} else {
+				n.System = defaults.System + "\n\n" + n.System
+			}
+		}
+		if defaults.ToolsAutoApproval != nil && !keys["tools_auto_approval"] {
+			n.ToolsAutoApproval = *defaults.ToolsAutoApproval
+		}
+		if defaults.MaxRetries > 0 && n.MaxRetries == 0 {
+			n.MaxRetries = defaults.MaxRetries
+		}
+		if defaults.MaxToolCalls > 0 && n.Type == "llm" && n.MaxToolCalls == 0 {
+			n.MaxToolCalls = defaults.MaxToolCalls
+		}
+		if defaults.Generation != nil {
+			n.Generation = mergeGeneration(defaults.Generation, n.Generation)
+		}
+		// A node's own provider keeps that provider's default model
+		if n.Type == "llm" && n.Provider == "" {
+			n.Provider = defaults.Provider
+			if n.Model == "" {
+				n.Model = defaults.Model
+			}
+		}
+	}
+}
+
+// mergeGeneration returns base with the fields set in override replacing it.
+func mergeGeneration(base, override *GenerationConfig) *GenerationConfig {
+	merged := *base
+	if override == nil {
+		return &merged
+	}
+	if override.Temperature != nil {
+		merged.Temperature = override.Temperature
+	}
+	if override.TopP != nil {
+		merged.TopP = override.TopP
+	}
+	if override.MaxOutputTokens != 0 {
+		merged.MaxOutputTokens = override.MaxOutputTokens
+	}
+	if len(override.StopSequences) > 0 {
+		merged.StopSequences = override.StopSequences
+	}
+	return &merged
+}
+
+// nodeKeys returns, for each entry of the flow's nodes: sequence, the keys
+// set in its YAML mapping.
+func nodeKeys(flow *yaml.Node) []map[string]bool {
+	if flow.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(flow.Content); i += 2 {
+		if flow.Content[i].Value != "nodes" || flow.Content[i+1].Kind != yaml.SequenceNode {
+			continue
+		}
+		entries := flow.Content[i+1].Content
+		keys := make([]map[string]bool, len(entries))
+		for j, entry := range entries {
+			keys[j] = make(map[string]bool)
+			if entry.Kind != yaml.MappingNode {
+				continue
+			}
+			for k := 0; k < len(entry.Content); k += 2 {
+				keys[j][entry.Content[k].Value] = true
+			}
+		}
+		return keys
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestNodeDefaults(t *testing.T) {
+	var cfg AgentConfig
+	src := `
+description: defaults
+defaults:
+  system: You are a release engineer.
+  tools_auto_approval: true
+  max_retries: 2
+  generation:
+    temperature: 0.2
+    max_output_tokens: 500
+  provider: azure:prod-gpt4
+  model: gpt-4o-prod
+nodes:
+  - name: plain
+    type: llm
+    prompt: hi
+  - name: custom
+    type: llm
+    system: Answer in French.
+    tools_auto_approval: false
+    max_retries: 5
+    provider: openai
+    generation:
+      temperature: 0.9
+  - name: fetch
+    type: tool
+flow: []
+`
+	if err := yaml.Unmarshal([]byte(src), &cfg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	plain, custom, fetch := cfg.Nodes[0], cfg.Nodes[1], cfg.Nodes[2]
+
+	if plain.System != "You are a release engineer." || !plain.ToolsAutoApproval || plain.MaxRetries != 2 {
+		t.Errorf("plain node did not inherit the defaults: %+v", plain)
+	}
+	if plain.Generation == nil || *plain.Generation.Temperature != 0.2 || plain.Generation.MaxOutputTokens != 500 {
+		t.Errorf("plain generation = %+v", plain.Generation)
+	}
+
+	if plain.Provider != "azure:prod-gpt4" || plain.Model != "gpt-4o-prod" {
+		t.Errorf("plain provider/model = %q/%q", plain.Provider, plain.Model)
+	}
+
+	if custom.System != "You are a release engineer.\n\nAnswer in French." {
+		t.Errorf("custom system = %q", custom.System)
+	}
+	if custom.ToolsAutoApproval || custom.MaxRetries != 5 {
+		t.Errorf("explicit node settings were overridden: %+v", custom)
+	}
+	if *custom.Generation.Temperature != 0.9 || custom.Generation.MaxOutputTokens != 500 {
+		t.Errorf("custom generation = %+v", custom.Generation)
+	}
+	if custom.Provider != "openai" || custom.Model != "" {
+		t.Errorf("custom provider/model = %q/%q, want its own provider with its default model", custom.Provider, custom.Model)
+	}
+
+	if fetch.System != "" || !fetch.ToolsAutoApproval || fetch.Provider != "" {
+		t.Errorf("tool node: system %q, auto approval %v", fetch.System, fetch.ToolsAutoApproval)
+	}
+
+	// Loading a saved flow again does not repeat the prefix
+	data, err := yaml.Marshal(&cfg)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var again AgentConfig
+	if err := yaml.Unmarshal(data, &again); err != nil {
+		t.Fatalf("unmarshal again: %v", err)
+	}
+	if again.Nodes[1].System != custom.System {
+		t.Errorf("reloaded system = %q", again.Nodes[1].System)
+	}
+}
@@ -0,0 +1,26 @@
+package config
+
+// FlowTest is a unit test of a flow, run by `astonish test` against a
+// scripted model instead of a real provider.
+type FlowTest struct {
+	Name      string              `yaml:"name" json:"name"`
+	Inputs    map[string]string   `yaml:"inputs,omitempty" json:"inputs,omitempty"`       // Answers for input nodes, by node name
+	Variables map[string]string   `yaml:"variables,omitempty" json:"variables,omitempty"` // Values for the flow's variables, as with --var
+	State     map[string]any      `yaml:"state,omitempty" json:"state,omitempty"`         // State values set before the flow starts
+	LLM       map[string][]string `yaml:"llm,omitempty" json:"llm,omitempty"`             // Canned model responses per node, in call order
+	Tools     map[string]any      `yaml:"tools,omitempty" json:"tools,omitempty"`         // Canned results per tool name, returned for every call
+	Expect    FlowTestExpect      `yaml:"expect" json:"expect"`
+}
+
+// FlowTestExpect is what a flow test asserts about the run.
+type FlowTestExpect struct {
+	Path  []string       `yaml:"path,omitempty" json:"path,omitempty"`   // Nodes visited, in order (START and END excluded)
+	State map[string]any `yaml:"state,omitempty" json:"state,omitempty"` // State values at the end of the run
+	Error string         `yaml:"error,omitempty" json:"error,omitempty"` // Text the run's error must contain; the run must fail
+}
+
+// FlowTestFile is a file holding the tests of a flow outside the flow
+// (astonish test --tests).
+type FlowTestFile struct {
+	Tests []FlowTest `yaml:"tests"`
+}
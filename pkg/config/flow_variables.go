@@ -0,0 +1,138 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FlowVariable is a typed flow input declared in the top-level variables:
+// section. Its value is placed in state at START, before the first node runs.
+type FlowVariable struct {
+	Type        string `yaml:"type,omitempty" json:"type,omitempty"`               // "str" (default), "int", "float", "bool", "list" or "dict"
+	Default     any    `yaml:"default,omitempty" json:"default,omitempty"`         // Value used when none is given with --var
+	Description string `yaml:"description,omitempty" json:"description,omitempty"` // Shown by `astonish flows info`
+	Required    bool   `yaml:"required,omitempty" json:"required,omitempty"`       // Fail the run when no value is given and there is no default
+}
+
+// validVariableTypes are the types a flow variable may declare.
+var validVariableTypes = map[string]bool{"": true, "str": true, "int": true, "float": true, "bool": true, "list": true, "dict": true}
+
+// VariableNames returns the names of the flow's variables, sorted.
+func (c *AgentConfig) VariableNames() []string {
+	names := make([]string, 0, len(c.Variables))
+	for name := range c.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ResolveVariables returns the initial state values of the flow's variables:
+// the override for each name (as given on the command line) converted to the
+// declared type, else the default. Overrides of undeclared variables and
+// required variables without a value are errors.
+func (c *AgentConfig) ResolveVariables(overrides map[string]string) (map[string]any, error) {
+	for name := range overrides {
+		if _, ok := c.Variables[name]; !ok {
+			return nil, fmt.Errorf("unknown variable %q (declared: %s)", name, strings.Join(c.VariableNames(), ", "))
+		}
+	}
+
+	values := make(map[string]any, len(c.Variables))
+	for _, name := range c.VariableNames() {
+		v := c.Variables[name]
+		if raw, ok := overrides[name]; ok {
+			val, err := v.Parse(raw)
+			if err != nil {
+				return nil, fmt.Errorf("variable %q: %w", name, err)
+			}
+			values[name] = val
+			continue
+		}
+		if v.Default != nil {
+			values[name] = v.Default
+			continue
+		}
+		if v.Required {
+			return nil, fmt.Errorf("variable %q is required: pass it with --var %s=<value>", name, name)
+		}
+		values[name] = v.zero()
+	}
+	return values, nil
+}
+
+// Parse converts a command-line value to the variable's type. Lists accept
+// JSON arrays or comma-separated values; dicts accept JSON objects.
+func (v FlowVariable) Parse(raw string) (any, error) {
+	switch v.Type {
+	case "", "str":
+		return raw, nil
+	case "int":
+		n, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an int", raw)
+		}
+		return n, nil
+	case "float":
+		f, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a float", raw)
+		}
+		return f, nil
+	case "bool":
+		b, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a bool", raw)
+		}
+		return b, nil
+	case "list":
+		trimmed := strings.TrimSpace(raw)
+		if strings.HasPrefix(trimmed, "[") {
+			var list []any
+			if err := json.Unmarshal([]byte(trimmed), &list); err != nil {
+				return nil, fmt.Errorf("invalid JSON list: %w", err)
+			}
+			return list, nil
+		}
+		list := []any{}
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		return list, nil
+	case "dict":
+		var dict map[string]any
+		if err := json.Unmarshal([]byte(raw), &dict); err != nil {
+			return nil, fmt.Errorf("invalid JSON object: %w", err)
+		}
+		return dict, nil
+	}
+	return nil, fmt.Errorf("unknown type %q", v.Type)
+}
+
+// zero is the value of a variable that has neither an override nor a
+// default, matching how output_model keys are pre-populated.
+func (v FlowVariable) zero() any {
+	switch v.Type {
+	case "int":
+		return 0
+	case "float":
+		return 0.0
+	case "bool":
+		return false
+	case "list":
+		return []any{}
+	case "dict":
+		return map[string]any{}
+	}
+	return ""
+}
+
+// ValidateVariableType reports whether t is a valid variable type.
+func ValidateVariableType(t string) bool {
+	return validVariableTypes[t]
+}
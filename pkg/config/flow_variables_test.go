@@ -0,0 +1,88 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestResolveVariables(t *testing.T) {
+	var cfg AgentConfig
+	src := `
+description: vars
+variables:
+  repo:
+    default: SAP/astonish
+    description: Repository
+  limit:
+    type: int
+    default: 5
+  labels:
+    type: list
+  strict:
+    type: bool
+  token:
+    required: true
+nodes: []
+flow: []
+`
+	if err := yaml.Unmarshal([]byte(src), &cfg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got := cfg.VariableNames(); !reflect.DeepEqual(got, []string{"labels", "limit", "repo", "strict", "token"}) {
+		t.Errorf("VariableNames = %v", got)
+	}
+
+	values, err := cfg.ResolveVariables(map[string]string{"token": "abc", "limit": "12", "labels": "bug, docs"})
+	if err != nil {
+		t.Fatalf("ResolveVariables: %v", err)
+	}
+	want := map[string]any{
+		"repo":   "SAP/astonish",
+		"limit":  12,
+		"labels": []any{"bug", "docs"},
+		"strict": false,
+		"token":  "abc",
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("values = %#v, want %#v", values, want)
+	}
+
+	failures := []struct {
+		overrides map[string]string
+		msg       string
+	}{
+		{nil, "is required"},
+		{map[string]string{"token": "x", "limit": "ten"}, "not an int"},
+		{map[string]string{"token": "x", "other": "1"}, "unknown variable"},
+	}
+	for _, tt := range failures {
+		if _, err := cfg.ResolveVariables(tt.overrides); err == nil || !strings.Contains(err.Error(), tt.msg) {
+			t.Errorf("ResolveVariables(%v) = %v, want error containing %q", tt.overrides, err, tt.msg)
+		}
+	}
+}
+
+func TestFlowVariableParse(t *testing.T) {
+	tests := []struct {
+		typ, raw string
+		want     any
+	}{
+		{"", "text", "text"},
+		{"float", "1.5", 1.5},
+		{"bool", "true", true},
+		{"list", `["a", 1]`, []any{"a", float64(1)}},
+		{"dict", `{"k": "v"}`, map[string]any{"k": "v"}},
+	}
+	for _, tt := range tests {
+		got, err := FlowVariable{Type: tt.typ}.Parse(tt.raw)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%s, %q) = %#v, %v; want %#v", tt.typ, tt.raw, got, err, tt.want)
+		}
+	}
+	if _, err := (FlowVariable{Type: "dict"}).Parse("not json"); err == nil {
+		t.Error("expected invalid dict to fail")
+	}
+}
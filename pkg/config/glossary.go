@@ -0,0 +1,43 @@
+package config
+
+import (
+	"sort"
+	"strings"
+)
+
+// GlossaryFor returns the glossary section appended to the system prompt of
+// an LLM node, or "" when the flow has no glossary or the node opts out with
+// glossary: false. glossary_terms restricts the section to the listed terms.
+// Terms are sorted so the prompt stays stable across runs.
+func (c *AgentConfig) GlossaryFor(node *Node) string {
+	if c == nil || len(c.Glossary) == 0 || node == nil {
+		return ""
+	}
+	if node.Glossary != nil && !*node.Glossary {
+		return ""
+	}
+
+	terms := node.GlossaryTerms
+	if len(terms) == 0 {
+		terms = make([]string, 0, len(c.Glossary))
+		for term := range c.Glossary {
+			terms = append(terms, term)
+		}
+	}
+	terms = append([]string(nil), terms...)
+	sort.Strings(terms)
+
+	var sb strings.Builder
+	for _, term := range terms {
+		def, ok := c.Glossary[term]
+		if !ok {
+			continue
+		}
+		sb.WriteString("- " + term + ": " + strings.TrimSpace(def) + "\n")
+	}
+	if sb.Len() == 0 {
+		return ""
+	}
+	return "## Glossary\nThese terms have a specific meaning in this task. Use them as defined:\n" +
+		strings.TrimRight(sb.String(), "\n")
+}
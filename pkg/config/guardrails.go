@@ -0,0 +1,35 @@
+package config
+
+// GuardrailsConfig declares the checks LLM output must pass before it is
+// stored in state or shown to the user, and what happens when it does not.
+type GuardrailsConfig struct {
+	Deny       []string             `yaml:"deny,omitempty" json:"deny,omitempty"`             // Regular expressions the output must not match
+	PII        []string             `yaml:"pii,omitempty" json:"pii,omitempty"`               // Kinds of personal data to detect: email, phone, credit_card, ssn, iban, ip_address
+	Classifier *GuardrailClassifier `yaml:"classifier,omitempty" json:"classifier,omitempty"` // LLM-based safety check against a policy
+	Action     string               `yaml:"action,omitempty" json:"action,omitempty"`         // "block" (default), "redact" or "route"
+	Node       string               `yaml:"node,omitempty" json:"node,omitempty"`             // route: node that handles the violation
+}
+
+// GuardrailClassifier asks a model whether the output violates a policy.
+type GuardrailClassifier struct {
+	Policy string `yaml:"policy" json:"policy"`                   // What the output must not contain, in plain language
+	Model  string `yaml:"model,omitempty" json:"model,omitempty"` // "provider/model" or a model on the flow's provider (default: the node's model)
+}
+
+// GuardrailActions are the valid guardrails actions.
+var GuardrailActions = []string{"block", "redact", "route"}
+
+// PIIKinds are the kinds of personal data guardrails can detect.
+var PIIKinds = []string{"email", "phone", "credit_card", "ssn", "iban", "ip_address"}
+
+// GuardrailsFor returns the guardrails of an LLM node: its own, else the
+// flow's. Nil means the output is not checked.
+func (c *AgentConfig) GuardrailsFor(node *Node) *GuardrailsConfig {
+	if node != nil && node.Guardrails != nil {
+		return node.Guardrails
+	}
+	if c == nil {
+		return nil
+	}
+	return c.Guardrails
+}
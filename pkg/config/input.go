@@ -0,0 +1,28 @@
+package config
+
+// Types of input nodes (input_type).
+const (
+	InputText      = "text"      // Free text (default)
+	InputNumber    = "number"    // Stored as an int, or a float with a fraction
+	InputBoolean   = "boolean"   // yes/no, true/false, y/n or 1/0; stored as a bool
+	InputRegex     = "regex"     // A regular expression; it must compile
+	InputMultiline = "multiline" // Free text over several lines
+	InputSecret    = "secret"    // Masked while typed; its output keys are sensitive
+)
+
+// Input modes of input nodes (input_mode).
+const (
+	InputModeText = "text" // The user types the answer or picks an option (default)
+	InputModeFile = "file" // The user picks files under file_root
+)
+
+// InputTypes lists the valid input_type values.
+var InputTypes = []string{InputText, InputNumber, InputBoolean, InputRegex, InputMultiline, InputSecret}
+
+// InputValidation checks the answer to an input node. An answer that fails
+// is not stored; the question is asked again with Message.
+type InputValidation struct {
+	Expr    string `yaml:"expr,omitempty" json:"expr,omitempty"`       // Starlark expression over value (the typed answer) and the state; must be true
+	Regex   string `yaml:"regex,omitempty" json:"regex,omitempty"`     // Pattern the answer must match
+	Message string `yaml:"message,omitempty" json:"message,omitempty"` // Shown when the answer is rejected (default: describes the failed check)
+}
@@ -0,0 +1,337 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FlowJSONSchema returns the JSON Schema of flow files, generated from the
+// structs they are loaded into, for editor validation and autocompletion
+// (astonish schema).
+func FlowJSONSchema() map[string]any {
+	g := &schemaGenerator{defs: make(map[string]any)}
+	schema := g.structSchema(reflect.TypeOf(agentConfigRaw{}))
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "Astonish flow"
+	schema["$defs"] = g.defs
+	return schema
+}
+
+// schemaAdjuster is implemented by config types whose UnmarshalYAML accepts
+// more than their fields, so their schema says so too.
+type schemaAdjuster interface {
+	adjustSchema(schema map[string]any) map[string]any
+}
+
+var schemaAdjusterType = reflect.TypeOf((*schemaAdjuster)(nil)).Elem()
+
+// schemaGenerator turns Go types into JSON Schema. Structs become $defs
+// named after their type, so recursive types terminate.
+type schemaGenerator struct {
+	defs map[string]any
+}
+
+func (g *schemaGenerator) schemaFor(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": g.schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schemaFor(t.Elem())}
+	case reflect.Struct:
+		name := t.Name()
+		if _, ok := g.defs[name]; !ok {
+			g.defs[name] = map[string]any{} // Placeholder while the fields refer back to it
+			g.defs[name] = g.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/$defs/" + name}
+	default:
+		return map[string]any{}
+	}
+}
+
+// structSchema returns the object schema of struct t: one property per
+// YAML field, and no others.
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]any {
+	props := make(map[string]any)
+	g.addFields(t, props)
+	schema := map[string]any{"type": "object", "properties": props, "additionalProperties": false}
+	if reflect.PointerTo(t).Implements(schemaAdjusterType) {
+		schema = reflect.New(t).Interface().(schemaAdjuster).adjustSchema(schema)
+	}
+	return schema
+}
+
+func (g *schemaGenerator) addFields(t reflect.Type, props map[string]any) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("yaml")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if slices.Contains(strings.Split(opts, ","), "inline") {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			g.addFields(ft, props)
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		props[name] = g.schemaFor(f.Type)
+	}
+}
+
+// SchemaDiagnostic is a problem found by checking a flow file against the
+// flow schema.
+type SchemaDiagnostic struct {
+	File    string // Empty for flows loaded from bytes
+	Line    int
+	Column  int
+	Path    string // Field path, e.g. nodes[2].max_tool_calls
+	Message string
+	Unknown bool // An unknown field, which the loader ignores
+}
+
+func (d SchemaDiagnostic) String() string {
+	pos := fmt.Sprintf("line %d:%d", d.Line, d.Column)
+	if d.File != "" {
+		pos = fmt.Sprintf("%s:%d:%d", d.File, d.Line, d.Column)
+	}
+	if d.Path == "" {
+		return pos + ": " + d.Message
+	}
+	return pos + ": " + d.Path + ": " + d.Message
+}
+
+// ValidateFlowFile checks the flow file at path, with the files it
+// includes, against the flow schema.
+func ValidateFlowFile(path string) ([]SchemaDiagnostic, error) {
+	r := &includeResolver{}
+	doc, err := r.load(path)
+	if err != nil || doc == nil {
+		return nil, err
+	}
+	return r.validate(doc), nil
+}
+
+// validate checks a loaded document against the flow schema.
+func (r *includeResolver) validate(doc *yaml.Node) []SchemaDiagnostic {
+	schema := FlowJSONSchema()
+	v := &schemaValidator{defs: schema["$defs"].(map[string]any), position: r.position}
+	v.check(schema, doc, "")
+	return v.diags
+}
+
+// position returns the file, line and column of n in a loaded document.
+func (r *includeResolver) position(n *yaml.Node) (string, int, int) {
+	if len(r.files) == 0 {
+		return "", n.Line, n.Column
+	}
+	file := min(n.Line/includeLineOffset, len(r.files)-1)
+	return r.name(r.files[file]), n.Line % includeLineOffset, n.Column
+}
+
+// schemaError turns the diagnostics that are not unknown fields into one
+// error, or returns nil when there are none.
+func schemaError(diags []SchemaDiagnostic) error {
+	var msgs []string
+	for _, d := range diags {
+		if !d.Unknown {
+			msgs = append(msgs, d.String())
+		}
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(msgs, "\n"))
+}
+
+// schemaValidator checks YAML nodes against the subset of JSON Schema the
+// generator produces. Scalars are matched the way yaml.v3 decodes them.
+type schemaValidator struct {
+	defs     map[string]any
+	position func(*yaml.Node) (string, int, int)
+	diags    []SchemaDiagnostic
+}
+
+func (v *schemaValidator) report(n *yaml.Node, path, msg string, unknown bool) {
+	file, line, col := v.position(n)
+	v.diags = append(v.diags, SchemaDiagnostic{File: file, Line: line, Column: col, Path: path, Message: msg, Unknown: unknown})
+}
+
+func (v *schemaValidator) check(schema map[string]any, n *yaml.Node, path string) {
+	if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		n = n.Content[0]
+	}
+	if n.Kind == yaml.AliasNode && n.Alias != nil {
+		n = n.Alias
+	}
+	if ref, ok := schema["$ref"].(string); ok {
+		def, _ := v.defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]any)
+		schema = def
+	}
+	if alternatives, ok := schema["oneOf"].([]any); ok {
+		v.checkOneOf(alternatives, n, path)
+		return
+	}
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!null" {
+		return // Decodes to the zero value
+	}
+
+	switch schema["type"] {
+	case "object":
+		if n.Kind != yaml.MappingNode {
+			v.report(n, path, "expected a mapping, got "+describeNode(n), false)
+			return
+		}
+		props, _ := schema["properties"].(map[string]any)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			if key.Value == "<<" {
+				continue // Merge key of an anchor
+			}
+			if props != nil {
+				if prop, ok := props[key.Value].(map[string]any); ok {
+					v.check(prop, value, joinPath(path, key.Value))
+					continue
+				}
+			}
+			switch extra := schema["additionalProperties"].(type) {
+			case map[string]any:
+				v.check(extra, value, joinPath(path, key.Value))
+			case bool:
+				if !extra {
+					v.report(key, path, unknownFieldMessage(key.Value, props), true)
+				}
+			}
+		}
+	case "array":
+		if n.Kind != yaml.SequenceNode {
+			v.report(n, path, "expected a list, got "+describeNode(n), false)
+			return
+		}
+		items, _ := schema["items"].(map[string]any)
+		for i, item := range n.Content {
+			v.check(items, item, fmt.Sprintf("%s[%d]", path, i))
+		}
+	case "string":
+		if n.Kind != yaml.ScalarNode {
+			v.report(n, path, "expected a string, got "+describeNode(n), false)
+		}
+	case "integer", "number":
+		if n.Kind != yaml.ScalarNode || (n.Tag != "!!int" && n.Tag != "!!float") {
+			v.report(n, path, fmt.Sprintf("expected %s, got %s", schema["type"], describeNode(n)), false)
+		}
+	case "boolean":
+		if n.Kind != yaml.ScalarNode || (n.Tag != "!!bool" && !isYAML11Bool(n.Value)) {
+			v.report(n, path, "expected true or false, got "+describeNode(n), false)
+		}
+	}
+}
+
+// checkOneOf accepts n when one alternative matches, and otherwise reports
+// the problems of the alternative that came closest.
+func (v *schemaValidator) checkOneOf(alternatives []any, n *yaml.Node, path string) {
+	var best []SchemaDiagnostic
+	for i, alt := range alternatives {
+		sub := &schemaValidator{defs: v.defs, position: v.position}
+		sub.check(alt.(map[string]any), n, path)
+		if len(sub.diags) == 0 {
+			return
+		}
+		if i == 0 || len(sub.diags) < len(best) {
+			best = sub.diags
+		}
+	}
+	v.diags = append(v.diags, best...)
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// describeNode names what a YAML node holds, for error messages.
+func describeNode(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a list"
+	}
+	if n.Tag == "!!str" {
+		return fmt.Sprintf("%q", n.Value)
+	}
+	return n.Value
+}
+
+// isYAML11Bool reports whether s is a YAML 1.1 boolean, which yaml.v3
+// still decodes into bool fields.
+func isYAML11Bool(s string) bool {
+	switch strings.ToLower(s) {
+	case "y", "yes", "n", "no", "on", "off":
+		return true
+	}
+	return false
+}
+
+// unknownFieldMessage reports an unknown field, suggesting the closest
+// known one when it looks like a typo.
+func unknownFieldMessage(name string, props map[string]any) string {
+	best, bestDist := "", max(1, len(name)/4)+1
+	for known := range props {
+		if d := editDistance(name, known); d < bestDist || (d == bestDist && known < best) {
+			best, bestDist = known, d
+		}
+	}
+	if best == "" {
+		return fmt.Sprintf("unknown field %q", name)
+	}
+	return fmt.Sprintf("unknown field %q (did you mean %q?)", name, best)
+}
+
+// editDistance returns the Levenshtein distance of a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
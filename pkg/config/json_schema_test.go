@@ -0,0 +1,78 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestFlowJSONSchema(t *testing.T) {
+	schema := FlowJSONSchema()
+	props := schema["properties"].(map[string]any)
+	if nodes := props["nodes"].(map[string]any); nodes["items"].(map[string]any)["$ref"] != "#/$defs/Node" {
+		t.Errorf("nodes = %v, want a list of Node", nodes)
+	}
+	node := schema["$defs"].(map[string]any)["Node"].(map[string]any)["properties"].(map[string]any)
+	if _, ok := node["cache_response"]; !ok {
+		t.Error("Node schema misses cache_response")
+	}
+	if _, ok := node["output_model"].(map[string]any)["additionalProperties"].(map[string]any)["oneOf"]; !ok {
+		t.Error("output_model does not accept the long form of its entries")
+	}
+
+	_, err := LoadAgentFromBytes([]byte("description: x\nnodes:\n  - name: a\n    type: llm\n    max_retries: lots\n"))
+	if err == nil || err.Error() != `line 5:18: nodes[0].max_retries: expected integer, got "lots"` {
+		t.Errorf("load error = %v, want the position and field of the bad value", err)
+	}
+
+	// Valid short and long forms pass; unknown fields are reported, not errors
+	r := &includeResolver{}
+	var diags []SchemaDiagnostic
+	for _, flow := range []string{
+		"nodes:\n  - name: out\n    type: output\n    report: true\n",
+		"nodes:\n  - name: ask\n    type: llm\n    output_model:\n      token:\n        type: str\n        sensitive: true\n",
+		"defaults: &d\n  max_retries: 2\nnodes:\n  - name: a\n    type: llm\n    promt: hi\n",
+	} {
+		var doc yaml.Node
+		if err := yaml.Unmarshal([]byte(flow), &doc); err != nil {
+			t.Fatal(err)
+		}
+		diags = append(diags, r.validate(&doc)...)
+	}
+	if len(diags) != 1 || !diags[0].Unknown || !strings.Contains(diags[0].String(), `line 6:5: nodes[0]: unknown field "promt" (did you mean "prompt"?)`) {
+		t.Errorf("diagnostics = %v, want only the unknown field", diags)
+	}
+}
+
+func TestLoadAgentSchemaWarnings(t *testing.T) {
+	const flow = "description: x\nnodes:\n  - name: a\n    type: llm\n    promt: hi\n"
+	want := `nodes[0]: unknown field "promt" (did you mean "prompt"?)`
+
+	cfg, err := LoadAgentFromBytes([]byte(flow))
+	if err != nil {
+		t.Fatalf("LoadAgentFromBytes: %v", err)
+	}
+	if len(cfg.SchemaWarnings) != 1 || cfg.SchemaWarnings[0].String() != "line 5:5: "+want {
+		t.Errorf("warnings = %v, want the typo", cfg.SchemaWarnings)
+	}
+
+	path := filepath.Join(t.TempDir(), "typo.yaml")
+	if err := os.WriteFile(path, []byte(flow), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = LoadAgent(path)
+	if err != nil {
+		t.Fatalf("LoadAgent: %v", err)
+	}
+	if len(cfg.SchemaWarnings) != 1 || cfg.SchemaWarnings[0].String() != "typo.yaml:5:5: "+want {
+		t.Errorf("warnings = %v, want the typo with its file", cfg.SchemaWarnings)
+	}
+
+	cfg, err = LoadAgentFromBytes([]byte("description: x\nnodes:\n  - name: a\n    type: llm\n    prompt: hi\n"))
+	if err != nil || cfg.SchemaWarnings != nil {
+		t.Errorf("valid flow: warnings = %v, err = %v", cfg.SchemaWarnings, err)
+	}
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -13,9 +14,37 @@ type MCPServerConfig struct {
 	Command   string            `json:"command" yaml:"command"`
 	Args      []string          `json:"args" yaml:"args,omitempty"`
 	Env       map[string]string `json:"env" yaml:"env,omitempty"`
-	Transport string            `json:"transport" yaml:"transport,omitempty"`       // "stdio" or "sse"
-	URL       string            `json:"url,omitempty" yaml:"url,omitempty"`         // For SSE transport
+	Transport string            `json:"transport" yaml:"transport,omitempty"`       // "stdio", "sse" or "streamable-http"
+	URL       string            `json:"url,omitempty" yaml:"url,omitempty"`         // For remote transports
 	Enabled   *bool             `json:"enabled,omitempty" yaml:"enabled,omitempty"` // nil defaults to true
+
+	// HTTP headers and authentication of remote transports. Values may
+	// reference environment variables as ${VAR} to keep secrets out of the file.
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Auth    *MCPAuthConfig    `json:"auth,omitempty" yaml:"auth,omitempty"`
+
+	// Network options for remote transports; unset fields fall back to the http section of config.yaml
+	Proxy              string `json:"proxy,omitempty" yaml:"proxy,omitempty"`
+	CABundle           string `json:"ca_bundle,omitempty" yaml:"ca_bundle,omitempty"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty" yaml:"insecure_skip_verify,omitempty"`
+}
+
+// MCPAuthConfig is the authentication of an MCP server.
+type MCPAuthConfig struct {
+	Type     string `json:"type" yaml:"type"`                             // "bearer", "basic" or "oauth"
+	Token    string `json:"token,omitempty" yaml:"token,omitempty"`       // For bearer; set at startup for oauth
+	Username string `json:"username,omitempty" yaml:"username,omitempty"` // For basic
+	Password string `json:"password,omitempty" yaml:"password,omitempty"` // For basic
+
+	// OAuth device flow, run by 'astonish mcp login'. The tokens are kept in
+	// the credential store and refreshed when the server is started.
+	Credential    string `json:"credential,omitempty" yaml:"credential,omitempty"` // Credential store name (default "mcp-<server>")
+	ClientID      string `json:"client_id,omitempty" yaml:"client_id,omitempty"`
+	ClientSecret  string `json:"client_secret,omitempty" yaml:"client_secret,omitempty"`     // Only for confidential clients
+	DeviceAuthURL string `json:"device_auth_url,omitempty" yaml:"device_auth_url,omitempty"` // Device authorization endpoint
+	TokenURL      string `json:"token_url,omitempty" yaml:"token_url,omitempty"`
+	Scope         string `json:"scope,omitempty" yaml:"scope,omitempty"`
+	TokenEnv      string `json:"token_env,omitempty" yaml:"token_env,omitempty"` // Env var that receives the token of a stdio server
 }
 
 // IsEnabled returns true if the server is enabled (defaults to true if not set)
@@ -23,6 +52,96 @@ func (c *MCPServerConfig) IsEnabled() bool {
 	return c.Enabled == nil || *c.Enabled
 }
 
+// IsRemote returns true if the server is reached over HTTP (SSE or
+// Streamable HTTP) rather than launched as a local process.
+func (c *MCPServerConfig) IsRemote() bool {
+	switch strings.ToLower(c.Transport) {
+	case "sse", "streamable-http", "http":
+		return true
+	}
+	return false
+}
+
+// RequestHeaders returns the HTTP headers sent with every request to a
+// remote server: the configured headers plus the Authorization header of
+// the auth config, with ${VAR} references expanded.
+func (c *MCPServerConfig) RequestHeaders() (map[string]string, error) {
+	headers := make(map[string]string, len(c.Headers)+1)
+	for k, v := range c.Headers {
+		headers[k] = os.ExpandEnv(v)
+	}
+	if c.Auth == nil {
+		return headers, nil
+	}
+	switch strings.ToLower(c.Auth.Type) {
+	case "bearer":
+		token := os.ExpandEnv(c.Auth.Token)
+		if token == "" {
+			return nil, fmt.Errorf("bearer auth requires a token")
+		}
+		headers["Authorization"] = "Bearer " + token
+	case "basic":
+		username := os.ExpandEnv(c.Auth.Username)
+		if username == "" {
+			return nil, fmt.Errorf("basic auth requires a username")
+		}
+		creds := username + ":" + os.ExpandEnv(c.Auth.Password)
+		headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(creds))
+	case "oauth":
+		// The token comes from the credential store, never from the file
+		if c.Auth.Token == "" {
+			return nil, fmt.Errorf("oauth auth has no token; run 'astonish mcp login' first")
+		}
+		headers["Authorization"] = "Bearer " + c.Auth.Token
+	default:
+		return nil, fmt.Errorf("unsupported auth type %q (expected bearer, basic or oauth)", c.Auth.Type)
+	}
+	return headers, nil
+}
+
+// WithEnv returns the config of the server run with extra environment
+// variables: added to the environment of a stdio server, and expanding
+// ${VAR} references in the headers and auth of a remote server. Other
+// references are left for RequestHeaders.
+func (c MCPServerConfig) WithEnv(env map[string]string) MCPServerConfig {
+	if len(env) == 0 {
+		return c
+	}
+	if !c.IsRemote() {
+		merged := make(map[string]string, len(c.Env)+len(env))
+		for k, v := range c.Env {
+			merged[k] = v
+		}
+		for k, v := range env {
+			merged[k] = v
+		}
+		c.Env = merged
+		return c
+	}
+
+	expand := func(s string) string {
+		return os.Expand(s, func(name string) string {
+			if v, ok := env[name]; ok {
+				return v
+			}
+			return "${" + name + "}"
+		})
+	}
+	headers := make(map[string]string, len(c.Headers))
+	for k, v := range c.Headers {
+		headers[k] = expand(v)
+	}
+	c.Headers = headers
+	if c.Auth != nil {
+		auth := *c.Auth
+		auth.Token = expand(auth.Token)
+		auth.Username = expand(auth.Username)
+		auth.Password = expand(auth.Password)
+		c.Auth = &auth
+	}
+	return c
+}
+
 // MCPConfig represents the entire MCP configuration
 type MCPConfig struct {
 	MCPServers map[string]MCPServerConfig `json:"mcpServers"`
@@ -212,6 +331,9 @@ func SaveMCPConfig(config *MCPConfig) error {
 	}
 
 	// Write to file
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
 	if err := os.WriteFile(mcpConfigPath, data, 0644); err != nil {
 		return fmt.Errorf("failed to write MCP config file: %w", err)
 	}
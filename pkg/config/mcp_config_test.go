@@ -366,3 +366,24 @@ func TestMergeStandardServersWithConfig_NilAppCfgNoFileFallback(t *testing.T) {
 		t.Fatal("tavily (secret server) should NOT be injected when nil appCfg is passed — no file fallback")
 	}
 }
+
+func TestMCPServerConfigWithEnv(t *testing.T) {
+	stdio := MCPServerConfig{Command: "server", Env: map[string]string{"A": "1", "B": "2"}}
+	got := stdio.WithEnv(map[string]string{"B": "3"})
+	if got.Env["A"] != "1" || got.Env["B"] != "3" || stdio.Env["B"] != "2" {
+		t.Errorf("stdio env = %v (original %v), want B overridden on a copy", got.Env, stdio.Env)
+	}
+
+	remote := MCPServerConfig{
+		Transport: "sse",
+		Headers:   map[string]string{"X-Org": "${ORG}", "X-Home": "${HOME}"},
+		Auth:      &MCPAuthConfig{Type: "bearer", Token: "${TOKEN}"},
+	}
+	got = remote.WithEnv(map[string]string{"ORG": "acme", "TOKEN": "t"})
+	if got.Headers["X-Org"] != "acme" || got.Headers["X-Home"] != "${HOME}" || got.Auth.Token != "t" {
+		t.Errorf("remote config = %v %+v, want node variables expanded and others kept", got.Headers, got.Auth)
+	}
+	if remote.Auth.Token != "${TOKEN}" {
+		t.Error("WithEnv changed the original auth config")
+	}
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OutputType is a parsed output_model type. Besides the scalar names
+// (str, int, float, bool, dict, any) it supports typed lists such as
+// list[int] or list[dict], and inline object schemas such as
+// list[{severity: str, line: int}].
+type OutputType struct {
+	Kind   string                 // "str", "int", "float", "bool", "dict", "any" or "list"
+	Items  *OutputType            // Element type for lists (nil means str)
+	Fields map[string]*OutputType // Properties of an inline object schema
+}
+
+// ParseOutputType parses an output_model type name. Anything that is not a
+// known type (including free-text descriptions) is treated as str, matching
+// how output_model has always been read; only malformed list[...] or
+// {...} syntax is an error.
+func ParseOutputType(s string) (*OutputType, error) {
+	trimmed := strings.ToLower(strings.TrimSpace(s))
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "list[") && !strings.HasPrefix(trimmed, "array[") {
+		p := &outputTypeParser{src: trimmed}
+		t, _ := p.parse()
+		if p.pos != len(trimmed) {
+			t = &OutputType{Kind: "str"}
+		}
+		return t, nil
+	}
+
+	p := &outputTypeParser{src: s}
+	t, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("invalid output type %q: %w", s, err)
+	}
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return nil, fmt.Errorf("invalid output type %q: unexpected %q", s, p.src[p.pos:])
+	}
+	return t, nil
+}
+
+// IsList reports whether the type is a list.
+func (t *OutputType) IsList() bool {
+	return t != nil && t.Kind == "list"
+}
+
+type outputTypeParser struct {
+	src string
+	pos int
+}
+
+func (p *outputTypeParser) skipSpace() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func (p *outputTypeParser) parse() (*OutputType, error) {
+	p.skipSpace()
+	if p.pos < len(p.src) && p.src[p.pos] == '{' {
+		return p.parseObject()
+	}
+
+	start := p.pos
+	for p.pos < len(p.src) && !strings.ContainsRune("[]{},: \t", rune(p.src[p.pos])) {
+		p.pos++
+	}
+	name := strings.ToLower(p.src[start:p.pos])
+
+	var t *OutputType
+	switch name {
+	case "list", "array":
+		t = &OutputType{Kind: "list"}
+	case "int", "integer":
+		t = &OutputType{Kind: "int"}
+	case "float", "number":
+		t = &OutputType{Kind: "float"}
+	case "bool", "boolean":
+		t = &OutputType{Kind: "bool"}
+	case "dict", "object":
+		t = &OutputType{Kind: "dict"}
+	case "any":
+		t = &OutputType{Kind: "any"}
+	default:
+		t = &OutputType{Kind: "str"}
+	}
+
+	p.skipSpace()
+	if p.pos < len(p.src) && p.src[p.pos] == '[' {
+		if t.Kind != "list" {
+			return nil, fmt.Errorf("only list takes an item type")
+		}
+		p.pos++
+		items, err := p.parse()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.pos >= len(p.src) || p.src[p.pos] != ']' {
+			return nil, fmt.Errorf("missing ']'")
+		}
+		p.pos++
+		t.Items = items
+	}
+	return t, nil
+}
+
+func (p *outputTypeParser) parseObject() (*OutputType, error) {
+	p.pos++ // '{'
+	t := &OutputType{Kind: "dict", Fields: map[string]*OutputType{}}
+	for {
+		p.skipSpace()
+		if p.pos < len(p.src) && p.src[p.pos] == '}' {
+			p.pos++
+			return t, nil
+		}
+		start := p.pos
+		for p.pos < len(p.src) && !strings.ContainsRune(":,{}[] \t", rune(p.src[p.pos])) {
+			p.pos++
+		}
+		key := strings.Trim(p.src[start:p.pos], `"'`)
+		p.skipSpace()
+		if key == "" || p.pos >= len(p.src) || p.src[p.pos] != ':' {
+			return nil, fmt.Errorf("expected 'field: type' in object")
+		}
+		p.pos++
+		field, err := p.parse()
+		if err != nil {
+			return nil, err
+		}
+		t.Fields[key] = field
+		p.skipSpace()
+		if p.pos < len(p.src) && p.src[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+		if p.pos >= len(p.src) || p.src[p.pos] != '}' {
+			return nil, fmt.Errorf("missing '}'")
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package config
+
+import "testing"
+
+func TestParseOutputType(t *testing.T) {
+	for _, tc := range []struct {
+		in, kind, items string
+	}{
+		{"str", "str", ""},
+		{"list", "list", ""},
+		{"list[int]", "list", "int"},
+		{"array[dict]", "list", "dict"},
+		{"The currently deployed version", "str", ""},
+	} {
+		got, err := ParseOutputType(tc.in)
+		if err != nil {
+			t.Fatalf("%q: %v", tc.in, err)
+		}
+		if got.Kind != tc.kind || (tc.items != "" && (got.Items == nil || got.Items.Kind != tc.items)) {
+			t.Errorf("%q = %+v, want %s[%s]", tc.in, got, tc.kind, tc.items)
+		}
+	}
+
+	got, err := ParseOutputType("list[{severity: str, line: int, tags: list[str]}]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := got.Items.Fields
+	if got.Items.Kind != "dict" || fields["line"].Kind != "int" || fields["tags"].Items.Kind != "str" {
+		t.Errorf("inline item schema parsed as %+v", got.Items)
+	}
+
+	for _, bad := range []string{"list[int", "{severity str}", "list[{a: int]"} {
+		if _, err := ParseOutputType(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
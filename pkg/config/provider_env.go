@@ -193,6 +193,8 @@ func SetupMCPEnv(mcpCfg *MCPConfig) {
 // to avoid a circular import (config ← credentials → config).
 var providerSecretKeys = map[string][]string{
 	"anthropic":     {"api_key"},
+	"azure":         {"api_key", "ad_token"},
+	"bedrock":       {"access_key_id", "secret_access_key", "session_token", "api_key"},
 	"gemini":        {"api_key"},
 	"openai":        {"api_key"},
 	"openrouter":    {"api_key"},
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"gopkg.in/yaml.v3"
+)
+
+// ReportConfig turns an output node's message into a standalone HTML report
+// written to the workspace. report: true uses the defaults.
+type ReportConfig struct {
+	Title     string   `yaml:"title,omitempty" json:"title,omitempty"`         // Report title (default: the flow description)
+	File      string   `yaml:"file,omitempty" json:"file,omitempty"`           // Path relative to the workspace, {var} interpolated (default: reports/<node>-<timestamp>.html)
+	Artifacts []string `yaml:"artifacts,omitempty" json:"artifacts,omitempty"` // Files embedded in the report: paths, or state keys holding paths
+
+	disabled bool // report: false
+}
+
+// Enabled reports whether the node writes a report.
+func (r *ReportConfig) Enabled() bool {
+	return r != nil && !r.disabled
+}
+
+// UnmarshalYAML accepts report: true / false as the short form of an empty
+// config.
+func (r *ReportConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		var enabled bool
+		if err := value.Decode(&enabled); err != nil {
+			return err
+		}
+		*r = ReportConfig{disabled: !enabled}
+		return nil
+	}
+	type plain ReportConfig
+	return value.Decode((*plain)(r))
+}
+
+// adjustSchema accepts the boolean short form in the flow schema.
+func (r *ReportConfig) adjustSchema(schema map[string]any) map[string]any {
+	return map[string]any{"oneOf": []any{map[string]any{"type": "boolean"}, schema}}
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// UnmarshalYAML accepts the long form of output_model entries, which marks
+// a state key as sensitive:
+//
+//	output_model:
+//	  api_token:
+//	    type: str
+//	    sensitive: true
+//
+// The entry is stored as its plain type in OutputModel and the key is added
+// to Sensitive.
+func (n *Node) UnmarshalYAML(value *yaml.Node) error {
+	sensitive, err := normalizeOutputModel(value)
+	if err != nil {
+		return err
+	}
+	type plain Node
+	if err := value.Decode((*plain)(n)); err != nil {
+		return err
+	}
+	for _, key := range sensitive {
+		if !slices.Contains(n.Sensitive, key) {
+			n.Sensitive = append(n.Sensitive, key)
+		}
+	}
+	return nil
+}
+
+// adjustSchema accepts the long form of output_model entries in the flow
+// schema.
+func (n *Node) adjustSchema(schema map[string]any) map[string]any {
+	props := schema["properties"].(map[string]any)
+	props["output_model"] = map[string]any{
+		"type": "object",
+		"additionalProperties": map[string]any{"oneOf": []any{
+			map[string]any{"type": "string"},
+			map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"type":      map[string]any{"type": "string"},
+					"sensitive": map[string]any{"type": "boolean"},
+				},
+				"additionalProperties": false,
+			},
+		}},
+	}
+	return schema
+}
+
+// normalizeOutputModel rewrites mapping entries of a node's output_model to
+// their type scalar and returns the keys marked sensitive.
+func normalizeOutputModel(node *yaml.Node) ([]string, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	var model *yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == "output_model" {
+			model = node.Content[i+1]
+		}
+	}
+	if model == nil || model.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	var sensitive []string
+	for i := 0; i+1 < len(model.Content); i += 2 {
+		key, entry := model.Content[i].Value, model.Content[i+1]
+		if entry.Kind != yaml.MappingNode {
+			continue
+		}
+		var spec struct {
+			Type      string `yaml:"type"`
+			Sensitive bool   `yaml:"sensitive"`
+		}
+		if err := entry.Decode(&spec); err != nil {
+			return nil, fmt.Errorf("output_model %q: %w", key, err)
+		}
+		if spec.Type == "" {
+			spec.Type = "str"
+		}
+		if spec.Sensitive {
+			sensitive = append(sensitive, key)
+		}
+		model.Content[i+1] = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: spec.Type, Line: entry.Line, Column: entry.Column}
+	}
+	return sensitive, nil
+}
+
+// SensitiveKeys returns the state keys marked sensitive by any node, and
+// the answers of secret input nodes.
+func (c *AgentConfig) SensitiveKeys() map[string]bool {
+	keys := make(map[string]bool)
+	for _, node := range c.Nodes {
+		for _, key := range node.Sensitive {
+			keys[key] = true
+		}
+		if node.Type == "input" && node.InputType == InputSecret {
+			for key := range node.OutputModel {
+				keys[key] = true
+			}
+		}
+	}
+	return keys
+}
@@ -0,0 +1,189 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// includeTag marks a value that is replaced by the content of another YAML
+// file, e.g. `nodes: !include nodes/review.yaml`.
+const includeTag = "!include"
+
+// includeLineOffset separates the lines of the files in a merged document:
+// lines of the i-th loaded file are shifted by i*includeLineOffset, so a
+// decode error can name the file its line belongs to.
+const includeLineOffset = 1_000_000
+
+// includeResolver loads a flow file and the files it includes into one
+// document.
+type includeResolver struct {
+	dir   string   // Directory of the flow file, for the file names in errors
+	root  string   // dir with symlinks resolved; included files must be inside it
+	files []string // Loaded files, in the order their line offsets were given
+	stack []string // Files being loaded, outermost first, to detect cycles
+}
+
+// load parses the YAML file at path and resolves its includes. It returns
+// nil for an empty file.
+func (r *includeResolver) load(path string) (*yaml.Node, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if r.dir == "" {
+		r.dir = filepath.Dir(abs)
+		if r.root, err = filepath.EvalSymlinks(r.dir); err != nil {
+			return nil, err
+		}
+	}
+	if slices.Contains(r.stack, abs) {
+		cycle := make([]string, 0, len(r.stack)+1)
+		for _, f := range append(r.stack, abs) {
+			cycle = append(cycle, r.name(f))
+		}
+		return nil, fmt.Errorf("include cycle: %s", strings.Join(cycle, " → "))
+	}
+
+	data, err := os.ReadFile(abs)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", r.name(abs), err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+
+	offset := len(r.files) * includeLineOffset
+	r.files = append(r.files, abs)
+	r.stack = append(r.stack, abs)
+	defer func() { r.stack = r.stack[:len(r.stack)-1] }()
+	if err := r.resolve(&doc, abs, offset); err != nil {
+		return nil, err
+	}
+	return doc.Content[0], nil
+}
+
+// resolve replaces the !include values under n, a node of file, with the
+// content of the files they name. An included list inside a list is
+// spliced in, so a file can contribute a group of nodes or edges.
+func (r *includeResolver) resolve(n *yaml.Node, file string, offset int) error {
+	n.Line += offset
+	content := make([]*yaml.Node, 0, len(n.Content))
+	for _, child := range n.Content {
+		if child.Tag != includeTag {
+			if err := r.resolve(child, file, offset); err != nil {
+				return err
+			}
+			content = append(content, child)
+			continue
+		}
+
+		if child.Kind != yaml.ScalarNode || child.Value == "" {
+			return fmt.Errorf("%s:%d: %s needs a file path", r.name(file), child.Line, includeTag)
+		}
+		if filepath.IsAbs(child.Value) {
+			return fmt.Errorf("%s:%d: %s %s: the path must be relative to the flow file", r.name(file), child.Line, includeTag, child.Value)
+		}
+		target := filepath.Join(filepath.Dir(file), child.Value)
+		if err := r.checkInside(target); err != nil {
+			return fmt.Errorf("%s:%d: %s %s: %w", r.name(file), child.Line, includeTag, child.Value, err)
+		}
+		included, err := r.load(target)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %s %s: %w", r.name(file), child.Line, includeTag, child.Value, err)
+		}
+		if included == nil {
+			return fmt.Errorf("%s:%d: %s %s: the file is empty", r.name(file), child.Line, includeTag, child.Value)
+		}
+		if n.Kind == yaml.SequenceNode && included.Kind == yaml.SequenceNode {
+			content = append(content, included.Content...)
+		} else {
+			content = append(content, included)
+		}
+	}
+	n.Content = content
+	return nil
+}
+
+// checkInside fails when target, once its symlinks are resolved, is not
+// inside the flow file's directory: a flow must not read arbitrary files
+// of the machine it runs on.
+func (r *includeResolver) checkInside(target string) error {
+	real, err := filepath.EvalSymlinks(target)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(r.root, real)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return errors.New("the file is outside the flow file's directory")
+	}
+	return nil
+}
+
+// name returns how errors refer to file: relative to the flow file.
+func (r *includeResolver) name(file string) string {
+	if rel, err := filepath.Rel(r.dir, file); err == nil {
+		return rel
+	}
+	return file
+}
+
+var yamlErrorLine = regexp.MustCompile(`^line (\d+): `)
+
+// decodeError rewrites the line numbers of a decode error of a document
+// with includes into file:line positions.
+func (r *includeResolver) decodeError(err error) error {
+	var typeErr *yaml.TypeError
+	if len(r.files) < 2 || !errors.As(err, &typeErr) {
+		return err
+	}
+	msgs := make([]string, len(typeErr.Errors))
+	for i, msg := range typeErr.Errors {
+		msgs[i] = yamlErrorLine.ReplaceAllStringFunc(msg, func(m string) string {
+			line, _ := strconv.Atoi(yamlErrorLine.FindStringSubmatch(m)[1])
+			file := min(line/includeLineOffset, len(r.files)-1)
+			return fmt.Sprintf("%s:%d: ", r.name(r.files[file]), line%includeLineOffset)
+		})
+	}
+	return fmt.Errorf("yaml: unmarshal errors:\n  %s", strings.Join(msgs, "\n  "))
+}
+
+// findInclude returns the first !include value under n.
+func findInclude(n *yaml.Node) *yaml.Node {
+	if n.Tag == includeTag {
+		return n
+	}
+	for _, child := range n.Content {
+		if found := findInclude(child); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// ExpandIncludes returns the flow file at path with its includes inlined,
+// for copying a flow somewhere its included files are not. A file without
+// includes is returned as is.
+func ExpandIncludes(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil || !bytes.Contains(data, []byte(includeTag)) {
+		return data, err
+	}
+	doc, err := (&includeResolver{}).load(path)
+	if err != nil || doc == nil {
+		return data, err
+	}
+	return yaml.Marshal(doc)
+}
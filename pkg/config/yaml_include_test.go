@@ -0,0 +1,111 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadAgentIncludes(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	write("shared/defaults.yaml", "generation:\n  temperature: 0\n")
+	write("nodes/review.yaml", "- name: review\n  type: llm\n  prompt: Review\n- name: summarize\n  type: llm\n  prompt: Summarize\n")
+	flow := write("flow.yaml", `description: Review
+defaults: !include shared/defaults.yaml
+nodes:
+  - name: fetch
+    type: llm
+    prompt: Fetch
+  - !include nodes/review.yaml
+flow:
+  - from: START
+    to: fetch
+`)
+	cfg, err := LoadAgent(flow)
+	if err != nil {
+		t.Fatalf("LoadAgent: %v", err)
+	}
+	if len(cfg.Nodes) != 3 || cfg.Nodes[1].Name != "review" || cfg.Nodes[2].Name != "summarize" {
+		t.Errorf("nodes = %+v, want the included group spliced after fetch", cfg.Nodes)
+	}
+	if g := cfg.Nodes[0].Generation; g == nil || g.Temperature == nil || *g.Temperature != 0 {
+		t.Error("included defaults were not applied")
+	}
+
+	expanded, err := ExpandIncludes(flow)
+	if err != nil || strings.Contains(string(expanded), includeTag) || !strings.Contains(string(expanded), "summarize") {
+		t.Errorf("ExpandIncludes = %s, %v, want the includes inlined", expanded, err)
+	}
+	if _, err := LoadAgentFromBytes([]byte("nodes: !include nodes/review.yaml\n")); err == nil {
+		t.Error("an include without a flow file was accepted")
+	}
+
+	write("a.yaml", "nodes: !include b.yaml\n")
+	write("b.yaml", "- !include a.yaml\n")
+	if _, err := LoadAgent(filepath.Join(dir, "a.yaml")); err == nil || !strings.Contains(err.Error(), "include cycle: a.yaml → b.yaml → a.yaml") {
+		t.Errorf("cycle error = %v", err)
+	}
+
+	write("bad.yaml", "- name: broken\n  max_tool_calls: many\n")
+	bad := write("uses_bad.yaml", "description: Bad\nnodes: !include bad.yaml\n")
+	if _, err := LoadAgent(bad); err == nil || !strings.Contains(err.Error(), "bad.yaml:2:19: nodes[0].max_tool_calls") {
+		t.Errorf("decode error = %v, want the position in the included file", err)
+	}
+}
+
+func TestLoadAgentIncludesStayInFlowDir(t *testing.T) {
+	base := t.TempDir()
+	dir := filepath.Join(base, "flows")
+	write := func(path, content string) string {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	secret := write(filepath.Join(base, "secret.yaml"), "- name: leak\n  type: llm\n  prompt: Leak\n")
+	write(filepath.Join(dir, "shared/nodes.yaml"), "- name: review\n  type: llm\n  prompt: Review\n")
+	if err := os.Symlink(secret, filepath.Join(dir, "link.yaml")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		include string
+		wantErr string
+	}{
+		{name: "absolute path", include: secret, wantErr: "must be relative"},
+		{name: "parent directory", include: "../secret.yaml", wantErr: "outside the flow file's directory"},
+		{name: "symlink out of the directory", include: "link.yaml", wantErr: "outside the flow file's directory"},
+		{name: "parent of a subdirectory", include: "sub/../shared/nodes.yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flow := write(filepath.Join(dir, "flow.yaml"), "description: Review\nnodes: !include "+tt.include+"\n")
+			_, err := LoadAgent(flow)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("LoadAgent: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadAgent error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
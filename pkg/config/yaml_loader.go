@@ -1,8 +1,8 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
-	"os"
 	"path/filepath"
 	"strings"
 
@@ -11,32 +11,68 @@ import (
 
 // AgentConfig represents the top-level structure of the agent YAML.
 type AgentConfig struct {
-	Description     string              `yaml:"description"`
-	Type            string              `yaml:"type,omitempty"`         // "drill", "drill_suite" (legacy: "test", "test_suite"), or empty for regular flows
-	Template        string              `yaml:"template,omitempty"`     // Sandbox template (also accepted inside suite_config; top-level is reconciled down)
-	Suite           string              `yaml:"suite,omitempty"`        // For type: drill — which suite this belongs to
-	SuiteConfig     *DrillSuiteConfig   `yaml:"suite_config,omitempty"` // For type: drill_suite — infrastructure config
-	DrillConfig     *DrillConfig        `yaml:"drill_config,omitempty"` // For type: drill — drill-specific config
-	Parameters      []map[string]string `yaml:"parameters,omitempty"`   // Parameter sets for data-driven tests (each map is one test run)
-	Nodes           []Node              `yaml:"nodes"`
-	Flow            []FlowItem          `yaml:"flow"`
-	MCPDependencies []MCPDependency     `yaml:"mcp_dependencies,omitempty"`
+	Description         string                  `yaml:"description"`
+	Type                string                  `yaml:"type,omitempty"`         // "drill", "drill_suite" (legacy: "test", "test_suite"), or empty for regular flows
+	Template            string                  `yaml:"template,omitempty"`     // Sandbox template (also accepted inside suite_config; top-level is reconciled down)
+	Suite               string                  `yaml:"suite,omitempty"`        // For type: drill — which suite this belongs to
+	SuiteConfig         *DrillSuiteConfig       `yaml:"suite_config,omitempty"` // For type: drill_suite — infrastructure config
+	DrillConfig         *DrillConfig            `yaml:"drill_config,omitempty"` // For type: drill — drill-specific config
+	Parameters          []map[string]string     `yaml:"parameters,omitempty"`   // Parameter sets for data-driven tests (each map is one test run)
+	Nodes               []Node                  `yaml:"nodes"`
+	Flow                []FlowItem              `yaml:"flow"`
+	MCPDependencies     []MCPDependency         `yaml:"mcp_dependencies,omitempty"`
+	ModelFallbacks      []string                `yaml:"model_fallbacks,omitempty"`        // Models tried in order when the provider fails (e.g. openrouter/gpt-4o)
+	Glossary            map[string]string       `yaml:"glossary,omitempty"`               // Domain terms → definitions, appended to the system prompt of LLM nodes
+	MaxToolOutputTokens int                     `yaml:"max_tool_output_tokens,omitempty"` // Default limit for the tool results LLM nodes pass to the model
+	ContextBudget       int                     `yaml:"context_budget,omitempty"`         // Token budget for the history LLM nodes send; older events are summarized beyond it (default: half the context window, -1 = off)
+	Variables           map[string]FlowVariable `yaml:"variables,omitempty"`              // Typed flow inputs placed in state at START; set with --var
+	ExportState         string                  `yaml:"export_state,omitempty"`           // JSON file the final state is written to when the flow reaches END
+	ChatMode            bool                    `yaml:"chat_mode,omitempty"`              // Loop back for another user message at END instead of finishing
+	LoopTo              string                  `yaml:"loop_to,omitempty"`                // Node each chat turn starts at (default: the first node); implies chat_mode
+	Defaults            *NodeDefaults           `yaml:"defaults,omitempty"`               // Settings every node inherits unless it sets them (system prefix, tools_auto_approval, max_retries, generation)
+	Guardrails          *GuardrailsConfig       `yaml:"guardrails,omitempty"`             // Checks on the output of every LLM node (nodes can set their own)
+	OnError             string                  `yaml:"on_error,omitempty"`               // Node that handles failures of nodes without their own on_error
+	MaxNodeVisits       int                     `yaml:"max_node_visits,omitempty"`        // Times a node may run without an input wait before the run stops as a loop (default: 100, -1 = off)
+	Tests               []FlowTest              `yaml:"tests,omitempty"`                  // Unit tests run by `astonish test` with canned model responses
+	Shell               *FlowShellConfig        `yaml:"shell,omitempty"`                  // Container image and further denied commands for this flow's shell_command calls
+	Hooks               []HookConfig            `yaml:"hooks,omitempty"`                  // Webhooks told about the run's lifecycle events (commands only in the app config)
+
+	// SchemaWarnings are the problems the flow schema finds in a flow that
+	// loads anyway, mostly unknown fields, which are usually typos. Set by
+	// LoadAgent and LoadAgentFromBytes; runners show or log them as
+	// warnings and run the flow.
+	SchemaWarnings []SchemaDiagnostic `yaml:"-" json:"-"`
 }
 
 // agentConfigRaw is the intermediate struct used for backward-compatible YAML parsing.
 // It supports both old (test_config) and new (drill_config) YAML tags.
 type agentConfigRaw struct {
-	Description     string              `yaml:"description"`
-	Type            string              `yaml:"type,omitempty"`
-	Template        string              `yaml:"template,omitempty"`
-	Suite           string              `yaml:"suite,omitempty"`
-	SuiteConfig     *DrillSuiteConfig   `yaml:"suite_config,omitempty"`
-	DrillConfig     *DrillConfig        `yaml:"drill_config,omitempty"`
-	TestConfig      *DrillConfig        `yaml:"test_config,omitempty"` // backward compat
-	Parameters      []map[string]string `yaml:"parameters,omitempty"`
-	Nodes           []Node              `yaml:"nodes"`
-	Flow            []FlowItem          `yaml:"flow"`
-	MCPDependencies []MCPDependency     `yaml:"mcp_dependencies,omitempty"`
+	Description         string                  `yaml:"description"`
+	Type                string                  `yaml:"type,omitempty"`
+	Template            string                  `yaml:"template,omitempty"`
+	Suite               string                  `yaml:"suite,omitempty"`
+	SuiteConfig         *DrillSuiteConfig       `yaml:"suite_config,omitempty"`
+	DrillConfig         *DrillConfig            `yaml:"drill_config,omitempty"`
+	TestConfig          *DrillConfig            `yaml:"test_config,omitempty"` // backward compat
+	Parameters          []map[string]string     `yaml:"parameters,omitempty"`
+	Nodes               []Node                  `yaml:"nodes"`
+	Flow                []FlowItem              `yaml:"flow"`
+	MCPDependencies     []MCPDependency         `yaml:"mcp_dependencies,omitempty"`
+	ModelFallbacks      []string                `yaml:"model_fallbacks,omitempty"`
+	Glossary            map[string]string       `yaml:"glossary,omitempty"`
+	MaxToolOutputTokens int                     `yaml:"max_tool_output_tokens,omitempty"`
+	ContextBudget       int                     `yaml:"context_budget,omitempty"`
+	Variables           map[string]FlowVariable `yaml:"variables,omitempty"`
+	ExportState         string                  `yaml:"export_state,omitempty"`
+	ChatMode            bool                    `yaml:"chat_mode,omitempty"`
+	LoopTo              string                  `yaml:"loop_to,omitempty"`
+	Defaults            *NodeDefaults           `yaml:"defaults,omitempty"`
+	Guardrails          *GuardrailsConfig       `yaml:"guardrails,omitempty"`
+	OnError             string                  `yaml:"on_error,omitempty"`
+	MaxNodeVisits       int                     `yaml:"max_node_visits,omitempty"`
+	Tests               []FlowTest              `yaml:"tests,omitempty"`
+	Shell               *FlowShellConfig        `yaml:"shell,omitempty"`
+	Hooks               []HookConfig            `yaml:"hooks,omitempty"`
 }
 
 // UnmarshalYAML implements custom unmarshaling for AgentConfig to support
@@ -57,7 +93,28 @@ func (c *AgentConfig) UnmarshalYAML(value *yaml.Node) error {
 	c.Parameters = raw.Parameters
 	c.Nodes = raw.Nodes
 	c.Flow = raw.Flow
+	for i := range c.Nodes {
+		if n := &c.Nodes[i]; len(n.OutputSchema) > 0 && len(n.OutputModel) == 0 {
+			n.OutputModel = OutputModelFromSchema(n.OutputSchema)
+		}
+	}
 	c.MCPDependencies = raw.MCPDependencies
+	c.ModelFallbacks = raw.ModelFallbacks
+	c.Glossary = raw.Glossary
+	c.MaxToolOutputTokens = raw.MaxToolOutputTokens
+	c.ContextBudget = raw.ContextBudget
+	c.Variables = raw.Variables
+	c.ExportState = raw.ExportState
+	c.ChatMode = raw.ChatMode
+	c.LoopTo = raw.LoopTo
+	c.Defaults = raw.Defaults
+	c.Guardrails = raw.Guardrails
+	c.OnError = raw.OnError
+	c.MaxNodeVisits = raw.MaxNodeVisits
+	c.Tests = raw.Tests
+	c.Shell = raw.Shell
+	c.Hooks = raw.Hooks
+	applyNodeDefaults(c.Nodes, c.Defaults, nodeKeys(value))
 
 	// drill_config takes precedence; fall back to test_config for backward compat
 	if raw.DrillConfig != nil {
@@ -83,6 +140,25 @@ func (c *AgentConfig) UnmarshalYAML(value *yaml.Node) error {
 	return nil
 }
 
+// ErrorHandlerFor returns the node a failed node continues at: its own
+// on_error, else the flow's. A flow-level handler does not handle its own
+// failures. "" means the run ends.
+func (c *AgentConfig) ErrorHandlerFor(node *Node) string {
+	if node != nil && node.OnError != "" {
+		return node.OnError
+	}
+	if c == nil || node == nil || node.Name == c.OnError {
+		return ""
+	}
+	return c.OnError
+}
+
+// ChatEnabled reports whether the flow runs as a multi-turn chat: at END it
+// waits for the next user message and starts over at the loop_to node.
+func (c *AgentConfig) ChatEnabled() bool {
+	return c != nil && (c.ChatMode || c.LoopTo != "")
+}
+
 // DrillSuiteConfig defines infrastructure for running drills.
 // Used by type: drill_suite flows.
 //
@@ -203,42 +279,223 @@ type MCPDependency struct {
 
 // Node represents a single step in the agent's execution.
 type Node struct {
-	Name              string                 `yaml:"name" json:"name"`
-	Type              string                 `yaml:"type" json:"type"` // "input", "llm", "tool"
-	Prompt            string                 `yaml:"prompt,omitempty" json:"prompt,omitempty"`
-	System            string                 `yaml:"system,omitempty" json:"system,omitempty"`
-	RawContext        string                 `yaml:"raw_context,omitempty" json:"raw_context,omitempty"` // Verbatim context appended to system instruction (no state interpolation)
-	OutputModel       map[string]string      `yaml:"output_model,omitempty" json:"output_model,omitempty"`
-	Tools             bool                   `yaml:"tools,omitempty" json:"tools,omitempty"`
-	ToolsSelection    []string               `yaml:"tools_selection,omitempty" json:"tools_selection,omitempty"`
-	Options           []string               `yaml:"options,omitempty" json:"options,omitempty"`
-	UserMessage       []string               `yaml:"user_message,omitempty" json:"user_message,omitempty"`
-	Args              map[string]interface{} `yaml:"args,omitempty" json:"args,omitempty"`
-	RawToolOutput     map[string]string      `yaml:"raw_tool_output,omitempty" json:"raw_tool_output,omitempty"`
-	ToolsAutoApproval bool                   `yaml:"tools_auto_approval,omitempty" json:"tools_auto_approval,omitempty"`
-	ContinueOnError   bool                   `yaml:"continue_on_error,omitempty" json:"continue_on_error,omitempty"`
-	Updates           map[string]string      `yaml:"updates,omitempty" json:"updates,omitempty"`
-	Action            string                 `yaml:"action,omitempty" json:"action,omitempty"`
-	Value             interface{}            `yaml:"value,omitempty" json:"value,omitempty"`
-	SourceVariable    string                 `yaml:"source_variable,omitempty" json:"source_variable,omitempty"`
-	Parallel          *ParallelConfig        `yaml:"parallel,omitempty" json:"parallel,omitempty"`
-	OutputAction      string                 `yaml:"output_action,omitempty" json:"output_action,omitempty"`   // "append" or other aggregation strategies
-	MaxRetries        int                    `yaml:"max_retries,omitempty" json:"max_retries,omitempty"`       // Maximum retry attempts (default: 3)
-	RetryStrategy     string                 `yaml:"retry_strategy,omitempty" json:"retry_strategy,omitempty"` // "intelligent" or "simple" (default: intelligent)
-	Silent            bool                   `yaml:"silent,omitempty" json:"silent,omitempty"`                 // If true, node execution is not shown in UI/CLI
-	Assert            *AssertConfig          `yaml:"assert,omitempty" json:"assert,omitempty"`                 // Assertion for drill flows (Spec 17)
+	Name                string                 `yaml:"name" json:"name"`
+	Type                string                 `yaml:"type" json:"type"` // "input", "llm", "tool", "script"
+	Prompt              string                 `yaml:"prompt,omitempty" json:"prompt,omitempty"`
+	System              string                 `yaml:"system,omitempty" json:"system,omitempty"`
+	Provider            string                 `yaml:"provider,omitempty" json:"provider,omitempty"`       // Provider instance for this node (default: the flow's provider)
+	Model               string                 `yaml:"model,omitempty" json:"model,omitempty"`             // Model for this node (default: the flow's model)
+	Generation          *GenerationConfig      `yaml:"generation,omitempty" json:"generation,omitempty"`   // Sampling parameters for LLM nodes
+	RawContext          string                 `yaml:"raw_context,omitempty" json:"raw_context,omitempty"` // Verbatim context appended to system instruction (no state interpolation)
+	Templating          string                 `yaml:"templating,omitempty" json:"templating,omitempty"`   // "basic" (default) or "rich": filters, {% if %} and {% for %} in prompt, system, args and user_message
+	OutputModel         map[string]string      `yaml:"output_model,omitempty" json:"output_model,omitempty"`
+	OutputSchema        map[string]any         `yaml:"output_schema,omitempty" json:"output_schema,omitempty"` // JSON Schema for the node's output object; its top-level properties become state keys
+	Sensitive           []string               `yaml:"sensitive,omitempty" json:"sensitive,omitempty"`         // State keys encrypted at rest and masked in output (output_model entries with sensitive: true)
+	Tools               bool                   `yaml:"tools,omitempty" json:"tools,omitempty"`
+	ToolsSelection      []string               `yaml:"tools_selection,omitempty" json:"tools_selection,omitempty"`
+	Options             []string               `yaml:"options,omitempty" json:"options,omitempty"`
+	MultiSelect         bool                   `yaml:"multi_select,omitempty" json:"multi_select,omitempty"` // Input nodes with options: several options can be chosen; the answer is stored as a list
+	InputType           string                 `yaml:"input_type,omitempty" json:"input_type,omitempty"`     // Input nodes: "text" (default), "number", "boolean", "regex", "multiline" or "secret"
+	Default             string                 `yaml:"default,omitempty" json:"default,omitempty"`           // Input nodes: answer used when the user enters nothing (templated from state)
+	Validation          *InputValidation       `yaml:"validation,omitempty" json:"validation,omitempty"`     // Input nodes: the question is asked again until the answer passes
+	InputMode           string                 `yaml:"input_mode,omitempty" json:"input_mode,omitempty"`     // Input nodes: "text" (default) or "file" to pick files; their paths are stored
+	FileRoot            string                 `yaml:"file_root,omitempty" json:"file_root,omitempty"`       // input_mode file: directory to pick from (templated; default the working directory)
+	FilePattern         string                 `yaml:"file_pattern,omitempty" json:"file_pattern,omitempty"` // input_mode file: glob the file names must match, e.g. "*.md"
+	UserMessage         []string               `yaml:"user_message,omitempty" json:"user_message,omitempty"`
+	Args                map[string]interface{} `yaml:"args,omitempty" json:"args,omitempty"`
+	RawToolOutput       map[string]string      `yaml:"raw_tool_output,omitempty" json:"raw_tool_output,omitempty"`
+	ToolsAutoApproval   bool                   `yaml:"tools_auto_approval,omitempty" json:"tools_auto_approval,omitempty"`
+	OnToolDenied        string                 `yaml:"on_tool_denied,omitempty" json:"on_tool_denied,omitempty"`                 // "skip" (default) moves on when the user denies a tool call, "feedback" tells the model so it can try another way
+	MaxParallelTools    int                    `yaml:"max_parallel_tools,omitempty" json:"max_parallel_tools,omitempty"`         // Tool calls of one LLM turn run at once (default: 4, 1 runs them one by one)
+	MaxToolOutputTokens int                    `yaml:"max_tool_output_tokens,omitempty" json:"max_tool_output_tokens,omitempty"` // Larger tool results are cut down before the model sees them (default: flow setting, 0 = no limit)
+	ContextBudget       int                    `yaml:"context_budget,omitempty" json:"context_budget,omitempty"`                 // Token budget for the node's history (default: flow setting, -1 = off)
+	ToolOutputOverflow  string                 `yaml:"tool_output_overflow,omitempty" json:"tool_output_overflow,omitempty"`     // "truncate" (default) or "summarize" results above max_tool_output_tokens
+	CacheToolResults    string                 `yaml:"cache_tool_results,omitempty" json:"cache_tool_results,omitempty"`         // Reuse results of identical tool calls for this Go duration, e.g. 10m (default: off)
+	CacheResponse       bool                   `yaml:"cache_response,omitempty" json:"cache_response,omitempty"`                 // LLM nodes without tools at temperature 0: reuse the response to an identical request (--no-cache skips it)
+	Env                 map[string]string      `yaml:"env,omitempty" json:"env,omitempty"`                                       // Environment of the MCP servers and shell commands of this node's tools (templated from state)
+	ContinueOnError     bool                   `yaml:"continue_on_error,omitempty" json:"continue_on_error,omitempty"`
+	OnError             string                 `yaml:"on_error,omitempty" json:"on_error,omitempty"` // Node the flow continues at when this node fails (default: the flow's on_error, else the run ends)
+	Updates             map[string]string      `yaml:"updates,omitempty" json:"updates,omitempty"`
+	Action              string                 `yaml:"action,omitempty" json:"action,omitempty"`
+	Value               interface{}            `yaml:"value,omitempty" json:"value,omitempty"`
+	SourceVariable      string                 `yaml:"source_variable,omitempty" json:"source_variable,omitempty"`
+	Script              string                 `yaml:"script,omitempty" json:"script,omitempty"` // Starlark program of a script node
+	Parallel            *ParallelConfig        `yaml:"parallel,omitempty" json:"parallel,omitempty"`
+	OutputAction        string                 `yaml:"output_action,omitempty" json:"output_action,omitempty"`         // "append" or other aggregation strategies
+	Report              *ReportConfig          `yaml:"report,omitempty" json:"report,omitempty"`                       // Output nodes: also render the message as an HTML report
+	Artifact            string                 `yaml:"artifact,omitempty" json:"artifact,omitempty"`                   // Output nodes: also save the message as an artifact with this file name (template)
+	Diff                *DiffPreview           `yaml:"diff,omitempty" json:"diff,omitempty"`                           // Output nodes: show the diff between two texts in state
+	Confirm             string                 `yaml:"confirm,omitempty" json:"confirm,omitempty"`                     // Output nodes: ask Accept/Reject after the message and store true or false under this state key
+	Message             string                 `yaml:"message,omitempty" json:"message,omitempty"`                     // Abort nodes: why the run stops (templated from state)
+	ExitCode            int                    `yaml:"exit_code,omitempty" json:"exit_code,omitempty"`                 // Abort nodes: exit code of 'flows run' (default: 1)
+	Memory              *MemoryNodeConfig      `yaml:"memory,omitempty" json:"memory,omitempty"`                       // Memory nodes: store or recall long-term facts
+	Vector              *VectorNodeConfig      `yaml:"vector,omitempty" json:"vector,omitempty"`                       // Vector nodes: index text into or search a vector store collection
+	MaxRetries          int                    `yaml:"max_retries,omitempty" json:"max_retries,omitempty"`             // Maximum retry attempts (default: 3)
+	MaxToolCalls        int                    `yaml:"max_tool_calls,omitempty" json:"max_tool_calls,omitempty"`       // LLM nodes: tool calls per attempt before the node fails (default: 20)
+	RetryStrategy       string                 `yaml:"retry_strategy,omitempty" json:"retry_strategy,omitempty"`       // "intelligent" or "simple" (default: intelligent)
+	RetryBackoff        string                 `yaml:"retry_backoff,omitempty" json:"retry_backoff,omitempty"`         // "exponential", "constant" or "none" (default: exponential)
+	InitialDelay        string                 `yaml:"initial_delay,omitempty" json:"initial_delay,omitempty"`         // Wait before the first retry as a Go duration (default: 2s)
+	MaxDelay            string                 `yaml:"max_delay,omitempty" json:"max_delay,omitempty"`                 // Upper bound for retry waits (default: 30s)
+	ModelFallbacks      []string               `yaml:"model_fallbacks,omitempty" json:"model_fallbacks,omitempty"`     // Overrides the flow-level failover chain for this node
+	OnContentFilter     *ContentFilterConfig   `yaml:"on_content_filter,omitempty" json:"on_content_filter,omitempty"` // Fallback when the provider's safety filter blocks the response
+	Guardrails          *GuardrailsConfig      `yaml:"guardrails,omitempty" json:"guardrails,omitempty"`               // Checks on the node's output before it is stored or shown (replaces the flow's)
+	Glossary            *bool                  `yaml:"glossary,omitempty" json:"glossary,omitempty"`                   // false keeps the flow glossary out of this node's system prompt
+	GlossaryTerms       []string               `yaml:"glossary_terms,omitempty" json:"glossary_terms,omitempty"`       // Only these glossary terms are injected (default: all)
+	Silent              bool                   `yaml:"silent,omitempty" json:"silent,omitempty"`                       // If true, node execution is not shown in UI/CLI
+	Display             string                 `yaml:"display,omitempty" json:"display,omitempty"`                     // "none", "user_message", "stream" or "summary" (default: derived, see DisplayMode)
+	Preview             bool                   `yaml:"preview,omitempty" json:"preview,omitempty"`                     // LLM nodes whose text is not streamed: show a dimmed live preview of it in the console
+	RetryOnEmpty        *RetryOnEmptyConfig    `yaml:"retry_on_empty,omitempty" json:"retry_on_empty,omitempty"`       // Tool nodes: retry when the tool returns an empty result
+	Assert              *AssertConfig          `yaml:"assert,omitempty" json:"assert,omitempty"`                       // Assertion for drill flows (Spec 17)
 	// Tutorial / scene fields (used when drill_config.mode is "tutorial")
 	Narration string `yaml:"narration,omitempty" json:"narration,omitempty"` // Spoken script for this beat
 	HoldMs    int    `yaml:"hold_ms,omitempty" json:"hold_ms,omitempty"`     // Pause after the tool succeeds (pacing)
 	Record    string `yaml:"record,omitempty" json:"record,omitempty"`       // "", "start", "stop", or "segment"
 }
 
+// OutputModelFromSchema derives the flat output_model (state key → type)
+// from the top-level properties of an output_schema, so nodes declaring only
+// a schema still distribute their output to state keys.
+func OutputModelFromSchema(schema map[string]any) map[string]string {
+	props, _ := schema["properties"].(map[string]any)
+	model := make(map[string]string, len(props))
+	for key, p := range props {
+		typeName := "any"
+		if prop, ok := p.(map[string]any); ok {
+			switch prop["type"] {
+			case "string":
+				typeName = "str"
+			case "integer":
+				typeName = "int"
+			case "number":
+				typeName = "float"
+			case "boolean":
+				typeName = "bool"
+			case "array":
+				typeName = "list"
+			case "object":
+				typeName = "dict"
+			}
+		}
+		model[key] = typeName
+	}
+	return model
+}
+
+// Display modes control what a node shows to the user while it runs.
+const (
+	DisplayNone        = "none"         // Nothing from the node's LLM text is shown
+	DisplayUserMessage = "user_message" // Only the state keys listed in user_message are shown
+	DisplayStream      = "stream"       // LLM text is streamed as it is generated
+	DisplaySummary     = "summary"      // A compact summary of output_model values is shown when the node finishes
+)
+
+// DisplayMode returns the node's effective display mode. When display is not
+// set it follows the historical rules: user_message shows the selected keys,
+// output_model hides the raw JSON, and everything else streams.
+func (n *Node) DisplayMode() string {
+	if n.Display != "" {
+		return n.Display
+	}
+	if len(n.UserMessage) > 0 {
+		return DisplayUserMessage
+	}
+	if len(n.OutputModel) > 0 {
+		return DisplayNone
+	}
+	return DisplayStream
+}
+
+// FlowShellConfig is a flow's addition to the user's security.shell
+// settings. A flow can only narrow them: it picks the container its
+// commands run in and denies further commands.
+type FlowShellConfig struct {
+	Container    *ShellContainerConfig `yaml:"container,omitempty" json:"container,omitempty"`         // Image of the commands; a configured security.shell.container keeps its runtime and network
+	DenyCommands []string              `yaml:"deny_commands,omitempty" json:"deny_commands,omitempty"` // Regexes refused in addition to security.shell.deny_commands
+}
+
+// HookConfig registers a webhook or a local command for lifecycle events
+// of flow runs (run_start, node_enter, tool_denied, ...). The event goes to
+// the URL as a JSON POST, or to the command's stdin. Only the app config
+// may register commands.
+type HookConfig struct {
+	Events  []string          `yaml:"events,omitempty" json:"events,omitempty"`   // Events the hook receives (default: all)
+	URL     string            `yaml:"url,omitempty" json:"url,omitempty"`         // Webhook the event is posted to
+	Command string            `yaml:"command,omitempty" json:"command,omitempty"` // Shell command run with the event on stdin
+	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"` // Extra headers of webhook requests; $VAR is read from the environment in the app config
+}
+
+// Reactions of an LLM node to a denied tool call (on_tool_denied).
+const (
+	ToolDeniedSkip     = "skip"     // The flow continues at the next node
+	ToolDeniedFeedback = "feedback" // The node continues, told the call was denied
+)
+
+// GenerationConfig holds the sampling parameters of an LLM node. Unset
+// fields keep the provider's defaults.
+type GenerationConfig struct {
+	Temperature     *float32 `yaml:"temperature,omitempty" json:"temperature,omitempty"`
+	TopP            *float32 `yaml:"top_p,omitempty" json:"top_p,omitempty"`
+	MaxOutputTokens int32    `yaml:"max_output_tokens,omitempty" json:"max_output_tokens,omitempty"`
+	StopSequences   []string `yaml:"stop_sequences,omitempty" json:"stop_sequences,omitempty"`
+}
+
+// ContentFilterConfig is an LLM node's fallback when the provider's safety
+// filter blocks a response. Without it the node fails without retrying.
+type ContentFilterConfig struct {
+	Action      string `yaml:"action" json:"action"`                               // "rephrase", "switch_model" or "route"
+	Instruction string `yaml:"instruction,omitempty" json:"instruction,omitempty"` // rephrase: instruction added to the system prompt (default: built-in)
+	Model       string `yaml:"model,omitempty" json:"model,omitempty"`             // switch_model: "provider/model", or a model on the flow's provider
+	Node        string `yaml:"node,omitempty" json:"node,omitempty"`               // route: node to continue at, e.g. a human-review input node
+}
+
+// RetryOnEmptyConfig defines how a tool node retries an empty tool result.
+// Alternate tools are tried in order once attempts are exhausted; they run
+// with the node's args and only when the node's tools are auto-approved.
+type RetryOnEmptyConfig struct {
+	Attempts       int      `yaml:"attempts,omitempty" json:"attempts,omitempty"`               // Extra attempts with the same tool (default: 2)
+	Delay          string   `yaml:"delay,omitempty" json:"delay,omitempty"`                     // Wait between attempts as a Go duration (default: 1s)
+	AlternateTools []string `yaml:"alternate_tools,omitempty" json:"alternate_tools,omitempty"` // Fallback tools, tried in order
+}
+
+// MemoryNodeConfig configures a memory node: it stores a fact in long-term
+// memory or recalls the facts that match a query into state. Facts are kept
+// per flow or per user and persist across runs.
+type MemoryNodeConfig struct {
+	Action  string `yaml:"action" json:"action"`                       // "store" or "recall"
+	Scope   string `yaml:"scope,omitempty" json:"scope,omitempty"`     // "flow" (default) or "user"
+	Key     string `yaml:"key,omitempty" json:"key,omitempty"`         // Optional sub-namespace within the scope, e.g. "{repo}"
+	Content string `yaml:"content,omitempty" json:"content,omitempty"` // store: the fact to remember (template)
+	Query   string `yaml:"query,omitempty" json:"query,omitempty"`     // recall: what to look for (template, empty = most recent)
+	Limit   int    `yaml:"limit,omitempty" json:"limit,omitempty"`     // recall: maximum facts returned (default: 5)
+}
+
+// VectorNodeConfig configures a vector node: it embeds text into a vector
+// store collection, or searches a collection for the chunks most similar to
+// a query, for retrieval-augmented generation.
+type VectorNodeConfig struct {
+	Action         string  `yaml:"action" json:"action"`                                       // "index" or "search"
+	Collection     string  `yaml:"collection,omitempty" json:"collection,omitempty"`           // Collection name (template, default: the flow name)
+	Text           string  `yaml:"text,omitempty" json:"text,omitempty"`                       // index: text to embed (template)
+	SourceVariable string  `yaml:"source_variable,omitempty" json:"source_variable,omitempty"` // index: state key holding a string or a list of texts/documents
+	Source         string  `yaml:"source,omitempty" json:"source,omitempty"`                   // index: name of the text in results (template, default: node name)
+	Query          string  `yaml:"query,omitempty" json:"query,omitempty"`                     // search: what to look for (template)
+	Limit          int     `yaml:"limit,omitempty" json:"limit,omitempty"`                     // search: maximum chunks returned (default: memory.search.max_results)
+	MinScore       float64 `yaml:"min_score,omitempty" json:"min_score,omitempty"`             // search: minimum similarity (default: memory.search.min_score)
+}
+
 // ParallelConfig defines configuration for parallel execution.
 type ParallelConfig struct {
-	ForEach        string `yaml:"forEach"`
-	As             string `yaml:"as"`
-	IndexAs        string `yaml:"index_as,omitempty"`
-	MaxConcurrency int    `yaml:"maxConcurrency,omitempty"`
+	ForEach        string  `yaml:"forEach"`
+	As             string  `yaml:"as"`
+	IndexAs        string  `yaml:"index_as,omitempty"`
+	KeyAs          string  `yaml:"key_as,omitempty"`   // Maps: variable holding the entry's key
+	ValueAs        string  `yaml:"value_as,omitempty"` // Maps: variable holding the entry's value (default: as)
+	MaxConcurrency int     `yaml:"maxConcurrency,omitempty"`
+	RateLimit      float64 `yaml:"rate_limit,omitempty"`     // Max LLM/tool calls per second across all branches (0 = unlimited)
+	Adaptive       bool    `yaml:"adaptive,omitempty"`       // Back off and retry when the provider returns 429
+	StreamResults  bool    `yaml:"stream_results,omitempty"` // Emit an event as each item completes
+	MaxItems       int     `yaml:"max_items,omitempty"`      // Process lists longer than this in chunks of this size (0 = no chunking)
+	Reduce         string  `yaml:"reduce,omitempty"`         // Instructions for merging results; each chunk is reduced, then the chunk results
 }
 
 // FlowItem represents a transition in the flow.
@@ -267,19 +524,57 @@ func LoadAgent(path string) (*AgentConfig, error) {
 		return nil, fmt.Errorf("invalid agent path: %w", err)
 	}
 
-	data, err := os.ReadFile(absPath)
+	// Values tagged !include are replaced by the content of the file they
+	// name, relative to the including file
+	r := &includeResolver{}
+	doc, err := r.load(absPath)
 	if err != nil {
 		return nil, err
 	}
 
-	return LoadAgentFromBytes(data)
+	var config AgentConfig
+	if doc != nil {
+		diags := r.validate(doc)
+		if err := doc.Decode(&config); err != nil {
+			// The schema check points at each offending value
+			if schemaErr := schemaError(diags); schemaErr != nil {
+				return nil, schemaErr
+			}
+			return nil, r.decodeError(err)
+		}
+		config.SchemaWarnings = diags
+	}
+	return &config, nil
 }
 
-// LoadAgentFromBytes parses an AgentConfig from raw YAML bytes.
+// LoadAgentFromBytes parses an AgentConfig from raw YAML bytes. They have
+// no file to resolve !include paths against, so includes are rejected.
 func LoadAgentFromBytes(data []byte) (*AgentConfig, error) {
+	if bytes.Contains(data, []byte(includeTag)) {
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		if inc := findInclude(&doc); inc != nil {
+			return nil, fmt.Errorf("line %d: %s %s: includes are only resolved in flow files loaded from disk", inc.Line, includeTag, inc.Value)
+		}
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
 	var config AgentConfig
-	if err := yaml.Unmarshal(data, &config); err != nil {
+	if len(doc.Content) == 0 {
+		return &config, nil
+	}
+	diags := (&includeResolver{}).validate(&doc)
+	if err := doc.Decode(&config); err != nil {
+		if schemaErr := schemaError(diags); schemaErr != nil {
+			return nil, schemaErr
+		}
 		return nil, err
 	}
+	config.SchemaWarnings = diags
 	return &config, nil
 }
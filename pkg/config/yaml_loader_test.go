@@ -1,6 +1,7 @@
 package config
 
 import (
+	"strings"
 	"testing"
 
 	"gopkg.in/yaml.v3"
@@ -83,6 +84,72 @@ tools_selection:
 	}
 }
 
+// TestNodeDisplayMode verifies explicit display values and the implicit defaults
+func TestNodeDisplayMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		yaml     string
+		expected string
+	}{
+		{
+			name: "plain llm node streams",
+			yaml: `
+name: chat
+type: llm
+prompt: "Hello"
+`,
+			expected: DisplayStream,
+		},
+		{
+			name: "output_model hides text",
+			yaml: `
+name: classify
+type: llm
+output_model:
+  label: str
+`,
+			expected: DisplayNone,
+		},
+		{
+			name: "user_message shows selected keys",
+			yaml: `
+name: classify
+type: llm
+output_model:
+  label: str
+user_message:
+  - label
+`,
+			expected: DisplayUserMessage,
+		},
+		{
+			name: "explicit display overrides defaults",
+			yaml: `
+name: classify
+type: llm
+display: summary
+output_model:
+  label: str
+user_message:
+  - label
+`,
+			expected: DisplaySummary,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var node Node
+			if err := yaml.Unmarshal([]byte(tt.yaml), &node); err != nil {
+				t.Fatalf("failed to unmarshal YAML: %v", err)
+			}
+			if got := node.DisplayMode(); got != tt.expected {
+				t.Errorf("DisplayMode() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
 func TestAgentConfigTestSuiteParsing(t *testing.T) {
 	input := `
 description: "MyApp Integration Tests"
@@ -551,3 +618,76 @@ timeout: 20
 		t.Errorf("Pattern = %q, want %q", rc.Pattern, "Server listening on")
 	}
 }
+
+// TestSensitiveOutputModelParsing verifies the long output_model form that
+// marks keys sensitive
+func TestSensitiveOutputModelParsing(t *testing.T) {
+	data := `
+description: token flow
+nodes:
+  - name: ask_token
+    type: input
+    prompt: Paste your API token
+    output_model:
+      api_token:
+        type: str
+        sensitive: true
+      team: str
+flow:
+  - from: START
+    to: ask_token
+`
+	cfg, err := LoadAgentFromBytes([]byte(data))
+	if err != nil {
+		t.Fatalf("LoadAgentFromBytes() error = %v", err)
+	}
+	node := cfg.Nodes[0]
+	if node.OutputModel["api_token"] != "str" || node.OutputModel["team"] != "str" {
+		t.Errorf("OutputModel = %v, want plain types", node.OutputModel)
+	}
+	if len(node.Sensitive) != 1 || node.Sensitive[0] != "api_token" {
+		t.Errorf("Sensitive = %v, want [api_token]", node.Sensitive)
+	}
+	if keys := cfg.SensitiveKeys(); !keys["api_token"] || keys["team"] {
+		t.Errorf("SensitiveKeys() = %v", keys)
+	}
+}
+
+func TestGlossaryFor(t *testing.T) {
+	data := `
+description: release flow
+glossary:
+  Falcon: the internal codename of the billing service
+  RC: a release candidate build
+nodes:
+  - name: plan
+    type: llm
+    prompt: Plan the release
+  - name: notes
+    type: llm
+    prompt: Write the notes
+    glossary_terms: [RC]
+  - name: chat
+    type: llm
+    prompt: Say hi
+    glossary: false
+flow:
+  - from: START
+    to: plan
+`
+	cfg, err := LoadAgentFromBytes([]byte(data))
+	if err != nil {
+		t.Fatalf("LoadAgentFromBytes() error = %v", err)
+	}
+
+	all := cfg.GlossaryFor(&cfg.Nodes[0])
+	if !strings.Contains(all, "- Falcon: the internal codename") || strings.Index(all, "Falcon") > strings.Index(all, "- RC:") {
+		t.Errorf("GlossaryFor(plan) = %q, want both terms sorted", all)
+	}
+	if got := cfg.GlossaryFor(&cfg.Nodes[1]); strings.Contains(got, "Falcon") || !strings.Contains(got, "- RC: a release candidate build") {
+		t.Errorf("GlossaryFor(notes) = %q, want only RC", got)
+	}
+	if got := cfg.GlossaryFor(&cfg.Nodes[2]); got != "" {
+		t.Errorf("GlossaryFor(chat) = %q, want none", got)
+	}
+}
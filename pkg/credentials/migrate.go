@@ -12,6 +12,8 @@ import (
 // here — non-sensitive config like base_url and resource_group stays in config.yaml.
 var secretKeyMapping = map[string][]string{
 	"anthropic":     {"api_key"},
+	"azure":         {"api_key", "ad_token"},
+	"bedrock":       {"access_key_id", "secret_access_key", "session_token", "api_key"},
 	"gemini":        {"api_key"},
 	"openai":        {"api_key"},
 	"openrouter":    {"api_key"},
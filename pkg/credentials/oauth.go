@@ -127,7 +127,10 @@ func FetchOAuthToken(cred *Credential) (token string, expiresIn int, err error)
 		return "", 0, fmt.Errorf("build token request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.SetBasicAuth(cred.ClientID, cred.ClientSecret)
+	req.Header.Set("Accept", "application/json")
+	if cred.ClientSecret != "" {
+		req.SetBasicAuth(cred.ClientID, cred.ClientSecret)
+	}
 
 	client := &http.Client{Timeout: oauthTimeout}
 	resp, err := client.Do(req)
@@ -188,21 +191,26 @@ func refreshAuthCodeToken(cred *Credential) (accessToken, newRefreshToken string
 	if cred.ClientID == "" {
 		return "", "", 0, fmt.Errorf("client_id is required for oauth_authorization_code credentials")
 	}
-	if cred.ClientSecret == "" {
-		return "", "", 0, fmt.Errorf("client_secret is required for oauth_authorization_code credentials")
-	}
 
 	form := url.Values{
 		"grant_type":    {"refresh_token"},
 		"refresh_token": {cred.RefreshToken},
 	}
+	// Public clients (e.g. device flow apps) have no secret and identify
+	// themselves with client_id in the form instead
+	if cred.ClientSecret == "" {
+		form.Set("client_id", cred.ClientID)
+	}
 
 	req, err := http.NewRequest("POST", cred.TokenURL, strings.NewReader(form.Encode()))
 	if err != nil {
 		return "", "", 0, fmt.Errorf("build refresh token request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.SetBasicAuth(cred.ClientID, cred.ClientSecret)
+	req.Header.Set("Accept", "application/json")
+	if cred.ClientSecret != "" {
+		req.SetBasicAuth(cred.ClientID, cred.ClientSecret)
+	}
 
 	client := &http.Client{Timeout: oauthTimeout}
 	resp, err := client.Do(req)
@@ -1,7 +1,9 @@
 package credentials
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/base64"
 	"encoding/hex"
@@ -379,6 +381,18 @@ func (s *Store) Redactor() *Redactor {
 	return s.redactor
 }
 
+// DeriveKey returns a 256-bit key for purpose, derived from the store key so
+// that other components can encrypt data at rest without sharing the store
+// key itself. It returns nil for a nil store.
+func (s *Store) DeriveKey(purpose string) []byte {
+	if s == nil {
+		return nil
+	}
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte("astonish/" + purpose))
+	return mac.Sum(nil)
+}
+
 // --- Flat secret storage (for provider keys, bot tokens, etc.) ---
 
 // SetSecret stores a single key-value secret in the encrypted store.
@@ -659,6 +673,15 @@ func (s *Store) resolveAuthCode(name string, cred *Credential) (string, error) {
 		}
 	}
 
+	// Tokens issued without an expiry or refresh_token (e.g. GitHub OAuth
+	// apps) stay valid until revoked
+	if cred.AccessToken != "" && cred.TokenExpiry == "" && cred.RefreshToken == "" {
+		if s.redactor != nil {
+			s.redactor.AddSecret(name+"/token", cred.AccessToken)
+		}
+		return cred.AccessToken, nil
+	}
+
 	// Token expired or missing — refresh
 	if cred.RefreshToken == "" {
 		return "", fmt.Errorf("access token expired and no refresh_token available — the user needs to re-authorize")
@@ -10,6 +10,7 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -29,6 +30,7 @@ import (
 	"github.com/SAP/astonish/pkg/fleet"
 	"github.com/SAP/astonish/pkg/launcher"
 	"github.com/SAP/astonish/pkg/mailer"
+	"github.com/SAP/astonish/pkg/mcp"
 	"github.com/SAP/astonish/pkg/memory"
 	"github.com/SAP/astonish/pkg/provider"
 	"github.com/SAP/astonish/pkg/sandbox"
@@ -67,6 +69,12 @@ func Run(cfg RunConfig) error {
 		slog.Warn("invalid sandbox config, using defaults", "error", err)
 	}
 
+	// Route providers, MCP servers and built-in tools through the configured
+	// proxy / CA bundle
+	if err := provider.ConfigureHTTP(appCfg); err != nil {
+		return err
+	}
+
 	// Resolve port
 	port := cfg.Port
 	if port <= 0 {
@@ -953,6 +961,22 @@ func Run(cfg RunConfig) error {
 		}()
 	}
 
+	// --- Keep the local tools cache warm for CLI commands ---
+	// Only the default (single-process) daemon shares a machine with the CLI.
+	if daemonMode == config.DaemonModeDefault {
+		go func() {
+			if err := mcp.WatchToolsCache(ctx, mcp.ToolsCacheWatcherOpts{
+				OnSync: func(refreshed []string, failed map[string]error) {
+					if len(refreshed) > 0 || len(failed) > 0 {
+						logger.Printf("[tools-cache] Refreshed %d server(s), %d failed", len(refreshed), len(failed))
+					}
+				},
+			}); err != nil {
+				logger.Printf("Warning: Tools cache watcher stopped: %v", err)
+			}
+		}()
+	}
+
 	// --- Initialize scheduler if enabled ---
 	// Skipped in API mode — only default and worker modes run the scheduler.
 	var mtSched *MultiTenantScheduler
@@ -962,6 +986,7 @@ func Run(cfg RunConfig) error {
 	// API pods need this to construct a local executor for schedule_job test
 	// execution (test_first=true) without routing through the worker.
 	api.SetRunHeadlessFunc(makeHeadlessRunner())
+	api.ConfigureFlowRunQueue(appCfg.Daemon.RunQueue)
 
 	// fleetSessionStore holds the PG-backed session store used for fleet
 	// sessions in platform mode. In personal mode it remains nil, and
@@ -1008,6 +1033,7 @@ func Run(cfg RunConfig) error {
 		deliver := scheduler.NewPlatformDeliverFunc(func() *channels.ChannelManager {
 			return channelMgr
 		}, &deliveryResolver{backend: backend}, log.Default())
+		deliver = scheduler.WithFailureWebhook(deliver, appCfg.Scheduler.FailureWebhook, log.Default())
 
 		// Create and start the multi-tenant scheduler
 		mtSched = NewMultiTenantScheduler(backend, schedExec, deliver, log.Default())
@@ -1617,13 +1643,29 @@ func makeHeadlessRunner() scheduler.RunHeadlessFunc {
 			}
 		}
 
+		// Scheduled runs go to the configured session store, so their
+		// results can be inspected like console runs.
+		sessionService, err := launcher.NewFlowSessionService(cfg.AppConfig)
+		if err != nil {
+			return "", err
+		}
+
+		var flowName string
+		if cfg.FlowPath != "" {
+			flowName = strings.TrimSuffix(filepath.Base(cfg.FlowPath), filepath.Ext(cfg.FlowPath))
+		}
+
 		return launcher.RunHeadless(ctx, &launcher.HeadlessConfig{
-			AgentConfig:  agentCfg,
-			AppConfig:    cfg.AppConfig,
-			ProviderName: cfg.ProviderName,
-			ModelName:    cfg.ModelName,
-			Parameters:   cfg.Parameters,
-			DebugMode:    cfg.DebugMode,
+			AgentConfig:    agentCfg,
+			AppConfig:      cfg.AppConfig,
+			FlowName:       flowName,
+			ProviderName:   cfg.ProviderName,
+			ModelName:      cfg.ModelName,
+			Parameters:     cfg.Parameters,
+			Variables:      cfg.Variables,
+			DebugMode:      cfg.DebugMode,
+			DenyApprovals:  cfg.DenyApprovals,
+			SessionService: sessionService,
 		})
 
 	}
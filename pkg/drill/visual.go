@@ -8,6 +8,8 @@ import (
 	"image/png"
 	"os"
 	"path/filepath"
+
+	"github.com/SAP/astonish/pkg/config"
 )
 
 // CompareImages compares two PNG images pixel by pixel and returns the
@@ -118,16 +120,16 @@ func absDiff(a, b uint32) uint32 {
 
 // BaselineDir returns the directory where visual baselines are stored.
 // If an artifact manager is available, baselines go alongside artifacts.
-// Otherwise, uses ~/.config/astonish/drill-baselines/.
+// Otherwise, uses drill-baselines/ in the config directory.
 func BaselineDir(am *ArtifactManager) string {
 	if am != nil {
 		return filepath.Join(filepath.Dir(am.baseDir), "baselines")
 	}
-	home, err := os.UserHomeDir()
+	configDir, err := config.GetConfigDir()
 	if err != nil {
 		return filepath.Join(os.TempDir(), "astonish-drill-baselines")
 	}
-	return filepath.Join(home, ".config", "astonish", "drill-baselines")
+	return filepath.Join(configDir, "drill-baselines")
 }
 
 // SaveBaseline saves PNG image data as a baseline for visual regression tests.
@@ -0,0 +1,218 @@
+// Package flowgraph renders the node graph of a flow (config.AgentConfig)
+// as Mermaid or Graphviz DOT, with conditional edges labeled by their
+// conditions.
+package flowgraph
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/SAP/astonish/pkg/config"
+)
+
+// Output formats accepted by Render.
+const (
+	FormatMermaid = "mermaid"
+	FormatDOT     = "dot"
+)
+
+// maxLabelLen bounds condition labels so long lambdas keep the graph readable.
+const maxLabelLen = 60
+
+// graphNode is a vertex of the rendered graph.
+type graphNode struct {
+	id    string // identifier in the output (names may not be valid identifiers)
+	name  string
+	typ   string // node type, "start" or "end"
+	label string
+}
+
+// graphEdge is a transition between two vertices.
+type graphEdge struct {
+	from, to string // node names
+	label    string // shortened condition, empty for unconditional edges
+}
+
+// graph is the format-independent form of a flow.
+type graph struct {
+	nodes []graphNode
+	index map[string]int // node name → position in nodes
+	edges []graphEdge
+}
+
+// build collects the vertices (START, the declared nodes in order, END and
+// any undeclared edge targets) and the edges of cfg.
+func build(cfg *config.AgentConfig) *graph {
+	g := &graph{index: make(map[string]int)}
+	add := func(name, typ string) {
+		if _, ok := g.index[name]; ok {
+			return
+		}
+		label := name
+		if typ != "start" && typ != "end" && typ != "" {
+			label = fmt.Sprintf("%s\n(%s)", name, typ)
+		}
+		g.index[name] = len(g.nodes)
+		g.nodes = append(g.nodes, graphNode{id: fmt.Sprintf("n%d", len(g.nodes)), name: name, typ: typ, label: label})
+	}
+
+	add("START", "start")
+	for _, n := range cfg.Nodes {
+		add(n.Name, n.Type)
+	}
+	for _, item := range cfg.Flow {
+		if item.To != "" {
+			g.edges = append(g.edges, graphEdge{from: item.From, to: item.To})
+		}
+		for _, e := range item.Edges {
+			g.edges = append(g.edges, graphEdge{from: item.From, to: e.To, label: conditionLabel(e.Condition)})
+		}
+	}
+	for _, e := range g.edges {
+		for _, name := range []string{e.from, e.to} {
+			if name == "END" {
+				add(name, "end")
+			} else {
+				add(name, "")
+			}
+		}
+	}
+	return g
+}
+
+func (g *graph) id(name string) string {
+	return g.nodes[g.index[name]].id
+}
+
+// conditionLabel shortens an edge condition for display: the "lambda x:"
+// prefix is dropped and long expressions are truncated.
+func conditionLabel(cond string) string {
+	cond = strings.TrimSpace(cond)
+	if rest, ok := strings.CutPrefix(cond, "lambda x:"); ok {
+		cond = strings.TrimSpace(rest)
+	}
+	cond = strings.Join(strings.Fields(cond), " ")
+	if r := []rune(cond); len(r) > maxLabelLen {
+		cond = string(r[:maxLabelLen-1]) + "…"
+	}
+	return cond
+}
+
+// Render returns the graph of cfg in format (FormatMermaid or FormatDOT).
+func Render(cfg *config.AgentConfig, format string) (string, error) {
+	switch format {
+	case FormatMermaid, "":
+		return Mermaid(cfg), nil
+	case FormatDOT:
+		return DOT(cfg), nil
+	}
+	return "", fmt.Errorf("unknown graph format %q (use mermaid or dot)", format)
+}
+
+// Mermaid renders cfg as a Mermaid flowchart.
+func Mermaid(cfg *config.AgentConfig) string {
+	g := build(cfg)
+	var sb strings.Builder
+	sb.WriteString("flowchart TD\n")
+	for _, n := range g.nodes {
+		label := mermaidText(n.label)
+		switch n.typ {
+		case "start", "end":
+			fmt.Fprintf(&sb, "    %s((\"%s\"))\n", n.id, label)
+		case "input":
+			fmt.Fprintf(&sb, "    %s[/\"%s\"/]\n", n.id, label)
+		case "llm":
+			fmt.Fprintf(&sb, "    %s(\"%s\")\n", n.id, label)
+		case "tool":
+			fmt.Fprintf(&sb, "    %s[[\"%s\"]]\n", n.id, label)
+		case "output":
+			fmt.Fprintf(&sb, "    %s>\"%s\"]\n", n.id, label)
+		default:
+			fmt.Fprintf(&sb, "    %s[\"%s\"]\n", n.id, label)
+		}
+	}
+	for _, e := range g.edges {
+		if e.label != "" {
+			fmt.Fprintf(&sb, "    %s -->|\"%s\"| %s\n", g.id(e.from), mermaidText(e.label), g.id(e.to))
+		} else {
+			fmt.Fprintf(&sb, "    %s --> %s\n", g.id(e.from), g.id(e.to))
+		}
+	}
+	return sb.String()
+}
+
+// mermaidText escapes s for a quoted Mermaid label. Quotes and characters
+// Mermaid parses inside labels become entity codes; newlines become <br/>.
+func mermaidText(s string) string {
+	r := strings.NewReplacer(
+		"\"", "#quot;",
+		"<", "#lt;",
+		">", "#gt;",
+		"|", "#124;",
+		"\n", "<br/>",
+	)
+	return r.Replace(s)
+}
+
+// DOT renders cfg as a Graphviz digraph.
+func DOT(cfg *config.AgentConfig) string {
+	g := build(cfg)
+	var sb strings.Builder
+	sb.WriteString("digraph flow {\n")
+	sb.WriteString("    rankdir=TB;\n")
+	sb.WriteString("    node [fontname=\"Helvetica\", fontsize=11];\n")
+	sb.WriteString("    edge [fontname=\"Helvetica\", fontsize=9];\n")
+	for _, n := range g.nodes {
+		attrs := "shape=box"
+		switch n.typ {
+		case "start":
+			attrs = "shape=circle"
+		case "end":
+			attrs = "shape=doublecircle"
+		case "input":
+			attrs = "shape=parallelogram"
+		case "llm":
+			attrs = "shape=box, style=rounded"
+		case "tool":
+			attrs = "shape=box3d"
+		case "output":
+			attrs = "shape=note"
+		}
+		fmt.Fprintf(&sb, "    %s [label=%s, %s];\n", n.id, dotString(n.label), attrs)
+	}
+	for _, e := range g.edges {
+		if e.label != "" {
+			fmt.Fprintf(&sb, "    %s -> %s [label=%s];\n", g.id(e.from), g.id(e.to), dotString(e.label))
+		} else {
+			fmt.Fprintf(&sb, "    %s -> %s;\n", g.id(e.from), g.id(e.to))
+		}
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// dotString quotes s as a DOT string literal.
+func dotString(s string) string {
+	r := strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n")
+	return "\"" + r.Replace(s) + "\""
+}
+
+// HTML returns a standalone page that renders the Mermaid graph of cfg in
+// the browser.
+func HTML(title string, cfg *config.AgentConfig) string {
+	subtitle := ""
+	if cfg.Description != "" {
+		subtitle = "<p>" + html.EscapeString(cfg.Description) + "</p>\n"
+	}
+	return fmt.Sprintf("<!DOCTYPE html>\n"+
+		"<html lang=\"en\">\n<head>\n<meta charset=\"UTF-8\">\n"+
+		"<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n"+
+		"<title>%s</title>\n"+
+		"<style>\nbody { font-family: -apple-system, BlinkMacSystemFont, \"Segoe UI\", Helvetica, sans-serif; margin: 2rem; color: #24292f; }\n"+
+		"h1 { font-size: 1.4rem; margin-bottom: 0.2rem; }\np { color: #57606a; margin-top: 0; }\n</style>\n"+
+		"<script src=\"https://cdn.jsdelivr.net/npm/mermaid@11/dist/mermaid.min.js\"></script>\n"+
+		"<script>mermaid.initialize({ startOnLoad: true, securityLevel: \"strict\" });</script>\n"+
+		"</head>\n<body>\n<h1>%s</h1>\n%s<pre class=\"mermaid\">\n%s</pre>\n</body>\n</html>\n",
+		html.EscapeString(title), html.EscapeString(title), subtitle, html.EscapeString(Mermaid(cfg)))
+}
@@ -0,0 +1,72 @@
+package flowgraph
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/config"
+)
+
+func testFlow() *config.AgentConfig {
+	return &config.AgentConfig{
+		Description: "demo",
+		Nodes: []config.Node{
+			{Name: "ask", Type: "input"},
+			{Name: "end", Type: "llm"},
+		},
+		Flow: []config.FlowItem{
+			{From: "START", To: "ask"},
+			{From: "ask", Edges: []config.Edge{
+				{To: "end", Condition: `lambda x: x["choice"] == "yes"`},
+				{To: "END", Condition: "lambda x: x['choice'] == 'no'"},
+			}},
+			{From: "end", To: "ask"},
+		},
+	}
+}
+
+func TestMermaid(t *testing.T) {
+	got := Mermaid(testFlow())
+	for _, want := range []string{
+		"flowchart TD\n",
+		`n0(("START"))`,
+		`n1[/"ask<br/>(input)"/]`,
+		`n2("end<br/>(llm)")`, // "end" is a Mermaid keyword, so names are never used as IDs
+		`n3(("END"))`,
+		`n1 -->|"x[#quot;choice#quot;] == #quot;yes#quot;"| n2`,
+		`n1 -->|"x['choice'] == 'no'"| n3`,
+		"n2 --> n1\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestDOT(t *testing.T) {
+	got := DOT(testFlow())
+	for _, want := range []string{
+		"digraph flow {\n",
+		`n1 [label="ask\n(input)", shape=parallelogram];`,
+		`n3 [label="END", shape=doublecircle];`,
+		`n1 -> n2 [label="x[\"choice\"] == \"yes\""];`,
+		"n0 -> n1;\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+	if _, err := Render(testFlow(), "svg"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestConditionLabel(t *testing.T) {
+	long := "lambda x: " + strings.Repeat("a", 100)
+	if got := []rune(conditionLabel(long)); len(got) != maxLabelLen || got[len(got)-1] != '…' {
+		t.Errorf("long condition not truncated: %q", string(got))
+	}
+	if got := conditionLabel("lambda x:\n  x['a']   and x['b']"); got != "x['a'] and x['b']" {
+		t.Errorf("got %q", got)
+	}
+}
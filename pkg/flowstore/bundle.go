@@ -0,0 +1,214 @@
+package flowstore
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SAP/astonish/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+// BundleVersion is the format version of the bundles ExportBundle creates
+const BundleVersion = 1
+
+// BundleSuffix is the file name suffix of exported bundles
+const BundleSuffix = ".bundle.yaml"
+
+// Bundle is a flow packaged to be shared between machines: its YAML, the
+// MCP servers its tools come from and the docs of its variables. Secrets
+// in the server specs are blanked out and asked for on import.
+type Bundle struct {
+	BundleVersion int                               `yaml:"bundle_version"`
+	Name          string                            `yaml:"name"`
+	Description   string                            `yaml:"description,omitempty"`
+	Flow          string                            `yaml:"flow"`                  // The flow YAML, includes inlined
+	Tools         []string                          `yaml:"tools,omitempty"`       // MCP tools the flow's nodes use
+	MCPServers    map[string]config.MCPServerConfig `yaml:"mcp_servers,omitempty"` // Specs of the servers providing the tools
+	Variables     map[string]config.FlowVariable    `yaml:"variables,omitempty"`   // The flow's variables, for reference
+}
+
+// ExportBundle packages the flow name from the file at flowPath, with its
+// includes inlined. The server of each tool is looked up with
+// serverForTool (empty when unknown), then in the flow's mcp_dependencies,
+// and its spec taken from mcpConfig or an inline dependency. Tools in
+// builtIn need no server and are left out.
+func ExportBundle(name, flowPath string, mcpConfig *config.MCPConfig, serverForTool func(string) string, builtIn map[string]bool) (*Bundle, error) {
+	cfg, err := config.LoadAgent(flowPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flow file: %w", err)
+	}
+	data, err := config.ExpandIncludes(flowPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read flow: %w", err)
+	}
+
+	b := &Bundle{
+		BundleVersion: BundleVersion,
+		Name:          name,
+		Description:   cfg.Description,
+		Flow:          string(data),
+		Variables:     cfg.Variables,
+	}
+
+	depServer := make(map[string]string)
+	for _, dep := range cfg.MCPDependencies {
+		for _, tool := range dep.Tools {
+			depServer[tool] = dep.Server
+		}
+	}
+	servers := make(map[string]bool)
+	seen := make(map[string]bool)
+	for _, node := range cfg.Nodes {
+		for _, tool := range node.ToolsSelection {
+			if builtIn[tool] || seen[tool] {
+				continue
+			}
+			seen[tool] = true
+			b.Tools = append(b.Tools, tool)
+			server := serverForTool(tool)
+			if server == "" {
+				server = depServer[tool]
+			}
+			if server != "" && server != "internal" {
+				servers[server] = true
+			}
+		}
+	}
+	sort.Strings(b.Tools)
+	for _, dep := range cfg.MCPDependencies {
+		if len(dep.Tools) == 0 {
+			servers[dep.Server] = true
+		}
+	}
+
+	for server := range servers {
+		spec, ok := mcpConfig.MCPServers[server]
+		if !ok {
+			for _, dep := range cfg.MCPDependencies {
+				if dep.Server == server && dep.Config != nil {
+					spec, ok = *dep.Config, true
+					break
+				}
+			}
+		}
+		if !ok {
+			continue // Unknown here; reported as a missing tool on import
+		}
+		if b.MCPServers == nil {
+			b.MCPServers = make(map[string]config.MCPServerConfig)
+		}
+		b.MCPServers[server] = redactServerConfig(spec)
+	}
+	return b, nil
+}
+
+// redactServerConfig returns spec without its secrets: environment values,
+// and header and auth values that are not ${VAR} references, are blanked.
+// Whether the server is enabled is left to the importer.
+func redactServerConfig(spec config.MCPServerConfig) config.MCPServerConfig {
+	blank := func(v string) string {
+		if strings.Contains(v, "${") {
+			return v
+		}
+		return ""
+	}
+	spec.Enabled = nil
+	if spec.Env != nil {
+		env := make(map[string]string, len(spec.Env))
+		for k := range spec.Env {
+			env[k] = ""
+		}
+		spec.Env = env
+	}
+	if spec.Headers != nil {
+		headers := make(map[string]string, len(spec.Headers))
+		for k, v := range spec.Headers {
+			headers[k] = blank(v)
+		}
+		spec.Headers = headers
+	}
+	if spec.Auth != nil {
+		auth := *spec.Auth
+		auth.Token = blank(auth.Token)
+		auth.Password = blank(auth.Password)
+		auth.ClientSecret = blank(auth.ClientSecret)
+		spec.Auth = &auth
+	}
+	return spec
+}
+
+// IsBundle reports whether data is a bundle rather than a plain flow YAML.
+func IsBundle(data []byte) bool {
+	var head struct {
+		BundleVersion int `yaml:"bundle_version"`
+	}
+	return yaml.Unmarshal(data, &head) == nil && head.BundleVersion > 0
+}
+
+// LoadBundle parses a bundle and checks that its flow is valid.
+func LoadBundle(data []byte) (*Bundle, error) {
+	var b Bundle
+	if err := yaml.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+	if b.BundleVersion < 1 || b.BundleVersion > BundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d (this version of astonish reads up to %d)", b.BundleVersion, BundleVersion)
+	}
+	if b.Name == "" || b.Flow == "" {
+		return nil, fmt.Errorf("invalid bundle: name and flow are required")
+	}
+	if strings.ContainsAny(b.Name, `/\`) || strings.HasPrefix(b.Name, ".") {
+		return nil, fmt.Errorf("invalid bundle: bad flow name %q", b.Name)
+	}
+	if _, err := config.LoadAgentFromBytes([]byte(b.Flow)); err != nil {
+		return nil, fmt.Errorf("invalid flow in bundle: %w", err)
+	}
+	return &b, nil
+}
+
+// ReadBundleSource reads a bundle or flow file from a local path or an
+// http(s) URL.
+func ReadBundleSource(source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return os.ReadFile(source)
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", source, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: HTTP %d", source, resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+}
+
+// MissingServers returns the names of the bundle's servers that mcpConfig
+// does not have, sorted.
+func (b *Bundle) MissingServers(mcpConfig *config.MCPConfig) []string {
+	var missing []string
+	for name := range b.MCPServers {
+		if _, ok := mcpConfig.MCPServers[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// MissingTools returns the bundle's tools for which available is false.
+func (b *Bundle) MissingTools(available func(string) bool) []string {
+	var missing []string
+	for _, tool := range b.Tools {
+		if !available(tool) {
+			missing = append(missing, tool)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,115 @@
+package flowstore
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+// TestExportBundle tests that a bundle carries the servers of the flow's
+// tools without their secrets, and loads back.
+func TestExportBundle(t *testing.T) {
+	flow := `description: Triage issues
+variables:
+  repo:
+    description: Repository to triage
+    required: true
+nodes:
+  - name: triage
+    type: llm
+    prompt: Triage {repo}
+    tools_selection: [list_issues, read_file, search]
+flow:
+  - from: START
+    to: triage
+  - from: triage
+    to: END
+`
+	mcpConfig := &config.MCPConfig{MCPServers: map[string]config.MCPServerConfig{
+		"github": {Command: "github-mcp", Env: map[string]string{"GITHUB_TOKEN": "ghp_secret"}},
+		"search": {
+			Transport: "streamable-http", URL: "https://search.example.com/mcp",
+			Headers: map[string]string{"X-Api-Key": "secret", "X-Team": "${TEAM}"},
+		},
+		"unused": {Command: "unused-mcp"},
+	}}
+	servers := map[string]string{"list_issues": "github", "search": "search"}
+	builtIn := map[string]bool{"read_file": true}
+
+	dir := t.TempDir()
+	flowPath := filepath.Join(dir, "triage.yaml")
+	if err := os.WriteFile(flowPath, []byte(flow), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ExportBundle("triage", flowPath, mcpConfig, func(tool string) string { return servers[tool] }, builtIn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(b.Tools, []string{"list_issues", "search"}) {
+		t.Errorf("Tools = %v", b.Tools)
+	}
+	if len(b.MCPServers) != 2 {
+		t.Fatalf("MCPServers = %v, want github and search", b.MCPServers)
+	}
+	if env := b.MCPServers["github"].Env; env["GITHUB_TOKEN"] != "" {
+		t.Errorf("github env = %v, want the token blanked", env)
+	}
+	if h := b.MCPServers["search"].Headers; h["X-Api-Key"] != "" || h["X-Team"] != "${TEAM}" {
+		t.Errorf("search headers = %v", h)
+	}
+	if mcpConfig.MCPServers["github"].Env["GITHUB_TOKEN"] != "ghp_secret" {
+		t.Error("export changed the local MCP config")
+	}
+
+	data, err := yaml.Marshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsBundle(data) || IsBundle([]byte(flow)) {
+		t.Error("IsBundle does not tell bundles from flows")
+	}
+	loaded, err := LoadBundle(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Flow != flow || !loaded.Variables["repo"].Required {
+		t.Errorf("loaded bundle = %+v", loaded)
+	}
+
+	local := &config.MCPConfig{MCPServers: map[string]config.MCPServerConfig{"github": {}}}
+	if missing := loaded.MissingServers(local); !slices.Equal(missing, []string{"search"}) {
+		t.Errorf("MissingServers = %v", missing)
+	}
+	if missing := loaded.MissingTools(func(tool string) bool { return tool == "search" }); !slices.Equal(missing, []string{"list_issues"}) {
+		t.Errorf("MissingTools = %v", missing)
+	}
+
+	loaded.Name = "../evil"
+	data, _ = yaml.Marshal(loaded)
+	if _, err := LoadBundle(data); err == nil {
+		t.Error("LoadBundle accepted a name outside the flows directory")
+	}
+
+	// Included files are inlined, since they do not travel with the bundle
+	nodes := "- name: triage\n  type: llm\n  prompt: Triage\n  tools_selection: [search]\n"
+	if err := os.WriteFile(filepath.Join(dir, "nodes.yaml"), []byte(nodes), 0644); err != nil {
+		t.Fatal(err)
+	}
+	split := filepath.Join(dir, "split.yaml")
+	if err := os.WriteFile(split, []byte("nodes: !include nodes.yaml\nflow:\n  - from: START\n    to: triage\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	b, err = ExportBundle("split", split, mcpConfig, func(tool string) string { return servers[tool] }, builtIn)
+	if err != nil {
+		t.Fatalf("ExportBundle with an include: %v", err)
+	}
+	if strings.Contains(b.Flow, "!include") || !strings.Contains(b.Flow, "Triage") || len(b.MCPServers) != 1 {
+		t.Errorf("bundle = %+v, want the include inlined and the search server", b)
+	}
+}
@@ -0,0 +1,296 @@
+// Package flowtest runs the unit tests of a flow (its tests: section) with a
+// scripted model that returns canned responses per node and stub tools that
+// return canned results, so flows can be checked without provider calls.
+package flowtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/SAP/astonish/pkg/agent"
+	"github.com/SAP/astonish/pkg/config"
+	adkagent "google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+	"google.golang.org/genai"
+)
+
+// Result is the outcome of one flow test.
+type Result struct {
+	Name     string
+	Path     []string       // Nodes visited, in order
+	State    map[string]any // State at the end of the run
+	Err      error          // Why the run failed, if it did
+	Failures []string       // Unmet expectations; empty when the test passed
+}
+
+// Passed reports whether the run met all expectations.
+func (r Result) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// Run runs the flow once for test and checks its expectations.
+func Run(ctx context.Context, flow *config.AgentConfig, test config.FlowTest) Result {
+	res := Result{Name: test.Name}
+	res.Path, res.State, res.Err = run(ctx, isolate(flow), test)
+	res.Failures = check(test.Expect, res)
+	return res
+}
+
+// RunAll runs the tests named in only (all of them when only is empty).
+func RunAll(ctx context.Context, flow *config.AgentConfig, tests []config.FlowTest, only string) []Result {
+	var results []Result
+	for _, test := range tests {
+		if only != "" && test.Name != only {
+			continue
+		}
+		results = append(results, Run(ctx, flow, test))
+	}
+	return results
+}
+
+// isolate returns a copy of flow whose nodes all use the scripted model:
+// per-node models and fallbacks are dropped, and failed calls are retried
+// at once without asking the model to analyse the error, which would use
+// up the node's canned responses.
+func isolate(flow *config.AgentConfig) *config.AgentConfig {
+	cfg := *flow
+	cfg.ModelFallbacks = nil
+	cfg.Nodes = slices.Clone(flow.Nodes)
+	for i := range cfg.Nodes {
+		n := &cfg.Nodes[i]
+		n.Provider, n.Model, n.ModelFallbacks = "", "", nil
+		n.RetryStrategy = "simple"
+		n.RetryBackoff = "none"
+	}
+	return &cfg
+}
+
+// run executes the flow, answering input nodes from test.Inputs, and
+// returns the nodes visited and the final state.
+func run(ctx context.Context, cfg *config.AgentConfig, test config.FlowTest) ([]string, map[string]any, error) {
+	llm := &scriptedModel{responses: make(map[string][]string), calls: make(map[string]int)}
+	for node, replies := range test.LLM {
+		llm.responses[node] = slices.Clone(replies)
+	}
+	stubs, err := stubTools(test.Tools)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sessionService := session.InMemoryService()
+	a := agent.NewAstonishAgent(cfg, llm, stubs)
+	a.AutoApprove = true
+	a.Variables = test.Variables
+	a.SessionService = sessionService
+
+	adkAgent, err := adkagent.New(adkagent.Config{
+		Name:        "astonish_test",
+		Description: cfg.Description,
+		Run:         a.Run,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	userID, appName := "flow_test", "astonish"
+	initial := maps.Clone(test.State)
+	if initial == nil {
+		initial = make(map[string]any)
+	}
+	resp, err := sessionService.Create(ctx, &session.CreateRequest{AppName: appName, UserID: userID, State: initial})
+	if err != nil {
+		return nil, nil, err
+	}
+	sess := resp.Session
+	r, err := runner.New(runner.Config{AppName: appName, Agent: adkAgent, SessionService: sessionService})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var path []string
+	var runErr error
+	var failure string // Failure of the last node, when no other node ran after it
+	var userMsg *genai.Content
+	for {
+		waiting := ""
+		for event, err := range r.Run(ctx, userID, sess.ID(), userMsg, adkagent.RunConfig{}) {
+			if err != nil {
+				runErr = err
+				break
+			}
+			delta := event.Actions.StateDelta
+			node, _ := delta["current_node"].(string)
+			waitingForInput, _ := delta["waiting_for_input"].(bool)
+			switch {
+			case node != "" && delta["node_type"] != nil:
+				llm.setNode(node)
+				if node != "END" {
+					path = append(path, node)
+					failure = "" // The flow went on (e.g. to an on_error handler)
+				}
+			case node != "" && waitingForInput && (len(path) == 0 || path[len(path)-1] != node):
+				// The first node of the flow is an input node
+				llm.setNode(node)
+				path = append(path, node)
+			}
+			if waitingForInput {
+				waiting = node
+			}
+			if info, ok := delta["_failure_info"].(map[string]any); ok {
+				failure, _ = info["original_error"].(string)
+			}
+		}
+		if runErr != nil || waiting == "" {
+			break
+		}
+		answer, ok := test.Inputs[waiting]
+		if !ok {
+			runErr = fmt.Errorf("input node '%s' has no value in inputs", waiting)
+			break
+		}
+		userMsg = genai.NewContentFromText(answer, genai.RoleUser)
+	}
+
+	state := make(map[string]any)
+	if got, err := sessionService.Get(ctx, &session.GetRequest{AppName: appName, UserID: userID, SessionID: sess.ID()}); err == nil {
+		maps.Insert(state, got.Session.State().All())
+	}
+	if runErr == nil && failure != "" {
+		// The failed node ended the run at END
+		runErr = fmt.Errorf("%s", failure)
+	}
+	if runErr == nil && state["current_node"] != "END" {
+		runErr = fmt.Errorf("run stopped at node '%v' before reaching END", state["current_node"])
+	}
+	return path, state, runErr
+}
+
+// check compares the run with the expectations and describes each mismatch.
+func check(expect config.FlowTestExpect, res Result) []string {
+	var failures []string
+	switch {
+	case expect.Error == "" && res.Err != nil:
+		failures = append(failures, fmt.Sprintf("run failed: %v", res.Err))
+	case expect.Error != "" && res.Err == nil:
+		failures = append(failures, fmt.Sprintf("expected the run to fail with %q, but it succeeded", expect.Error))
+	case expect.Error != "" && !strings.Contains(res.Err.Error(), expect.Error):
+		failures = append(failures, fmt.Sprintf("expected an error containing %q, got: %v", expect.Error, res.Err))
+	}
+
+	if expect.Path != nil && !slices.Equal(expect.Path, res.Path) {
+		failures = append(failures, fmt.Sprintf("path: got [%s], want [%s]", strings.Join(res.Path, " → "), strings.Join(expect.Path, " → ")))
+	}
+
+	keys := slices.Sorted(maps.Keys(expect.State))
+	for _, key := range keys {
+		got, ok := res.State[key]
+		if !ok {
+			failures = append(failures, fmt.Sprintf("state %s: not set", key))
+			continue
+		}
+		if !sameValue(got, expect.State[key]) {
+			failures = append(failures, fmt.Sprintf("state %s: got %s, want %s", key, show(got), show(expect.State[key])))
+		}
+	}
+	return failures
+}
+
+// sameValue compares state values through their JSON form, so numbers
+// compare equal whether YAML, a script or a model produced them.
+func sameValue(got, want any) bool {
+	return reflect.DeepEqual(normalize(got), normalize(want))
+}
+
+func normalize(v any) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out any
+	if err := json.Unmarshal(data, &out); err != nil {
+		return v
+	}
+	return out
+}
+
+func show(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
+
+// stubTools returns a tool per canned result. Results that are not objects
+// are returned as {"result": value}.
+func stubTools(results map[string]any) ([]tool.Tool, error) {
+	var stubs []tool.Tool
+	for _, name := range slices.Sorted(maps.Keys(results)) {
+		result, ok := normalize(results[name]).(map[string]any)
+		if !ok {
+			result = map[string]any{"result": results[name]}
+		}
+		stub, err := functiontool.New(functiontool.Config{
+			Name:        name,
+			Description: "Canned result for flow tests",
+		}, func(_ tool.Context, _ map[string]any) (map[string]any, error) {
+			return maps.Clone(result), nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("stub tool %s: %w", name, err)
+		}
+		stubs = append(stubs, stub)
+	}
+	return stubs, nil
+}
+
+// scriptedModel answers each call with the next canned response of the
+// node that is running.
+type scriptedModel struct {
+	mu        sync.Mutex
+	node      string
+	responses map[string][]string
+	calls     map[string]int
+}
+
+var _ model.LLM = (*scriptedModel)(nil)
+
+func (m *scriptedModel) Name() string { return "flow_test" }
+
+func (m *scriptedModel) setNode(node string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.node = node
+}
+
+func (m *scriptedModel) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		m.mu.Lock()
+		node := m.node
+		m.calls[node]++
+		call := m.calls[node]
+		queue := m.responses[node]
+		if len(queue) == 0 {
+			m.mu.Unlock()
+			yield(nil, fmt.Errorf("no canned LLM response for node '%s' (call %d)", node, call))
+			return
+		}
+		m.responses[node] = queue[1:]
+		m.mu.Unlock()
+
+		yield(&model.LLMResponse{
+			Content:      genai.NewContentFromText(queue[0], genai.RoleModel),
+			TurnComplete: true,
+		}, nil)
+	}
+}
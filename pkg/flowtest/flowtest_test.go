@@ -0,0 +1,102 @@
+package flowtest
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+const triageFlow = `
+description: Triage an issue
+nodes:
+  - name: ask_issue
+    type: input
+    prompt: Which issue?
+    output_model:
+      issue: str
+  - name: fetch
+    type: tool
+    tools_selection: [get_issue]
+    args:
+      id: "{issue}"
+    output_model:
+      details: any
+  - name: classify
+    type: llm
+    prompt: "Classify: {details}"
+    output_model:
+      label: str
+  - name: report
+    type: output
+    user_message: [label]
+flow:
+  - from: START
+    to: ask_issue
+  - from: ask_issue
+    to: fetch
+  - from: fetch
+    to: classify
+  - from: classify
+    to: report
+  - from: report
+    to: END
+tests:
+  - name: bug report
+    inputs:
+      ask_issue: "42"
+    tools:
+      get_issue:
+        title: Crash on start
+    llm:
+      classify: ['{"label": "bug"}']
+    expect:
+      path: [ask_issue, fetch, classify, report]
+      state:
+        issue: "42"
+        label: bug
+  - name: wrong label
+    inputs:
+      ask_issue: "7"
+    tools:
+      get_issue: {}
+    llm:
+      classify: ['{"label": "question"}']
+    expect:
+      state:
+        label: bug
+  - name: model gives up
+    inputs:
+      ask_issue: "7"
+    tools:
+      get_issue: {}
+    expect:
+      error: no canned LLM response for node 'classify'
+`
+
+func TestRunFlowTests(t *testing.T) {
+	var flow config.AgentConfig
+	if err := yaml.Unmarshal([]byte(triageFlow), &flow); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	results := RunAll(context.Background(), &flow, flow.Tests, "")
+	if len(results) != 3 {
+		t.Fatalf("got %d results", len(results))
+	}
+	if !results[0].Passed() {
+		t.Errorf("bug report failed: %v (err %v, path %v)", results[0].Failures, results[0].Err, results[0].Path)
+	}
+	if results[1].Passed() || !strings.Contains(strings.Join(results[1].Failures, "\n"), `state label: got "question", want "bug"`) {
+		t.Errorf("wrong label: %v", results[1].Failures)
+	}
+	if !results[2].Passed() {
+		t.Errorf("model gives up: %v (err %v)", results[2].Failures, results[2].Err)
+	}
+
+	if only := RunAll(context.Background(), &flow, flow.Tests, "wrong label"); len(only) != 1 || only[0].Name != "wrong label" {
+		t.Errorf("filtered run = %v", only)
+	}
+}
@@ -0,0 +1,236 @@
+// Package hooks delivers lifecycle events of flow runs to the webhooks
+// registered in a flow or the app config, and the local commands of the
+// app config, for notifications and audit pipelines outside the agent.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/SAP/astonish/pkg/config"
+)
+
+// Lifecycle events of a run.
+const (
+	RunStart     = "run_start"     // The run entered its first node
+	RunEnd       = "run_end"       // The run reached END
+	RunFailed    = "run_failed"    // The run stopped on an error, or reached END after a failure
+	NodeEnter    = "node_enter"    // A node started
+	NodeExit     = "node_exit"     // A node finished, with its wall time
+	ToolApproved = "tool_approved" // The user approved a tool call
+	ToolDenied   = "tool_denied"   // The user denied a tool call
+)
+
+// Events lists the lifecycle events hooks can register for.
+var Events = []string{RunStart, RunEnd, RunFailed, NodeEnter, NodeExit, ToolApproved, ToolDenied}
+
+// deliveryTimeout bounds a webhook request or command, so a slow endpoint
+// cannot hold up the end of a run for long.
+const deliveryTimeout = 10 * time.Second
+
+// closeTimeout bounds the total wait of Close, however many events are
+// still queued. A variable so tests can shorten it.
+var closeTimeout = 30 * time.Second
+
+// queueSize is how many events wait for delivery before new ones are
+// dropped.
+const queueSize = 256
+
+// Event is the JSON payload of a lifecycle event.
+type Event struct {
+	Event      string         `json:"event"`
+	Time       time.Time      `json:"time"`
+	Flow       string         `json:"flow,omitempty"`
+	SessionID  string         `json:"session_id,omitempty"`
+	Node       string         `json:"node,omitempty"`
+	NodeType   string         `json:"node_type,omitempty"`
+	DurationMs int64          `json:"duration_ms,omitempty"` // node_exit
+	Tool       string         `json:"tool,omitempty"`        // tool_approved, tool_denied
+	Args       map[string]any `json:"args,omitempty"`        // tool_approved, tool_denied
+	Error      string         `json:"error,omitempty"`       // run_failed
+	Summary    any            `json:"summary,omitempty"`     // run_end, run_failed: the run summary
+}
+
+// hook is a registered hook and whether it comes from the app config.
+// Hooks of a flow are written by whoever wrote the flow, so they do not
+// get the host's environment or the arguments of tool calls.
+type hook struct {
+	config.HookConfig
+	trusted bool
+}
+
+// Dispatcher delivers events to hooks in the background, in the order they
+// were sent. Failed deliveries are logged and do not affect the run.
+type Dispatcher struct {
+	hooks  []hook
+	client *http.Client
+	queue  chan Event
+	done   chan struct{}
+	ctx    context.Context // Cancelled when Close gives up on the queue
+	cancel context.CancelFunc
+}
+
+// New starts a dispatcher for the hooks of the app config and of a flow.
+// It returns nil when there are none; a nil dispatcher ignores events.
+// Command hooks of the flow are ignored: they would run shell commands
+// nobody approved.
+func New(appHooks, flowHooks []config.HookConfig) *Dispatcher {
+	var hooks []hook
+	for _, h := range appHooks {
+		hooks = append(hooks, hook{HookConfig: h, trusted: true})
+	}
+	for _, h := range flowHooks {
+		if h.Command != "" {
+			slog.Warn("ignoring command hook of the flow, command hooks are only allowed in the app config", "command", h.Command)
+			continue
+		}
+		hooks = append(hooks, hook{HookConfig: h})
+	}
+	if len(hooks) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		hooks:  hooks,
+		client: &http.Client{Timeout: deliveryTimeout},
+		queue:  make(chan Event, queueSize),
+		done:   make(chan struct{}),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	go d.run()
+	return d
+}
+
+// Send queues e for the hooks registered for it. It does not block: when
+// the queue is full the event is dropped with a warning.
+func (d *Dispatcher) Send(e Event) {
+	if d == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	select {
+	case d.queue <- e:
+	default:
+		slog.Warn("hook queue full, event dropped", "event", e.Event)
+	}
+}
+
+// Close delivers the queued events and stops the dispatcher. Send must not
+// be called afterwards. Events still undelivered after closeTimeout are
+// dropped with a warning, so slow hooks cannot hold up the end of a run.
+func (d *Dispatcher) Close() {
+	if d == nil {
+		return
+	}
+	close(d.queue)
+	timer := time.NewTimer(closeTimeout)
+	defer timer.Stop()
+	select {
+	case <-d.done:
+	case <-timer.C:
+		slog.Warn("hook delivery timed out, remaining events dropped", "pending", len(d.queue))
+		d.cancel()
+		<-d.done
+	}
+	d.cancel()
+}
+
+func (d *Dispatcher) run() {
+	defer close(d.done)
+	for e := range d.queue {
+		if d.ctx.Err() != nil {
+			continue // Close gave up; drain the queue
+		}
+		// Flow hooks do not see the arguments of tool calls
+		redacted := e
+		redacted.Args = nil
+		body, err := json.Marshal(e)
+		var untrustedBody []byte
+		if err == nil {
+			untrustedBody, err = json.Marshal(redacted)
+		}
+		if err != nil {
+			slog.Warn("failed to encode hook event", "event", e.Event, "error", err)
+			continue
+		}
+		for _, h := range d.hooks {
+			if len(h.Events) > 0 && !slices.Contains(h.Events, e.Event) {
+				continue
+			}
+			if d.ctx.Err() != nil {
+				break
+			}
+			b := body
+			if !h.trusted {
+				b = untrustedBody
+			}
+			if err := d.deliver(h, e.Event, b); err != nil {
+				slog.Warn("hook failed", "event", e.Event, "hook", target(h), "error", err)
+			}
+		}
+	}
+}
+
+// deliver posts body to the hook's URL or runs its command with body on
+// stdin. $VAR in headers is read from the environment for app config hooks
+// only; a flow's headers are sent as written.
+func (d *Dispatcher) deliver(h hook, event string, body []byte) error {
+	ctx, cancel := context.WithTimeout(d.ctx, deliveryTimeout)
+	defer cancel()
+
+	if h.Command != "" {
+		cmd := exec.CommandContext(ctx, "sh", "-c", h.Command)
+		cmd.Stdin = bytes.NewReader(body)
+		cmd.Env = append(os.Environ(), "ASTONISH_HOOK_EVENT="+event)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			if msg := strings.TrimSpace(string(out)); msg != "" {
+				return fmt.Errorf("%w: %s", err, msg)
+			}
+			return err
+		}
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range h.Headers {
+		if h.trusted {
+			v = os.ExpandEnv(v)
+		}
+		req.Header.Set(k, v)
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// target names a hook in logs.
+func target(h hook) string {
+	if h.Command != "" {
+		return h.Command
+	}
+	return h.URL
+}
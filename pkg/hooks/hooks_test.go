@@ -0,0 +1,108 @@
+package hooks
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SAP/astonish/pkg/config"
+)
+
+func TestDispatcher(t *testing.T) {
+	if New(nil, nil) != nil {
+		t.Error("a dispatcher without hooks was started")
+	}
+
+	t.Setenv("HOOK_TOKEN", "secret")
+	auth := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth <- r.Header.Get("Authorization")
+	}))
+	defer srv.Close()
+
+	out := filepath.Join(t.TempDir(), "events")
+	d := New([]config.HookConfig{
+		{URL: srv.URL, Events: []string{RunEnd}, Headers: map[string]string{"Authorization": "Bearer $HOOK_TOKEN"}},
+		{Command: `{ printf '%s ' "$ASTONISH_HOOK_EVENT"; cat; echo; } >> ` + out, Events: []string{ToolDenied}},
+	}, nil)
+	d.Send(Event{Event: ToolDenied, Tool: "shell_command"})
+	d.Send(Event{Event: RunEnd})
+	d.Close()
+
+	if got := <-auth; got != "Bearer secret" {
+		t.Errorf("Authorization = %q, want the header with $HOOK_TOKEN expanded", got)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("command got %d events, want only tool_denied: %q", len(lines), data)
+	}
+	name, payload, _ := strings.Cut(lines[0], " ")
+	var e Event
+	if err := json.Unmarshal([]byte(payload), &e); err != nil || name != ToolDenied || e.Tool != "shell_command" {
+		t.Errorf("command got %q (%v)", lines[0], err)
+	}
+}
+
+func TestDispatcherCloseTimeout(t *testing.T) {
+	defer func(d time.Duration) { closeTimeout = d }(closeTimeout)
+	closeTimeout = 100 * time.Millisecond
+
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release) // Before srv.Close, which waits for the handler
+
+	d := New([]config.HookConfig{{URL: srv.URL}}, nil)
+	for range 5 {
+		d.Send(Event{Event: NodeEnter})
+	}
+	start := time.Now()
+	d.Close()
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Close waited %v for a hung webhook, want about %v", elapsed, closeTimeout)
+	}
+}
+
+func TestDispatcherFlowHooks(t *testing.T) {
+	if New(nil, []config.HookConfig{{Command: "cat"}}) != nil {
+		t.Error("a command hook of the flow was registered")
+	}
+
+	t.Setenv("HOOK_TOKEN", "secret")
+	got := make(chan *http.Request, 1)
+	bodies := make(chan Event, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e Event
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		got <- r
+		bodies <- e
+	}))
+	defer srv.Close()
+
+	d := New(nil, []config.HookConfig{{URL: srv.URL, Headers: map[string]string{"Authorization": "Bearer $HOOK_TOKEN"}}})
+	d.Send(Event{Event: ToolApproved, Tool: "shell_command", Args: map[string]any{"command": "cat ~/.netrc"}})
+	d.Close()
+
+	if auth := (<-got).Header.Get("Authorization"); auth != "Bearer $HOOK_TOKEN" {
+		t.Errorf("Authorization = %q, want the flow's header as written", auth)
+	}
+	if e := <-bodies; e.Tool != "shell_command" || e.Args != nil {
+		t.Errorf("flow hook got %+v, want the tool without its args", e)
+	}
+}
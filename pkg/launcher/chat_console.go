@@ -57,6 +57,9 @@ func RunChatConsole(ctx context.Context, cfg *ChatConsoleConfig) error {
 	if !cfg.DebugMode {
 		log.SetOutput(io.Discard)
 	}
+	if err := configureTheme(cfg.AppConfig); err != nil {
+		return err
+	}
 
 	// --- Build fully-wired ChatAgent via factory ---
 	result, err := NewWiredChatAgent(ctx, &ChatFactoryConfig{
@@ -347,7 +350,7 @@ func RunChatConsole(ctx context.Context, cfg *ChatConsoleConfig) error {
 				description, previewErr := chatAgent.PreviewDistill(ctx, ds)
 				stopSpinner()
 				if previewErr != nil {
-					fmt.Printf("%sError:%s %v\n\n", "\033[31m", ColorReset, previewErr)
+					fmt.Printf("%sError:%s %v\n\n", ColorRed, ColorReset, previewErr)
 					break
 				}
 				fmt.Printf("%sTask identified:%s %s\n", ColorGreen, ColorReset, description)
@@ -365,7 +368,7 @@ func RunChatConsole(ctx context.Context, cfg *ChatConsoleConfig) error {
 				})
 				stopSpinner()
 				if distillErr != nil {
-					fmt.Printf("%sError:%s %v\n", "\033[31m", ColorReset, distillErr)
+					fmt.Printf("%sError:%s %v\n", ColorRed, ColorReset, distillErr)
 					fmt.Println()
 					break
 				}
@@ -386,7 +389,7 @@ func RunChatConsole(ctx context.Context, cfg *ChatConsoleConfig) error {
 					case "s", "save":
 						filePath, runCmd, saveErr := chatAgent.SaveDistillReview(ctx, sess.ID())
 						if saveErr != nil {
-							fmt.Printf("%sError:%s %v\n", "\033[31m", ColorReset, saveErr)
+							fmt.Printf("%sError:%s %v\n", ColorRed, ColorReset, saveErr)
 						} else {
 							fmt.Printf("%sSaved:%s %s\n", ColorGreen, ColorReset, filePath)
 							fmt.Printf("%sRun with:%s %s\n", ColorGreen, ColorReset, runCmd)
@@ -395,14 +398,14 @@ func RunChatConsole(ctx context.Context, cfg *ChatConsoleConfig) error {
 
 					case "t", "test", "test run":
 						if chatAgent.FlowRunner == nil {
-							fmt.Printf("%sError:%s dry-run execution is not available\n", "\033[31m", ColorReset)
+							fmt.Printf("%sError:%s dry-run execution is not available\n", ColorRed, ColorReset)
 							continue
 						}
 						startSpinner("Executing test run...")
 						dryResult, dryErr := chatAgent.DryRunDistilledFlow(ctx, sess.ID())
 						stopSpinner()
 						if dryErr != nil {
-							fmt.Printf("%sError:%s %v\n", "\033[31m", ColorReset, dryErr)
+							fmt.Printf("%sError:%s %v\n", ColorRed, ColorReset, dryErr)
 							continue
 						}
 						if dryResult.Success {
@@ -416,7 +419,7 @@ func RunChatConsole(ctx context.Context, cfg *ChatConsoleConfig) error {
 								fmt.Printf("%s\n", output)
 							}
 						} else {
-							fmt.Printf("\n%s✗ Test run failed%s\n", "\033[31m", ColorReset)
+							fmt.Printf("\n%s✗ Test run failed%s\n", ColorRed, ColorReset)
 							if dryResult.Error != "" {
 								fmt.Printf("Error: %s\n", dryResult.Error)
 							}
@@ -442,7 +445,7 @@ func RunChatConsole(ctx context.Context, cfg *ChatConsoleConfig) error {
 						modified, modErr := chatAgent.ModifyDistillReview(ctx, sess.ID(), change)
 						stopSpinner()
 						if modErr != nil {
-							fmt.Printf("%sError:%s %v\n", "\033[31m", ColorReset, modErr)
+							fmt.Printf("%sError:%s %v\n", ColorRed, ColorReset, modErr)
 							continue
 						}
 						fmt.Printf("\n%s─── Modified Flow ───%s\n", ColorCyan, ColorReset)
@@ -510,7 +513,7 @@ func RunChatConsole(ctx context.Context, cfg *ChatConsoleConfig) error {
 				fmt.Printf("  Session:   %s\n\n", shortID)
 			case input == "/compact":
 				if compactor == nil {
-					fmt.Printf("%sCompaction is disabled.%s\n\n", "\033[31m", ColorReset)
+					fmt.Printf("%sCompaction is disabled.%s\n\n", ColorRed, ColorReset)
 				} else {
 					est, win := compactor.TokenUsage()
 					pct := float64(est) / float64(win) * 100
@@ -537,7 +540,7 @@ func RunChatConsole(ctx context.Context, cfg *ChatConsoleConfig) error {
 					UserID:  userID,
 				})
 				if newErr != nil {
-					fmt.Printf("%sError:%s Failed to create new session: %v\n\n", "\033[31m", ColorReset, newErr)
+					fmt.Printf("%sError:%s Failed to create new session: %v\n\n", ColorRed, ColorReset, newErr)
 				} else {
 					sess = newResp.Session
 					shortID = persistentsession.SafeShortID(sess.ID(), 16)
@@ -683,7 +686,7 @@ func RunChatConsole(ctx context.Context, cfg *ChatConsoleConfig) error {
 		}) {
 			if err != nil {
 				stopSpinner()
-				fmt.Printf("\n%sError:%s %v\n", "\033[31m", ColorReset, err)
+				fmt.Printf("\n%sError:%s %v\n", ColorRed, ColorReset, err)
 				break
 			}
 
@@ -1011,9 +1014,8 @@ func printRecentHistory(sess session.Session, maxExchanges int, colorCyan, color
 		}
 	}
 
-	const colorGray = "\033[90m"
-	divider := colorGray + "── Recent history ──────────────────────────" + colorReset
-	dividerEnd := colorGray + "────────────────────────────────────────────" + colorReset
+	divider := ColorGray + "── Recent history ──────────────────────────" + colorReset
+	dividerEnd := ColorGray + "────────────────────────────────────────────" + colorReset
 
 	fmt.Println(divider)
 	for _, msg := range messages[startIdx:] {
@@ -167,6 +167,9 @@ func NewWiredChatAgent(ctx context.Context, cfg *ChatFactoryConfig) (*ChatFactor
 	if cfg.DebugMode {
 		slog.Debug("initializing LLM provider", "component", "chat-factory")
 	}
+	if err := provider.ConfigureHTTP(cfg.AppConfig); err != nil {
+		return nil, err
+	}
 	rawLLM, err := provider.GetProvider(ctx, cfg.ProviderName, cfg.ModelName, cfg.AppConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize provider '%s' with model '%s': %w",
@@ -585,8 +588,8 @@ func NewWiredChatAgent(ctx context.Context, cfg *ChatFactoryConfig) (*ChatFactor
 			}
 
 			// Lazy MCP toolsets: wire the pool so stdio MCP servers start
-			// inside the session's sandbox (same as Incus path). SSE
-			// transports are unaffected (isSSETransport check inside).
+			// inside the session's sandbox (same as Incus path). Remote
+			// transports are unaffected (isRemoteTransport check inside).
 			for _, lt := range lazyToolsets {
 				lt.SetSandboxPool(pool)
 			}
@@ -684,7 +687,7 @@ func NewWiredChatAgent(ctx context.Context, cfg *ChatFactoryConfig) (*ChatFactor
 
 			// Wire sandbox pool to all lazy MCP toolsets so stdio MCP servers
 			// start inside the session's container instead of on the host.
-			// SSE transport servers are unaffected (isSSETransport check inside).
+			// Remote transport servers are unaffected (isRemoteTransport check inside).
 			for _, lt := range lazyToolsets {
 				lt.SetSandboxPool(sandbox.AsNodePool(nodePool))
 			}
@@ -24,6 +24,10 @@ type RemoteChatConfig struct {
 // Astonish server, providing the same TUI experience as the local mode
 // (spinners, streaming text, tool call displays, approval prompts).
 func RunRemoteChatConsole(ctx context.Context, cfg *RemoteChatConfig) error {
+	// No app config here; ASTONISH_THEME and NO_COLOR still apply
+	if err := configureTheme(nil); err != nil {
+		return err
+	}
 	c, err := client.New()
 	if err != nil {
 		return fmt.Errorf("failed to connect: %w", err)
@@ -126,7 +130,7 @@ func RunRemoteChatConsole(ctx context.Context, cfg *RemoteChatConfig) error {
 
 		// Send message to server
 		if err := runRemoteTurn(ctx, c, &sessionID, input, cfg.AutoApprove, cfg.DebugMode, startSpinner, stopSpinner, &lineHasContent, reader); err != nil {
-			fmt.Printf("\n%sError:%s %v\n\n", "\033[31m", ColorReset, err)
+			fmt.Printf("\n%sError:%s %v\n\n", ColorRed, ColorReset, err)
 		}
 	}
 
@@ -318,7 +322,7 @@ func runRemoteTurn(
 					msg = payload.Title + ": " + msg
 				}
 				if msg != "" {
-					fmt.Printf("\n%sError:%s %s\n", "\033[31m", ColorReset, msg)
+					fmt.Printf("\n%sError:%s %s\n", ColorRed, ColorReset, msg)
 					*lineHasContent = false
 				}
 			}
@@ -1,7 +1,13 @@
 package launcher
 
-// ANSI color codes shared across console launchers.
-const (
+import (
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/ui"
+)
+
+// ANSI color codes shared across console launchers. They are empty when
+// the console theme has colors off.
+var (
 	ColorReset  = "\033[0m"
 	ColorRed    = "\033[31m"
 	ColorGreen  = "\033[32m"
@@ -10,3 +16,15 @@ const (
 	ColorCyan   = "\033[36m"
 	ColorGray   = "\033[90m"
 )
+
+// configureTheme applies the console theme of the app config and turns
+// the ANSI colors off with it.
+func configureTheme(cfg *config.AppConfig) error {
+	if err := ui.ConfigureTheme(cfg); err != nil {
+		return err
+	}
+	if ui.CurrentTheme().NoColor {
+		ColorReset, ColorRed, ColorGreen, ColorYellow, ColorBlue, ColorCyan, ColorGray = "", "", "", "", "", "", ""
+	}
+	return nil
+}
@@ -1,19 +1,27 @@
 package launcher
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"log/slog"
+	"math"
+	"os"
+	"os/signal"
 	"regexp"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/SAP/astonish/pkg/agent"
+	"github.com/SAP/astonish/pkg/artifacts"
 	"github.com/SAP/astonish/pkg/browser"
 	"github.com/SAP/astonish/pkg/cache"
-	"github.com/SAP/astonish/pkg/common"
+	"github.com/SAP/astonish/pkg/cassette"
 	"github.com/SAP/astonish/pkg/config"
 	"github.com/SAP/astonish/pkg/credentials"
 	"github.com/SAP/astonish/pkg/mcp"
@@ -23,6 +31,7 @@ import (
 	"github.com/SAP/astonish/pkg/tools"
 	"github.com/SAP/astonish/pkg/ui"
 	adkagent "google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
 	"google.golang.org/adk/runner"
 	"google.golang.org/adk/session"
 	"google.golang.org/adk/tool"
@@ -35,12 +44,70 @@ import (
 type ConsoleConfig struct {
 	AgentConfig    *config.AgentConfig
 	AppConfig      *config.AppConfig
+	FlowName       string // Name of the flow, for flow-scoped memory
 	ProviderName   string
 	ModelName      string
 	SessionService session.Service
 	DebugMode      bool
 	AutoApprove    bool
+	ApprovalPolicy *agent.ApprovalPolicy // Approval profile deciding tool calls before the user is asked (--approval-profile)
+	NoCache        bool                  // Call the model even for cache_response nodes (--no-cache)
+	DumpLLMIO      string                // Write every model request and response as JSON to this directory
 	Parameters     map[string]string
+	Variables      map[string]string // --var values for the flow's variables: section
+	Trace          bool              // Show the live execution trace panel
+	NonInteractive bool              // Never prompt: inputs come from Parameters, approvals are denied unless auto-approved
+	ExportState    string            // Write the final state to this JSON file (overrides the flow's export_state)
+	Chat           bool              // Run the flow as a multi-turn chat, as with chat_mode: true in the flow
+	ResumeSession  string            // Continue this stored session (e.g. a cancelled run) instead of starting a new one
+	Step           bool              // Pause before each node to inspect it, skip it, edit state or abort
+	Record         string            // Record every model and tool call of the run into this cassette file
+	Replay         string            // Answer model and tool calls from this cassette instead of providers and tools
+	Preview        bool              // Show a dimmed live preview of LLM text that is not streamed (output_model nodes), as with preview: true on every node
+	Transcript     string            // Write a Markdown transcript of the run to this file
+	// TranscriptPrompts includes the prompt of each LLM node in the transcript.
+	TranscriptPrompts bool
+}
+
+// readSelection asks the user to pick one of the options. Tests replace it.
+var readSelection = ui.ReadSelection
+
+// previewInterval is the shortest time between two updates of the live
+// preview of a node's text.
+const previewInterval = 150 * time.Millisecond
+
+// ErrRunCancelled is returned by RunConsole when the run was interrupted
+// (Ctrl+C or SIGTERM). The session records the node it stopped at.
+var ErrRunCancelled = errors.New("run cancelled")
+
+// RunAbortedError is returned when an abort node or the _abort state key
+// stopped the run. ExitCode is the exit code the flow asked for.
+type RunAbortedError struct {
+	Node     string
+	Message  string
+	ExitCode int
+}
+
+func (e *RunAbortedError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("run aborted by node '%s'", e.Node)
+	}
+	return fmt.Sprintf("run aborted by node '%s': %s", e.Node, e.Message)
+}
+
+// runAbortedError reads the _run_aborted event of a run stopped early.
+func runAbortedError(delta map[string]any) *RunAbortedError {
+	info, ok := delta[agent.RunAbortedKey].(map[string]any)
+	if !ok {
+		return nil
+	}
+	e := &RunAbortedError{ExitCode: intValue(info["exit_code"])}
+	e.Node, _ = info["node"].(string)
+	e.Message, _ = info["message"].(string)
+	if e.ExitCode <= 0 {
+		e.ExitCode = 1
+	}
+	return e
 }
 
 // minimalReadonlyContext implements agent.ReadonlyContext for tool discovery
@@ -94,34 +161,23 @@ func getRequiredMCPServersFromConfig(ctx context.Context, agentCfg *config.Agent
 		return servers
 	}
 
+	// Refresh new or changed servers synchronously for CLI
+	refreshed, failed := mcp.SyncToolsCache(ctx, mcpCfg)
+	if verbose && len(refreshed) > 0 {
+		slog.Info("refreshed servers", "component", "cache", "servers", refreshed)
+	}
+	for serverName, err := range failed {
+		if verbose {
+			slog.Warn("failed to refresh server", "component", "cache", "server", serverName, "error", err)
+		}
+	}
+
 	// Load persistent cache for tool→server lookup
 	persistentCache, cacheLoadErr := cache.LoadCache()
 	if cacheLoadErr != nil {
 		slog.Warn("failed to load persistent cache", "error", cacheLoadErr)
 	}
 
-	// Validate checksums and refresh any changed servers (synchronously for CLI)
-	needsRefresh, removed := cache.ValidateChecksums(verbose)
-
-	// Remove stale servers from cache
-	for _, serverName := range removed {
-		cache.RemoveServer(serverName)
-	}
-
-	// Refresh changed servers synchronously
-	if len(needsRefresh) > 0 {
-		if verbose {
-			slog.Info("refreshing servers", "component", "cache", "count", len(needsRefresh), "servers", needsRefresh)
-		}
-		refreshServers(ctx, mcpCfg, needsRefresh, verbose)
-		// Reload cache after refresh
-		var cacheErr error
-		persistentCache, cacheErr = cache.LoadCache()
-		if cacheErr != nil {
-			slog.Warn("failed to reload persistent cache", "error", cacheErr)
-		}
-	}
-
 	requiredServers := make(map[string]bool)
 
 	for toolName := range toolsNeeded {
@@ -177,60 +233,6 @@ func getRequiredMCPServersFromConfig(ctx context.Context, agentCfg *config.Agent
 	return servers
 }
 
-// refreshServers initializes the given servers and updates the cache
-func refreshServers(ctx context.Context, mcpCfg *config.MCPConfig, servers []string, verbose bool) {
-	mcpManager, err := mcp.NewManager()
-	if err != nil {
-		if verbose {
-			slog.Warn("failed to create mcp manager for refresh", "component", "cache", "error", err)
-		}
-		return
-	}
-
-	for _, serverName := range servers {
-		namedToolset, err := mcpManager.InitializeSingleToolset(ctx, serverName)
-		if err != nil {
-			if verbose {
-				slog.Warn("failed to initialize server", "component", "cache", "server", serverName, "error", err)
-			}
-			continue
-		}
-
-		// Get tools from this server
-		minimalCtx := &minimalReadonlyContext{Context: ctx}
-		mcpTools, err := namedToolset.Toolset.Tools(minimalCtx)
-		if err != nil {
-			if verbose {
-				slog.Warn("failed to get tools from server", "component", "cache", "server", serverName, "error", err)
-			}
-			continue
-		}
-
-		// Update persistent cache
-		persistentTools := make([]cache.ToolEntry, 0, len(mcpTools))
-		for _, t := range mcpTools {
-			persistentTools = append(persistentTools, cache.ToolEntry{
-				Name:        t.Name(),
-				Description: t.Description(),
-				Source:      serverName,
-				InputSchema: common.ExtractToolInputSchema(t),
-			})
-		}
-		serverCfg := mcpCfg.MCPServers[serverName]
-		checksum := cache.ComputeServerChecksum(serverCfg.Command, serverCfg.Args, serverCfg.Env)
-		cache.AddServerTools(serverName, persistentTools, checksum)
-		if verbose {
-			slog.Info("refreshed server", "component", "cache", "server", serverName, "tools", len(persistentTools))
-		}
-	}
-
-	if err := cache.SaveCache(); err != nil {
-		if verbose {
-			slog.Warn("failed to save persistent cache after refresh", "component", "cache", "error", err)
-		}
-	}
-}
-
 // RunConsole runs the agent in console mode with agent-controlled flow
 func RunConsole(ctx context.Context, cfg *ConsoleConfig) error {
 	// Suppress default logger (used by ADK for "unknown agent" warnings)
@@ -238,16 +240,50 @@ func RunConsole(ctx context.Context, cfg *ConsoleConfig) error {
 	if !cfg.DebugMode {
 		log.SetOutput(io.Discard)
 	}
+	if cfg.Chat {
+		cfg.AgentConfig.ChatMode = true
+	}
 
 	// Initialize LLM
 	if cfg.DebugMode {
 		fmt.Println("Initializing LLM provider...")
 		provider.SetDebugMode(true)
 	}
-	llm, err := provider.GetProvider(ctx, cfg.ProviderName, cfg.ModelName, cfg.AppConfig)
-	if err != nil {
-		fmt.Printf("ERROR: Failed to initialize provider '%s' with model '%s': %v\n", cfg.ProviderName, cfg.ModelName, err)
-		return fmt.Errorf("failed to initialize provider: %w", err)
+	if err := provider.ConfigureHTTP(cfg.AppConfig); err != nil {
+		return err
+	}
+	if err := configureTheme(cfg.AppConfig); err != nil {
+		return err
+	}
+	var player *cassette.Player
+	var recorder *cassette.Recorder
+	var llm model.LLM
+	if cfg.Replay != "" {
+		tape, err := cassette.Load(cfg.Replay)
+		if err != nil {
+			return err
+		}
+		player = cassette.NewPlayer(tape)
+		llm = player.Model()
+		fmt.Println(ui.RenderStatusBadge(fmt.Sprintf("Replaying %d recorded calls from %s", player.Remaining(), cfg.Replay), true))
+	} else {
+		var err error
+		llm, err = provider.GetProvider(ctx, cfg.ProviderName, cfg.ModelName, cfg.AppConfig)
+		if err != nil {
+			fmt.Printf("ERROR: Failed to initialize provider '%s' with model '%s': %v\n", cfg.ProviderName, cfg.ModelName, err)
+			return fmt.Errorf("failed to initialize provider: %w", err)
+		}
+	}
+	if cfg.Record != "" {
+		recorder = cassette.NewRecorder(cfg.FlowName)
+		llm = recorder.Model(llm)
+		defer func() {
+			if err := recorder.Save(cfg.Record); err != nil {
+				fmt.Println(ui.RenderStatusBadge(fmt.Sprintf("Failed to save the cassette: %v", err), false))
+				return
+			}
+			fmt.Println(ui.RenderStatusBadge(fmt.Sprintf("Recorded %d calls to %s", recorder.Len(), cfg.Record), true))
+		}()
 	}
 	if cfg.DebugMode {
 		fmt.Printf("✓ Provider initialized: %s (model: %s)\n", cfg.ProviderName, cfg.ModelName)
@@ -296,12 +332,22 @@ func RunConsole(ctx context.Context, cfg *ConsoleConfig) error {
 	}
 	defer browserMgr.Cleanup()
 
-	// Wrap tools with sandbox if enabled (container isolation for file/shell/network tools)
-	internalTools, sandboxCleanup, sandboxErr := setupFlowSandbox(cfg.AppConfig, internalTools, cfg.DebugMode)
-	if sandboxErr != nil {
-		return fmt.Errorf("sandbox is enabled but the runtime is not available: %w\n\nTo disable sandbox, set 'sandbox.enabled: false' in ~/.config/astonish/config.yaml", sandboxErr)
+	if player != nil {
+		// Tool calls are answered from the cassette: nothing runs, so no sandbox is needed
+		internalTools = player.Tools()
+	} else {
+		// Wrap tools with sandbox if enabled (container isolation for file/shell/network tools)
+		var sandboxCleanup func()
+		var sandboxErr error
+		internalTools, sandboxCleanup, sandboxErr = setupFlowSandbox(cfg.AppConfig, internalTools, cfg.DebugMode)
+		if sandboxErr != nil {
+			return fmt.Errorf("sandbox is enabled but the runtime is not available: %w\n\nTo disable sandbox, set 'sandbox.enabled: false' in ~/.config/astonish/config.yaml", sandboxErr)
+		}
+		defer sandboxCleanup()
+	}
+	if recorder != nil {
+		internalTools = recorder.Tools(internalTools)
 	}
-	defer sandboxCleanup()
 
 	if cfg.DebugMode {
 		fmt.Printf("✓ Internal tools initialized: %d tools available\n", len(internalTools))
@@ -313,7 +359,10 @@ func RunConsole(ctx context.Context, cfg *ConsoleConfig) error {
 	}
 
 	// Extract required MCP servers from flow config (validates cache and refreshes if needed)
-	requiredServers := getRequiredMCPServersFromConfig(ctx, cfg.AgentConfig, cfg.DebugMode)
+	var requiredServers []string
+	if player == nil {
+		requiredServers = getRequiredMCPServersFromConfig(ctx, cfg.AgentConfig, cfg.DebugMode)
+	}
 
 	var mcpManager *mcp.Manager
 	var mcpToolsets []tool.Toolset
@@ -332,6 +381,7 @@ func RunConsole(ctx context.Context, cfg *ConsoleConfig) error {
 				}
 			} else {
 				mcpToolsets = mcpManager.GetToolsets()
+				mcpManager.StartHealthChecks(ctx, mcp.HealthConfig{})
 				if cfg.DebugMode {
 					fmt.Printf("✓ MCP servers initialized: %d/%d server(s) needed for this flow\n", len(mcpToolsets), len(requiredServers))
 				}
@@ -347,24 +397,23 @@ func RunConsole(ctx context.Context, cfg *ConsoleConfig) error {
 	if mcpManager != nil {
 		defer mcpManager.Cleanup()
 	}
+	if recorder != nil {
+		mcpToolsets = recorder.Toolsets(mcpToolsets)
+	}
 
 	// Create session service
 	sessionService := cfg.SessionService
 	if sessionService == nil {
-		if cfg.AppConfig != nil && cfg.AppConfig.Sessions.Storage == "file" {
-			sessDir, dirErr := config.GetSessionsDir(&cfg.AppConfig.Sessions)
-			if dirErr != nil {
-				return fmt.Errorf("failed to resolve sessions directory: %w", dirErr)
-			}
-			fileStore, fsErr := persistentsession.NewFileStore(sessDir)
-			if fsErr != nil {
-				return fmt.Errorf("failed to create file session store: %w", fsErr)
-			}
-			sessionService = fileStore
-		} else {
-			sessionService = session.InMemoryService()
+		sessionService, err = NewFlowSessionService(cfg.AppConfig)
+		if err != nil {
+			return err
 		}
 	}
+	// Values of sensitive state keys are encrypted before they reach the store
+	sessionService, err = persistentsession.NewSensitiveService(sessionService, cfg.AgentConfig.SensitiveKeys(), tools.GetCredentialStore().DeriveKey("session-state"))
+	if err != nil {
+		return err
+	}
 
 	// Create Astonish agent with internal tools
 	// MCP toolsets will be passed directly to llmagent when creating nodes
@@ -372,9 +421,52 @@ func RunConsole(ctx context.Context, cfg *ConsoleConfig) error {
 		fmt.Println("Creating agent...")
 	}
 	astonishAgent := agent.NewAstonishAgentWithToolsets(cfg.AgentConfig, llm, internalTools, mcpToolsets)
+	astonishAgent.AppConfig = cfg.AppConfig // per-node models and model_fallbacks resolve providers from here
+	astonishAgent.ProviderName = cfg.ProviderName
+	astonishAgent.ModelName = cfg.ModelName
+	switch {
+	case player != nil:
+		astonishAgent.NewModel = func(context.Context, string, string) (model.LLM, error) {
+			return player.Model(), nil
+		}
+	case recorder != nil:
+		pool := provider.NewPool()
+		astonishAgent.NewModel = func(ctx context.Context, providerName, modelName string) (model.LLM, error) {
+			llm, err := pool.Get(ctx, providerName, modelName, cfg.AppConfig)
+			if err != nil {
+				return nil, err
+			}
+			return recorder.Model(llm), nil
+		}
+	}
 	astonishAgent.DebugMode = cfg.DebugMode
 	astonishAgent.AutoApprove = cfg.AutoApprove
+	astonishAgent.ApprovalPolicy = cfg.ApprovalPolicy
+	astonishAgent.NoCache = cfg.NoCache
+	astonishAgent.DumpLLMIO = cfg.DumpLLMIO
+	astonishAgent.Variables = cfg.Variables
 	astonishAgent.SessionService = sessionService
+	astonishAgent.FlowName = cfg.FlowName
+	facts, memoryService, err := flowMemory(cfg.AgentConfig, cfg.AppConfig, cfg.DebugMode)
+	if err != nil {
+		return err
+	}
+	astonishAgent.Memory = facts
+	if mcpManager != nil {
+		astonishAgent.MCPHealth = mcpManager.HealthState
+		astonishAgent.ToolsetsWithEnv = mcpManager.ToolsetsWithEnv
+		if recorder != nil {
+			astonishAgent.ToolsetsWithEnv = func(ctx context.Context, env map[string]string) ([]tool.Toolset, error) {
+				toolsets, err := mcpManager.ToolsetsWithEnv(ctx, env)
+				return recorder.Toolsets(toolsets), err
+			}
+		}
+	}
+	var debugger *stepDebugger
+	if cfg.Step {
+		debugger = &stepDebugger{}
+		astonishAgent.Debugger = debugger
+	}
 
 	// Wire credential redactor and store for placeholder substitution
 	if cs := tools.GetCredentialStore(); cs != nil {
@@ -418,19 +510,34 @@ func RunConsole(ctx context.Context, cfg *ConsoleConfig) error {
 		fmt.Println("Creating session...")
 	}
 	userID, appName := "console_user", "astonish"
-	resp, err := sessionService.Create(ctx, &session.CreateRequest{
-		AppName: appName,
-		UserID:  userID,
-	})
-	if err != nil {
-		fmt.Printf("ERROR: Failed to create session: %v\n", err)
-		return fmt.Errorf("failed to create session: %w", err)
+	var sess session.Session
+	if cfg.ResumeSession != "" {
+		resp, err := sessionService.Get(ctx, &session.GetRequest{
+			AppName:   appName,
+			UserID:    userID,
+			SessionID: cfg.ResumeSession,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to resume session %s: %w", cfg.ResumeSession, err)
+		}
+		sess = resp.Session
+		node, _ := sess.State().Get("current_node")
+		fmt.Printf("%sResuming session %s at node %v%s\n", ColorGray, sess.ID(), node, ColorReset)
+	} else {
+		resp, err := sessionService.Create(ctx, &session.CreateRequest{
+			AppName: appName,
+			UserID:  userID,
+		})
+		if err != nil {
+			fmt.Printf("ERROR: Failed to create session: %v\n", err)
+			return fmt.Errorf("failed to create session: %w", err)
+		}
+		sess = resp.Session
 	}
 	if cfg.DebugMode {
 		fmt.Println("✓ Session created")
 	}
 
-	sess := resp.Session
 	sandbox.WarmFlowSession(ctx, internalTools, sess.ID())
 
 	// Create runner
@@ -438,9 +545,11 @@ func RunConsole(ctx context.Context, cfg *ConsoleConfig) error {
 		fmt.Println("Creating runner...")
 	}
 	r, err := runner.New(runner.Config{
-		AppName:        appName,
-		Agent:          adkAgent,
-		SessionService: sessionService,
+		AppName:         appName,
+		Agent:           adkAgent,
+		SessionService:  sessionService,
+		MemoryService:   memoryService,
+		ArtifactService: artifacts.RunnerService(),
 	})
 	if err != nil {
 		fmt.Printf("ERROR: Failed to create runner: %v\n", err)
@@ -455,6 +564,20 @@ func RunConsole(ctx context.Context, cfg *ConsoleConfig) error {
 	// Start with empty message to let agent initialize and show first prompt
 	var userMsg *genai.Content
 
+	// Chat-mode flows run once per user message until the user exits
+	var chatInput *bufio.Reader
+	if cfg.AgentConfig.ChatEnabled() {
+		chatInput = bufio.NewReader(os.Stdin)
+		fmt.Printf("%sChatting with the flow. Type 'exit' to quit.%s\n", ColorGray, ColorReset)
+		if cfg.ResumeSession == "" {
+			input, ok := readChatMessage(chatInput)
+			if !ok {
+				return nil
+			}
+			userMsg = agent.NewTimestampedUserContent(input)
+		}
+	}
+
 	// Track current node to determine visibility across turns
 	var currentNodeName string
 
@@ -469,6 +592,28 @@ func RunConsole(ctx context.Context, cfg *ConsoleConfig) error {
 	var spinnerDone chan struct{}
 	var currentSpinnerText string
 
+	// Summary of the run, sent when it reaches END
+	var runSummary *agent.RunSummary
+
+	// Live execution trace (--trace), shown beside the spinner
+	var trace *ui.FlowTrace
+	if cfg.Trace {
+		trace = ui.NewFlowTrace()
+	}
+
+	// Markdown transcript (--transcript), written when the console exits
+	var transcript *TranscriptWriter
+	if cfg.Transcript != "" {
+		transcript = NewTranscriptWriter(cfg.AgentConfig, cfg.FlowName, cfg.TranscriptPrompts)
+		defer func() {
+			if err := transcript.Save(cfg.Transcript); err != nil {
+				fmt.Println(ui.RenderStatusBadge(fmt.Sprintf("Failed to save the transcript: %v", err), false))
+				return
+			}
+			fmt.Println(ui.RenderStatusBadge(fmt.Sprintf("Transcript written to %s", cfg.Transcript), true))
+		}()
+	}
+
 	stopSpinner := func(markDone bool, success bool) {
 		if spinnerProgram != nil {
 			spinnerProgram.Quit()
@@ -489,11 +634,18 @@ func RunConsole(ctx context.Context, cfg *ConsoleConfig) error {
 		}
 	}
 
+	if debugger != nil {
+		debugger.pause = func() { stopSpinner(true, true) }
+	}
+
 	startSpinner := func(text string) {
 		stopSpinner(true, true) // Mark previous spinner as done before starting new one
 		currentSpinnerText = text
 		spinnerDone = make(chan struct{})
-		model := ui.NewSpinner(text)
+		var model tea.Model = ui.NewSpinner(text)
+		if trace != nil {
+			model = ui.NewTraceSpinner(text, trace)
+		}
 		spinnerProgram = tea.NewProgram(model, tea.WithInput(nil))
 		go func() {
 			spinnerProgram.Run()
@@ -515,12 +667,20 @@ func RunConsole(ctx context.Context, cfg *ConsoleConfig) error {
 		isOutputNode := false
 		waitingForInput := false
 		waitingForApproval := false
+		costPrompt := "" // Set when the run waits for a cost_guard confirmation
 		var approvalOptions []string
 		var inputOptions []string
+		var inputType, inputDefault string
+		inputMultiSelect := false
+		var inputMode, inputFileRoot, inputFilePattern string
 		isAutoApproved := false
 
 		// Declare suppression variables here so they are accessible throughout the loop and after
 		suppressStreaming := false
+		// LLM nodes with preview: text that is not streamed is shown dimmed under the spinner
+		previewing := false
+		var previewText strings.Builder
+		var lastPreview time.Time
 		var userMessageFields []string
 
 		// seenPartialText filters out aggregated text events that duplicate
@@ -529,13 +689,23 @@ func RunConsole(ctx context.Context, cfg *ConsoleConfig) error {
 		nodeJustChanged := false          // Flag to skip userMessage processing on initial node change event
 		turnHadUserMessageFields := false // Track if any node in this turn had userMessageFields (persists across node changes)
 
-		for event, err := range r.Run(ctx, userID, sess.ID(), userMsg, adkagent.RunConfig{
+		// Ctrl+C stops the flow at the running node instead of killing the
+		// process, so MCP servers and sandboxes are cleaned up and the
+		// session can be resumed. Prompts between turns keep the default.
+		runCtx, stopSignals := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stopSignals()
+		var cancelledAt string
+		stepAborted := false
+		var runAborted *RunAbortedError
+
+		for event, err := range r.Run(runCtx, userID, sess.ID(), userMsg, adkagent.RunConfig{
 			StreamingMode: adkagent.StreamingModeSSE,
 		}) {
 			if err != nil {
 				fmt.Printf("\nERROR: %v\n", err)
 				return err
 			}
+			transcript.HandleEvent(event)
 
 			// Reset the nodeJustChanged flag at start of each event
 			// It will be set to true only if this event triggers a node change
@@ -572,6 +742,35 @@ func RunConsole(ctx context.Context, cfg *ConsoleConfig) error {
 
 			// Check for user_message display marker - this indicates user_message text will be in this event
 			if event.Actions.StateDelta != nil {
+				// display: summary events are printed verbatim regardless of suppression
+				if _, hasMarker := event.Actions.StateDelta["_display_summary"]; hasMarker {
+					stopSpinner(true, true)
+					if !aiPrefixPrinted {
+						fmt.Printf("\n%sAgent:%s\n", ColorGreen, ColorReset)
+						aiPrefixPrinted = true
+					}
+					if event.LLMResponse.Content != nil {
+						for _, part := range event.LLMResponse.Content.Parts {
+							if part.Text != "" {
+								fmt.Println(part.Text)
+							}
+						}
+					}
+					continue
+				}
+
+				// Cost confirmations can come before any node runs, so they are
+				// asked at the end of the turn instead of as node input
+				if awaiting, _ := event.Actions.StateDelta["awaiting_cost_confirmation"].(bool); awaiting {
+					costPrompt = ""
+					if event.LLMResponse.Content != nil {
+						for _, part := range event.LLMResponse.Content.Parts {
+							costPrompt += part.Text
+						}
+					}
+					continue
+				}
+
 				if _, hasMarker := event.Actions.StateDelta["_user_message_display"]; hasMarker {
 					// Stop spinner and print Agent: prefix before the user_message content
 					stopSpinner(true, true)
@@ -605,6 +804,55 @@ func RunConsole(ctx context.Context, cfg *ConsoleConfig) error {
 					startSpinner(spinnerText)
 				}
 
+				// Retry with a compressed context after a context overflow
+				if recovery, ok := event.Actions.StateDelta["_context_recovery"].(map[string]any); ok {
+					stopSpinner(false, true)
+					note := "history compressed"
+					if keys, _ := recovery["truncated_keys"].([]string); len(keys) > 0 {
+						note += ", truncated " + strings.Join(keys, ", ")
+					}
+					fmt.Printf("   %sContext window exceeded, retrying once (%s)%s\n", ColorYellow, note, ColorReset)
+				}
+
+				// Blocked responses handled by on_content_filter (failures
+				// without a fallback are shown by _failure_info)
+				if filterInfo, ok := event.Actions.StateDelta["_content_filter"].(map[string]any); ok {
+					if action, _ := filterInfo["action"].(string); action != "fail" {
+						stopSpinner(false, true)
+						fmt.Printf("   %sResponse blocked by the content filter, fallback: %s%s\n", ColorYellow, action, ColorReset)
+					}
+				}
+
+				// A node nearing its max_tool_calls
+				if warning, ok := event.Actions.StateDelta[agent.ToolCallWarningKey].(map[string]any); ok {
+					stopSpinner(false, true)
+					fmt.Printf("   %sWarning: %v%s\n", ColorYellow, warning["message"], ColorReset)
+				}
+
+				if cancelled, ok := event.Actions.StateDelta["_cancelled"].(map[string]any); ok {
+					cancelledAt, _ = cancelled["node"].(string)
+				}
+				if e := runAbortedError(event.Actions.StateDelta); e != nil {
+					runAborted = e
+				}
+				if aborted, ok := event.Actions.StateDelta["_step_aborted"].(map[string]any); ok {
+					stepAborted = true
+					fmt.Printf("\n%sRun aborted before node %v.%s\n", ColorYellow, aborted["node"], ColorReset)
+				}
+
+				// Output that failed the node's guardrails (blocked output is
+				// shown by _failure_info)
+				if guardrail, ok := event.Actions.StateDelta["_guardrail"].(map[string]any); ok {
+					switch action, _ := guardrail["action"].(string); action {
+					case "redact":
+						stopSpinner(false, true)
+						fmt.Printf("   %sGuardrails redacted parts of the output%s\n", ColorYellow, ColorReset)
+					case "route":
+						stopSpinner(false, true)
+						fmt.Printf("   %sOutput failed the guardrails, handing over to the error handler%s\n", ColorYellow, ColorReset)
+					}
+				}
+
 				// Check for Retry Info
 				if retryInfoVal, ok := event.Actions.StateDelta["_retry_info"]; ok {
 					if retryInfo, ok := retryInfoVal.(map[string]any); ok {
@@ -626,12 +874,29 @@ func RunConsole(ctx context.Context, cfg *ConsoleConfig) error {
 						}
 
 						reason := retryInfo["reason"].(string)
+						if trace != nil {
+							trace.Retry(attempt, maxRetries)
+						}
 
 						// Render badge
 						badge := ui.RenderRetryBadge(attempt, maxRetries, reason)
 
 						// Print with indentation (3 spaces) - no leading newline
 						fmt.Printf("   %s\n", badge)
+
+						// Count down the backoff wait until the next attempt starts
+						if wait, ok := retryInfo["wait_seconds"].(float64); ok && wait >= 1 {
+							secs := int(math.Ceil(wait))
+							startSpinner(fmt.Sprintf("Retrying in %ds...", secs))
+							currentSpinnerText = "" // transient; don't leave a ✓ line behind
+							prog := spinnerProgram
+							go func() {
+								for s := secs - 1; s > 0; s-- {
+									time.Sleep(time.Second)
+									prog.Send(ui.SpinnerTextMsg{Text: fmt.Sprintf("Retrying in %ds...", s)})
+								}
+							}()
+						}
 					}
 				}
 
@@ -655,6 +920,23 @@ func RunConsole(ctx context.Context, cfg *ConsoleConfig) error {
 				}
 			}
 
+			// Node start events carry node_type; they mark every visit, loops included.
+			// An input node that opens the flow only sends its prompt event.
+			if trace != nil && event.Actions.StateDelta != nil {
+				node, _ := event.Actions.StateDelta["current_node"].(string)
+				nodeType, ok := event.Actions.StateDelta["node_type"].(string)
+				waiting, _ := event.Actions.StateDelta["waiting_for_input"].(bool)
+				switch {
+				case node == "":
+				case ok && node == "END":
+					trace.Finish()
+				case ok:
+					trace.Enter(node, nodeType)
+				case waiting && trace.Current() != node:
+					trace.Enter(node, "input")
+				}
+			}
+
 			// Update current node from StateDelta if present
 			if event.Actions.StateDelta != nil {
 				if node, ok := event.Actions.StateDelta["current_node"].(string); ok {
@@ -671,13 +953,13 @@ func RunConsole(ctx context.Context, cfg *ConsoleConfig) error {
 
 						// Reset and re-evaluate suppression for the NEW node
 						suppressStreaming = false
+						previewing = false
+						previewText.Reset()
 						userMessageFields = nil
 						isInputNode = false
 						isOutputNode = false
 						isParallel := false
 						isSilent := false
-						hasUserMessage := false
-						hasOutputModel := false
 						isAutoApproved = false
 
 						for _, n := range cfg.AgentConfig.Nodes {
@@ -696,16 +978,15 @@ func RunConsole(ctx context.Context, cfg *ConsoleConfig) error {
 										isSilent = true
 									}
 
-									hasUserMessage = len(n.UserMessage) > 0
-									hasOutputModel = len(n.OutputModel) > 0
-
-									if hasUserMessage {
+									switch n.DisplayMode() {
+									case config.DisplayUserMessage:
 										suppressStreaming = true
 										userMessageFields = n.UserMessage
 										turnHadUserMessageFields = true // Remember this turn had user_message
-									} else if hasOutputModel {
+									case config.DisplayNone, config.DisplaySummary:
 										suppressStreaming = true
 									}
+									previewing = suppressStreaming && n.Type == "llm" && (n.Preview || cfg.Preview)
 								}
 								if cfg.DebugMode {
 									slog.Debug("node changed", "node", currentNodeName, "suppressStreaming", suppressStreaming, "isParallel", isParallel)
@@ -819,6 +1100,22 @@ func RunConsole(ctx context.Context, cfg *ConsoleConfig) error {
 					}
 				}
 
+				// Check for the input type and default of input nodes
+				if v, ok := event.Actions.StateDelta["input_type"].(string); ok {
+					inputType = v
+				}
+				if v, ok := event.Actions.StateDelta["input_default"].(string); ok {
+					inputDefault = v
+				}
+				if v, ok := event.Actions.StateDelta["input_multi_select"].(bool); ok {
+					inputMultiSelect = v
+				}
+				if v, ok := event.Actions.StateDelta["input_mode"].(string); ok {
+					inputMode = v
+					inputFileRoot, _ = event.Actions.StateDelta["input_file_root"].(string)
+					inputFilePattern, _ = event.Actions.StateDelta["input_file_pattern"].(string)
+				}
+
 				// Check for UserMessage fields in StateDelta and print them if found
 				// Only run this if we're in user_message mode (suppressStreaming with userMessageFields)
 				// IMPORTANT: Skip on events that just triggered a node change - wait for actual LLM response
@@ -843,9 +1140,10 @@ func RunConsole(ctx context.Context, cfg *ConsoleConfig) error {
 							aiPrefixPrinted = true
 						}
 
-						// Now print each field
+						// Now print each field (sensitive values masked)
+						displayDelta := astonishAgent.MaskSensitiveState(event.Actions.StateDelta)
 						for _, field := range userMessageFields {
-							if val, ok := event.Actions.StateDelta[field]; ok {
+							if val, ok := displayDelta[field]; ok {
 								// Format the value for display
 								var displayStr string
 								switch v := val.(type) {
@@ -884,6 +1182,23 @@ func RunConsole(ctx context.Context, cfg *ConsoleConfig) error {
 				}
 			}
 
+			// The run summary is printed once the run ends
+			if summary, ok := event.Actions.StateDelta[agent.RunSummaryKey].(agent.RunSummary); ok {
+				runSummary = &summary
+				continue
+			}
+
+			// Diff previews of output nodes are rendered instead of their text
+			if preview, ok := event.Actions.StateDelta[agent.DiffPreviewKey].(map[string]any); ok {
+				stopSpinner(true, true)
+				path, _ := preview["path"].(string)
+				diff, _ := preview["diff"].(string)
+				style, _ := preview["style"].(string)
+				fmt.Println()
+				fmt.Print(ui.RenderDiff(diff, path, style == config.DiffSideBySide))
+				continue
+			}
+
 			if event.LLMResponse.Content == nil {
 				continue
 			}
@@ -906,6 +1221,15 @@ func RunConsole(ctx context.Context, cfg *ConsoleConfig) error {
 				}
 			}
 
+			// Throttled so fast streams do not redraw the spinner for every token
+			if chunk != "" && previewing && spinnerProgram != nil {
+				previewText.WriteString(chunk)
+				if time.Since(lastPreview) >= previewInterval {
+					spinnerProgram.Send(ui.SpinnerPreviewMsg{Text: ui.PreviewTail(previewText.String())})
+					lastPreview = time.Now()
+				}
+			}
+
 			if chunk != "" {
 				lineBuffer += chunk
 
@@ -1078,7 +1402,7 @@ func RunConsole(ctx context.Context, cfg *ConsoleConfig) error {
 			// Check if we're at an input node
 			if currentNodeName != "" {
 				for _, node := range cfg.AgentConfig.Nodes {
-					if node.Name == currentNodeName && node.Type == "input" {
+					if node.Name == currentNodeName && (node.Type == "input" || node.Confirm != "") {
 						waitingForInput = true
 						break
 					}
@@ -1086,6 +1410,24 @@ func RunConsole(ctx context.Context, cfg *ConsoleConfig) error {
 			}
 		}
 
+		if costPrompt != "" {
+			stopSpinner(true, true)
+			title, description, _ := strings.Cut(strings.TrimSpace(costPrompt), "\n")
+			// Non-interactive runs fail rather than cancel silently
+			if cfg.NonInteractive {
+				return fmt.Errorf("the run needs a cost confirmation in non-interactive mode (%s): pass --auto-approve or raise cost_guard.threshold", title)
+			}
+			selection, err := readSelection([]string{"Yes", "No"}, title, description)
+			if err != nil {
+				return err
+			}
+			if selection == "Yes" {
+				fmt.Println(ui.RenderStatusBadge("Cost confirmed", true))
+			}
+			userMsg = agent.NewTimestampedUserContent(selection)
+			continue
+		}
+
 		// Flush any remaining content in lineBuffer
 		if lineBuffer != "" {
 			// Only flush if NOT suppressed OR if it's an input node (to capture prompt)
@@ -1136,11 +1478,33 @@ func RunConsole(ctx context.Context, cfg *ConsoleConfig) error {
 			// Stop spinner before showing input
 			stopSpinner(true, true)
 
+			// Replays answer input nodes and approvals as they were recorded
+			if player != nil && (waitingForInput || waitingForApproval) {
+				kind := cassette.KindInput
+				if waitingForApproval {
+					kind = cassette.KindApproval
+				}
+				if val, ok := player.Answer(kind, currentNodeName); ok {
+					fmt.Printf("✓ Replaying recorded answer for '%s': %s\n", currentNodeName, val)
+					if waitingForApproval {
+						transcript.Approval(val)
+					} else {
+						transcript.Input(val)
+					}
+					userMsg = agent.NewTimestampedUserContent(val)
+					waitingForInput, waitingForApproval = false, false
+					approvalOptions, inputOptions = nil, nil
+					continue
+				}
+			}
+
 			// Check for CLI parameter override for input nodes
 			if waitingForInput && cfg.Parameters != nil {
 				if val, ok := cfg.Parameters[currentNodeName]; ok {
 					// Print confirmation
 					fmt.Printf("✓ Using provided value for '%s': %s\n", currentNodeName, val)
+					recorder.Answer(cassette.KindInput, currentNodeName, val)
+					transcript.Input(val)
 
 					// Create user message with provided value
 					userMsg = agent.NewTimestampedUserContent(val)
@@ -1151,6 +1515,20 @@ func RunConsole(ctx context.Context, cfg *ConsoleConfig) error {
 				}
 			}
 
+			// Non-interactive runs (CI) take the input node's default, or
+			// fail instead of blocking on it
+			if waitingForInput && cfg.NonInteractive && inputDefault != "" {
+				fmt.Printf("✓ Using the default value for '%s': %s\n", currentNodeName, inputDefault)
+				recorder.Answer(cassette.KindInput, currentNodeName, inputDefault)
+				transcript.Input(inputDefault)
+				userMsg = agent.NewTimestampedUserContent(inputDefault)
+				waitingForInput = false
+				continue
+			}
+			if waitingForInput && cfg.NonInteractive {
+				return fmt.Errorf("input node '%s' needs a value in non-interactive mode: pass -p %s=<value>", currentNodeName, currentNodeName)
+			}
+
 			// Show input dialog
 			if waitingForApproval {
 				// Handle Auto-Approval
@@ -1164,6 +1542,8 @@ func RunConsole(ctx context.Context, cfg *ConsoleConfig) error {
 					fmt.Println(ui.RenderStatusBadge("Auto Approved", true))
 
 					// Simulate "Yes" selection
+					recorder.Answer(cassette.KindApproval, currentNodeName, "Yes")
+					transcript.Approval("Yes")
 					userMsg = agent.NewTimestampedUserContent("Yes")
 
 					// Reset state
@@ -1172,6 +1552,20 @@ func RunConsole(ctx context.Context, cfg *ConsoleConfig) error {
 					isAutoApproved = false
 					continue
 				}
+				// Non-interactive runs deny what was not auto-approved
+				if cfg.NonInteractive {
+					if description != "" {
+						fmt.Println(description)
+					}
+					fmt.Println(ui.RenderStatusBadge("Denied (non-interactive, use --auto-approve to allow)", false))
+					recorder.Answer(cassette.KindApproval, currentNodeName, "No")
+					transcript.Approval("No")
+					userMsg = agent.NewTimestampedUserContent("No")
+
+					waitingForApproval = false
+					approvalOptions = nil
+					continue
+				}
 				// Use approval options if available
 				opts := []string{"Yes", "No"}
 				if len(approvalOptions) > 0 {
@@ -1183,7 +1577,7 @@ func RunConsole(ctx context.Context, cfg *ConsoleConfig) error {
 					title = "Approval Required"
 				}
 
-				selection, err := ui.ReadSelection(opts, title, description)
+				selection, err := readSelection(opts, title, description)
 				if err != nil {
 					return err
 				}
@@ -1191,9 +1585,13 @@ func RunConsole(ctx context.Context, cfg *ConsoleConfig) error {
 				// Send selection back to agent
 				if selection == "Yes" {
 					fmt.Println(ui.RenderStatusBadge("Command approved", true))
+				} else if agent.ApprovesCall(selection) {
+					fmt.Println(ui.RenderStatusBadge("Command approved, "+strings.ToLower(selection), true))
 				} else {
 					fmt.Println(ui.RenderStatusBadge("Command rejected", false))
 				}
+				recorder.Answer(cassette.KindApproval, currentNodeName, selection)
+				transcript.Approval(selection)
 				userMsg = agent.NewTimestampedUserContent(selection)
 				continue
 
@@ -1207,26 +1605,77 @@ func RunConsole(ctx context.Context, cfg *ConsoleConfig) error {
 					title = "Input Required"
 				}
 
+				// File pickers send the chosen path, or a JSON list of paths
+				if inputMode == config.InputModeFile {
+					paths, err := ui.ReadFiles(inputFileRoot, inputFilePattern, inputMultiSelect, title, description)
+					if err != nil {
+						return err
+					}
+					answer := strings.Join(paths, ", ")
+					if inputMultiSelect {
+						data, err := json.Marshal(paths)
+						if err != nil {
+							return err
+						}
+						answer = string(data)
+					}
+					displayTitle := strings.TrimSuffix(title, ":")
+					fmt.Println(ui.RenderStatusBadge(fmt.Sprintf("%s: %s", displayTitle, strings.Join(paths, ", ")), true))
+					recorder.Answer(cassette.KindInput, currentNodeName, answer)
+					transcript.Input(strings.Join(paths, ", "))
+					userMsg = agent.NewTimestampedUserContent(answer)
+					continue
+				}
+
 				// Check if we have options for selection
-				if len(inputOptions) > 0 {
-					selection, err := ui.ReadSelection(inputOptions, title, description)
+				if len(inputOptions) > 0 && inputMultiSelect {
+					selection, err := ui.ReadMultiSelection(inputOptions, title, description)
+					if err != nil {
+						return err
+					}
+					answer, err := json.Marshal(selection)
+					if err != nil {
+						return err
+					}
+					displayTitle := strings.TrimSuffix(title, ":")
+					fmt.Println(ui.RenderStatusBadge(fmt.Sprintf("%s: %s", displayTitle, strings.Join(selection, ", ")), true))
+					recorder.Answer(cassette.KindInput, currentNodeName, string(answer))
+					transcript.Input(strings.Join(selection, ", "))
+					userMsg = agent.NewTimestampedUserContent(string(answer))
+					continue
+				} else if len(inputOptions) > 0 {
+					selection, err := readSelection(inputOptions, title, description)
 					if err != nil {
 						return err
 					}
 					// Strip trailing colon from title for cleaner display
 					displayTitle := strings.TrimSuffix(title, ":")
 					fmt.Println(ui.RenderStatusBadge(fmt.Sprintf("%s: %s", displayTitle, selection), true))
+					recorder.Answer(cassette.KindInput, currentNodeName, selection)
+					transcript.Input(selection)
 					userMsg = agent.NewTimestampedUserContent(selection)
 					continue
 				} else {
 					// Free text input
-					input, err := ui.ReadInput(title, description)
+					input, err := ui.ReadInputWith(title, description, ui.InputOptions{
+						Secret:    inputType == config.InputSecret,
+						Multiline: inputType == config.InputMultiline,
+						Default:   inputDefault,
+					})
 					if err != nil {
 						return err
 					}
 					// Strip trailing colon from title for cleaner display
 					displayTitle := strings.TrimSuffix(title, ":")
-					fmt.Println(ui.RenderStatusBadge(fmt.Sprintf("%s: %s", displayTitle, input), true))
+					shown := input
+					if inputType == config.InputSecret {
+						shown = "••••••"
+					} else if shown == "" {
+						shown = inputDefault
+					}
+					fmt.Println(ui.RenderStatusBadge(fmt.Sprintf("%s: %s", displayTitle, shown), true))
+					recorder.Answer(cassette.KindInput, currentNodeName, input)
+					transcript.Input(input)
 					userMsg = agent.NewTimestampedUserContent(input)
 					continue
 				}
@@ -1263,10 +1712,65 @@ func RunConsole(ctx context.Context, cfg *ConsoleConfig) error {
 				textBuffer.Reset()
 			}
 		}
+		// Checked before stopSignals, which cancels runCtx too
+		interrupted := runCtx.Err() != nil && ctx.Err() == nil
+		stopSignals()
+
+		if interrupted {
+			stopSpinner(true, false)
+			fmt.Printf("\n%sRun cancelled at node %s.%s\n", ColorYellow, cancelledAt, ColorReset)
+			if cfg.AppConfig != nil && cfg.AppConfig.Sessions.Storage == "file" {
+				fmt.Printf("%sResume with: astonish flows run %s --resume %s%s\n", ColorGray, cfg.FlowName, sess.ID(), ColorReset)
+			}
+			return ErrRunCancelled
+		}
+		if stepAborted {
+			return nil
+		}
 
 		// If we broke out of the loop (e.g. END node), stop spinner
 		if currentNodeName == "END" {
 			stopSpinner(true, true)
+			if trace != nil {
+				trace.Finish()
+				fmt.Println(trace.View())
+			}
+			if runSummary != nil {
+				fmt.Println()
+				fmt.Print(renderRunSummary(*runSummary))
+				runSummary = nil
+			}
+			if transcript != nil {
+				transcript.Finish(headlessFinalState(ctx, sessionService, appName, userID, sess.ID()))
+			}
+			if path := exportStatePath(cfg.ExportState, cfg.AgentConfig); path != "" {
+				state := headlessFinalState(ctx, sessionService, appName, userID, sess.ID())
+				if err := ExportState(path, cfg.AgentConfig, state); err != nil {
+					return fmt.Errorf("failed to export state: %w", err)
+				}
+				fmt.Println(ui.RenderStatusBadge(fmt.Sprintf("State exported to %s", path), true))
+			}
+			if chatInput != nil && runAborted == nil {
+				if input, ok := readChatMessage(chatInput); ok {
+					userMsg = agent.NewTimestampedUserContent(input)
+					if trace != nil {
+						trace = ui.NewFlowTrace()
+					}
+					continue
+				}
+			}
+			if svc, err := artifacts.Default(); err == nil {
+				if saved, _ := svc.SessionArtifacts(sess.ID()); len(saved) > 0 {
+					names := make([]string, 0, len(saved))
+					for _, info := range saved {
+						names = append(names, info.Name)
+					}
+					fmt.Println(ui.RenderStatusBadge(fmt.Sprintf("Artifacts saved: %s (astonish flows artifacts %s)", strings.Join(names, ", "), sess.ID()), true))
+				}
+			}
+			if runAborted != nil {
+				return runAborted
+			}
 			if cfg.DebugMode {
 				slog.Debug("reached END node, exiting main loop")
 			}
@@ -1277,3 +1781,48 @@ func RunConsole(ctx context.Context, cfg *ConsoleConfig) error {
 	}
 	return nil
 }
+
+// readChatMessage reads the next message of a chat-mode flow. It returns
+// false when the user exits or the input ends.
+func readChatMessage(reader *bufio.Reader) (string, bool) {
+	for {
+		fmt.Printf("\n%sYou:%s ", ColorCyan, ColorReset)
+		input, err := reader.ReadString('\n')
+		input = strings.TrimSpace(input)
+		if strings.EqualFold(input, "exit") || strings.EqualFold(input, "quit") {
+			return "", false
+		}
+		if input != "" {
+			return input, true
+		}
+		if err != nil {
+			fmt.Println()
+			return "", false
+		}
+	}
+}
+
+// renderRunSummary renders the summary of a run as the table printed at END.
+func renderRunSummary(s agent.RunSummary) string {
+	rows := make([]ui.RunSummaryRow, len(s.Path))
+	for i, n := range s.Path {
+		rows[i] = ui.RunSummaryRow{
+			Node:      n.Node,
+			Duration:  time.Duration(n.DurationMs) * time.Millisecond,
+			Retries:   n.Retries,
+			ToolCalls: n.ToolCalls,
+			Tokens:    n.InputTokens + n.OutputTokens,
+		}
+	}
+	footer := []string{fmt.Sprintf("%d tool calls", s.ToolCalls)}
+	if s.ApprovalsRequested > 0 {
+		footer = append(footer, fmt.Sprintf("%d approvals (%d denied)", s.ApprovalsRequested, s.ApprovalsDenied))
+	}
+	if tokens := s.InputTokens + s.OutputTokens; tokens > 0 {
+		footer = append(footer, fmt.Sprintf("%d tokens (%d in, %d out)", tokens, s.InputTokens, s.OutputTokens))
+	}
+	if s.CostUSD > 0 {
+		footer = append(footer, fmt.Sprintf("~$%.4f", s.CostUSD))
+	}
+	return ui.RenderRunSummary(rows, time.Duration(s.DurationMs)*time.Millisecond, strings.Join(footer, " · "))
+}
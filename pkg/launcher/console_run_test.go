@@ -0,0 +1,231 @@
+package launcher
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/cassette"
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// greetFlow is a one-node flow whose model call greetTape answers.
+func greetFlow() *config.AgentConfig {
+	return &config.AgentConfig{
+		Nodes: []config.Node{{Name: "greet", Type: "llm", Prompt: "Say hello"}},
+		Flow:  []config.FlowItem{{From: "START", To: "greet"}, {From: "greet", To: "END"}},
+	}
+}
+
+var greetTape = cassette.Cassette{
+	Version: cassette.Version,
+	Interactions: []cassette.Interaction{{
+		Kind:      cassette.KindLLM,
+		Model:     "test-model",
+		Responses: []cassette.Response{{Content: genai.NewContentFromText("Hello from greet", genai.RoleModel), TurnComplete: true}},
+	}},
+}
+
+// costlyConfig makes every LLM node exceed the cost_guard threshold.
+func costlyConfig() *config.AppConfig {
+	appCfg := &config.AppConfig{}
+	appCfg.CostGuard.Threshold = 0.01
+	appCfg.CostGuard.Pricing = map[string]config.ModelPrice{"test-model": {Input: 1000, Output: 1000}}
+	return appCfg
+}
+
+// replayConsole runs cfg's flow through RunConsole with model and tool calls
+// answered by tape, and returns the run's error and the text of the
+// session's events.
+func replayConsole(t *testing.T, cfg *ConsoleConfig, tape cassette.Cassette) (string, error) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	data, err := json.Marshal(tape)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Replay = filepath.Join(t.TempDir(), "run.cassette.json")
+	if err := os.WriteFile(cfg.Replay, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.AppConfig == nil {
+		cfg.AppConfig = &config.AppConfig{}
+	}
+	cfg.ModelName = "test-model"
+	sessions := session.InMemoryService()
+	cfg.SessionService = sessions
+
+	runErr := RunConsole(context.Background(), cfg)
+
+	ctx := context.Background()
+	list, err := sessions.List(ctx, &session.ListRequest{AppName: "astonish", UserID: "console_user"})
+	if err != nil || len(list.Sessions) != 1 {
+		t.Fatalf("sessions = %v, %v; want one", list, err)
+	}
+	resp, err := sessions.Get(ctx, &session.GetRequest{AppName: "astonish", UserID: "console_user", SessionID: list.Sessions[0].ID()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var text strings.Builder
+	for event := range resp.Session.Events().All() {
+		if event.LLMResponse.Content == nil {
+			continue
+		}
+		for _, part := range event.LLMResponse.Content.Parts {
+			text.WriteString(part.Text)
+			if part.FunctionResponse != nil {
+				data, _ := json.Marshal(part.FunctionResponse.Response)
+				text.Write(data)
+			}
+		}
+	}
+	return text.String(), runErr
+}
+
+// stubSelection answers every selection prompt with answer and records the
+// prompt titles.
+func stubSelection(t *testing.T, answer string) *[]string {
+	t.Helper()
+	var asked []string
+	prev := readSelection
+	readSelection = func(options []string, title, description string) (string, error) {
+		asked = append(asked, title)
+		return answer, nil
+	}
+	t.Cleanup(func() { readSelection = prev })
+	return &asked
+}
+
+func TestRunConsoleCostConfirmation(t *testing.T) {
+	tests := []struct {
+		answer      string
+		wantGreeted bool
+	}{
+		{answer: "Yes", wantGreeted: true},
+		{answer: "No", wantGreeted: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.answer, func(t *testing.T) {
+			asked := stubSelection(t, tt.answer)
+			text, err := replayConsole(t, &ConsoleConfig{AgentConfig: greetFlow(), AppConfig: costlyConfig()}, greetTape)
+			if err != nil {
+				t.Fatalf("RunConsole: %v", err)
+			}
+			if len(*asked) != 1 || !strings.HasPrefix((*asked)[0], "Estimated cost:") {
+				t.Fatalf("prompts = %q, want one cost confirmation", *asked)
+			}
+			if greeted := strings.Contains(text, "Hello from greet"); greeted != tt.wantGreeted {
+				t.Errorf("node ran = %v, want %v (events: %q)", greeted, tt.wantGreeted, text)
+			}
+		})
+	}
+}
+
+func TestRunConsoleNonInteractive(t *testing.T) {
+	noteTape := cassette.Cassette{
+		Version: cassette.Version,
+		Tools:   map[string]*genai.FunctionDeclaration{"write_note": {Name: "write_note", Description: "Write a note"}},
+		Interactions: []cassette.Interaction{{
+			Kind:   cassette.KindTool,
+			Tool:   "write_note",
+			Args:   map[string]any{"text": "hi"},
+			Result: map[string]any{"status": "note written"},
+		}},
+	}
+	tests := []struct {
+		name    string
+		flow    *config.AgentConfig
+		appCfg  *config.AppConfig
+		tape    cassette.Cassette
+		wantErr string // Empty when the run must finish
+		notRun  string // Text that must not appear in the session
+		ran     string // Text that must appear in the session
+	}{
+		{
+			name: "input node without a value fails",
+			flow: &config.AgentConfig{
+				Nodes: []config.Node{{Name: "ask_env", Type: "input", Prompt: "Which environment?"}},
+				Flow:  []config.FlowItem{{From: "START", To: "ask_env"}, {From: "ask_env", To: "END"}},
+			},
+			tape:    cassette.Cassette{Version: cassette.Version},
+			wantErr: "input node 'ask_env' needs a value in non-interactive mode",
+		},
+		{
+			name: "input node answers with its default",
+			flow: &config.AgentConfig{
+				Nodes: []config.Node{{Name: "ask_env", Type: "input", Prompt: "Which environment?", Default: "staging"}},
+				Flow:  []config.FlowItem{{From: "START", To: "ask_env"}, {From: "ask_env", To: "END"}},
+			},
+			tape: cassette.Cassette{Version: cassette.Version},
+			ran:  "staging",
+		},
+		{
+			name: "tool approval is denied",
+			flow: &config.AgentConfig{
+				Nodes: []config.Node{{Name: "note", Type: "tool", ToolsSelection: []string{"write_note"}, Args: map[string]any{"text": "hi"}}},
+				Flow:  []config.FlowItem{{From: "START", To: "note"}, {From: "note", To: "END"}},
+			},
+			tape:   noteTape,
+			notRun: "note written",
+		},
+		{
+			name:    "cost confirmation fails",
+			flow:    greetFlow(),
+			appCfg:  costlyConfig(),
+			tape:    greetTape,
+			wantErr: "the run needs a cost confirmation in non-interactive mode",
+			notRun:  "Hello from greet",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asked := stubSelection(t, "Yes")
+			text, err := replayConsole(t, &ConsoleConfig{AgentConfig: tt.flow, AppConfig: tt.appCfg, NonInteractive: true}, tt.tape)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("RunConsole: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("RunConsole error = %v, want %q", err, tt.wantErr)
+			}
+			if len(*asked) != 0 {
+				t.Errorf("non-interactive run prompted for %q", *asked)
+			}
+			if tt.notRun != "" && strings.Contains(text, tt.notRun) {
+				t.Errorf("session contains %q: %q", tt.notRun, text)
+			}
+			if !strings.Contains(text, tt.ran) {
+				t.Errorf("session = %q, want %q", text, tt.ran)
+			}
+		})
+	}
+}
+
+func TestRunConsoleReActAfterToolCallingRefused(t *testing.T) {
+	// The provider is assumed to support tool calling, but the model
+	// refuses; the node is answered again through the ReAct planner
+	tape := cassette.Cassette{
+		Version: cassette.Version,
+		Interactions: []cassette.Interaction{
+			{Kind: cassette.KindLLM, Model: "test-model", Error: "error, status code: 404, message: No endpoints found that support tool use"},
+			{
+				Kind:      cassette.KindLLM,
+				Model:     "test-model",
+				Responses: []cassette.Response{{Content: genai.NewContentFromText("Final Answer: Hello from ReAct", genai.RoleModel), TurnComplete: true}},
+			},
+		},
+	}
+	text, err := replayConsole(t, &ConsoleConfig{AgentConfig: greetFlow()}, tape)
+	if err != nil {
+		t.Fatalf("RunConsole: %v", err)
+	}
+	if !strings.Contains(text, "Hello from ReAct") {
+		t.Errorf("session = %q, want the ReAct answer", text)
+	}
+}
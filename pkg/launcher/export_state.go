@@ -0,0 +1,38 @@
+package launcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/SAP/astonish/pkg/config"
+)
+
+// exportStatePath returns the file the final state of a run is written to:
+// the --export-state value, or else the flow's export_state option.
+func exportStatePath(override string, cfg *config.AgentConfig) string {
+	if override != "" {
+		return override
+	}
+	return cfg.ExportState
+}
+
+// ExportState writes the final state of a run to path as indented JSON.
+// Internal (_-prefixed), temporary, engine and sensitive keys are left out,
+// as in the end event of --output json.
+func ExportState(path string, cfg *config.AgentConfig, state map[string]any) error {
+	data, err := json.MarshalIndent(publicState(cfg, state), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", path, err)
+		}
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package launcher
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/config"
+)
+
+func TestExportState(t *testing.T) {
+	cfg := &config.AgentConfig{
+		ExportState: "from_flow.json",
+		Nodes:       []config.Node{{Name: "login", Type: "llm", OutputModel: map[string]string{"token": "str"}, Sensitive: []string{"token"}}},
+	}
+	if got := exportStatePath("", cfg); got != "from_flow.json" {
+		t.Errorf("exportStatePath = %q, want the flow option", got)
+	}
+	if got := exportStatePath("cli.json", cfg); got != "cli.json" {
+		t.Errorf("exportStatePath = %q, want the flag value", got)
+	}
+
+	path := filepath.Join(t.TempDir(), "out", "state.json")
+	state := map[string]any{
+		"summary":      "ok",
+		"count":        3,
+		"token":        "secret",
+		"_has_error":   false,
+		"temp:scratch": "x",
+		"current_node": "END",
+	}
+	if err := ExportState(path, cfg, state); err != nil {
+		t.Fatalf("ExportState: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("invalid JSON %s: %v", data, err)
+	}
+	if len(got) != 2 || got["summary"] != "ok" || got["count"] != 3.0 {
+		t.Errorf("exported state = %v", got)
+	}
+}
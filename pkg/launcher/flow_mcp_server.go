@@ -0,0 +1,177 @@
+package launcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/version"
+)
+
+// FlowMCPServerConfig configures a flow exposed as an MCP server.
+type FlowMCPServerConfig struct {
+	FlowName     string // Tool name (sanitized) and server name
+	AgentConfig  *config.AgentConfig
+	AppConfig    *config.AppConfig
+	ProviderName string
+	ModelName    string
+	DebugMode    bool
+}
+
+// flowToolNameInvalid matches characters MCP tool names may not contain.
+var flowToolNameInvalid = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// NewFlowMCPServer returns an MCP server with one tool that runs the flow
+// headlessly. The flow's input nodes become the tool's parameters (keyed by
+// node name, like `flows run -p`), and each call returns the flow's output
+// text plus the final values of the state keys its nodes declare in
+// output_model or raw_tool_output as structured content.
+func NewFlowMCPServer(cfg *FlowMCPServerConfig) *mcp.Server {
+	server := mcp.NewServer(&mcp.Implementation{Name: "astonish-" + cfg.FlowName, Version: version.GetVersion()}, nil)
+	server.AddTool(&mcp.Tool{
+		Name:        FlowToolName(cfg.FlowName),
+		Description: cfg.AgentConfig.Description,
+		InputSchema: flowInputSchema(cfg.AgentConfig),
+	}, func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args map[string]any
+		if len(req.Params.Arguments) > 0 {
+			if err := json.Unmarshal(req.Params.Arguments, &args); err != nil {
+				return nil, fmt.Errorf("invalid arguments: %w", err)
+			}
+		}
+		return runFlowTool(ctx, cfg, args), nil
+	})
+	return server
+}
+
+// RunFlowMCPServer serves a flow as an MCP tool over stdin/stdout until the
+// client disconnects or ctx is cancelled.
+func RunFlowMCPServer(ctx context.Context, cfg *FlowMCPServerConfig) error {
+	return NewFlowMCPServer(cfg).Run(ctx, &mcp.StdioTransport{})
+}
+
+// runFlowTool runs the flow for one tool call. Flow failures are reported as
+// tool errors so the calling model can see them.
+func runFlowTool(ctx context.Context, cfg *FlowMCPServerConfig, args map[string]any) *mcp.CallToolResult {
+	params := make(map[string]string, len(args))
+	for k, v := range args {
+		if s, ok := v.(string); ok {
+			params[k] = s
+		} else {
+			params[k] = fmt.Sprint(v)
+		}
+	}
+
+	output, state, err := RunHeadlessWithState(ctx, &HeadlessConfig{
+		AgentConfig:  cfg.AgentConfig,
+		AppConfig:    cfg.AppConfig,
+		ProviderName: cfg.ProviderName,
+		ModelName:    cfg.ModelName,
+		Parameters:   params,
+		DebugMode:    cfg.DebugMode,
+	})
+	if err != nil {
+		slog.Warn("flow tool call failed", "flow", cfg.FlowName, "error", err)
+		text := err.Error()
+		if output != "" {
+			text = output + "\n\n" + text
+		}
+		return &mcp.CallToolResult{IsError: true, Content: []mcp.Content{&mcp.TextContent{Text: text}}}
+	}
+
+	result := &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: output}}}
+	if results := flowResults(cfg.AgentConfig, state); len(results) > 0 {
+		result.StructuredContent = results
+		// Clients without structured content support read the JSON text
+		if data, err := json.Marshal(results); err == nil {
+			result.Content = append(result.Content, &mcp.TextContent{Text: string(data)})
+		}
+	}
+	return result
+}
+
+// FlowToolName turns a flow name into a valid MCP tool name.
+func FlowToolName(flowName string) string {
+	name := strings.Trim(flowToolNameInvalid.ReplaceAllString(flowName, "_"), "_")
+	if name == "" {
+		name = "flow"
+	}
+	if len(name) > 128 {
+		name = name[:128]
+	}
+	return name
+}
+
+// flowInputSchema builds the tool's input schema from the flow's input
+// nodes. Static options become an enum (listed in the description for
+// multi_select nodes, which take them comma-separated); all inputs are
+// required because a headless run cannot ask for missing ones.
+func flowInputSchema(cfg *config.AgentConfig) map[string]any {
+	properties := make(map[string]any)
+	required := []string{}
+	for _, node := range cfg.Nodes {
+		if node.Type != "input" {
+			continue
+		}
+		prop := map[string]any{"type": "string"}
+		if node.Prompt != "" {
+			prop["description"] = node.Prompt
+		}
+		if opts := staticOptions(node.Options); len(opts) > 0 && node.MultiSelect {
+			prop["description"] = strings.TrimSpace(node.Prompt + " (any of, comma-separated: " + strings.Join(opts, ", ") + ")")
+		} else if len(opts) > 0 {
+			prop["enum"] = opts
+		}
+		properties[node.Name] = prop
+		required = append(required, node.Name)
+	}
+	return map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
+
+// staticOptions returns the options of an input node unless they are
+// rendered from state at run time.
+func staticOptions(options []string) []string {
+	for _, o := range options {
+		if strings.Contains(o, "{") {
+			return nil
+		}
+	}
+	return options
+}
+
+// flowResults returns the final values of the state keys the flow's nodes
+// declare as results. Input values, internal keys and sensitive keys are
+// left out.
+func flowResults(cfg *config.AgentConfig, state map[string]any) map[string]any {
+	sensitive := cfg.SensitiveKeys()
+	results := make(map[string]any)
+	add := func(key string) {
+		if strings.HasPrefix(key, "_") || sensitive[key] {
+			return
+		}
+		if v, ok := state[key]; ok {
+			results[key] = v
+		}
+	}
+	for _, node := range cfg.Nodes {
+		if node.Type == "input" {
+			continue
+		}
+		for key := range node.OutputModel {
+			add(key)
+		}
+		for key := range node.RawToolOutput {
+			add(key)
+		}
+	}
+	return results
+}
@@ -0,0 +1,58 @@
+package launcher
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/SAP/astonish/pkg/config"
+)
+
+func TestFlowMCPServer_ToolFromInputNodes(t *testing.T) {
+	cfg := &config.AgentConfig{
+		Description: "Reviews a pull request",
+		Nodes: []config.Node{
+			{Name: "pr_url", Type: "input", Prompt: "Which PR?", OutputModel: map[string]string{"pr_url": "str"}},
+			{Name: "depth", Type: "input", Prompt: "How deep?", Options: []string{"quick", "thorough"}, OutputModel: map[string]string{"depth": "str"}},
+			{Name: "review", Type: "llm", OutputModel: map[string]string{"summary": "str", "_scratch": "str", "token": "str"}, Sensitive: []string{"token"}},
+		},
+	}
+
+	ctx := context.Background()
+	serverT, clientT := mcp.NewInMemoryTransports()
+	if _, err := NewFlowMCPServer(&FlowMCPServerConfig{FlowName: "team/pr review", AgentConfig: cfg}).Connect(ctx, serverT, nil); err != nil {
+		t.Fatal(err)
+	}
+	cs, err := mcp.NewClient(&mcp.Implementation{Name: "test"}, nil).Connect(ctx, clientT, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+
+	tools, err := cs.ListTools(ctx, nil)
+	if err != nil || len(tools.Tools) != 1 {
+		t.Fatalf("ListTools = %v, %v", tools, err)
+	}
+	tool := tools.Tools[0]
+	if tool.Name != "team_pr_review" || tool.Description != "Reviews a pull request" {
+		t.Errorf("tool = %q %q", tool.Name, tool.Description)
+	}
+	data, _ := json.Marshal(tool.InputSchema)
+	var schema struct {
+		Properties map[string]struct {
+			Description string   `json:"description"`
+			Enum        []string `json:"enum"`
+		} `json:"properties"`
+		Required []string `json:"required"`
+	}
+	json.Unmarshal(data, &schema)
+	if len(schema.Required) != 2 || schema.Properties["pr_url"].Description != "Which PR?" || len(schema.Properties["depth"].Enum) != 2 {
+		t.Errorf("input schema = %s", data)
+	}
+
+	results := flowResults(cfg, map[string]any{"pr_url": "u", "summary": "LGTM", "_scratch": "x", "token": "secret"})
+	if len(results) != 1 || results["summary"] != "LGTM" {
+		t.Errorf("flowResults = %v", results)
+	}
+}
@@ -7,15 +7,19 @@ import (
 	"strings"
 
 	"github.com/SAP/astonish/pkg/agent"
+	"github.com/SAP/astonish/pkg/artifacts"
 	"github.com/SAP/astonish/pkg/browser"
 	"github.com/SAP/astonish/pkg/config"
 	"github.com/SAP/astonish/pkg/credentials"
 	"github.com/SAP/astonish/pkg/mcp"
+	"github.com/SAP/astonish/pkg/memory"
 	"github.com/SAP/astonish/pkg/provider"
 	"github.com/SAP/astonish/pkg/sandbox"
+	persistentsession "github.com/SAP/astonish/pkg/session"
 	"github.com/SAP/astonish/pkg/store"
 	"github.com/SAP/astonish/pkg/tools"
 	adkagent "google.golang.org/adk/agent"
+	adkmemory "google.golang.org/adk/memory"
 	"google.golang.org/adk/runner"
 	"google.golang.org/adk/session"
 	"google.golang.org/adk/tool"
@@ -26,19 +30,34 @@ import (
 type HeadlessConfig struct {
 	AgentConfig    *config.AgentConfig
 	AppConfig      *config.AppConfig
+	FlowName       string // Name of the flow, for flow-scoped memory
 	ProviderName   string
 	ModelName      string
 	SessionService session.Service
 	Parameters     map[string]string
+	Variables      map[string]string // --var values for the flow's variables: section
 	DebugMode      bool
+	OnEvent        func(*session.Event)  // Called with every event of the run (e.g. JSON event output)
+	DenyApprovals  bool                  // Deny tool approvals instead of approving them (tools_auto_approval still applies)
+	ApprovalPolicy *agent.ApprovalPolicy // Approval profile deciding tool calls before they are approved or denied (--approval-profile)
+	NoCache        bool                  // Call the model even for cache_response nodes (--no-cache)
+	DumpLLMIO      string                // Write every model request and response as JSON to this directory
+	ExportState    string                // Write the final state to this JSON file (overrides the flow's export_state)
 }
 
 // RunHeadless executes a flow without a TUI. It runs the flow engine with
-// auto-approve enabled, injects parameters for input nodes, and collects
+// auto-approve enabled (unless DenyApprovals), injects parameters for input nodes, and collects
 // all output text into a string. Returns the collected output and any error.
 //
 // This is used by the scheduler for "routine" mode jobs.
 func RunHeadless(ctx context.Context, cfg *HeadlessConfig) (string, error) {
+	output, _, err := RunHeadlessWithState(ctx, cfg)
+	return output, err
+}
+
+// RunHeadlessWithState is RunHeadless that also returns the session state at
+// the end of the run (nil when the run did not get that far).
+func RunHeadlessWithState(ctx context.Context, cfg *HeadlessConfig) (string, map[string]any, error) {
 	// NOTE: We intentionally do NOT suppress log output here.
 	// Previously log.SetOutput(io.Discard) was used to hide ADK warnings,
 	// but it also silenced all slog diagnostics (slog delegates through the
@@ -72,15 +91,18 @@ func RunHeadless(ctx context.Context, cfg *HeadlessConfig) (string, error) {
 	if cfg.DebugMode {
 		provider.SetDebugMode(true)
 	}
+	if err := provider.ConfigureHTTP(cfg.AppConfig); err != nil {
+		return "", nil, err
+	}
 	llm, err := provider.GetProvider(ctx, cfg.ProviderName, cfg.ModelName, cfg.AppConfig)
 	if err != nil {
-		return "", fmt.Errorf("failed to initialize provider: %w", err)
+		return "", nil, fmt.Errorf("failed to initialize provider: %w", err)
 	}
 
 	// Initialize internal tools
 	internalTools, err := tools.GetInternalTools()
 	if err != nil {
-		return "", fmt.Errorf("failed to initialize tools: %w", err)
+		return "", nil, fmt.Errorf("failed to initialize tools: %w", err)
 	}
 
 	// Register credential tools (resolve_credential, etc.)
@@ -119,7 +141,7 @@ func RunHeadless(ctx context.Context, cfg *HeadlessConfig) (string, error) {
 	// Wrap tools with sandbox if enabled (container isolation for file/shell/network tools)
 	internalTools, sandboxCleanup, sandboxErr := setupFlowSandbox(cfg.AppConfig, internalTools, cfg.DebugMode)
 	if sandboxErr != nil {
-		return "", fmt.Errorf("sandbox is enabled but the runtime is not available: %w", sandboxErr)
+		return "", nil, fmt.Errorf("sandbox is enabled but the runtime is not available: %w", sandboxErr)
 	}
 	defer sandboxCleanup()
 
@@ -142,6 +164,7 @@ func RunHeadless(ctx context.Context, cfg *HeadlessConfig) (string, error) {
 				}
 			} else {
 				mcpToolsets = mcpManager.GetToolsets()
+				mcpManager.StartHealthChecks(ctx, mcp.HealthConfig{})
 			}
 		}
 	}
@@ -154,12 +177,34 @@ func RunHeadless(ctx context.Context, cfg *HeadlessConfig) (string, error) {
 	if sessionService == nil {
 		sessionService = session.InMemoryService()
 	}
+	// Values of sensitive state keys are encrypted before they reach the store
+	sessionService, err = persistentsession.NewSensitiveService(sessionService, cfg.AgentConfig.SensitiveKeys(), tools.GetCredentialStore().DeriveKey("session-state"))
+	if err != nil {
+		return "", nil, err
+	}
 
-	// Create the AstonishAgent with auto-approve
+	// Create the AstonishAgent, auto-approving tool calls unless denied
 	astonishAgent := agent.NewAstonishAgentWithToolsets(cfg.AgentConfig, llm, internalTools, mcpToolsets)
+	astonishAgent.AppConfig = cfg.AppConfig // per-node models and model_fallbacks resolve providers from here
+	astonishAgent.ProviderName = cfg.ProviderName
+	astonishAgent.ModelName = cfg.ModelName
 	astonishAgent.DebugMode = cfg.DebugMode
-	astonishAgent.AutoApprove = true
+	if mcpManager != nil {
+		astonishAgent.MCPHealth = mcpManager.HealthState
+		astonishAgent.ToolsetsWithEnv = mcpManager.ToolsetsWithEnv
+	}
+	astonishAgent.AutoApprove = !cfg.DenyApprovals
+	astonishAgent.ApprovalPolicy = cfg.ApprovalPolicy
+	astonishAgent.NoCache = cfg.NoCache
+	astonishAgent.DumpLLMIO = cfg.DumpLLMIO
+	astonishAgent.Variables = cfg.Variables
 	astonishAgent.SessionService = sessionService
+	astonishAgent.FlowName = cfg.FlowName
+	facts, memoryService, err := flowMemory(cfg.AgentConfig, cfg.AppConfig, cfg.DebugMode)
+	if err != nil {
+		return "", nil, err
+	}
+	astonishAgent.Memory = facts
 
 	// Wire credential redactor and store for placeholder substitution
 	if cs := tools.GetCredentialStore(); cs != nil {
@@ -191,7 +236,7 @@ func RunHeadless(ctx context.Context, cfg *HeadlessConfig) (string, error) {
 		Run:         astonishAgent.Run,
 	})
 	if err != nil {
-		return "", fmt.Errorf("failed to create ADK agent: %w", err)
+		return "", nil, fmt.Errorf("failed to create ADK agent: %w", err)
 	}
 
 	// Create session
@@ -201,7 +246,7 @@ func RunHeadless(ctx context.Context, cfg *HeadlessConfig) (string, error) {
 		UserID:  userID,
 	})
 	if err != nil {
-		return "", fmt.Errorf("failed to create session: %w", err)
+		return "", nil, fmt.Errorf("failed to create session: %w", err)
 	}
 	sess := resp.Session
 
@@ -210,12 +255,14 @@ func RunHeadless(ctx context.Context, cfg *HeadlessConfig) (string, error) {
 
 	// Create runner
 	r, err := runner.New(runner.Config{
-		AppName:        appName,
-		Agent:          adkAgent,
-		SessionService: sessionService,
+		AppName:         appName,
+		Agent:           adkAgent,
+		SessionService:  sessionService,
+		MemoryService:   memoryService,
+		ArtifactService: artifacts.RunnerService(),
 	})
 	if err != nil {
-		return "", fmt.Errorf("failed to create runner: %w", err)
+		return "", nil, fmt.Errorf("failed to create runner: %w", err)
 	}
 
 	// Main execution loop — mirrors console.go logic but collects text instead of rendering
@@ -223,6 +270,7 @@ func RunHeadless(ctx context.Context, cfg *HeadlessConfig) (string, error) {
 	var currentNodeName string
 	var output strings.Builder
 	var flowError string // captured from _failure_info StateDelta events
+	var aborted *RunAbortedError
 
 	for {
 		isInputNode := false
@@ -238,7 +286,10 @@ func RunHeadless(ctx context.Context, cfg *HeadlessConfig) (string, error) {
 		for event, err := range r.Run(ctx, userID, sess.ID(), userMsg, adkagent.RunConfig{}) {
 			if err != nil {
 				slog.Error("[headless] agent returned error", "node", currentNodeName, "error", err)
-				return output.String(), fmt.Errorf("agent error: %w", err)
+				return output.String(), nil, fmt.Errorf("agent error: %w", err)
+			}
+			if cfg.OnEvent != nil {
+				cfg.OnEvent(event)
 			}
 
 			nodeJustChanged = false
@@ -274,10 +325,11 @@ func RunHeadless(ctx context.Context, cfg *HeadlessConfig) (string, error) {
 									isOutputNode = true
 									suppressStreaming = false
 								default:
-									if len(n.UserMessage) > 0 {
+									switch n.DisplayMode() {
+									case config.DisplayUserMessage:
 										suppressStreaming = true
 										userMessageFields = n.UserMessage
-									} else if len(n.OutputModel) > 0 {
+									case config.DisplayNone, config.DisplaySummary:
 										suppressStreaming = true
 									}
 								}
@@ -329,10 +381,19 @@ func RunHeadless(ctx context.Context, cfg *HeadlessConfig) (string, error) {
 				// Track internal flow errors.
 				// _failure_info is emitted via StateDelta when retries exhaust.
 				// Capture it so we can return a proper error from RunHeadless.
+				if e := runAbortedError(event.Actions.StateDelta); e != nil {
+					aborted = e
+				}
 				if failInfo, ok := event.Actions.StateDelta["_failure_info"]; ok {
 					slog.Warn("[headless] node failure detected", "node", currentNodeName, "info", failInfo)
 					if infoMap, ok := failInfo.(map[string]any); ok {
-						if reason, ok := infoMap["original_error"].(string); ok {
+						class, _ := infoMap["error_class"].(string)
+						if class != "" && class != "other" {
+							// Classified provider error: lead with the plain-language reason
+							reason, _ := infoMap["reason"].(string)
+							original, _ := infoMap["original_error"].(string)
+							flowError = fmt.Sprintf("node %q failed (%s): %s: %s", currentNodeName, class, reason, original)
+						} else if reason, ok := infoMap["original_error"].(string); ok {
 							flowError = fmt.Sprintf("node %q failed: %s", currentNodeName, reason)
 						} else if reason, ok := infoMap["reason"].(string); ok {
 							flowError = fmt.Sprintf("node %q failed: %s", currentNodeName, reason)
@@ -346,10 +407,15 @@ func RunHeadless(ctx context.Context, cfg *HeadlessConfig) (string, error) {
 			}
 
 			// Collect text from LLM response
+			_, isSummary := event.Actions.StateDelta["_display_summary"]
 			if event.LLMResponse.Content != nil {
 				for _, part := range event.LLMResponse.Content.Parts {
 					if part.Text != "" {
-						if !suppressStreaming || isInputNode || isOutputNode {
+						if isSummary {
+							// display: summary output bypasses suppression
+							output.WriteString(part.Text)
+							output.WriteString("\n")
+						} else if !suppressStreaming || isInputNode || isOutputNode {
 							turnText.WriteString(part.Text)
 						}
 					}
@@ -376,12 +442,16 @@ func RunHeadless(ctx context.Context, cfg *HeadlessConfig) (string, error) {
 				}
 			}
 			// No parameter available for this input node
-			return output.String(), fmt.Errorf("input node %q requires a value but no parameter was provided", currentNodeName)
+			return output.String(), nil, fmt.Errorf("input node %q requires a value but no parameter was provided", currentNodeName)
 		}
 
-		// Handle approval — always approve
+		// Handle approval — approve unless approvals are denied
 		if waitingForApproval {
-			userMsg = agent.NewTimestampedUserContent("Yes")
+			if cfg.DenyApprovals {
+				userMsg = agent.NewTimestampedUserContent("No")
+			} else {
+				userMsg = agent.NewTimestampedUserContent("Yes")
+			}
 			continue
 		}
 
@@ -390,18 +460,78 @@ func RunHeadless(ctx context.Context, cfg *HeadlessConfig) (string, error) {
 	}
 
 	result := strings.TrimSpace(output.String())
+	state := headlessFinalState(ctx, sessionService, appName, userID, sess.ID())
+
+	if path := exportStatePath(cfg.ExportState, cfg.AgentConfig); path != "" && currentNodeName == "END" && state != nil {
+		if err := ExportState(path, cfg.AgentConfig, state); err != nil {
+			return result, state, fmt.Errorf("failed to export state: %w", err)
+		}
+		slog.Info("[headless] state exported", "path", path)
+	}
 
 	// Check if the flow failed internally. We detect this from _failure_info
 	// StateDelta events captured during the run (the only reliable signal —
 	// state.Set("_has_error") mutations are NOT persisted to the session service).
+	if aborted != nil {
+		slog.Warn("[headless] flow aborted", "node", aborted.Node, "exit_code", aborted.ExitCode)
+		return result, state, aborted
+	}
 	if flowError != "" {
 		slog.Warn("[headless] flow completed with error", "error", flowError, "output_len", len(result))
 		if result == "" {
-			return "", fmt.Errorf("%s", flowError)
+			return "", nil, fmt.Errorf("%s", flowError)
 		}
-		return result, fmt.Errorf("%s", flowError)
+		return result, state, fmt.Errorf("%s", flowError)
 	}
 
 	slog.Info("[headless] flow completed", "output_len", len(result), "final_node", currentNodeName)
-	return result, nil
+	return result, state, nil
+}
+
+// headlessFinalState returns a copy of the session state after a run.
+func headlessFinalState(ctx context.Context, svc session.Service, appName, userID, sessionID string) map[string]any {
+	resp, err := svc.Get(ctx, &session.GetRequest{AppName: appName, UserID: userID, SessionID: sessionID})
+	if err != nil {
+		slog.Warn("[headless] could not read final session state", "error", err)
+		return nil
+	}
+	state := make(map[string]any)
+	for k, v := range resp.Session.State().All() {
+		state[k] = v
+	}
+	return state
+}
+
+// NewFlowSessionService returns the session store for flow runs: the file
+// store when sessions.storage is "file", so runs are kept and can be
+// inspected later, and an in-memory store otherwise.
+func NewFlowSessionService(appCfg *config.AppConfig) (session.Service, error) {
+	if appCfg == nil || appCfg.Sessions.Storage != "file" {
+		return session.InMemoryService(), nil
+	}
+	sessDir, err := config.GetSessionsDir(&appCfg.Sessions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve sessions directory: %w", err)
+	}
+	fileStore, err := persistentsession.NewFileStore(sessDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file session store: %w", err)
+	}
+	return fileStore, nil
+}
+
+// flowMemory returns the fact store of the flow's memory nodes and the ADK
+// memory service over it, or nils when the flow has no memory node.
+func flowMemory(agentCfg *config.AgentConfig, appCfg *config.AppConfig, debugMode bool) (memory.FactStore, adkmemory.Service, error) {
+	for _, node := range agentCfg.Nodes {
+		if node.Type != "memory" {
+			continue
+		}
+		facts, err := memory.FlowFactStore(appCfg, debugMode)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open flow memory: %w", err)
+		}
+		return facts, &memory.FactService{Facts: facts}, nil
+	}
+	return nil, nil, nil
 }
@@ -10,6 +10,7 @@ import (
 	"sync"
 
 	"github.com/SAP/astonish/pkg/agent"
+	"github.com/SAP/astonish/pkg/artifacts"
 	"github.com/SAP/astonish/pkg/browser"
 	"github.com/SAP/astonish/pkg/config"
 	"github.com/SAP/astonish/pkg/credentials"
@@ -18,6 +19,7 @@ import (
 	"github.com/SAP/astonish/pkg/sandbox"
 	"github.com/SAP/astonish/pkg/sandbox/netpolicy"
 	"github.com/SAP/astonish/pkg/sandbox/openshell"
+	persistentsession "github.com/SAP/astonish/pkg/session"
 	"github.com/SAP/astonish/pkg/store"
 	"github.com/SAP/astonish/pkg/tools"
 	adkagent "google.golang.org/adk/agent"
@@ -53,6 +55,7 @@ type flowSession struct {
 	currentNode     string
 	nodesVisited    []string // ordered list of nodes executed
 	resolvedOptions []string // runtime-resolved options from the flow engine
+	multiSelect     bool     // the input node accepts several options
 	resolvedPrompt  string   // runtime-resolved prompt from the flow engine
 	cleanupFuncs    []func() // deferred cleanup (MCP, browser, sandbox)
 }
@@ -216,6 +219,12 @@ func (ifr *InteractiveFlowRunner) startFlowWithConfig(
 	if ifr.DebugMode {
 		provider.SetDebugMode(true)
 	}
+	if err := provider.ConfigureHTTP(ifr.AppConfig); err != nil {
+		return nil, err
+	}
+	if err := configureTheme(ifr.AppConfig); err != nil {
+		return nil, err
+	}
 	llm, err := provider.GetProvider(ctx, ifr.ProviderName, ifr.ModelName, ifr.AppConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize provider: %w", err)
@@ -259,25 +268,38 @@ func (ifr *InteractiveFlowRunner) startFlowWithConfig(
 	// MCP tools
 	requiredServers := getRequiredMCPServersFromConfig(ctx, agentCfg, ifr.DebugMode)
 	var mcpToolsets []tool.Toolset
+	var mcpHealth func() map[string]bool
+	var mcpToolsetsWithEnv func(context.Context, map[string]string) ([]tool.Toolset, error)
 
 	if len(requiredServers) > 0 {
 		mcpManager, mcpErr := mcp.NewManager()
 		if mcpErr == nil {
 			if initErr := mcpManager.InitializeSelectiveToolsets(ctx, requiredServers); initErr == nil {
 				mcpToolsets = mcpManager.GetToolsets()
+				mcpManager.StartHealthChecks(ctx, mcp.HealthConfig{})
+				mcpHealth = mcpManager.HealthState
+				mcpToolsetsWithEnv = mcpManager.ToolsetsWithEnv
 			}
 			cleanups = append(cleanups, mcpManager.Cleanup)
 		}
 	}
 
-	// Session service
-	sessionService := session.InMemoryService()
+	// Session service; values of sensitive state keys are encrypted before they reach the store
+	sessionService, err := persistentsession.NewSensitiveService(session.InMemoryService(), agentCfg.SensitiveKeys(), tools.GetCredentialStore().DeriveKey("session-state"))
+	if err != nil {
+		return nil, err
+	}
 
 	// Create AstonishAgent with auto-approve (the user's decision to run the flow is the approval)
 	astonishAgent := agent.NewAstonishAgentWithToolsets(agentCfg, llm, internalTools, mcpToolsets)
+	astonishAgent.AppConfig = ifr.AppConfig // per-node models and model_fallbacks resolve providers from here
+	astonishAgent.ProviderName = ifr.ProviderName
+	astonishAgent.ModelName = ifr.ModelName
 	astonishAgent.DebugMode = ifr.DebugMode
 	astonishAgent.AutoApprove = true
 	astonishAgent.SessionService = sessionService
+	astonishAgent.MCPHealth = mcpHealth
+	astonishAgent.ToolsetsWithEnv = mcpToolsetsWithEnv
 
 	// Wire credential redactor and store for placeholder substitution
 	if cs := tools.GetCredentialStore(); cs != nil {
@@ -330,9 +352,10 @@ func (ifr *InteractiveFlowRunner) startFlowWithConfig(
 
 	// Create runner
 	rnr, err := runner.New(runner.Config{
-		AppName:        appName,
-		Agent:          adkAgent,
-		SessionService: sessionService,
+		AppName:         appName,
+		Agent:           adkAgent,
+		SessionService:  sessionService,
+		ArtifactService: artifacts.RunnerService(),
 	})
 	if err != nil {
 		for _, c := range cleanups {
@@ -464,6 +487,7 @@ func (ifr *InteractiveFlowRunner) executeFlowTurn(
 						sess.currentNode = node
 						sess.nodesVisited = append(sess.nodesVisited, node)
 						sess.resolvedOptions = nil
+						sess.multiSelect = false
 						sess.resolvedPrompt = ""
 						suppressStreaming = false
 						userMessageFields = nil
@@ -478,10 +502,11 @@ func (ifr *InteractiveFlowRunner) executeFlowTurn(
 								case "output":
 									suppressStreaming = false
 								default:
-									if len(n.UserMessage) > 0 {
+									switch n.DisplayMode() {
+									case config.DisplayUserMessage:
 										suppressStreaming = true
 										userMessageFields = n.UserMessage
-									} else if len(n.OutputModel) > 0 {
+									case config.DisplayNone, config.DisplaySummary:
 										suppressStreaming = true
 									}
 								}
@@ -503,6 +528,10 @@ func (ifr *InteractiveFlowRunner) executeFlowTurn(
 					}
 				}
 
+				if multi, ok := delta["input_multi_select"].(bool); ok {
+					sess.multiSelect = multi
+				}
+
 				// Check for waiting_for_input
 				if waiting, ok := delta["waiting_for_input"].(bool); ok && waiting {
 					waitingForInput = true
@@ -537,6 +566,7 @@ func (ifr *InteractiveFlowRunner) executeFlowTurn(
 			}
 
 			// Collect text
+			_, isSummary := event.Actions.StateDelta["_display_summary"]
 			if event.LLMResponse.Content != nil {
 				for _, part := range event.LLMResponse.Content.Parts {
 					if part.Text != "" {
@@ -544,7 +574,10 @@ func (ifr *InteractiveFlowRunner) executeFlowTurn(
 							// Capture the rendered prompt from the flow engine
 							sess.resolvedPrompt += part.Text
 						}
-						if !suppressStreaming || isInputNode {
+						if isSummary {
+							// display: summary output bypasses suppression
+							sess.output.WriteString(part.Text + "\n")
+						} else if !suppressStreaming || isInputNode {
 							turnText.WriteString(part.Text)
 						}
 					}
@@ -585,7 +618,9 @@ func (ifr *InteractiveFlowRunner) executeFlowTurn(
 
 			// Build the guidance message based on whether this is a selection or free-text input
 			msg := fmt.Sprintf("The flow needs input for node %q.", sess.currentNode)
-			if len(options) > 0 {
+			if len(options) > 0 && sess.multiSelect {
+				msg += " The user may select any number of the listed options; pass them exactly, separated by commas."
+			} else if len(options) > 0 {
 				msg += " The user MUST select one of the listed options exactly. If the user's answer doesn't match an option, ask them to choose from the list."
 			} else {
 				msg += " This is a free-text input — pass the user's exact words."
@@ -599,6 +634,7 @@ func (ifr *InteractiveFlowRunner) executeFlowTurn(
 				InputNode:    sess.currentNode,
 				InputPrompt:  prompt,
 				InputOptions: options,
+				MultiSelect:  sess.multiSelect,
 				NodesVisited: sess.nodesVisited,
 			}, nil
 		}
@@ -0,0 +1,221 @@
+package launcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SAP/astonish/pkg/agent"
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/adk/session"
+)
+
+// JSON event types written by JSONEventWriter, one JSON object per line.
+const (
+	JSONEventNode       = "node"        // a node started (node, node_type)
+	JSONEventText       = "text"        // model text of the current node (text, partial)
+	JSONEventToolCall   = "tool_call"   // a tool was called (tool, args)
+	JSONEventToolResult = "tool_result" // a tool returned (tool, result)
+	JSONEventApproval   = "approval"    // a tool call needed approval (tool, args, approved)
+	JSONEventRetry      = "retry"       // a node is retried (attempt, max_retries, reason)
+	JSONEventWarning    = "warning"     // a node nears its tool call limit (text)
+	JSONEventError      = "error"       // a node failed (error)
+	JSONEventSummary    = "summary"     // the run reached END (summary: path, timing, counts, tokens)
+	JSONEventEnd        = "end"         // the run finished (output, results, state, error)
+)
+
+// JSONEvent is one line of the JSON event stream of a flow run.
+type JSONEvent struct {
+	Type       string         `json:"type"`
+	Time       time.Time      `json:"time"`
+	Node       string         `json:"node,omitempty"`
+	NodeType   string         `json:"node_type,omitempty"`
+	Text       string         `json:"text,omitempty"`
+	Partial    bool           `json:"partial,omitempty"`
+	Tool       string         `json:"tool,omitempty"`
+	Args       map[string]any `json:"args,omitempty"`
+	Result     any            `json:"result,omitempty"`
+	Approved   *bool          `json:"approved,omitempty"`
+	Attempt    int            `json:"attempt,omitempty"`
+	MaxRetries int            `json:"max_retries,omitempty"`
+	Reason     string         `json:"reason,omitempty"`
+	Error      string         `json:"error,omitempty"`
+	Output     *string        `json:"output,omitempty"`
+	Results    map[string]any `json:"results,omitempty"`
+	State      map[string]any `json:"state,omitempty"`
+	Summary    any            `json:"summary,omitempty"`
+}
+
+// JSONEventWriter renders the events of a flow run as newline-delimited
+// JSON, for scripts and programs that consume runs instead of a terminal.
+// It is used as HeadlessConfig.OnEvent.
+type JSONEventWriter struct {
+	mu      sync.Mutex
+	enc     *json.Encoder
+	cfg     *config.AgentConfig
+	approve bool
+	node    string
+	now     func() time.Time
+}
+
+// NewJSONEventWriter creates a writer for runs of cfg. approve tells whether
+// approval requests of the run are approved or denied.
+func NewJSONEventWriter(w io.Writer, cfg *config.AgentConfig, approve bool) *JSONEventWriter {
+	return &JSONEventWriter{enc: json.NewEncoder(w), cfg: cfg, approve: approve, now: time.Now}
+}
+
+// HandleEvent writes the JSON events for one event of the run.
+func (w *JSONEventWriter) HandleEvent(event *session.Event) {
+	if event == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	delta := event.Actions.StateDelta
+	if node, ok := delta["current_node"].(string); ok && node != "" {
+		nodeType, isStart := delta["node_type"].(string)
+		waiting, _ := delta["waiting_for_input"].(bool)
+		if node != "END" && (isStart || (waiting && node != w.node)) {
+			if !isStart {
+				nodeType = "input"
+			}
+			w.write(JSONEvent{Type: JSONEventNode, Node: node, NodeType: nodeType})
+		}
+		w.node = node
+	}
+
+	if event.LLMResponse.Content != nil {
+		for _, part := range event.LLMResponse.Content.Parts {
+			switch {
+			case part.FunctionCall != nil:
+				w.write(JSONEvent{Type: JSONEventToolCall, Node: w.node, Tool: part.FunctionCall.Name, Args: part.FunctionCall.Args})
+			case part.FunctionResponse != nil:
+				w.write(JSONEvent{Type: JSONEventToolResult, Node: w.node, Tool: part.FunctionResponse.Name, Result: part.FunctionResponse.Response})
+			case part.Text != "" && !part.Thought:
+				w.write(JSONEvent{Type: JSONEventText, Node: w.node, Text: part.Text, Partial: event.LLMResponse.Partial})
+			}
+		}
+	}
+
+	if awaiting, _ := delta["awaiting_approval"].(bool); awaiting {
+		tool, _ := delta["approval_tool"].(string)
+		args, _ := delta["approval_args"].(map[string]any)
+		reason, _ := delta["approval_reason"].(string)
+		approved := w.approve
+		w.write(JSONEvent{Type: JSONEventApproval, Node: w.node, Tool: tool, Args: args, Reason: reason, Approved: &approved})
+	}
+
+	if info, ok := delta["_retry_info"].(map[string]any); ok {
+		reason, _ := info["reason"].(string)
+		w.write(JSONEvent{Type: JSONEventRetry, Node: w.node, Attempt: intValue(info["attempt"]), MaxRetries: intValue(info["max_retries"]), Reason: reason})
+	}
+
+	if warning, ok := delta[agent.ToolCallWarningKey].(map[string]any); ok {
+		text, _ := warning["message"].(string)
+		w.write(JSONEvent{Type: JSONEventWarning, Node: w.node, Text: text})
+	}
+
+	if summary, ok := delta[agent.RunSummaryKey]; ok {
+		w.write(JSONEvent{Type: JSONEventSummary, Summary: summary})
+	}
+
+	if info, ok := delta["_failure_info"]; ok {
+		msg := fmt.Sprint(info)
+		if m, ok := info.(map[string]any); ok {
+			reason, _ := m["reason"].(string)
+			original, _ := m["original_error"].(string)
+			msg = strings.TrimSpace(strings.Join(nonEmpty(reason, original), ": "))
+		}
+		w.write(JSONEvent{Type: JSONEventError, Node: w.node, Error: msg})
+	}
+}
+
+// Finish writes the final event with the run's output, the values of the
+// state keys the nodes declare as results, the final state and the error of
+// the run, if any. Internal and sensitive state keys are left out.
+func (w *JSONEventWriter) Finish(output string, state map[string]any, runErr error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	end := JSONEvent{Type: JSONEventEnd, Node: w.node, Output: &output}
+	if state != nil {
+		end.Results = flowResults(w.cfg, state)
+		end.State = publicState(w.cfg, state)
+	}
+	if runErr != nil {
+		end.Error = runErr.Error()
+	}
+	w.write(end)
+}
+
+func (w *JSONEventWriter) write(ev JSONEvent) {
+	ev.Time = w.now().UTC()
+	// Encoding only fails for values JSON cannot represent; keep the stream going
+	if err := w.enc.Encode(ev); err != nil {
+		ev.Args, ev.Result = nil, fmt.Sprint(ev.Result)
+		_ = w.enc.Encode(ev)
+	}
+}
+
+// engineStateKeys are the state keys the flow engine uses for its own
+// bookkeeping (node tracking, input and approval prompts).
+var engineStateKeys = map[string]bool{
+	"current_node":               true,
+	"node_type":                  true,
+	"silent":                     true,
+	"waiting_for_input":          true,
+	"input_options":              true,
+	"input_type":                 true,
+	"input_default":              true,
+	"input_error":                true,
+	"input_multi_select":         true,
+	"input_mode":                 true,
+	"input_file_root":            true,
+	"input_file_pattern":         true,
+	"awaiting_approval":          true,
+	"approval_tool":              true,
+	"approval_args":              true,
+	"approval_options":           true,
+	"approval_reason":            true,
+	"auto_approved":              true,
+	"awaiting_cost_confirmation": true,
+}
+
+// publicState returns state without internal (_-prefixed), temporary,
+// engine and sensitive keys.
+func publicState(cfg *config.AgentConfig, state map[string]any) map[string]any {
+	sensitive := cfg.SensitiveKeys()
+	public := make(map[string]any, len(state))
+	for k, v := range state {
+		if strings.HasPrefix(k, "_") || strings.HasPrefix(k, session.KeyPrefixTemp) || engineStateKeys[k] || sensitive[k] {
+			continue
+		}
+		public[k] = v
+	}
+	return public
+}
+
+// intValue reads an int that may have been decoded as a float64.
+func intValue(v any) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case float64:
+		return int(n)
+	}
+	return 0
+}
+
+func nonEmpty(values ...string) []string {
+	var out []string
+	for _, v := range values {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,105 @@
+package launcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+func decodeJSONEvents(t *testing.T, out string) []map[string]any {
+	t.Helper()
+	var events []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		var ev map[string]any
+		if err := json.Unmarshal([]byte(line), &ev); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", line, err)
+		}
+		events = append(events, ev)
+	}
+	return events
+}
+
+func TestJSONEventWriter(t *testing.T) {
+	cfg := &config.AgentConfig{Nodes: []config.Node{
+		{Name: "fetch", Type: "tool"},
+		{Name: "summarize", Type: "llm", OutputModel: map[string]string{"summary": "str", "token": "str"}, Sensitive: []string{"token"}},
+	}}
+	var buf bytes.Buffer
+	w := NewJSONEventWriter(&buf, cfg, false)
+
+	delta := func(d map[string]any) *session.Event {
+		return &session.Event{Actions: session.EventActions{StateDelta: d}}
+	}
+	content := func(parts ...*genai.Part) *session.Event {
+		return &session.Event{LLMResponse: model.LLMResponse{Content: &genai.Content{Role: "model", Parts: parts}}}
+	}
+
+	w.HandleEvent(delta(map[string]any{"current_node": "fetch", "node_type": "tool"}))
+	w.HandleEvent(delta(map[string]any{"awaiting_approval": true, "current_node": "fetch", "approval_tool": "shell_command", "approval_args": map[string]any{"command": "ls"}}))
+	w.HandleEvent(delta(map[string]any{"current_node": "summarize", "node_type": "llm"}))
+	w.HandleEvent(content(&genai.Part{FunctionCall: &genai.FunctionCall{Name: "read_file", Args: map[string]any{"path": "a.txt"}}}))
+	w.HandleEvent(content(&genai.Part{FunctionResponse: &genai.FunctionResponse{Name: "read_file", Response: map[string]any{"content": "hi"}}}))
+	w.HandleEvent(content(&genai.Part{Text: "thinking", Thought: true}, &genai.Part{Text: "Done."}))
+	w.HandleEvent(delta(map[string]any{"_retry_info": map[string]any{"attempt": 1, "max_retries": 3.0, "reason": "timeout"}}))
+	w.HandleEvent(delta(map[string]any{"current_node": "END", "node_type": "END"}))
+	w.Finish("Done.", map[string]any{"summary": "ok", "token": "secret", "_has_error": false, "current_node": "END", "temp:x": 1}, errors.New("boom"))
+
+	events := decodeJSONEvents(t, buf.String())
+	var types []string
+	for _, ev := range events {
+		types = append(types, ev["type"].(string))
+	}
+	want := "node approval node tool_call tool_result text retry end"
+	if got := strings.Join(types, " "); got != want {
+		t.Fatalf("event types = %q, want %q", got, want)
+	}
+
+	if ev := events[1]; ev["tool"] != "shell_command" || ev["approved"] != false {
+		t.Errorf("approval event = %v", ev)
+	}
+	if ev := events[3]; ev["node"] != "summarize" || ev["tool"] != "read_file" {
+		t.Errorf("tool_call event = %v", ev)
+	}
+	if ev := events[5]; ev["text"] != "Done." {
+		t.Errorf("text event = %v", ev)
+	}
+	if ev := events[6]; ev["attempt"] != 1.0 || ev["max_retries"] != 3.0 {
+		t.Errorf("retry event = %v", ev)
+	}
+
+	end := events[7]
+	if end["output"] != "Done." || end["error"] != "boom" {
+		t.Errorf("end event = %v", end)
+	}
+	state, _ := end["state"].(map[string]any)
+	if len(state) != 1 || state["summary"] != "ok" {
+		t.Errorf("expected only public state keys, got %v", state)
+	}
+	if results, _ := end["results"].(map[string]any); len(results) != 1 || results["summary"] != "ok" {
+		t.Errorf("results = %v", end["results"])
+	}
+}
+
+func TestJSONEventWriterFirstInputNode(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewJSONEventWriter(&buf, &config.AgentConfig{}, true)
+
+	// A flow that opens with an input node only sends the prompt event
+	w.HandleEvent(&session.Event{Actions: session.EventActions{StateDelta: map[string]any{"current_node": "repo", "waiting_for_input": true}}})
+	w.Finish("", nil, nil)
+
+	events := decodeJSONEvents(t, buf.String())
+	if len(events) != 2 || events[0]["type"] != JSONEventNode || events[0]["node_type"] != "input" {
+		t.Fatalf("events = %v", events)
+	}
+	if _, ok := events[1]["output"]; !ok {
+		t.Errorf("end event should always carry output: %v", events[1])
+	}
+}
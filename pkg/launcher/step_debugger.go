@@ -0,0 +1,90 @@
+package launcher
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/SAP/astonish/pkg/agent"
+	"github.com/SAP/astonish/pkg/ui"
+	"gopkg.in/yaml.v3"
+)
+
+// Choices of the step debugger prompt.
+const (
+	stepContinue = "Continue"
+	stepSkip     = "Skip node"
+	stepEdit     = "Edit state"
+	stepRunToEnd = "Run to end"
+	stepAbort    = "Abort"
+)
+
+// stepDebugger pauses a console run before each node (flows run --step),
+// shows what the node is about to do and lets the user continue, skip it,
+// edit state or abort.
+type stepDebugger struct {
+	pause    func() // Stops the spinner before the panel is shown
+	detached bool   // "Run to end" was chosen: stop pausing
+}
+
+// BeforeNode implements agent.Debugger.
+func (d *stepDebugger) BeforeNode(ctx context.Context, info agent.StepInfo) agent.StepDecision {
+	decision := agent.StepDecision{Action: agent.StepContinue, Set: make(map[string]any)}
+	if d.detached {
+		return decision
+	}
+	if d.pause != nil {
+		d.pause()
+	}
+	fmt.Print("\n" + ui.RenderStepPanel(info.Node.Name, info.Node.Type, info.System, info.Prompt, info.Args, info.Vars))
+
+	for {
+		choice, err := ui.ReadSelection([]string{stepContinue, stepSkip, stepEdit, stepRunToEnd, stepAbort}, "Step", "")
+		if err != nil {
+			// Interrupted prompt (Ctrl+C)
+			decision.Action = agent.StepAbort
+			return decision
+		}
+		switch choice {
+		case stepContinue:
+			return decision
+		case stepSkip:
+			decision.Action = agent.StepSkip
+			return decision
+		case stepRunToEnd:
+			d.detached = true
+			return decision
+		case stepAbort:
+			decision.Action = agent.StepAbort
+			return decision
+		case stepEdit:
+			edit, err := ui.ReadInput("Edit state", "key=value; the value is read as YAML (42, true, [a, b])")
+			if err != nil || strings.TrimSpace(edit) == "" {
+				continue
+			}
+			key, val, err := parseStateEdit(edit)
+			if err != nil {
+				fmt.Println(ui.RenderStatusBadge(err.Error(), false))
+				continue
+			}
+			decision.Set[key] = val
+			fmt.Println(ui.RenderStatusBadge(fmt.Sprintf("%s = %s", key, strings.TrimSpace(ui.FormatAsYamlLike(val, 0))), true))
+		}
+	}
+}
+
+// parseStateEdit parses a "key=value" state edit. The value is read as YAML,
+// so numbers, booleans, lists and maps keep their type; anything that is not
+// valid YAML is kept as a string.
+func parseStateEdit(edit string) (string, any, error) {
+	key, raw, ok := strings.Cut(edit, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" {
+		return "", nil, fmt.Errorf("expected key=value, got %q", edit)
+	}
+	var val any
+	if err := yaml.Unmarshal([]byte(raw), &val); err != nil || val == nil {
+		return key, strings.TrimSpace(raw), nil
+	}
+	return key, val, nil
+}
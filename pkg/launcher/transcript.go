@@ -0,0 +1,215 @@
+package launcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/SAP/astonish/pkg/agent"
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/ui"
+	"google.golang.org/adk/session"
+)
+
+// TranscriptWriter renders the events of a flow run as a Markdown
+// transcript (--transcript): a heading per node, optionally the node's
+// prompt, the agent's messages, tool calls with their arguments and results
+// collapsed, the user's answers and the flow's final outputs. It consumes
+// the same events as the console renderer.
+type TranscriptWriter struct {
+	mu      sync.Mutex
+	buf     strings.Builder
+	cfg     *config.AgentConfig
+	prompts bool
+	node    string
+	nodes   int
+	state   map[string]any // State as far as the events show it, for prompts
+}
+
+// NewTranscriptWriter creates a transcript of a run of cfg. With prompts,
+// the prompt of each LLM node is included.
+func NewTranscriptWriter(cfg *config.AgentConfig, flowName string, prompts bool) *TranscriptWriter {
+	w := &TranscriptWriter{cfg: cfg, prompts: prompts, state: make(map[string]any)}
+	title := flowName
+	if cfg.Description != "" {
+		title = cfg.Description
+	}
+	fmt.Fprintf(&w.buf, "# %s\n\nTranscript of a run of `%s`.\n", title, flowName)
+	return w
+}
+
+// HandleEvent adds one event of the run. Partial (streamed) text is left
+// out; the final event of a response carries the whole text.
+func (w *TranscriptWriter) HandleEvent(event *session.Event) {
+	if w == nil || event == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	delta := event.Actions.StateDelta
+	for k, v := range delta {
+		w.state[k] = v
+	}
+	if node, ok := delta["current_node"].(string); ok && node != "" {
+		nodeType, isStart := delta["node_type"].(string)
+		waiting, _ := delta["waiting_for_input"].(bool)
+		if node != "END" && (isStart || (waiting && node != w.node)) {
+			if !isStart {
+				nodeType = "input"
+			}
+			w.startNode(node, nodeType)
+		}
+		w.node = node
+	}
+
+	if content := event.LLMResponse.Content; content != nil && !event.LLMResponse.Partial {
+		for _, part := range content.Parts {
+			switch {
+			case part.FunctionCall != nil:
+				w.details("Tool call: `"+part.FunctionCall.Name+"`", part.FunctionCall.Args)
+			case part.FunctionResponse != nil:
+				w.details("Result of `"+part.FunctionResponse.Name+"`", part.FunctionResponse.Response)
+			case part.Text != "" && !part.Thought && event.Author != "user":
+				fmt.Fprintf(&w.buf, "\n%s\n", strings.TrimSpace(part.Text))
+			}
+		}
+	}
+
+	if info, ok := delta["_retry_info"].(map[string]any); ok {
+		reason, _ := info["reason"].(string)
+		fmt.Fprintf(&w.buf, "\n_Retry %d/%d: %s_\n", intValue(info["attempt"]), intValue(info["max_retries"]), reason)
+	}
+	if warning, ok := delta[agent.ToolCallWarningKey].(map[string]any); ok {
+		fmt.Fprintf(&w.buf, "\n_Warning: %v_\n", warning["message"])
+	}
+	if info, ok := delta["_failure_info"]; ok {
+		msg := fmt.Sprint(info)
+		if m, ok := info.(map[string]any); ok {
+			reason, _ := m["reason"].(string)
+			original, _ := m["original_error"].(string)
+			msg = strings.TrimSpace(strings.Join(nonEmpty(reason, original), ": "))
+		}
+		fmt.Fprintf(&w.buf, "\n> **Error:** %s\n", msg)
+	}
+}
+
+// Input adds the user's answer to the input node that is running. Answers
+// to nodes with sensitive outputs are masked.
+func (w *TranscriptWriter) Input(answer string) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	sensitive := w.cfg.SensitiveKeys()
+	for _, node := range w.cfg.Nodes {
+		if node.Name != w.node {
+			continue
+		}
+		for key := range node.OutputModel {
+			if sensitive[key] {
+				answer = "••••••"
+			}
+		}
+	}
+	fmt.Fprintf(&w.buf, "\n**User:** %s\n", answer)
+}
+
+// Approval adds the answer to a tool approval request.
+func (w *TranscriptWriter) Approval(answer string) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	fmt.Fprintf(&w.buf, "\n**Approval:** %s\n", answer)
+}
+
+// Finish adds the values of the state keys the nodes declare as results.
+func (w *TranscriptWriter) Finish(state map[string]any) {
+	if w == nil || state == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	results := flowResults(w.cfg, state)
+	if len(results) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(results))
+	for k := range results {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	w.buf.WriteString("\n## Outputs\n")
+	for _, k := range keys {
+		fmt.Fprintf(&w.buf, "\n### %s\n\n%s\n", k, fence(ui.FormatAsYamlLike(results[k], 0), ""))
+	}
+}
+
+// Markdown returns the transcript so far.
+func (w *TranscriptWriter) Markdown() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+// Save writes the transcript to path.
+func (w *TranscriptWriter) Save(path string) error {
+	if w == nil {
+		return nil
+	}
+	return os.WriteFile(path, []byte(w.Markdown()), 0o644)
+}
+
+func (w *TranscriptWriter) startNode(name, nodeType string) {
+	w.nodes++
+	fmt.Fprintf(&w.buf, "\n## %d. %s (%s)\n", w.nodes, name, nodeType)
+	if !w.prompts {
+		return
+	}
+	for _, node := range w.cfg.Nodes {
+		if node.Name == name && node.Prompt != "" {
+			prompt := renderTranscriptPrompt(node.Prompt, w.state)
+			fmt.Fprintf(&w.buf, "\n> **Prompt**\n>\n> %s\n", strings.ReplaceAll(strings.TrimSpace(prompt), "\n", "\n> "))
+		}
+	}
+}
+
+// details adds a collapsed block with v as JSON.
+func (w *TranscriptWriter) details(summary string, v any) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		data = []byte(fmt.Sprint(v))
+	}
+	fmt.Fprintf(&w.buf, "\n<details>\n<summary>%s</summary>\n\n%s\n\n</details>\n", summary, fence(string(data), "json"))
+}
+
+// fence returns text in a code block whose fence is longer than any
+// backtick run in text.
+func fence(text, lang string) string {
+	ticks := "```"
+	for strings.Contains(text, ticks) {
+		ticks += "`"
+	}
+	return ticks + lang + "\n" + strings.TrimRight(text, "\n") + "\n" + ticks
+}
+
+var transcriptPlaceholder = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// renderTranscriptPrompt fills the {expressions} of a prompt from state the
+// way LLM nodes do; expressions state cannot answer are kept.
+func renderTranscriptPrompt(tmpl string, state map[string]any) string {
+	return transcriptPlaceholder.ReplaceAllStringFunc(tmpl, func(match string) string {
+		val, err := agent.EvaluateExpression(match[1:len(match)-1], state)
+		if err != nil || val == nil {
+			return match
+		}
+		return ui.FormatAsYamlLike(val, 0)
+	})
+}
@@ -0,0 +1,73 @@
+package launcher
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+func TestTranscriptWriter(t *testing.T) {
+	cfg := &config.AgentConfig{Description: "Summarize a repo", Nodes: []config.Node{
+		{Name: "ask", Type: "input", OutputModel: map[string]string{"api_key": "str"}, Sensitive: []string{"api_key"}},
+		{Name: "summarize", Type: "llm", Prompt: "Summarize {repo}", OutputModel: map[string]string{"summary": "str"}},
+	}}
+	w := NewTranscriptWriter(cfg, "summarize-repo", true)
+
+	delta := func(d map[string]any) *session.Event {
+		return &session.Event{Actions: session.EventActions{StateDelta: d}}
+	}
+	content := func(partial bool, parts ...*genai.Part) *session.Event {
+		return &session.Event{LLMResponse: model.LLMResponse{Partial: partial, Content: &genai.Content{Role: "model", Parts: parts}}}
+	}
+
+	w.HandleEvent(content(false, &genai.Part{Text: "Your API key?"}))
+	w.HandleEvent(delta(map[string]any{"current_node": "ask", "waiting_for_input": true}))
+	w.Input("sk-123")
+	w.HandleEvent(delta(map[string]any{"current_node": "summarize", "repo": "SAP/astonish"}))
+	w.HandleEvent(delta(map[string]any{"current_node": "summarize", "node_type": "llm"}))
+	w.HandleEvent(content(false, &genai.Part{FunctionCall: &genai.FunctionCall{Name: "read_file", Args: map[string]any{"path": "README.md"}}}))
+	w.HandleEvent(content(false, &genai.Part{FunctionResponse: &genai.FunctionResponse{Name: "read_file", Response: map[string]any{"content": "```go\nfmt.Println()\n```"}}}))
+	w.Approval("Yes")
+	w.HandleEvent(content(true, &genai.Part{Text: "A flow"}))
+	w.HandleEvent(content(false, &genai.Part{Text: "thinking", Thought: true}, &genai.Part{Text: "A flow engine."}))
+	w.HandleEvent(delta(map[string]any{"current_node": "END", "node_type": "END"}))
+	w.Finish(map[string]any{"summary": "A flow engine.", "api_key": "sk-123"})
+
+	path := filepath.Join(t.TempDir(), "run.md")
+	if err := w.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := string(data)
+	for _, want := range []string{
+		"# Summarize a repo",
+		"## 1. ask (input)",
+		"**User:** ••••••",
+		"## 2. summarize (llm)",
+		"> Summarize SAP/astonish",
+		"<summary>Tool call: `read_file`</summary>",
+		"<summary>Result of `read_file`</summary>",
+		"````json",
+		"**Approval:** Yes",
+		"\nA flow engine.\n",
+		"## Outputs\n\n### summary",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("transcript is missing %q:\n%s", want, md)
+		}
+	}
+	for _, unwanted := range []string{"sk-123", "thinking", "END", "\nA flow\n"} {
+		if strings.Contains(md, unwanted) {
+			t.Errorf("transcript contains %q:\n%s", unwanted, md)
+		}
+	}
+}
@@ -28,6 +28,10 @@ type SimpleWebConfig struct {
 	SessionService session.Service
 	Port           int
 	AutoApprove    bool
+	ApprovalPolicy *agent.ApprovalPolicy // Approval profile deciding tool calls before the user is asked (--approval-profile)
+	NoCache        bool                  // Call the model even for cache_response nodes (--no-cache)
+	DumpLLMIO      string                // Write every model request and response as JSON to this directory
+	Variables      map[string]string     // --var values for the flow's variables: section
 }
 
 type chatServer struct {
@@ -54,6 +58,10 @@ func RunSimpleWeb(ctx context.Context, cfg *SimpleWebConfig) error {
 	// Create Astonish agent
 	astonishAgent := agent.NewAstonishAgent(cfg.AgentConfig, llm, internalTools)
 	astonishAgent.AutoApprove = cfg.AutoApprove
+	astonishAgent.ApprovalPolicy = cfg.ApprovalPolicy
+	astonishAgent.NoCache = cfg.NoCache
+	astonishAgent.DumpLLMIO = cfg.DumpLLMIO
+	astonishAgent.Variables = cfg.Variables
 
 	// Create ADK agent wrapper
 	adkAgent, err := adkagent.New(adkagent.Config{
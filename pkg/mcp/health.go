@@ -0,0 +1,351 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/procgroup"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/mcptoolset"
+	"google.golang.org/genai"
+)
+
+// Health statuses reported in ServerHealth.Status.
+const (
+	HealthUnknown   = "unknown"   // Not checked yet
+	HealthHealthy   = "healthy"   // Last check succeeded
+	HealthUnhealthy = "unhealthy" // Last check failed, a restart is scheduled
+)
+
+// HealthConfig controls the periodic health checks of a Manager.
+type HealthConfig struct {
+	Interval       time.Duration // Time between checks
+	Timeout        time.Duration // Deadline of a single check
+	InitialBackoff time.Duration // Wait before the first restart of a failing server
+	MaxBackoff     time.Duration // Upper bound of the exponential restart backoff
+}
+
+// DefaultHealthConfig is used by StartHealthChecks for zero fields.
+var DefaultHealthConfig = HealthConfig{
+	Interval:       30 * time.Second,
+	Timeout:        10 * time.Second,
+	InitialBackoff: time.Second,
+	MaxBackoff:     time.Minute,
+}
+
+// ServerHealth is the health of one MCP server.
+type ServerHealth struct {
+	Name        string        `json:"name"`
+	Transport   string        `json:"transport"`
+	Status      string        `json:"status"`
+	Tools       int           `json:"tools"`
+	Latency     time.Duration `json:"latency"`
+	LastCheck   time.Time     `json:"lastCheck,omitzero"`
+	LastError   string        `json:"lastError,omitempty"`
+	Failures    int           `json:"failures"` // Consecutive failed checks
+	Restarts    int           `json:"restarts"`
+	NextRestart time.Time     `json:"nextRestart,omitzero"`
+}
+
+// Healthy reports whether the server can be used. Servers that were not
+// checked yet count as healthy.
+func (h ServerHealth) Healthy() bool {
+	return h.Status != HealthUnhealthy
+}
+
+// HealthStateKey returns the state key flows branch on for a server's
+// health, e.g. x['mcp:github:healthy'].
+func HealthStateKey(server string) string {
+	return "mcp:" + server + ":healthy"
+}
+
+// restartableToolset is the toolset a Manager hands out for a server. It
+// delegates to the current ADK toolset, which a restart replaces, so
+// agents holding the wrapper pick up the restarted server on their next
+// request.
+type restartableToolset struct {
+	name string
+	cfg  config.MCPServerConfig
+
+	mu        sync.RWMutex
+	inner     tool.Toolset
+	transport mcp.Transport
+	health    ServerHealth
+}
+
+func newRestartableToolset(name string, cfg config.MCPServerConfig, transport mcp.Transport, inner tool.Toolset) *restartableToolset {
+	transportType := cfg.Transport
+	if transportType == "" {
+		transportType = "stdio"
+	}
+	return &restartableToolset{
+		name:      name,
+		cfg:       cfg,
+		inner:     inner,
+		transport: transport,
+		health:    ServerHealth{Name: name, Transport: transportType, Status: HealthUnknown},
+	}
+}
+
+func (t *restartableToolset) current() tool.Toolset {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.inner
+}
+
+func (t *restartableToolset) Name() string { return t.current().Name() }
+
+func (t *restartableToolset) Tools(ctx agent.ReadonlyContext) ([]tool.Tool, error) {
+	return t.current().Tools(ctx)
+}
+
+// check probes the server with a tools/list request, which also starts a
+// server that was not used yet.
+func (t *restartableToolset) check(ctx context.Context, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	tools, err := t.current().Tools(&probeContext{ctx: ctx})
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.health.LastCheck = time.Now()
+	t.health.Latency = time.Since(start)
+	if err != nil {
+		t.health.Status = HealthUnhealthy
+		t.health.LastError = err.Error()
+		t.health.Failures++
+		return err
+	}
+	t.health.Status = HealthHealthy
+	t.health.LastError = ""
+	t.health.Failures = 0
+	t.health.NextRestart = time.Time{}
+	t.health.Tools = len(tools)
+	return nil
+}
+
+// restart replaces the server's connection with a fresh transport (a new
+// process for stdio servers). The old process is killed, since a hung
+// server would otherwise linger.
+func (t *restartableToolset) restart() error {
+	transport, stderrBuf, err := createTransport(t.cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create transport: %w (Stderr: %s)", err, GetStderr(stderrBuf))
+	}
+	inner, err := mcptoolset.New(mcptoolset.Config{Transport: transport})
+	if err != nil {
+		return fmt.Errorf("failed to create toolset: %w (Stderr: %s)", err, GetStderr(stderrBuf))
+	}
+
+	t.mu.Lock()
+	old := t.transport
+	t.inner = inner
+	t.transport = transport
+	t.health.Restarts++
+	t.health.NextRestart = time.Time{}
+	t.mu.Unlock()
+
+	closeTransport(old)
+	return nil
+}
+
+// scheduleRestart sets the time of the next restart from the number of
+// consecutive failures (exponential backoff) and reports whether a restart
+// is due now.
+func (t *restartableToolset) scheduleRestart(cfg HealthConfig, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.health.NextRestart.IsZero() {
+		return !now.Before(t.health.NextRestart)
+	}
+	backoff := cfg.InitialBackoff
+	for i := 1; i < t.health.Failures && backoff < cfg.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	t.health.NextRestart = now.Add(min(backoff, cfg.MaxBackoff))
+	return false
+}
+
+func (t *restartableToolset) snapshot() ServerHealth {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.health
+}
+
+func (t *restartableToolset) close() {
+	t.mu.Lock()
+	transport := t.transport
+	t.transport = nil
+	t.mu.Unlock()
+	closeTransport(transport)
+}
+
+// closeTransport releases a transport: closable transports are closed and
+// the process group of a stdio transport is killed.
+func closeTransport(transport mcp.Transport) {
+	switch tr := transport.(type) {
+	case nil:
+	case *mcp.CommandTransport:
+		if tr.Command != nil && tr.Command.Process != nil && tr.Command.ProcessState == nil {
+			_ = procgroup.Kill(tr.Command)
+		}
+	case interface{ Close() error }:
+		if err := tr.Close(); err != nil {
+			slog.Warn("failed to close transport", "component", "mcp", "error", err)
+		}
+	}
+}
+
+// CheckHealth probes every server once, restarting failing servers whose
+// backoff has elapsed, and returns their health sorted by name.
+func (m *Manager) CheckHealth(ctx context.Context) []ServerHealth {
+	m.mu.Lock()
+	cfg := m.healthConfig
+	servers := append([]*restartableToolset(nil), m.servers...)
+	m.mu.Unlock()
+	if cfg.Interval == 0 {
+		cfg = DefaultHealthConfig
+	}
+
+	var wg sync.WaitGroup
+	for _, srv := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if srv.snapshot().Status == HealthUnhealthy {
+				if !srv.scheduleRestart(cfg, time.Now()) {
+					return
+				}
+				slog.Info("restarting unhealthy MCP server", "component", "mcp", "server", srv.name)
+				if err := srv.restart(); err != nil {
+					srv.mu.Lock()
+					srv.health.Failures++
+					srv.health.LastError = err.Error()
+					srv.health.NextRestart = time.Time{}
+					srv.mu.Unlock()
+					srv.scheduleRestart(cfg, time.Now())
+					return
+				}
+			}
+			if err := srv.check(ctx, cfg.Timeout); err != nil {
+				slog.Warn("MCP server health check failed", "component", "mcp", "server", srv.name, "error", err)
+				srv.scheduleRestart(cfg, time.Now())
+			}
+		}()
+	}
+	wg.Wait()
+	return m.Health()
+}
+
+// Health returns the last known health of every server, sorted by name.
+func (m *Manager) Health() []ServerHealth {
+	m.mu.Lock()
+	servers := append([]*restartableToolset(nil), m.servers...)
+	m.mu.Unlock()
+	out := make([]ServerHealth, 0, len(servers))
+	for _, srv := range servers {
+		out = append(out, srv.snapshot())
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// HealthState returns the health flags flows branch on, keyed by
+// HealthStateKey.
+func (m *Manager) HealthState() map[string]bool {
+	health := m.Health()
+	state := make(map[string]bool, len(health))
+	for _, h := range health {
+		state[HealthStateKey(h.Name)] = h.Healthy()
+	}
+	return state
+}
+
+// StartHealthChecks checks the servers periodically in the background and
+// restarts failing ones with exponential backoff until Cleanup is called.
+// Zero fields of cfg take their DefaultHealthConfig values.
+func (m *Manager) StartHealthChecks(ctx context.Context, cfg HealthConfig) {
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultHealthConfig.Interval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultHealthConfig.Timeout
+	}
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = DefaultHealthConfig.InitialBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = DefaultHealthConfig.MaxBackoff
+	}
+
+	m.mu.Lock()
+	if m.stopHealth != nil {
+		m.mu.Unlock()
+		return
+	}
+	m.healthConfig = cfg
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	m.stopHealth = cancel
+	m.mu.Unlock()
+
+	go func() {
+		// Failing servers are re-checked at the backoff pace, healthy ones
+		// at the configured interval.
+		tick := min(cfg.Interval, cfg.InitialBackoff)
+		ticker := time.NewTicker(tick)
+		defer ticker.Stop()
+		var last time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if now.Sub(last) < cfg.Interval && !m.anyUnhealthy() {
+					continue
+				}
+				last = now
+				m.CheckHealth(ctx)
+			}
+		}
+	}()
+}
+
+func (m *Manager) anyUnhealthy() bool {
+	for _, h := range m.Health() {
+		if h.Status == HealthUnhealthy {
+			return true
+		}
+	}
+	return false
+}
+
+// probeContext is the minimal agent.ReadonlyContext health checks list
+// tools with.
+type probeContext struct {
+	ctx context.Context
+}
+
+func (p *probeContext) Deadline() (time.Time, bool)          { return p.ctx.Deadline() }
+func (p *probeContext) Done() <-chan struct{}                { return p.ctx.Done() }
+func (p *probeContext) Err() error                           { return p.ctx.Err() }
+func (p *probeContext) Value(key any) any                    { return p.ctx.Value(key) }
+func (p *probeContext) AgentName() string                    { return "mcp-health" }
+func (p *probeContext) AppName() string                      { return "astonish" }
+func (p *probeContext) UserContent() *genai.Content          { return nil }
+func (p *probeContext) InvocationID() string                 { return "" }
+func (p *probeContext) ReadonlyState() session.ReadonlyState { return nil }
+func (p *probeContext) UserID() string                       { return "" }
+func (p *probeContext) SessionID() string                    { return "" }
+func (p *probeContext) Branch() string                       { return "" }
@@ -0,0 +1,70 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/tool"
+)
+
+// fakeToolset lists no tools, or fails with err.
+type fakeToolset struct{ err error }
+
+func (f *fakeToolset) Name() string { return "fake" }
+
+func (f *fakeToolset) Tools(agent.ReadonlyContext) ([]tool.Tool, error) { return nil, f.err }
+
+func TestCheckHealth_FlagsAndBackoff(t *testing.T) {
+	t.Parallel()
+	m := NewManagerFromConfig(&config.MCPConfig{})
+	up := &fakeToolset{}
+	down := &fakeToolset{err: errors.New("connection closed")}
+	m.track("github", config.MCPServerConfig{}, nil, up)
+	m.track("jira", config.MCPServerConfig{Transport: "sse"}, nil, down)
+
+	state := m.HealthState()
+	if !state["mcp:github:healthy"] || !state["mcp:jira:healthy"] {
+		t.Errorf("unchecked servers should count as healthy, got %v", state)
+	}
+
+	before := time.Now()
+	health := m.CheckHealth(context.Background())
+	if len(health) != 2 || health[0].Name != "github" || health[0].Status != HealthHealthy {
+		t.Fatalf("health = %+v, want github healthy first", health)
+	}
+	jira := health[1]
+	if jira.Status != HealthUnhealthy || jira.Failures != 1 || jira.LastError != "connection closed" {
+		t.Errorf("jira health = %+v, want one failure", jira)
+	}
+	if wait := jira.NextRestart.Sub(before); wait < time.Second || wait > 2*time.Second {
+		t.Errorf("first restart in %v, want the initial backoff (1s)", wait)
+	}
+
+	state = m.HealthState()
+	if !state["mcp:github:healthy"] || state["mcp:jira:healthy"] {
+		t.Errorf("HealthState() = %v, want github up and jira down", state)
+	}
+}
+
+func TestScheduleRestart_ExponentialBackoff(t *testing.T) {
+	t.Parallel()
+	cfg := HealthConfig{InitialBackoff: time.Second, MaxBackoff: 10 * time.Second}
+	now := time.Now()
+	for failures, want := range map[int]time.Duration{1: time.Second, 3: 4 * time.Second, 8: 10 * time.Second} {
+		srv := newRestartableToolset("s", config.MCPServerConfig{}, nil, &fakeToolset{})
+		srv.health.Failures = failures
+		if srv.scheduleRestart(cfg, now) {
+			t.Errorf("failures=%d: restart due immediately", failures)
+		}
+		if got := srv.health.NextRestart.Sub(now); got != want {
+			t.Errorf("failures=%d: backoff %v, want %v", failures, got, want)
+		}
+		if !srv.scheduleRestart(cfg, now.Add(want)) {
+			t.Errorf("failures=%d: restart not due after the backoff", failures)
+		}
+	}
+}
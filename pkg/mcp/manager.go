@@ -5,10 +5,16 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os/exec"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/procgroup"
+	"github.com/SAP/astonish/pkg/provider/httpool"
 	"google.golang.org/adk/tool"
 	"google.golang.org/adk/tool/mcptoolset"
 )
@@ -20,6 +26,13 @@ type Manager struct {
 	namedToolsets []NamedToolset
 	transports    []mcp.Transport // Track transports for cleanup
 	initResults   []InitResult    // Track initialization results per server
+	selected      []string        // Enabled servers requested by InitializeSelectiveToolsets, also those that failed
+
+	mu           sync.Mutex
+	servers      []*restartableToolset            // Restartable toolsets, one per initialized server
+	envServers   map[string][]*restartableToolset // Instances started by ToolsetsWithEnv, by env
+	healthConfig HealthConfig
+	stopHealth   context.CancelFunc // Stops StartHealthChecks (nil when not started)
 }
 
 // NamedToolset wraps an ADK toolset with its server name and stderr buffer
@@ -112,13 +125,13 @@ func (m *Manager) InitializeToolsets(ctx context.Context) error {
 			continue
 		}
 
+		toolset = m.track(serverName, serverConfig, transport, toolset)
 		m.toolsets = append(m.toolsets, toolset)
 		m.namedToolsets = append(m.namedToolsets, NamedToolset{
 			Name:    serverName,
 			Toolset: toolset,
 			Stderr:  stderrBuf,
 		})
-		m.transports = append(m.transports, transport)
 		m.initResults = append(m.initResults, InitResult{
 			Name:    serverName,
 			Success: true,
@@ -155,6 +168,7 @@ func (m *Manager) InitializeSingleToolset(ctx context.Context, serverName string
 		return nil, fmt.Errorf("failed to create toolset: %w (Stderr: %s)", err, GetStderr(stderrBuf))
 	}
 
+	toolset = m.track(serverName, serverConfig, transport, toolset)
 	namedToolset := &NamedToolset{
 		Name:    serverName,
 		Toolset: toolset,
@@ -207,6 +221,7 @@ func (m *Manager) InitializeSelectiveToolsets(ctx context.Context, serverNames [
 			slog.Info("skipping disabled MCP server", "component", "mcp", "server", serverName)
 			continue
 		}
+		m.selected = append(m.selected, serverName)
 
 		transport, stderrBuf, err := createTransport(serverConfig)
 		if err != nil {
@@ -222,12 +237,12 @@ func (m *Manager) InitializeSelectiveToolsets(ctx context.Context, serverNames [
 			continue
 		}
 
+		toolset = m.track(serverName, serverConfig, transport, toolset)
 		m.toolsets = append(m.toolsets, toolset)
 		m.namedToolsets = append(m.namedToolsets, NamedToolset{
 			Name:    serverName,
 			Toolset: toolset,
 		})
-		m.transports = append(m.transports, transport)
 		slog.Info("initialized MCP server for flow", "component", "mcp", "server", serverName)
 	}
 
@@ -235,9 +250,87 @@ func (m *Manager) InitializeSelectiveToolsets(ctx context.Context, serverNames [
 	return nil
 }
 
+// ToolsetsWithEnv returns the toolsets of the initialized servers started
+// again with extra environment variables (see MCPServerConfig.WithEnv),
+// for nodes that run their tools with their own env. This includes the
+// selected servers that failed without it, e.g. for a token only the node
+// sets. Instances are kept per env and reused until Cleanup.
+func (m *Manager) ToolsetsWithEnv(ctx context.Context, env map[string]string) ([]tool.Toolset, error) {
+	if len(env) == 0 {
+		return m.toolsets, nil
+	}
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var key strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&key, "%s=%s\x00", k, env[k])
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	servers, ok := m.envServers[key.String()]
+	if !ok {
+		names := append([]string(nil), m.selected...)
+		if m.selected == nil {
+			for _, named := range m.namedToolsets {
+				names = append(names, named.Name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			cfg := m.config.MCPServers[name].WithEnv(env)
+			transport, stderrBuf, err := createTransport(cfg)
+			if err != nil {
+				closeServers(servers)
+				return nil, fmt.Errorf("MCP server '%s': failed to create transport: %w (Stderr: %s)", name, err, GetStderr(stderrBuf))
+			}
+			toolset, err := mcptoolset.New(mcptoolset.Config{Transport: transport})
+			if err != nil {
+				closeTransport(transport)
+				closeServers(servers)
+				return nil, fmt.Errorf("MCP server '%s': failed to create toolset: %w (Stderr: %s)", name, err, GetStderr(stderrBuf))
+			}
+			servers = append(servers, newRestartableToolset(name, cfg, transport, toolset))
+		}
+		if m.envServers == nil {
+			m.envServers = make(map[string][]*restartableToolset)
+		}
+		m.envServers[key.String()] = servers
+		slog.Info("started MCP servers with node env", "component", "mcp", "servers", len(servers), "vars", keys)
+	}
+
+	toolsets := make([]tool.Toolset, len(servers))
+	for i, srv := range servers {
+		toolsets[i] = srv
+	}
+	return toolsets, nil
+}
+
+func closeServers(servers []*restartableToolset) {
+	for _, srv := range servers {
+		srv.close()
+	}
+}
+
 // Cleanup closes all MCP transports and clears the manager state
 // Should be called when the flow run completes
 func (m *Manager) Cleanup() {
+	m.mu.Lock()
+	if m.stopHealth != nil {
+		m.stopHealth()
+		m.stopHealth = nil
+	}
+	servers := m.servers
+	m.servers = nil
+	for _, envServers := range m.envServers {
+		servers = append(servers, envServers...)
+	}
+	m.envServers = nil
+	m.mu.Unlock()
+
 	for i, transport := range m.transports {
 		if closer, ok := transport.(interface{ Close() error }); ok {
 			if err := closer.Close(); err != nil {
@@ -245,14 +338,43 @@ func (m *Manager) Cleanup() {
 			}
 		}
 	}
+	for _, srv := range servers {
+		srv.close()
+	}
 	m.transports = nil
 	m.toolsets = nil
 	m.namedToolsets = nil
+	m.selected = nil
 	slog.Info("MCP manager cleaned up", "component", "mcp")
 }
 
+// track wraps a server's toolset so health checks can restart it.
+func (m *Manager) track(name string, cfg config.MCPServerConfig, transport mcp.Transport, toolset tool.Toolset) tool.Toolset {
+	srv := newRestartableToolset(name, cfg, transport, toolset)
+	m.mu.Lock()
+	m.servers = append(m.servers, srv)
+	m.mu.Unlock()
+	return srv
+}
+
+// NewTransport creates the transport of a remote (SSE or Streamable HTTP)
+// server, for callers that connect to it without a Manager.
+func NewTransport(cfg config.MCPServerConfig) (mcp.Transport, error) {
+	if !cfg.IsRemote() {
+		return nil, fmt.Errorf("transport %q is not a remote transport", cfg.Transport)
+	}
+	transport, _, err := createTransport(cfg)
+	return transport, err
+}
+
 // createTransport creates the appropriate MCP transport based on configuration
 func createTransport(cfg config.MCPServerConfig) (mcp.Transport, *bytes.Buffer, error) {
+	// OAuth servers start with a fresh token, refreshed if it has expired
+	cfg, err := withOAuthToken(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// Default to stdio if not specified
 	transportType := cfg.Transport
 	if transportType == "" {
@@ -264,6 +386,8 @@ func createTransport(cfg config.MCPServerConfig) (mcp.Transport, *bytes.Buffer,
 		return createStdioTransport(cfg)
 	case "sse":
 		return createSSETransport(cfg)
+	case "streamable-http", "http":
+		return createStreamableTransport(cfg)
 	default:
 		return nil, nil, fmt.Errorf("unsupported transport type: %s", transportType)
 	}
@@ -275,8 +399,10 @@ func createStdioTransport(cfg config.MCPServerConfig) (mcp.Transport, *bytes.Buf
 		return nil, nil, fmt.Errorf("command is required for stdio transport")
 	}
 
-	// Create the command
+	// Create the command. It leads its own process group so cleanup also
+	// stops what it spawned, e.g. the node process behind npx.
 	cmd := exec.Command(cfg.Command, cfg.Args...)
+	procgroup.Set(cmd)
 
 	// Buffer to capture stderr
 	var stderrBuf bytes.Buffer
@@ -304,14 +430,79 @@ func createSSETransport(cfg config.MCPServerConfig) (mcp.Transport, *bytes.Buffe
 	if cfg.URL == "" {
 		return nil, nil, fmt.Errorf("URL is required for SSE transport")
 	}
-
-	// Create SSE client transport
+	httpClient, err := remoteHTTPClient(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
 	return &mcp.SSEClientTransport{
-		Endpoint: cfg.URL,
-		// HTTPClient can be customized here if needed (e.g., for auth)
+		Endpoint:   cfg.URL,
+		HTTPClient: httpClient,
+	}, nil, nil
+}
+
+// createStreamableTransport creates a Streamable HTTP transport, the
+// transport of current hosted MCP services
+func createStreamableTransport(cfg config.MCPServerConfig) (mcp.Transport, *bytes.Buffer, error) {
+	if cfg.URL == "" {
+		return nil, nil, fmt.Errorf("URL is required for streamable-http transport")
+	}
+	httpClient, err := remoteHTTPClient(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Tools are listed on demand, so the standalone SSE stream for server
+	// notifications would only hold a connection open for the whole run.
+	return &mcp.StreamableClientTransport{
+		Endpoint:             cfg.URL,
+		HTTPClient:           httpClient,
+		DisableStandaloneSSE: true,
 	}, nil, nil
 }
 
+// remoteHTTPClient returns the HTTP client of a remote server: the
+// streaming client with the server's proxy / CA / TLS settings on top of
+// the global http config, sending its headers and credentials.
+func remoteHTTPClient(cfg config.MCPServerConfig) (*http.Client, error) {
+	httpClient := httpool.StreamingClient()
+	opts := httpool.Options{Proxy: cfg.Proxy, CABundle: cfg.CABundle, InsecureSkipVerify: cfg.InsecureSkipVerify}
+	if !opts.IsZero() {
+		var err error
+		httpClient, err = httpool.ClientFor(opts.Merge(httpool.DefaultOptions()), 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	headers, err := cfg.RequestHeaders()
+	if err != nil {
+		return nil, err
+	}
+	if len(headers) == 0 {
+		return httpClient, nil
+	}
+	base := httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	client := *httpClient
+	client.Transport = &headerTransport{base: base, headers: headers}
+	return &client, nil
+}
+
+// headerTransport adds fixed headers to every request.
+type headerTransport struct {
+	base    http.RoundTripper
+	headers map[string]string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	return t.base.RoundTrip(req)
+}
+
 // Helper to safely get stderr string
 func GetStderr(buf *bytes.Buffer) string {
 	if buf == nil {
@@ -2,8 +2,12 @@ package mcp
 
 import (
 	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/SAP/astonish/pkg/config"
 )
 
@@ -244,3 +248,95 @@ func TestCreateSSETransport_WithURL(t *testing.T) {
 		t.Errorf("expected nil stderr buffer for SSE transport, got %v", buf)
 	}
 }
+
+func TestStreamableTransport_SendsHeadersAndAuth(t *testing.T) {
+	t.Setenv("MCP_TEST_TOKEN", "s3cret")
+	server := mcp.NewServer(&mcp.Implementation{Name: "remote", Version: "1.0.0"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "ping", Description: "Ping"},
+		func(ctx context.Context, req *mcp.CallToolRequest, in struct{}) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{}, nil, nil
+		})
+	handler := mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer s3cret" || r.Header.Get("X-Tenant") != "acme" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	cfg := config.MCPServerConfig{
+		Transport: "streamable-http",
+		URL:       srv.URL,
+		Headers:   map[string]string{"X-Tenant": "acme"},
+		Auth:      &config.MCPAuthConfig{Type: "bearer", Token: "${MCP_TEST_TOKEN}"},
+	}
+	m := NewManagerFromConfig(&config.MCPConfig{MCPServers: map[string]config.MCPServerConfig{"remote": cfg}})
+	defer m.Cleanup()
+	if err := m.InitializeSelectiveToolsets(context.Background(), []string{"remote"}); err != nil {
+		t.Fatalf("InitializeSelectiveToolsets: %v", err)
+	}
+	health := m.CheckHealth(context.Background())
+	if len(health) != 1 || health[0].Status != HealthHealthy || health[0].Tools != 1 {
+		t.Fatalf("health = %+v, want remote healthy with 1 tool", health)
+	}
+
+	cfg.Auth.Token = "wrong"
+	denied := NewManagerFromConfig(&config.MCPConfig{MCPServers: map[string]config.MCPServerConfig{"remote": cfg}})
+	defer denied.Cleanup()
+	if err := denied.InitializeSelectiveToolsets(context.Background(), []string{"remote"}); err != nil {
+		t.Fatalf("InitializeSelectiveToolsets: %v", err)
+	}
+	if health := denied.CheckHealth(context.Background()); len(health) != 1 || health[0].Healthy() {
+		t.Errorf("health with a wrong token = %+v, want unhealthy", health)
+	}
+}
+
+func TestToolsetsWithEnv(t *testing.T) {
+	server := mcp.NewServer(&mcp.Implementation{Name: "remote", Version: "1.0.0"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "ping", Description: "Ping"},
+		func(ctx context.Context, req *mcp.CallToolRequest, in struct{}) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{}, nil, nil
+		})
+	handler := mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer repo-token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	cfg := config.MCPServerConfig{
+		Transport: "streamable-http",
+		URL:       srv.URL,
+		Auth:      &config.MCPAuthConfig{Type: "bearer", Token: "${REPO_TOKEN}"},
+	}
+	m := NewManagerFromConfig(&config.MCPConfig{MCPServers: map[string]config.MCPServerConfig{"remote": cfg}})
+	defer m.Cleanup()
+	if err := m.InitializeSelectiveToolsets(context.Background(), []string{"remote"}); err != nil {
+		t.Fatal(err)
+	}
+	// Only nodes set the token, so the flow's instance cannot start
+	if len(m.GetToolsets()) != 0 {
+		t.Fatal("the server started without its token")
+	}
+
+	env := map[string]string{"REPO_TOKEN": "repo-token"}
+	toolsets, err := m.ToolsetsWithEnv(context.Background(), env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tools, err := toolsets[0].Tools(&probeContext{context.Background()})
+	if err != nil || len(tools) != 1 {
+		t.Fatalf("tools with the node's env = %v, %v; want ping", tools, err)
+	}
+	if again, _ := m.ToolsetsWithEnv(context.Background(), env); again[0] != toolsets[0] {
+		t.Error("the same env started the servers again")
+	}
+	if len(m.GetToolsets()) != 0 || len(m.Health()) != 0 {
+		t.Error("the env-scoped servers were added to the flow's servers")
+	}
+}
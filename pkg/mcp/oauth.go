@@ -0,0 +1,282 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/credentials"
+	"github.com/SAP/astonish/pkg/provider/httpool"
+)
+
+// TokenStore keeps the OAuth credentials of MCP servers.
+type TokenStore interface {
+	// Resolve returns the Authorization header of the named credential,
+	// refreshing its access token when it has expired.
+	Resolve(name string) (headerKey, headerValue string, err error)
+	Set(name string, cred *credentials.Credential) error
+}
+
+var (
+	tokenStoreMu sync.RWMutex
+	tokenStore   TokenStore
+)
+
+// SetTokenStore sets the store OAuth tokens are read from and saved to.
+// With no store set, the credential store of the config directory is
+// opened on each use so logins from other processes are picked up.
+func SetTokenStore(s TokenStore) {
+	tokenStoreMu.Lock()
+	tokenStore = s
+	tokenStoreMu.Unlock()
+}
+
+func getTokenStore() (TokenStore, error) {
+	tokenStoreMu.RLock()
+	s := tokenStore
+	tokenStoreMu.RUnlock()
+	if s != nil {
+		return s, nil
+	}
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return nil, fmt.Errorf("config dir: %w", err)
+	}
+	store, err := credentials.Open(configDir)
+	if err != nil {
+		return nil, fmt.Errorf("credential store: %w", err)
+	}
+	return store, nil
+}
+
+// OAuthCredentialName returns the credential store name of a server's
+// OAuth tokens.
+func OAuthCredentialName(serverName string, auth *config.MCPAuthConfig) string {
+	if auth != nil && auth.Credential != "" {
+		return auth.Credential
+	}
+	return "mcp-" + serverName
+}
+
+// withOAuthToken returns cfg with a fresh access token of its OAuth
+// credential: in the auth config of a remote server, in the TokenEnv
+// variable of a stdio server. Other configs are returned unchanged.
+func withOAuthToken(cfg config.MCPServerConfig) (config.MCPServerConfig, error) {
+	if cfg.Auth == nil || !strings.EqualFold(cfg.Auth.Type, "oauth") {
+		return cfg, nil
+	}
+	if cfg.Auth.Credential == "" {
+		return cfg, fmt.Errorf("oauth auth has no credential; run 'astonish mcp login <server>'")
+	}
+	if !cfg.IsRemote() && cfg.Auth.TokenEnv == "" {
+		return cfg, fmt.Errorf("oauth auth of a stdio server requires token_env")
+	}
+
+	store, err := getTokenStore()
+	if err != nil {
+		return cfg, err
+	}
+	_, headerValue, err := store.Resolve(cfg.Auth.Credential)
+	if err != nil {
+		return cfg, fmt.Errorf("%w (run 'astonish mcp login' to authorize again)", err)
+	}
+	token := strings.TrimPrefix(headerValue, "Bearer ")
+
+	// Copy what is changed so the manager's config keeps no token
+	if cfg.IsRemote() {
+		auth := *cfg.Auth
+		auth.Token = token
+		cfg.Auth = &auth
+		return cfg, nil
+	}
+	env := make(map[string]string, len(cfg.Env)+1)
+	for k, v := range cfg.Env {
+		env[k] = v
+	}
+	env[cfg.Auth.TokenEnv] = token
+	cfg.Env = env
+	return cfg, nil
+}
+
+// DeviceCode is the code of a device flow login (RFC 8628) the user enters
+// at VerificationURI.
+type DeviceCode struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// deviceTokenResponse is the token endpoint response while polling.
+type deviceTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	Error        string `json:"error"`
+	ErrorDesc    string `json:"error_description"`
+}
+
+// Login authorizes a server with the OAuth device flow of its auth config.
+// prompt is called with the code the user has to enter; the token endpoint
+// is then polled until the user approves, and the tokens are saved under
+// auth.Credential.
+func Login(ctx context.Context, auth *config.MCPAuthConfig, prompt func(*DeviceCode)) error {
+	if auth == nil || !strings.EqualFold(auth.Type, "oauth") {
+		return fmt.Errorf("server has no oauth auth config")
+	}
+	if auth.Credential == "" || auth.ClientID == "" || auth.DeviceAuthURL == "" || auth.TokenURL == "" {
+		return fmt.Errorf("oauth auth requires credential, client_id, device_auth_url and token_url")
+	}
+	store, err := getTokenStore()
+	if err != nil {
+		return err
+	}
+	clientSecret := os.ExpandEnv(auth.ClientSecret)
+
+	code, err := requestDeviceCode(ctx, auth, clientSecret)
+	if err != nil {
+		return err
+	}
+	prompt(code)
+
+	token, err := pollDeviceToken(ctx, auth, clientSecret, code)
+	if err != nil {
+		return err
+	}
+	cred := &credentials.Credential{
+		Type:         credentials.CredOAuthAuthCode,
+		TokenURL:     auth.TokenURL,
+		ClientID:     auth.ClientID,
+		ClientSecret: clientSecret,
+		Scope:        auth.Scope,
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+	}
+	if token.ExpiresIn > 0 {
+		cred.TokenExpiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second).Format(time.RFC3339)
+	}
+	if err := store.Set(auth.Credential, cred); err != nil {
+		return fmt.Errorf("failed to save credential: %w", err)
+	}
+	return nil
+}
+
+// requestDeviceCode starts a device flow login.
+func requestDeviceCode(ctx context.Context, auth *config.MCPAuthConfig, clientSecret string) (*DeviceCode, error) {
+	form := url.Values{"client_id": {auth.ClientID}}
+	if auth.Scope != "" {
+		form.Set("scope", auth.Scope)
+	}
+	if clientSecret != "" {
+		form.Set("client_secret", clientSecret)
+	}
+	body, status, err := postOAuthForm(ctx, auth.DeviceAuthURL, form)
+	if err != nil {
+		return nil, fmt.Errorf("device authorization request: %w", err)
+	}
+
+	var code struct {
+		DeviceCode
+		VerificationURL string `json:"verification_url"` // Google's name for verification_uri
+		Error           string `json:"error"`
+		ErrorDesc       string `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &code); err != nil {
+		return nil, fmt.Errorf("parse device authorization response (status %d): %w", status, err)
+	}
+	if code.Error != "" {
+		return nil, fmt.Errorf("device authorization error: %s (%s)", code.Error, code.ErrorDesc)
+	}
+	if code.VerificationURI == "" {
+		code.VerificationURI = code.VerificationURL
+	}
+	if code.DeviceCode.DeviceCode == "" || code.UserCode == "" || code.VerificationURI == "" {
+		return nil, fmt.Errorf("device authorization response is incomplete (status %d)", status)
+	}
+	if code.Interval <= 0 {
+		code.Interval = 5
+	}
+	if code.ExpiresIn <= 0 {
+		code.ExpiresIn = 900
+	}
+	return &code.DeviceCode, nil
+}
+
+// pollDeviceToken polls the token endpoint until the user has approved the
+// login, the code expires or ctx is done.
+func pollDeviceToken(ctx context.Context, auth *config.MCPAuthConfig, clientSecret string, code *DeviceCode) (*deviceTokenResponse, error) {
+	form := url.Values{
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+		"device_code": {code.DeviceCode},
+		"client_id":   {auth.ClientID},
+	}
+	if clientSecret != "" {
+		form.Set("client_secret", clientSecret)
+	}
+
+	interval := time.Duration(code.Interval) * time.Second
+	deadline := time.Now().Add(time.Duration(code.ExpiresIn) * time.Second)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("the login code expired before it was approved")
+		}
+
+		body, status, err := postOAuthForm(ctx, auth.TokenURL, form)
+		if err != nil {
+			return nil, fmt.Errorf("token request: %w", err)
+		}
+		var token deviceTokenResponse
+		if err := json.Unmarshal(body, &token); err != nil {
+			return nil, fmt.Errorf("parse token response (status %d): %w", status, err)
+		}
+		switch token.Error {
+		case "":
+			if token.AccessToken == "" {
+				return nil, fmt.Errorf("token response missing access_token (status %d)", status)
+			}
+			return &token, nil
+		case "authorization_pending":
+		case "slow_down":
+			interval += 5 * time.Second
+		case "access_denied":
+			return nil, fmt.Errorf("the login was denied")
+		case "expired_token":
+			return nil, fmt.Errorf("the login code expired before it was approved")
+		default:
+			return nil, fmt.Errorf("OAuth error: %s (%s)", token.Error, token.ErrorDesc)
+		}
+	}
+}
+
+// postOAuthForm posts form to an OAuth endpoint and returns the response
+// body, asking for JSON (GitHub answers form-encoded otherwise).
+func postOAuthForm(ctx context.Context, endpoint string, form url.Values) ([]byte, int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := httpool.Client(30 * time.Second).Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	return body, resp.StatusCode, err
+}
@@ -0,0 +1,116 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/credentials"
+)
+
+// oauthServer serves a device flow that is approved on the second poll and
+// refreshes tokens for public clients.
+func oauthServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	polls := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("client_id") != "app" {
+			http.Error(w, "unknown client", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"device_code": "dev", "user_code": "ABCD-1234",
+			"verification_uri": "https://example.com/device", "expires_in": 60, "interval": 1,
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		switch r.FormValue("grant_type") {
+		case "urn:ietf:params:oauth:grant-type:device_code":
+			if polls++; polls < 2 {
+				json.NewEncoder(w).Encode(map[string]string{"error": "authorization_pending"})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{"access_token": "first", "refresh_token": "r1", "expires_in": 3600})
+		case "refresh_token":
+			if r.FormValue("refresh_token") != "r1" || r.FormValue("client_id") != "app" {
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{"access_token": "second", "expires_in": 3600})
+		}
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestOAuthLoginAndTokenInjection(t *testing.T) {
+	store, err := credentials.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetTokenStore(store)
+	t.Cleanup(func() { SetTokenStore(nil) })
+
+	oauth := oauthServer(t)
+	auth := &config.MCPAuthConfig{
+		Type: "oauth", Credential: "mcp-remote", ClientID: "app",
+		DeviceAuthURL: oauth.URL + "/device", TokenURL: oauth.URL + "/token",
+	}
+	var shown *DeviceCode
+	if err := Login(context.Background(), auth, func(code *DeviceCode) { shown = code }); err != nil {
+		t.Fatal(err)
+	}
+	if shown == nil || shown.UserCode != "ABCD-1234" {
+		t.Errorf("prompted with %+v, want the user code", shown)
+	}
+
+	// A remote server only answers requests with the current token
+	server := mcp.NewServer(&mcp.Implementation{Name: "remote", Version: "1.0.0"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "ping", Description: "Ping"},
+		func(ctx context.Context, req *mcp.CallToolRequest, in struct{}) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{}, nil, nil
+		})
+	handler := mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer first" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	cfg := config.MCPServerConfig{Transport: "streamable-http", URL: srv.URL, Auth: auth}
+	manager := NewManagerFromConfig(&config.MCPConfig{MCPServers: map[string]config.MCPServerConfig{"remote": cfg}})
+	defer manager.Cleanup()
+	if _, err := manager.CacheServerTools(context.Background(), "remote"); err != nil {
+		t.Fatalf("starting the server with the login token: %v", err)
+	}
+	if auth.Token != "" {
+		t.Error("the token was written into the server config")
+	}
+
+	// An expired token is refreshed when a stdio server is started
+	cred := store.Get("mcp-remote")
+	cred.TokenExpiry = time.Now().Add(-time.Hour).Format(time.RFC3339)
+	if err := store.Set("mcp-remote", cred); err != nil {
+		t.Fatal(err)
+	}
+	store.InvalidateToken("mcp-remote")
+	stdioAuth := *auth
+	stdioAuth.TokenEnv = "API_TOKEN"
+	got, err := withOAuthToken(config.MCPServerConfig{Command: "server", Auth: &stdioAuth})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Env["API_TOKEN"] != "second" {
+		t.Errorf("env = %v, want the refreshed token in API_TOKEN", got.Env)
+	}
+}
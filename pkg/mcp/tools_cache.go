@@ -0,0 +1,194 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/SAP/astonish/pkg/cache"
+	"github.com/SAP/astonish/pkg/common"
+	"github.com/SAP/astonish/pkg/config"
+)
+
+// toolsCacheServerTimeout bounds how long a refresh waits for one server to
+// start and list its tools.
+const toolsCacheServerTimeout = 2 * time.Minute
+
+// syncToolsCacheMu serializes cache syncs within the process, so the watcher
+// and a command never connect to the same servers twice.
+var syncToolsCacheMu sync.Mutex
+
+// CacheServerTools connects to a configured server and stores its tools in
+// the persistent tools cache. The cache is not saved. Returns the number of
+// tools cached.
+func (m *Manager) CacheServerTools(ctx context.Context, serverName string) (int, error) {
+	namedToolset, err := m.InitializeSingleToolset(ctx, serverName)
+	if err != nil {
+		return 0, err
+	}
+	mcpTools, err := namedToolset.Toolset.Tools(&probeContext{ctx: ctx})
+	if err != nil {
+		return 0, err
+	}
+
+	entries := make([]cache.ToolEntry, 0, len(mcpTools))
+	for _, t := range mcpTools {
+		entries = append(entries, cache.ToolEntry{
+			Name:        t.Name(),
+			Description: t.Description(),
+			Source:      serverName,
+			InputSchema: common.ExtractToolInputSchema(t),
+		})
+	}
+	serverCfg := m.config.MCPServers[serverName]
+	cache.AddServerTools(serverName, entries, cache.ComputeServerChecksum(serverCfg.Command, serverCfg.Args, serverCfg.Env))
+	return len(entries), nil
+}
+
+// SyncToolsCache brings the persistent tools cache in line with cfg: servers
+// no longer configured are dropped, and enabled servers that are new or whose
+// config changed are started and their tools cached. Returns the servers
+// refreshed and the ones that failed, which are retried on the next sync.
+func SyncToolsCache(ctx context.Context, cfg *config.MCPConfig) (refreshed []string, failed map[string]error) {
+	syncToolsCacheMu.Lock()
+	defer syncToolsCacheMu.Unlock()
+
+	// Another process may have written the cache since it was loaded
+	cache.InvalidateCache()
+	if _, err := cache.LoadCache(); err != nil {
+		slog.Warn("failed to load persistent cache", "component", "cache", "error", err)
+	}
+	needsRefresh, removed := cache.ValidateServerChecksums(cfg, false)
+	for _, serverName := range removed {
+		cache.RemoveServer(serverName)
+	}
+
+	m := NewManagerFromConfig(cfg)
+	defer m.Cleanup()
+	sort.Strings(needsRefresh)
+	failed = make(map[string]error)
+	for _, serverName := range needsRefresh {
+		if serverCfg := cfg.MCPServers[serverName]; !serverCfg.IsEnabled() {
+			continue
+		}
+		serverCtx, cancel := context.WithTimeout(ctx, toolsCacheServerTimeout)
+		count, err := m.CacheServerTools(serverCtx, serverName)
+		cancel()
+		if err != nil {
+			failed[serverName] = err
+			continue
+		}
+		slog.Info("refreshed server", "component", "cache", "server", serverName, "tools", count)
+		refreshed = append(refreshed, serverName)
+	}
+
+	if len(removed) > 0 || len(refreshed) > 0 {
+		if err := cache.SaveCache(); err != nil {
+			slog.Warn("failed to save persistent cache after refresh", "component", "cache", "error", err)
+		}
+	}
+	return refreshed, failed
+}
+
+// ToolsCacheWatcherOpts holds the options for WatchToolsCache.
+type ToolsCacheWatcherOpts struct {
+	// Debounce is how long the MCP config must be unchanged before a sync.
+	// Default: 1500ms.
+	Debounce time.Duration
+	// OnSync is called after every sync. Optional.
+	OnSync func(refreshed []string, failed map[string]error)
+}
+
+// WatchToolsCache keeps the persistent tools cache warm: it syncs the cache
+// once, then again whenever the MCP config file changes, so commands that
+// read the cache never wait for servers to start.
+//
+// The config directory is watched rather than the file, so the watch
+// survives editors' atomic saves and a config file created later. This
+// function blocks until ctx is cancelled.
+func WatchToolsCache(ctx context.Context, opts ToolsCacheWatcherOpts) error {
+	if opts.Debounce <= 0 {
+		opts.Debounce = 1500 * time.Millisecond
+	}
+	configPath, err := config.GetMCPConfigPath()
+	if err != nil {
+		return err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	configDir := filepath.Dir(configPath)
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return err
+	}
+	if err := watcher.Add(configDir); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", configDir, err)
+	}
+
+	resync := func() {
+		if ctx.Err() != nil {
+			return
+		}
+		mcpCfg, err := config.LoadMCPConfig()
+		if err != nil {
+			slog.Warn("failed to load MCP config for the tools cache", "component", "cache", "error", err)
+			return
+		}
+		refreshed, failed := SyncToolsCache(ctx, mcpCfg)
+		for serverName, err := range failed {
+			slog.Warn("failed to refresh server", "component", "cache", "server", serverName, "error", err)
+		}
+		if opts.OnSync != nil {
+			opts.OnSync(refreshed, failed)
+		}
+	}
+	go resync()
+
+	var debounceTimer *time.Timer
+	var debounceMu sync.Mutex
+	defer func() {
+		debounceMu.Lock()
+		if debounceTimer != nil {
+			debounceTimer.Stop()
+		}
+		debounceMu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Clean(event.Name) != configPath {
+				continue
+			}
+			if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) || event.Has(fsnotify.Rename) || event.Has(fsnotify.Remove) {
+				debounceMu.Lock()
+				if debounceTimer != nil {
+					debounceTimer.Stop()
+				}
+				debounceTimer = time.AfterFunc(opts.Debounce, resync)
+				debounceMu.Unlock()
+			}
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			slog.Warn("tools cache watcher error", "component", "cache", "error", err)
+		}
+	}
+}
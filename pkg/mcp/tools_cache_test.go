@@ -0,0 +1,116 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/SAP/astonish/pkg/cache"
+	"github.com/SAP/astonish/pkg/config"
+)
+
+// toolServer serves an MCP server with a single ping tool.
+func toolServer(t *testing.T) string {
+	t.Helper()
+	server := mcp.NewServer(&mcp.Implementation{Name: "remote", Version: "1.0.0"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "ping", Description: "Ping"},
+		func(ctx context.Context, req *mcp.CallToolRequest, in struct{}) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{}, nil, nil
+		})
+	srv := httptest.NewServer(mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func writeMCPConfig(t *testing.T, cfg *config.MCPConfig) {
+	t.Helper()
+	path, err := config.GetMCPConfigPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(cfg)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSyncToolsCache(t *testing.T) {
+	cache.SetCacheDir(t.TempDir())
+	t.Cleanup(func() { cache.SetCacheDir("") })
+	cache.LoadCache()
+	cache.AddServerTools("gone", []cache.ToolEntry{{Name: "old", Source: "gone"}}, "x")
+	if err := cache.SaveCache(); err != nil {
+		t.Fatal(err)
+	}
+
+	disabled := false
+	cfg := &config.MCPConfig{MCPServers: map[string]config.MCPServerConfig{
+		"remote": {Transport: "streamable-http", URL: toolServer(t)},
+		"off":    {Command: "does-not-exist", Enabled: &disabled},
+	}}
+	refreshed, failed := SyncToolsCache(context.Background(), cfg)
+	if !slices.Equal(refreshed, []string{"remote"}) || len(failed) != 0 {
+		t.Fatalf("refreshed %v, failed %v; want only remote refreshed", refreshed, failed)
+	}
+	if tools := cache.GetToolsForServer("remote"); len(tools) != 1 || tools[0].Name != "ping" {
+		t.Errorf("cached tools = %+v, want ping", tools)
+	}
+	if cache.HasServer("gone") {
+		t.Error("a server no longer configured is still cached")
+	}
+
+	// Nothing changed: no server is started again
+	if refreshed, _ := SyncToolsCache(context.Background(), cfg); len(refreshed) != 0 {
+		t.Errorf("refreshed %v on an unchanged config", refreshed)
+	}
+}
+
+func TestWatchToolsCache(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	cache.SetCacheDir(t.TempDir())
+	t.Cleanup(func() { cache.SetCacheDir("") })
+
+	url := toolServer(t)
+	servers := map[string]config.MCPServerConfig{"first": {Transport: "streamable-http", URL: url}}
+	writeMCPConfig(t, &config.MCPConfig{MCPServers: servers})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	synced := make(chan []string, 4)
+	go WatchToolsCache(ctx, ToolsCacheWatcherOpts{
+		Debounce: 20 * time.Millisecond,
+		OnSync:   func(refreshed []string, _ map[string]error) { synced <- refreshed },
+	})
+
+	wait := func() []string {
+		select {
+		case refreshed := <-synced:
+			return refreshed
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for a tools cache sync")
+			return nil
+		}
+	}
+	if refreshed := wait(); !slices.Equal(refreshed, []string{"first"}) {
+		t.Fatalf("initial sync refreshed %v, want first", refreshed)
+	}
+
+	servers["second"] = config.MCPServerConfig{Transport: "streamable-http", URL: url}
+	writeMCPConfig(t, &config.MCPConfig{MCPServers: servers})
+	if refreshed := wait(); !slices.Equal(refreshed, []string{"second"}) {
+		t.Fatalf("sync after the config change refreshed %v, want second", refreshed)
+	}
+	if !cache.HasServer("second") {
+		t.Error("the added server is not cached")
+	}
+}
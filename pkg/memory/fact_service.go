@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"context"
+
+	adkmemory "google.golang.org/adk/memory"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// factSearchLimit is the number of facts returned by FactService searches.
+const factSearchLimit = 10
+
+// FactService exposes a FactStore as an ADK memory service, so tools and
+// agents that search memory through the ADK context see the facts stored
+// for the user of the session.
+type FactService struct {
+	Facts FactStore
+}
+
+var _ adkmemory.Service = (*FactService)(nil)
+
+// UserNamespace is the fact namespace of a user.
+func UserNamespace(userID string) string {
+	return "user:" + userID
+}
+
+// FlowNamespace is the fact namespace of a flow.
+func FlowNamespace(flow string) string {
+	return "flow:" + flow
+}
+
+// AddSessionToMemory does nothing: flows store facts explicitly with memory
+// nodes rather than ingesting whole sessions.
+func (s *FactService) AddSessionToMemory(ctx context.Context, sess session.Session) error {
+	return nil
+}
+
+// SearchMemory returns the facts of the request's user that match its query.
+func (s *FactService) SearchMemory(ctx context.Context, req *adkmemory.SearchRequest) (*adkmemory.SearchResponse, error) {
+	facts, err := s.Facts.Recall(ctx, UserNamespace(req.UserID), req.Query, factSearchLimit)
+	if err != nil {
+		return nil, err
+	}
+	resp := &adkmemory.SearchResponse{Memories: make([]adkmemory.Entry, 0, len(facts))}
+	for _, f := range facts {
+		resp.Memories = append(resp.Memories, adkmemory.Entry{
+			ID:             f.ID,
+			Content:        genai.NewContentFromText(f.Content, genai.RoleUser),
+			Author:         "memory",
+			Timestamp:      f.CreatedAt,
+			CustomMetadata: map[string]any{"score": f.Score},
+		})
+	}
+	return resp, nil
+}
@@ -0,0 +1,290 @@
+package memory
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/google/uuid"
+)
+
+// Fact is a piece of long-term memory a flow stores with a memory node and
+// recalls in later runs.
+type Fact struct {
+	ID        string    `json:"id"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+	Embedding []float32 `json:"embedding,omitempty"`
+	// Score is the relevance of a recalled fact to the query (not stored).
+	Score float64 `json:"-"`
+}
+
+// FactStore keeps facts in namespaces (such as "flow:<name>" or
+// "user:<id>") so they can be recalled across runs.
+type FactStore interface {
+	// Remember stores content in namespace and returns the stored fact.
+	// Content that is already stored is not duplicated.
+	Remember(ctx context.Context, namespace, content string) (Fact, error)
+
+	// Recall returns up to limit facts of namespace, most relevant to query
+	// first. An empty query returns the most recent facts.
+	Recall(ctx context.Context, namespace, query string, limit int) ([]Fact, error)
+
+	// Close releases resources held by the store.
+	Close() error
+}
+
+// fileFactStore keeps each namespace as a JSONL file in a directory. With an
+// embedding function, facts are embedded when stored and recalled by cosine
+// similarity; without one, by keyword overlap.
+type fileFactStore struct {
+	mu      sync.Mutex
+	dir     string
+	embed   EmbeddingFunc
+	cleanup func() error
+	now     func() time.Time
+}
+
+// NewFileFactStore returns a fact store in dir that recalls by keyword
+// overlap.
+func NewFileFactStore(dir string) (FactStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create fact directory: %w", err)
+	}
+	return &fileFactStore{dir: dir, now: time.Now}, nil
+}
+
+// NewVectorFactStore returns a fact store in dir that embeds facts with
+// embed and recalls them by cosine similarity. cleanup, if not nil, is
+// called by Close.
+func NewVectorFactStore(dir string, embed EmbeddingFunc, cleanup func() error) (FactStore, error) {
+	if embed == nil {
+		return nil, fmt.Errorf("vector fact store needs an embedding function")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create fact directory: %w", err)
+	}
+	return &fileFactStore{dir: dir, embed: embed, cleanup: cleanup, now: time.Now}, nil
+}
+
+var (
+	flowFactStoresMu sync.Mutex
+	flowFactStores   = map[string]FactStore{}
+)
+
+// FlowFactStore returns the fact store of flow memory nodes configured in
+// memory.flows: file-backed (default) or vector-backed with the memory
+// embedding provider. Stores are opened once and shared by all runs of the
+// process, so an embedding model is loaded only once.
+func FlowFactStore(appCfg *config.AppConfig, debugMode bool) (FactStore, error) {
+	var memCfg config.MemoryConfig
+	if appCfg != nil {
+		memCfg = appCfg.Memory
+	}
+	dir, err := memCfg.Flows.GetDir()
+	if err != nil {
+		return nil, err
+	}
+
+	flowFactStoresMu.Lock()
+	defer flowFactStoresMu.Unlock()
+	key := memCfg.Flows.Backend + "|" + dir
+	if store, ok := flowFactStores[key]; ok {
+		return store, nil
+	}
+
+	var store FactStore
+	switch memCfg.Flows.Backend {
+	case "", "file":
+		store, err = NewFileFactStore(dir)
+	case "vector":
+		embed, embedErr := flowEmbedder(appCfg, &memCfg, debugMode)
+		if embedErr != nil {
+			return nil, fmt.Errorf("flow memory: %w", embedErr)
+		}
+		store, err = NewVectorFactStore(dir, embed, nil)
+	default:
+		return nil, fmt.Errorf("unknown flow memory backend %q (use file or vector)", memCfg.Flows.Backend)
+	}
+	if err != nil {
+		return nil, err
+	}
+	flowFactStores[key] = store
+	return store, nil
+}
+
+func (s *fileFactStore) Remember(ctx context.Context, namespace, content string) (Fact, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return Fact{}, fmt.Errorf("nothing to remember: content is empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	facts, err := s.load(namespace)
+	if err != nil {
+		return Fact{}, err
+	}
+	for _, f := range facts {
+		if f.Content == content {
+			return f, nil
+		}
+	}
+
+	fact := Fact{ID: uuid.New().String(), Content: content, CreatedAt: s.now().UTC()}
+	if s.embed != nil {
+		if fact.Embedding, err = s.embed(ctx, content); err != nil {
+			return Fact{}, fmt.Errorf("failed to embed fact: %w", err)
+		}
+	}
+	line, err := json.Marshal(fact)
+	if err != nil {
+		return Fact{}, err
+	}
+	f, err := os.OpenFile(s.path(namespace), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return Fact{}, fmt.Errorf("failed to open fact file: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return Fact{}, fmt.Errorf("failed to write fact: %w", err)
+	}
+	return fact, nil
+}
+
+func (s *fileFactStore) Recall(ctx context.Context, namespace, query string, limit int) ([]Fact, error) {
+	s.mu.Lock()
+	facts, err := s.load(namespace)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	query = strings.TrimSpace(query)
+	var queryVec []float32
+	if query != "" && s.embed != nil {
+		if queryVec, err = s.embed(ctx, query); err != nil {
+			return nil, fmt.Errorf("failed to embed query: %w", err)
+		}
+	}
+
+	matched := facts[:0]
+	for _, f := range facts {
+		switch {
+		case query == "":
+			f.Score = 1
+		case queryVec != nil && len(f.Embedding) == len(queryVec):
+			f.Score = cosineSimilarity(queryVec, f.Embedding)
+		default:
+			f.Score = keywordScore(query, f.Content)
+		}
+		if f.Score > 0 {
+			matched = append(matched, f)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		if matched[i].Score != matched[j].Score {
+			return matched[i].Score > matched[j].Score
+		}
+		return matched[i].CreatedAt.After(matched[j].CreatedAt)
+	})
+	if limit > 0 && len(matched) > limit {
+		matched = matched[:limit]
+	}
+	return matched, nil
+}
+
+func (s *fileFactStore) Close() error {
+	if s.cleanup != nil {
+		return s.cleanup()
+	}
+	return nil
+}
+
+// load reads the facts of namespace. Caller must hold s.mu.
+func (s *fileFactStore) load(namespace string) ([]Fact, error) {
+	f, err := os.Open(s.path(namespace))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open fact file: %w", err)
+	}
+	defer f.Close()
+
+	var facts []Fact
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var fact Fact
+		if err := json.Unmarshal(scanner.Bytes(), &fact); err != nil {
+			continue // skip a torn line rather than losing the namespace
+		}
+		facts = append(facts, fact)
+	}
+	return facts, scanner.Err()
+}
+
+var unsafeNamespaceChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// path returns the file of namespace: a readable form of the name plus a
+// short hash, so distinct namespaces never share a file.
+func (s *fileFactStore) path(namespace string) string {
+	sum := sha256.Sum256([]byte(namespace))
+	name := strings.Trim(unsafeNamespaceChars.ReplaceAllString(namespace, "_"), "_.")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return filepath.Join(s.dir, name+"-"+hex.EncodeToString(sum[:4])+".jsonl")
+}
+
+var wordPattern = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// keywordScore is the share of the query's words that appear in content.
+func keywordScore(query, content string) float64 {
+	words := make(map[string]bool)
+	for _, w := range wordPattern.FindAllString(strings.ToLower(content), -1) {
+		words[w] = true
+	}
+	var total, hits int
+	seen := make(map[string]bool)
+	for _, w := range wordPattern.FindAllString(strings.ToLower(query), -1) {
+		if len([]rune(w)) < 2 || seen[w] {
+			continue
+		}
+		seen[w] = true
+		total++
+		if words[w] {
+			hits++
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(hits) / float64(total)
+}
+
+func cosineSimilarity(a, b []float32) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	adkmemory "google.golang.org/adk/memory"
+)
+
+func TestFileFactStoreRecall(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFileFactStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := store.(*fileFactStore)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	fs.now = func() time.Time { now = now.Add(time.Minute); return now }
+
+	for _, c := range []string{"The deploy runs on Fridays", "Release notes live in CHANGELOG.md", "Deploy approvals go to the release team"} {
+		if _, err := store.Remember(ctx, "flow:release", c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := store.Remember(ctx, "flow:release", "  The deploy runs on Fridays "); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Remember(ctx, "flow:other", "Unrelated deploy fact"); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := store.Recall(ctx, "flow:release", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("expected 3 facts without duplicates, got %d", len(all))
+	}
+	if all[0].Content != "Deploy approvals go to the release team" {
+		t.Errorf("empty query should return the most recent first, got %q", all[0].Content)
+	}
+
+	got, err := store.Recall(ctx, "flow:release", "who approves the deploy release", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Content != "Deploy approvals go to the release team" || got[1].Content != "The deploy runs on Fridays" {
+		t.Errorf("recall = %+v", got)
+	}
+
+	if got, _ := store.Recall(ctx, "flow:missing", "deploy", 5); len(got) != 0 {
+		t.Errorf("unknown namespace returned %+v", got)
+	}
+}
+
+func TestVectorFactStoreAndService(t *testing.T) {
+	ctx := context.Background()
+	// Embed texts as (has "cat", has "dog") so similarity is predictable
+	embed := func(ctx context.Context, text string) ([]float32, error) {
+		text = strings.ToLower(text)
+		v := []float32{0.1, 0.1}
+		if strings.Contains(text, "cat") {
+			v[0] = 1
+		}
+		if strings.Contains(text, "dog") {
+			v[1] = 1
+		}
+		return v, nil
+	}
+	store, err := NewVectorFactStore(t.TempDir(), embed, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []string{"Prefers dogs", "Has a cat named Tom"} {
+		if _, err := store.Remember(ctx, UserNamespace("u1"), c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	svc := &FactService{Facts: store}
+	resp, err := svc.SearchMemory(ctx, &adkmemory.SearchRequest{UserID: "u1", Query: "my cat"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Memories) != 2 || resp.Memories[0].Content.Parts[0].Text != "Has a cat named Tom" {
+		t.Errorf("memories = %+v", resp.Memories)
+	}
+	if resp, _ := svc.SearchMemory(ctx, &adkmemory.SearchRequest{UserID: "u2", Query: "cat"}); len(resp.Memories) != 0 {
+		t.Errorf("other user saw %d memories", len(resp.Memories))
+	}
+}
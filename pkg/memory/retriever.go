@@ -0,0 +1,119 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/SAP/astonish/pkg/config"
+)
+
+// Retriever indexes text into a VectorStore and searches it, embedding
+// chunks and queries with the same embedding function.
+type Retriever struct {
+	Store VectorStore
+	Embed EmbeddingFunc
+	// MaxChars and Overlap control how indexed text is chunked.
+	MaxChars int
+	Overlap  int
+}
+
+// Index splits text into chunks, embeds them and stores them in
+// collection. source names the text in the chunks' metadata; chunks are
+// identified by source and content, so indexing the same text again
+// replaces its chunks rather than duplicating them. It returns the number
+// of chunks stored.
+func (r *Retriever) Index(ctx context.Context, collection, source, text string) (int, error) {
+	chunks := ChunkFile(source, text, r.MaxChars, r.Overlap)
+	docs := make([]Document, 0, len(chunks))
+	for _, c := range chunks {
+		vec, err := r.Embed(ctx, c.Text)
+		if err != nil {
+			return 0, fmt.Errorf("failed to embed chunk of %s: %w", source, err)
+		}
+		docs = append(docs, Document{
+			ID:        c.ID,
+			Content:   c.Text,
+			Metadata:  map[string]any{"source": source, "start_line": c.StartLine, "end_line": c.EndLine},
+			Embedding: vec,
+		})
+	}
+	if len(docs) == 0 {
+		return 0, nil
+	}
+	if err := r.Store.Upsert(ctx, collection, docs); err != nil {
+		return 0, err
+	}
+	return len(docs), nil
+}
+
+// Search embeds query and returns up to limit documents of collection with
+// a similarity of at least minScore, most similar first.
+func (r *Retriever) Search(ctx context.Context, collection, query string, limit int, minScore float64) ([]Document, error) {
+	vec, err := r.Embed(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	return r.Store.Query(ctx, collection, vec, limit, minScore)
+}
+
+var (
+	flowEmbedderMu sync.Mutex
+	flowEmbedders  = map[config.EmbeddingConfig]EmbeddingFunc{}
+
+	flowRetrieversMu sync.Mutex
+	flowRetrievers   = map[string]*Retriever{}
+)
+
+// flowEmbedder returns the embedding function of the memory.embedding
+// provider, resolved once per process so that flow memory and vector
+// nodes share one loaded model.
+func flowEmbedder(appCfg *config.AppConfig, memCfg *config.MemoryConfig, debugMode bool) (EmbeddingFunc, error) {
+	flowEmbedderMu.Lock()
+	defer flowEmbedderMu.Unlock()
+	if embed, ok := flowEmbedders[memCfg.Embedding]; ok {
+		return embed, nil
+	}
+	res, err := ResolveEmbeddingFunc(appCfg, memCfg, debugMode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up embeddings: %w", err)
+	}
+	flowEmbedders[memCfg.Embedding] = res.EmbeddingFunc
+	return res.EmbeddingFunc, nil
+}
+
+// FlowRetriever returns the retriever of flow vector nodes: the SQLite
+// store at memory.flows.vector_db with the memory.embedding provider and
+// memory.chunking settings. Retrievers are opened once and shared by all
+// runs of the process.
+func FlowRetriever(appCfg *config.AppConfig, debugMode bool) (*Retriever, error) {
+	var memCfg config.MemoryConfig
+	if appCfg != nil {
+		memCfg = appCfg.Memory
+	}
+	path, err := memCfg.Flows.GetVectorDB()
+	if err != nil {
+		return nil, err
+	}
+
+	flowRetrieversMu.Lock()
+	defer flowRetrieversMu.Unlock()
+	if r, ok := flowRetrievers[path]; ok {
+		return r, nil
+	}
+	embed, err := flowEmbedder(appCfg, &memCfg, debugMode)
+	if err != nil {
+		return nil, err
+	}
+	store, err := NewSQLiteVectorStore(path)
+	if err != nil {
+		return nil, err
+	}
+	maxChars, overlap := memCfg.Chunking.MaxChars, memCfg.Chunking.Overlap
+	if maxChars <= 0 {
+		maxChars, overlap = 1600, 320
+	}
+	r := &Retriever{Store: store, Embed: embed, MaxChars: maxChars, Overlap: overlap}
+	flowRetrievers[path] = r
+	return r, nil
+}
@@ -0,0 +1,163 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+
+	_ "modernc.org/sqlite" // Register the pure-Go sqlite driver.
+)
+
+// Document is a text chunk in a vector store collection.
+type Document struct {
+	ID        string         `json:"id"`
+	Content   string         `json:"content"`
+	Metadata  map[string]any `json:"metadata,omitempty"`
+	Embedding []float32      `json:"-"`
+	// Score is the similarity of a query result to the query (not stored).
+	Score float64 `json:"score,omitempty"`
+}
+
+// VectorStore keeps embedded documents in named collections and finds the
+// ones nearest to a query vector. Implementations other than the built-in
+// SQLite store can be given to the flow agent directly.
+type VectorStore interface {
+	// Upsert stores docs in collection, replacing documents with the same ID.
+	Upsert(ctx context.Context, collection string, docs []Document) error
+
+	// Query returns up to limit documents of collection whose cosine
+	// similarity to vector is at least minScore, most similar first.
+	Query(ctx context.Context, collection string, vector []float32, limit int, minScore float64) ([]Document, error)
+
+	// DeleteCollection removes all documents of collection.
+	DeleteCollection(ctx context.Context, collection string) error
+
+	// Close releases resources held by the store.
+	Close() error
+}
+
+// sqliteVectorStore is a flat (exhaustive) index in a SQLite database:
+// embeddings are stored as float32 blobs and a query scores every document
+// of the collection. This is exact and fast enough for the tens of
+// thousands of chunks a flow typically indexes.
+type sqliteVectorStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteVectorStore opens (creating if needed) a vector store in the
+// SQLite database at path.
+func NewSQLiteVectorStore(path string) (VectorStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create vector store directory: %w", err)
+	}
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open vector store: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS documents (
+		collection TEXT NOT NULL,
+		id TEXT NOT NULL,
+		content TEXT NOT NULL,
+		metadata TEXT,
+		embedding BLOB NOT NULL,
+		PRIMARY KEY (collection, id)
+	)`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create vector store schema: %w", err)
+	}
+	return &sqliteVectorStore{db: db}, nil
+}
+
+func (s *sqliteVectorStore) Upsert(ctx context.Context, collection string, docs []Document) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO documents (collection, id, content, metadata, embedding) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (collection, id) DO UPDATE SET content = excluded.content, metadata = excluded.metadata, embedding = excluded.embedding`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, d := range docs {
+		var metadata []byte
+		if len(d.Metadata) > 0 {
+			if metadata, err = json.Marshal(d.Metadata); err != nil {
+				return fmt.Errorf("failed to encode metadata of document %s: %w", d.ID, err)
+			}
+		}
+		if _, err := stmt.ExecContext(ctx, collection, d.ID, d.Content, metadata, encodeVector(d.Embedding)); err != nil {
+			return fmt.Errorf("failed to store document %s: %w", d.ID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *sqliteVectorStore) Query(ctx context.Context, collection string, vector []float32, limit int, minScore float64) ([]Document, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, content, metadata, embedding FROM documents WHERE collection = ?`, collection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query vector store: %w", err)
+	}
+	defer rows.Close()
+
+	var results []Document
+	for rows.Next() {
+		var d Document
+		var metadata, blob []byte
+		if err := rows.Scan(&d.ID, &d.Content, &metadata, &blob); err != nil {
+			return nil, err
+		}
+		embedding := decodeVector(blob)
+		if len(embedding) != len(vector) {
+			continue // embedded by a different model
+		}
+		if d.Score = cosineSimilarity(vector, embedding); d.Score < minScore {
+			continue
+		}
+		if len(metadata) > 0 {
+			_ = json.Unmarshal(metadata, &d.Metadata)
+		}
+		results = append(results, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+func (s *sqliteVectorStore) DeleteCollection(ctx context.Context, collection string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM documents WHERE collection = ?`, collection)
+	return err
+}
+
+func (s *sqliteVectorStore) Close() error {
+	return s.db.Close()
+}
+
+func encodeVector(v []float32) []byte {
+	buf := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(f))
+	}
+	return buf
+}
+
+func decodeVector(buf []byte) []float32 {
+	v := make([]float32, len(buf)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return v
+}
@@ -0,0 +1,100 @@
+package memory
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// keywordEmbed embeds a text as its counts of a few fixed words, so that
+// similarities in tests are predictable.
+func keywordEmbed(ctx context.Context, text string) ([]float32, error) {
+	text = strings.ToLower(text)
+	v := make([]float32, 0, 4)
+	for _, w := range []string{"kubernetes", "postgres", "billing", "the"} {
+		v = append(v, float32(strings.Count(text, w))+0.01)
+	}
+	return v, nil
+}
+
+func TestSQLiteVectorStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewSQLiteVectorStore(filepath.Join(t.TempDir(), "sub", "vectors.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	docs := []Document{
+		{ID: "a", Content: "alpha", Embedding: []float32{1, 0}, Metadata: map[string]any{"source": "a.md"}},
+		{ID: "b", Content: "beta", Embedding: []float32{0.7, 0.7}},
+		{ID: "c", Content: "gamma", Embedding: []float32{0, 1}},
+		{ID: "d", Content: "other model", Embedding: []float32{1, 0, 0}},
+	}
+	if err := store.Upsert(ctx, "docs", docs); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Upsert(ctx, "docs", []Document{{ID: "a", Content: "alpha v2", Embedding: []float32{1, 0.1}, Metadata: map[string]any{"source": "a.md"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Upsert(ctx, "other", []Document{{ID: "x", Content: "elsewhere", Embedding: []float32{1, 0}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.Query(ctx, "docs", []float32{1, 0}, 2, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Content != "alpha v2" || got[1].Content != "beta" {
+		t.Fatalf("query = %+v", got)
+	}
+	if got[0].Metadata["source"] != "a.md" || got[0].Score < got[1].Score {
+		t.Errorf("first result = %+v", got[0])
+	}
+	if got, _ := store.Query(ctx, "docs", []float32{1, 0}, 0, 0.99); len(got) != 1 {
+		t.Errorf("min score: got %d results, want 1", len(got))
+	}
+
+	if err := store.DeleteCollection(ctx, "docs"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := store.Query(ctx, "docs", []float32{1, 0}, 0, 0); len(got) != 0 {
+		t.Errorf("deleted collection returned %+v", got)
+	}
+	if got, _ := store.Query(ctx, "other", []float32{1, 0}, 0, 0); len(got) != 1 {
+		t.Errorf("other collection returned %+v", got)
+	}
+}
+
+func TestRetrieverIndexAndSearch(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewSQLiteVectorStore(filepath.Join(t.TempDir(), "vectors.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	r := &Retriever{Store: store, Embed: keywordEmbed, MaxChars: 200}
+
+	text := "## Deploy\nThe service runs on Kubernetes; kubernetes manifests live in deploy/ and are applied by the release pipeline.\n\n" +
+		"## Storage\nOrders are kept in Postgres. Postgres backups run nightly and are kept for a week."
+	n, err := r.Index(ctx, "runbook", "ops.md", text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("indexed %d chunks, want 2", n)
+	}
+	// Indexing the same text again replaces its chunks
+	if _, err := r.Index(ctx, "runbook", "ops.md", text); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := r.Search(ctx, "runbook", "where is postgres", 5, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || !strings.Contains(got[0].Content, "Orders are kept in Postgres") || got[0].Metadata["source"] != "ops.md" {
+		t.Errorf("search = %+v", got)
+	}
+}
@@ -0,0 +1,154 @@
+// html_report.go renders markdown as a standalone HTML report: the styled
+// document of the Chrome PDF path plus a header, a numbered list of the
+// sources the report links to, and embedded artifacts, so the file can be
+// shared and opened without the terminal or Studio.
+package pdfgen
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html"
+	"mime"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Report is the content of an HTML report.
+type Report struct {
+	Title       string
+	Subtitle    string // e.g. the flow that produced the report
+	Markdown    string
+	GeneratedAt time.Time
+	Artifacts   []ReportArtifact
+}
+
+// ReportArtifact is a file embedded in a report. Images are inlined as data
+// URIs, text files as collapsible blocks, and other files as download links.
+type ReportArtifact struct {
+	Name string
+	Data []byte
+}
+
+// reportLinkPattern matches markdown links and bare URLs.
+var reportLinkPattern = regexp.MustCompile(`\[([^\]]*)\]\((https?://[^)\s]+)\)|(https?://[^\s<>()\]]+)`)
+
+// RenderHTMLReport converts a report to a standalone HTML document.
+func RenderHTMLReport(r Report) ([]byte, error) {
+	body, err := markdownToHTML([]byte(r.Markdown))
+	if err != nil {
+		return nil, fmt.Errorf("markdown to HTML conversion failed: %w", err)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<header class=\"report-header\">\n")
+	if r.Title != "" {
+		fmt.Fprintf(&sb, "<h1>%s</h1>\n", html.EscapeString(r.Title))
+	}
+	var meta []string
+	if r.Subtitle != "" {
+		meta = append(meta, html.EscapeString(r.Subtitle))
+	}
+	if !r.GeneratedAt.IsZero() {
+		meta = append(meta, "Generated "+r.GeneratedAt.Format("2006-01-02 15:04 MST"))
+	}
+	if len(meta) > 0 {
+		fmt.Fprintf(&sb, "<p class=\"report-meta\">%s</p>\n", strings.Join(meta, " &middot; "))
+	}
+	sb.WriteString("</header>\n<main>\n")
+	sb.WriteString(body)
+	sb.WriteString("</main>\n")
+
+	if sources := reportSources(r.Markdown); len(sources) > 0 {
+		sb.WriteString("<section class=\"report-sources\">\n<h2>Sources</h2>\n<ol>\n")
+		for _, src := range sources {
+			u := html.EscapeString(src.url)
+			if src.label != "" && src.label != src.url {
+				fmt.Fprintf(&sb, "<li>%s &mdash; <a href=\"%s\">%s</a></li>\n", html.EscapeString(src.label), u, u)
+			} else {
+				fmt.Fprintf(&sb, "<li><a href=\"%s\">%s</a></li>\n", u, u)
+			}
+		}
+		sb.WriteString("</ol>\n</section>\n")
+	}
+
+	if len(r.Artifacts) > 0 {
+		sb.WriteString("<section class=\"report-artifacts\">\n<h2>Artifacts</h2>\n")
+		for _, a := range r.Artifacts {
+			writeReportArtifact(&sb, a)
+		}
+		sb.WriteString("</section>\n")
+	}
+
+	title := r.Title
+	if title == "" {
+		title = "Report"
+	}
+	return []byte(fmt.Sprintf("<!DOCTYPE html>\n"+
+		"<html lang=\"en\">\n<head>\n<meta charset=\"UTF-8\">\n"+
+		"<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n"+
+		"<title>%s</title>\n"+
+		"<style>\n%s\n%s\n</style>\n"+
+		"<script src=\"https://cdn.jsdelivr.net/npm/mermaid@11/dist/mermaid.min.js\"></script>\n"+
+		"<script>\n%s\n</script>\n"+
+		"</head>\n<body>\n%s</body>\n</html>\n",
+		html.EscapeString(title), pdfCSS, reportCSS, mermaidInitScript, sb.String())), nil
+}
+
+type reportSource struct {
+	label string
+	url   string
+}
+
+// reportSources returns the distinct URLs a report links to, in order of
+// first appearance.
+func reportSources(markdown string) []reportSource {
+	var sources []reportSource
+	seen := make(map[string]bool)
+	for _, m := range reportLinkPattern.FindAllStringSubmatch(markdown, -1) {
+		src := reportSource{label: m[1], url: m[2]}
+		if src.url == "" {
+			src.url = strings.TrimRight(m[3], ".,;:!?")
+		}
+		if seen[src.url] {
+			continue
+		}
+		seen[src.url] = true
+		sources = append(sources, src)
+	}
+	return sources
+}
+
+func writeReportArtifact(sb *strings.Builder, a ReportArtifact) {
+	name := html.EscapeString(a.Name)
+	mimeType := mime.TypeByExtension(strings.ToLower(filepath.Ext(a.Name)))
+	if i := strings.IndexByte(mimeType, ';'); i >= 0 {
+		mimeType = mimeType[:i]
+	}
+	dataURI := "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(a.Data)
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		fmt.Fprintf(sb, "<figure>\n<img src=\"%s\" alt=\"%s\">\n<figcaption>%s</figcaption>\n</figure>\n", dataURI, name, name)
+	case strings.HasPrefix(mimeType, "text/") || mimeType == "application/json" || mimeType == "":
+		fmt.Fprintf(sb, "<details>\n<summary>%s</summary>\n<pre><code>%s</code></pre>\n</details>\n", name, html.EscapeString(string(a.Data)))
+	default:
+		fmt.Fprintf(sb, "<p><a download=\"%s\" href=\"%s\">%s</a></p>\n", name, dataURI, name)
+	}
+}
+
+// reportCSS adapts the print stylesheet to reading in a browser.
+const reportCSS = `
+body { max-width: 860px; margin: 0 auto; padding: 2.5em 1.5em 4em; background: #fff; }
+.report-header { margin-bottom: 1.5em; }
+.report-header h1 { margin-top: 0; }
+.report-meta { color: #666; font-size: 10pt; margin-top: 0.4em; }
+.report-sources, .report-artifacts { margin-top: 2.5em; border-top: 1px solid #e0e0e0; padding-top: 0.5em; }
+.report-sources ol { padding-left: 1.6em; }
+.report-sources li { margin: 0.2em 0; word-break: break-all; }
+.report-artifacts figure { margin: 1em 0; }
+.report-artifacts img { max-width: 100%; border: 1px solid #e0e0e0; }
+.report-artifacts figcaption { color: #666; font-size: 9pt; }
+.report-artifacts details { margin: 0.8em 0; }
+.report-artifacts summary { cursor: pointer; font-weight: 600; }
+`
@@ -0,0 +1,44 @@
+package pdfgen
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderHTMLReport(t *testing.T) {
+	out, err := RenderHTMLReport(Report{
+		Title:       "Q3 <Review>",
+		Subtitle:    "Astonish flow · publish",
+		Markdown:    "# Findings\n\nSee [the docs](https://example.com/docs) and https://example.com/blog.\nAgain [docs](https://example.com/docs).\n",
+		GeneratedAt: time.Date(2026, 1, 2, 3, 4, 0, 0, time.UTC),
+		Artifacts: []ReportArtifact{
+			{Name: "chart.png", Data: []byte{0x89, 'P', 'N', 'G'}},
+			{Name: "data.csv", Data: []byte("a,b\n<1>,2\n")},
+		},
+	})
+	if err != nil {
+		t.Fatalf("RenderHTMLReport: %v", err)
+	}
+	html := string(out)
+
+	for _, want := range []string{
+		"<title>Q3 &lt;Review&gt;</title>",
+		"<h1>Q3 &lt;Review&gt;</h1>",
+		"Generated 2026-01-02 03:04 UTC",
+		"<h1>Findings</h1>",
+		"<li>the docs &mdash; <a href=\"https://example.com/docs\">",
+		"<li><a href=\"https://example.com/blog\">https://example.com/blog</a></li>",
+		"<img src=\"data:image/png;base64,",
+		"<summary>data.csv</summary>",
+		"&lt;1&gt;,2",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("report is missing %q", want)
+		}
+	}
+	sources := html[strings.Index(html, "<h2>Sources</h2>"):]
+	if n := strings.Count(sources[:strings.Index(sources, "</ol>")], "<li>"); n != 2 {
+		t.Errorf("sources list has %d entries, want the two distinct URLs", n)
+	}
+}
@@ -23,12 +23,26 @@ import (
 // ApprovalCallback is called when a tool needs approval
 type ApprovalCallback func(toolName string, args map[string]any) (bool, error)
 
+// ToolResultCallback is called with the result of each successful tool call
+// and returns the result the model observes.
+type ToolResultCallback func(toolName string, args map[string]any, result map[string]any) (map[string]any, error)
+
 type ReActPlanner struct {
 	LLM              model.LLM
 	Tools            []tool.Tool
 	ApprovalCallback ApprovalCallback
 	State            session.State
+	ToolResult       ToolResultCallback
 	DebugMode        bool
+
+	// Thought is the reasoning the model gave for the action being
+	// executed, for approval callbacks to show.
+	Thought string
+
+	// Generation holds the node's sampling parameters. Temperature, top_p and
+	// max_output_tokens override the planner's deterministic defaults; stop
+	// sequences are added to the planner's own.
+	Generation *genai.GenerateContentConfig
 }
 
 // NewReActPlanner creates a new ReActPlanner.
@@ -110,10 +124,12 @@ Thought:`, systemContext, toolDescriptions, toolNames, input)
 
 	maxSteps := 10
 
-	// Helper for float32 pointer
-	temp := float32(0.0)
-
 	for i := startStep; i < maxSteps; i++ {
+		// Stop between steps when the run is cancelled
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+
 		// If we are past the first step (and not resuming from a state where we already switched),
 		// switch to the full system prompt.
 		// We do this at the start of i=1 (after first tool execution).
@@ -201,10 +217,7 @@ Thought:`, systemContext, toolDescriptions, toolNames, input)
 						},
 					},
 				},
-				Config: &genai.GenerateContentConfig{
-					Temperature:   &temp,                    // Deterministic for planning
-					StopSequences: []string{"Observation:"}, // Stop at observation to let us execute tool
-				},
+				Config: p.stepConfig(),
 			}
 
 			var responseText string
@@ -290,6 +303,7 @@ Thought:`, systemContext, toolDescriptions, toolNames, input)
 				continue
 			}
 			action = strings.Trim(actionMatch[1], "`\"'")
+			p.Thought = parseThought(cleanedResponse)
 
 			// Parse Action Input - it might be multiline or contain code blocks
 			// Look for "Action Input:" and capture everything until "STOP HERE", "Observation:", or end
@@ -365,6 +379,26 @@ Thought:`, systemContext, toolDescriptions, toolNames, input)
 	return "", fmt.Errorf("max ReAct steps (%d) reached without final answer", maxSteps)
 }
 
+// stepConfig returns the generation config for one ReAct step: deterministic,
+// stopping at "Observation:" so the planner can execute the tool, with the
+// node's generation settings applied on top.
+func (p *ReActPlanner) stepConfig() *genai.GenerateContentConfig {
+	temp := float32(0.0)
+	cfg := &genai.GenerateContentConfig{
+		Temperature:   &temp,                    // Deterministic for planning
+		StopSequences: []string{"Observation:"}, // Stop at observation to let us execute tool
+	}
+	if g := p.Generation; g != nil {
+		if g.Temperature != nil {
+			cfg.Temperature = g.Temperature
+		}
+		cfg.TopP = g.TopP
+		cfg.MaxOutputTokens = g.MaxOutputTokens
+		cfg.StopSequences = append(cfg.StopSequences, g.StopSequences...)
+	}
+	return cfg
+}
+
 // FormatOutput takes the ReAct result and formats it according to the output schema.
 // This is called after the ReAct loop completes to ensure the output matches the expected structure.
 func (p *ReActPlanner) FormatOutput(ctx context.Context, reactResult string, outputSchema map[string]string, systemInstruction string) (string, error) {
@@ -623,6 +657,11 @@ func (p *ReActPlanner) executeTool(ctx context.Context, name string, inputJSON s
 	if err != nil {
 		return fmt.Sprintf("Error executing tool: %v", err), nil
 	}
+	if p.ToolResult != nil {
+		if result, err = p.ToolResult(name, args, result); err != nil {
+			return fmt.Sprintf("Error processing tool result: %v", err), nil
+		}
+	}
 
 	// Marshal result to string
 	resultBytes, err := json.Marshal(result)
@@ -698,6 +737,38 @@ func (m *minimalToolContext) ToolConfirmation() *toolconfirmation.ToolConfirmati
 	return nil // No HITL during ReAct planning
 }
 
+// DenyPendingAction drops the action a paused ReAct run waits to execute and
+// adds observation to its saved history, so the run resumes with the model
+// reacting to the denial. It reports whether an action was pending.
+func DenyPendingAction(state session.State, observation string) bool {
+	pending, _ := state.Get("_react_pending_action")
+	if pending == nil {
+		return false
+	}
+	history, _ := state.Get("_react_history")
+	h, _ := history.(string)
+	for key, value := range map[string]any{
+		"_react_pending_action": nil,
+		"_react_pending_input":  nil,
+		"_react_history":        h + fmt.Sprintf("\n\nObservation: %s\n\nThought: ", observation),
+	} {
+		if err := state.Set(key, value); err != nil {
+			slog.Warn("failed to record denied action", "component", "react", "key", key, "error", err)
+		}
+	}
+	return true
+}
+
+// parseThought returns the reasoning of a ReAct step: the text before its
+// Action, without the "Thought:" label.
+func parseThought(response string) string {
+	thought, _, _ := strings.Cut(response, "Action:")
+	if idx := strings.LastIndex(thought, "Thought:"); idx != -1 {
+		thought = thought[idx+len("Thought:"):]
+	}
+	return strings.TrimSpace(thought)
+}
+
 func removeThinkTags(input string) string {
 	re := regexp.MustCompile(`(?s)<think>.*?</think>`)
 	return re.ReplaceAllString(input, "")
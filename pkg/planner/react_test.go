@@ -155,6 +155,47 @@ func TestRemoveThinkTags(t *testing.T) {
 	}
 }
 
+func TestParseThought(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		expect string
+	}{
+		{"continuation", " I need the file list.\nAction: ls\nAction Input: {}", "I need the file list."},
+		{"labelled", "Thought: Check the disk.\nAction: shell_command", "Check the disk."},
+		{"repeated question", "Question: x\nThought: a\nThought: b\nAction: t", "b"},
+		{"no thought", "Action: t", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseThought(tt.input); got != tt.expect {
+				t.Errorf("parseThought(%q) = %q, want %q", tt.input, got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestDenyPendingAction(t *testing.T) {
+	state := newMockState()
+	if DenyPendingAction(state, "denied") {
+		t.Error("reported a denial without a pending action")
+	}
+	state.Set("_react_history", "Thought: clean up\nAction: shell_command")
+	state.Set("_react_pending_action", "shell_command")
+	state.Set("_react_pending_input", `{"command": "rm -rf /tmp"}`)
+
+	if !DenyPendingAction(state, "The user denied the call") {
+		t.Fatal("pending action not denied")
+	}
+	if pending, _ := state.Get("_react_pending_action"); pending != nil {
+		t.Errorf("pending action = %v, want it dropped", pending)
+	}
+	history, _ := state.Get("_react_history")
+	if h, _ := history.(string); !strings.HasSuffix(h, "Action: shell_command\n\nObservation: The user denied the call\n\nThought: ") {
+		t.Errorf("history = %q, want the denial as the observation", h)
+	}
+}
+
 func TestGetToolNames(t *testing.T) {
 	p := &ReActPlanner{
 		Tools: []tool.Tool{
@@ -268,6 +309,33 @@ func TestRun_FinalAnswerFirstStep(t *testing.T) {
 	}
 }
 
+func TestRun_AppliesNodeGeneration(t *testing.T) {
+	llm := &mockLLM{
+		responses: []*genai.Content{
+			textContent("Final Answer: done"),
+		},
+	}
+	temp, topP := float32(0.7), float32(0.9)
+	p := NewReActPlanner(llm, nil)
+	p.Generation = &genai.GenerateContentConfig{
+		Temperature:     &temp,
+		TopP:            &topP,
+		MaxOutputTokens: 256,
+		StopSequences:   []string{"END"},
+	}
+	if _, err := p.Run(context.Background(), "q", ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cfg := llm.requests[0].Config
+	if *cfg.Temperature != 0.7 || *cfg.TopP != 0.9 || cfg.MaxOutputTokens != 256 {
+		t.Errorf("generation not applied: temperature=%v top_p=%v max_output_tokens=%d", *cfg.Temperature, *cfg.TopP, cfg.MaxOutputTokens)
+	}
+	if got := strings.Join(cfg.StopSequences, ","); got != "Observation:,END" {
+		t.Errorf("StopSequences = %q, want planner stop plus node stops", got)
+	}
+}
+
 func TestRun_MultiStepToolUse(t *testing.T) {
 	step := 0
 	llm := &mockLLMFunc{
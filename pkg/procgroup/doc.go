@@ -0,0 +1,4 @@
+// Package procgroup starts child processes in their own process group so
+// that they can be stopped together with everything they spawned, e.g. the
+// node process behind an npx MCP server or the children of a shell command.
+package procgroup
@@ -0,0 +1,39 @@
+//go:build !windows
+
+package procgroup
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// Set makes cmd, once started, the leader of a new process group. Commands
+// started in a new session (as with a PTY) already lead their group and
+// are left as they are.
+func Set(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	if !cmd.SysProcAttr.Setsid {
+		cmd.SysProcAttr.Setpgid = true
+	}
+}
+
+// Terminate asks the process group of a started command to exit (SIGTERM).
+func Terminate(cmd *exec.Cmd) error {
+	return signal(cmd, syscall.SIGTERM)
+}
+
+// Kill kills the process group of a started command (SIGKILL).
+func Kill(cmd *exec.Cmd) error {
+	return signal(cmd, syscall.SIGKILL)
+}
+
+func signal(cmd *exec.Cmd, sig syscall.Signal) error {
+	if attr := cmd.SysProcAttr; attr != nil && (attr.Setpgid || attr.Setsid) {
+		// The group outlives its leader, so this reaches children that are
+		// still running after the command itself exited
+		return syscall.Kill(-cmd.Process.Pid, sig)
+	}
+	return cmd.Process.Signal(sig)
+}
@@ -0,0 +1,35 @@
+//go:build windows
+
+package procgroup
+
+import (
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// Set starts cmd in a new process group, so console control events of
+// astonish do not reach it and taskkill /T can stop its tree.
+func Set(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= syscall.CREATE_NEW_PROCESS_GROUP
+}
+
+// Terminate stops the process tree of a started command. Console programs
+// cannot be asked to exit from another group, so this is Kill.
+func Terminate(cmd *exec.Cmd) error {
+	return Kill(cmd)
+}
+
+// Kill kills the process tree of a started command with taskkill, falling
+// back to the process alone when taskkill fails.
+func Kill(cmd *exec.Cmd) error {
+	kill := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid))
+	kill.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	if err := kill.Run(); err != nil {
+		return cmd.Process.Kill()
+	}
+	return nil
+}
@@ -44,10 +44,16 @@ type Provider struct {
 
 // NewProvider creates a new Anthropic provider.
 func NewProvider(apiKey, modelName string) *Provider {
+	return NewProviderWithClient(apiKey, modelName, httpool.StreamingClient())
+}
+
+// NewProviderWithClient creates an Anthropic provider that sends requests
+// through client (e.g. one configured with a proxy or private CA).
+func NewProviderWithClient(apiKey, modelName string, client *http.Client) *Provider {
 	return &Provider{
 		apiKey: apiKey,
 		model:  modelName,
-		client: httpool.StreamingClient(),
+		client: client,
 	}
 }
 
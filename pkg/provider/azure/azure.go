@@ -0,0 +1,102 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+	openai_provider "github.com/SAP/astonish/pkg/provider/openai"
+)
+
+// DefaultAPIVersion is the Azure OpenAI API version used when a profile
+// does not set one.
+const DefaultAPIVersion = "2024-10-21"
+
+// Auth modes of an Azure OpenAI resource.
+const (
+	AuthAPIKey  = "api_key"  // api-key header
+	AuthAzureAD = "azure_ad" // Microsoft Entra ID bearer token
+)
+
+// Config is an Azure OpenAI resource.
+type Config struct {
+	Endpoint   string // https://<resource>.openai.azure.com
+	AuthMode   string // AuthAPIKey or AuthAzureAD; default AuthAPIKey
+	Credential string // API key, or the Entra ID token with AuthAzureAD
+	APIVersion string // default DefaultAPIVersion
+	HTTPClient *http.Client
+}
+
+func (c Config) clientConfig() (openai.ClientConfig, error) {
+	if c.Endpoint == "" {
+		return openai.ClientConfig{}, fmt.Errorf("azure: endpoint is required")
+	}
+	if c.Credential == "" {
+		return openai.ClientConfig{}, fmt.Errorf("azure: credentials not set")
+	}
+	cfg := openai.DefaultAzureConfig(c.Credential, strings.TrimRight(c.Endpoint, "/"))
+	switch c.AuthMode {
+	case "", AuthAPIKey:
+	case AuthAzureAD:
+		cfg.APIType = openai.APITypeAzureAD
+	default:
+		return openai.ClientConfig{}, fmt.Errorf("azure: unknown auth_mode %q (want %s or %s)", c.AuthMode, AuthAPIKey, AuthAzureAD)
+	}
+	if c.APIVersion != "" {
+		cfg.APIVersion = c.APIVersion
+	} else {
+		cfg.APIVersion = DefaultAPIVersion
+	}
+	// Requests name the deployment itself; the default mapper strips dots
+	cfg.AzureModelMapperFunc = func(deployment string) string { return deployment }
+	if c.HTTPClient != nil {
+		cfg.HTTPClient = c.HTTPClient
+	}
+	return cfg, nil
+}
+
+// NewProvider creates a model client for a deployment of the resource.
+func NewProvider(c Config, deployment string) (*openai_provider.Provider, error) {
+	if deployment == "" {
+		return nil, fmt.Errorf("azure: deployment is required")
+	}
+	cfg, err := c.clientConfig()
+	if err != nil {
+		return nil, err
+	}
+	return openai_provider.NewProvider(openai.NewClientWithConfig(cfg), deployment, true), nil
+}
+
+// ListModels checks the credentials against the resource and returns the
+// models it offers. Deployments are not listed by the data-plane API, so
+// callers with configured deployments should prefer those.
+func ListModels(ctx context.Context, c Config) ([]string, error) {
+	cfg, err := c.clientConfig()
+	if err != nil {
+		return nil, err
+	}
+	list, err := openai.NewClientWithConfig(cfg).ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	models := make([]string, 0, len(list.Models))
+	for _, m := range list.Models {
+		models = append(models, m.ID)
+	}
+	return models, nil
+}
+
+// NewEmbedder creates an embedder for an embedding deployment of the
+// resource.
+func NewEmbedder(c Config, deployment string) (*openai_provider.Embedder, error) {
+	if deployment == "" {
+		return nil, fmt.Errorf("azure: deployment is required")
+	}
+	cfg, err := c.clientConfig()
+	if err != nil {
+		return nil, err
+	}
+	return openai_provider.NewEmbedder(openai.NewClientWithConfig(cfg), deployment), nil
+}
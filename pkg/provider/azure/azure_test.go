@@ -0,0 +1,41 @@
+package azure
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestListModelsAuth(t *testing.T) {
+	var gotURL, gotKey, gotBearer string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotURL, gotKey, gotBearer = r.URL.String(), r.Header.Get("api-key"), r.Header.Get("Authorization")
+		w.Write([]byte(`{"data":[{"id":"gpt-4o"}]}`))
+	}))
+	defer srv.Close()
+
+	models, err := ListModels(context.Background(), Config{Endpoint: srv.URL + "/", Credential: "key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(models) != 1 || models[0] != "gpt-4o" {
+		t.Errorf("models = %v", models)
+	}
+	if gotURL != "/openai/models?api-version="+DefaultAPIVersion || gotKey != "key" {
+		t.Errorf("request %s with api-key %q", gotURL, gotKey)
+	}
+
+	if _, err := ListModels(context.Background(), Config{Endpoint: srv.URL, AuthMode: AuthAzureAD, Credential: "token", APIVersion: "2025-01-01-preview"}); err != nil {
+		t.Fatal(err)
+	}
+	if gotBearer != "Bearer token" || gotURL != "/openai/models?api-version=2025-01-01-preview" {
+		t.Errorf("azure_ad request %s with Authorization %q", gotURL, gotBearer)
+	}
+
+	for _, c := range []Config{{Credential: "key"}, {Endpoint: srv.URL}, {Endpoint: srv.URL, Credential: "key", AuthMode: "saml"}} {
+		if _, err := NewProvider(c, "gpt-4o"); err == nil {
+			t.Errorf("NewProvider(%+v) succeeded", c)
+		}
+	}
+}
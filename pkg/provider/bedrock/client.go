@@ -0,0 +1,148 @@
+package bedrock
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/SAP/astonish/pkg/provider/httpool"
+	"github.com/SAP/astonish/pkg/provider/llmerror"
+	"google.golang.org/adk/model"
+)
+
+// Config is an AWS Bedrock account and region.
+type Config struct {
+	Region      string
+	Endpoint    string // Runtime endpoint, e.g. a VPC endpoint; default https://bedrock-runtime.<region>.amazonaws.com
+	Credentials Credentials
+	HTTPClient  *http.Client // nil for the shared client
+}
+
+func (c Config) runtimeEndpoint() string {
+	if c.Endpoint != "" {
+		return strings.TrimRight(c.Endpoint, "/")
+	}
+	return "https://bedrock-runtime." + c.Region + ".amazonaws.com"
+}
+
+func (c Config) client() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return httpool.StreamingClient()
+}
+
+// Provider implements model.LLM for Anthropic models on AWS Bedrock. It
+// calls InvokeModel, so responses arrive in one piece.
+type Provider struct {
+	cfg   Config
+	model string
+}
+
+// NewProvider creates a Bedrock provider for a model ID or inference
+// profile, e.g. anthropic.claude-3-5-sonnet-20240620-v1:0.
+func NewProvider(cfg Config, modelID string) (*Provider, error) {
+	if cfg.Region == "" {
+		return nil, fmt.Errorf("bedrock: region is required")
+	}
+	return &Provider{cfg: cfg, model: modelID}, nil
+}
+
+// Name implements model.LLM.
+func (p *Provider) Name() string {
+	return p.model
+}
+
+// GenerateContent implements model.LLM. With streaming the complete
+// response is yielded once.
+func (p *Provider) GenerateContent(ctx context.Context, req *model.LLMRequest, _ bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		bedrockReq, err := ConvertRequest(req, 0)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		payload, err := json.Marshal(bedrockReq)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		url := p.cfg.runtimeEndpoint() + "/model/" + uriEncode(p.model) + "/invoke"
+		body, err := p.cfg.do(ctx, http.MethodPost, url, "bedrock", payload)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		llmResp, err := ParseResponse(body)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		yield(llmResp, nil)
+	}
+}
+
+// ListModels lists the Anthropic models available in the region.
+func ListModels(ctx context.Context, cfg Config) ([]string, error) {
+	if cfg.Region == "" {
+		return nil, fmt.Errorf("bedrock: region is required")
+	}
+	url := "https://bedrock." + cfg.Region + ".amazonaws.com/foundation-models?byOutputModality=TEXT&byProvider=anthropic"
+	body, err := cfg.do(ctx, http.MethodGet, url, "bedrock", nil)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		ModelSummaries []struct {
+			ModelID string `json:"modelId"`
+		} `json:"modelSummaries"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("bedrock: decoding model list: %w", err)
+	}
+	models := make([]string, 0, len(result.ModelSummaries))
+	for _, m := range result.ModelSummaries {
+		models = append(models, m.ModelID)
+	}
+	return models, nil
+}
+
+// do sends an authenticated request and returns the body of a 200 response.
+func (c Config) do(ctx context.Context, method, url, service string, payload []byte) ([]byte, error) {
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, err
+	}
+	if payload != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	if err := c.Credentials.authorize(httpReq, payload, c.Region, service, time.Now()); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client().Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, readErr := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		if readErr != nil {
+			body = []byte(fmt.Sprintf("<unreadable: %v>", readErr))
+		}
+		return nil, llmerror.NewFromResponse("bedrock", resp, body)
+	}
+	return body, readErr
+}
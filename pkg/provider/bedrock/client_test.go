@@ -0,0 +1,77 @@
+package bedrock
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+func TestSignV4(t *testing.T) {
+	// get-vanilla from the AWS Signature Version 4 test suite
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	creds := Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	signV4(req, nil, creds, "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization =\n%s\nwant\n%s", got, want)
+	}
+
+	if got := canonicalURI("/model/anthropic.claude-v2%3A1/invoke"); got != "/model/anthropic.claude-v2%253A1/invoke" {
+		t.Errorf("canonicalURI = %q, want the escaped path encoded again", got)
+	}
+}
+
+func TestProviderInvoke(t *testing.T) {
+	var gotPath, gotAuth, gotToken string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotAuth, gotToken = r.URL.EscapedPath(), r.Header.Get("Authorization"), r.Header.Get("X-Amz-Security-Token")
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), `"anthropic_version":"bedrock-2023-05-31"`) {
+			t.Errorf("request body = %s", body)
+		}
+		w.Write([]byte(`{"content":[{"type":"text","text":"hello"}],"stop_reason":"end_turn","usage":{"input_tokens":3,"output_tokens":1}}`))
+	}))
+	defer srv.Close()
+
+	p, err := NewProvider(Config{
+		Region:      "eu-central-1",
+		Endpoint:    srv.URL,
+		Credentials: Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret", SessionToken: "session"},
+	}, "anthropic.claude-3-5-sonnet-20240620-v1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("hi", genai.RoleUser)}}
+	var text string
+	for resp, err := range p.GenerateContent(context.Background(), req, true) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		text += resp.Content.Parts[0].Text
+	}
+
+	if text != "hello" {
+		t.Errorf("text = %q", text)
+	}
+	if gotPath != "/model/anthropic.claude-3-5-sonnet-20240620-v1%3A0/invoke" {
+		t.Errorf("path = %q", gotPath)
+	}
+	if !strings.HasPrefix(gotAuth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(gotAuth, "/eu-central-1/bedrock/aws4_request") || gotToken != "session" {
+		t.Errorf("Authorization = %q, security token = %q", gotAuth, gotToken)
+	}
+
+	if _, err := NewProvider(Config{}, "m"); err == nil {
+		t.Error("provider without a region was created")
+	}
+	if err := (Credentials{}).authorize(&http.Request{Header: http.Header{}}, nil, "us-east-1", "bedrock", time.Now()); err == nil {
+		t.Error("request authorized without credentials")
+	}
+}
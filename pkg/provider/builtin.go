@@ -0,0 +1,506 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/provider/anthropic"
+	"github.com/SAP/astonish/pkg/provider/azure"
+	"github.com/SAP/astonish/pkg/provider/bedrock"
+	"github.com/SAP/astonish/pkg/provider/google"
+	"github.com/SAP/astonish/pkg/provider/groq"
+	"github.com/SAP/astonish/pkg/provider/litellm"
+	"github.com/SAP/astonish/pkg/provider/lmstudio"
+	"github.com/SAP/astonish/pkg/provider/ollama"
+	openai_provider "github.com/SAP/astonish/pkg/provider/openai"
+	openai_compat "github.com/SAP/astonish/pkg/provider/openai_compat"
+	"github.com/SAP/astonish/pkg/provider/openrouter"
+	"github.com/SAP/astonish/pkg/provider/poe"
+	"github.com/SAP/astonish/pkg/provider/sap"
+	"github.com/SAP/astonish/pkg/provider/xai"
+	"google.golang.org/adk/model"
+)
+
+// allCapabilities is what the hosted chat APIs support.
+var allCapabilities = Capabilities{Tools: true, Streaming: true, StructuredOutput: true}
+
+// The built-in providers. A new provider registers itself the same way,
+// with no changes to GetProvider or the API.
+func init() {
+	Register(Registration{
+		Type:         "anthropic",
+		DisplayName:  "Anthropic",
+		Capabilities: Capabilities{Tools: true, Streaming: true},
+		Env:          map[string]string{"api_key": "ANTHROPIC_API_KEY"},
+		Required:     []string{"api_key"},
+		New: func(_ context.Context, p Params) (model.LLM, error) {
+			apiKey := p.Instance["api_key"]
+			if apiKey == "" {
+				return nil, fmt.Errorf("ANTHROPIC_API_KEY not set")
+			}
+			modelName := orDefault(p.Model, "claude-3-opus-20240229")
+			if p.HTTPClient != nil {
+				return anthropic.NewProviderWithClient(apiKey, modelName, p.HTTPClient), nil
+			}
+			return anthropic.NewProvider(apiKey, modelName), nil
+		},
+		ListModels: func(ctx context.Context, instance config.ProviderConfig) ([]string, error) {
+			if instance["api_key"] == "" {
+				return nil, fmt.Errorf("Anthropic API key not configured")
+			}
+			return anthropic.ListModels(ctx, instance["api_key"])
+		},
+	})
+
+	Register(Registration{
+		Type:         "gemini",
+		Aliases:      []string{"google_genai"},
+		DisplayName:  "Google GenAI",
+		Capabilities: allCapabilities,
+		Env:          map[string]string{"api_key": "GOOGLE_API_KEY"},
+		Required:     []string{"api_key"},
+		New: func(ctx context.Context, p Params) (model.LLM, error) {
+			apiKey := p.Instance["api_key"]
+			if apiKey == "" {
+				return nil, fmt.Errorf("GOOGLE_API_KEY not set")
+			}
+			return google.NewProviderWithClient(ctx, orDefault(p.Model, "gemini-1.5-flash"), apiKey, p.HTTPClient)
+		},
+		ListModels: func(ctx context.Context, instance config.ProviderConfig) ([]string, error) {
+			if instance["api_key"] == "" {
+				return nil, fmt.Errorf("Google API key not configured")
+			}
+			return google.ListModels(ctx, instance["api_key"])
+		},
+		NewEmbedder: func(ctx context.Context, p Params) (EmbeddingProvider, error) {
+			e, err := google.NewEmbedder(ctx, p.Instance["api_key"], orDefault(p.Model, "gemini-embedding-001"), p.HTTPClient)
+			if err != nil {
+				return nil, err
+			}
+			return e, nil
+		},
+	})
+
+	Register(Registration{
+		Type:         "openai",
+		DisplayName:  "OpenAI",
+		Capabilities: allCapabilities,
+		Env:          map[string]string{"api_key": "OPENAI_API_KEY"},
+		Required:     []string{"api_key"},
+		New: func(_ context.Context, p Params) (model.LLM, error) {
+			if p.Instance["api_key"] == "" {
+				return nil, fmt.Errorf("OPENAI_API_KEY not set")
+			}
+			return openaiClient(p, p.Instance["api_key"], "", orDefault(p.Model, "gpt-4"), true), nil
+		},
+		ListModels: func(ctx context.Context, instance config.ProviderConfig) ([]string, error) {
+			if instance["api_key"] == "" {
+				return nil, fmt.Errorf("OpenAI API key not configured")
+			}
+			return openai_provider.ListModels(ctx, instance["api_key"])
+		},
+		NewEmbedder: func(_ context.Context, p Params) (EmbeddingProvider, error) {
+			if p.Instance["api_key"] == "" {
+				return nil, fmt.Errorf("OPENAI_API_KEY not set")
+			}
+			return openaiEmbedder(p, p.Instance["api_key"], "", orDefault(p.Model, "text-embedding-3-small")), nil
+		},
+	})
+
+	Register(Registration{
+		Type:         "openrouter",
+		DisplayName:  "Openrouter",
+		Capabilities: allCapabilities,
+		Env:          map[string]string{"api_key": "OPENROUTER_API_KEY"},
+		Required:     []string{"api_key"},
+		New: func(ctx context.Context, p Params) (model.LLM, error) {
+			apiKey := p.Instance["api_key"]
+			if apiKey == "" {
+				return nil, fmt.Errorf("OPENROUTER_API_KEY not set")
+			}
+			if p.Model == "" {
+				return nil, fmt.Errorf("model name required for openrouter")
+			}
+			cfg := openai.DefaultConfig(apiKey)
+			cfg.BaseURL = "https://openrouter.ai/api/v1"
+			setHTTPClient(&cfg, p.HTTPClient)
+			client := openai.NewClientWithConfig(cfg)
+			maxTokens := openrouter.GetMaxCompletionTokens(ctx, apiKey, p.Model)
+			if maxTokens > 0 {
+				slog.Info("setting max_completion_tokens", "component", "openrouter", "model", p.Model, "max_tokens", maxTokens)
+				return openai_provider.NewProviderWithMaxTokens(client, p.Model, true, maxTokens), nil
+			}
+			return openai_provider.NewProvider(client, p.Model, true), nil
+		},
+		ListModels: func(_ context.Context, instance config.ProviderConfig) ([]string, error) {
+			models, err := openrouter.ListModels(instance["api_key"])
+			if err != nil {
+				return nil, err
+			}
+			var modelNames []string
+			for _, m := range models {
+				modelNames = append(modelNames, m.ID)
+			}
+			return modelNames, nil
+		},
+		// Models are served by many upstream providers; the catalog says
+		// which parameters each one accepts
+		ModelCapabilities: func(ctx context.Context, p Params) (Capabilities, bool) {
+			meta, ok := openrouter.GetModelMetadata(ctx, p.Instance["api_key"], p.Model)
+			if !ok || len(meta.SupportedParameters) == 0 {
+				return Capabilities{}, false
+			}
+			return Capabilities{
+				Tools:            meta.Supports("tools"),
+				Streaming:        true,
+				StructuredOutput: meta.Supports("response_format") || meta.Supports("structured_outputs"),
+			}, true
+		},
+	})
+
+	Register(Registration{
+		Type:         "poe",
+		DisplayName:  "Poe",
+		Capabilities: allCapabilities,
+		Env:          map[string]string{"api_key": "POE_API_KEY"},
+		Required:     []string{"api_key"},
+		New: func(_ context.Context, p Params) (model.LLM, error) {
+			if p.Instance["api_key"] == "" {
+				return nil, fmt.Errorf("POE_API_KEY not set")
+			}
+			return openaiClient(p, p.Instance["api_key"], poe.GetBaseURL(), orDefault(p.Model, "gpt-4o"), true), nil
+		},
+		ListModels: func(ctx context.Context, instance config.ProviderConfig) ([]string, error) {
+			return poe.ListModels(ctx, instance["api_key"])
+		},
+	})
+
+	Register(Registration{
+		Type:         "ollama",
+		DisplayName:  "Ollama",
+		Capabilities: allCapabilities,
+		New: func(_ context.Context, p Params) (model.LLM, error) {
+			if p.Model == "" {
+				return nil, fmt.Errorf("model name required for ollama")
+			}
+			return ollama.NewProvider(p.Instance["base_url"], p.Model, p.HTTPClient), nil
+		},
+		ListModels: func(ctx context.Context, instance config.ProviderConfig) ([]string, error) {
+			return ollama.ListModels(ctx, instance["base_url"])
+		},
+		// Local models differ in tool support; the server says which have it
+		ModelCapabilities: func(ctx context.Context, p Params) (Capabilities, bool) {
+			caps, err := ollama.ProbeCapabilities(ctx, p.Instance["base_url"], p.Model)
+			if err != nil {
+				slog.Debug("ollama capability probe failed", "component", "ollama", "model", p.Model, "error", err)
+				return Capabilities{}, false
+			}
+			return Capabilities{Tools: caps.Tools, Streaming: true, StructuredOutput: true}, true
+		},
+		NewEmbedder: func(_ context.Context, p Params) (EmbeddingProvider, error) {
+			baseURL := ollama.NormalizeBaseURL(p.Instance["base_url"]) + "/v1"
+			return openaiEmbedder(p, "ollama", baseURL, orDefault(p.Model, "nomic-embed-text")), nil
+		},
+	})
+
+	Register(Registration{
+		Type:         "groq",
+		DisplayName:  "Groq",
+		Capabilities: allCapabilities,
+		Env:          map[string]string{"api_key": "GROQ_API_KEY"},
+		Required:     []string{"api_key"},
+		New: func(_ context.Context, p Params) (model.LLM, error) {
+			if p.Instance["api_key"] == "" {
+				return nil, fmt.Errorf("GROQ_API_KEY not set")
+			}
+			return openaiClient(p, p.Instance["api_key"], "https://api.groq.com/openai/v1", orDefault(p.Model, "llama3-70b-8192"), true), nil
+		},
+		ListModels: func(ctx context.Context, instance config.ProviderConfig) ([]string, error) {
+			if instance["api_key"] == "" {
+				return nil, fmt.Errorf("Groq API key not configured")
+			}
+			return groq.ListModels(ctx, instance["api_key"])
+		},
+	})
+
+	Register(Registration{
+		Type:         "lm_studio",
+		DisplayName:  "LM Studio",
+		Capabilities: Capabilities{Tools: true, Streaming: true},
+		New: func(_ context.Context, p Params) (model.LLM, error) {
+			if p.Model == "" {
+				return nil, fmt.Errorf("model name required for lm_studio")
+			}
+			baseURL := orDefault(p.Instance["base_url"], "http://localhost:1234/v1")
+			return openaiClient(p, "lm-studio", baseURL, p.Model, false), nil
+		},
+		ListModels: func(ctx context.Context, instance config.ProviderConfig) ([]string, error) {
+			return lmstudio.ListModels(ctx, orDefault(instance["base_url"], "http://localhost:1234/v1"))
+		},
+		NewEmbedder: func(_ context.Context, p Params) (EmbeddingProvider, error) {
+			if p.Model == "" {
+				return nil, fmt.Errorf("embedding model name required for lm_studio")
+			}
+			return openaiEmbedder(p, "lm-studio", orDefault(p.Instance["base_url"], "http://localhost:1234/v1"), p.Model), nil
+		},
+	})
+
+	Register(Registration{
+		Type:         "litellm",
+		DisplayName:  "LiteLLM",
+		Capabilities: allCapabilities,
+		Env:          map[string]string{"api_key": "LITELLM_API_KEY"},
+		New: func(_ context.Context, p Params) (model.LLM, error) {
+			baseURL := "http://localhost:4000"
+			if p.Instance["base_url"] != "" {
+				baseURL = strings.TrimSuffix(p.Instance["base_url"], "/v1")
+			}
+			if p.Model == "" {
+				return nil, fmt.Errorf("model name required for litellm")
+			}
+			return litellm.NewProviderWithClient(p.Instance["api_key"], baseURL, p.Model, p.HTTPClient), nil
+		},
+		ListModels: func(ctx context.Context, instance config.ProviderConfig) ([]string, error) {
+			return litellm.ListModels(ctx, instance["api_key"], orDefault(instance["base_url"], "http://localhost:4000/v1"))
+		},
+	})
+
+	Register(Registration{
+		Type:         "sap_ai_core",
+		DisplayName:  "SAP AI Core",
+		Capabilities: Capabilities{Tools: true, Streaming: true},
+		Env: map[string]string{
+			"client_id":      "AICORE_CLIENT_ID",
+			"client_secret":  "AICORE_CLIENT_SECRET",
+			"auth_url":       "AICORE_AUTH_URL",
+			"base_url":       "AICORE_BASE_URL",
+			"resource_group": "AICORE_RESOURCE_GROUP",
+		},
+		Required: []string{"client_id", "client_secret", "auth_url", "base_url"},
+		New: func(ctx context.Context, p Params) (model.LLM, error) {
+			if p.Model == "" {
+				return nil, fmt.Errorf("model name required for sap_ai_core")
+			}
+			i := p.Instance
+			if i["client_id"] == "" || i["client_secret"] == "" || i["auth_url"] == "" || i["base_url"] == "" {
+				return nil, fmt.Errorf("SAP AI Core configuration incomplete")
+			}
+			if p.HTTPClient != nil {
+				return sap.NewProviderWithTransport(ctx, p.HTTPClient.Transport, p.Model, i["client_id"], i["client_secret"], i["auth_url"], i["base_url"], i["resource_group"])
+			}
+			return sap.NewProviderWithConfig(ctx, p.Model, i["client_id"], i["client_secret"], i["auth_url"], i["base_url"], i["resource_group"])
+		},
+		ListModels: func(ctx context.Context, i config.ProviderConfig) ([]string, error) {
+			if i["client_id"] == "" || i["client_secret"] == "" || i["auth_url"] == "" || i["base_url"] == "" {
+				return nil, fmt.Errorf("SAP AI Core configuration incomplete")
+			}
+			return sap.ListModels(ctx, i["client_id"], i["client_secret"], i["auth_url"], i["base_url"], i["resource_group"])
+		},
+	})
+
+	Register(Registration{
+		Type:         "xai",
+		Aliases:      []string{"grok"},
+		DisplayName:  "xAI",
+		Capabilities: allCapabilities,
+		Env:          map[string]string{"api_key": "XAI_API_KEY"},
+		Required:     []string{"api_key"},
+		New: func(_ context.Context, p Params) (model.LLM, error) {
+			if p.Instance["api_key"] == "" {
+				return nil, fmt.Errorf("XAI_API_KEY not set")
+			}
+			return openaiClient(p, p.Instance["api_key"], "https://api.x.ai/v1", orDefault(p.Model, "grok-beta"), true), nil
+		},
+		ListModels: func(ctx context.Context, instance config.ProviderConfig) ([]string, error) {
+			if instance["api_key"] == "" {
+				return nil, fmt.Errorf("xAI API key not configured")
+			}
+			return xai.ListModels(ctx, instance["api_key"])
+		},
+	})
+
+	Register(Registration{
+		Type:         "openai_compat",
+		DisplayName:  "OpenAI Compatible",
+		Capabilities: allCapabilities,
+		Required:     []string{"api_key", "base_url"},
+		New: func(_ context.Context, p Params) (model.LLM, error) {
+			if p.Instance["api_key"] == "" {
+				return nil, fmt.Errorf("API key not set for OpenAI Compatible provider")
+			}
+			baseURL := orDefault(p.Instance["base_url"], "https://api.openai.com/v1")
+			return openai_compat.NewProviderWithClient(p.Instance["api_key"], baseURL, orDefault(p.Model, "gpt-4o"), p.Debug, p.HTTPClient), nil
+		},
+		ListModels: func(ctx context.Context, instance config.ProviderConfig) ([]string, error) {
+			if instance["api_key"] == "" {
+				return nil, fmt.Errorf("API key not configured for OpenAI Compatible provider")
+			}
+			return openai_compat.ListModels(ctx, instance["api_key"], orDefault(instance["base_url"], "https://api.openai.com/v1"))
+		},
+		NewEmbedder: func(_ context.Context, p Params) (EmbeddingProvider, error) {
+			baseURL := orDefault(p.Instance["base_url"], "https://api.openai.com/v1")
+			return openaiEmbedder(p, p.Instance["api_key"], baseURL, orDefault(p.Model, "text-embedding-3-small")), nil
+		},
+	})
+
+	// Corporate endpoints. Each profile is its own instance, so several
+	// resources, regions or accounts can be configured side by side.
+	Register(Registration{
+		Type:         "azure",
+		DisplayName:  "Azure OpenAI",
+		Capabilities: allCapabilities,
+		Env: map[string]string{
+			"endpoint": "AZURE_OPENAI_ENDPOINT",
+			"api_key":  "AZURE_OPENAI_API_KEY",
+			"ad_token": "AZURE_OPENAI_AD_TOKEN",
+		},
+		Required: []string{"endpoint"},
+		New: func(_ context.Context, p Params) (model.LLM, error) {
+			deployment := orDefault(p.Model, azureDeployments(p.Instance)[0])
+			return azure.NewProvider(azureConfig(p.Instance, p.HTTPClient), deployment)
+		},
+		ListModels: func(ctx context.Context, instance config.ProviderConfig) ([]string, error) {
+			models, err := azure.ListModels(ctx, azureConfig(instance, nil))
+			if err != nil {
+				return nil, err
+			}
+			// Requests go to deployments; list the configured ones when known
+			if deployments := azureDeployments(instance); deployments[0] != "" {
+				return deployments, nil
+			}
+			return models, nil
+		},
+		NewEmbedder: func(_ context.Context, p Params) (EmbeddingProvider, error) {
+			e, err := azure.NewEmbedder(azureConfig(p.Instance, p.HTTPClient), orDefault(p.Model, p.Instance["embedding_deployment"]))
+			if err != nil {
+				return nil, err
+			}
+			return e, nil
+		},
+	})
+
+	Register(Registration{
+		Type:         "bedrock",
+		DisplayName:  "AWS Bedrock",
+		Capabilities: Capabilities{Tools: true},
+		Env: map[string]string{
+			"region":            "AWS_REGION",
+			"access_key_id":     "AWS_ACCESS_KEY_ID",
+			"secret_access_key": "AWS_SECRET_ACCESS_KEY",
+			"session_token":     "AWS_SESSION_TOKEN",
+			"api_key":           "AWS_BEARER_TOKEN_BEDROCK",
+		},
+		Required: []string{"region"},
+		New: func(_ context.Context, p Params) (model.LLM, error) {
+			if p.Model == "" {
+				return nil, fmt.Errorf("model name required for bedrock")
+			}
+			cfg, err := bedrockConfig(p.Instance, p.HTTPClient)
+			if err != nil {
+				return nil, err
+			}
+			return bedrock.NewProvider(cfg, p.Model)
+		},
+		ListModels: func(ctx context.Context, instance config.ProviderConfig) ([]string, error) {
+			cfg, err := bedrockConfig(instance, nil)
+			if err != nil {
+				return nil, err
+			}
+			return bedrock.ListModels(ctx, cfg)
+		},
+	})
+}
+
+// azureConfig returns the resource settings of an Azure OpenAI instance.
+// Without auth_mode, an api_key is used before an ad_token.
+func azureConfig(instance config.ProviderConfig, httpClient *http.Client) azure.Config {
+	c := azure.Config{
+		Endpoint:   instance["endpoint"],
+		AuthMode:   instance["auth_mode"],
+		APIVersion: instance["api_version"],
+		HTTPClient: httpClient,
+	}
+	if c.AuthMode == "" && instance["api_key"] == "" && instance["ad_token"] != "" {
+		c.AuthMode = azure.AuthAzureAD
+	}
+	c.Credential = instance["api_key"]
+	if c.AuthMode == azure.AuthAzureAD {
+		c.Credential = instance["ad_token"]
+	}
+	return c
+}
+
+// azureDeployments returns the comma-separated deployments of an Azure
+// OpenAI instance; the first is the default. It has at least one entry,
+// which is empty when none are configured.
+func azureDeployments(instance config.ProviderConfig) []string {
+	deployments := strings.Split(instance["deployment"], ",")
+	for i := range deployments {
+		deployments[i] = strings.TrimSpace(deployments[i])
+	}
+	return deployments
+}
+
+// bedrockConfig returns the account settings of a Bedrock instance.
+// Without auth_mode, access keys are used before an API key.
+func bedrockConfig(instance config.ProviderConfig, httpClient *http.Client) (bedrock.Config, error) {
+	cfg := bedrock.Config{Region: instance["region"], Endpoint: instance["endpoint"], HTTPClient: httpClient}
+	mode := instance["auth_mode"]
+	if mode == "" {
+		mode = "api_key"
+		if instance["access_key_id"] != "" {
+			mode = "access_key"
+		}
+	}
+	switch mode {
+	case "access_key":
+		cfg.Credentials = bedrock.Credentials{
+			AccessKeyID:     instance["access_key_id"],
+			SecretAccessKey: instance["secret_access_key"],
+			SessionToken:    instance["session_token"],
+		}
+	case "api_key":
+		cfg.Credentials = bedrock.Credentials{APIKey: instance["api_key"]}
+		if cfg.Credentials.APIKey == "" {
+			return cfg, fmt.Errorf("AWS credentials not set: configure access_key_id and secret_access_key, or api_key")
+		}
+	default:
+		return cfg, fmt.Errorf("bedrock: unknown auth_mode %q (want access_key or api_key)", mode)
+	}
+	return cfg, nil
+}
+
+// orDefault returns value, or def when it is empty.
+func orDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
+
+// openaiClient creates a model on an OpenAI-compatible endpoint; an empty
+// baseURL is OpenAI's.
+func openaiClient(p Params, apiKey, baseURL, modelName string, supportsJSONMode bool) model.LLM {
+	cfg := openai.DefaultConfig(apiKey)
+	if baseURL != "" {
+		cfg.BaseURL = baseURL
+	}
+	setHTTPClient(&cfg, p.HTTPClient)
+	return openai_provider.NewProvider(openai.NewClientWithConfig(cfg), modelName, supportsJSONMode)
+}
+
+// openaiEmbedder creates an embedder on an OpenAI-compatible endpoint; an
+// empty baseURL is OpenAI's.
+func openaiEmbedder(p Params, apiKey, baseURL, modelName string) EmbeddingProvider {
+	cfg := openai.DefaultConfig(apiKey)
+	if baseURL != "" {
+		cfg.BaseURL = baseURL
+	}
+	setHTTPClient(&cfg, p.HTTPClient)
+	return openai_provider.NewEmbedder(openai.NewClientWithConfig(cfg), modelName)
+}
@@ -0,0 +1,160 @@
+package provider
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+
+	"github.com/SAP/astonish/pkg/config"
+)
+
+// EmbeddingProvider turns text into vectors. Vectors of different
+// providers or models are not comparable.
+type EmbeddingProvider interface {
+	// Name identifies the embedding model.
+	Name() string
+	// Embed returns one vector per text, in order.
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// GetEmbeddingProvider returns an embedder for modelName on a configured
+// provider instance; an empty modelName is the provider's default
+// embedding model. Results are cached in the shared EmbeddingCache.
+func GetEmbeddingProvider(ctx context.Context, instanceName, modelName string, cfg *config.AppConfig) (EmbeddingProvider, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("no provider configuration available")
+	}
+	instanceName, instance, r, err := instanceRegistration(instanceName, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if r.NewEmbedder == nil {
+		return nil, fmt.Errorf("provider type %s does not support embeddings", r.Type)
+	}
+	httpClient, err := providerHTTPClient(instanceName, instance, cfg)
+	if err != nil {
+		return nil, err
+	}
+	e, err := r.NewEmbedder(ctx, Params{Instance: instance, Model: modelName, HTTPClient: httpClient, Debug: debugMode})
+	if err != nil {
+		return nil, err
+	}
+	return SharedEmbeddingCache.Wrap(instanceName, e), nil
+}
+
+// EmbedText embeds a single text.
+func EmbedText(ctx context.Context, e EmbeddingProvider, text string) ([]float32, error) {
+	vectors, err := e.Embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+// --- Cache ---
+
+// SharedEmbeddingCache is the cache of the embedders GetEmbeddingProvider
+// returns.
+var SharedEmbeddingCache = NewEmbeddingCache(10000)
+
+// EmbeddingCache keeps the vectors of recently embedded texts, keyed by a
+// hash of the text and the model that embedded it, so the same content is
+// sent to the provider once. The least recently used entries are evicted
+// beyond its size.
+type EmbeddingCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List // Front is the most recently used
+	entries map[[sha256.Size]byte]*list.Element
+}
+
+type embeddingEntry struct {
+	key    [sha256.Size]byte
+	vector []float32
+}
+
+// NewEmbeddingCache creates a cache of up to size vectors.
+func NewEmbeddingCache(size int) *EmbeddingCache {
+	return &EmbeddingCache{size: size, order: list.New(), entries: make(map[[sha256.Size]byte]*list.Element)}
+}
+
+// Wrap returns e with its vectors cached. namespace tells apart
+// embedders with the same model name, e.g. the provider instance.
+func (c *EmbeddingCache) Wrap(namespace string, e EmbeddingProvider) EmbeddingProvider {
+	return &cachedEmbedder{EmbeddingProvider: e, cache: c, prefix: namespace + "\x00" + e.Name() + "\x00"}
+}
+
+// Len returns the number of cached vectors.
+func (c *EmbeddingCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *EmbeddingCache) get(key [sha256.Size]byte) ([]float32, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*embeddingEntry).vector, true
+}
+
+func (c *EmbeddingCache) put(key [sha256.Size]byte, vector []float32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		el.Value.(*embeddingEntry).vector = vector
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&embeddingEntry{key: key, vector: vector})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*embeddingEntry).key)
+	}
+}
+
+type cachedEmbedder struct {
+	EmbeddingProvider
+	cache  *EmbeddingCache
+	prefix string
+}
+
+// Embed returns the cached vectors and embeds only the other texts.
+func (e *cachedEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	keys := make([][sha256.Size]byte, len(texts))
+	var missing []string
+	var missingAt []int
+	for i, text := range texts {
+		keys[i] = sha256.Sum256([]byte(e.prefix + text))
+		if v, ok := e.cache.get(keys[i]); ok {
+			vectors[i] = v
+			continue
+		}
+		missing = append(missing, text)
+		missingAt = append(missingAt, i)
+	}
+	if len(missing) == 0 {
+		return vectors, nil
+	}
+
+	embedded, err := e.EmbeddingProvider.Embed(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	if len(embedded) != len(missing) {
+		return nil, fmt.Errorf("%s returned %d vectors for %d texts", e.Name(), len(embedded), len(missing))
+	}
+	for j, i := range missingAt {
+		vectors[i] = embedded[j]
+		e.cache.put(keys[i], embedded[j])
+	}
+	return vectors, nil
+}
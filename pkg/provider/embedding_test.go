@@ -0,0 +1,111 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/config"
+)
+
+// countingEmbedder embeds a text as its length and records what it was asked.
+type countingEmbedder struct {
+	calls [][]string
+}
+
+func (e *countingEmbedder) Name() string { return "counting" }
+
+func (e *countingEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	e.calls = append(e.calls, texts)
+	vectors := make([][]float32, len(texts))
+	for i, t := range texts {
+		vectors[i] = []float32{float32(len(t))}
+	}
+	return vectors, nil
+}
+
+func TestEmbeddingCache(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingEmbedder{}
+	cache := NewEmbeddingCache(3)
+	e := cache.Wrap("local", inner)
+
+	if _, err := e.Embed(ctx, []string{"a", "bb"}); err != nil {
+		t.Fatal(err)
+	}
+	vectors, err := e.Embed(ctx, []string{"bb", "ccc", "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vectors[0][0] != 2 || vectors[1][0] != 3 || vectors[2][0] != 1 {
+		t.Errorf("vectors = %v, want them in input order", vectors)
+	}
+	if len(inner.calls) != 2 || !slices.Equal(inner.calls[1], []string{"ccc"}) {
+		t.Errorf("calls = %v, want only the uncached text embedded", inner.calls)
+	}
+
+	// Another namespace does not share vectors
+	other := cache.Wrap("remote", inner)
+	if _, err := EmbedText(ctx, other, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if len(inner.calls) != 3 {
+		t.Errorf("calls = %v, want the other namespace embedded separately", inner.calls)
+	}
+
+	// Beyond its size, the least recently used vector ("bb") is evicted
+	if cache.Len() != 3 {
+		t.Errorf("Len = %d, want 3", cache.Len())
+	}
+	EmbedText(ctx, e, "bb")
+	if len(inner.calls) != 4 {
+		t.Errorf("calls = %v, want the evicted text embedded again", inner.calls)
+	}
+}
+
+func TestGetEmbeddingProvider(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/v1/embeddings" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		var req struct {
+			Input []string `json:"input"`
+			Model string   `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "text-embedding-3-small" {
+			t.Errorf("model = %q, want the default", req.Model)
+		}
+		// Answer out of order; vectors are placed by index
+		w.Write([]byte(`{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}]}`))
+	}))
+	defer srv.Close()
+
+	cfg := &config.AppConfig{Providers: map[string]config.ProviderConfig{
+		"corp": {"type": "openai_compat", "api_key": "k", "base_url": srv.URL + "/v1"},
+		"anth": {"type": "anthropic", "api_key": "k"},
+	}}
+	e, err := GetEmbeddingProvider(context.Background(), "corp", "", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vectors, err := e.Embed(context.Background(), []string{"first", "second"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vectors[0][0] != 1 || vectors[1][1] != 1 {
+		t.Errorf("vectors = %v", vectors)
+	}
+	if _, err := e.Embed(context.Background(), []string{"second", "first"}); err != nil || requests != 1 {
+		t.Errorf("cached texts were embedded again: %d requests, %v", requests, err)
+	}
+
+	if _, err := GetEmbeddingProvider(context.Background(), "anth", "", cfg); err == nil {
+		t.Error("a provider without embeddings returned an embedder")
+	}
+}
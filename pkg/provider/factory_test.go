@@ -14,6 +14,8 @@ func TestGetProviderDisplayName(t *testing.T) {
 	}{
 		{"openai", "OpenAI"},
 		{"anthropic", "Anthropic"},
+		{"azure", "Azure OpenAI"},
+		{"bedrock", "AWS Bedrock"},
 		{"gemini", "Google GenAI"},
 		{"groq", "Groq"},
 		{"litellm", "LiteLLM"},
@@ -45,7 +47,7 @@ func TestGetProviderIDs(t *testing.T) {
 		t.Error("GetProviderIDs returned empty slice")
 	}
 
-	expectedCount := 12
+	expectedCount := 14
 	if len(ids) != expectedCount {
 		t.Errorf("GetProviderIDs returned %d IDs, expected %d", len(ids), expectedCount)
 	}
@@ -58,7 +60,7 @@ func TestGetProviderIDs(t *testing.T) {
 		idSet[id] = true
 	}
 
-	expectedIDs := []string{"anthropic", "gemini", "groq", "litellm", "lm_studio", "ollama", "openai", "openai_compat", "openrouter", "poe", "sap_ai_core", "xai"}
+	expectedIDs := []string{"anthropic", "azure", "bedrock", "gemini", "groq", "litellm", "lm_studio", "ollama", "openai", "openai_compat", "openrouter", "poe", "sap_ai_core", "xai"}
 	for _, expected := range expectedIDs {
 		if !idSet[expected] {
 			t.Errorf("expected provider ID %s not found", expected)
@@ -209,6 +211,8 @@ func TestResolveProviderInstance(t *testing.T) {
 			"SAP AI Core":  {"type": "sap_ai_core", "client_id": "xxx"},
 			"openai":       {"api_key": "sk-123"},
 			"My Anthropic": {"type": "anthropic", "api_key": "sk-ant"},
+			"prod-gpt4":    {"type": "azure", "endpoint": "https://prod.openai.azure.com"},
+			"gemini-eu":    {"type": "gemini", "api_key": "g-123"},
 		},
 	}
 
@@ -224,6 +228,9 @@ func TestResolveProviderInstance(t *testing.T) {
 		{"case insensitive match", "sap ai core", "SAP AI Core", true},
 		{"case insensitive match caps", "OPENAI", "openai", true},
 		{"normalized custom name", "my_anthropic", "My Anthropic", true},
+		{"type:profile", "azure:prod-gpt4", "prod-gpt4", true},
+		{"type alias:profile", "google_genai:gemini-eu", "gemini-eu", true},
+		{"profile of another type", "openai:prod-gpt4", "", false},
 		{"nonexistent", "nonexistent", "", false},
 		{"empty string", "", "", false},
 	}
@@ -0,0 +1,60 @@
+package google
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"google.golang.org/genai"
+)
+
+// Embedder embeds text with the Gemini API.
+type Embedder struct {
+	client *genai.Client
+	model  string
+}
+
+// NewEmbedder creates an embedder for modelName, e.g. gemini-embedding-001.
+// A nil httpClient uses the genai default.
+func NewEmbedder(ctx context.Context, apiKey, modelName string, httpClient *http.Client) (*Embedder, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("GOOGLE_API_KEY not set")
+	}
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+		APIKey:     apiKey,
+		Backend:    genai.BackendGeminiAPI,
+		HTTPClient: httpClient,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Embedder{client: client, model: modelName}, nil
+}
+
+// Name returns the embedding model.
+func (e *Embedder) Name() string {
+	return e.model
+}
+
+// Embed returns one vector per text, in order.
+func (e *Embedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	contents := make([]*genai.Content, len(texts))
+	for i, text := range texts {
+		contents[i] = genai.NewContentFromText(text, genai.RoleUser)
+	}
+	resp, err := e.client.Models.EmbedContent(ctx, e.model, contents, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("embedding response has %d vectors for %d inputs", len(resp.Embeddings), len(texts))
+	}
+	vectors := make([][]float32, len(texts))
+	for i, emb := range resp.Embeddings {
+		vectors[i] = emb.Values
+	}
+	return vectors, nil
+}
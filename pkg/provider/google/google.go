@@ -60,6 +60,12 @@ func (p *Provider) GenerateContent(ctx context.Context, req *model.LLMRequest, s
 
 // NewProvider creates a new Google GenAI provider.
 func NewProvider(ctx context.Context, modelName string, apiKey string) (model.LLM, error) {
+	return NewProviderWithClient(ctx, modelName, apiKey, nil)
+}
+
+// NewProviderWithClient creates a Google GenAI provider that sends requests
+// through httpClient. A nil client uses the genai default.
+func NewProviderWithClient(ctx context.Context, modelName string, apiKey string, httpClient *http.Client) (model.LLM, error) {
 	if apiKey == "" {
 		apiKey = os.Getenv("GOOGLE_API_KEY")
 	}
@@ -70,7 +76,8 @@ func NewProvider(ctx context.Context, modelName string, apiKey string) (model.LL
 	// Use ADK's gemini package to create the model
 	// This handles the model.LLM interface implementation
 	m, err := gemini.NewModel(ctx, modelName, &genai.ClientConfig{
-		APIKey: apiKey,
+		APIKey:     apiKey,
+		HTTPClient: httpClient,
 	})
 	if err != nil {
 		return nil, err
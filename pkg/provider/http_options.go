@@ -0,0 +1,50 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/provider/httpool"
+)
+
+// HTTPOptions converts an http config block to transport options.
+func HTTPOptions(c config.HTTPClientConfig) httpool.Options {
+	return httpool.Options{
+		Proxy:              c.Proxy,
+		CABundle:           c.CABundle,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+}
+
+// ConfigureHTTP applies the http section of config.yaml (proxy, CA bundle,
+// TLS options) to the shared transport used by providers and built-in tools.
+// Call it once after loading the app config.
+func ConfigureHTTP(cfg *config.AppConfig) error {
+	if cfg == nil {
+		return nil
+	}
+	if err := httpool.SetDefaults(HTTPOptions(cfg.HTTP)); err != nil {
+		return fmt.Errorf("invalid http config: %w", err)
+	}
+	return nil
+}
+
+// providerHTTPClient returns a streaming client for a provider instance that
+// sets its own proxy, ca_bundle or insecure_skip_verify keys, with unset keys
+// taken from the global http section. It returns nil when the instance has no
+// overrides, so the provider keeps its default client.
+func providerHTTPClient(instanceName string, instance config.ProviderConfig, cfg *config.AppConfig) (*http.Client, error) {
+	opts := HTTPOptions(instance.HTTPClientConfig())
+	if opts.IsZero() {
+		return nil, nil
+	}
+	if cfg != nil {
+		opts = opts.Merge(HTTPOptions(cfg.HTTP))
+	}
+	client, err := httpool.ClientFor(opts, 0)
+	if err != nil {
+		return nil, fmt.Errorf("provider instance '%s': %w", instanceName, err)
+	}
+	return client, nil
+}
@@ -4,8 +4,15 @@
 package httpool
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
 	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -13,6 +20,9 @@ import (
 // It overrides Go's conservative defaults to handle concurrent fleet workloads
 // where multiple agents call different provider endpoints simultaneously.
 var sharedTransport = &http.Transport{
+	// Honour HTTP_PROXY / HTTPS_PROXY / NO_PROXY unless a proxy is configured
+	Proxy: http.ProxyFromEnvironment,
+
 	// Connection pooling
 	MaxIdleConns:        200,               // total across all hosts (default: 100)
 	MaxIdleConnsPerHost: 20,                // per-host idle pool (default: 2)
@@ -44,7 +54,7 @@ var sharedTransport = &http.Transport{
 // disable the timeout (the caller controls cancellation via context).
 func Client(timeout time.Duration) *http.Client {
 	return &http.Client{
-		Transport: sharedTransport,
+		Transport: defaultTransport(),
 		Timeout:   timeout,
 	}
 }
@@ -54,7 +64,7 @@ func Client(timeout time.Duration) *http.Client {
 // streaming responses can run indefinitely.
 func StreamingClient() *http.Client {
 	return &http.Client{
-		Transport: sharedTransport,
+		Transport: defaultTransport(),
 		Timeout:   0, // no timeout — caller uses context
 	}
 }
@@ -62,5 +72,133 @@ func StreamingClient() *http.Client {
 // Transport returns the shared pool-aware transport for use as the base
 // round-tripper inside custom transports (e.g. auth-injecting wrappers).
 func Transport() http.RoundTripper {
+	return defaultTransport()
+}
+
+// Options configures the network path of a transport: an explicit proxy,
+// an extra CA bundle to trust, and whether to skip TLS verification. The
+// zero value means the shared transport.
+type Options struct {
+	Proxy              string // proxy URL; empty uses the environment
+	CABundle           string // PEM file appended to the system roots
+	InsecureSkipVerify bool
+}
+
+// IsZero reports whether o leaves the shared transport unchanged.
+func (o Options) IsZero() bool {
+	return o == Options{}
+}
+
+// Merge returns o with unset fields taken from defaults.
+func (o Options) Merge(defaults Options) Options {
+	if o.Proxy == "" {
+		o.Proxy = defaults.Proxy
+	}
+	if o.CABundle == "" {
+		o.CABundle = defaults.CABundle
+	}
+	if !o.InsecureSkipVerify {
+		o.InsecureSkipVerify = defaults.InsecureSkipVerify
+	}
+	return o
+}
+
+var (
+	// defaults is the transport used by Client, StreamingClient and
+	// Transport; nil means sharedTransport. Set by SetDefaults.
+	defaults    atomic.Pointer[http.Transport]
+	defaultOpts atomic.Pointer[Options]
+
+	// transports caches one transport per Options so that providers sharing
+	// a proxy also share a connection pool.
+	transportsMu sync.Mutex
+	transports   = map[Options]*http.Transport{}
+)
+
+func defaultTransport() *http.Transport {
+	if t := defaults.Load(); t != nil {
+		return t
+	}
 	return sharedTransport
 }
+
+// SetDefaults applies o to every client handed out by Client,
+// StreamingClient and Transport (providers and built-in tools alike).
+// Passing the zero Options restores the shared transport.
+func SetDefaults(o Options) error {
+	if o.IsZero() {
+		defaults.Store(nil)
+		defaultOpts.Store(nil)
+		return nil
+	}
+	t, err := TransportFor(o)
+	if err != nil {
+		return err
+	}
+	defaults.Store(t)
+	defaultOpts.Store(&o)
+	return nil
+}
+
+// DefaultOptions returns the options last passed to SetDefaults, for
+// callers merging their own overrides on top.
+func DefaultOptions() Options {
+	if o := defaultOpts.Load(); o != nil {
+		return *o
+	}
+	return Options{}
+}
+
+// TransportFor returns a pooled transport with o applied on top of the
+// shared transport settings. Transports are cached per Options.
+func TransportFor(o Options) (*http.Transport, error) {
+	if o.IsZero() {
+		return defaultTransport(), nil
+	}
+
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+	if t, ok := transports[o]; ok {
+		return t, nil
+	}
+
+	t := sharedTransport.Clone()
+	if o.Proxy != "" {
+		proxyURL, err := url.Parse(o.Proxy)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", o.Proxy)
+		}
+		t.Proxy = http.ProxyURL(proxyURL)
+	}
+	if o.CABundle != "" || o.InsecureSkipVerify {
+		tlsConfig := &tls.Config{InsecureSkipVerify: o.InsecureSkipVerify}
+		if o.CABundle != "" {
+			pem, err := os.ReadFile(o.CABundle)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+			}
+			pool, err := x509.SystemCertPool()
+			if err != nil || pool == nil {
+				pool = x509.NewCertPool()
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in CA bundle %s", o.CABundle)
+			}
+			tlsConfig.RootCAs = pool
+		}
+		t.TLSClientConfig = tlsConfig
+	}
+
+	transports[o] = t
+	return t, nil
+}
+
+// ClientFor returns a client using TransportFor(o). A zero timeout suits
+// streaming responses.
+func ClientFor(o Options, timeout time.Duration) (*http.Client, error) {
+	t, err := TransportFor(o)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: t, Timeout: timeout}, nil
+}
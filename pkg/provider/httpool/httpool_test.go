@@ -0,0 +1,60 @@
+package httpool
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTransportFor_Proxy(t *testing.T) {
+	opts := Options{Proxy: "http://proxy.internal:3128"}
+	tr, err := TransportFor(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("GET", "https://api.example.com/v1", nil)
+	proxyURL, err := tr.Proxy(req)
+	if err != nil || proxyURL == nil || proxyURL.Host != "proxy.internal:3128" {
+		t.Fatalf("proxy = %v, %v; want proxy.internal:3128", proxyURL, err)
+	}
+	if again, _ := TransportFor(opts); again != tr {
+		t.Error("expected transports to be cached per options")
+	}
+	if tr == sharedTransport {
+		t.Error("options must not modify the shared transport")
+	}
+}
+
+func TestTransportFor_InvalidOptions(t *testing.T) {
+	if _, err := TransportFor(Options{Proxy: "::not a url"}); err == nil {
+		t.Error("expected error for invalid proxy URL")
+	}
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	os.WriteFile(bundle, []byte("not a certificate"), 0o600)
+	if _, err := TransportFor(Options{CABundle: bundle}); err == nil {
+		t.Error("expected error for CA bundle without certificates")
+	}
+}
+
+func TestSetDefaults(t *testing.T) {
+	defer SetDefaults(Options{})
+
+	opts := Options{InsecureSkipVerify: true}
+	if err := SetDefaults(opts); err != nil {
+		t.Fatal(err)
+	}
+	tr, ok := Client(0).Transport.(*http.Transport)
+	if !ok || tr.TLSClientConfig == nil || !tr.TLSClientConfig.InsecureSkipVerify {
+		t.Fatal("expected default clients to use the configured transport")
+	}
+	if DefaultOptions() != opts {
+		t.Errorf("DefaultOptions() = %+v, want %+v", DefaultOptions(), opts)
+	}
+
+	SetDefaults(Options{})
+	if Transport() != sharedTransport {
+		t.Error("zero options should restore the shared transport")
+	}
+}
@@ -3,6 +3,7 @@ package litellm
 import (
 	"context"
 	"iter"
+	"net/http"
 	"strings"
 
 	"github.com/sashabaranov/go-openai"
@@ -18,6 +19,12 @@ type Provider struct {
 
 // NewProvider creates a new LiteLLM provider.
 func NewProvider(apiKey, baseURL, modelName string) model.LLM {
+	return NewProviderWithClient(apiKey, baseURL, modelName, nil)
+}
+
+// NewProviderWithClient creates a LiteLLM provider that sends requests
+// through httpClient. A nil client uses the go-openai default.
+func NewProviderWithClient(apiKey, baseURL, modelName string, httpClient *http.Client) model.LLM {
 	if apiKey == "" {
 		apiKey = "litellm"
 	}
@@ -33,6 +40,9 @@ func NewProvider(apiKey, baseURL, modelName string) model.LLM {
 	}
 
 	config.BaseURL = baseURL
+	if httpClient != nil {
+		config.HTTPClient = httpClient
+	}
 	client := openai.NewClientWithConfig(config)
 
 	// LiteLLM is a proxy for multiple providers with different capabilities.
@@ -0,0 +1,186 @@
+package llmerror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Class is a coarse classification of a provider failure that automation
+// can branch on (it is exposed to flows as _error_class) and that drives the
+// user-facing explanation of the error.
+type Class string
+
+const (
+	ClassAuth            Class = "auth"             // API key missing, invalid, expired or lacking permission
+	ClassQuota           Class = "quota"            // Billing quota or credits exhausted
+	ClassRateLimit       Class = "rate_limit"       // Too many requests; succeeds after waiting
+	ClassContentFilter   Class = "content_filter"   // Prompt or response blocked by the provider's safety filter
+	ClassContextOverflow Class = "context_overflow" // Request larger than the model's context window
+	ClassTransient       Class = "transient"        // Server errors, timeouts and network failures
+	ClassOther           Class = "other"            // Anything else, including non-provider errors
+)
+
+// ContentFilterError reports a response the provider blocked with its
+// safety filter. Unlike most provider failures it arrives as the finish
+// reason of an otherwise normal response rather than as an HTTP error.
+type ContentFilterError struct {
+	Provider     string // Provider name, if known
+	FinishReason string // e.g. "SAFETY", "PROHIBITED_CONTENT"
+}
+
+func (e *ContentFilterError) Error() string {
+	msg := fmt.Sprintf("response blocked by content filter (finish reason %s)", e.FinishReason)
+	if e.Provider != "" {
+		return e.Provider + ": " + msg
+	}
+	return msg
+}
+
+// Classify maps an error to its Class. Structured LLMErrors are classified
+// by status code and body; other errors (SDK clients that only expose text)
+// by well-known phrases.
+func Classify(err error) Class {
+	if err == nil {
+		return ClassOther
+	}
+	if errors.Is(err, context.Canceled) {
+		return ClassOther
+	}
+	var filterErr *ContentFilterError
+	if errors.As(err, &filterErr) {
+		return ClassContentFilter
+	}
+
+	msg := strings.ToLower(err.Error())
+	var llmErr *LLMError
+	if errors.As(err, &llmErr) {
+		msg = strings.ToLower(llmErr.Message + " " + llmErr.Body)
+		switch {
+		case IsContextOverflow(err):
+			return ClassContextOverflow
+		case containsAny(msg, contentFilterPhrases):
+			return ClassContentFilter
+		case llmErr.StatusCode == http.StatusPaymentRequired || containsAny(msg, quotaPhrases):
+			return ClassQuota
+		case llmErr.StatusCode == http.StatusUnauthorized || llmErr.StatusCode == http.StatusForbidden:
+			return ClassAuth
+		case llmErr.StatusCode == http.StatusTooManyRequests:
+			return ClassRateLimit
+		case llmErr.Retryable:
+			return ClassTransient
+		}
+		return ClassOther
+	}
+
+	switch {
+	case containsAny(msg, contextOverflowPhrases):
+		return ClassContextOverflow
+	case containsAny(msg, contentFilterPhrases):
+		return ClassContentFilter
+	case containsAny(msg, quotaPhrases):
+		return ClassQuota
+	case containsAny(msg, authPhrases):
+		return ClassAuth
+	case containsAny(msg, rateLimitPhrases):
+		return ClassRateLimit
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) || containsAny(msg, transientPhrases) {
+		return ClassTransient
+	}
+	return ClassOther
+}
+
+// Provider returns the provider name of an LLMError, or "" for other errors.
+func Provider(err error) string {
+	var llmErr *LLMError
+	if errors.As(err, &llmErr) {
+		return llmErr.Provider
+	}
+	var filterErr *ContentFilterError
+	if errors.As(err, &filterErr) {
+		return filterErr.Provider
+	}
+	return ""
+}
+
+// Explain returns a title, a plain-language reason and a suggestion for a
+// provider failure of this class. It returns empty strings for ClassOther,
+// where the raw error is the best description available.
+func (c Class) Explain(provider string) (title, reason, suggestion string) {
+	who := "The model provider"
+	if provider != "" {
+		who = "The " + provider + " provider"
+	}
+	switch c {
+	case ClassAuth:
+		return "Authentication Failed",
+			who + " rejected the API key: it is missing, invalid, expired or lacks access to this model.",
+			"Update the key for this provider (astonish setup) and try again."
+	case ClassQuota:
+		return "Quota Exhausted",
+			who + " reports that the account has run out of quota or credits.",
+			"Check the plan and billing of the provider account, or switch this node to another provider."
+	case ClassRateLimit:
+		return "Rate Limited",
+			who + " is rejecting requests because too many were sent in a short time.",
+			"Wait a moment and retry, or lower parallel.maxConcurrency / set parallel.rate_limit."
+	case ClassContentFilter:
+		return "Blocked by Content Filter",
+			who + " blocked the prompt or the response with its content safety filter.",
+			"Rephrase the prompt or the data passed to it; the filter cannot be bypassed by retrying."
+	case ClassContextOverflow:
+		return "Context Window Exceeded",
+			"The request is larger than the model's context window.",
+			"Pass less data to this node (summarize or split it), or use a model with a larger context window."
+	case ClassTransient:
+		return "Provider Unavailable",
+			who + " failed with a temporary error (server error, timeout or network problem).",
+			"Retry later; if it persists, check the provider status page."
+	}
+	return "", "", ""
+}
+
+// Fatal reports whether retrying the same request cannot succeed without a
+// change by the user (new key, more quota, different input).
+func (c Class) Fatal() bool {
+	return c == ClassAuth || c == ClassQuota || c == ClassContentFilter
+}
+
+var (
+	authPhrases = []string{
+		"invalid api key", "invalid_api_key", "incorrect api key", "api key expired", "api_key_invalid",
+		"api key not valid", "unauthorized", "authentication", "401", "forbidden", "permission denied", "permission_denied",
+	}
+	quotaPhrases = []string{
+		"insufficient_quota", "exceeded your current quota", "quota exceeded", "billing", "credit balance",
+		"payment required", "out of credits",
+	}
+	rateLimitPhrases     = []string{"429", "rate limit", "ratelimit", "too many requests", "resource_exhausted", "resource exhausted"}
+	contentFilterPhrases = []string{
+		"content_filter", "content filter", "content management policy", "responsible ai", "safety system",
+		"prohibited_content", "blocked due to safety", "finish_reason: safety", "content_policy_violation",
+	}
+	contextOverflowPhrases = []string{
+		"context length", "context window", "maximum context", "context_length_exceeded", "prompt is too long",
+		"input is too long", "too many tokens",
+	}
+	transientPhrases = []string{
+		"internal server error", "bad gateway", "service unavailable", "gateway timeout", "overloaded",
+		"connection reset", "connection refused", "timeout", "unexpected eof", "temporarily unavailable",
+	}
+)
+
+func containsAny(s string, phrases []string) bool {
+	for _, p := range phrases {
+		if strings.Contains(s, p) {
+			return true
+		}
+	}
+	return false
+}
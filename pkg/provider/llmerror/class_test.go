@@ -0,0 +1,48 @@
+package llmerror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want Class
+	}{
+		{"expired_key", NewLLMError("openai", 401, "401 Unauthorized", `{"error":{"code":"invalid_api_key"}}`), ClassAuth},
+		{"insufficient_quota", NewLLMError("openai", 429, "429 Too Many Requests", `{"error":{"code":"insufficient_quota"}}`), ClassQuota},
+		{"rate_limit", NewLLMError("anthropic", 429, "429 Too Many Requests", `{"error":"rate_limit_error"}`), ClassRateLimit},
+		{"content_filter", NewLLMError("azure", 400, "400 Bad Request", `{"error":{"code":"content_filter"}}`), ClassContentFilter},
+		{"context_overflow", NewLLMError("openai", 400, "400 Bad Request", `context_length_exceeded`), ClassContextOverflow},
+		{"server_error", NewLLMError("gemini", 503, "503 Service Unavailable", ""), ClassTransient},
+		{"bad_request", NewLLMError("gemini", 400, "400 Bad Request", "invalid schema"), ClassOther},
+		{"wrapped_sdk_text", fmt.Errorf("node failed: %w", errors.New("Error 401: API key expired")), ClassAuth},
+		{"deadline", context.DeadlineExceeded, ClassTransient},
+		{"canceled", context.Canceled, ClassOther},
+		{"plain", errors.New("tool returned nothing"), ClassOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Classify(tt.err); got != tt.want {
+				t.Errorf("Classify() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassExplain(t *testing.T) {
+	title, reason, _ := ClassAuth.Explain("openai")
+	if title != "Authentication Failed" || reason == "" {
+		t.Errorf("auth explanation = %q / %q", title, reason)
+	}
+	if title, _, _ := ClassOther.Explain("openai"); title != "" {
+		t.Errorf("other should have no explanation, got %q", title)
+	}
+	if !ClassAuth.Fatal() || ClassTransient.Fatal() {
+		t.Error("auth should be fatal and transient should not")
+	}
+}
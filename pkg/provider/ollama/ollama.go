@@ -7,7 +7,6 @@ import (
 	"io"
 	"net/http"
 	"sort"
-	"strings"
 	"sync"
 	"time"
 
@@ -57,11 +56,7 @@ type ollamaTagsResponse struct {
 
 // fetchModels fetches models from Ollama API
 func fetchModels(ctx context.Context, baseURL string) ([]ModelInfo, error) {
-	if baseURL == "" {
-		baseURL = "http://localhost:11434"
-	}
-	// Ensure no trailing slash
-	baseURL = strings.TrimRight(baseURL, "/")
+	baseURL = NormalizeBaseURL(baseURL)
 
 	url := fmt.Sprintf("%s/api/tags", baseURL)
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
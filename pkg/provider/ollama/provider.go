@@ -0,0 +1,133 @@
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	openai_provider "github.com/SAP/astonish/pkg/provider/openai"
+	"github.com/SAP/astonish/pkg/provider/httpool"
+)
+
+// DefaultBaseURL is where a local Ollama listens.
+const DefaultBaseURL = "http://localhost:11434"
+
+// Provider implements model.LLM for Ollama. It talks to Ollama's
+// OpenAI-compatible endpoint, which streams and takes tools for models
+// that support them.
+type Provider struct {
+	*openai_provider.Provider
+}
+
+// NewProvider creates an Ollama provider for modelName. baseURL is the
+// Ollama server, with or without the /v1 suffix; a nil httpClient uses
+// the go-openai default.
+func NewProvider(baseURL, modelName string, httpClient *http.Client) *Provider {
+	config := openai.DefaultConfig("ollama")
+	config.BaseURL = NormalizeBaseURL(baseURL) + "/v1"
+	if httpClient != nil {
+		config.HTTPClient = httpClient
+	}
+	return &Provider{
+		Provider: openai_provider.NewProvider(openai.NewClientWithConfig(config), modelName, true),
+	}
+}
+
+// NormalizeBaseURL returns the server URL without a trailing slash or the
+// /v1 suffix of the OpenAI-compatible API, DefaultBaseURL when empty.
+func NormalizeBaseURL(baseURL string) string {
+	if baseURL == "" {
+		return DefaultBaseURL
+	}
+	baseURL = strings.TrimRight(baseURL, "/")
+	return strings.TrimSuffix(baseURL, "/v1")
+}
+
+// Capabilities are what a local model supports, as reported by Ollama.
+type Capabilities struct {
+	Completion bool
+	Tools      bool
+	Vision     bool
+	Thinking   bool
+}
+
+// ollamaShowResponse is the part of the /api/show response read here.
+type ollamaShowResponse struct {
+	Capabilities []string `json:"capabilities"`
+	Template     string   `json:"template"`
+}
+
+// Probed capabilities are cached per server and model; a pulled model
+// does not change, and probing on every node would add a request each.
+var (
+	capsCacheMu  sync.Mutex
+	capsCache    = map[string]capsCacheEntry{}
+	capsCacheTTL = 10 * time.Minute
+)
+
+type capsCacheEntry struct {
+	caps Capabilities
+	at   time.Time
+}
+
+// ProbeCapabilities asks the Ollama server at baseURL what modelName
+// supports. Servers before the capabilities field are read from the
+// model's template: tool-capable templates render .Tools.
+func ProbeCapabilities(ctx context.Context, baseURL, modelName string) (Capabilities, error) {
+	baseURL = NormalizeBaseURL(baseURL)
+	key := baseURL + "|" + modelName
+	capsCacheMu.Lock()
+	if e, ok := capsCache[key]; ok && time.Since(e.at) < capsCacheTTL {
+		capsCacheMu.Unlock()
+		return e.caps, nil
+	}
+	capsCacheMu.Unlock()
+
+	body, err := json.Marshal(map[string]string{"model": modelName, "name": modelName})
+	if err != nil {
+		return Capabilities{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+"/api/show", bytes.NewReader(body))
+	if err != nil {
+		return Capabilities{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpool.Client(10 * time.Second).Do(req)
+	if err != nil {
+		return Capabilities{}, fmt.Errorf("failed to probe model %s: %w", modelName, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return Capabilities{}, fmt.Errorf("failed to probe model %s: %s - %s", modelName, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var show ollamaShowResponse
+	if err := json.NewDecoder(resp.Body).Decode(&show); err != nil {
+		return Capabilities{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+	var caps Capabilities
+	if len(show.Capabilities) > 0 {
+		caps = Capabilities{
+			Completion: slices.Contains(show.Capabilities, "completion"),
+			Tools:      slices.Contains(show.Capabilities, "tools"),
+			Vision:     slices.Contains(show.Capabilities, "vision"),
+			Thinking:   slices.Contains(show.Capabilities, "thinking"),
+		}
+	} else {
+		caps = Capabilities{Completion: true, Tools: strings.Contains(show.Template, ".Tools")}
+	}
+
+	capsCacheMu.Lock()
+	capsCache[key] = capsCacheEntry{caps: caps, at: time.Now()}
+	capsCacheMu.Unlock()
+	return caps, nil
+}
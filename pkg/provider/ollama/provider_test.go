@@ -0,0 +1,66 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProbeCapabilities(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/api/show" || r.Method != http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		var body struct{ Model string }
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		switch body.Model {
+		case "qwen3:8b":
+			_, _ = w.Write([]byte(`{"capabilities":["completion","tools","thinking"]}`))
+		case "gemma:2b":
+			_, _ = w.Write([]byte(`{"capabilities":["completion"]}`))
+		case "old:7b": // Servers before the capabilities field
+			_, _ = w.Write([]byte(`{"template":"{{ if .Tools }}[TOOLS]{{ end }}{{ .Prompt }}"}`))
+		default:
+			http.Error(w, `{"error":"model not found"}`, http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	for model, wantTools := range map[string]bool{"qwen3:8b": true, "gemma:2b": false, "old:7b": true} {
+		caps, err := ProbeCapabilities(ctx, srv.URL+"/v1/", model)
+		if err != nil {
+			t.Fatalf("ProbeCapabilities(%s): %v", model, err)
+		}
+		if caps.Tools != wantTools || !caps.Completion {
+			t.Errorf("ProbeCapabilities(%s) = %+v, want tools %v", model, caps, wantTools)
+		}
+	}
+	if _, err := ProbeCapabilities(ctx, srv.URL, "missing"); err == nil {
+		t.Error("probing a missing model succeeded")
+	}
+
+	before := requests
+	if caps, _ := ProbeCapabilities(ctx, srv.URL, "qwen3:8b"); !caps.Thinking || requests != before {
+		t.Errorf("second probe: caps = %+v, %d requests; want the cached result", caps, requests-before)
+	}
+}
+
+func TestNormalizeBaseURL(t *testing.T) {
+	for in, want := range map[string]string{
+		"":                           DefaultBaseURL,
+		"http://gpu-box:11434/":      "http://gpu-box:11434",
+		"http://gpu-box:11434/v1":    "http://gpu-box:11434",
+		"http://gpu-box:11434/v1/":   "http://gpu-box:11434",
+		"https://ollama.example.com": "https://ollama.example.com",
+	} {
+		if got := NormalizeBaseURL(in); got != want {
+			t.Errorf("NormalizeBaseURL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Embedder embeds text with the /embeddings endpoint of OpenAI or any
+// OpenAI-compatible API.
+type Embedder struct {
+	client *openai.Client
+	model  string
+}
+
+// NewEmbedder creates an embedder for modelName.
+func NewEmbedder(client *openai.Client, modelName string) *Embedder {
+	return &Embedder{client: client, model: modelName}
+}
+
+// Name returns the embedding model.
+func (e *Embedder) Name() string {
+	return e.model
+}
+
+// Embed returns one vector per text, in order.
+func (e *Embedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	resp, err := e.client.CreateEmbeddings(ctx, openai.EmbeddingRequestStrings{
+		Input: texts,
+		Model: openai.EmbeddingModel(e.model),
+	})
+	if err != nil {
+		return nil, wrapOpenAIError(err)
+	}
+	vectors := make([][]float32, len(texts))
+	for _, d := range resp.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding response has index %d for %d inputs", d.Index, len(texts))
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	for i, v := range vectors {
+		if len(v) == 0 {
+			return nil, fmt.Errorf("embedding response has no vector for input %d", i)
+		}
+	}
+	return vectors, nil
+}
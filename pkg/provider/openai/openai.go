@@ -147,6 +147,7 @@ func (p *Provider) GenerateContent(ctx context.Context, req *model.LLMRequest, s
 			// ends (io.EOF) without any finish_reason, the response was truncated
 			// — typically by a gateway timeout or connection drop.
 			finishReasonSeen := false
+			var lastFinishReason openai.FinishReason
 
 			// Capture token usage from the final stream chunk (sent when
 			// StreamOptions.IncludeUsage is true).
@@ -177,13 +178,16 @@ func (p *Provider) GenerateContent(ctx context.Context, req *model.LLMRequest, s
 						yield(nil, fmt.Errorf("LLM stream ended without a finish_reason — the response was likely truncated by a gateway timeout or connection drop"))
 						return
 					}
-					// Normal completion: emit aggregated response at stream end
-					if len(finalParts) > 0 {
+					// Normal completion: emit aggregated response at stream end.
+					// A filtered response may have no text, but the finish
+					// reason must still reach the caller.
+					if len(finalParts) > 0 || lastFinishReason == openai.FinishReasonContentFilter {
 						yield(&model.LLMResponse{
 							Content: &genai.Content{
 								Role:  "model",
 								Parts: finalParts,
 							},
+							FinishReason:  finishReason(lastFinishReason),
 							UsageMetadata: streamUsage,
 						}, nil)
 					}
@@ -212,6 +216,7 @@ func (p *Provider) GenerateContent(ctx context.Context, req *model.LLMRequest, s
 					// completed its response normally (not a premature disconnect).
 					if choice.FinishReason != "" {
 						finishReasonSeen = true
+						lastFinishReason = choice.FinishReason
 					}
 
 					// Accumulate reasoning_content (DeepSeek thinking mode).
@@ -840,6 +845,7 @@ func (p *Provider) toLLMResponse(resp openai.ChatCompletionResponse) *model.LLMR
 			Role:  "model",
 			Parts: parts,
 		},
+		FinishReason: finishReason(choice.FinishReason),
 		UsageMetadata: &genai.GenerateContentResponseUsageMetadata{
 			PromptTokenCount:     int32(resp.Usage.PromptTokens),
 			CandidatesTokenCount: int32(resp.Usage.CompletionTokens),
@@ -866,9 +872,25 @@ func (p *Provider) toLLMResponseStream(resp openai.ChatCompletionStreamResponse)
 			Role:  "model",
 			Parts: parts,
 		},
+		FinishReason: finishReason(choice.FinishReason),
 	}
 }
 
+// finishReason maps the OpenAI finish reasons callers act on to genai's.
+// content_filter becomes SAFETY so blocked responses are detected the same
+// way for every provider.
+func finishReason(reason openai.FinishReason) genai.FinishReason {
+	switch reason {
+	case openai.FinishReasonContentFilter:
+		return genai.FinishReasonSafety
+	case openai.FinishReasonLength:
+		return genai.FinishReasonMaxTokens
+	case openai.FinishReasonStop:
+		return genai.FinishReasonStop
+	}
+	return ""
+}
+
 // wrapOpenAIError converts go-openai library errors into structured LLMError
 // types that support retry classification. The library returns *APIError for
 // HTTP-level failures and *RequestError for transport-level failures.
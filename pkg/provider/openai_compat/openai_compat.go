@@ -22,6 +22,12 @@ type Provider struct {
 
 // NewProvider creates a new OpenAI Compatible provider.
 func NewProvider(apiKey, baseURL, modelName string, debug bool) model.LLM {
+	return NewProviderWithClient(apiKey, baseURL, modelName, debug, nil)
+}
+
+// NewProviderWithClient creates an OpenAI Compatible provider that sends
+// requests through httpClient. A nil client uses the go-openai default.
+func NewProviderWithClient(apiKey, baseURL, modelName string, debug bool, httpClient *http.Client) model.LLM {
 	config := openai.DefaultConfig(apiKey)
 
 	// Ensure baseURL has /v1 suffix
@@ -36,9 +42,16 @@ func NewProvider(apiKey, baseURL, modelName string, debug bool) model.LLM {
 		config.BaseURL = baseURL
 	}
 
+	if httpClient != nil {
+		config.HTTPClient = httpClient
+	}
 	if debug {
+		base := http.DefaultTransport
+		if httpClient != nil && httpClient.Transport != nil {
+			base = httpClient.Transport
+		}
 		config.HTTPClient = &http.Client{
-			Transport: &debugHTTPTransport{base: http.DefaultTransport},
+			Transport: &debugHTTPTransport{base: base},
 		}
 	}
 
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -29,6 +30,12 @@ type ModelMetadata struct {
 		Prompt     string `json:"prompt"`
 		Completion string `json:"completion"`
 	} `json:"pricing"`
+	SupportedParameters []string `json:"supported_parameters"` // e.g. tools, response_format
+}
+
+// Supports reports whether the model accepts a request parameter, e.g. tools.
+func (m ModelMetadata) Supports(param string) bool {
+	return slices.Contains(m.SupportedParameters, param)
 }
 
 // ModelsResponse represents the API response for models
@@ -0,0 +1,175 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/adk/model"
+)
+
+// Capabilities describes what the models of a provider can do, so callers
+// can choose an approach before the first request instead of reacting to
+// the errors of a failed one.
+type Capabilities struct {
+	Tools            bool `json:"tools"`             // Native function calling
+	Streaming        bool `json:"streaming"`         // Streamed responses
+	StructuredOutput bool `json:"structured_output"` // JSON mode or response schemas
+}
+
+// Params are the settings a provider creates a model from.
+type Params struct {
+	Instance   config.ProviderConfig // Instance settings, with Env fallbacks applied
+	Model      string                // Requested model; may be empty
+	HTTPClient *http.Client          // Per-instance proxy / TLS client; nil for the shared transport
+	Debug      bool                  // Verbose HTTP logging, from SetDebugMode
+}
+
+// Registration describes a provider type. Providers register themselves
+// with Register from an init function; GetProvider, ListModelsForProvider
+// and TestProviderConnection look them up by type.
+type Registration struct {
+	Type         string            // Type name used in config, e.g. "openai"
+	Aliases      []string          // Other accepted type names, e.g. "google_genai"
+	DisplayName  string            // Name shown in the CLI and UI
+	Capabilities Capabilities      // What the provider's models support
+	Env          map[string]string // Instance fields read from the environment when unset, e.g. api_key: OPENAI_API_KEY
+	Required     []string          // Fields TestProviderConnection requires
+
+	// New creates a model client.
+	New func(ctx context.Context, p Params) (model.LLM, error)
+	// ListModels lists the models of an instance.
+	ListModels func(ctx context.Context, instance config.ProviderConfig) ([]string, error)
+	// ModelCapabilities refines Capabilities for one model, for providers
+	// whose models differ. ok false keeps the provider's capabilities.
+	ModelCapabilities func(ctx context.Context, p Params) (caps Capabilities, ok bool)
+	// NewEmbedder creates an embedder, for providers with embedding
+	// models; p.Model empty means the provider's default.
+	NewEmbedder func(ctx context.Context, p Params) (EmbeddingProvider, error)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]*Registration{}
+)
+
+// Register adds a provider type. It panics when the type or an alias is
+// already registered, or when New or ListModels is missing.
+func Register(r Registration) {
+	if r.Type == "" || r.New == nil || r.ListModels == nil {
+		panic("provider: Register needs Type, New and ListModels")
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	for _, name := range append([]string{r.Type}, r.Aliases...) {
+		if _, dup := registry[name]; dup {
+			panic(fmt.Sprintf("provider: type %q registered twice", name))
+		}
+		registry[name] = &r
+	}
+	if r.DisplayName != "" {
+		ProviderDisplayNames[r.Type] = r.DisplayName
+	}
+}
+
+// lookup returns the registration of a provider type or alias.
+func lookup(providerType string) (*Registration, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	r, ok := registry[providerType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported provider type: %s", providerType)
+	}
+	return r, nil
+}
+
+// RegisteredTypes returns the registered provider types, without aliases,
+// sorted.
+func RegisteredTypes() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	var types []string
+	for name, r := range registry {
+		if name == r.Type {
+			types = append(types, name)
+		}
+	}
+	sort.Strings(types)
+	return types
+}
+
+// withEnv returns a copy of instance with the unset Env fields read from
+// the environment.
+func (r *Registration) withEnv(instance config.ProviderConfig) config.ProviderConfig {
+	resolved := make(config.ProviderConfig, len(instance)+len(r.Env))
+	for k, v := range instance {
+		resolved[k] = v
+	}
+	for field, env := range r.Env {
+		if resolved[field] == "" {
+			resolved[field] = os.Getenv(env)
+		}
+	}
+	return resolved
+}
+
+// instanceRegistration resolves a configured provider instance to its
+// name, settings and registration.
+func instanceRegistration(instanceName string, cfg *config.AppConfig) (string, config.ProviderConfig, *Registration, error) {
+	resolvedName, instance, exists := resolveProviderInstance(instanceName, cfg)
+	if !exists {
+		return "", nil, nil, fmt.Errorf("provider instance '%s' not found", instanceName)
+	}
+	providerType := config.GetProviderType(resolvedName, instance)
+	if providerType == "" {
+		return "", nil, nil, fmt.Errorf("provider instance '%s' has no 'type' field and name is not a known provider type", resolvedName)
+	}
+	r, err := lookup(providerType)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return resolvedName, r.withEnv(instance), r, nil
+}
+
+// CapabilitiesFor returns the capabilities of modelName on a configured
+// provider instance: the model's when its provider can tell, else the
+// provider's. ok is false when the instance or its type is unknown.
+func CapabilitiesFor(ctx context.Context, instanceName, modelName string, cfg *config.AppConfig) (Capabilities, bool) {
+	if cfg == nil {
+		return Capabilities{}, false
+	}
+	_, instance, r, err := instanceRegistration(instanceName, cfg)
+	if err != nil {
+		return Capabilities{}, false
+	}
+	if r.ModelCapabilities != nil && modelName != "" {
+		if caps, ok := r.ModelCapabilities(ctx, Params{Instance: instance, Model: modelName}); ok {
+			return caps, true
+		}
+	}
+	return r.Capabilities, true
+}
+
+// requireFields returns an error naming the fields of params that are
+// empty.
+func requireFields(params map[string]string, fields []string) error {
+	var missing []string
+	for _, f := range fields {
+		if params[f] == "" {
+			missing = append(missing, f)
+		}
+	}
+	switch len(missing) {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf("%s is required", missing[0])
+	default:
+		return fmt.Errorf("%s and %s are required", strings.Join(missing[:len(missing)-1], ", "), missing[len(missing)-1])
+	}
+}
@@ -0,0 +1,81 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/config"
+	"google.golang.org/adk/model"
+)
+
+func TestRegistry(t *testing.T) {
+	var gotInstance config.ProviderConfig
+	Register(Registration{
+		Type:         "registry_test",
+		Aliases:      []string{"registry_test_alias"},
+		DisplayName:  "Registry Test",
+		Capabilities: Capabilities{Streaming: true},
+		Env:          map[string]string{"api_key": "REGISTRY_TEST_KEY"},
+		Required:     []string{"api_key", "base_url"},
+		New: func(_ context.Context, p Params) (model.LLM, error) {
+			gotInstance = p.Instance
+			return nil, nil
+		},
+		ListModels: func(context.Context, config.ProviderConfig) ([]string, error) {
+			return []string{"small", "large"}, nil
+		},
+		ModelCapabilities: func(_ context.Context, p Params) (Capabilities, bool) {
+			return Capabilities{Tools: true, Streaming: true}, p.Model == "large"
+		},
+	})
+	t.Cleanup(func() {
+		registryMu.Lock()
+		delete(registry, "registry_test")
+		delete(registry, "registry_test_alias")
+		registryMu.Unlock()
+		delete(ProviderDisplayNames, "registry_test")
+	})
+	t.Setenv("REGISTRY_TEST_KEY", "from-env")
+	cfg := &config.AppConfig{Providers: map[string]config.ProviderConfig{
+		"mine":  {"type": "registry_test_alias"},
+		"other": {"type": "nonexistent"},
+	}}
+	ctx := context.Background()
+
+	if GetProviderDisplayName("registry_test") != "Registry Test" {
+		t.Error("display name not registered")
+	}
+	if _, err := GetProvider(ctx, "mine", "small", cfg); err != nil || gotInstance["api_key"] != "from-env" {
+		t.Errorf("GetProvider via alias: err = %v, instance = %v; want the env fallback applied", err, gotInstance)
+	}
+	if _, err := GetProvider(ctx, "other", "x", cfg); err == nil || !strings.Contains(err.Error(), "unsupported provider type") {
+		t.Errorf("unknown type: err = %v", err)
+	}
+
+	if caps, ok := CapabilitiesFor(ctx, "mine", "small", cfg); !ok || caps.Tools {
+		t.Errorf("CapabilitiesFor(small) = %+v, %v; want the provider's, without tools", caps, ok)
+	}
+	if caps, ok := CapabilitiesFor(ctx, "mine", "large", cfg); !ok || !caps.Tools {
+		t.Errorf("CapabilitiesFor(large) = %+v, %v; want the model's, with tools", caps, ok)
+	}
+	if _, ok := CapabilitiesFor(ctx, "missing", "", cfg); ok {
+		t.Error("CapabilitiesFor reported an unknown instance")
+	}
+
+	// The connection test takes raw settings: no env fallback, all required fields
+	if _, err := TestProviderConnection(ctx, "registry_test", map[string]string{}); err == nil || err.Error() != "api_key and base_url are required" {
+		t.Errorf("TestProviderConnection err = %v", err)
+	}
+	if models, err := TestProviderConnection(ctx, "registry_test", map[string]string{"api_key": "k", "base_url": "u"}); err != nil || len(models) != 2 {
+		t.Errorf("TestProviderConnection = %v, %v", models, err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a type twice did not panic")
+		}
+	}()
+	Register(Registration{Type: "registry_test_alias", New: func(context.Context, Params) (model.LLM, error) { return nil, nil },
+		ListModels: func(context.Context, config.ProviderConfig) ([]string, error) { return nil, nil }})
+}
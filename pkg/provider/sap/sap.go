@@ -126,6 +126,13 @@ func NewProvider(ctx context.Context, modelName string) (model.LLM, error) {
 
 // NewProviderWithConfig creates a new SAP AI Core provider with explicit configuration.
 func NewProviderWithConfig(ctx context.Context, modelName, clientID, clientSecret, authURL, baseURL, resourceGroup string) (model.LLM, error) {
+	return NewProviderWithTransport(ctx, httpool.Transport(), modelName, clientID, clientSecret, authURL, baseURL, resourceGroup)
+}
+
+// NewProviderWithTransport creates an SAP AI Core provider whose token,
+// deployment and inference requests all go through base (e.g. a transport
+// configured with a proxy or private CA).
+func NewProviderWithTransport(ctx context.Context, base http.RoundTripper, modelName, clientID, clientSecret, authURL, baseURL, resourceGroup string) (model.LLM, error) {
 	if !strings.HasSuffix(baseURL, "/v2") {
 		if strings.HasSuffix(baseURL, "/") {
 			baseURL += "v2"
@@ -135,13 +142,13 @@ func NewProviderWithConfig(ctx context.Context, modelName, clientID, clientSecre
 	}
 
 	// Resolve deployment ID
-	deploymentID, err := resolveDeploymentIDWithConfig(ctx, modelName, clientID, clientSecret, authURL, baseURL, resourceGroup)
+	deploymentID, err := resolveDeploymentIDWithConfig(ctx, base, modelName, clientID, clientSecret, authURL, baseURL, resourceGroup)
 	if err != nil {
 		return nil, err
 	}
 
 	transport := &sapTransport{
-		base:          httpool.Transport(),
+		base:          base,
 		clientID:      clientID,
 		clientSecret:  clientSecret,
 		authURL:       authURL,
@@ -178,14 +185,14 @@ func NewProviderWithConfig(ctx context.Context, modelName, clientID, clientSecre
 }
 
 // resolveDeploymentIDWithConfig finds the deployment ID for a given model name using explicit config.
-func resolveDeploymentIDWithConfig(ctx context.Context, modelName, clientID, clientSecret, authURL, baseURL, resourceGroup string) (string, error) {
+func resolveDeploymentIDWithConfig(ctx context.Context, base http.RoundTripper, modelName, clientID, clientSecret, authURL, baseURL, resourceGroup string) (string, error) {
 	// Check map first
 	if mapped, ok := ModelIDMap[modelName]; ok {
 		modelName = mapped
 	}
 
 	t := &sapTransport{
-		base:          http.DefaultTransport,
+		base:          base,
 		clientID:      clientID,
 		clientSecret:  clientSecret,
 		authURL:       authURL,
@@ -208,7 +215,7 @@ func resolveDeploymentIDWithConfig(ctx context.Context, modelName, clientID, cli
 	req.Header.Set("Authorization", "Bearer "+token)
 	req.Header.Set("AI-Resource-Group", resourceGroup)
 
-	client := httpool.Client(10 * time.Second)
+	client := &http.Client{Transport: base, Timeout: 10 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
 		return "", err
@@ -324,7 +331,7 @@ func (t *sapTransport) getToken() (string, error) {
 	}
 	authReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	client := httpool.Client(10 * time.Second)
+	client := &http.Client{Transport: t.base, Timeout: 10 * time.Second}
 	resp, err := client.Do(authReq)
 	if err != nil {
 		return "", err
@@ -426,7 +433,7 @@ func ResolveDeploymentID(ctx context.Context, modelName string) (string, error)
 
 	// We need a temporary transport to get the token
 	t := &sapTransport{
-		base:          http.DefaultTransport,
+		base:          httpool.Transport(),
 		clientID:      os.Getenv("AICORE_CLIENT_ID"),
 		clientSecret:  os.Getenv("AICORE_CLIENT_SECRET"),
 		authURL:       os.Getenv("AICORE_AUTH_URL"),
@@ -497,7 +504,7 @@ func ResolveDeploymentID(ctx context.Context, modelName string) (string, error)
 // ListModels fetches the list of available models from running deployments.
 func ListModels(ctx context.Context, clientID, clientSecret, authURL, baseURL, resourceGroup string) ([]string, error) {
 	t := &sapTransport{
-		base:          http.DefaultTransport,
+		base:          httpool.Transport(),
 		clientID:      clientID,
 		clientSecret:  clientSecret,
 		authURL:       authURL,
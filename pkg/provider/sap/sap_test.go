@@ -439,6 +439,7 @@ func TestResolveDeploymentID_Success(t *testing.T) {
 
 	id, err := resolveDeploymentIDWithConfig(
 		context.Background(),
+		http.DefaultTransport,
 		"gpt-4o",
 		"id", "secret",
 		tokenSrv.URL,
@@ -470,6 +471,7 @@ func TestResolveDeploymentID_NotFound(t *testing.T) {
 
 	_, err := resolveDeploymentIDWithConfig(
 		context.Background(),
+		http.DefaultTransport,
 		"nonexistent-model",
 		"id", "secret",
 		tokenSrv.URL,
@@ -518,6 +520,7 @@ func TestResolveDeploymentID_ModelIDMapping(t *testing.T) {
 	// "gpt-4o" is in ModelIDMap and maps to "gpt-4o"
 	id, err := resolveDeploymentIDWithConfig(
 		context.Background(),
+		http.DefaultTransport,
 		"gpt-4o",
 		"id", "secret",
 		tokenSrv.URL,
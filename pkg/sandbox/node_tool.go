@@ -363,6 +363,8 @@ var containerTools = map[string]bool{
 	"file_tree":                 true,
 	"grep_search":               true,
 	"find_files":                true,
+	"list_directory":            true,
+	"glob":                      true,
 	"repo_map":                  true,
 	"code_definition":           true,
 	"code_references":           true,
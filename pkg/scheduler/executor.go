@@ -52,7 +52,11 @@ type HeadlessRunConfig struct {
 	ProviderName string
 	ModelName    string
 	Parameters   map[string]string
+	Variables    map[string]string // values for the flow's variables: section
 	DebugMode    bool
+	// DenyApprovals denies tool calls instead of approving them; the
+	// flow's tools_auto_approval still applies.
+	DenyApprovals bool
 }
 
 // Executor runs scheduled jobs. It uses an injected headless runner for routine
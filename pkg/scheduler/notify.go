@@ -0,0 +1,88 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// failureWebhookTimeout bounds a failure notification so a slow endpoint
+// cannot hold up result delivery.
+const failureWebhookTimeout = 10 * time.Second
+
+// FailureNotification is the JSON body posted to the failure webhook when a
+// scheduled job fails.
+type FailureNotification struct {
+	Job                 string    `json:"job"`
+	JobID               string    `json:"job_id"`
+	Mode                JobMode   `json:"mode"`
+	Flow                string    `json:"flow,omitempty"`
+	Error               string    `json:"error"`
+	Output              string    `json:"output,omitempty"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	Time                time.Time `json:"time"`
+}
+
+// WithFailureWebhook wraps deliver so that failed runs are also posted to
+// the webhook at url (see FailureNotification). Webhook errors are logged
+// and do not affect delivery. An empty url returns deliver unchanged.
+func WithFailureWebhook(deliver DeliverFunc, url string, logger *log.Logger) DeliverFunc {
+	if url == "" {
+		return deliver
+	}
+	if logger == nil {
+		logger = log.Default()
+	}
+	client := &http.Client{Timeout: failureWebhookTimeout}
+	return func(ctx context.Context, job *Job, result string, execErr error) error {
+		if execErr != nil {
+			if err := postFailure(ctx, client, url, job, result, execErr); err != nil {
+				logger.Printf("[scheduler] Failure webhook for job %q failed: %v", job.Name, err)
+			} else {
+				logger.Printf("[scheduler] Failure webhook sent for job %q", job.Name)
+			}
+		}
+		if deliver == nil {
+			return nil
+		}
+		return deliver(ctx, job, result, execErr)
+	}
+}
+
+func postFailure(ctx context.Context, client *http.Client, url string, job *Job, result string, execErr error) error {
+	body, err := json.Marshal(FailureNotification{
+		Job:   job.Name,
+		JobID: job.ID,
+		Mode:  job.Mode,
+		Flow:  job.Payload.Flow,
+		Error: execErr.Error(),
+		// Output is truncated like channel deliveries
+		Output: truncateResult(result),
+		// The job passed to delivery still holds the count before this run
+		ConsecutiveFailures: job.ConsecutiveFailures + 1,
+		Time:                time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithFailureWebhook(t *testing.T) {
+	var got []FailureNotification
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n FailureNotification
+		if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+			t.Errorf("invalid body: %v", err)
+		}
+		got = append(got, n)
+	}))
+	defer srv.Close()
+
+	delivered := 0
+	deliver := WithFailureWebhook(func(ctx context.Context, job *Job, result string, err error) error {
+		delivered++
+		return nil
+	}, srv.URL, log.New(&bytes.Buffer{}, "", 0))
+
+	job := &Job{ID: "j1", Name: "nightly", Mode: ModeRoutine, Payload: JobPayload{Flow: "report"}, ConsecutiveFailures: 1}
+	if err := deliver(context.Background(), job, "ok", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := deliver(context.Background(), job, "partial", errors.New("boom")); err != nil {
+		t.Fatal(err)
+	}
+
+	if delivered != 2 {
+		t.Errorf("delivered %d times, want 2", delivered)
+	}
+	if len(got) != 1 {
+		t.Fatalf("webhook called %d times, want 1", len(got))
+	}
+	n := got[0]
+	if n.Job != "nightly" || n.Flow != "report" || n.Error != "boom" || n.Output != "partial" || n.ConsecutiveFailures != 2 {
+		t.Errorf("notification = %+v", n)
+	}
+}
+
+func TestWithFailureWebhookErrorDoesNotBlockDelivery(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	var logs bytes.Buffer
+	delivered := false
+	deliver := WithFailureWebhook(func(ctx context.Context, job *Job, result string, err error) error {
+		delivered = true
+		return nil
+	}, srv.URL, log.New(&logs, "", 0))
+
+	if err := deliver(context.Background(), &Job{Name: "x"}, "", errors.New("boom")); err != nil {
+		t.Fatal(err)
+	}
+	if !delivered {
+		t.Error("delivery should still run when the webhook fails")
+	}
+	if !bytes.Contains(logs.Bytes(), []byte("HTTP 500")) {
+		t.Errorf("expected the webhook error to be logged, got %q", logs.String())
+	}
+}
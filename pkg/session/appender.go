@@ -0,0 +1,173 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	adksession "google.golang.org/adk/session"
+)
+
+// BatchAppender is implemented by session services that can persist several
+// events of a session in one call. AppendEvents returns how many of the
+// events were persisted, in order, before an error.
+type BatchAppender interface {
+	AppendEvents(ctx context.Context, sess adksession.Session, events []*adksession.Event) (int, error)
+}
+
+// Retry policy of EventAppender.
+const (
+	appendAttempts = 3
+	appendBackoff  = 50 * time.Millisecond
+)
+
+// EventAppender persists events written outside the runner (e.g. from
+// parallel branches and tool callbacks) to a session service. Appends to a
+// session are made in the order they were submitted, by one worker per
+// session; the events queued while the worker is busy are written as one
+// batch (in one call when the service is a BatchAppender). Failed appends
+// are retried. Append returns once the event is persisted.
+type EventAppender struct {
+	svc adksession.Service
+
+	mu     sync.Mutex
+	queues map[string][]*pendingAppend // By session ID; present while its worker runs
+}
+
+type pendingAppend struct {
+	ctx   context.Context
+	sess  adksession.Session
+	event *adksession.Event
+	done  chan error
+}
+
+// NewEventAppender returns an appender writing to svc.
+func NewEventAppender(svc adksession.Service) *EventAppender {
+	return &EventAppender{svc: svc, queues: make(map[string][]*pendingAppend)}
+}
+
+// sharedAppenders holds the appender of each session service, by service.
+var sharedAppenders sync.Map
+
+// SharedAppender returns the appender of svc shared by all code paths that
+// persist events to it outside the runner, so their appends to a session
+// are ordered with each other.
+func SharedAppender(svc adksession.Service) *EventAppender {
+	if ea, ok := sharedAppenders.Load(svc); ok {
+		return ea.(*EventAppender)
+	}
+	ea, _ := sharedAppenders.LoadOrStore(svc, NewEventAppender(svc))
+	return ea.(*EventAppender)
+}
+
+// Append persists event to sess after the events submitted before it. The
+// append is not cut short when ctx is cancelled: the event is still written
+// so the session stays complete.
+func (ea *EventAppender) Append(ctx context.Context, sess adksession.Session, event *adksession.Event) error {
+	if sess == nil {
+		return fmt.Errorf("session is nil")
+	}
+	p := &pendingAppend{ctx: context.WithoutCancel(ctx), sess: sess, event: event, done: make(chan error, 1)}
+
+	id := sess.ID()
+	ea.mu.Lock()
+	queue, running := ea.queues[id]
+	ea.queues[id] = append(queue, p)
+	ea.mu.Unlock()
+	if !running {
+		go ea.drain(id)
+	}
+	return <-p.done
+}
+
+// drain writes the queued events of a session until none is left.
+func (ea *EventAppender) drain(id string) {
+	for {
+		ea.mu.Lock()
+		batch := ea.queues[id]
+		if len(batch) == 0 {
+			delete(ea.queues, id)
+			ea.mu.Unlock()
+			return
+		}
+		ea.queues[id] = nil // Keep the entry: this worker still runs
+		ea.mu.Unlock()
+
+		ea.write(batch)
+	}
+}
+
+// write persists a batch, in one call when the service supports it and all
+// events go to the same session object. Events the batch call did not
+// persist are appended one by one.
+func (ea *EventAppender) write(batch []*pendingAppend) {
+	if b, ok := ea.svc.(BatchAppender); ok && len(batch) > 1 && sameSession(batch) {
+		events := make([]*adksession.Event, len(batch))
+		for i, p := range batch {
+			events[i] = p.event
+		}
+		n, err := b.AppendEvents(batch[0].ctx, batch[0].sess, events)
+		for _, p := range batch[:n] {
+			p.done <- nil
+		}
+		if err != nil {
+			slog.Debug("batched event append failed, appending the rest one by one", "session", batch[0].sess.ID(), "appended", n, "events", len(batch), "error", err)
+		}
+		batch = batch[n:]
+	}
+	for _, p := range batch {
+		p.done <- ea.appendWithRetry(p)
+	}
+}
+
+func sameSession(batch []*pendingAppend) bool {
+	for _, p := range batch[1:] {
+		if p.sess != batch[0].sess {
+			return false
+		}
+	}
+	return true
+}
+
+// appendWithRetry appends one event. A failed append is retried at once
+// with the session as stored by the service, which helps when the caller
+// holds a wrapped or stale session object, then once more after a pause.
+func (ea *EventAppender) appendWithRetry(p *pendingAppend) error {
+	sess := p.sess
+	var err error
+	for attempt := 1; attempt <= appendAttempts; attempt++ {
+		if err = ea.svc.AppendEvent(p.ctx, sess, p.event); err == nil {
+			return nil
+		}
+		if attempt == 1 {
+			if fresh := ea.refetch(p.ctx, p.sess); fresh != nil {
+				sess = fresh
+			}
+			continue
+		}
+		if attempt < appendAttempts {
+			time.Sleep(appendBackoff)
+		}
+	}
+	return err
+}
+
+func (ea *EventAppender) refetch(ctx context.Context, sess adksession.Session) adksession.Session {
+	if sess.ID() == "" {
+		return nil
+	}
+	appName, userID := sess.AppName(), sess.UserID()
+	if appName == "" {
+		appName = "astonish"
+	}
+	if userID == "" {
+		userID = "console_user"
+	}
+	resp, err := ea.svc.Get(ctx, &adksession.GetRequest{AppName: appName, UserID: userID, SessionID: sess.ID()})
+	if err != nil || resp == nil {
+		return nil
+	}
+	return resp.Session
+}
@@ -0,0 +1,147 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	adksession "google.golang.org/adk/session"
+)
+
+// countingService records the events appended to it and how many appends
+// ran at the same time.
+type countingService struct {
+	adksession.Service
+
+	mu       sync.Mutex
+	inFlight int
+	maxIn    int
+	events   []string
+	batches  int
+	failNext int // Appends that fail before one succeeds
+}
+
+func (s *countingService) AppendEvent(ctx context.Context, sess adksession.Session, event *adksession.Event) error {
+	s.mu.Lock()
+	if s.failNext > 0 {
+		s.failNext--
+		s.mu.Unlock()
+		return errors.New("store busy")
+	}
+	s.inFlight++
+	s.maxIn = max(s.maxIn, s.inFlight)
+	s.mu.Unlock()
+
+	time.Sleep(time.Millisecond)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inFlight--
+	s.events = append(s.events, event.ID)
+	return nil
+}
+
+// batchService also appends several events in one call.
+type batchService struct {
+	*countingService
+}
+
+func (s batchService) AppendEvents(ctx context.Context, sess adksession.Session, events []*adksession.Event) (int, error) {
+	s.mu.Lock()
+	s.batches++
+	s.mu.Unlock()
+	for i, e := range events {
+		if err := s.AppendEvent(ctx, sess, e); err != nil {
+			return i, err
+		}
+	}
+	return len(events), nil
+}
+
+func newTestSession(t *testing.T, svc adksession.Service) adksession.Session {
+	t.Helper()
+	resp, err := svc.Create(context.Background(), &adksession.CreateRequest{AppName: "astonish", UserID: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.Session
+}
+
+func TestEventAppenderOrdersConcurrentAppends(t *testing.T) {
+	for name, wrap := range map[string]func(*countingService) adksession.Service{
+		"one by one": func(s *countingService) adksession.Service { return s },
+		"batched":    func(s *countingService) adksession.Service { return batchService{s} },
+	} {
+		t.Run(name, func(t *testing.T) {
+			counting := &countingService{Service: adksession.InMemoryService()}
+			svc := wrap(counting)
+			sess := newTestSession(t, svc)
+			ea := NewEventAppender(svc)
+
+			const writers, perWriter = 8, 10
+			var wg sync.WaitGroup
+			for w := range writers {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := range perWriter {
+						if err := ea.Append(context.Background(), sess, &adksession.Event{ID: fmt.Sprintf("%d-%d", w, i)}); err != nil {
+							t.Errorf("Append: %v", err)
+						}
+					}
+				}()
+			}
+			wg.Wait()
+
+			if len(counting.events) != writers*perWriter {
+				t.Fatalf("persisted %d events, want %d", len(counting.events), writers*perWriter)
+			}
+			if counting.maxIn != 1 {
+				t.Errorf("%d appends to the session ran at once, want 1", counting.maxIn)
+			}
+			// Each writer's events are persisted in the order it submitted them
+			next := make(map[int]int)
+			for _, id := range counting.events {
+				var w, i int
+				fmt.Sscanf(id, "%d-%d", &w, &i)
+				if i != next[w] {
+					t.Fatalf("writer %d: event %d persisted before event %d", w, i, next[w])
+				}
+				next[w]++
+			}
+			if name == "batched" && counting.batches == 0 {
+				t.Error("queued events were not written in a batch")
+			}
+		})
+	}
+}
+
+func TestEventAppenderRetries(t *testing.T) {
+	svc := &countingService{Service: adksession.InMemoryService(), failNext: 2}
+	sess := newTestSession(t, svc)
+
+	if err := NewEventAppender(svc).Append(context.Background(), sess, &adksession.Event{ID: "e1"}); err != nil {
+		t.Fatalf("Append after two failures: %v", err)
+	}
+	if len(svc.events) != 1 {
+		t.Errorf("persisted %v, want e1 once", svc.events)
+	}
+
+	svc.failNext = appendAttempts
+	if err := NewEventAppender(svc).Append(context.Background(), sess, &adksession.Event{ID: "e2"}); err == nil {
+		t.Error("expected an error once all attempts failed")
+	}
+}
+
+func TestSharedAppender(t *testing.T) {
+	svc := adksession.InMemoryService()
+	if SharedAppender(svc) != SharedAppender(svc) {
+		t.Error("SharedAppender returned different appenders for one service")
+	}
+	if SharedAppender(svc) == SharedAppender(adksession.InMemoryService()) {
+		t.Error("SharedAppender shared an appender between services")
+	}
+}
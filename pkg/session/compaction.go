@@ -354,6 +354,19 @@ func hasFunctionCall(c *genai.Content) bool {
 	return false
 }
 
+// Summarize returns a summary of contents: the LLM's, or a truncation
+// summary when there is no LLM or it fails.
+func (c *Compactor) Summarize(ctx context.Context, contents []*genai.Content) string {
+	summary, err := c.summarize(ctx, contents)
+	if err != nil || strings.TrimSpace(summary) == "" {
+		if err != nil {
+			slog.Debug("compactor summarization failed, falling back to truncation", "component", "compactor", "error", err)
+		}
+		return c.truncationSummary(contents)
+	}
+	return summary
+}
+
 // summarize uses the LLM to create a concise summary of old messages.
 func (c *Compactor) summarize(ctx context.Context, contents []*genai.Content) (string, error) {
 	if c.LLM == nil {
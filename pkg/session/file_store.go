@@ -292,14 +292,40 @@ func (s *FileStore) AppendEvent(ctx context.Context, curSession adksession.Sessi
 		return nil
 	}
 
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.appendEventLocked(curSession, event)
+}
+
+// AppendEvents appends several events of a session in order under one lock.
+// It returns the number of events appended.
+func (s *FileStore) AppendEvents(ctx context.Context, curSession adksession.Session, events []*adksession.Event) (int, error) {
+	if curSession == nil {
+		return 0, fmt.Errorf("session is nil")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, event := range events {
+		if event == nil {
+			return i, fmt.Errorf("event is nil")
+		}
+		if event.Partial {
+			continue
+		}
+		if err := s.appendEventLocked(curSession, event); err != nil {
+			return i, err
+		}
+	}
+	return len(events), nil
+}
+
+// appendEventLocked applies and persists one event; s.mu must be held.
+func (s *FileStore) appendEventLocked(curSession adksession.Session, event *adksession.Event) error {
 	sess, ok := curSession.(*fileSession)
 	if !ok {
 		return fmt.Errorf("unexpected session type %T (expected *fileSession)", curSession)
 	}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	// Look up the stored session
 	storedSession, ok := s.sessions[sess.id]
 	if !ok {
@@ -0,0 +1,232 @@
+package session
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"maps"
+	"strings"
+
+	"github.com/SAP/astonish/pkg/credentials"
+	adksession "google.golang.org/adk/session"
+)
+
+// sensitivePrefix marks an encrypted state value: the rest is the base64
+// AES-GCM ciphertext of the value's JSON encoding.
+const sensitivePrefix = "enc:v1:"
+
+// SensitiveService wraps a session service so that the values of sensitive
+// state keys are encrypted before they reach the underlying store (state,
+// events and transcripts) and decrypted again when read through the
+// sessions it returns. Events yielded to the caller keep their plain values;
+// masking them for display is up to the caller.
+type SensitiveService struct {
+	adksession.Service
+	keys map[string]bool
+	key  []byte
+}
+
+// NewSensitiveService wraps inner for the given sensitive keys. It returns
+// inner unchanged when there are none. A nil key (no credential store)
+// selects a random per-process key: values are still never stored in the
+// clear, but cannot be read back by another process.
+func NewSensitiveService(inner adksession.Service, keys map[string]bool, key []byte) (adksession.Service, error) {
+	if len(keys) == 0 {
+		return inner, nil
+	}
+	if key == nil {
+		var err error
+		if key, err = credentials.GenerateKey(); err != nil {
+			return nil, fmt.Errorf("generate state key: %w", err)
+		}
+	}
+	return &SensitiveService{Service: inner, keys: keys, key: key}, nil
+}
+
+func (s *SensitiveService) Create(ctx context.Context, req *adksession.CreateRequest) (*adksession.CreateResponse, error) {
+	if len(req.State) > 0 {
+		state, err := s.encryptState(req.State)
+		if err != nil {
+			return nil, err
+		}
+		r := *req
+		r.State = state
+		req = &r
+	}
+	resp, err := s.Service.Create(ctx, req)
+	if err != nil || resp == nil || resp.Session == nil {
+		return resp, err
+	}
+	return &adksession.CreateResponse{Session: s.wrap(resp.Session)}, nil
+}
+
+func (s *SensitiveService) Get(ctx context.Context, req *adksession.GetRequest) (*adksession.GetResponse, error) {
+	resp, err := s.Service.Get(ctx, req)
+	if err != nil || resp == nil || resp.Session == nil {
+		return resp, err
+	}
+	return &adksession.GetResponse{Session: s.wrap(resp.Session)}, nil
+}
+
+func (s *SensitiveService) List(ctx context.Context, req *adksession.ListRequest) (*adksession.ListResponse, error) {
+	resp, err := s.Service.List(ctx, req)
+	if err != nil || resp == nil {
+		return resp, err
+	}
+	sessions := make([]adksession.Session, len(resp.Sessions))
+	for i, sess := range resp.Sessions {
+		sessions[i] = s.wrap(sess)
+	}
+	return &adksession.ListResponse{Sessions: sessions}, nil
+}
+
+// AppendEvent stores a copy of event whose sensitive state values are
+// encrypted. The caller's event is left untouched.
+func (s *SensitiveService) AppendEvent(ctx context.Context, sess adksession.Session, event *adksession.Event) error {
+	if wrapped, ok := sess.(*sensitiveSession); ok {
+		sess = wrapped.Session
+	}
+	if event == nil || !s.hasSensitive(event.Actions.StateDelta) {
+		return s.Service.AppendEvent(ctx, sess, event)
+	}
+	delta, err := s.encryptState(event.Actions.StateDelta)
+	if err != nil {
+		return err
+	}
+	stored := *event
+	stored.Actions.StateDelta = delta
+	return s.Service.AppendEvent(ctx, sess, &stored)
+}
+
+// AppendEvents stores encrypted copies of events in one call when the
+// underlying service is a BatchAppender, else one by one.
+func (s *SensitiveService) AppendEvents(ctx context.Context, sess adksession.Session, events []*adksession.Event) (int, error) {
+	if wrapped, ok := sess.(*sensitiveSession); ok {
+		sess = wrapped.Session
+	}
+	b, ok := s.Service.(BatchAppender)
+	if !ok {
+		for i, event := range events {
+			if err := s.AppendEvent(ctx, sess, event); err != nil {
+				return i, err
+			}
+		}
+		return len(events), nil
+	}
+	stored := make([]*adksession.Event, len(events))
+	for i, event := range events {
+		stored[i] = event
+		if event == nil || !s.hasSensitive(event.Actions.StateDelta) {
+			continue
+		}
+		delta, err := s.encryptState(event.Actions.StateDelta)
+		if err != nil {
+			return 0, err
+		}
+		e := *event
+		e.Actions.StateDelta = delta
+		stored[i] = &e
+	}
+	return b.AppendEvents(ctx, sess, stored)
+}
+
+func (s *SensitiveService) wrap(sess adksession.Session) adksession.Session {
+	if _, ok := sess.(*sensitiveSession); ok {
+		return sess
+	}
+	return &sensitiveSession{Session: sess, svc: s}
+}
+
+func (s *SensitiveService) hasSensitive(delta map[string]any) bool {
+	for key := range delta {
+		if s.keys[key] {
+			return true
+		}
+	}
+	return false
+}
+
+// encryptState returns a copy of state with the sensitive values encrypted.
+func (s *SensitiveService) encryptState(state map[string]any) (map[string]any, error) {
+	out := maps.Clone(state)
+	for key, val := range state {
+		if !s.keys[key] || val == nil {
+			continue
+		}
+		if str, ok := val.(string); ok && strings.HasPrefix(str, sensitivePrefix) {
+			continue
+		}
+		plain, err := json.Marshal(val)
+		if err != nil {
+			return nil, fmt.Errorf("encode sensitive state key %q: %w", key, err)
+		}
+		sealed, err := credentials.Encrypt(plain, s.key)
+		if err != nil {
+			return nil, fmt.Errorf("encrypt sensitive state key %q: %w", key, err)
+		}
+		out[key] = sensitivePrefix + base64.StdEncoding.EncodeToString(sealed)
+	}
+	return out, nil
+}
+
+// decrypt returns the plain value of an encrypted state value. Values that
+// are not encrypted are returned unchanged.
+func (s *SensitiveService) decrypt(val any) (any, error) {
+	str, ok := val.(string)
+	if !ok || !strings.HasPrefix(str, sensitivePrefix) {
+		return val, nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(str, sensitivePrefix))
+	if err != nil {
+		return nil, fmt.Errorf("decode sensitive state value: %w", err)
+	}
+	plain, err := credentials.Decrypt(sealed, s.key)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt sensitive state value: %w", err)
+	}
+	var out any
+	if err := json.Unmarshal(plain, &out); err != nil {
+		return nil, fmt.Errorf("decode sensitive state value: %w", err)
+	}
+	return out, nil
+}
+
+// sensitiveSession decrypts sensitive values on read.
+type sensitiveSession struct {
+	adksession.Session
+	svc *SensitiveService
+}
+
+func (ss *sensitiveSession) State() adksession.State {
+	return &sensitiveState{State: ss.Session.State(), svc: ss.svc}
+}
+
+type sensitiveState struct {
+	adksession.State
+	svc *SensitiveService
+}
+
+func (st *sensitiveState) Get(key string) (any, error) {
+	val, err := st.State.Get(key)
+	if err != nil || !st.svc.keys[key] {
+		return val, err
+	}
+	return st.svc.decrypt(val)
+}
+
+func (st *sensitiveState) All() iter.Seq2[string, any] {
+	return func(yield func(string, any) bool) {
+		for key, val := range st.State.All() {
+			if st.svc.keys[key] {
+				if plain, err := st.svc.decrypt(val); err == nil {
+					val = plain
+				}
+			}
+			if !yield(key, val) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package session
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	adksession "google.golang.org/adk/session"
+)
+
+func TestSensitiveService_EncryptsAtRest(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	svc, err := NewSensitiveService(store, map[string]bool{"api_token": true}, nil)
+	if err != nil {
+		t.Fatalf("NewSensitiveService() error = %v", err)
+	}
+
+	sess := func() adksession.Session {
+		resp, err := svc.Create(ctx, &adksession.CreateRequest{AppName: "app", UserID: "u"})
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		return resp.Session
+	}()
+
+	event := testEvent("e1", "astonish", "")
+	event.Actions.StateDelta = map[string]any{"api_token": "tok-1234567890", "user": "alice"}
+	if err := svc.AppendEvent(ctx, sess, event); err != nil {
+		t.Fatalf("AppendEvent() error = %v", err)
+	}
+
+	if got := event.Actions.StateDelta["api_token"]; got != "tok-1234567890" {
+		t.Errorf("caller's event was modified: api_token = %v", got)
+	}
+
+	// Through the wrapper the value reads back in the clear
+	resp, err := svc.Get(ctx, &adksession.GetRequest{AppName: "app", UserID: "u", SessionID: sess.ID()})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got, _ := resp.Session.State().Get("api_token"); got != "tok-1234567890" {
+		t.Errorf("wrapped state api_token = %v, want the plain value", got)
+	}
+	if got, _ := resp.Session.State().Get("user"); got != "alice" {
+		t.Errorf("wrapped state user = %v, want alice", got)
+	}
+
+	// The underlying store and its transcript only hold ciphertext
+	raw, err := store.Get(ctx, &adksession.GetRequest{AppName: "app", UserID: "u", SessionID: sess.ID()})
+	if err != nil {
+		t.Fatalf("store Get() error = %v", err)
+	}
+	stored, _ := raw.Session.State().Get("api_token")
+	if s, _ := stored.(string); !strings.HasPrefix(s, sensitivePrefix) {
+		t.Errorf("stored api_token = %v, want an encrypted value", stored)
+	}
+	transcript, err := os.ReadFile(filepath.Join(store.BaseDir(), "app", "u", sess.ID()+".jsonl"))
+	if err != nil {
+		t.Fatalf("read transcript: %v", err)
+	}
+	if strings.Contains(string(transcript), "tok-1234567890") {
+		t.Error("transcript contains the sensitive value in the clear")
+	}
+}
+
+func TestNewSensitiveService_NoKeys(t *testing.T) {
+	inner := adksession.InMemoryService()
+	svc, err := NewSensitiveService(inner, nil, nil)
+	if err != nil {
+		t.Fatalf("NewSensitiveService() error = %v", err)
+	}
+	if svc != inner {
+		t.Error("expected the inner service to be returned unchanged")
+	}
+}
@@ -71,12 +71,15 @@ func internalToolDeclarations() []ToolDeclaration {
 		{Name: "grep_search", Description: "Search for text/regex patterns with context lines, type filters, and glob support", Category: "internal"},
 		{Name: "find_files", Description: "Find files by glob pattern with .gitignore respect and mtime sorting", Category: "internal"},
 		{Name: "edit_file", Description: "Edit a file by finding and replacing text", Category: "internal"},
+		{Name: "list_directory", Description: "List the entries of one directory with type, size and modification time", Category: "internal"},
+		{Name: "glob", Description: "Find paths matching a full glob pattern with ** support", Category: "internal"},
 		{Name: "repo_map", Description: "Build a structural source map using tree-sitter definitions and references", Category: "internal"},
 		{Name: "code_definition", Description: "Find structural definitions of a symbol using tree-sitter", Category: "internal"},
 		{Name: "code_references", Description: "Find structural references to a symbol using tree-sitter", Category: "internal"},
 		{Name: "web_fetch", Description: "Fetch and extract content from a URL", Category: "internal"},
 		{Name: "read_pdf", Description: "Extract text content from a PDF file", Category: "internal"},
 		{Name: "http_request", Description: "Make an HTTP request with full control over method, headers, and body", Category: "internal"},
+		{Name: "save_artifact", Description: "Store a file or text as an artifact of the current flow run", Category: "internal"},
 	}
 }
 
@@ -39,6 +39,9 @@ func EditFile(ctx tool.Context, args EditFileArgs) (EditFileResult, error) {
 	}
 
 	args.Path = expandPath(args.Path)
+	if err := checkPathAllowed(args.Path); err != nil {
+		return EditFileResult{}, err
+	}
 
 	// Must-read-before-edit guard: if the cache is active (has any read entries),
 	// verify that this specific file has been read before allowing edits.
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
@@ -11,10 +12,9 @@ import (
 	"time"
 )
 
-const (
-	defaultCacheFilePath = "/tmp/.astonish_read_cache.json"
-	maxCacheSize         = 100
-)
+const maxCacheSize = 100
+
+var defaultCacheFilePath = filepath.Join(os.TempDir(), ".astonish_read_cache.json")
 
 // cacheFilePath is the path to the read cache file. Tests can override this.
 var cacheFilePath = defaultCacheFilePath
@@ -94,6 +94,9 @@ func FileTree(ctx tool.Context, args FileTreeArgs) (FileTreeResult, error) {
 	if err != nil {
 		return FileTreeResult{}, err
 	}
+	if err := checkPathAllowed(absPath); err != nil {
+		return FileTreeResult{}, err
+	}
 
 	// Verify path exists and is a directory
 	info, err := os.Stat(absPath)
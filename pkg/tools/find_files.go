@@ -58,6 +58,9 @@ func FindFiles(ctx tool.Context, args FindFilesArgs) (FindFilesResult, error) {
 	if err != nil {
 		return FindFilesResult{}, err
 	}
+	if err := checkPathAllowed(absPath); err != nil {
+		return FindFilesResult{}, err
+	}
 
 	// Verify path exists
 	if _, err := os.Stat(absPath); err != nil {
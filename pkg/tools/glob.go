@@ -0,0 +1,118 @@
+package tools
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"google.golang.org/adk/tool"
+)
+
+// GlobArgs defines arguments for the glob tool
+type GlobArgs struct {
+	Pattern    string `json:"pattern" jsonschema:"Glob pattern, absolute or relative to base_dir (e.g. /repo/src/**/*.go, docs/*.md). ** matches any number of directories"`
+	BaseDir    string `json:"base_dir,omitempty" jsonschema:"Directory relative patterns are resolved against (default: current dir)"`
+	MaxResults int    `json:"max_results,omitempty" jsonschema:"Maximum paths to return (default: 500)"`
+}
+
+// GlobResult is the result returned by the glob tool
+type GlobResult struct {
+	Paths  []string `json:"paths"`
+	Total  int      `json:"total"`
+	Capped bool     `json:"capped"`
+}
+
+// Glob returns the files and directories matching a glob pattern, sorted.
+// Unlike find_files, the pattern is a full path pattern: its leading
+// directories without wildcards are where the walk starts.
+func Glob(ctx tool.Context, args GlobArgs) (GlobResult, error) {
+	if args.Pattern == "" {
+		return GlobResult{}, fmt.Errorf("pattern is required")
+	}
+	maxResults := args.MaxResults
+	if maxResults <= 0 {
+		maxResults = 500
+	}
+
+	pattern := expandPath(args.Pattern)
+	if !filepath.IsAbs(pattern) {
+		base := args.BaseDir
+		if base == "" {
+			cwd, err := os.Getwd()
+			if err != nil {
+				return GlobResult{}, err
+			}
+			base = cwd
+		}
+		pattern = filepath.Join(expandPath(base), pattern)
+	}
+	root, rest := splitGlobRoot(filepath.ToSlash(pattern))
+	root = filepath.FromSlash(root)
+	if err := checkPathAllowed(root); err != nil {
+		return GlobResult{}, err
+	}
+	if rest == "" {
+		// No wildcards: the pattern is a plain path
+		if _, err := os.Stat(root); err != nil {
+			return GlobResult{Paths: []string{}}, nil
+		}
+		return GlobResult{Paths: []string{root}, Total: 1}, nil
+	}
+
+	var paths []string
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil // Skip entries we can't access
+		}
+		if path == root {
+			return nil
+		}
+		if d.IsDir() && defaultExclusions[d.Name()] && !strings.Contains(rest, d.Name()) {
+			return filepath.SkipDir
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			slog.Warn("glob: skipping entry", "path", path, "error", err)
+			return nil
+		}
+		if matchDoublestar(rest, rel) {
+			paths = append(paths, path)
+			if len(paths) > maxResults {
+				return filepath.SkipAll
+			}
+		}
+		return nil
+	})
+	if err != nil && err != filepath.SkipAll {
+		return GlobResult{}, err
+	}
+
+	sort.Strings(paths)
+	capped := len(paths) > maxResults
+	if capped {
+		paths = paths[:maxResults]
+	}
+	if paths == nil {
+		paths = []string{}
+	}
+	return GlobResult{Paths: paths, Total: len(paths), Capped: capped}, nil
+}
+
+// splitGlobRoot splits a slash-separated pattern into its leading segments
+// without wildcards and the rest.
+func splitGlobRoot(pattern string) (root, rest string) {
+	segments := strings.Split(pattern, "/")
+	for i, seg := range segments {
+		if strings.ContainsAny(seg, "*?[") {
+			root = strings.Join(segments[:i], "/")
+			if root == "" {
+				root = "/"
+			}
+			return root, strings.Join(segments[i:], "/")
+		}
+	}
+	return pattern, ""
+}
@@ -94,6 +94,9 @@ func GrepSearch(ctx tool.Context, args GrepSearchArgs) (GrepSearchResult, error)
 	if err != nil {
 		return GrepSearchResult{}, err
 	}
+	if err := checkPathAllowed(absPath); err != nil {
+		return GrepSearchResult{}, err
+	}
 
 	patternMode := "literal"
 	if args.Regex {
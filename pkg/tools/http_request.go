@@ -3,8 +3,10 @@ package tools
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"mime"
 	"mime/multipart"
 	"net/http"
@@ -12,8 +14,11 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/SAP/astonish/pkg/config"
+	"github.com/SAP/astonish/pkg/provider/httpool"
 	"google.golang.org/adk/tool"
 )
 
@@ -28,6 +33,84 @@ const (
 // httpReqSkipSSRF is a test hook to bypass SSRF checks for httptest servers.
 var httpReqSkipSSRF bool
 
+// httpReqAllowedDomains restricts http_request to these hosts when non-empty.
+// Set from security.http_request.allowed_domains.
+var (
+	httpReqAllowedDomainsMu     sync.RWMutex
+	httpReqAllowedDomains       []string
+	httpReqAllowedDomainsLoaded bool // Set by SetHTTPAllowedDomains or the first check
+)
+
+// loadHTTPAllowedDomains reads security.http_request.allowed_domains from
+// the app config. Tests replace it.
+var loadHTTPAllowedDomains = func() ([]string, error) {
+	appCfg, err := config.LoadAppConfig()
+	if err != nil || appCfg == nil {
+		return nil, err
+	}
+	return appCfg.Security.HTTPRequest.AllowedDomains, nil
+}
+
+// SetHTTPAllowedDomains sets the domains http_request may call. An entry
+// matches the host itself and its subdomains ("example.com" allows
+// "api.example.com"); "*.example.com" allows only subdomains. An empty list
+// allows every public host.
+func SetHTTPAllowedDomains(domains []string) {
+	normalized := make([]string, 0, len(domains))
+	for _, d := range domains {
+		if d = strings.ToLower(strings.TrimSpace(d)); d != "" {
+			normalized = append(normalized, strings.TrimSuffix(d, "."))
+		}
+	}
+	httpReqAllowedDomainsMu.Lock()
+	httpReqAllowedDomains = normalized
+	httpReqAllowedDomainsLoaded = true
+	httpReqAllowedDomainsMu.Unlock()
+}
+
+// currentHTTPAllowedDomains returns the domain allowlist, loading it from
+// the app config on first use, so requests made without GetInternalTools
+// (ExecuteTool) are restricted too.
+func currentHTTPAllowedDomains() []string {
+	httpReqAllowedDomainsMu.RLock()
+	allowed, loaded := httpReqAllowedDomains, httpReqAllowedDomainsLoaded
+	httpReqAllowedDomainsMu.RUnlock()
+	if loaded {
+		return allowed
+	}
+
+	domains, err := loadHTTPAllowedDomains()
+	if err != nil {
+		slog.Warn("failed to load security.http_request.allowed_domains", "error", err)
+	}
+	SetHTTPAllowedDomains(domains)
+	httpReqAllowedDomainsMu.RLock()
+	defer httpReqAllowedDomainsMu.RUnlock()
+	return httpReqAllowedDomains
+}
+
+// checkHTTPDomain returns an error when host is not on the domain allowlist.
+func checkHTTPDomain(host string) error {
+	allowed := currentHTTPAllowedDomains()
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, d := range allowed {
+		if suffix, ok := strings.CutPrefix(d, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return nil
+			}
+			continue
+		}
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return nil
+		}
+	}
+	return fmt.Errorf("domain %q is not allowed (security.http_request.allowed_domains)", host)
+}
+
 var httpReqAllowedMethods = map[string]bool{
 	"GET":     true,
 	"POST":    true,
@@ -68,6 +151,7 @@ type HttpRequestResult struct {
 	ContentType string            `json:"content_type"`
 	DurationMs  int64             `json:"duration_ms"`
 	Truncated   bool              `json:"truncated,omitempty"`
+	JSON        any               `json:"json,omitempty"` // Parsed body of complete JSON responses
 }
 
 // HttpRequest makes an HTTP request with optional credential injection.
@@ -94,6 +178,9 @@ func httpRequest(ctx context.Context, args HttpRequestArgs) (HttpRequestResult,
 		return HttpRequestResult{}, fmt.Errorf("only http and https URLs are supported, got %q", parsedURL.Scheme)
 	}
 
+	if err := checkHTTPDomain(parsedURL.Hostname()); err != nil {
+		return HttpRequestResult{}, err
+	}
 	if !httpReqSkipSSRF {
 		if err := checkSSRF(parsedURL.Hostname()); err != nil {
 			return HttpRequestResult{}, err
@@ -179,13 +266,15 @@ func httpRequest(ctx context.Context, args HttpRequestArgs) (HttpRequestResult,
 
 	redirectCount := 0
 	client := &http.Client{
-		Timeout: timeout,
-		CheckRedirect: func(_ *http.Request, via []*http.Request) error {
+		Transport: httpool.Transport(),
+		Timeout:   timeout,
+		CheckRedirect: func(next *http.Request, via []*http.Request) error {
 			redirectCount++
 			if redirectCount > httpReqMaxRedirects {
 				return fmt.Errorf("too many redirects (max %d)", httpReqMaxRedirects)
 			}
-			return nil
+			// Redirects must not leave the allowlist
+			return checkHTTPDomain(next.URL.Hostname())
 		},
 	}
 
@@ -212,9 +301,13 @@ func httpRequest(ctx context.Context, args HttpRequestArgs) (HttpRequestResult,
 	bodyStr := string(bodyBytes)
 
 	ct := resp.Header.Get("Content-Type")
+	var parsed any
 	if strings.Contains(ct, "application/json") || strings.Contains(ct, "+json") {
 		if pretty, err := prettyJSON(bodyStr); err == nil {
 			bodyStr = pretty
+			// Expose the parsed value so flows can read fields via
+			// raw_tool_output without a filter_json step
+			_ = json.Unmarshal(bodyBytes, &parsed)
 		}
 	}
 
@@ -233,6 +326,7 @@ func httpRequest(ctx context.Context, args HttpRequestArgs) (HttpRequestResult,
 		ContentType: ct,
 		DurationMs:  durationMs,
 		Truncated:   truncated,
+		JSON:        parsed,
 	}, nil
 }
 
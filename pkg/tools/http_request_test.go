@@ -726,3 +726,99 @@ func TestHttpRequest_MutuallyExclusiveBody(t *testing.T) {
 		t.Fatalf("expected mutual exclusion error, got %v", err)
 	}
 }
+
+// --- Domain allowlist and parsed JSON ---
+
+func TestHttpRequest_DomainAllowlist(t *testing.T) {
+	SetHTTPAllowedDomains([]string{"example.com", "*.internal.test"})
+	defer SetHTTPAllowedDomains(nil)
+
+	tests := []struct {
+		host    string
+		allowed bool
+	}{
+		{"example.com", true},
+		{"API.Example.com", true},
+		{"badexample.com", false},
+		{"svc.internal.test", true},
+		{"internal.test", false},
+		{"other.org", false},
+	}
+	for _, tt := range tests {
+		if err := checkHTTPDomain(tt.host); (err == nil) != tt.allowed {
+			t.Errorf("checkHTTPDomain(%q) err = %v, want allowed=%v", tt.host, err, tt.allowed)
+		}
+	}
+
+	_, err := HttpRequest(nil, HttpRequestArgs{URL: "https://other.org/api"})
+	if err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Errorf("expected domain rejection, got %v", err)
+	}
+}
+
+func TestHttpRequest_AllowlistLoadedOnFirstCheck(t *testing.T) {
+	// As for tools run through ExecuteTool, without GetInternalTools
+	httpReqAllowedDomainsMu.Lock()
+	httpReqAllowedDomains, httpReqAllowedDomainsLoaded = nil, false
+	httpReqAllowedDomainsMu.Unlock()
+	prevLoad := loadHTTPAllowedDomains
+	loadHTTPAllowedDomains = func() ([]string, error) { return []string{"example.com"}, nil }
+	t.Cleanup(func() {
+		loadHTTPAllowedDomains = prevLoad
+		SetHTTPAllowedDomains(nil)
+	})
+
+	_, err := ExecuteTool(context.Background(), "http_request", map[string]any{"url": "https://other.org/api"}, "test")
+	if err == nil || !strings.Contains(err.Error(), "security.http_request.allowed_domains") {
+		t.Errorf("expected the configured allowlist to reject the domain, got %v", err)
+	}
+}
+
+func TestHttpRequest_RedirectOutsideAllowlist(t *testing.T) {
+	httpReqSkipSSRF = true
+	defer func() { httpReqSkipSSRF = false }()
+	SetHTTPAllowedDomains([]string{"127.0.0.1"})
+	defer SetHTTPAllowedDomains(nil)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://localhost.invalid/next", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	_, err := HttpRequest(nil, HttpRequestArgs{URL: srv.URL})
+	if err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Errorf("expected redirect to be rejected, got %v", err)
+	}
+}
+
+func TestHttpRequest_ParsedJSON(t *testing.T) {
+	httpReqSkipSSRF = true
+	defer func() { httpReqSkipSSRF = false }()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/text" {
+			w.Write([]byte(`{"not":"declared as json"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":7,"tags":["a","b"]}`))
+	}))
+	defer srv.Close()
+
+	result, err := HttpRequest(nil, HttpRequestArgs{URL: srv.URL})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	obj, ok := result.JSON.(map[string]any)
+	if !ok || obj["id"] != float64(7) {
+		t.Fatalf("expected parsed JSON object, got %#v", result.JSON)
+	}
+
+	result, err = HttpRequest(nil, HttpRequestArgs{URL: srv.URL + "/text"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.JSON != nil {
+		t.Errorf("expected no parsed JSON for non-JSON content type, got %#v", result.JSON)
+	}
+}
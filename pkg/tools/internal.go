@@ -123,6 +123,9 @@ func ReadFile(ctx tool.Context, args ReadFileArgs) (ReadFileResult, error) {
 	if isProtectedPath(args.Path) {
 		return ReadFileResult{}, fmt.Errorf("access denied: this file is part of the credential store and cannot be read")
 	}
+	if err := checkPathAllowed(args.Path); err != nil {
+		return ReadFileResult{}, err
+	}
 
 	// Determine offset and limit
 	offset := 1
@@ -292,6 +295,9 @@ func WriteFile(ctx tool.Context, args WriteFileArgs) (WriteFileResult, error) {
 	if isProtectedPath(args.FilePath) {
 		return WriteFileResult{}, fmt.Errorf("access denied: this file is part of the credential store and cannot be modified")
 	}
+	if err := checkPathAllowed(args.FilePath); err != nil {
+		return WriteFileResult{}, err
+	}
 
 	// Try to extract stdout from JSON (for shell_command output)
 	var finalContent string
@@ -370,6 +376,16 @@ func ShellCommand(ctx tool.Context, args ShellCommandArgs) (ShellCommandResult,
 		cache.Save()
 	}
 
+	// security.shell and the flow's shell section confine the command
+	sandbox, err := shellSandboxFor(ctx)
+	if err != nil {
+		return ShellCommandResult{}, err
+	}
+	cmd, err := sandbox.command(args.Command, args.WorkingDir)
+	if err != nil {
+		return ShellCommandResult{}, err
+	}
+
 	// Apply timeout defaults and bounds
 	timeout := args.Timeout
 	if timeout <= 0 {
@@ -378,11 +394,12 @@ func ShellCommand(ctx tool.Context, args ShellCommandArgs) (ShellCommandResult,
 	if timeout > 3600 {
 		timeout = 3600
 	}
+	timeout = sandbox.timeout(timeout)
 
 	pm := GetProcessManager()
 
 	// Start process with PTY
-	sess, err := pm.Start(args.Command, args.WorkingDir, 24, 80)
+	sess, err := pm.StartCmd(cmd, args.Command, 24, 80)
 	if err != nil {
 		return ShellCommandResult{}, fmt.Errorf("failed to start command: %w", err)
 	}
@@ -393,7 +410,7 @@ func ShellCommand(ctx tool.Context, args ShellCommandArgs) (ShellCommandResult,
 		time.Sleep(300 * time.Millisecond)
 		data := sess.Output.Bytes()
 		return ShellCommandResult{
-			Stdout:    string(data),
+			Stdout:    sandbox.limitOutput(string(data)),
 			SessionID: sess.ID,
 		}, nil
 	}
@@ -409,7 +426,7 @@ func ShellCommand(ctx tool.Context, args ShellCommandArgs) (ShellCommandResult,
 			// Process exited — return output
 			data := sess.Output.Bytes()
 			result := ShellCommandResult{
-				Stdout:   string(data),
+				Stdout:   sandbox.limitOutput(string(data)),
 				ExitCode: sess.ExitCode,
 			}
 			return result, nil
@@ -419,7 +436,7 @@ func ShellCommand(ctx tool.Context, args ShellCommandArgs) (ShellCommandResult,
 			_ = pm.Kill(sess.ID)
 			data := sess.Output.Bytes()
 			return ShellCommandResult{
-				Stdout:    string(data),
+				Stdout:    sandbox.limitOutput(string(data)),
 				TimedOut:  true,
 				SessionID: sess.ID,
 			}, nil
@@ -430,7 +447,7 @@ func ShellCommand(ctx tool.Context, args ShellCommandArgs) (ShellCommandResult,
 				data := sess.Output.Bytes()
 				if len(data) > 0 && looksLikePrompt(string(data)) {
 					return ShellCommandResult{
-						Stdout:          string(data),
+						Stdout:          sandbox.limitOutput(string(data)),
 						WaitingForInput: true,
 						SessionID:       sess.ID,
 					}, nil
@@ -693,8 +710,27 @@ func GetInternalTools() ([]tool.Tool, error) {
 		return nil, err
 	}
 
+	listDirectoryTool, err := functiontool.New(functiontool.Config{
+		Name:        "list_directory",
+		Description: "List the entries of a single directory (not recursive) with their type, size and modification time, directories first. Hidden entries are skipped unless show_hidden=true. Use file_tree for a recursive view.",
+	}, ListDirectory)
+	if err != nil {
+		return nil, err
+	}
+
+	globTool, err := functiontool.New(functiontool.Config{
+		Name:        "glob",
+		Description: "Return the paths matching a full glob pattern such as '/repo/src/**/*.go' or 'docs/*.md' (relative to base_dir). ** matches any number of directories. Results are sorted and capped at max_results.",
+	}, Glob)
+	if err != nil {
+		return nil, err
+	}
+
 	codeIntelEnabled := true
 	if appCfg, cfgErr := config.LoadAppConfig(); cfgErr == nil && appCfg != nil {
+		SetAllowedPaths(appCfg.Security.FileAccess.AllowedPaths)
+		SetHTTPAllowedDomains(appCfg.Security.HTTPRequest.AllowedDomains)
+		SetShellSandbox(shellSandboxFromConfig(appCfg.Security.Shell))
 		codeIntelEnabled = appCfg.CodeIntel.IsEnabled()
 		if appCfg.CodeIntel.LibraryPath != "" {
 			// Prefer configured path over the hard-coded default; the loader
@@ -749,18 +785,26 @@ func GetInternalTools() ([]tool.Tool, error) {
 
 	httpRequestTool, err := functiontool.New(functiontool.Config{
 		Name:        "http_request",
-		Description: `Make an HTTP request (GET, POST, PUT, PATCH, DELETE, HEAD, OPTIONS). Set 'credential' to a stored credential name for automatic auth header injection. JSON Content-Type is set automatically when body starts with { or [.`,
+		Description: `Make an HTTP request (GET, POST, PUT, PATCH, DELETE, HEAD, OPTIONS). Set 'credential' to a stored credential name for automatic auth header injection. JSON Content-Type is set automatically when body starts with { or [. JSON responses are also returned parsed in 'json'.`,
 	}, HttpRequest)
 	if err != nil {
 		return nil, err
 	}
 
+	saveArtifactTool, err := functiontool.New(functiontool.Config{
+		Name:        "save_artifact",
+		Description: "Store a local file (screenshot, PDF, export) or text as an artifact of the current flow run. Artifacts are referenced in the run's state under _artifacts and can be downloaded after the run with 'astonish flows artifacts'.",
+	}, SaveArtifact)
+	if err != nil {
+		return nil, err
+	}
+
 	out := []tool.Tool{
 		readFileTool, writeFileTool, shellCommandTool, filterJsonTool, gitDiffAddLineNumbersTool,
-		fileTreeTool, grepSearchTool, findFilesTool, editFileTool,
+		fileTreeTool, grepSearchTool, findFilesTool, editFileTool, listDirectoryTool, globTool,
 	}
 	out = append(out, codeIntelTools...)
-	out = append(out, webFetchTool, readPDFTool, httpRequestTool)
+	out = append(out, webFetchTool, readPDFTool, httpRequestTool, saveArtifactTool)
 	return out, nil
 }
 
@@ -850,6 +894,20 @@ func ExecuteTool(ctx context.Context, name string, args map[string]interface{},
 		}
 		return EditFile(nil, toolArgs)
 
+	case "list_directory":
+		var toolArgs ListDirectoryArgs
+		if err := toStruct(args, &toolArgs); err != nil {
+			return nil, fmt.Errorf("invalid args for list_directory: %w", err)
+		}
+		return ListDirectory(nil, toolArgs)
+
+	case "glob":
+		var toolArgs GlobArgs
+		if err := toStruct(args, &toolArgs); err != nil {
+			return nil, fmt.Errorf("invalid args for glob: %w", err)
+		}
+		return Glob(nil, toolArgs)
+
 	case "repo_map":
 		var toolArgs codeintel.RepoMapArgs
 		if err := toStruct(args, &toolArgs); err != nil {
@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"google.golang.org/adk/tool"
+)
+
+// ListDirectoryArgs defines arguments for the list_directory tool
+type ListDirectoryArgs struct {
+	Path       string `json:"path" jsonschema:"Directory to list"`
+	ShowHidden bool   `json:"show_hidden,omitempty" jsonschema:"Include entries starting with a dot (default: false)"`
+}
+
+// DirectoryEntry is one entry of a directory listing
+type DirectoryEntry struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"` // "file", "dir" or "symlink"
+	Size     int64  `json:"size"`
+	Modified string `json:"modified"`
+}
+
+// ListDirectoryResult is the result returned by the list_directory tool
+type ListDirectoryResult struct {
+	Path    string           `json:"path"`
+	Entries []DirectoryEntry `json:"entries"`
+	Total   int              `json:"total"`
+}
+
+// ListDirectory lists the immediate children of a directory, directories
+// first. Use file_tree for a recursive overview.
+func ListDirectory(ctx tool.Context, args ListDirectoryArgs) (ListDirectoryResult, error) {
+	if args.Path == "" {
+		return ListDirectoryResult{}, fmt.Errorf("path is required")
+	}
+	absPath, err := filepath.Abs(expandPath(args.Path))
+	if err != nil {
+		return ListDirectoryResult{}, err
+	}
+	if err := checkPathAllowed(absPath); err != nil {
+		return ListDirectoryResult{}, err
+	}
+
+	dirEntries, err := os.ReadDir(absPath)
+	if err != nil {
+		return ListDirectoryResult{}, err
+	}
+
+	entries := make([]DirectoryEntry, 0, len(dirEntries))
+	for _, e := range dirEntries {
+		if !args.ShowHidden && strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		entry := DirectoryEntry{Name: e.Name(), Type: "file"}
+		switch {
+		case e.Type()&os.ModeSymlink != 0:
+			entry.Type = "symlink"
+		case e.IsDir():
+			entry.Type = "dir"
+		}
+		if info, err := e.Info(); err == nil {
+			entry.Modified = info.ModTime().UTC().Format("2006-01-02T15:04:05Z")
+			if entry.Type == "file" {
+				entry.Size = info.Size()
+			}
+		}
+		entries = append(entries, entry)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if (entries[i].Type == "dir") != (entries[j].Type == "dir") {
+			return entries[i].Type == "dir"
+		}
+		return entries[i].Name < entries[j].Name
+	})
+
+	return ListDirectoryResult{Path: absPath, Entries: entries, Total: len(entries)}, nil
+}
@@ -0,0 +1,132 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestListDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "b.txt", "hello")
+	writeTestFile(t, dir, ".hidden", "x")
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ListDirectory(nil, ListDirectoryArgs{Path: dir})
+	if err != nil {
+		t.Fatalf("ListDirectory: %v", err)
+	}
+	if result.Total != 2 {
+		t.Fatalf("expected 2 entries without hidden files, got %+v", result.Entries)
+	}
+	if result.Entries[0].Name != "sub" || result.Entries[0].Type != "dir" {
+		t.Errorf("expected directory first, got %+v", result.Entries[0])
+	}
+	if result.Entries[1].Name != "b.txt" || result.Entries[1].Size != 5 {
+		t.Errorf("unexpected file entry %+v", result.Entries[1])
+	}
+
+	result, err = ListDirectory(nil, ListDirectoryArgs{Path: dir, ShowHidden: true})
+	if err != nil {
+		t.Fatalf("ListDirectory: %v", err)
+	}
+	if result.Total != 3 {
+		t.Errorf("expected 3 entries with hidden files, got %d", result.Total)
+	}
+}
+
+func TestGlob(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"a/b", "node_modules/x"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeTestFile(t, dir, "top.go", "")
+	writeTestFile(t, dir, "a/one.go", "")
+	writeTestFile(t, dir, "a/b/two.go", "")
+	writeTestFile(t, dir, "a/b/notes.md", "")
+	writeTestFile(t, dir, "node_modules/x/dep.go", "")
+
+	result, err := Glob(nil, GlobArgs{Pattern: "**/*.go", BaseDir: dir})
+	if err != nil {
+		t.Fatalf("Glob: %v", err)
+	}
+	want := []string{
+		filepath.Join(dir, "a", "b", "two.go"),
+		filepath.Join(dir, "a", "one.go"),
+		filepath.Join(dir, "top.go"),
+	}
+	if strings.Join(result.Paths, ",") != strings.Join(want, ",") {
+		t.Errorf("got %v, want %v", result.Paths, want)
+	}
+
+	result, err = Glob(nil, GlobArgs{Pattern: filepath.Join(dir, "a", "*.go")})
+	if err != nil {
+		t.Fatalf("Glob: %v", err)
+	}
+	if len(result.Paths) != 1 || result.Paths[0] != filepath.Join(dir, "a", "one.go") {
+		t.Errorf("absolute pattern: got %v", result.Paths)
+	}
+
+	result, err = Glob(nil, GlobArgs{Pattern: "**/*.go", BaseDir: dir, MaxResults: 2})
+	if err != nil {
+		t.Fatalf("Glob: %v", err)
+	}
+	if !result.Capped || len(result.Paths) != 2 {
+		t.Errorf("expected 2 capped results, got %+v", result)
+	}
+}
+
+func TestAllowedPaths(t *testing.T) {
+	allowed := t.TempDir()
+	other := t.TempDir()
+	writeTestFile(t, allowed, "in.txt", "in")
+	outside := writeTestFile(t, other, "out.txt", "out")
+	if err := os.Symlink(other, filepath.Join(allowed, "escape")); err != nil {
+		t.Fatal(err)
+	}
+
+	SetAllowedPaths([]string{allowed})
+	t.Cleanup(func() { SetAllowedPaths(nil) })
+
+	if _, err := ListDirectory(nil, ListDirectoryArgs{Path: allowed}); err != nil {
+		t.Errorf("allowed directory rejected: %v", err)
+	}
+	if _, err := ReadFile(nil, ReadFileArgs{Path: outside}); err == nil || !strings.Contains(err.Error(), "outside the allowed paths") {
+		t.Errorf("expected read outside the allowlist to fail, got %v", err)
+	}
+	if _, err := ListDirectory(nil, ListDirectoryArgs{Path: filepath.Join(allowed, "escape")}); err == nil {
+		t.Error("expected symlink out of the allowlist to be rejected")
+	}
+	if _, err := WriteFile(nil, WriteFileArgs{FilePath: filepath.Join(other, "new.txt"), Content: "x"}); err == nil {
+		t.Error("expected write outside the allowlist to fail")
+	}
+	if _, err := Glob(nil, GlobArgs{Pattern: filepath.Join(other, "*.txt")}); err == nil {
+		t.Error("expected glob outside the allowlist to fail")
+	}
+}
+
+func TestAllowedPathsLoadedOnFirstCheck(t *testing.T) {
+	allowed := t.TempDir()
+	outside := writeTestFile(t, t.TempDir(), "out.txt", "out")
+
+	// As for tools run through ExecuteTool, without GetInternalTools
+	allowedPathsMu.Lock()
+	allowedPaths, allowedPathsLoaded = nil, false
+	allowedPathsMu.Unlock()
+	prevLoad := loadAllowedPaths
+	loadAllowedPaths = func() ([]string, error) { return []string{allowed}, nil }
+	t.Cleanup(func() {
+		loadAllowedPaths = prevLoad
+		SetAllowedPaths(nil)
+	})
+
+	if _, err := ExecuteTool(context.Background(), "read_file", map[string]any{"path": outside}, "test"); err == nil || !strings.Contains(err.Error(), "outside the allowed paths") {
+		t.Errorf("expected read outside the configured allowlist to fail, got %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package tools
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/SAP/astonish/pkg/config"
+)
+
+// --- File tool path allowlist ---
+
+// allowedPaths restricts the file tools to these directories (and files)
+// when non-empty. Set from security.file_access.allowed_paths.
+var (
+	allowedPathsMu     sync.RWMutex
+	allowedPaths       []string
+	allowedPathsLoaded bool // Set by SetAllowedPaths or the first check
+)
+
+// loadAllowedPaths reads security.file_access.allowed_paths from the app
+// config. Tests replace it.
+var loadAllowedPaths = func() ([]string, error) {
+	appCfg, err := config.LoadAppConfig()
+	if err != nil || appCfg == nil {
+		return nil, err
+	}
+	return appCfg.Security.FileAccess.AllowedPaths, nil
+}
+
+// SetAllowedPaths sets the directories the file tools may access. An empty
+// list allows every path except the credential store files.
+func SetAllowedPaths(paths []string) {
+	resolved := make([]string, 0, len(paths))
+	for _, p := range paths {
+		if p = strings.TrimSpace(p); p != "" {
+			resolved = append(resolved, resolvePath(expandPath(p)))
+		}
+	}
+	allowedPathsMu.Lock()
+	allowedPaths = resolved
+	allowedPathsLoaded = true
+	allowedPathsMu.Unlock()
+}
+
+// currentAllowedPaths returns the allowlist, loading it from the app config
+// on first use, so tools run without GetInternalTools (ExecuteTool) are
+// restricted too.
+func currentAllowedPaths() []string {
+	allowedPathsMu.RLock()
+	allowed, loaded := allowedPaths, allowedPathsLoaded
+	allowedPathsMu.RUnlock()
+	if loaded {
+		return allowed
+	}
+
+	paths, err := loadAllowedPaths()
+	if err != nil {
+		slog.Warn("failed to load security.file_access.allowed_paths", "error", err)
+	}
+	SetAllowedPaths(paths)
+	allowedPathsMu.RLock()
+	defer allowedPathsMu.RUnlock()
+	return allowedPaths
+}
+
+// resolvePath makes a path absolute and follows symlinks where it exists, so
+// links cannot point out of an allowed directory.
+func resolvePath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return resolved
+	}
+	// The path may not exist yet (write_file): resolve its parent
+	if parent, err := filepath.EvalSymlinks(filepath.Dir(abs)); err == nil {
+		return filepath.Join(parent, filepath.Base(abs))
+	}
+	return abs
+}
+
+// checkPathAllowed returns an error when path is a credential store file or
+// lies outside the configured allowlist.
+func checkPathAllowed(path string) error {
+	if isProtectedPath(path) {
+		return fmt.Errorf("access denied: this file is part of the credential store")
+	}
+	allowed := currentAllowedPaths()
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	resolved := resolvePath(path)
+	for _, dir := range allowed {
+		if resolved == dir || strings.HasPrefix(resolved, dir+string(filepath.Separator)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("access denied: %s is outside the allowed paths (security.file_access.allowed_paths)", path)
+}
@@ -7,10 +7,9 @@ import (
 	"os/exec"
 	"regexp"
 	"sync"
-	"syscall"
 	"time"
 
-	"github.com/creack/pty"
+	"github.com/SAP/astonish/pkg/procgroup"
 	"github.com/google/uuid"
 )
 
@@ -125,7 +124,7 @@ type ProcessSession struct {
 	EndedAt   *time.Time
 	ExitCode  *int
 	Output    *RingBuffer
-	pty       *os.File // master side of PTY
+	pty       io.ReadWriteCloser // master side of PTY (pipes on Windows)
 	cmd       *exec.Cmd
 	mu        sync.Mutex
 	lastWrite time.Time // last time output was received from the process
@@ -161,33 +160,42 @@ func NewProcessManager() *ProcessManager {
 	return pm
 }
 
-// Start launches a command in a PTY and returns the session.
+// Start launches a command with the default shell in a PTY and returns
+// the session.
 func (pm *ProcessManager) Start(command, workDir string, rows, cols uint16) (*ProcessSession, error) {
-	if rows == 0 {
-		rows = 24
-	}
-	if cols == 0 {
-		cols = 80
-	}
-
-	cmd := exec.Command("sh", "-c", command)
+	cmd := shellExec("", command)
 	if workDir != "" {
 		cmd.Dir = expandPath(workDir)
 	}
+	cmd.Env = processEnv(os.Environ())
+	return pm.StartCmd(cmd, command, rows, cols)
+}
 
-	// Set environment: inherit parent env and add safe defaults.
-	// EDITOR/VISUAL=true prevents commands that spawn a text editor (commit messages,
-	// interactive configs, etc.) from hanging — the agent cannot operate a text editor.
-	// TERM=xterm-256color ensures consistent terminal behavior regardless of how
-	// Astonish is launched.
-	cmd.Env = append(os.Environ(),
+// processEnv adds safe defaults to the environment of a process.
+// EDITOR/VISUAL=true prevents commands that spawn a text editor (commit messages,
+// interactive configs, etc.) from hanging — the agent cannot operate a text editor.
+// TERM=xterm-256color ensures consistent terminal behavior regardless of how
+// Astonish is launched.
+func processEnv(env []string) []string {
+	return append(env,
 		"EDITOR=true",
 		"VISUAL=true",
 		"TERM=xterm-256color",
 	)
+}
+
+// StartCmd starts a prepared command in a new PTY session. command is the
+// command line recorded for the session.
+func (pm *ProcessManager) StartCmd(cmd *exec.Cmd, command string, rows, cols uint16) (*ProcessSession, error) {
+	if rows == 0 {
+		rows = 24
+	}
+	if cols == 0 {
+		cols = 80
+	}
 
 	// Start with PTY
-	ptmx, err := pty.StartWithSize(cmd, &pty.Winsize{Rows: rows, Cols: cols})
+	ptmx, err := startTerminal(cmd, rows, cols)
 	if err != nil {
 		return nil, err
 	}
@@ -237,7 +245,8 @@ func (pm *ProcessManager) List() []*ProcessSession {
 	return list
 }
 
-// Kill sends a signal to a process. Default is SIGTERM, with SIGKILL fallback after 5s.
+// Kill stops a process and its children: SIGTERM to its process group,
+// with SIGKILL fallback after 5s. On Windows the process tree is killed.
 func (pm *ProcessManager) Kill(id string) error {
 	pm.mu.RLock()
 	sess := pm.sessions[id]
@@ -253,7 +262,7 @@ func (pm *ProcessManager) Kill(id string) error {
 
 	// Send SIGTERM
 	if sess.cmd.Process != nil {
-		_ = sess.cmd.Process.Signal(syscall.SIGTERM)
+		_ = procgroup.Terminate(sess.cmd)
 	}
 
 	// Wait up to 5s for exit, then SIGKILL
@@ -262,7 +271,7 @@ func (pm *ProcessManager) Kill(id string) error {
 		return nil
 	case <-time.After(5 * time.Second):
 		if sess.cmd.Process != nil {
-			_ = sess.cmd.Process.Signal(syscall.SIGKILL)
+			_ = procgroup.Kill(sess.cmd)
 		}
 		// Wait for the kill to take effect
 		select {
@@ -376,12 +385,13 @@ func (s *ProcessSession) waitLoop() {
 // terminal behavior — keyboards send \r for Enter, and the PTY line
 // discipline normally converts it. Programs in raw mode (like SSH
 // password prompts) disable that translation, so sending \r directly
-// ensures interactive prompts receive the expected Enter keypress.
+// ensures interactive prompts receive the expected Enter keypress. On
+// Windows, where the process reads a pipe, lines end with \r\n instead.
 func (s *ProcessSession) Write(data []byte) (int, error) {
 	if !s.IsRunning() {
 		return 0, io.EOF
 	}
-	translated := bytes.ReplaceAll(data, []byte("\n"), []byte("\r"))
+	translated := bytes.ReplaceAll(data, []byte("\n"), []byte(terminalEnter))
 	return s.pty.Write(translated)
 }
 
@@ -0,0 +1,20 @@
+//go:build !windows
+
+package tools
+
+import (
+	"io"
+	"os/exec"
+
+	"github.com/creack/pty"
+)
+
+// terminalEnter is what the Enter key sends to a PTY.
+const terminalEnter = "\r"
+
+// startTerminal starts cmd attached to a new PTY of the given size and
+// returns its master side. The PTY puts the command in a new session, so
+// it leads a process group that Kill stops as a whole.
+func startTerminal(cmd *exec.Cmd, rows, cols uint16) (io.ReadWriteCloser, error) {
+	return pty.StartWithSize(cmd, &pty.Winsize{Rows: rows, Cols: cols})
+}
@@ -0,0 +1,58 @@
+//go:build windows
+
+package tools
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/SAP/astonish/pkg/procgroup"
+)
+
+// terminalEnter is what ends a line read from a console program's stdin.
+const terminalEnter = "\r\n"
+
+// startTerminal starts cmd with its stdin and output on pipes. Windows has
+// no PTY here, so programs see a non-interactive console; the size is
+// unused. cmd gets a process group so Kill stops its children.
+func startTerminal(cmd *exec.Cmd, _, _ uint16) (io.ReadWriteCloser, error) {
+	stdinR, stdinW, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	outR, outW, err := os.Pipe()
+	if err != nil {
+		stdinR.Close()
+		stdinW.Close()
+		return nil, err
+	}
+	cmd.Stdin = stdinR
+	cmd.Stdout = outW
+	cmd.Stderr = outW
+	procgroup.Set(cmd)
+	err = cmd.Start()
+	// The child holds its ends now
+	stdinR.Close()
+	outW.Close()
+	if err != nil {
+		stdinW.Close()
+		outR.Close()
+		return nil, err
+	}
+	return &pipeTerminal{in: stdinW, out: outR}, nil
+}
+
+// pipeTerminal is the terminal of a process started on pipes.
+type pipeTerminal struct {
+	in  *os.File
+	out *os.File
+}
+
+func (t *pipeTerminal) Read(p []byte) (int, error)  { return t.out.Read(p) }
+func (t *pipeTerminal) Write(p []byte) (int, error) { return t.in.Write(p) }
+
+func (t *pipeTerminal) Close() error {
+	return errors.Join(t.in.Close(), t.out.Close())
+}
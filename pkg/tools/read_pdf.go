@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/SAP/astonish/pkg/provider/httpool"
 	"github.com/ledongthuc/pdf"
 	"google.golang.org/adk/tool"
 )
@@ -172,7 +173,7 @@ func downloadPDFToTemp(url string) (string, string, error) {
 		return "", "", err
 	}
 
-	resp, err := http.Get(url)
+	resp, err := httpool.Client(0).Get(url)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to fetch PDF: %w", err)
 	}
@@ -347,6 +347,8 @@ var testContainerTools = map[string]bool{
 	"file_tree":                 true,
 	"grep_search":               true,
 	"find_files":                true,
+	"list_directory":            true,
+	"glob":                      true,
 	"process_read":              true,
 	"process_write":             true,
 	"process_list":              true,
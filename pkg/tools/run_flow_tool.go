@@ -58,6 +58,7 @@ type FlowRunResult struct {
 	InputNode    string   `json:"input_node,omitempty"`    // Name of the input node
 	InputPrompt  string   `json:"input_prompt,omitempty"`  // Prompt text for the user
 	InputOptions []string `json:"input_options,omitempty"` // Selection options (empty = free text)
+	MultiSelect  bool     `json:"multi_select,omitempty"`  // Several options may be chosen (comma-separated)
 
 	// Execution trace:
 	NodesVisited []string `json:"nodes_visited,omitempty"` // Ordered list of nodes executed
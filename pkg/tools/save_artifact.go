@@ -0,0 +1,99 @@
+package tools
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/artifact"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+)
+
+// saveArtifactMaxSize bounds the files save_artifact stores.
+const saveArtifactMaxSize = 100e6 // 100MB
+
+// SaveArtifactArgs are the arguments for the save_artifact tool.
+type SaveArtifactArgs struct {
+	Name     string `json:"name,omitempty" jsonschema:"Artifact file name, e.g. screenshot.png (default: the base name of path)"`
+	Path     string `json:"path,omitempty" jsonschema:"Local file to store as the artifact"`
+	Content  string `json:"content,omitempty" jsonschema:"Text to store as the artifact, instead of a file"`
+	MimeType string `json:"mime_type,omitempty" jsonschema:"MIME type (default: detected from the name and content)"`
+}
+
+// SaveArtifactResult is the result of the save_artifact tool.
+type SaveArtifactResult struct {
+	Name     string `json:"name"`
+	Version  int64  `json:"version"`
+	MimeType string `json:"mime_type"`
+	Size     int    `json:"size"`
+}
+
+// SaveArtifact stores a file or text as an artifact of the current run, so
+// that it is referenced in state and can be downloaded after the run.
+func SaveArtifact(ctx tool.Context, args SaveArtifactArgs) (SaveArtifactResult, error) {
+	if (args.Path == "") == (args.Content == "") {
+		return SaveArtifactResult{}, fmt.Errorf("set exactly one of path or content")
+	}
+	name := args.Name
+	var data []byte
+	if args.Path != "" {
+		path := expandPath(args.Path)
+		if err := checkPathAllowed(path); err != nil {
+			return SaveArtifactResult{}, err
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return SaveArtifactResult{}, fmt.Errorf("failed to read %s: %w", args.Path, err)
+		}
+		if info.Size() > saveArtifactMaxSize {
+			return SaveArtifactResult{}, fmt.Errorf("%s is too large to store as an artifact (%d bytes)", args.Path, info.Size())
+		}
+		if data, err = os.ReadFile(path); err != nil {
+			return SaveArtifactResult{}, fmt.Errorf("failed to read %s: %w", args.Path, err)
+		}
+		if name == "" {
+			name = filepath.Base(path)
+		}
+	} else {
+		data = []byte(args.Content)
+		if name == "" {
+			return SaveArtifactResult{}, fmt.Errorf("name is required when saving content")
+		}
+	}
+
+	mimeType := args.MimeType
+	if mimeType == "" {
+		if mimeType = mime.TypeByExtension(filepath.Ext(name)); mimeType == "" {
+			mimeType = http.DetectContentType(data)
+		}
+	}
+
+	resp, err := saveArtifact(ctx, name, genai.NewPartFromBytes(data, mimeType))
+	if err != nil {
+		return SaveArtifactResult{}, err
+	}
+	return SaveArtifactResult{Name: name, Version: resp.Version, MimeType: mimeType, Size: len(data)}, nil
+}
+
+// saveArtifact saves part through the tool context's artifacts. Without an
+// artifact service on the runner, ADK hands tools a wrapper around a nil
+// service whose methods panic, so the panic is reported as unavailability.
+func saveArtifact(ctx tool.Context, name string, part *genai.Part) (resp *artifact.SaveResponse, err error) {
+	var arts agent.Artifacts
+	if ctx != nil {
+		arts = ctx.Artifacts()
+	}
+	if arts == nil {
+		return nil, fmt.Errorf("artifact storage is not available in this run")
+	}
+	defer func() {
+		if recover() != nil {
+			resp, err = nil, fmt.Errorf("artifact storage is not available in this run")
+		}
+	}()
+	return arts.Save(ctx, name, part)
+}
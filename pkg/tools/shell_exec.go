@@ -0,0 +1,43 @@
+package tools
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"slices"
+)
+
+// shellInterpreters are the values of security.shell.interpreter.
+var shellInterpreters = []string{"sh", "bash", "cmd", "powershell", "pwsh"}
+
+// validShellInterpreter checks a security.shell.interpreter value; empty
+// selects the platform default.
+func validShellInterpreter(name string) error {
+	if name == "" {
+		return nil
+	}
+	if !slices.Contains(shellInterpreters, name) {
+		return fmt.Errorf("security.shell.interpreter: unknown interpreter %q (want one of %v)", name, shellInterpreters)
+	}
+	if name == "cmd" && runtime.GOOS != "windows" {
+		return fmt.Errorf("security.shell.interpreter: cmd is only available on Windows")
+	}
+	return nil
+}
+
+// shellExec returns the process running command with interpreter, or with
+// the platform's default shell (sh, or PowerShell on Windows) when it is
+// empty.
+func shellExec(interpreter, command string) *exec.Cmd {
+	if interpreter == "" {
+		interpreter = defaultShell()
+	}
+	switch interpreter {
+	case "cmd":
+		return cmdExec(command)
+	case "powershell", "pwsh":
+		return exec.Command(interpreter, "-NoProfile", "-NonInteractive", "-Command", command)
+	default:
+		return exec.Command(interpreter, "-c", command)
+	}
+}
@@ -0,0 +1,54 @@
+package tools
+
+import (
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/config"
+)
+
+func TestShellExec(t *testing.T) {
+	// The default shell runs the command
+	out, err := shellExec("", "echo hello").Output()
+	if err != nil || strings.TrimSpace(string(out)) != "hello" {
+		t.Errorf("default shell output = %q, %v", out, err)
+	}
+
+	ps := shellExec("pwsh", "Get-ChildItem | Select-Object -First 1")
+	if want := []string{"pwsh", "-NoProfile", "-NonInteractive", "-Command", "Get-ChildItem | Select-Object -First 1"}; !slices.Equal(ps.Args, want) {
+		t.Errorf("pwsh args = %q, want %q", ps.Args, want)
+	}
+
+	if runtime.GOOS == "windows" {
+		// cmd gets the command line unquoted, so its own syntax works
+		out, err := shellExec("cmd", `echo "a b"& echo c`).Output()
+		if err != nil || strings.Join(strings.Fields(string(out)), " ") != `"a b" c` {
+			t.Errorf("cmd output = %q, %v", out, err)
+		}
+	} else {
+		if bash := shellExec("bash", "echo $0"); !slices.Equal(bash.Args, []string{"bash", "-c", "echo $0"}) {
+			t.Errorf("bash args = %q", bash.Args)
+		}
+		if err := validShellInterpreter("cmd"); err == nil {
+			t.Error("cmd accepted off Windows")
+		}
+	}
+
+	for name, valid := range map[string]bool{"": true, "sh": true, "powershell": true, "zsh": false} {
+		if err := validShellInterpreter(name); (err == nil) != valid {
+			t.Errorf("validShellInterpreter(%q) = %v, want valid %v", name, err, valid)
+		}
+	}
+	if _, err := NewShellSandbox(config.ShellSandboxConfig{Interpreter: "fish"}); err == nil {
+		t.Error("unknown interpreter accepted by NewShellSandbox")
+	}
+	s, err := NewShellSandbox(config.ShellSandboxConfig{Interpreter: "sh"})
+	if err != nil || s == nil {
+		t.Fatalf("NewShellSandbox(interpreter: sh) = %v, %v; want a sandbox", s, err)
+	}
+	if cmd, err := s.command("true", ""); err != nil || cmd.Args[0] != "sh" {
+		t.Errorf("sandbox command = %v, %v; want it run by sh", cmd, err)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package tools
+
+import "os/exec"
+
+func defaultShell() string {
+	return "sh"
+}
+
+// cmdExec is never reached: validShellInterpreter refuses cmd here.
+func cmdExec(command string) *exec.Cmd {
+	return exec.Command("cmd", "/C", command)
+}
@@ -0,0 +1,30 @@
+//go:build windows
+
+package tools
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// defaultShell is PowerShell, whose aliases (ls, cat, rm, ...) cover most
+// of what models write for sh, falling back to cmd.
+func defaultShell() string {
+	if _, err := exec.LookPath("powershell"); err == nil {
+		return "powershell"
+	}
+	return "cmd"
+}
+
+// cmdExec runs command with cmd.exe. cmd does not parse its arguments the
+// way Go quotes them, so the command line is passed as is.
+func cmdExec(command string) *exec.Cmd {
+	comspec := os.Getenv("ComSpec")
+	if comspec == "" {
+		comspec = "cmd.exe"
+	}
+	cmd := exec.Command(comspec)
+	cmd.SysProcAttr = &syscall.SysProcAttr{CmdLine: `cmd.exe /S /C "` + command + `"`}
+	return cmd
+}
@@ -0,0 +1,364 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SAP/astonish/pkg/agent"
+	"github.com/SAP/astonish/pkg/config"
+)
+
+// --- shell_command sandbox ---
+
+// ShellSandbox confines the commands of shell_command: where they run,
+// which environment and commands they get, and how long and how much
+// output they may produce. A nil *ShellSandbox leaves commands unrestricted.
+type ShellSandbox struct {
+	root       string
+	scrubEnv   bool
+	envAllow   []string
+	allow      []*regexp.Regexp
+	deny       []*regexp.Regexp
+	container  *config.ShellContainerConfig
+	maxRuntime time.Duration
+	maxOutput  int
+	shell      string   // security.shell.interpreter; empty for the platform default
+	nodeEnv    []string // KEY=VALUE of the running node's env, kept with scrub_env
+
+	// invalid is why the settings could not be compiled; every command
+	// is refused then
+	invalid error
+}
+
+// baseShellEnv are the variables commands keep with scrub_env.
+var baseShellEnv = []string{"PATH", "HOME", "USER", "LOGNAME", "SHELL", "LANG", "TZ", "TMPDIR"}
+
+// NewShellSandbox compiles the security.shell settings. It returns nil
+// when they are empty.
+func NewShellSandbox(cfg config.ShellSandboxConfig) (*ShellSandbox, error) {
+	if cfg.WorkingDir == "" && !cfg.ScrubEnv && len(cfg.AllowCommands) == 0 && len(cfg.DenyCommands) == 0 &&
+		cfg.Container == nil && cfg.MaxRuntime == "" && cfg.MaxOutputBytes == 0 && cfg.Interpreter == "" {
+		return nil, nil
+	}
+	s := &ShellSandbox{
+		scrubEnv:  cfg.ScrubEnv,
+		envAllow:  cfg.EnvAllow,
+		container: cfg.Container,
+		maxOutput: cfg.MaxOutputBytes,
+		shell:     cfg.Interpreter,
+	}
+	if cfg.WorkingDir != "" {
+		s.root = resolvePath(expandPath(cfg.WorkingDir))
+	}
+	err := validShellInterpreter(cfg.Interpreter)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Container != nil {
+		if err := validContainerRuntime(cfg.Container.Runtime); err != nil {
+			return nil, fmt.Errorf("security.shell.container: %w", err)
+		}
+	}
+	if s.allow, err = compileCommandPatterns("allow_commands", cfg.AllowCommands); err != nil {
+		return nil, err
+	}
+	if s.deny, err = compileCommandPatterns("deny_commands", cfg.DenyCommands); err != nil {
+		return nil, err
+	}
+	if cfg.MaxRuntime != "" {
+		if s.maxRuntime, err = time.ParseDuration(cfg.MaxRuntime); err != nil || s.maxRuntime <= 0 {
+			return nil, fmt.Errorf("security.shell.max_runtime: invalid duration %q", cfg.MaxRuntime)
+		}
+	}
+	return s, nil
+}
+
+func compileCommandPatterns(field string, patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("security.shell.%s: invalid pattern %q: %w", field, p, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// validContainerRuntime checks the runtime of a shell container, which is
+// run as a program: only docker and podman are.
+func validContainerRuntime(runtime string) error {
+	switch runtime {
+	case "", "docker", "podman":
+		return nil
+	}
+	return fmt.Errorf("unsupported runtime %q (docker or podman)", runtime)
+}
+
+// ForFlow returns the sandbox for the commands of a flow with a shell
+// section: its deny_commands are refused too, and its container picks the
+// image. A configured container keeps its runtime and network, so a flow
+// cannot undo them. Everything else stays as configured.
+func (s *ShellSandbox) ForFlow(flow *config.FlowShellConfig) (*ShellSandbox, error) {
+	if flow == nil {
+		return s, nil
+	}
+	deny, err := compileCommandPatterns("deny_commands", flow.DenyCommands)
+	if err != nil {
+		return nil, fmt.Errorf("flow shell: %w", err)
+	}
+	var merged ShellSandbox
+	if s != nil {
+		merged = *s
+	}
+	merged.deny = append(append([]*regexp.Regexp{}, merged.deny...), deny...)
+	if flow.Container != nil {
+		container := *flow.Container
+		if merged.container != nil {
+			container = *merged.container
+			container.Image = flow.Container.Image
+		}
+		if err := validContainerRuntime(container.Runtime); err != nil {
+			return nil, fmt.Errorf("flow shell container: %w", err)
+		}
+		merged.container = &container
+	}
+	return &merged, nil
+}
+
+var (
+	shellSandboxMu     sync.RWMutex
+	shellSandbox       *ShellSandbox
+	shellSandboxLoaded bool // Set by SetShellSandbox or the first command
+)
+
+// loadShellSandboxConfig reads security.shell from the app config. Tests
+// replace it.
+var loadShellSandboxConfig = func() (config.ShellSandboxConfig, error) {
+	appCfg, err := config.LoadAppConfig()
+	if err != nil || appCfg == nil {
+		return config.ShellSandboxConfig{}, err
+	}
+	return appCfg.Security.Shell, nil
+}
+
+// SetShellSandbox sets the sandbox of shell_command, from security.shell.
+func SetShellSandbox(s *ShellSandbox) {
+	shellSandboxMu.Lock()
+	shellSandbox = s
+	shellSandboxLoaded = true
+	shellSandboxMu.Unlock()
+}
+
+// shellSandboxFromConfig compiles the security.shell settings. Invalid
+// settings give a sandbox that refuses every command, rather than running
+// them unconfined.
+func shellSandboxFromConfig(cfg config.ShellSandboxConfig) *ShellSandbox {
+	s, err := NewShellSandbox(cfg)
+	if err != nil {
+		slog.Error("invalid security.shell settings, shell_command is disabled", "error", err)
+		return &ShellSandbox{invalid: err}
+	}
+	return s
+}
+
+// currentShellSandbox returns the sandbox of shell_command, loading it
+// from the app config on first use, so commands run without
+// GetInternalTools (ExecuteTool) are confined too.
+func currentShellSandbox() *ShellSandbox {
+	shellSandboxMu.RLock()
+	s, loaded := shellSandbox, shellSandboxLoaded
+	shellSandboxMu.RUnlock()
+	if loaded {
+		return s
+	}
+
+	cfg, err := loadShellSandboxConfig()
+	if err != nil {
+		slog.Warn("failed to load security.shell", "error", err)
+	}
+	SetShellSandbox(shellSandboxFromConfig(cfg))
+	shellSandboxMu.RLock()
+	defer shellSandboxMu.RUnlock()
+	return shellSandbox
+}
+
+// WithNodeEnv returns the sandbox for the commands of a node with env:
+// its variables are added to the environment, also with scrub_env and in
+// the container.
+func (s *ShellSandbox) WithNodeEnv(env map[string]string) *ShellSandbox {
+	if len(env) == 0 {
+		return s
+	}
+	var scoped ShellSandbox
+	if s != nil {
+		scoped = *s
+	}
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	scoped.nodeEnv = nil
+	for _, name := range names {
+		scoped.nodeEnv = append(scoped.nodeEnv, name+"="+env[name])
+	}
+	return &scoped
+}
+
+// shellSandboxFor returns the sandbox for a command run in ctx: the
+// configured one, narrowed by the shell section of the running flow and
+// with the env of the running node.
+func shellSandboxFor(ctx context.Context) (*ShellSandbox, error) {
+	s := currentShellSandbox()
+	if ctx == nil {
+		return s, nil
+	}
+	s, err := s.ForFlow(agent.FlowShell(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return s.WithNodeEnv(agent.NodeEnv(ctx)), nil
+}
+
+// command returns the process running command in workDir, or an error
+// when the sandbox refuses it.
+func (s *ShellSandbox) command(command, workDir string) (*exec.Cmd, error) {
+	if workDir != "" {
+		workDir = expandPath(workDir)
+	}
+	if s == nil {
+		cmd := shellExec("", command)
+		cmd.Dir = workDir
+		cmd.Env = processEnv(os.Environ())
+		return cmd, nil
+	}
+
+	if s.invalid != nil {
+		return nil, fmt.Errorf("shell_command is disabled: %w", s.invalid)
+	}
+	for _, re := range s.deny {
+		if re.MatchString(command) {
+			return nil, fmt.Errorf("access denied: command matches denied pattern %q (security.shell.deny_commands)", re.String())
+		}
+	}
+	if len(s.allow) > 0 && !matchesAny(s.allow, command) {
+		return nil, fmt.Errorf("access denied: command matches none of security.shell.allow_commands")
+	}
+
+	if s.root != "" {
+		if workDir == "" {
+			workDir = s.root
+		}
+		resolved := resolvePath(workDir)
+		if resolved != s.root && !strings.HasPrefix(resolved, s.root+string(filepath.Separator)) {
+			return nil, fmt.Errorf("access denied: working directory %s is outside security.shell.working_dir", workDir)
+		}
+		workDir = resolved
+	}
+
+	env := os.Environ()
+	if s.scrubEnv {
+		env = s.scrubbedEnv(env)
+	}
+	env = append(env, s.nodeEnv...)
+
+	if s.container == nil {
+		cmd := shellExec(s.shell, command)
+		cmd.Dir = workDir
+		cmd.Env = processEnv(env)
+		return cmd, nil
+	}
+	return s.containerCommand(command, workDir, env)
+}
+
+// containerCommand runs command in a throwaway container. The working
+// directory (or the sandbox root) is mounted at the same path, and only
+// env_allow variables are passed in.
+func (s *ShellSandbox) containerCommand(command, workDir string, env []string) (*exec.Cmd, error) {
+	if s.container.Image == "" {
+		return nil, fmt.Errorf("security.shell.container: image is required")
+	}
+	runtime := s.container.Runtime
+	if runtime == "" {
+		runtime = "docker"
+	}
+	if workDir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		workDir = wd
+	}
+	mount := workDir
+	if s.root != "" {
+		mount = s.root
+	}
+
+	args := []string{"run", "--rm", "-i", "-t", "-v", mount + ":" + mount, "-w", workDir}
+	if s.container.Network != "" {
+		args = append(args, "--network", s.container.Network)
+	}
+	for _, name := range s.envAllow {
+		args = append(args, "-e", name)
+	}
+	for _, kv := range s.nodeEnv {
+		name, _, _ := strings.Cut(kv, "=")
+		args = append(args, "-e", name)
+	}
+	args = append(args, "-e", "TERM=xterm-256color", s.container.Image, "sh", "-c", command)
+
+	cmd := exec.Command(runtime, args...)
+	cmd.Env = processEnv(env)
+	return cmd, nil
+}
+
+// scrubbedEnv keeps the basic variables, locale settings and env_allow.
+func (s *ShellSandbox) scrubbedEnv(env []string) []string {
+	keep := make(map[string]bool)
+	for _, name := range append(append([]string{}, baseShellEnv...), s.envAllow...) {
+		keep[name] = true
+	}
+	var scrubbed []string
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		if keep[name] || strings.HasPrefix(name, "LC_") {
+			scrubbed = append(scrubbed, kv)
+		}
+	}
+	return scrubbed
+}
+
+// timeout caps a command's timeout in seconds at max_runtime.
+func (s *ShellSandbox) timeout(seconds int) int {
+	if s == nil || s.maxRuntime <= 0 {
+		return seconds
+	}
+	return min(seconds, max(1, int(s.maxRuntime/time.Second)))
+}
+
+// limitOutput cuts output beyond max_output_bytes.
+func (s *ShellSandbox) limitOutput(output string) string {
+	if s == nil || s.maxOutput <= 0 || len(output) <= s.maxOutput {
+		return output
+	}
+	return output[:s.maxOutput] + fmt.Sprintf("\n[output truncated: %d of %d bytes shown (security.shell.max_output_bytes)]", s.maxOutput, len(output))
+}
+
+func matchesAny(patterns []*regexp.Regexp, s string) bool {
+	for _, re := range patterns {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,201 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/config"
+)
+
+func TestShellSandbox_Commands(t *testing.T) {
+	s, err := NewShellSandbox(config.ShellSandboxConfig{
+		AllowCommands: []string{`^(ls|git|echo)\b`},
+		DenyCommands:  []string{`\bgit\s+push\b`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for cmd, allowed := range map[string]bool{
+		"ls -la":          true,
+		"git status":      true,
+		"git push origin": false,
+		"rm -rf build":    false,
+	} {
+		_, err := s.command(cmd, "")
+		if (err == nil) != allowed {
+			t.Errorf("command(%q) err = %v, want allowed %v", cmd, err, allowed)
+		}
+	}
+
+	// A flow denies further commands but cannot lift the configured ones
+	flow, err := s.ForFlow(&config.FlowShellConfig{DenyCommands: []string{`^ls\b`}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := flow.command("ls", ""); err == nil {
+		t.Error("flow deny_commands not applied")
+	}
+	if _, err := flow.command("git push", ""); err == nil {
+		t.Error("flow lifted security.shell.deny_commands")
+	}
+	if _, err := s.command("ls", ""); err != nil {
+		t.Errorf("flow deny_commands leaked into the configured sandbox: %v", err)
+	}
+
+	if _, err := NewShellSandbox(config.ShellSandboxConfig{DenyCommands: []string{"("}}); err == nil {
+		t.Error("invalid pattern accepted")
+	}
+	broken := &ShellSandbox{invalid: os.ErrInvalid}
+	if _, err := broken.command("echo hi", ""); err == nil {
+		t.Error("invalid settings did not disable the tool")
+	}
+}
+
+func TestShellSandbox_WorkingDir(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "src"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewShellSandbox(config.ShellSandboxConfig{WorkingDir: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd, err := s.command("pwd", "")
+	if err != nil || cmd.Dir != resolvePath(root) {
+		t.Errorf("default dir = %v, %v; want the sandbox root", cmd, err)
+	}
+	if _, err := s.command("pwd", filepath.Join(root, "src")); err != nil {
+		t.Errorf("subdirectory refused: %v", err)
+	}
+	for _, dir := range []string{t.TempDir(), filepath.Join(root, "..")} {
+		if _, err := s.command("pwd", dir); err == nil {
+			t.Errorf("working dir %s outside the root was allowed", dir)
+		}
+	}
+}
+
+func TestShellSandbox_EnvAndContainer(t *testing.T) {
+	t.Setenv("ASTONISH_TEST_SECRET", "s3cret")
+	t.Setenv("GOPATH", "/go")
+	s, err := NewShellSandbox(config.ShellSandboxConfig{
+		ScrubEnv: true,
+		EnvAllow: []string{"GOPATH"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd, err := s.command("env", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slices.Contains(cmd.Env, "ASTONISH_TEST_SECRET=s3cret") {
+		t.Error("scrub_env passed a variable that is not allowed")
+	}
+	if !slices.Contains(cmd.Env, "GOPATH=/go") || !slices.Contains(cmd.Env, "EDITOR=true") {
+		t.Errorf("env = %v, want env_allow and the process defaults", cmd.Env)
+	}
+
+	flow, _ := s.ForFlow(&config.FlowShellConfig{Container: &config.ShellContainerConfig{Runtime: "podman", Image: "alpine:3.20", Network: "none"}})
+	dir := t.TempDir()
+	cmd, err = flow.command("make test", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(cmd.Args, " ")
+	want := "podman run --rm -i -t -v " + dir + ":" + dir + " -w " + dir + " --network none -e GOPATH -e TERM=xterm-256color alpine:3.20 sh -c make test"
+	if got != want {
+		t.Errorf("container command =\n%s\nwant\n%s", got, want)
+	}
+
+	// A flow only picks the image of a configured container
+	confined, err := NewShellSandbox(config.ShellSandboxConfig{Container: &config.ShellContainerConfig{Image: "alpine:3.20", Network: "none"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	flow, err = confined.ForFlow(&config.FlowShellConfig{Container: &config.ShellContainerConfig{Runtime: "podman", Image: "python:3.12", Network: "host"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd, err = flow.command("make test", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "docker run --rm -i -t -v " + dir + ":" + dir + " -w " + dir + " --network none -e TERM=xterm-256color python:3.12 sh -c make test"
+	if got := strings.Join(cmd.Args, " "); got != want {
+		t.Errorf("flow container command =\n%s\nwant\n%s", got, want)
+	}
+	if _, err := s.ForFlow(&config.FlowShellConfig{Container: &config.ShellContainerConfig{Runtime: "/tmp/evil", Image: "alpine"}}); err == nil {
+		t.Error("a flow container with an unknown runtime was accepted")
+	}
+	if _, err := NewShellSandbox(config.ShellSandboxConfig{Container: &config.ShellContainerConfig{Runtime: "sh", Image: "alpine"}}); err == nil {
+		t.Error("a configured container with an unknown runtime was accepted")
+	}
+
+	// A node's env survives scrub_env and is passed into the container
+	scoped := s.WithNodeEnv(map[string]string{"GITHUB_TOKEN": "t"})
+	if cmd, _ := scoped.command("env", ""); !slices.Contains(cmd.Env, "GITHUB_TOKEN=t") {
+		t.Errorf("env = %v, want the node's GITHUB_TOKEN", cmd.Env)
+	}
+	cmd, _ = flow.WithNodeEnv(map[string]string{"GITHUB_TOKEN": "t"}).command("make test", dir)
+	if !slices.Contains(cmd.Args, "GITHUB_TOKEN") || !slices.Contains(cmd.Env, "GITHUB_TOKEN=t") {
+		t.Errorf("container command %v does not pass the node's GITHUB_TOKEN", cmd.Args)
+	}
+	var unrestricted *ShellSandbox
+	if cmd, _ := unrestricted.WithNodeEnv(map[string]string{"A": "1"}).command("env", ""); !slices.Contains(cmd.Env, "A=1") {
+		t.Error("a node env on an unrestricted shell was dropped")
+	}
+}
+
+func TestShellSandbox_Limits(t *testing.T) {
+	s, err := NewShellSandbox(config.ShellSandboxConfig{MaxRuntime: "30s", MaxOutputBytes: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.timeout(120); got != 30 {
+		t.Errorf("timeout(120) = %d, want max_runtime 30", got)
+	}
+	if got := s.timeout(10); got != 10 {
+		t.Errorf("timeout(10) = %d, want 10", got)
+	}
+	if got := s.limitOutput("hello world"); !strings.HasPrefix(got, "hello\n[output truncated: 5 of 11 bytes") {
+		t.Errorf("limitOutput = %q", got)
+	}
+
+	SetShellSandbox(s)
+	defer SetShellSandbox(nil)
+	result, err := ShellCommand(nil, ShellCommandArgs{Command: "echo hello world"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Stdout, "[output truncated") {
+		t.Errorf("Stdout = %q, want it cut at max_output_bytes", result.Stdout)
+	}
+	if s, _ := NewShellSandbox(config.ShellSandboxConfig{}); s != nil {
+		t.Error("empty settings created a sandbox")
+	}
+}
+
+func TestShellSandboxLoadedOnFirstCommand(t *testing.T) {
+	// As for commands run through ExecuteTool, without GetInternalTools
+	shellSandboxMu.Lock()
+	shellSandbox, shellSandboxLoaded = nil, false
+	shellSandboxMu.Unlock()
+	prevLoad := loadShellSandboxConfig
+	loadShellSandboxConfig = func() (config.ShellSandboxConfig, error) {
+		return config.ShellSandboxConfig{DenyCommands: []string{`^echo\b`}}, nil
+	}
+	t.Cleanup(func() {
+		loadShellSandboxConfig = prevLoad
+		SetShellSandbox(nil)
+	})
+
+	_, err := ExecuteTool(context.Background(), "shell_command", map[string]any{"command": "echo hi"}, "test")
+	if err == nil || !strings.Contains(err.Error(), "security.shell.deny_commands") {
+		t.Errorf("expected the configured deny_commands to refuse the command, got %v", err)
+	}
+}
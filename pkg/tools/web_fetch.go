@@ -13,6 +13,7 @@ import (
 
 	readability "codeberg.org/readeck/go-readability/v2"
 	htmltomarkdown "github.com/JohannesKaufmann/html-to-markdown/v2"
+	"github.com/SAP/astonish/pkg/provider/httpool"
 	"golang.org/x/net/html"
 	"google.golang.org/adk/tool"
 )
@@ -156,7 +157,8 @@ func checkSSRF(hostname string) error {
 func fetchURL(rawURL string) (body, finalURL, contentType string, statusCode int, err error) {
 	redirectCount := 0
 	client := &http.Client{
-		Timeout: webFetchTimeout,
+		Transport: httpool.Transport(),
+		Timeout:   webFetchTimeout,
 		CheckRedirect: func(_ *http.Request, via []*http.Request) error {
 			redirectCount++
 			if redirectCount > webFetchMaxRedirects {
@@ -178,6 +178,14 @@ func getIconAndStyle(nodeName string, nodeType string, isEnd bool) (string, lipg
 		return "📥", inputStyle
 	case "update_state":
 		return "💾", stateStyle
+	case "script":
+		return "📜", stateStyle
+	case "memory":
+		return "🧠", stateStyle
+	case "vector":
+		return "🔎", stateStyle
+	case "abort":
+		return "🛑", endStyle
 	case "system":
 		return "⚡", systemStyle
 	default:
@@ -85,45 +85,161 @@ func readSelectionFallback(options []string, title string, description string) (
 	return options[choice-1], nil
 }
 
-// ReadInput prompts the user for text input using huh
-func ReadInput(title string, description string) (string, error) {
+// ReadMultiSelection prompts the user to check any number of options using huh
+func ReadMultiSelection(options []string, title string, description string) ([]string, error) {
+	if len(options) == 0 {
+		return nil, fmt.Errorf("no options provided")
+	}
+
 	// Fall back to simple input if running under debugger
 	if isRunningUnderDebugger() {
-		return readInputFallback(title, description)
+		return readMultiSelectionFallback(options, title, description)
 	}
 
-	var input string
+	var selected []string
+
+	huhOptions := make([]huh.Option[string], len(options))
+	for i, opt := range options {
+		huhOptions[i] = huh.NewOption(opt, opt)
+	}
 
 	form := huh.NewForm(
 		huh.NewGroup(
-			huh.NewInput().
+			huh.NewMultiSelect[string]().
 				Title(title).
 				Description(description).
-				Value(&input),
+				Options(huhOptions...).
+				Value(&selected),
 		),
 	)
 
 	err := form.Run()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return input, nil
+	return selected, nil
 }
 
-// readInputFallback provides simple text-based input when TTY is not available
-func readInputFallback(title string, description string) (string, error) {
+// readMultiSelectionFallback reads comma-separated choices when TTY is not available
+func readMultiSelectionFallback(options []string, title string, description string) ([]string, error) {
 	fmt.Println("\n" + title)
 	if description != "" {
 		fmt.Println(description)
 	}
-	fmt.Print("\nInput: ")
+	fmt.Println()
+
+	for i, opt := range options {
+		fmt.Printf("%d. %s\n", i+1, opt)
+	}
+
+	fmt.Print("\nEnter your choices, separated by commas (1-", len(options), "): ")
 
 	reader := bufio.NewReader(os.Stdin)
 	input, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []string
+	for _, field := range strings.Split(input, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		choice, err := strconv.Atoi(field)
+		if err != nil || choice < 1 || choice > len(options) {
+			return nil, fmt.Errorf("invalid choice: %s", field)
+		}
+		selected = append(selected, options[choice-1])
+	}
+
+	return selected, nil
+}
+
+// InputOptions change how ReadInputWith reads an answer.
+type InputOptions struct {
+	Secret    bool   // Do not echo the answer
+	Multiline bool   // Read a text area instead of a single line
+	Default   string // Shown as placeholder; an empty answer means the default
+}
+
+// ReadInput prompts the user for text input using huh
+func ReadInput(title string, description string) (string, error) {
+	return ReadInputWith(title, description, InputOptions{})
+}
+
+// ReadInputWith prompts the user for text input using huh, masked,
+// multi-line or with a default as opts say
+func ReadInputWith(title string, description string, opts InputOptions) (string, error) {
+	// Fall back to simple input if running under debugger
+	if isRunningUnderDebugger() {
+		return readInputFallback(title, description, opts)
+	}
+
+	var input string
+
+	var field huh.Field
+	if opts.Multiline {
+		field = huh.NewText().
+			Title(title).
+			Description(description).
+			Placeholder(opts.Default).
+			Value(&input)
+	} else {
+		in := huh.NewInput().
+			Title(title).
+			Description(description).
+			Placeholder(opts.Default).
+			Value(&input)
+		if opts.Secret {
+			in = in.EchoMode(huh.EchoModePassword)
+		}
+		field = in
+	}
+	form := huh.NewForm(huh.NewGroup(field))
+
+	err := form.Run()
 	if err != nil {
 		return "", err
 	}
 
-	return strings.TrimSpace(input), nil
+	return input, nil
+}
+
+// readInputFallback provides simple text-based input when TTY is not available.
+// Multi-line input ends with an empty line.
+func readInputFallback(title string, description string, opts InputOptions) (string, error) {
+	fmt.Println("\n" + title)
+	if description != "" {
+		fmt.Println(description)
+	}
+	switch {
+	case opts.Multiline:
+		fmt.Print("\nInput (end with an empty line): ")
+	case opts.Default != "":
+		fmt.Printf("\nInput [%s]: ", opts.Default)
+	default:
+		fmt.Print("\nInput: ")
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	if !opts.Multiline {
+		input, err := reader.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(input), nil
+	}
+
+	var lines []string
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" || err != nil {
+			break
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n"), nil
 }
@@ -0,0 +1,159 @@
+package ui
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// diffRow is one line of a diff: ' ' (context), '-' or '+'.
+type diffRow struct {
+	op   byte
+	text string
+}
+
+// RenderDiff renders a unified diff of the file at path for the console:
+// removed and added lines colored, their code highlighted by the file's
+// extension, either as one column or side by side. Headers and hunk
+// markers are shown as a title and dividers.
+func RenderDiff(diff, path string, sideBySide bool) string {
+	theme := CurrentTheme()
+	titleStyle := lipgloss.NewStyle().Foreground(theme.Accent).Bold(true)
+	mutedStyle := lipgloss.NewStyle().Foreground(theme.Muted)
+
+	if path == "" {
+		path = "changes"
+	}
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(theme.icon("📝 ", "")+path) + "\n")
+	if diff == "" {
+		b.WriteString(mutedStyle.Render("No changes") + "\n")
+		return b.String()
+	}
+
+	highlight := highlighter(path, theme)
+	var hunk []diffRow
+	flush := func() {
+		if len(hunk) == 0 {
+			return
+		}
+		if sideBySide {
+			b.WriteString(renderSideBySide(hunk, highlight, theme))
+		} else {
+			b.WriteString(renderUnified(hunk, highlight, theme))
+		}
+		hunk = nil
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "--- "), strings.HasPrefix(line, "+++ "):
+		case strings.HasPrefix(line, "@@"):
+			flush()
+			b.WriteString(mutedStyle.Render(line) + "\n")
+		case line == "":
+			hunk = append(hunk, diffRow{op: ' '})
+		case strings.HasPrefix(line, `\`): // "\ No newline at end of file"
+		default:
+			hunk = append(hunk, diffRow{op: line[0], text: line[1:]})
+		}
+	}
+	flush()
+	return b.String()
+}
+
+// renderUnified renders the rows of a hunk in one column.
+func renderUnified(rows []diffRow, highlight func(string) string, theme Theme) string {
+	var b strings.Builder
+	for _, r := range rows {
+		b.WriteString(diffMarker(r.op, theme) + highlight(r.text) + "\n")
+	}
+	return b.String()
+}
+
+// renderSideBySide renders the rows of a hunk in two columns: the old text
+// left, the new text right, with removed and added runs paired up.
+func renderSideBySide(rows []diffRow, highlight func(string) string, theme Theme) string {
+	width := theme.boxWidth(160)
+	sep := lipgloss.NewStyle().Foreground(theme.Divider).Render(" " + theme.icon("│", "|") + " ")
+	col := (width - 3) / 2
+	cell := func(r *diffRow) string {
+		if r == nil {
+			return strings.Repeat(" ", col)
+		}
+		text := ansi.Truncate(diffMarker(r.op, theme)+highlight(r.text), col, "…")
+		return text + strings.Repeat(" ", max(col-ansi.StringWidth(text), 0))
+	}
+
+	var b strings.Builder
+	var removed, added []diffRow
+	pair := func() {
+		for i := 0; i < max(len(removed), len(added)); i++ {
+			var left, right *diffRow
+			if i < len(removed) {
+				left = &removed[i]
+			}
+			if i < len(added) {
+				right = &added[i]
+			}
+			b.WriteString(cell(left) + sep + strings.TrimRight(cell(right), " ") + "\n")
+		}
+		removed, added = nil, nil
+	}
+	for i := range rows {
+		switch rows[i].op {
+		case '-':
+			removed = append(removed, rows[i])
+		case '+':
+			added = append(added, rows[i])
+		default:
+			pair()
+			b.WriteString(cell(&rows[i]) + sep + strings.TrimRight(cell(&rows[i]), " ") + "\n")
+		}
+	}
+	pair()
+	return b.String()
+}
+
+// diffMarker returns the colored -, + or blank column of a diff line.
+func diffMarker(op byte, theme Theme) string {
+	switch op {
+	case '-':
+		return lipgloss.NewStyle().Foreground(theme.Error).Render("-")
+	case '+':
+		return lipgloss.NewStyle().Foreground(theme.Success).Render("+")
+	}
+	return " "
+}
+
+// highlighter returns a function that highlights one line of code of the
+// file at path, or leaves it as is without colors or a known language.
+func highlighter(path string, theme Theme) func(string) string {
+	plain := func(s string) string { return s }
+	lexer := lexers.Match(path)
+	if theme.NoColor || lexer == nil {
+		return plain
+	}
+	lexer = chroma.Coalesce(lexer)
+	style := styles.Get("monokai")
+	if theme.Name == "light" {
+		style = styles.Get("github")
+	}
+	formatter := formatters.Get("terminal256")
+	return func(s string) string {
+		it, err := lexer.Tokenise(nil, s)
+		if err != nil {
+			return s
+		}
+		var buf bytes.Buffer
+		if err := formatter.Format(&buf, style, it); err != nil {
+			return s
+		}
+		return strings.TrimRight(buf.String(), "\n")
+	}
+}
@@ -0,0 +1,35 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderDiff(t *testing.T) {
+	prev := CurrentTheme()
+	t.Cleanup(func() { SetTheme(prev) })
+	theme := monoTheme()
+	theme.ASCII, theme.Width = true, 41
+	SetTheme(theme)
+
+	diff := "--- a/app.py\n+++ b/app.py\n@@ -1,2 +1,2 @@\n x = 1\n-print(x)\n+print(x + 1)\n"
+
+	unified := RenderDiff(diff, "app.py", false)
+	for _, want := range []string{"app.py\n", "@@ -1,2 +1,2 @@\n", "\n x = 1\n", "-print(x)\n", "+print(x + 1)\n"} {
+		if !strings.Contains(unified, want) {
+			t.Errorf("unified diff is missing %q:\n%s", want, unified)
+		}
+	}
+	if strings.Contains(unified, "+++") {
+		t.Errorf("file headers were not dropped:\n%s", unified)
+	}
+
+	sideBySide := RenderDiff(diff, "app.py", true)
+	if !strings.Contains(sideBySide, "-print(x)          | +print(x + 1)\n") {
+		t.Errorf("removed and added lines are not paired:\n%s", sideBySide)
+	}
+
+	if got := RenderDiff("", "app.py", false); !strings.Contains(got, "No changes") {
+		t.Errorf("empty diff = %q", got)
+	}
+}
@@ -2,81 +2,69 @@ package ui
 
 import (
 	"fmt"
-	"os"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
-	"golang.org/x/term"
 )
 
-var (
-	// --- COLORS ---
-	colorRed    = lipgloss.Color("196")
-	colorOrange = lipgloss.Color("#FFA500")
-	colorYellow = lipgloss.Color("226")
-	colorWhite  = lipgloss.Color("252")
-	colorGrey   = lipgloss.Color("240")
-
-	// --- STYLES ---
-
+// errorStyles are the styles of retry badges and error boxes.
+type errorStyles struct {
 	// 1. Retry Badge
-	retryBadgeStyle = lipgloss.NewStyle().
-			Foreground(colorOrange).
-			Bold(true)
-
-	retryMessageStyle = lipgloss.NewStyle().
-				Foreground(colorGrey).
-				PaddingLeft(1)
+	retryBadge   lipgloss.Style
+	retryMessage lipgloss.Style
 
 	// 2. Error Components
-	headerStyle = lipgloss.NewStyle().
-			Foreground(colorRed).
-			Bold(true)
-
-	// Indentation wrapper
-	indentStyle = lipgloss.NewStyle().
-			PaddingLeft(3)
+	header lipgloss.Style
+	indent lipgloss.Style // Indentation wrapper
 
 	// Section Text Styles
-	reasonTextStyle = lipgloss.NewStyle().
-			Foreground(colorWhite)
-
-	suggestionTitleStyle = lipgloss.NewStyle().
-				Foreground(colorYellow).
-				Bold(true)
-
-	suggestionTextStyle = lipgloss.NewStyle().
-				Foreground(colorYellow)
-
-	rawErrorTitleStyle = lipgloss.NewStyle().
-				Foreground(colorGrey).
-				Bold(true)
+	reasonText      lipgloss.Style
+	suggestionTitle lipgloss.Style
+	suggestionText  lipgloss.Style
+	rawErrorTitle   lipgloss.Style
+	rawErrorText    lipgloss.Style
+}
 
-	rawErrorTextStyle = lipgloss.NewStyle().
-				Foreground(colorGrey)
-)
+func newErrorStyles(t Theme) errorStyles {
+	return errorStyles{
+		retryBadge:      lipgloss.NewStyle().Foreground(t.Notice).Bold(true),
+		retryMessage:    lipgloss.NewStyle().Foreground(t.Muted).PaddingLeft(1),
+		header:          lipgloss.NewStyle().Foreground(t.Error).Bold(true),
+		indent:          lipgloss.NewStyle().PaddingLeft(3),
+		reasonText:      lipgloss.NewStyle().Foreground(t.Text),
+		suggestionTitle: lipgloss.NewStyle().Foreground(t.Warning).Bold(true),
+		suggestionText:  lipgloss.NewStyle().Foreground(t.Warning),
+		rawErrorTitle:   lipgloss.NewStyle().Foreground(t.Muted).Bold(true),
+		rawErrorText:    lipgloss.NewStyle().Foreground(t.Muted),
+	}
+}
 
 // RenderRetryBadge: Clean text-only line
 func RenderRetryBadge(attempt, maxRetries int, oneLiner string) string {
-	badge := retryBadgeStyle.Render(fmt.Sprintf("⟳ Retry %d/%d:", attempt, maxRetries))
-	message := retryMessageStyle.Render(oneLiner)
+	theme := CurrentTheme()
+	styles := newErrorStyles(theme)
+	badge := styles.retryBadge.Render(fmt.Sprintf("%s Retry %d/%d:", theme.icon("⟳", "~"), attempt, maxRetries))
+	message := styles.retryMessage.Render(oneLiner)
 	return lipgloss.JoinHorizontal(lipgloss.Left, badge, message)
 }
 
 // RenderErrorBox: Pure lipgloss implementation with dynamic wrapping
 func RenderErrorBox(title, reason, suggestion, originalError string) string {
+	theme := CurrentTheme()
+	styles := newErrorStyles(theme)
+
 	// 1. Calculate Width (Crucial for fixing line breaks)
-	width, _, err := term.GetSize(int(os.Stdout.Fd()))
-	if err != nil || width <= 0 {
-		width = 80 // Fallback
+	width := theme.Width
+	if width <= 0 {
+		width = terminalWidth()
 	}
 	// Content width = Terminal Width - Indent(3) - Safety Margin(2)
 	contentWidth := width - 5
 
 	// 2. Header (Now Indented!)
 	// We render the red text first, then wrap it in the indentation style
-	rawHeader := headerStyle.Render(fmt.Sprintf("✕ %s", title))
-	header := indentStyle.Render(rawHeader)
+	rawHeader := styles.header.Render(fmt.Sprintf("%s %s", theme.icon("✕", "x"), title))
+	header := styles.indent.Render(rawHeader)
 
 	// 3. Build Body Blocks
 	var bodyBlocks []string
@@ -91,15 +79,15 @@ func RenderErrorBox(title, reason, suggestion, originalError string) string {
 	// Reason
 	if reason != "" {
 		// Setting Width() forces lipgloss to wrap, preserving the indentation on new lines
-		bodyBlocks = append(bodyBlocks, reasonTextStyle.Width(contentWidth).Render(reason))
+		bodyBlocks = append(bodyBlocks, styles.reasonText.Width(contentWidth).Render(reason))
 	}
 
 	// Suggestion
 	if suggestion != "" {
 		addSpacer()
 		bodyBlocks = append(bodyBlocks,
-			suggestionTitleStyle.Render("Suggestion:"),
-			suggestionTextStyle.Width(contentWidth).Render(suggestion),
+			styles.suggestionTitle.Render("Suggestion:"),
+			styles.suggestionText.Width(contentWidth).Render(suggestion),
 		)
 	}
 
@@ -107,8 +95,8 @@ func RenderErrorBox(title, reason, suggestion, originalError string) string {
 	if originalError != "" {
 		addSpacer()
 		bodyBlocks = append(bodyBlocks,
-			rawErrorTitleStyle.Render("Raw Error:"),
-			rawErrorTextStyle.Width(contentWidth).Render(strings.TrimSpace(originalError)),
+			styles.rawErrorTitle.Render("Raw Error:"),
+			styles.rawErrorText.Width(contentWidth).Render(strings.TrimSpace(originalError)),
 		)
 	}
 
@@ -117,7 +105,7 @@ func RenderErrorBox(title, reason, suggestion, originalError string) string {
 	bodyContent := lipgloss.JoinVertical(lipgloss.Left, bodyBlocks...)
 
 	// Apply indentation to the whole body
-	indentedBody := indentStyle.Render(bodyContent)
+	indentedBody := styles.indent.Render(bodyContent)
 
 	// Join Header + Indented Body
 	return fmt.Sprintf("\n%s\n%s\n", header, indentedBody)
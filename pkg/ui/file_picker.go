@@ -0,0 +1,138 @@
+package ui
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+)
+
+// maxPickerFiles caps the files offered by ReadFiles, so a picker rooted at
+// a large tree opens quickly.
+const maxPickerFiles = 10000
+
+// skippedDirs are not descended into when listing files.
+var skippedDirs = map[string]bool{"node_modules": true, "vendor": true, "__pycache__": true}
+
+// ListFiles returns the paths, relative to root, of the files under root
+// whose names match pattern (all files when empty). Hidden files and
+// directories and dependency directories are skipped; at most limit paths
+// are returned.
+func ListFiles(root, pattern string, limit int) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return nil // Unreadable entries are left out
+		}
+		name := d.Name()
+		if path != root && strings.HasPrefix(name, ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if skippedDirs[name] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if pattern != "" {
+			if ok, _ := filepath.Match(pattern, name); !ok {
+				return nil
+			}
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+		files = append(files, filepath.ToSlash(rel))
+		if len(files) >= limit {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return files, err
+}
+
+// ReadFiles lets the user pick files under root with a filterable list
+// (type / to filter), one or, with multi, several. The paths are relative
+// to root.
+func ReadFiles(root, pattern string, multi bool, title string, description string) ([]string, error) {
+	// Fall back to simple input if running under debugger
+	if isRunningUnderDebugger() {
+		return readFilesFallback(root, multi, title, description)
+	}
+
+	files, err := ListFiles(root, pattern, maxPickerFiles)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files to choose from in %s", root)
+	}
+
+	huhOptions := make([]huh.Option[string], len(files))
+	for i, f := range files {
+		huhOptions[i] = huh.NewOption(f, f)
+	}
+	if description == "" {
+		description = root
+	}
+
+	var selected []string
+	var one string
+	var field huh.Field
+	if multi {
+		field = huh.NewMultiSelect[string]().
+			Title(title).
+			Description(description).
+			Options(huhOptions...).
+			Filterable(true).
+			Height(15).
+			Value(&selected)
+	} else {
+		field = huh.NewSelect[string]().
+			Title(title).
+			Description(description).
+			Options(huhOptions...).
+			Height(15).
+			Value(&one)
+	}
+
+	if err := huh.NewForm(huh.NewGroup(field)).Run(); err != nil {
+		return nil, err
+	}
+	if !multi {
+		selected = []string{one}
+	}
+	return selected, nil
+}
+
+// readFilesFallback reads paths (comma-separated with multi) when TTY is not available
+func readFilesFallback(root string, multi bool, title string, description string) ([]string, error) {
+	if multi {
+		description = strings.TrimSpace(description + "\n(paths relative to " + root + ", separated by commas)")
+	} else {
+		description = strings.TrimSpace(description + "\n(path relative to " + root + ")")
+	}
+	input, err := readInputFallback(title, description, InputOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if !multi {
+		return []string{input}, nil
+	}
+	var paths []string
+	for _, p := range strings.Split(input, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths, nil
+}
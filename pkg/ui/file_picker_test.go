@@ -0,0 +1,32 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestListFiles(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a.md", "b.go", "docs/c.md", ".git/d.md", "node_modules/e.md", ".hidden.md"} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := ListFiles(root, "*.md", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a.md", "docs/c.md"}; !slices.Equal(files, want) {
+		t.Errorf("ListFiles = %v, want %v", files, want)
+	}
+	if files, _ := ListFiles(root, "", 1); len(files) != 1 {
+		t.Errorf("limit 1 returned %v", files)
+	}
+}
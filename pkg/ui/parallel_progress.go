@@ -22,8 +22,11 @@ type ParallelModel struct {
 	lastLog     string
 }
 
-// ItemFinishedMsg signals that a worker has finished an item
-type ItemFinishedMsg struct{}
+// ItemFinishedMsg signals that a worker has finished an item.
+// A non-empty Result is printed above the progress bar.
+type ItemFinishedMsg struct {
+	Result string
+}
 
 // ActiveCountMsg signals an update to the number of active workers
 type ActiveCountMsg int
@@ -133,12 +136,18 @@ func (m ParallelModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case ItemFinishedMsg:
 		m.processed++
+		var cmd tea.Cmd
 		if m.processed >= m.totalItems {
 			m.done = true
-			return m, tea.Quit
+			cmd = tea.Quit
+		} else {
+			// Update progress bar
+			cmd = m.progress.SetPercent(float64(m.processed) / float64(m.totalItems))
+		}
+		if msg.Result != "" {
+			// Sequence so the line is printed before the program quits
+			return m, tea.Sequence(tea.Println(msg.Result), cmd)
 		}
-		// Update progress bar
-		cmd := m.progress.SetPercent(float64(m.processed) / float64(m.totalItems))
 		return m, cmd
 
 	case ActiveCountMsg:
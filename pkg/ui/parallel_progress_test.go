@@ -0,0 +1,32 @@
+package ui
+
+import "testing"
+
+func TestParallelModelItemFinished(t *testing.T) {
+	tests := []struct {
+		name          string
+		total         int
+		finished      []ItemFinishedMsg
+		wantProcessed int
+		wantDone      bool
+	}{
+		{name: "first of three", total: 3, finished: []ItemFinishedMsg{{}}, wantProcessed: 1},
+		{name: "with results", total: 3, finished: []ItemFinishedMsg{{Result: "[1/3] item 0 done: ok"}, {Result: "[2/3] item 2 failed"}}, wantProcessed: 2},
+		{name: "last item", total: 2, finished: []ItemFinishedMsg{{}, {Result: "[2/2] item 1 done"}}, wantProcessed: 2, wantDone: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := initialParallelModel(tt.total, "review")
+			for _, msg := range tt.finished {
+				next, cmd := m.Update(msg)
+				m = next.(ParallelModel)
+				if cmd == nil {
+					t.Fatalf("Update(%+v) returned no command", msg)
+				}
+			}
+			if m.processed != tt.wantProcessed || m.done != tt.wantDone {
+				t.Errorf("processed = %d, done = %v; want %d, %v", m.processed, m.done, tt.wantProcessed, tt.wantDone)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// RunSummaryRow is one node execution in the summary table of a run.
+type RunSummaryRow struct {
+	Node      string
+	Duration  time.Duration
+	Retries   int
+	ToolCalls int
+	Tokens    int
+}
+
+// RenderRunSummary renders the table printed when a run reaches END: the
+// nodes the run went through with their wall time, retries, tool calls and
+// tokens, the total time, and a footer with the run's other totals.
+func RenderRunSummary(rows []RunSummaryRow, total time.Duration, footer string) string {
+	theme := CurrentTheme()
+	titleStyle := lipgloss.NewStyle().Foreground(theme.Accent).Bold(true)
+	mutedStyle := lipgloss.NewStyle().Foreground(theme.Muted)
+	noticeStyle := lipgloss.NewStyle().Foreground(theme.Notice)
+
+	nameWidth := len("Node")
+	for _, r := range rows {
+		nameWidth = max(nameWidth, len([]rune(r.Node)))
+	}
+	nameWidth = min(nameWidth, traceNameWidth)
+	count := func(n int) string {
+		if n == 0 {
+			return "-"
+		}
+		return fmt.Sprint(n)
+	}
+
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(theme.icon("📊 ", "")+"Run summary") + "\n")
+	b.WriteString(mutedStyle.Render(fmt.Sprintf("  %-*s %8s %7s %6s %8s", nameWidth, "Node", "Time", "Retries", "Tools", "Tokens")) + "\n")
+	for _, r := range rows {
+		name := r.Node
+		if runes := []rune(name); len(runes) > nameWidth {
+			name = string(runes[:nameWidth-1]) + "…"
+		}
+		retries := fmt.Sprintf("%7s", count(r.Retries))
+		if r.Retries > 0 {
+			retries = noticeStyle.Render(retries)
+		}
+		b.WriteString(fmt.Sprintf("  %-*s %8s %s %6s %8s\n", nameWidth, name, formatElapsed(r.Duration), retries, count(r.ToolCalls), count(r.Tokens)))
+	}
+	b.WriteString(mutedStyle.Render(fmt.Sprintf("  %-*s %8s", nameWidth, "Total", formatElapsed(total))))
+	if footer != "" {
+		b.WriteString(mutedStyle.Render(" · " + footer))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
@@ -2,6 +2,7 @@ package ui
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
@@ -11,6 +12,7 @@ import (
 type SpinnerModel struct {
 	spinner  spinner.Model
 	text     string
+	preview  string
 	quitting bool
 }
 
@@ -19,6 +21,27 @@ type SpinnerTextMsg struct {
 	Text string
 }
 
+// SpinnerPreviewMsg is sent to update the dimmed preview line shown under
+// the spinner, e.g. the text a model is generating.
+type SpinnerPreviewMsg struct {
+	Text string
+}
+
+// spinnerPreviewWidth bounds the preview line under the spinner.
+const spinnerPreviewWidth = 72
+
+var spinnerPreviewStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("242")).Italic(true)
+
+// PreviewTail returns the end of text as a single line of at most
+// spinnerPreviewWidth characters, for SpinnerPreviewMsg.
+func PreviewTail(text string) string {
+	line := []rune(strings.Join(strings.Fields(text), " "))
+	if len(line) <= spinnerPreviewWidth {
+		return string(line)
+	}
+	return "…" + string(line[len(line)-spinnerPreviewWidth+1:])
+}
+
 func NewSpinner(text string) SpinnerModel {
 	s := spinner.New()
 	s.Spinner = spinner.Dot
@@ -44,6 +67,9 @@ func (m SpinnerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case SpinnerTextMsg:
 		m.text = msg.Text
 		return m, nil
+	case SpinnerPreviewMsg:
+		m.preview = msg.Text
+		return m, nil
 	}
 
 	var cmd tea.Cmd
@@ -55,5 +81,8 @@ func (m SpinnerModel) View() string {
 	if m.quitting {
 		return ""
 	}
+	if m.preview != "" {
+		return fmt.Sprintf("%s %s\n  %s", m.spinner.View(), m.text, spinnerPreviewStyle.Render(m.preview))
+	}
 	return fmt.Sprintf("%s %s", m.spinner.View(), m.text)
 }
@@ -0,0 +1,30 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPreviewTail(t *testing.T) {
+	if got := PreviewTail("{\n  \"label\":   \"bug\"\n"); got != `{ "label": "bug"` {
+		t.Errorf("PreviewTail() = %q, want the text on one line", got)
+	}
+
+	long := strings.Repeat("a", 100) + " the end"
+	got := PreviewTail(long)
+	if len([]rune(got)) != spinnerPreviewWidth || !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "the end") {
+		t.Errorf("PreviewTail() = %q, want the last %d characters", got, spinnerPreviewWidth)
+	}
+}
+
+func TestSpinnerPreview(t *testing.T) {
+	m := NewSpinner("Processing classify...")
+	if strings.Contains(m.View(), "\n") {
+		t.Fatalf("spinner without preview has several lines: %q", m.View())
+	}
+	updated, _ := m.Update(SpinnerPreviewMsg{Text: `{"label": "bu`})
+	view := updated.View()
+	if !strings.Contains(view, "Processing classify...") || !strings.Contains(view, `{"label": "bu`) {
+		t.Errorf("View() = %q, want the spinner text and the preview", view)
+	}
+}
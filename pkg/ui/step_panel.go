@@ -0,0 +1,74 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// stepPanelValueLines bounds the lines shown per prompt or value in the step
+// panel; longer text is cut with a note.
+const stepPanelValueLines = 12
+
+// RenderStepPanel renders the node the step debugger paused before: its
+// rendered prompt and system instruction, resolved tool arguments and the
+// state values it references. Empty sections are left out.
+func RenderStepPanel(node, nodeType, system, prompt string, args, vars map[string]any) string {
+	borderColor := lipgloss.Color("39") // Blue
+
+	boxStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(borderColor).
+		Padding(0, 1).
+		Width(76)
+
+	headerStyle := lipgloss.NewStyle().Foreground(borderColor).Bold(true)
+	sectionStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("244")).Bold(true)
+	textStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("252"))
+	keyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("244"))
+
+	blocks := []string{headerStyle.Render(fmt.Sprintf("⏸  Next: %s (%s)", node, nodeType))}
+	addText := func(title, text string) {
+		if strings.TrimSpace(text) == "" {
+			return
+		}
+		blocks = append(blocks, "", sectionStyle.Render(title), textStyle.Render(clipLines(text)))
+	}
+	addValues := func(title string, values map[string]any) {
+		if len(values) == 0 {
+			return
+		}
+		keys := make([]string, 0, len(values))
+		for k := range values {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		blocks = append(blocks, "", sectionStyle.Render(title))
+		for _, k := range keys {
+			val := strings.TrimRight(FormatAsYamlLike(values[k], 0), "\n")
+			if strings.Contains(val, "\n") {
+				blocks = append(blocks, keyStyle.Render(k+":"), textStyle.Render(clipLines(val)))
+				continue
+			}
+			blocks = append(blocks, keyStyle.Render(k+": ")+textStyle.Render(val))
+		}
+	}
+
+	addText("System", system)
+	addText("Prompt", prompt)
+	addValues("Arguments", args)
+	addValues("State", vars)
+
+	return boxStyle.Render(lipgloss.JoinVertical(lipgloss.Left, blocks...)) + "\n"
+}
+
+// clipLines cuts text to stepPanelValueLines lines.
+func clipLines(text string) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if len(lines) <= stepPanelValueLines {
+		return strings.Join(lines, "\n")
+	}
+	return strings.Join(lines[:stepPanelValueLines], "\n") + fmt.Sprintf("\n… (%d more lines)", len(lines)-stepPanelValueLines)
+}
@@ -9,34 +9,42 @@ import (
 
 // RenderToolBox renders a styled box for tool execution approval.
 func RenderToolBox(toolName string, args map[string]interface{}) string {
+	return RenderToolBoxWithReason(toolName, args, "")
+}
+
+// RenderToolBoxWithReason renders the tool box with the reason the model
+// gave for the call under its header, e.g. for approval requests.
+func RenderToolBoxWithReason(toolName string, args map[string]interface{}, reason string) string {
 	// --- Styles ---
-	borderColor := lipgloss.Color("63") // Purple
+	theme := CurrentTheme()
+	borderColor := theme.Accent
+	width := theme.boxWidth(60)
 
 	// The outer box
 	boxStyle := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
+		Border(theme.border()).
 		BorderForeground(borderColor).
 		BorderTop(true).
 		BorderLeft(true).
 		BorderRight(true).
 		BorderBottom(true).
 		Padding(0, 1). // Compact padding
-		Width(60)
+		Width(width)
 
 	// Style for the keys (e.g., "max_results:")
 	keyStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("244")). // Lighter Grey for better contrast
-		Width(14).                         // Fixed width for alignment
-		Align(lipgloss.Right).             // Right align looks cleaner for kv-pairs
+		Foreground(theme.Muted). // Lighter Grey for better contrast
+		Width(14).               // Fixed width for alignment
+		Align(lipgloss.Right).   // Right align looks cleaner for kv-pairs
 		MarginRight(1)
 
 	// Style for the values
 	valStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("252")) // White/Light Grey
+		Foreground(theme.Text)
 
 	// Style for numbers (optional pop of color)
 	numberStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("208")) // Orange for numbers
+		Foreground(theme.Number)
 
 	// --- Rendering Logic ---
 
@@ -79,42 +87,51 @@ func RenderToolBox(toolName string, args map[string]interface{}) string {
 	header := lipgloss.NewStyle().
 		Foreground(borderColor).
 		Bold(true).
-		Render("🛠  " + toolName)
+		Render(theme.icon("🛠  ", "") + toolName)
 
 	// 4. Create a subtle divider
 	divider := lipgloss.NewStyle().
-		Border(lipgloss.NormalBorder(), true, false, false, false). // Top border only
-		BorderForeground(lipgloss.Color("236")).                    // Very dark grey
-		Width(58).                                                  // Match box width approx
+		Border(theme.rule(), true, false, false, false). // Top border only
+		BorderForeground(theme.Divider).
+		Width(width - 2). // Match box width approx
 		Padding(0)
 
 	// 5. Join everything
 	body := lipgloss.JoinVertical(lipgloss.Left, rows...)
 
-	content := lipgloss.JoinVertical(lipgloss.Left,
-		header,
+	parts := []string{header}
+	if reason != "" {
+		parts = append(parts, lipgloss.NewStyle().
+			Foreground(theme.Muted).
+			Italic(true).
+			Width(width-2).
+			Render("Why: "+reason))
+	}
+	parts = append(parts,
 		divider.String(), // The new divider
 		body,
 	)
+	content := lipgloss.JoinVertical(lipgloss.Left, parts...)
 
 	return boxStyle.Render(content) + "\n"
 }
 
 // RenderStatusBadge renders a styled status badge (e.g. "✓ Command approved")
 func RenderStatusBadge(text string, success bool) string {
+	theme := CurrentTheme()
 	var icon string
-	var iconColor lipgloss.Color
+	var iconColor lipgloss.TerminalColor
 
 	if success {
-		icon = "✓"
-		iconColor = lipgloss.Color("42") // Green
+		icon = theme.icon("✓", "+")
+		iconColor = theme.Success
 	} else {
-		icon = "✗"
-		iconColor = lipgloss.Color("196") // Red
+		icon = theme.icon("✗", "x")
+		iconColor = theme.Error
 	}
 
 	checkStyle := lipgloss.NewStyle().Foreground(iconColor).SetString(icon)
-	textStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("244")) // Lighter Grey text
+	textStyle := lipgloss.NewStyle().Foreground(theme.Muted)
 
 	return checkStyle.String() + " " + textStyle.Render(text)
 }
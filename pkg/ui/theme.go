@@ -0,0 +1,190 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/SAP/astonish/pkg/config"
+	"golang.org/x/term"
+)
+
+// Theme is the color scheme and box style of console output: tool boxes,
+// status badges, retry badges and error boxes.
+type Theme struct {
+	Name    string
+	Accent  lipgloss.TerminalColor // Tool box border and header
+	Text    lipgloss.TerminalColor // Values and error reasons
+	Muted   lipgloss.TerminalColor // Keys, badge text, raw errors
+	Number  lipgloss.TerminalColor // Numeric values
+	Divider lipgloss.TerminalColor // Rule under box headers
+	Success lipgloss.TerminalColor
+	Error   lipgloss.TerminalColor
+	Warning lipgloss.TerminalColor // Suggestions
+	Notice  lipgloss.TerminalColor // Retry badges
+
+	NoColor bool // Colors are off (mono theme, NO_COLOR, output not a terminal)
+	ASCII   bool // Boxes and icons use ASCII characters only
+	Width   int  // Wrap width of boxes (0 = the terminal width)
+}
+
+// themes are the built-in themes by name.
+var themes = map[string]Theme{
+	"default": {
+		Accent: lipgloss.Color("63"), Text: lipgloss.Color("252"), Muted: lipgloss.Color("244"),
+		Number: lipgloss.Color("208"), Divider: lipgloss.Color("236"), Success: lipgloss.Color("42"),
+		Error: lipgloss.Color("196"), Warning: lipgloss.Color("226"), Notice: lipgloss.Color("#FFA500"),
+	},
+	// For light terminal backgrounds
+	"light": {
+		Accent: lipgloss.Color("55"), Text: lipgloss.Color("235"), Muted: lipgloss.Color("242"),
+		Number: lipgloss.Color("130"), Divider: lipgloss.Color("250"), Success: lipgloss.Color("28"),
+		Error: lipgloss.Color("160"), Warning: lipgloss.Color("136"), Notice: lipgloss.Color("166"),
+	},
+	"high-contrast": {
+		Accent: lipgloss.Color("51"), Text: lipgloss.Color("231"), Muted: lipgloss.Color("250"),
+		Number: lipgloss.Color("226"), Divider: lipgloss.Color("250"), Success: lipgloss.Color("46"),
+		Error: lipgloss.Color("196"), Warning: lipgloss.Color("226"), Notice: lipgloss.Color("214"),
+	},
+	"mono": monoTheme(),
+}
+
+func monoTheme() Theme {
+	none := lipgloss.NoColor{}
+	return Theme{
+		Accent: none, Text: none, Muted: none, Number: none, Divider: none,
+		Success: none, Error: none, Warning: none, Notice: none, NoColor: true,
+	}
+}
+
+// ThemeNames returns the names of the built-in themes.
+func ThemeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ThemeByName returns a built-in theme; an empty name is the default.
+func ThemeByName(name string) (Theme, error) {
+	if name == "" {
+		name = "default"
+	}
+	theme, ok := themes[name]
+	if !ok {
+		return Theme{}, fmt.Errorf("unknown theme %q (available: %s)", name, strings.Join(ThemeNames(), ", "))
+	}
+	theme.Name = name
+	return theme, nil
+}
+
+var currentTheme atomic.Pointer[Theme]
+
+// CurrentTheme returns the theme console output is rendered with.
+func CurrentTheme() Theme {
+	if t := currentTheme.Load(); t != nil {
+		return *t
+	}
+	theme, _ := ThemeByName("")
+	return theme
+}
+
+// SetTheme sets the theme console output is rendered with.
+func SetTheme(t Theme) {
+	currentTheme.Store(&t)
+}
+
+// ConfigureTheme applies the ui section of config.yaml. ASTONISH_THEME
+// overrides it with a theme name, "ascii" or both ("light,ascii"). Colors
+// are off with NO_COLOR set or when stdout is not a terminal. Call it once
+// after loading the app config.
+func ConfigureTheme(cfg *config.AppConfig) error {
+	var uiCfg config.UIConfig
+	if cfg != nil {
+		uiCfg = cfg.UI
+	}
+	name, ascii := uiCfg.Theme, uiCfg.ASCII
+	if env := os.Getenv("ASTONISH_THEME"); env != "" {
+		name = ""
+		for _, part := range strings.Split(env, ",") {
+			switch part = strings.TrimSpace(part); part {
+			case "ascii":
+				ascii = true
+			case "":
+			default:
+				name = part
+			}
+		}
+	}
+	theme, err := ThemeByName(name)
+	if err != nil {
+		return fmt.Errorf("invalid ui config: %w", err)
+	}
+	if _, noColor := os.LookupEnv("NO_COLOR"); noColor || !term.IsTerminal(int(os.Stdout.Fd())) {
+		mono := monoTheme()
+		mono.Name = theme.Name
+		theme = mono
+	}
+	theme.ASCII = ascii
+	theme.Width = uiCfg.Width
+	SetTheme(theme)
+	return nil
+}
+
+// asciiBorder draws boxes with ASCII characters.
+var asciiBorder = lipgloss.Border{
+	Top: "-", Bottom: "-", Left: "|", Right: "|",
+	TopLeft: "+", TopRight: "+", BottomLeft: "+", BottomRight: "+",
+}
+
+// border returns the box border of the theme.
+func (t Theme) border() lipgloss.Border {
+	if t.ASCII {
+		return asciiBorder
+	}
+	return lipgloss.RoundedBorder()
+}
+
+// rule returns the border of dividers.
+func (t Theme) rule() lipgloss.Border {
+	if t.ASCII {
+		return asciiBorder
+	}
+	return lipgloss.NormalBorder()
+}
+
+// icon returns fancy, or plain with ASCII-only output.
+func (t Theme) icon(fancy, plain string) string {
+	if t.ASCII {
+		return plain
+	}
+	return fancy
+}
+
+// boxWidth returns preferred, narrowed to the wrap width of the theme
+// (the terminal width by default) with room for the border.
+func (t Theme) boxWidth(preferred int) int {
+	width := t.Width
+	if width <= 0 {
+		width = terminalWidth()
+	}
+	if width-2 < preferred {
+		preferred = max(width-2, 20)
+	}
+	return preferred
+}
+
+// terminalWidth returns the width of stdout, or 80 when it is not a
+// terminal.
+func terminalWidth() int {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 {
+		return 80
+	}
+	return width
+}
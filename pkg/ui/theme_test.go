@@ -0,0 +1,45 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SAP/astonish/pkg/config"
+)
+
+func TestConfigureTheme(t *testing.T) {
+	prev := CurrentTheme()
+	t.Cleanup(func() { SetTheme(prev) })
+
+	// ASTONISH_THEME overrides the config; stdout is not a terminal here,
+	// so colors are off
+	t.Setenv("ASTONISH_THEME", "light,ascii")
+	if err := ConfigureTheme(&config.AppConfig{UI: config.UIConfig{Theme: "high-contrast", Width: 40}}); err != nil {
+		t.Fatal(err)
+	}
+	theme := CurrentTheme()
+	if theme.Name != "light" || !theme.ASCII || !theme.NoColor || theme.Width != 40 {
+		t.Errorf("theme = %+v, want light, ASCII, no colors, width 40", theme)
+	}
+
+	box := RenderToolBox("search", map[string]any{"query": "go"})
+	if strings.ContainsAny(box, "╭│🛠") || !strings.Contains(box, "+--") {
+		t.Errorf("tool box is not ASCII-only:\n%s", box)
+	}
+	for _, line := range strings.Split(strings.TrimRight(box, "\n"), "\n") {
+		if len(line) > 40 {
+			t.Errorf("tool box line is %d wide, over the 40 wrap width: %q", len(line), line)
+		}
+	}
+	if badge := RenderStatusBadge("Command approved", true); badge != "+ Command approved" {
+		t.Errorf("badge = %q", badge)
+	}
+	if errBox := RenderErrorBox("Failed", "reason", "", ""); strings.Contains(errBox, "✕") || strings.Contains(errBox, "\x1b[") {
+		t.Errorf("error box = %q, want ASCII without colors", errBox)
+	}
+
+	t.Setenv("ASTONISH_THEME", "solarized")
+	if err := ConfigureTheme(nil); err == nil {
+		t.Error("an unknown theme was accepted")
+	}
+}
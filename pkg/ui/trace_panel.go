@@ -0,0 +1,194 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// maxTraceRows bounds the number of steps shown in the trace panel; older
+// steps are collapsed into a single line.
+const maxTraceRows = 12
+
+// traceNameWidth is the column width of node names in the trace panel.
+const traceNameWidth = 24
+
+// TraceStep is one visit of a node during a flow run.
+type TraceStep struct {
+	Node       string
+	Type       string
+	Start      time.Time
+	End        time.Time // zero while the node is running
+	Retries    int
+	MaxRetries int
+}
+
+// FlowTrace records the path a flow run takes through its nodes. It is
+// updated from the agent's state deltas and rendered as a side panel by
+// TraceSpinnerModel. It is safe for concurrent use.
+type FlowTrace struct {
+	mu    sync.Mutex
+	steps []TraceStep
+	start time.Time
+	end   time.Time
+	now   func() time.Time
+}
+
+// NewFlowTrace creates an empty trace whose clock starts now.
+func NewFlowTrace() *FlowTrace {
+	return &FlowTrace{start: time.Now(), now: time.Now}
+}
+
+// Enter closes the running step and starts a new one for node.
+func (t *FlowTrace) Enter(node, nodeType string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	t.closeLocked(now)
+	t.steps = append(t.steps, TraceStep{Node: node, Type: nodeType, Start: now})
+}
+
+// Retry records the retry number (as in the _retry_info badge) of the
+// running step.
+func (t *FlowTrace) Retry(attempt, maxRetries int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.steps) == 0 {
+		return
+	}
+	step := &t.steps[len(t.steps)-1]
+	if attempt > step.Retries {
+		step.Retries = attempt
+	}
+	step.MaxRetries = maxRetries
+}
+
+// Finish closes the running step and stops the total clock.
+func (t *FlowTrace) Finish() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	t.closeLocked(now)
+	if t.end.IsZero() {
+		t.end = now
+	}
+}
+
+// Current returns the name of the running node, or "" when none is running.
+func (t *FlowTrace) Current() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if n := len(t.steps); n > 0 && t.steps[n-1].End.IsZero() {
+		return t.steps[n-1].Node
+	}
+	return ""
+}
+
+// Steps returns a copy of the recorded steps.
+func (t *FlowTrace) Steps() []TraceStep {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]TraceStep(nil), t.steps...)
+}
+
+func (t *FlowTrace) closeLocked(now time.Time) {
+	if n := len(t.steps); n > 0 && t.steps[n-1].End.IsZero() {
+		t.steps[n-1].End = now
+	}
+}
+
+// View renders the trace as a bordered panel: one row per visited node
+// with its type, retries and elapsed time, the running node highlighted.
+func (t *FlowTrace) View() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+	done := lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+	running := lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Bold(true)
+	retry := lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+
+	total := now.Sub(t.start)
+	if !t.end.IsZero() {
+		total = t.end.Sub(t.start)
+	}
+	var lines []string
+	noun := "steps"
+	if len(t.steps) == 1 {
+		noun = "step"
+	}
+	lines = append(lines, fmt.Sprintf("%s  %s", lipgloss.NewStyle().Bold(true).Render("Trace"),
+		dim.Render(fmt.Sprintf("%d %s · %s", len(t.steps), noun, formatElapsed(total)))))
+
+	steps := t.steps
+	if hidden := len(steps) - maxTraceRows; hidden > 0 {
+		lines = append(lines, dim.Render(fmt.Sprintf("  … %d earlier", hidden)))
+		steps = steps[hidden:]
+	}
+	for _, s := range steps {
+		marker, nameStyle := done.Render("✓"), lipgloss.NewStyle()
+		end := s.End
+		if end.IsZero() {
+			marker, nameStyle = running.Render("▶"), running
+			end = now
+		}
+		name := s.Node
+		if r := []rune(name); len(r) > traceNameWidth {
+			name = string(r[:traceNameWidth-1]) + "…"
+		}
+		row := fmt.Sprintf("%s %s %s", marker, nameStyle.Render(fmt.Sprintf("%-*s", traceNameWidth, name)),
+			dim.Render(fmt.Sprintf("%-7s", s.Type)))
+		if s.Retries > 0 {
+			row += " " + retry.Render(fmt.Sprintf("↻%d/%d", s.Retries, s.MaxRetries))
+		}
+		row += " " + dim.Render(formatElapsed(end.Sub(s.Start)))
+		lines = append(lines, row)
+	}
+
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("63")).
+		Padding(0, 1).
+		Render(strings.Join(lines, "\n"))
+}
+
+// formatElapsed formats d as seconds with one decimal below a minute and as
+// minutes and seconds (2m05s) above.
+func formatElapsed(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	}
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+}
+
+// TraceSpinnerModel is a spinner with the flow trace panel beside it. The
+// panel is redrawn on every spinner tick, so elapsed times keep updating.
+type TraceSpinnerModel struct {
+	SpinnerModel
+	trace *FlowTrace
+}
+
+// NewTraceSpinner creates a spinner showing text next to the trace panel.
+func NewTraceSpinner(text string, trace *FlowTrace) TraceSpinnerModel {
+	return TraceSpinnerModel{SpinnerModel: NewSpinner(text), trace: trace}
+}
+
+func (m TraceSpinnerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	updated, cmd := m.SpinnerModel.Update(msg)
+	m.SpinnerModel = updated.(SpinnerModel)
+	return m, cmd
+}
+
+func (m TraceSpinnerModel) View() string {
+	if m.quitting {
+		return ""
+	}
+	left := lipgloss.NewStyle().Width(40).Render(m.SpinnerModel.View())
+	return lipgloss.JoinHorizontal(lipgloss.Top, left, " ", m.trace.View())
+}
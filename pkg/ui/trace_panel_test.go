@@ -0,0 +1,79 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock returns a clock that advances by step on every call.
+func fakeClock(step time.Duration) func() time.Time {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	return func() time.Time {
+		now = now.Add(step)
+		return now
+	}
+}
+
+func TestFlowTraceSteps(t *testing.T) {
+	t.Parallel()
+	tr := NewFlowTrace()
+	tr.now = fakeClock(time.Second)
+
+	tr.Enter("fetch", "tool")
+	tr.Enter("summarize", "llm")
+	tr.Retry(1, 3)
+	tr.Retry(2, 3)
+	tr.Enter("summarize", "llm") // loops revisit the same node
+	tr.Finish()
+
+	steps := tr.Steps()
+	if len(steps) != 3 {
+		t.Fatalf("expected 3 steps, got %+v", steps)
+	}
+	if steps[1].Retries != 2 || steps[1].MaxRetries != 3 || steps[2].Retries != 0 {
+		t.Errorf("unexpected retries %+v", steps)
+	}
+	for _, s := range steps {
+		if s.End.IsZero() || !s.End.After(s.Start) {
+			t.Errorf("step %s not closed: %+v", s.Node, s)
+		}
+	}
+}
+
+func TestFlowTraceView(t *testing.T) {
+	t.Parallel()
+	tr := NewFlowTrace()
+	tr.now = fakeClock(time.Second)
+	tr.Retry(1, 3) // ignored before the first node
+	tr.Enter("fetch_issues", "tool")
+	tr.Enter("triage", "llm")
+	tr.Retry(1, 3)
+
+	view := tr.View()
+	for _, want := range []string{"Trace", "2 steps", "✓ fetch_issues", "▶", "triage", "↻1/3", "1.0s"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("expected %q in view:\n%s", want, view)
+		}
+	}
+
+	// Long runs collapse the oldest steps
+	for i := 0; i < maxTraceRows+3; i++ {
+		tr.Enter(fmt.Sprintf("step_%d", i), "llm")
+	}
+	view = tr.View()
+	if !strings.Contains(view, "… 5 earlier") || strings.Contains(view, "fetch_issues") {
+		t.Errorf("expected older steps to be collapsed:\n%s", view)
+	}
+}
+
+func TestFormatElapsed(t *testing.T) {
+	t.Parallel()
+	if got := formatElapsed(1500 * time.Millisecond); got != "1.5s" {
+		t.Errorf("got %q", got)
+	}
+	if got := formatElapsed(125 * time.Second); got != "2m05s" {
+		t.Errorf("got %q", got)
+	}
+}
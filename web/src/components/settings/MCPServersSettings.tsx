@@ -666,6 +666,7 @@ export default function MCPServersSettings({
                               >
                                 <option value="stdio">stdio</option>
                                 <option value="sse">sse</option>
+                                <option value="streamable-http">streamable-http</option>
                               </select>
                             </div>
                             {(server.transport || 'stdio') === 'stdio' ? (
@@ -57,6 +57,7 @@ const NODE_TYPE_MAP: Record<string, string> = {
   tool: 'tool',
   output: 'output',
   update_state: 'updateState',
+  script: 'updateState',
 }
 
 const elkOptions: Record<string, string> = {
@@ -102,7 +103,7 @@ export function parseNodes(yamlData: YamlData, savedLayout: SavedLayout | null =
         }
         
         const validationErrors: string[] = []
-        const VALID_NODE_TYPES = ['input', 'llm', 'tool', 'output', 'update_state']
+        const VALID_NODE_TYPES = ['input', 'llm', 'tool', 'output', 'update_state', 'script']
         
         if (!node.type) {
           validationErrors.push(`'type' is required. Valid types: ${VALID_NODE_TYPES.join(', ')}`)
@@ -132,6 +133,10 @@ export function parseNodes(yamlData: YamlData, savedLayout: SavedLayout | null =
           }
         }
         
+        if (node.type === 'script' && !node.script) {
+          validationErrors.push(`'script' is required for script nodes`)
+        }
+        
         if (node.type === 'output') {
           if (!node.user_message) {
             validationErrors.push(`'user_message' is required for output nodes to display content to the user`)